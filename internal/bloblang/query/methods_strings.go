@@ -24,12 +24,17 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	igrok "github.com/Jeffail/benthos/v3/internal/grok"
 	"github.com/Jeffail/benthos/v3/internal/xml"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/Jeffail/grok"
 	"github.com/OneOfOne/xxhash"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/tilinna/z85"
+	"github.com/ua-parser/uap-go/uaparser"
 )
 
 var _ = RegisterMethod(
@@ -968,6 +973,75 @@ func parseCSVMethod(target Function, _ ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"parse_grok", "",
+	).InCategory(
+		MethodCategoryParsing,
+		"Attempts to parse a string with a [Grok expression](/docs/components/processors/grok) and returns an object containing the named captures of the expression. Type hints within patterns are respected. Optional string arguments following the expression specify paths to files, directories or glob patterns of files containing [custom pattern definitions](/docs/components/processors/grok#pattern_paths), which can be referenced along with the [default patterns](/docs/components/processors/grok#default-patterns). When the arguments are static the expression is compiled once and reused.",
+		NewExampleSpec("",
+			`root.doc = this.line.parse_grok("%{WORD:method} %{URIPATH:path} %{INT:status:int}")`,
+			`{"line":"GET /foo/bar 404"}`,
+			`{"doc":{"method":"GET","path":"/foo/bar","status":404}}`,
+		),
+	).Beta(),
+	true, parseGrokMethod,
+	ExpectAtLeastOneArg(),
+	ExpectAllStringArgs(),
+)
+
+func parseGrokMethod(target Function, args ...interface{}) (Function, error) {
+	patterns := map[string]string{}
+	for _, path := range args[1:] {
+		if err := igrok.AddPatternsFromPath(path.(string), patterns); err != nil {
+			return nil, fmt.Errorf("failed to parse patterns from path '%v': %w", path, err)
+		}
+	}
+
+	gcompiler, err := grok.New(grok.Config{
+		RemoveEmptyValues: true,
+		NamedCapturesOnly: true,
+		Patterns:          patterns,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grok compiler: %w", err)
+	}
+
+	expr := args[0].(string)
+	gcompiled, err := gcompiler.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile grok expression '%v': %w", expr, err)
+	}
+
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		var body []byte
+		switch t := v.(type) {
+		case string:
+			body = []byte(t)
+		case []byte:
+			body = t
+		default:
+			return nil, NewTypeError(v, ValueString)
+		}
+
+		values, err := gcompiled.ParseTyped(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return nil, errors.New("no grok pattern matches found")
+		}
+
+		gObj := gabs.New()
+		for k, v := range values {
+			gObj.SetP(v, k)
+		}
+		return gObj.Data(), nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"parse_json", "",
@@ -1047,6 +1121,80 @@ func parseXMLMethod(target Function, _ ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = RegisterMethod(
+	NewMethodSpec(
+		"parse_user_agent", "",
+	).InCategory(
+		MethodCategoryParsing,
+		"Attempts to parse a User-Agent string using the [uap-core](https://github.com/ua-parser/uap-core) rules, and returns an object containing the `browser`, `os` and `device` of the client. The browser and operating system each contain a `family` along with any `major`, `minor` and `patch` version numbers that were found, and the device contains a `family`, `brand` and `model`, where fields that could not be determined are omitted. The rules are compiled once and shared by all usages of this method.",
+		NewExampleSpec("",
+			`let ua = this.user_agent.parse_user_agent()
+root.browser = $ua.browser.family
+root.os = $ua.os.family
+root.device = $ua.device.family`,
+			`{"user_agent":"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/95.0.4638.69 Safari/537.36"}`,
+			`{"browser":"Chrome","device":"Mac","os":"Mac OS X"}`,
+		),
+	).Beta(),
+	false, parseUserAgentMethod,
+	ExpectNArgs(0),
+)
+
+var (
+	userAgentParser     *uaparser.Parser
+	userAgentParserOnce sync.Once
+)
+
+func userAgentSetNonEmpty(obj map[string]interface{}, key, value string) {
+	if value != "" {
+		obj[key] = value
+	}
+}
+
+func parseUserAgentMethod(target Function, _ ...interface{}) (Function, error) {
+	userAgentParserOnce.Do(func() {
+		userAgentParser = uaparser.NewFromSaved()
+	})
+	return simpleMethod(target, func(v interface{}, ctx FunctionContext) (interface{}, error) {
+		var uaStr string
+		switch t := v.(type) {
+		case string:
+			uaStr = t
+		case []byte:
+			uaStr = string(t)
+		default:
+			return nil, NewTypeError(v, ValueString)
+		}
+
+		client := userAgentParser.Parse(uaStr)
+
+		browser := map[string]interface{}{}
+		userAgentSetNonEmpty(browser, "family", client.UserAgent.Family)
+		userAgentSetNonEmpty(browser, "major", client.UserAgent.Major)
+		userAgentSetNonEmpty(browser, "minor", client.UserAgent.Minor)
+		userAgentSetNonEmpty(browser, "patch", client.UserAgent.Patch)
+
+		os := map[string]interface{}{}
+		userAgentSetNonEmpty(os, "family", client.Os.Family)
+		userAgentSetNonEmpty(os, "major", client.Os.Major)
+		userAgentSetNonEmpty(os, "minor", client.Os.Minor)
+		userAgentSetNonEmpty(os, "patch", client.Os.Patch)
+
+		device := map[string]interface{}{}
+		userAgentSetNonEmpty(device, "family", client.Device.Family)
+		userAgentSetNonEmpty(device, "brand", client.Device.Brand)
+		userAgentSetNonEmpty(device, "model", client.Device.Model)
+
+		return map[string]interface{}{
+			"browser": browser,
+			"os":      os,
+			"device":  device,
+		}, nil
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = RegisterMethod(
 	NewMethodSpec(
 		"parse_timestamp_unix", "",
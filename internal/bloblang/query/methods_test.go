@@ -2,6 +2,9 @@ package query
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -116,6 +119,44 @@ func TestMethods(t *testing.T) {
 			),
 			err: "record on line 2: wrong number of fields",
 		},
+		"check parse grok": {
+			input: methods(
+				literalFn("foo 10"),
+				method("parse_grok", "%{WORD:a.b} %{INT:a.c:int}"),
+				method("string"),
+			),
+			output: `{"a":{"b":"foo","c":10}}`,
+		},
+		"check parse grok no matches": {
+			input: methods(
+				literalFn("foo bar"),
+				method("parse_grok", "%{INT:a}"),
+			),
+			err: "no grok pattern matches found",
+		},
+		"check parse user agent": {
+			input: methods(
+				literalFn("Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:94.0) Gecko/20100101 Firefox/94.0"),
+				method("parse_user_agent"),
+				method("string"),
+			),
+			output: `{"browser":{"family":"Firefox","major":"94","minor":"0"},"device":{"family":"Other"},"os":{"family":"Windows","major":"10"}}`,
+		},
+		"check parse user agent unknown": {
+			input: methods(
+				literalFn("foo"),
+				method("parse_user_agent"),
+				method("string"),
+			),
+			output: `{"browser":{"family":"Other"},"device":{"family":"Other"},"os":{"family":"Other"}}`,
+		},
+		"check parse user agent not string": {
+			input: methods(
+				literalFn(int64(10)),
+				method("parse_user_agent"),
+			),
+			err: `expected string value, found number: 10`,
+		},
 		"check explode 1": {
 			input: methods(
 				jsonFn(`{"foo":[1,2,3],"id":"bar"}`),
@@ -1995,3 +2036,24 @@ func TestMethodNoArgsTargets(t *testing.T) {
 		assert.Contains(t, targets, exp)
 	}
 }
+
+func TestMethodParseGrokPatternPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_bloblang_grok_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo"), []byte("FOO %{WORD:foo} %{BAR}\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bar"), []byte("BAR %{INT:bar:int}\n"), 0644))
+
+	fn, err := InitMethod("parse_grok", NewLiteralFunction("foo 10"), "%{FOO}", filepath.Join(dir, "foo"), filepath.Join(dir, "bar"))
+	require.NoError(t, err)
+
+	res, err := fn.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "foo", "bar": 10}, res)
+
+	_, err = InitMethod("parse_grok", NewLiteralFunction("foo 10"), "%{FOO}", filepath.Join(dir, "nope"))
+	require.Error(t, err)
+}
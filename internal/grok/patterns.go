@@ -0,0 +1,56 @@
+// Package grok provides utilities shared by the components that parse Grok
+// expressions.
+package grok
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/filepath"
+)
+
+// AddPatternsFromPath reads Grok pattern definitions from a file, each file of
+// a directory, or each file matching a glob pattern, and adds them to a map of
+// patterns. Each line of a file is expected to be of the form `NAME pattern`,
+// where empty lines and lines beginning with a `#` are ignored.
+func AddPatternsFromPath(path string, patterns map[string]string) error {
+	if s, err := os.Stat(path); err == nil && s.IsDir() {
+		path = path + "/*"
+	}
+
+	files, err := filepath.Globs([]string{path})
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := addPatternsFromFile(f, patterns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addPatternsFromFile(path string, patterns map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 1; scanner.Scan(); i++ {
+		l := strings.TrimSpace(scanner.Text())
+		if len(l) == 0 || l[0] == '#' {
+			continue
+		}
+		names := strings.SplitN(l, " ", 2)
+		if len(names) != 2 {
+			return fmt.Errorf("%v: line %v: expected a pattern name followed by a definition", path, i)
+		}
+		patterns[names[0]] = strings.TrimSpace(names[1])
+	}
+	return scanner.Err()
+}
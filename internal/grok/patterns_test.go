@@ -0,0 +1,46 @@
+package grok
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddPatternsFromPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_grok_patterns_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo"), []byte(`
+# A comment
+FOO %{WORD:foo}
+
+BAR %{INT:bar:int}
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bar"), []byte(`BAZ \d+`), 0644))
+
+	patterns := map[string]string{}
+	require.NoError(t, AddPatternsFromPath(dir, patterns))
+	assert.Equal(t, map[string]string{
+		"FOO": "%{WORD:foo}",
+		"BAR": "%{INT:bar:int}",
+		"BAZ": `\d+`,
+	}, patterns)
+
+	patterns = map[string]string{}
+	require.NoError(t, AddPatternsFromPath(filepath.Join(dir, "b*"), patterns))
+	assert.Equal(t, map[string]string{
+		"BAZ": `\d+`,
+	}, patterns)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad"), []byte("NOPE"), 0644))
+	require.Error(t, AddPatternsFromPath(dir, map[string]string{}))
+
+	require.Error(t, AddPatternsFromPath(filepath.Join(dir, "does_not_exist"), map[string]string{}))
+}
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -13,18 +14,21 @@ import (
 
 // Config is a config struct for a redis connection.
 type Config struct {
-	URL    string      `json:"url" yaml:"url"`
-	Kind   string      `json:"kind" yaml:"kind"`
-	Master string      `json:"master" yaml:"master"`
-	TLS    btls.Config `json:"tls" yaml:"tls"`
+	URL      string      `json:"url" yaml:"url"`
+	Kind     string      `json:"kind" yaml:"kind"`
+	Master   string      `json:"master" yaml:"master"`
+	Username string      `json:"username" yaml:"username"`
+	TLS      btls.Config `json:"tls" yaml:"tls"`
 }
 
 // NewConfig returns a Config with default values.
 func NewConfig() Config {
 	return Config{
-		URL:  "tcp://localhost:6379",
-		Kind: "simple",
-		TLS:  btls.NewConfig(),
+		URL:      "tcp://localhost:6379",
+		Kind:     "simple",
+		Master:   "",
+		Username: "",
+		TLS:      btls.NewConfig(),
 	}
 }
 
@@ -75,12 +79,33 @@ func (r Config) Client() (redis.UniversalClient, error) {
 		TLSConfig: tlsConf,
 	}
 
+	// Authenticating as an ACL user requires the two argument form of AUTH,
+	// which we send ourselves on each new connection instead of the password
+	// only form.
+	if r.Username != "" {
+		if pass == "" {
+			return nil, errors.New("a password must be specified within the url when a username is set")
+		}
+		username := r.Username
+		opts.Password = ""
+		opts.OnConnect = func(cn *redis.Conn) error {
+			_, err := cn.Pipelined(func(pipe redis.Pipeliner) error {
+				pipe.Do("AUTH", username, pass)
+				return nil
+			})
+			return err
+		}
+	}
+
 	switch r.Kind {
 	case "simple":
 		client = redis.NewClient(opts.Simple())
 	case "cluster":
 		client = redis.NewClusterClient(opts.Cluster())
 	case "failover":
+		if r.Master == "" {
+			return nil, errors.New("a master name must be specified when kind is failover")
+		}
 		opts.MasterName = r.Master
 		client = redis.NewFailoverClient(opts.Failover())
 	default:
@@ -101,8 +126,9 @@ func ConfigDocs() docs.FieldSpecs {
 			"redis://localhost:6379/1",
 			"redis://localhost:6379/1,redis://localhost:6380/1",
 		),
-		docs.FieldAdvanced("kind", "Specifies a simple, cluster-aware, or failover-aware redis client.", "simple", "cluster", "failover"),
+		docs.FieldAdvanced("kind", "Specifies a simple, cluster-aware, or failover-aware redis client. When `kind` is `cluster` the URLs are used as seed nodes of a Redis Cluster, and when `kind` is `failover` the URLs are the addresses of Redis Sentinel nodes.", "simple", "cluster", "failover"),
 		docs.FieldAdvanced("master", "Name of the redis master when `kind` is `failover`", "mymaster"),
+		docs.FieldAdvanced("username", "An optional [ACL](https://redis.io/topics/acl) username to authenticate as, with the password supplied within the URL, which must be set when a username is set. Requires Redis 6 or later.", "benthos").AtVersion("3.39.0"),
 		btls.FieldSpec(),
 	}
 }
@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigClientKinds(t *testing.T) {
+	for _, kind := range []string{"simple", "cluster", "failover"} {
+		conf := NewConfig()
+		conf.URL = "redis://:secret@localhost:6379,redis://:secret@localhost:6380"
+		conf.Kind = kind
+		conf.Master = "mymaster"
+		conf.Username = "benthos"
+
+		client, err := conf.Client()
+		require.NoError(t, err, kind)
+		require.NoError(t, client.Close(), kind)
+	}
+}
+
+func TestConfigClientErrors(t *testing.T) {
+	tests := map[string]func(c *Config){
+		"bad kind": func(c *Config) {
+			c.Kind = "nope"
+		},
+		"failover without master": func(c *Config) {
+			c.Kind = "failover"
+		},
+		"bad url": func(c *Config) {
+			c.URL = "not a url%"
+		},
+		"username without password": func(c *Config) {
+			c.Username = "benthos"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		fn(&conf)
+
+		_, err := conf.Client()
+		assert.Error(t, err, name)
+	}
+}
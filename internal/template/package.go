@@ -0,0 +1,3 @@
+// Package template implements user defined component templates, which are
+// components with parameters that expand into the config of another component.
+package template
@@ -0,0 +1,357 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	ifilepath "github.com/Jeffail/benthos/v3/internal/filepath"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// FieldConfig describes a parameter of a template.
+type FieldConfig struct {
+	Name        string       `json:"name" yaml:"name"`
+	Description string       `json:"description" yaml:"description"`
+	Type        string       `json:"type" yaml:"type"`
+	Default     *interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Config describes a template, which is a component with parameters that is
+// expanded into the config of another component with a Bloblang mapping.
+type Config struct {
+	Name        string        `json:"name" yaml:"name"`
+	Type        string        `json:"type" yaml:"type"`
+	Description string        `json:"description" yaml:"description"`
+	Fields      []FieldConfig `json:"fields" yaml:"fields"`
+	Mapping     string        `json:"mapping" yaml:"mapping"`
+}
+
+var fieldTypes = map[string]struct{}{
+	"":       {},
+	"string": {},
+	"int":    {},
+	"float":  {},
+	"bool":   {},
+	"array":  {},
+	"object": {},
+}
+
+//------------------------------------------------------------------------------
+
+// Template is a parsed template that can expand parameters into a component
+// config.
+type Template struct {
+	conf   Config
+	exec   *mapping.Executor
+	fields map[string]FieldConfig
+}
+
+// New parses a template from a config.
+func New(conf Config) (*Template, error) {
+	if conf.Name == "" {
+		return nil, errors.New("a template name must be specified")
+	}
+	switch conf.Type {
+	case "input", "processor", "output":
+	default:
+		return nil, fmt.Errorf("template type '%v' not recognised, expected input, processor or output", conf.Type)
+	}
+
+	t := &Template{
+		conf:   conf,
+		fields: map[string]FieldConfig{},
+	}
+	for _, f := range conf.Fields {
+		if f.Name == "" {
+			return nil, errors.New("template fields must have a name")
+		}
+		if _, exists := fieldTypes[f.Type]; !exists {
+			return nil, fmt.Errorf("field '%v': type '%v' not recognised", f.Name, f.Type)
+		}
+		if _, exists := t.fields[f.Name]; exists {
+			return nil, fmt.Errorf("field '%v' is specified more than once", f.Name)
+		}
+		t.fields[f.Name] = f
+	}
+
+	var err error
+	if t.exec, err = bloblang.NewMapping("", conf.Mapping); err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			err = errors.New(perr.ErrorAtPosition([]rune(conf.Mapping)))
+		}
+		return nil, fmt.Errorf("failed to parse mapping: %v", err)
+	}
+	return t, nil
+}
+
+// ReadFile reads and parses a template from a YAML file.
+func ReadFile(path string) (*Template, error) {
+	confBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var conf Config
+	if err = yaml.Unmarshal(confBytes, &conf); err != nil {
+		return nil, err
+	}
+	return New(conf)
+}
+
+// Name returns the name of the template.
+func (t *Template) Name() string {
+	return t.conf.Name
+}
+
+// Type returns the type of component the template expands into.
+func (t *Template) Type() string {
+	return t.conf.Type
+}
+
+//------------------------------------------------------------------------------
+
+// checkField returns an error if a parameter value does not match the type of
+// its field.
+func checkField(f FieldConfig, v interface{}) error {
+	var ok bool
+	switch f.Type {
+	case "string":
+		_, ok = v.(string)
+	case "int":
+		var n float64
+		if n, ok = v.(float64); ok {
+			ok = n == float64(int64(n))
+		}
+	case "float":
+		_, ok = v.(float64)
+	case "bool":
+		_, ok = v.(bool)
+	case "array":
+		_, ok = v.([]interface{})
+	case "object":
+		_, ok = v.(map[string]interface{})
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("field '%v': expected %v value, got %T", f.Name, f.Type, v)
+	}
+	return nil
+}
+
+// params normalises parameters, applying defaults and returning an error if
+// any are unrecognised, missing or of the wrong type.
+func (t *Template) params(conf interface{}) (map[string]interface{}, error) {
+	// Normalise into JSON types so that values are consistent regardless of
+	// how they were parsed.
+	jBytes, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	var params map[string]interface{}
+	if err = json.Unmarshal(jBytes, &params); err != nil {
+		return nil, fmt.Errorf("expected an object of parameters: %v", err)
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	var unknown []string
+	for k := range params {
+		if _, exists := t.fields[k]; !exists {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("fields not recognised: %v", strings.Join(unknown, ", "))
+	}
+
+	for _, f := range t.conf.Fields {
+		v, exists := params[f.Name]
+		if !exists {
+			if f.Default == nil {
+				return nil, fmt.Errorf("field '%v' is required", f.Name)
+			}
+			if jBytes, err = json.Marshal(*f.Default); err != nil {
+				return nil, err
+			}
+			if err = json.Unmarshal(jBytes, &v); err != nil {
+				return nil, err
+			}
+			params[f.Name] = v
+		}
+		if err = checkField(f, v); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// Expand applies parameters to the template and returns the resulting
+// component config as YAML.
+func (t *Template) Expand(conf interface{}) ([]byte, error) {
+	params, err := t.params(conf)
+	if err != nil {
+		return nil, fmt.Errorf("template '%v': %v", t.conf.Name, err)
+	}
+
+	res, err := t.exec.Exec(query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{},
+		MsgBatch: message.New(nil),
+	}.WithValue(params))
+	if err != nil {
+		return nil, fmt.Errorf("template '%v': %v", t.conf.Name, err)
+	}
+	if _, ok := res.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("template '%v': expected mapping to result in an object, got %T", t.conf.Name, res)
+	}
+	return yaml.Marshal(res)
+}
+
+func (t *Template) expandInto(conf interface{}, target interface{}) error {
+	confBytes, err := t.Expand(conf)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(confBytes, target); err != nil {
+		return fmt.Errorf("template '%v': failed to parse expanded config: %v", t.conf.Name, err)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func newParams() interface{} {
+	return &map[string]interface{}{}
+}
+
+var (
+	registeredMut sync.Mutex
+	registered    = map[string]struct{}{}
+)
+
+// Register adds the template as a plugin of its component type, which can be
+// used within configs by setting the type to the name of the template and the
+// plugin field to its parameters.
+func (t *Template) Register() error {
+	var exists bool
+	switch t.conf.Type {
+	case "input":
+		_, exists = input.Constructors[t.conf.Name]
+	case "processor":
+		_, exists = processor.Constructors[t.conf.Name]
+	case "output":
+		_, exists = output.Constructors[t.conf.Name]
+	}
+	if exists {
+		return fmt.Errorf("template '%v' conflicts with an existing %v", t.conf.Name, t.conf.Type)
+	}
+
+	registeredMut.Lock()
+	key := t.conf.Type + ":" + t.conf.Name
+	_, exists = registered[key]
+	registered[key] = struct{}{}
+	registeredMut.Unlock()
+	if exists {
+		return fmt.Errorf("%v template '%v' is registered more than once", t.conf.Type, t.conf.Name)
+	}
+
+	switch t.conf.Type {
+	case "input":
+		input.RegisterPlugin(t.conf.Name, newParams, func(
+			conf interface{}, mgr types.Manager, log log.Modular, stats metrics.Type,
+		) (types.Input, error) {
+			inConf := input.NewConfig()
+			if err := t.expandInto(conf, &inConf); err != nil {
+				return nil, err
+			}
+			return input.New(inConf, mgr, log, stats)
+		})
+		input.DocumentPlugin(t.conf.Name, t.conf.Description, nil)
+	case "processor":
+		processor.RegisterPlugin(t.conf.Name, newParams, func(
+			conf interface{}, mgr types.Manager, log log.Modular, stats metrics.Type,
+		) (types.Processor, error) {
+			procConf := processor.NewConfig()
+			if err := t.expandInto(conf, &procConf); err != nil {
+				return nil, err
+			}
+			return processor.New(procConf, mgr, log, stats)
+		})
+		processor.DocumentPlugin(t.conf.Name, t.conf.Description, nil)
+	case "output":
+		output.RegisterPlugin(t.conf.Name, newParams, func(
+			conf interface{}, mgr types.Manager, log log.Modular, stats metrics.Type,
+		) (types.Output, error) {
+			outConf := output.NewConfig()
+			if err := t.expandInto(conf, &outConf); err != nil {
+				return nil, err
+			}
+			return output.New(outConf, mgr, log, stats)
+		})
+		output.DocumentPlugin(t.conf.Name, t.conf.Description, nil)
+	}
+	return nil
+}
+
+// InitTemplates reads templates from a list of paths, which may be files, glob
+// patterns or directories that are walked for .yaml and .yml files, and
+// registers each of them.
+func InitTemplates(paths ...string) error {
+	paths, err := ifilepath.Globs(paths)
+	if err != nil {
+		return err
+	}
+
+	var files []string
+	for _, p := range paths {
+		if err = filepath.Walk(p, func(path string, info os.FileInfo, werr error) error {
+			if werr != nil {
+				return werr
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if path == p || strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+				files = append(files, path)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range files {
+		t, err := ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template '%v': %v", path, err)
+		}
+		if err = t.Register(); err != nil {
+			return fmt.Errorf("failed to register template '%v': %v", path, err)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,116 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplateExpand(t *testing.T) {
+	var conf Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+name: kafka_archive
+type: output
+fields:
+  - name: topic
+    type: string
+  - name: max_in_flight
+    type: int
+    default: 10
+mapping: |
+  root.kafka.topic = this.topic
+  root.kafka.max_in_flight = this.max_in_flight
+`), &conf))
+
+	tmpl, err := New(conf)
+	require.NoError(t, err)
+
+	res, err := tmpl.Expand(map[string]interface{}{
+		"topic": "foo",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "kafka:\n    max_in_flight: 10\n    topic: foo\n", string(res))
+
+	_, err = tmpl.Expand(map[string]interface{}{})
+	assert.EqualError(t, err, "template 'kafka_archive': field 'topic' is required")
+
+	_, err = tmpl.Expand(map[string]interface{}{
+		"topic": "foo",
+		"nope":  "bar",
+	})
+	assert.EqualError(t, err, "template 'kafka_archive': fields not recognised: nope")
+
+	_, err = tmpl.Expand(map[string]interface{}{
+		"topic":         "foo",
+		"max_in_flight": 1.5,
+	})
+	assert.EqualError(t, err, "template 'kafka_archive': field 'max_in_flight': expected int value, got float64")
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := map[string]struct {
+		conf Config
+		err  string
+	}{
+		"no name": {
+			conf: Config{Type: "input", Mapping: "root = this"},
+			err:  "a template name must be specified",
+		},
+		"bad type": {
+			conf: Config{Name: "foo", Type: "cache", Mapping: "root = this"},
+			err:  "template type 'cache' not recognised, expected input, processor or output",
+		},
+		"bad field type": {
+			conf: Config{Name: "foo", Type: "input", Fields: []FieldConfig{{Name: "bar", Type: "nope"}}},
+			err:  "field 'bar': type 'nope' not recognised",
+		},
+		"duplicate field": {
+			conf: Config{Name: "foo", Type: "input", Fields: []FieldConfig{{Name: "bar"}, {Name: "bar"}}},
+			err:  "field 'bar' is specified more than once",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := New(test.conf)
+		assert.EqualError(t, err, test.err, name)
+	}
+
+	tmpl, err := New(Config{Name: "bloblang", Type: "processor", Mapping: "root = this"})
+	require.NoError(t, err)
+	assert.EqualError(t, tmpl.Register(), "template 'bloblang' conflicts with an existing processor")
+}
+
+func TestTemplateRegister(t *testing.T) {
+	tmpl, err := New(Config{
+		Name: "template_test_uppercase",
+		Type: "processor",
+		Fields: []FieldConfig{
+			{Name: "field", Type: "string"},
+		},
+		Mapping: `root.bloblang = "root.%v = this.%v.uppercase()".format(this.field, this.field)`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, tmpl.Register())
+	assert.Error(t, tmpl.Register())
+
+	conf := processor.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+type: template_test_uppercase
+plugin:
+  field: foo
+`), &conf))
+
+	proc, err := processor.New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"foo":"bar"}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"foo":"BAR"}`, string(msgs[0].Get(0).Get()))
+}
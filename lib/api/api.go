@@ -13,19 +13,22 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
 	"github.com/gorilla/mux"
-	yaml "gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
 
 // Config contains the configuration fields for the Benthos API.
 type Config struct {
-	Address        string `json:"address" yaml:"address"`
-	Enabled        bool   `json:"enabled" yaml:"enabled"`
-	ReadTimeout    string `json:"read_timeout" yaml:"read_timeout"`
-	RootPath       string `json:"root_path" yaml:"root_path"`
-	DebugEndpoints bool   `json:"debug_endpoints" yaml:"debug_endpoints"`
+	Address        string               `json:"address" yaml:"address"`
+	Enabled        bool                 `json:"enabled" yaml:"enabled"`
+	ReadTimeout    string               `json:"read_timeout" yaml:"read_timeout"`
+	RootPath       string               `json:"root_path" yaml:"root_path"`
+	DebugEndpoints bool                 `json:"debug_endpoints" yaml:"debug_endpoints"`
+	BasicAuth      auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	Profiling      ProfilingConfig      `json:"profiling" yaml:"profiling"`
 }
 
 // NewConfig creates a new API config with default values.
@@ -36,6 +39,8 @@ func NewConfig() Config {
 		ReadTimeout:    "5s",
 		RootPath:       "/benthos",
 		DebugEndpoints: false,
+		BasicAuth:      auth.NewBasicAuthConfig(),
+		Profiling:      NewProfilingConfig(),
 	}
 }
 
@@ -124,11 +129,11 @@ func New(
 			w.WriteHeader(http.StatusBadGateway)
 			return
 		}
-		w.Write(resBytes)
+		w.Write(secrets.RedactJSON(resBytes))
 	}
 
 	handlePrintYAMLConfig := func(w http.ResponseWriter, r *http.Request) {
-		resBytes, err := yaml.Marshal(wholeConf)
+		resBytes, err := secrets.MarshalRedactedYAML(wholeConf)
 		if err != nil {
 			w.WriteHeader(http.StatusBadGateway)
 			return
@@ -200,6 +205,12 @@ func New(
 	t.RegisterEndpoint("/version", "Returns the service version.", handleVersion)
 	t.RegisterEndpoint("/endpoints", "Returns this map of endpoints.", handleEndpoints)
 
+	// Endpoints that modify the running service are only registered when
+	// requests can be authenticated.
+	if t.conf.BasicAuth.Enabled {
+		t.registerLogLevelEndpoint(log)
+	}
+
 	// If we want to expose a JSON stats endpoint we register the endpoints.
 	if wHandlerFunc, ok := stats.(metrics.WithHandlerFunc); ok {
 		t.RegisterEndpoint(
@@ -216,6 +227,14 @@ func New(
 		opt(t)
 	}
 
+	if t.conf.Profiling.Enabled {
+		prof, err := newProfiler(t.conf.Profiling, log)
+		if err != nil {
+			return nil, err
+		}
+		go prof.loop(t.ctx)
+	}
+
 	return t, nil
 }
 
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
+)
+
+//------------------------------------------------------------------------------
+
+// RegisterJourneysEndpoint registers an endpoint for sampling the journeys of
+// messages through processors with a tap and viewing the most recent of them.
+// The endpoint is only registered when requests can be authenticated.
+func (t *Type) RegisterJourneysEndpoint(tap *journey.Tap) {
+	if !t.conf.BasicAuth.Enabled {
+		return
+	}
+
+	handleJourneys := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+		case "POST":
+			rate, err := strconv.ParseInt(r.FormValue("rate"), 10, 64)
+			if err != nil || rate < 0 {
+				http.Error(w, fmt.Sprintf("Error: rate must be a non-negative integer: %v", r.FormValue("rate")), http.StatusBadRequest)
+				return
+			}
+			tap.SetRate(rate)
+		default:
+			http.Error(w, "Error: method not supported", http.StatusBadRequest)
+			return
+		}
+
+		resBytes, err := json.Marshal(struct {
+			Rate     int64             `json:"rate"`
+			Journeys []journey.Journey `json:"journeys"`
+		}{
+			Rate:     tap.Rate(),
+			Journeys: tap.Recent(),
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+
+	t.RegisterEndpoint(
+		"/debug/journeys",
+		"GET: Returns the most recently sampled message journeys through"+
+			" processors. POST: Set the number of messages sampled per second"+
+			" with the parameter rate, where zero disables sampling. Requires"+
+			" basic authentication.",
+		t.basicAuth(handleJourneys),
+	)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// basicAuth wraps a handler so that requests are rejected unless they provide
+// the configured basic authentication credentials.
+func (t *Type) basicAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(t.conf.BasicAuth.Username)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass), []byte(t.conf.BasicAuth.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="benthos"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// registerLogLevelEndpoint registers an endpoint for changing the level of
+// logs at runtime if the logger supports it.
+func (t *Type) registerLogLevelEndpoint(l log.Modular) {
+	ls, ok := l.(log.LevelSetter)
+	if !ok {
+		return
+	}
+
+	handleLogLevel := func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+		case "POST":
+			if err := ls.SetLevel(r.FormValue("component"), r.FormValue("level")); err != nil {
+				http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "Error: method not supported", http.StatusBadRequest)
+			return
+		}
+
+		resBytes, err := json.Marshal(ls.LevelOverrides())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	}
+
+	t.RegisterEndpoint(
+		"/log/level",
+		"GET: Returns the log level overrides that are currently active."+
+			" POST: Override the log level of all components, or of a single"+
+			" component with the parameter component, where an empty level"+
+			" removes the override. Requires basic authentication.",
+		t.basicAuth(handleLogLevel),
+	)
+}
+
+//------------------------------------------------------------------------------
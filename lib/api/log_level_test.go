@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	logConf := log.NewConfig()
+	logConf.AddTimeStamp = false
+	logConf.Format = "logfmt"
+	logConf.StaticFields = nil
+
+	var buf bytes.Buffer
+	logger, err := log.NewV2(&buf, logConf)
+	require.NoError(t, err)
+
+	conf := NewConfig()
+	conf.BasicAuth.Enabled = true
+	conf.BasicAuth.Username = "foo"
+	conf.BasicAuth.Password = "bar"
+
+	s, err := New("", "", conf, nil, logger, metrics.Noop())
+	require.NoError(t, err)
+
+	do := func(method, target string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if auth {
+			req.SetBasicAuth("foo", "bar")
+		}
+		res := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(res, req)
+		return res
+	}
+
+	res := do("POST", "/log/level?level=DEBUG", false)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	outputLogger := logger.NewModule(".output")
+	outputLogger.Debugln("not logged")
+
+	res = do("POST", "/log/level?component=benthos.output&level=debug", true)
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	assert.Equal(t, `{"benthos.output":"DEBUG"}`, res.Body.String())
+
+	outputLogger.Debugln("logged")
+	logger.NewModule(".input").Debugln("not logged")
+
+	res = do("POST", "/log/level?level=nope", true)
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do("POST", "/log/level?component=benthos.output", true)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, `{}`, res.Body.String())
+
+	outputLogger.Debugln("not logged")
+
+	assert.Equal(t, "level=DEBUG component=benthos.output msg=\"logged\"\n", buf.String())
+	assert.False(t, strings.Contains(buf.String(), "not logged"))
+}
+
+func TestLogLevelEndpointRequiresAuth(t *testing.T) {
+	s, err := New("", "", NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	res := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(res, httptest.NewRequest("POST", "/log/level?level=DEBUG", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
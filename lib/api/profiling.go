@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// ProfilingConfig contains fields for periodically capturing profiles of the
+// service and pushing them to a profiling backend.
+type ProfilingConfig struct {
+	Enabled     bool              `json:"enabled" yaml:"enabled"`
+	URL         string            `json:"url" yaml:"url"`
+	AppName     string            `json:"app_name" yaml:"app_name"`
+	Period      string            `json:"period" yaml:"period"`
+	CPUDuration string            `json:"cpu_duration" yaml:"cpu_duration"`
+	Profiles    []string          `json:"profiles" yaml:"profiles"`
+	Labels      map[string]string `json:"labels" yaml:"labels"`
+}
+
+// NewProfilingConfig creates a new profiling config with default values.
+func NewProfilingConfig() ProfilingConfig {
+	return ProfilingConfig{
+		Enabled:     false,
+		URL:         "",
+		AppName:     "benthos",
+		Period:      "1m",
+		CPUDuration: "10s",
+		Profiles:    []string{"cpu", "heap"},
+		Labels:      map[string]string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// profiler periodically captures pprof profiles and pushes them to an endpoint
+// compatible with the Pyroscope ingestion API.
+type profiler struct {
+	conf        ProfilingConfig
+	period      time.Duration
+	cpuDuration time.Duration
+
+	log    log.Modular
+	client *http.Client
+}
+
+func newProfiler(conf ProfilingConfig, log log.Modular) (*profiler, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("a url must be specified when profiling is enabled")
+	}
+	p := &profiler{
+		conf:   conf,
+		log:    log,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	var err error
+	if p.period, err = time.ParseDuration(conf.Period); err != nil {
+		return nil, fmt.Errorf("failed to parse profiling period: %v", err)
+	}
+	if p.cpuDuration, err = time.ParseDuration(conf.CPUDuration); err != nil {
+		return nil, fmt.Errorf("failed to parse profiling cpu duration: %v", err)
+	}
+	if p.cpuDuration > p.period {
+		return nil, fmt.Errorf("profiling cpu duration %v must not exceed the period %v", p.cpuDuration, p.period)
+	}
+	for _, t := range conf.Profiles {
+		if t != "cpu" && pprof.Lookup(t) == nil {
+			return nil, fmt.Errorf("profile type not recognised: %v", t)
+		}
+	}
+	return p, nil
+}
+
+// loop captures and pushes profiles every period until the context is
+// cancelled.
+func (p *profiler) loop(ctx context.Context) {
+	ticker := time.NewTicker(p.period)
+	defer ticker.Stop()
+	for {
+		for _, t := range p.conf.Profiles {
+			if err := p.capture(ctx, t); err != nil {
+				p.log.Warnf("Failed to export %v profile: %v\n", t, err)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// capture records a profile of a given type and pushes it.
+func (p *profiler) capture(ctx context.Context, profileType string) error {
+	var buf bytes.Buffer
+	from := time.Now()
+	if profileType == "cpu" {
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(p.cpuDuration):
+		case <-ctx.Done():
+		}
+		pprof.StopCPUProfile()
+	} else if err := pprof.Lookup(profileType).WriteTo(&buf, 0); err != nil {
+		return err
+	}
+	return p.push(profileType, from, time.Now(), &buf)
+}
+
+// appName returns the name of an application in the format expected by the
+// Pyroscope ingestion API, e.g. `benthos.cpu{env=prod}`.
+func (p *profiler) appName(profileType string) string {
+	name := p.conf.AppName + "." + profileType
+	if len(p.conf.Labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(p.conf.Labels))
+	for k := range p.conf.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labels := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labels = append(labels, k+"="+p.conf.Labels[k])
+	}
+	return name + "{" + strings.Join(labels, ",") + "}"
+}
+
+func (p *profiler) push(profileType string, from, until time.Time, body *bytes.Buffer) error {
+	query := url.Values{}
+	query.Set("name", p.appName(profileType))
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	query.Set("format", "pprof")
+	query.Set("spyName", "gospy")
+
+	req, err := http.NewRequest("POST", strings.TrimSuffix(p.conf.URL, "/")+"/ingest?"+query.Encode(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status: %v", res.Status)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
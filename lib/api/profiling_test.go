@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfilingConfigErrors(t *testing.T) {
+	tests := map[string]func(c *ProfilingConfig){
+		"no url": func(c *ProfilingConfig) {
+			c.URL = ""
+		},
+		"bad period": func(c *ProfilingConfig) {
+			c.Period = "nope"
+		},
+		"cpu duration exceeds period": func(c *ProfilingConfig) {
+			c.Period = "1s"
+			c.CPUDuration = "2s"
+		},
+		"bad profile": func(c *ProfilingConfig) {
+			c.Profiles = []string{"nope"}
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Profiling.Enabled = true
+		conf.Profiling.URL = "http://localhost:4040"
+		fn(&conf.Profiling)
+
+		_, err := New("", "", conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestProfilingPush(t *testing.T) {
+	type pushed struct {
+		query url.Values
+		body  []byte
+	}
+	pushChan := make(chan pushed, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/ingest", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		pushChan <- pushed{query: r.URL.Query(), body: body}
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Profiling.Enabled = true
+	conf.Profiling.URL = server.URL
+	conf.Profiling.Profiles = []string{"heap"}
+	conf.Profiling.Labels = map[string]string{
+		"pod": "foo",
+		"env": "prod",
+	}
+
+	s, err := New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer s.Shutdown(context.Background())
+
+	select {
+	case p := <-pushChan:
+		assert.Equal(t, "benthos.heap{env=prod,pod=foo}", p.query.Get("name"))
+		assert.Equal(t, "pprof", p.query.Get("format"))
+		assert.NotEmpty(t, p.query.Get("from"))
+		assert.NotEmpty(t, p.query.Get("until"))
+		assert.NotEmpty(t, p.body)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for profile")
+	}
+}
@@ -0,0 +1,268 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Failover is a broker that implements types.Consumer and sends each message
+// to a single active output, which is initially the first output. When the
+// active output fails to send a message the subsequent outputs are attempted
+// in order, and the first that succeeds becomes the new active output.
+//
+// Whilst the active output is not the first output (the primary), the primary
+// is periodically probed by attempting to send a message to it first, and if
+// this succeeds it becomes the active output again.
+type Failover struct {
+	log           log.Modular
+	stats         metrics.Type
+	outputsPrefix string
+
+	maxInFlight   int
+	probeInterval time.Duration
+	transactions  <-chan types.Transaction
+
+	outputTsChans []chan types.Transaction
+	outputs       []types.Output
+
+	activeMut sync.Mutex
+	active    int
+	lastProbe time.Time
+
+	mActive   metrics.StatGauge
+	mSwitches metrics.StatCounter
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+// NewFailover creates a new Failover type by providing consumers.
+func NewFailover(outputs []types.Output, log log.Modular, stats metrics.Type) (*Failover, error) {
+	ctx, done := context.WithCancel(context.Background())
+	f := &Failover{
+		log:           log,
+		stats:         stats,
+		outputsPrefix: "broker.outputs",
+		maxInFlight:   1,
+		probeInterval: time.Second * 30,
+		transactions:  nil,
+		outputs:       outputs,
+		mActive:       stats.GetGauge("failover.active"),
+		mSwitches:     stats.GetCounter("failover.switches"),
+		closedChan:    make(chan struct{}),
+		ctx:           ctx,
+		close:         done,
+	}
+	if len(outputs) == 0 {
+		return nil, errors.New("missing outputs")
+	}
+	f.outputTsChans = make([]chan types.Transaction, len(f.outputs))
+	for i := range f.outputTsChans {
+		f.outputTsChans[i] = make(chan types.Transaction)
+		if err := f.outputs[i].Consume(f.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+	f.mActive.Set(0)
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+// WithMaxInFlight sets the maximum number of in-flight messages this broker
+// supports. This must be set before calling Consume.
+func (f *Failover) WithMaxInFlight(i int) *Failover {
+	if i < 1 {
+		i = 1
+	}
+	f.maxInFlight = i
+	return f
+}
+
+// WithProbeInterval sets the period to wait between attempts to send messages
+// to the primary output whilst it is not active. This must be set before
+// calling Consume.
+func (f *Failover) WithProbeInterval(d time.Duration) *Failover {
+	f.probeInterval = d
+	return f
+}
+
+// WithOutputMetricsPrefix changes the prefix used for counter metrics showing
+// errors of an output.
+func (f *Failover) WithOutputMetricsPrefix(prefix string) *Failover {
+	f.outputsPrefix = prefix
+	return f
+}
+
+// Consume assigns a new messages channel for the broker to read.
+func (f *Failover) Consume(ts <-chan types.Transaction) error {
+	if f.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	f.transactions = ts
+
+	go f.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (f *Failover) Connected() bool {
+	f.activeMut.Lock()
+	active := f.active
+	f.activeMut.Unlock()
+	return f.outputs[active].Connected()
+}
+
+// Active returns the index of the output that messages are currently sent to.
+func (f *Failover) Active() int {
+	f.activeMut.Lock()
+	defer f.activeMut.Unlock()
+	return f.active
+}
+
+//------------------------------------------------------------------------------
+
+// firstOutput returns the index of the output that the next message should be
+// attempted with.
+func (f *Failover) firstOutput() int {
+	f.activeMut.Lock()
+	defer f.activeMut.Unlock()
+
+	if f.active != 0 && time.Since(f.lastProbe) >= f.probeInterval {
+		f.lastProbe = time.Now()
+		return 0
+	}
+	return f.active
+}
+
+// succeeded marks an output as having successfully sent a message, which
+// makes it the active output. Outputs are attempted in order of priority from
+// the first output, therefore any other output that succeeds is either of a
+// higher priority than the active output or was attempted after the active
+// output failed.
+func (f *Failover) succeeded(index int) {
+	f.activeMut.Lock()
+	defer f.activeMut.Unlock()
+
+	if index == f.active {
+		return
+	}
+
+	if index == 0 {
+		f.log.Infof("Primary output has recovered, switching back from output '%v'\n", f.active)
+	} else {
+		f.log.Warnf("Switching from output '%v' to output '%v'\n", f.active, index)
+	}
+	f.active = index
+	f.lastProbe = time.Now()
+	f.mActive.Set(int64(index))
+	f.mSwitches.Incr(1)
+}
+
+// loop is an internal loop that brokers incoming messages to many outputs.
+func (f *Failover) loop() {
+	var (
+		wg        = sync.WaitGroup{}
+		mMsgsRcvd = f.stats.GetCounter("count")
+		mErrs     = []metrics.StatCounter{}
+	)
+
+	defer func() {
+		wg.Wait()
+		for _, c := range f.outputTsChans {
+			close(c)
+		}
+		close(f.closedChan)
+	}()
+
+	for i := range f.outputs {
+		mErrs = append(mErrs, f.stats.GetCounter(fmt.Sprintf("%v.%v.failed", f.outputsPrefix, i)))
+	}
+
+	sendLoop := func() {
+		defer wg.Done()
+		for {
+			var open bool
+			var tran types.Transaction
+
+			select {
+			case tran, open = <-f.transactions:
+				if !open {
+					return
+				}
+			case <-f.ctx.Done():
+				return
+			}
+			mMsgsRcvd.Incr(1)
+
+			first := f.firstOutput()
+			rChan := make(chan types.Response)
+
+			var res types.Response
+			for attempt := 0; attempt < len(f.outputTsChans); attempt++ {
+				index := (first + attempt) % len(f.outputTsChans)
+				select {
+				case f.outputTsChans[index] <- types.NewTransaction(tran.Payload, rChan):
+				case <-f.ctx.Done():
+					return
+				}
+
+				var lOpen bool
+				select {
+				case res, lOpen = <-rChan:
+					if !lOpen {
+						return
+					}
+				case <-f.ctx.Done():
+					return
+				}
+
+				if res.Error() == nil {
+					f.succeeded(index)
+					break
+				}
+				mErrs[index].Incr(1)
+			}
+
+			select {
+			case tran.ResponseChan <- res:
+			case <-f.ctx.Done():
+				return
+			}
+		}
+	}
+
+	// Max in flight
+	for i := 0; i < f.maxInFlight; i++ {
+		wg.Add(1)
+		go sendLoop()
+	}
+}
+
+// CloseAsync shuts down the Failover broker and stops processing requests.
+func (f *Failover) CloseAsync() {
+	f.close()
+}
+
+// WaitForClose blocks until the Failover broker has closed down.
+func (f *Failover) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-f.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,149 @@
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func TestFailoverInterfaces(t *testing.T) {
+	f := &Failover{}
+	if types.Consumer(f) == nil {
+		t.Errorf("Failover: nil types.Consumer")
+	}
+	if types.Closable(f) == nil {
+		t.Errorf("Failover: nil types.Closable")
+	}
+}
+
+func TestFailoverDoubleClose(t *testing.T) {
+	oTM, err := NewFailover([]types.Output{&MockOutputType{}}, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// This shouldn't cause a panic
+	oTM.CloseAsync()
+	oTM.CloseAsync()
+}
+
+//------------------------------------------------------------------------------
+
+func TestFailoverRecovery(t *testing.T) {
+	mockOutputs := []*MockOutputType{{}, {}, {}}
+	outputs := []types.Output{}
+	for _, o := range mockOutputs {
+		outputs = append(outputs, o)
+	}
+
+	readChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+
+	oTM, err := NewFailover(outputs, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oTM = oTM.WithProbeInterval(time.Millisecond * 50)
+	if err = oTM.Consume(readChan); err != nil {
+		t.Fatal(err)
+	}
+
+	failing := make([]int32, len(mockOutputs))
+
+	var receivedMut sync.Mutex
+	var received []string
+
+	for i, o := range mockOutputs {
+		go func(i int, tChan <-chan types.Transaction) {
+			for ts := range tChan {
+				receivedMut.Lock()
+				received = append(received, fmt.Sprintf("%v:%s", i, ts.Payload.Get(0).Get()))
+				receivedMut.Unlock()
+
+				var res types.Response = response.NewAck()
+				if atomic.LoadInt32(&failing[i]) == 1 {
+					res = response.NewError(errors.New("nope"))
+				}
+				ts.ResponseChan <- res
+			}
+		}(i, o.TChan)
+	}
+
+	send := func(content string) {
+		t.Helper()
+		select {
+		case readChan <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker send")
+		}
+		select {
+		case res := <-resChan:
+			if res.Error() != nil {
+				t.Fatal(res.Error())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for broker response")
+		}
+	}
+
+	send("first")
+	if exp, act := 0, oTM.Active(); exp != act {
+		t.Errorf("Wrong active output: %v != %v", act, exp)
+	}
+
+	atomic.StoreInt32(&failing[0], 1)
+	atomic.StoreInt32(&failing[1], 1)
+	send("second")
+	send("third")
+	if exp, act := 2, oTM.Active(); exp != act {
+		t.Errorf("Wrong active output: %v != %v", act, exp)
+	}
+
+	<-time.After(time.Millisecond * 60)
+	send("fourth")
+	if exp, act := 2, oTM.Active(); exp != act {
+		t.Errorf("Wrong active output: %v != %v", act, exp)
+	}
+
+	atomic.StoreInt32(&failing[0], 0)
+	send("fifth")
+	<-time.After(time.Millisecond * 60)
+	send("sixth")
+	send("seventh")
+	if exp, act := 0, oTM.Active(); exp != act {
+		t.Errorf("Wrong active output: %v != %v", act, exp)
+	}
+
+	receivedMut.Lock()
+	exp := []string{
+		"0:first",
+		"0:second", "1:second", "2:second",
+		"2:third",
+		"0:fourth", "1:fourth", "2:fourth",
+		"2:fifth",
+		"0:sixth",
+		"0:seventh",
+	}
+	if act := received; fmt.Sprintf("%v", exp) != fmt.Sprintf("%v", act) {
+		t.Errorf("Wrong sends: %v != %v", act, exp)
+	}
+	receivedMut.Unlock()
+
+	oTM.CloseAsync()
+	if err := oTM.WaitForClose(time.Second * 10); err != nil {
+		t.Error(err)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -1,6 +1,7 @@
 package broker
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -89,6 +90,21 @@ func (i *FanIn) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each input of the
+// broker.
+func (i *FanIn) ConnectionStatuses() []types.ConnectionStatus {
+	type connector interface {
+		Connected() bool
+	}
+	var statuses []types.ConnectionStatus
+	for n, in := range i.closables {
+		if c, ok := in.(connector); ok {
+			statuses = append(statuses, types.ConnectionStatusesOf(fmt.Sprintf("broker.inputs.%v", n), c)...)
+		}
+	}
+	return statuses
+}
+
 //------------------------------------------------------------------------------
 
 // loop is an internal loop that brokers incoming messages to many outputs.
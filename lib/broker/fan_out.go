@@ -92,6 +92,12 @@ func (o *FanOut) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each output of the
+// broker.
+func (o *FanOut) ConnectionStatuses() []types.ConnectionStatus {
+	return outputConnectionStatuses(o.outputs)
+}
+
 //------------------------------------------------------------------------------
 
 // loop is an internal loop that brokers incoming messages to many outputs.
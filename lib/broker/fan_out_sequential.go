@@ -93,6 +93,12 @@ func (o *FanOutSequential) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each output of the
+// broker.
+func (o *FanOutSequential) ConnectionStatuses() []types.ConnectionStatus {
+	return outputConnectionStatuses(o.outputs)
+}
+
 //------------------------------------------------------------------------------
 
 // loop is an internal loop that brokers incoming messages to many outputs.
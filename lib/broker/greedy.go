@@ -45,6 +45,12 @@ func (g *Greedy) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each output of the
+// broker.
+func (g *Greedy) ConnectionStatuses() []types.ConnectionStatus {
+	return outputConnectionStatuses(g.outputs)
+}
+
 //------------------------------------------------------------------------------
 
 // CloseAsync shuts down the Greedy broker and stops processing requests.
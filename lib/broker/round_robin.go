@@ -71,6 +71,12 @@ func (o *RoundRobin) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each output of the
+// broker.
+func (o *RoundRobin) ConnectionStatuses() []types.ConnectionStatus {
+	return outputConnectionStatuses(o.outputs)
+}
+
 //------------------------------------------------------------------------------
 
 // loop is an internal loop that brokers incoming messages to many outputs.
@@ -0,0 +1,21 @@
+package broker
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// outputConnectionStatuses returns the connection statuses of a slice of
+// brokered outputs, where each is namespaced by its index.
+func outputConnectionStatuses(outputs []types.Output) []types.ConnectionStatus {
+	var statuses []types.ConnectionStatus
+	for i, out := range outputs {
+		statuses = append(statuses, types.ConnectionStatusesOf(fmt.Sprintf("broker.outputs.%v", i), out)...)
+	}
+	return statuses
+}
+
+//------------------------------------------------------------------------------
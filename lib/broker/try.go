@@ -98,6 +98,12 @@ func (t *Try) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each output of the
+// broker.
+func (t *Try) ConnectionStatuses() []types.ConnectionStatus {
+	return outputConnectionStatuses(t.outputs)
+}
+
 //------------------------------------------------------------------------------
 
 // loop is an internal loop that brokers incoming messages to many outputs.
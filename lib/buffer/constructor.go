@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer/single"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -36,25 +37,39 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each buffer type.
 const (
+	TypeDisk   = "disk"
+	TypeKafka  = "kafka"
 	TypeMemory = "memory"
 	TypeNone   = "none"
+	TypeSQLite = "sqlite"
+	TypeWindow = "window"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all buffer types.
 type Config struct {
-	Type   string       `json:"type" yaml:"type"`
-	Memory MemoryConfig `json:"memory" yaml:"memory"`
-	None   struct{}     `json:"none" yaml:"none"`
+	Type   string              `json:"type" yaml:"type"`
+	Disk   single.DiskConfig   `json:"disk" yaml:"disk"`
+	Kafka  KafkaConfig         `json:"kafka" yaml:"kafka"`
+	Memory MemoryConfig        `json:"memory" yaml:"memory"`
+	None   struct{}            `json:"none" yaml:"none"`
+	Plugin interface{}         `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	SQLite single.SQLiteConfig `json:"sqlite" yaml:"sqlite"`
+	Window WindowConfig        `json:"window" yaml:"window"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
 		Type:   "none",
+		Disk:   single.NewDiskConfig(),
+		Kafka:  NewKafkaConfig(),
 		Memory: NewMemoryConfig(),
 		None:   struct{}{},
+		Plugin: nil,
+		SQLite: single.NewSQLiteConfig(),
+		Window: NewWindowConfig(),
 	}
 }
 
@@ -114,8 +129,24 @@ func (conf *Config) UnmarshalYAML(value *yaml.Node) error {
 		}
 		aliased.Type = inferredType
 	}
-	if _, exists := Constructors[aliased.Type]; !exists {
-		return fmt.Errorf("line %v: type '%v' was not recognised", value.Line, aliased.Type)
+	if spec, exists := pluginSpecs[aliased.Type]; exists && spec.confConstructor != nil {
+		confBytes, err := yaml.Marshal(aliased.Plugin)
+		if err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+
+		conf := spec.confConstructor()
+		if err = yaml.Unmarshal(confBytes, conf); err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+		aliased.Plugin = conf
+	} else {
+		if !exists {
+			if _, exists = Constructors[aliased.Type]; !exists {
+				return fmt.Errorf("line %v: type '%v' was not recognised", value.Line, aliased.Type)
+			}
+		}
+		aliased.Plugin = nil
 	}
 
 	*conf = Config(aliased)
@@ -155,13 +186,21 @@ different options and their qualities:
 
 | Type      | Throughput | Consumers | Capacity |
 | --------- | ---------- | --------- | -------- |
+| Disk      | Low        | Single    | Disk     |
+| Kafka     | High       | Single    | Kafka    |
 | Memory    | Highest    | Parallel  | RAM      |
+| SQLite    | Low        | Single    | Disk     |
+| Window    | High       | Single    | RAM      |
 
 #### Delivery Guarantees
 
 | Event     | Shutdown  | Crash     | Disk Corruption |
 | --------- | --------- | --------- | --------------- |
+| Disk      | Persisted | Persisted | Lost            |
+| Kafka     | Persisted | Persisted | Persisted       |
 | Memory    | Flushed\* | Lost      | Lost            |
+| SQLite    | Persisted | Persisted | Lost            |
+| Window    | Flushed\* | Lost      | Lost            |
 
 \* Makes a best attempt at flushing the remaining messages before closing
   gracefully.`
@@ -220,6 +259,9 @@ func New(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (T
 	if c, ok := Constructors[conf.Type]; ok {
 		return c.constructor(conf, mgr, log, stats)
 	}
+	if c, ok := pluginSpecs[conf.Type]; ok {
+		return c.constructor(conf, mgr, log, stats)
+	}
 	return nil, types.ErrInvalidBufferType
 }
 
@@ -91,7 +91,8 @@ func TestSanitise(t *testing.T) {
 		`"type":"memory",` +
 		`"memory":{` +
 		`"batch_policy":{"byte_size":0,"check":"","count":0,"enabled":false,"period":"","processors":[]},` +
-		`"limit":20` +
+		`"limit":20,` +
+		`"watermarks":{"high":0,"low":0,"pause":false}` +
 		`}` +
 		`}`
 
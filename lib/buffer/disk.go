@@ -0,0 +1,65 @@
+package buffer
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer/single"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDisk] = TypeSpec{
+		constructor: NewDisk,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Stores consumed messages on disk and acknowledges them at the input level once
+they are persisted. Messages that were not delivered before a crash or shutdown
+are delivered once Benthos is restarted.`,
+		Description: `
+Messages are written to an embedded database at the configured ` + "`path`" + `
+and are only removed once they have been successfully sent by the output. This
+means that messages are delivered at least once even when Benthos crashes, but
+messages that were in flight during a crash might be delivered again once the
+buffer is reopened. Messages are delivered in the order in which they were
+consumed, including the metadata of each message.
+
+The buffer file is locked whilst open, and therefore the same path cannot be
+shared by multiple buffers or instances of Benthos.
+
+### Full Policy
+
+The field ` + "`limit`" + ` caps the total size in bytes of messages stored in
+the buffer, and ` + "`full_policy`" + ` determines what happens when a
+consumed message would exceed it:
+
+- ` + "`block`" + ` applies back pressure upstream until space is freed by
+  messages being sent.
+- ` + "`drop_newest`" + ` acknowledges and discards the consumed message.
+- ` + "`drop_oldest`" + ` discards the oldest messages of the buffer until the
+  consumed message fits.
+
+Discarded messages are counted by the metric ` + "`dropped`" + `.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The path of the file to store buffered messages in."),
+			docs.FieldCommon("limit", "The maximum buffer size (in bytes) of stored messages."),
+			docs.FieldCommon("full_policy", "What to do with consumed messages when the buffer is full, see [full policy](#full-policy) for more information.").HasOptions("block", "drop_newest", "drop_oldest"),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewDisk creates a buffer that persists messages to disk.
+func NewDisk(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	d, err := single.NewDisk(config.Disk, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleWrapper(config, d, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
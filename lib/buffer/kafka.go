@@ -0,0 +1,430 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/kafka/sasl"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/Shopify/sarama"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeKafka] = TypeSpec{
+		constructor: NewKafka,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Writes consumed messages to a Kafka topic and acknowledges them at the input
+level once they are stored, then consumes them back from the topic.`,
+		Description: `
+This buffer decouples the rate of inputs from the rest of the pipeline with a
+capacity limited only by the retention of the topic. Messages are consumed from
+the topic as a member of the configured consumer group, and offsets are only
+marked once messages have been successfully sent by the output. Therefore when
+Benthos is restarted, or crashes, it continues from the backlog of messages that
+were not yet delivered, although messages that were in flight during a crash
+might be delivered again.
+
+Each message of a batch is written as an individual record with its metadata
+stored as record headers, which requires a ` + "`target_version`" + ` of at
+least 0.11.0. Messages are read back individually and are delivered in order
+for each partition of the topic, a topic with a single partition is therefore
+required in order to preserve the order of all messages.
+
+The topic should be dedicated to the buffer, and when the consumer group has no
+committed offsets the topic is consumed from the oldest available offset.
+
+### Metrics
+
+The gauge ` + "`lag`" + ` tracks the number of messages remaining in the
+partition of each message read from the topic.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"addresses", "A list of broker addresses to connect to. If an item of the list contains commas it will be expanded into multiple addresses.",
+				[]string{"localhost:9092"}, []string{"localhost:9041,localhost:9042"}, []string{"localhost:9041", "localhost:9042"},
+			),
+			docs.FieldCommon("topic", "The topic to store buffered messages in."),
+			btls.FieldSpec(),
+			sasl.FieldSpec(),
+			docs.FieldCommon("consumer_group", "An identifier for the consumer group that reads messages back from the topic."),
+			docs.FieldAdvanced("client_id", "An identifier for the client connection."),
+			docs.FieldAdvanced("commit_period", "The period of time between each commit of the current partition offsets. Offsets are always committed during shutdown."),
+			docs.FieldAdvanced("target_version", "The version of the Kafka protocol to use, which must be at least 0.11.0."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// KafkaConfig contains configuration fields for the kafka buffer.
+type KafkaConfig struct {
+	Addresses     []string    `json:"addresses" yaml:"addresses"`
+	Topic         string      `json:"topic" yaml:"topic"`
+	TLS           btls.Config `json:"tls" yaml:"tls"`
+	SASL          sasl.Config `json:"sasl" yaml:"sasl"`
+	ConsumerGroup string      `json:"consumer_group" yaml:"consumer_group"`
+	ClientID      string      `json:"client_id" yaml:"client_id"`
+	CommitPeriod  string      `json:"commit_period" yaml:"commit_period"`
+	TargetVersion string      `json:"target_version" yaml:"target_version"`
+}
+
+// NewKafkaConfig creates a new KafkaConfig with default values.
+func NewKafkaConfig() KafkaConfig {
+	return KafkaConfig{
+		Addresses:     []string{"localhost:9092"},
+		Topic:         "benthos_buffer",
+		TLS:           btls.NewConfig(),
+		SASL:          sasl.NewConfig(),
+		ConsumerGroup: "benthos_buffer",
+		ClientID:      "benthos_buffer",
+		CommitPeriod:  "1s",
+		TargetVersion: sarama.V1_0_0_0.String(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewKafka creates a buffer that stores messages in a Kafka topic.
+func NewKafka(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	k, err := newKafkaBuffer(config.Kafka, mgr, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleWrapper(config, k, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
+
+type kafkaRecord struct {
+	sess          sarama.ConsumerGroupSession
+	msg           *sarama.ConsumerMessage
+	highWaterMark int64
+}
+
+// kafkaBuffer is a Single buffer that produces messages to a topic and consumes
+// them back as a member of a consumer group. NextMessage and ShiftMessage must
+// be called from a single goroutine.
+type kafkaBuffer struct {
+	topic string
+
+	producerMut sync.RWMutex
+	producer    sarama.SyncProducer
+	group       sarama.ConsumerGroup
+
+	records chan kafkaRecord
+	pending *kafkaRecord
+
+	// The next offsets of each partition that have been produced and consumed
+	// by this buffer, used in order to determine whether the buffer is empty.
+	cond     *sync.Cond
+	produced map[int32]int64
+	consumed map[int32]int64
+	closed   bool
+
+	log  log.Modular
+	mLag metrics.StatGauge
+
+	closeFn   context.CancelFunc
+	closeChan chan struct{}
+	groupDone chan struct{}
+}
+
+func newKafkaBuffer(conf KafkaConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*kafkaBuffer, error) {
+	var addresses []string
+	for _, addr := range conf.Addresses {
+		for _, splitAddr := range strings.Split(addr, ",") {
+			if trimmed := strings.TrimSpace(splitAddr); len(trimmed) > 0 {
+				addresses = append(addresses, trimmed)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+	if conf.Topic == "" {
+		return nil, errors.New("a topic must be specified")
+	}
+	if conf.ConsumerGroup == "" {
+		return nil, errors.New("a consumer group must be specified")
+	}
+
+	version, err := sarama.ParseKafkaVersion(conf.TargetVersion)
+	if err != nil {
+		return nil, err
+	}
+	if !version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, errors.New("target version must be at least 0.11.0 in order to support record headers")
+	}
+
+	commitPeriod, err := time.ParseDuration(conf.CommitPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse commit period string: %v", err)
+	}
+
+	config := sarama.NewConfig()
+	config.ClientID = conf.ClientID
+	config.Version = version
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Return.Errors = true
+	config.Producer.Return.Successes = true
+	config.Consumer.Return.Errors = true
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Offsets.AutoCommit.Enable = true
+	config.Consumer.Offsets.AutoCommit.Interval = commitPeriod
+	config.Net.TLS.Enable = conf.TLS.Enabled
+	if conf.TLS.Enabled {
+		if config.Net.TLS.Config, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	if err = conf.SASL.Apply(mgr, config); err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducer(addresses, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %v", err)
+	}
+	group, err := sarama.NewConsumerGroup(addresses, conf.ConsumerGroup, config)
+	if err != nil {
+		producer.Close()
+		return nil, fmt.Errorf("failed to create consumer group: %v", err)
+	}
+
+	ctx, closeFn := context.WithCancel(context.Background())
+	k := &kafkaBuffer{
+		topic:     conf.Topic,
+		producer:  producer,
+		group:     group,
+		records:   make(chan kafkaRecord),
+		cond:      sync.NewCond(&sync.Mutex{}),
+		produced:  map[int32]int64{},
+		consumed:  map[int32]int64{},
+		log:       log,
+		mLag:      stats.GetGauge("lag"),
+		closeFn:   closeFn,
+		closeChan: make(chan struct{}),
+		groupDone: make(chan struct{}),
+	}
+
+	go func() {
+		for gerr := range group.Errors() {
+			k.log.Errorf("Kafka group message recv error: %v\n", gerr)
+		}
+	}()
+	go k.consumeLoop(ctx)
+
+	k.log.Infof("Buffering messages in kafka topic '%v' from brokers %s as group '%v'\n", conf.Topic, addresses, conf.ConsumerGroup)
+	return k, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (k *kafkaBuffer) consumeLoop(ctx context.Context) {
+	defer func() {
+		if err := k.group.Close(); err != nil {
+			k.log.Errorf("Failed to close consumer group: %v\n", err)
+		}
+		close(k.groupDone)
+	}()
+	for {
+		if err := k.group.Consume(ctx, []string{k.topic}, k); err != nil && ctx.Err() == nil {
+			k.log.Errorf("Kafka group session error: %v\n", err)
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim.
+func (k *kafkaBuffer) Setup(sesh sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have
+// exited but before the offsets are committed for the very last time.
+func (k *kafkaBuffer) Cleanup(sesh sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
+// Once the Messages() channel is closed, the Handler must finish its processing
+// loop and exit.
+func (k *kafkaBuffer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case data, open := <-claim.Messages():
+			if !open {
+				return nil
+			}
+			select {
+			case k.records <- kafkaRecord{
+				sess:          sess,
+				msg:           data,
+				highWaterMark: claim.HighWaterMarkOffset(),
+			}:
+			case <-sess.Context().Done():
+				return nil
+			}
+		case <-sess.Context().Done():
+			return nil
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// caughtUp returns whether all messages produced by this buffer have been
+// consumed, the buffer must be locked.
+func (k *kafkaBuffer) caughtUp() bool {
+	for partition, offset := range k.produced {
+		if k.consumed[partition] < offset {
+			return false
+		}
+	}
+	return true
+}
+
+// ShiftMessage marks the offset of the last message read from the topic.
+func (k *kafkaBuffer) ShiftMessage() (int, error) {
+	if k.pending == nil {
+		return 0, nil
+	}
+	rec := k.pending
+	k.pending = nil
+
+	rec.sess.MarkMessage(rec.msg, "")
+
+	k.cond.L.Lock()
+	if next := rec.msg.Offset + 1; next > k.consumed[rec.msg.Partition] {
+		k.consumed[rec.msg.Partition] = next
+	}
+	k.cond.Broadcast()
+	k.cond.L.Unlock()
+	return 0, nil
+}
+
+// NextMessage reads the next message from the topic, this call blocks until
+// there's something to read.
+func (k *kafkaBuffer) NextMessage() (types.Message, error) {
+	if k.pending == nil {
+		select {
+		case rec := <-k.records:
+			k.pending = &rec
+		case <-k.closeChan:
+			return nil, types.ErrTypeClosed
+		}
+	}
+
+	data := k.pending.msg
+	lag := k.pending.highWaterMark - data.Offset - 1
+	if lag < 0 {
+		lag = 0
+	}
+	k.mLag.Set(lag)
+
+	part := message.NewPart(data.Value)
+	meta := part.Metadata()
+	for _, hdr := range data.Headers {
+		meta.Set(string(hdr.Key), string(hdr.Value))
+	}
+
+	msg := message.New(nil)
+	msg.Append(part)
+	return msg, nil
+}
+
+// PushMessage writes each message of a batch to the topic, and blocks until
+// they are acknowledged.
+func (k *kafkaBuffer) PushMessage(msg types.Message) (int, error) {
+	k.producerMut.RLock()
+	defer k.producerMut.RUnlock()
+	if k.producer == nil {
+		return 0, types.ErrTypeClosed
+	}
+
+	msgs := make([]*sarama.ProducerMessage, 0, msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		var headers []sarama.RecordHeader
+		p.Metadata().Iter(func(key, value string) error {
+			headers = append(headers, sarama.RecordHeader{
+				Key:   []byte(key),
+				Value: []byte(value),
+			})
+			return nil
+		})
+		msgs = append(msgs, &sarama.ProducerMessage{
+			Topic:   k.topic,
+			Value:   sarama.ByteEncoder(p.Get()),
+			Headers: headers,
+		})
+		return nil
+	})
+
+	if err := k.producer.SendMessages(msgs); err != nil {
+		return 0, err
+	}
+
+	k.cond.L.Lock()
+	for _, m := range msgs {
+		if next := m.Offset + 1; next > k.produced[m.Partition] {
+			k.produced[m.Partition] = next
+		}
+	}
+	k.cond.L.Unlock()
+	return 0, nil
+}
+
+// CloseOnceEmpty closes the buffer once all messages produced by it have been
+// consumed.
+func (k *kafkaBuffer) CloseOnceEmpty() {
+	k.cond.L.Lock()
+	for !k.closed && !k.caughtUp() {
+		k.cond.Wait()
+	}
+	k.cond.L.Unlock()
+	k.Close()
+}
+
+// Close unblocks any blocked calls and closes the connections of the buffer,
+// messages that remain in the topic are consumed once it is reopened.
+func (k *kafkaBuffer) Close() {
+	k.cond.L.Lock()
+	if k.closed {
+		k.cond.L.Unlock()
+		return
+	}
+	k.closed = true
+	k.cond.Broadcast()
+	k.cond.L.Unlock()
+
+	close(k.closeChan)
+	k.closeFn()
+	<-k.groupDone
+
+	k.producerMut.Lock()
+	if err := k.producer.Close(); err != nil {
+		k.log.Errorf("Failed to close producer: %v\n", err)
+	}
+	k.producer = nil
+	k.producerMut.Unlock()
+}
+
+//------------------------------------------------------------------------------
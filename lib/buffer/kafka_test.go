@@ -0,0 +1,41 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+func TestKafkaBufferBadConfig(t *testing.T) {
+	tests := map[string]func(c *KafkaConfig){
+		"no addresses": func(c *KafkaConfig) {
+			c.Addresses = []string{" , "}
+		},
+		"no topic": func(c *KafkaConfig) {
+			c.Topic = ""
+		},
+		"no consumer group": func(c *KafkaConfig) {
+			c.ConsumerGroup = ""
+		},
+		"bad target version": func(c *KafkaConfig) {
+			c.TargetVersion = "nope"
+		},
+		"target version without headers": func(c *KafkaConfig) {
+			c.TargetVersion = "0.10.2.0"
+		},
+		"bad commit period": func(c *KafkaConfig) {
+			c.CommitPeriod = "nope"
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeKafka
+		test(&conf.Kafka)
+
+		if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package buffer
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/buffer/parallel"
@@ -33,7 +35,27 @@ significantly below the amount of RAM available.
 ### Batching
 
 It is possible to batch up messages sent from this buffer using a
-[batch policy](/docs/configuration/batching#batch-policy).`,
+[batch policy](/docs/configuration/batching#batch-policy).
+
+### Watermarks
+
+A high and low watermark of the buffer size (in bytes) can be configured in
+order to observe when the buffer is filling up before the limit is reached.
+Once the size of the buffer reaches ` + "`watermarks.high`" + ` the counter
+` + "`watermark.high`" + ` is incremented and the gauge
+` + "`watermark.above`" + ` is set to 1, and once it then drains to
+` + "`watermarks.low`" + ` the counter ` + "`watermark.low`" + ` is
+incremented and the gauge is set back to 0. When ` + "`watermarks.pause`" + `
+is true consumption is stopped with back pressure upstream whilst the buffer is
+above the watermarks, which pauses inputs until the buffer has drained.
+
+### Metrics
+
+In addition to the metrics common to all buffers, the gauges
+` + "`backlog.messages`" + ` and ` + "`backlog.percentage`" + ` track the
+number of messages stored and the size of the buffer as a percentage of the
+limit, and the timer ` + "`age`" + ` tracks how long each message was stored
+before being read.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("limit", "The maximum buffer size (in bytes) to allow before applying backpressure upstream."),
 			docs.FieldCommon("batch_policy", "Optionally configure a policy to flush buffered messages in batches.").WithChildren(
@@ -41,6 +63,11 @@ It is possible to batch up messages sent from this buffer using a
 					docs.FieldCommon("enabled", "Whether to batch messages as they are flushed."),
 				}, batch.FieldSpec().Children...)...,
 			),
+			docs.FieldAdvanced("watermarks", "Optionally configure watermarks of the buffer size, see [watermarks](#watermarks) for more information.").WithChildren(
+				docs.FieldAdvanced("high", "The buffer size (in bytes) at which the buffer is above the watermarks, watermarks are disabled when set to zero."),
+				docs.FieldAdvanced("low", "The buffer size (in bytes) at which the buffer is no longer above the watermarks, this must be lower than the high watermark."),
+				docs.FieldAdvanced("pause", "Whether to stop consuming messages whilst the buffer is above the watermarks."),
+			).AtVersion("3.39.0"),
 		},
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			bSanit, err := batch.SanitisePolicyConfig(batch.PolicyConfig(conf.Memory.BatchPolicy.PolicyConfig))
@@ -53,6 +80,11 @@ It is possible to batch up messages sent from this buffer using a
 			return map[string]interface{}{
 				"limit":        conf.Memory.Limit,
 				"batch_policy": bSanit,
+				"watermarks": map[string]interface{}{
+					"high":  conf.Memory.Watermarks.High,
+					"low":   conf.Memory.Watermarks.Low,
+					"pause": conf.Memory.Watermarks.Pause,
+				},
 			}, nil
 		},
 	}
@@ -66,10 +98,19 @@ type EnabledBatchPolicyConfig struct {
 	batch.PolicyConfig `json:",inline" yaml:",inline"`
 }
 
+// MemoryWatermarksConfig contains fields for configuring high and low
+// watermarks of a memory buffer.
+type MemoryWatermarksConfig struct {
+	High  int  `json:"high" yaml:"high"`
+	Low   int  `json:"low" yaml:"low"`
+	Pause bool `json:"pause" yaml:"pause"`
+}
+
 // MemoryConfig is config values for a purely memory based ring buffer type.
 type MemoryConfig struct {
 	Limit       int                      `json:"limit" yaml:"limit"`
 	BatchPolicy EnabledBatchPolicyConfig `json:"batch_policy" yaml:"batch_policy"`
+	Watermarks  MemoryWatermarksConfig   `json:"watermarks" yaml:"watermarks"`
 }
 
 // NewMemoryConfig creates a new MemoryConfig with default values.
@@ -80,6 +121,11 @@ func NewMemoryConfig() MemoryConfig {
 			Enabled:      false,
 			PolicyConfig: batch.NewPolicyConfig(),
 		},
+		Watermarks: MemoryWatermarksConfig{
+			High:  0,
+			Low:   0,
+			Pause: false,
+		},
 	}
 }
 
@@ -87,7 +133,57 @@ func NewMemoryConfig() MemoryConfig {
 
 // NewMemory creates a buffer held in memory.
 func NewMemory(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
-	wrap := NewParallelWrapper(config, parallel.NewMemory(config.Memory.Limit), log, stats)
+	marks := config.Memory.Watermarks
+	if marks.High < 0 || marks.Low < 0 {
+		return nil, errors.New("watermarks must not be negative")
+	}
+	if marks.High > 0 {
+		if marks.High > config.Memory.Limit {
+			return nil, errors.New("high watermark must not exceed the limit")
+		}
+		if marks.Low >= marks.High {
+			return nil, errors.New("low watermark must be lower than the high watermark")
+		}
+	}
+
+	var (
+		limit       = int64(config.Memory.Limit)
+		mMessages   = stats.GetGauge("backlog.messages")
+		mPercentage = stats.GetGauge("backlog.percentage")
+		mAge        = stats.GetTimer("age")
+		mHigh       = stats.GetCounter("watermark.high")
+		mLow        = stats.GetCounter("watermark.low")
+		mAbove      = stats.GetGauge("watermark.above")
+	)
+
+	buf := parallel.NewMemoryWithHooks(config.Memory.Limit, parallel.MemoryWatermarks{
+		High:  marks.High,
+		Low:   marks.Low,
+		Pause: marks.Pause,
+	}, parallel.MemoryHooks{
+		Backlog: func(bytes, messages int) {
+			mMessages.Set(int64(messages))
+			if limit > 0 {
+				mPercentage.Set(int64(bytes) * 100 / limit)
+			}
+		},
+		Read: func(age time.Duration) {
+			mAge.Timing(age.Nanoseconds())
+		},
+		Watermark: func(high bool) {
+			if high {
+				mHigh.Incr(1)
+				mAbove.Set(1)
+				log.Warnf("Buffer size has reached the high watermark of %v bytes\n", marks.High)
+			} else {
+				mLow.Incr(1)
+				mAbove.Set(0)
+				log.Infof("Buffer size has drained to the low watermark of %v bytes\n", marks.Low)
+			}
+		},
+	})
+
+	wrap := NewParallelWrapper(config, buf, log, stats)
 	if !config.Memory.BatchPolicy.Enabled {
 		return wrap, nil
 	}
@@ -79,3 +79,22 @@ func TestMemoryBuffer(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestMemoryBufferBadWatermarks(t *testing.T) {
+	tests := map[string]MemoryWatermarksConfig{
+		"negative":       {High: -1},
+		"above limit":    {High: 200},
+		"low above high": {High: 50, Low: 50},
+	}
+
+	for name, marks := range tests {
+		conf := NewConfig()
+		conf.Type = "memory"
+		conf.Memory.Limit = 100
+		conf.Memory.Watermarks = marks
+
+		if _, err := New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
@@ -2,31 +2,106 @@ package parallel
 
 import (
 	"sync"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
 //------------------------------------------------------------------------------
 
+// MemoryWatermarks describes a high and low watermark of the backlog of a
+// memory buffer in bytes. Once the backlog reaches the high watermark it is
+// considered above the watermarks until it drains back to the low watermark.
+type MemoryWatermarks struct {
+	// High is the backlog in bytes at which the buffer is considered above the
+	// watermarks, watermarks are disabled when this is zero.
+	High int
+
+	// Low is the backlog in bytes at which the buffer is no longer considered
+	// above the watermarks.
+	Low int
+
+	// Pause indicates whether new messages should be blocked whilst the buffer
+	// is above the watermarks.
+	Pause bool
+}
+
+// MemoryHooks are optional funcs called by a memory buffer as its state
+// changes. Hooks are called whilst the buffer is locked and therefore must not
+// block or call the buffer.
+type MemoryHooks struct {
+	// Backlog is called with the number of bytes and messages stored in the
+	// buffer, including those pending acknowledgement, each time they change.
+	Backlog func(bytes, messages int)
+
+	// Read is called with the length of time that each message read from the
+	// buffer was stored for.
+	Read func(age time.Duration)
+
+	// Watermark is called with true once the backlog reaches the high
+	// watermark, and with false once it then drains to the low watermark.
+	Watermark func(high bool)
+}
+
+type memoryMessage struct {
+	msg      types.Message
+	pushedAt time.Time
+}
+
 // Memory is a parallel buffer implementation that allows multiple parallel
 // consumers to read and purge messages from the buffer asynchronously.
 type Memory struct {
-	messages     []types.Message
-	bytes        int
-	pendingBytes int
+	messages        []memoryMessage
+	bytes           int
+	pendingBytes    int
+	pendingMessages int
 
 	cap  int
 	cond *sync.Cond
 
+	marks          MemoryWatermarks
+	aboveWatermark bool
+	hooks          MemoryHooks
+
 	closed bool
 }
 
 // NewMemory creates a memory based parallel buffer.
 func NewMemory(cap int) *Memory {
+	return NewMemoryWithHooks(cap, MemoryWatermarks{}, MemoryHooks{})
+}
+
+// NewMemoryWithHooks creates a memory based parallel buffer with watermarks and
+// hooks that are called as the state of the buffer changes.
+func NewMemoryWithHooks(cap int, marks MemoryWatermarks, hooks MemoryHooks) *Memory {
 	return &Memory{
 		bytes: 0,
 		cap:   cap,
 		cond:  sync.NewCond(&sync.Mutex{}),
+		marks: marks,
+		hooks: hooks,
+	}
+}
+
+// backlogChanged calls hooks after the backlog of the buffer has changed, the
+// buffer must be locked.
+func (m *Memory) backlogChanged() {
+	if m.hooks.Backlog != nil {
+		m.hooks.Backlog(m.bytes, len(m.messages)+m.pendingMessages)
+	}
+	if m.marks.High <= 0 {
+		return
+	}
+	if !m.aboveWatermark && m.bytes >= m.marks.High {
+		m.aboveWatermark = true
+		if m.hooks.Watermark != nil {
+			m.hooks.Watermark(true)
+		}
+	} else if m.aboveWatermark && m.bytes <= m.marks.Low {
+		m.aboveWatermark = false
+		if m.hooks.Watermark != nil {
+			m.hooks.Watermark(false)
+		}
 	}
 }
 
@@ -45,9 +120,10 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 		return nil, nil, types.ErrTypeClosed
 	}
 
-	msg := m.messages[0]
+	mMsg := m.messages[0]
+	msg := mMsg.msg
 
-	m.messages[0] = nil
+	m.messages[0] = memoryMessage{}
 	m.messages = m.messages[1:]
 
 	messageSize := 0
@@ -56,6 +132,11 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 		return nil
 	})
 	m.pendingBytes += messageSize
+	m.pendingMessages++
+
+	if m.hooks.Read != nil {
+		m.hooks.Read(time.Since(mMsg.pushedAt))
+	}
 
 	m.cond.Broadcast()
 	m.cond.L.Unlock()
@@ -67,11 +148,13 @@ func (m *Memory) NextMessage() (types.Message, AckFunc, error) {
 			return 0, types.ErrTypeClosed
 		}
 		m.pendingBytes -= messageSize
+		m.pendingMessages--
 		if ack {
 			m.bytes -= messageSize
 		} else {
-			m.messages = append([]types.Message{msg}, m.messages...)
+			m.messages = append([]memoryMessage{mMsg}, m.messages...)
 		}
+		m.backlogChanged()
 		m.cond.Broadcast()
 
 		backlog := m.bytes
@@ -100,7 +183,7 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		return 0, types.ErrTypeClosed
 	}
 
-	for (m.bytes + extraBytes) > m.cap {
+	for (m.bytes+extraBytes) > m.cap || (m.marks.Pause && m.aboveWatermark) {
 		m.cond.Wait()
 		if m.closed {
 			m.cond.L.Unlock()
@@ -108,8 +191,12 @@ func (m *Memory) PushMessage(msg types.Message) (int, error) {
 		}
 	}
 
-	m.messages = append(m.messages, msg.DeepCopy())
+	m.messages = append(m.messages, memoryMessage{
+		msg:      msg.DeepCopy(),
+		pushedAt: time.Now(),
+	})
 	m.bytes += extraBytes
+	m.backlogChanged()
 
 	backlog := m.bytes
 
@@ -3,6 +3,7 @@ package parallel
 import (
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -322,3 +323,91 @@ func TestMemoryRejectLargeMessage(t *testing.T) {
 		t.Errorf("Unexpected error: %v != %v", exp, actual)
 	}
 }
+
+func TestMemoryWatermarks(t *testing.T) {
+	var mut sync.Mutex
+	var events []bool
+	var lastBytes, lastMessages, reads int
+
+	block := NewMemoryWithHooks(100, MemoryWatermarks{
+		High:  10,
+		Low:   4,
+		Pause: true,
+	}, MemoryHooks{
+		Backlog: func(bytes, messages int) {
+			mut.Lock()
+			lastBytes, lastMessages = bytes, messages
+			mut.Unlock()
+		},
+		Read: func(age time.Duration) {
+			mut.Lock()
+			reads++
+			mut.Unlock()
+		},
+		Watermark: func(high bool) {
+			mut.Lock()
+			events = append(events, high)
+			mut.Unlock()
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := block.PushMessage(message.New([][]byte{[]byte("1234")})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mut.Lock()
+	if exp, act := []bool{true}, events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong watermark events: %v != %v", act, exp)
+	}
+	if lastBytes != 12 || lastMessages != 3 {
+		t.Errorf("Wrong backlog: %v bytes %v messages", lastBytes, lastMessages)
+	}
+	mut.Unlock()
+
+	pushed := make(chan error)
+	go func() {
+		_, err := block.PushMessage(message.New([][]byte{[]byte("1234")}))
+		pushed <- err
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("Expected push to be paused")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	for i := 0; i < 2; i++ {
+		_, ackFunc, err := block.NextMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ackFunc(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	mut.Lock()
+	if exp, act := []bool{true, false}, events; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong watermark events: %v != %v", act, exp)
+	}
+	if lastBytes != 8 || lastMessages != 2 {
+		t.Errorf("Wrong backlog: %v bytes %v messages", lastBytes, lastMessages)
+	}
+	if reads != 2 {
+		t.Errorf("Wrong count of reads: %v != %v", reads, 2)
+	}
+	mut.Unlock()
+
+	block.Close()
+}
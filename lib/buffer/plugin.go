@@ -0,0 +1,98 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/config"
+)
+
+//------------------------------------------------------------------------------
+
+type pluginSpec struct {
+	constructor     func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error)
+	confConstructor func() interface{}
+	description     string
+}
+
+// pluginSpecs is a map of all buffer plugin type specs.
+var pluginSpecs = map[string]pluginSpec{}
+
+// PluginCount returns the number of registered plugins. This does NOT count the
+// standard set of components.
+func PluginCount() int {
+	return len(pluginSpecs)
+}
+
+//------------------------------------------------------------------------------
+
+var pluginHeader = `This document lists any buffer plugins that this flavour of Benthos offers
+beyond the standard set.`
+
+// PluginDescriptions generates and returns a markdown formatted document
+// listing each registered plugin and an example configuration for it.
+func PluginDescriptions() string {
+	// Order alphabetically
+	names := []string{}
+	for name := range pluginSpecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.Buffer{}
+	buf.WriteString("Buffer Plugins\n")
+	buf.WriteString(strings.Repeat("=", 14))
+	buf.WriteString("\n\n")
+	buf.WriteString(pluginHeader)
+	buf.WriteString("\n\n")
+
+	buf.WriteString("### Contents\n\n")
+	for i, name := range names {
+		buf.WriteString(fmt.Sprintf("%v. [`%v`](#%v)\n", i+1, name, name))
+	}
+
+	if len(names) == 0 {
+		buf.WriteString("There are no plugins loaded.")
+	} else {
+		buf.WriteString("\n")
+	}
+
+	// Append each description
+	for i, name := range names {
+		var confBytes []byte
+
+		if confCtor := pluginSpecs[name].confConstructor; confCtor != nil {
+			conf := NewConfig()
+			conf.Type = name
+			conf.Plugin = confCtor()
+			if confSanit, err := SanitiseConfig(conf); err == nil {
+				confBytes, _ = config.MarshalYAML(confSanit)
+			}
+		}
+
+		buf.WriteString("## ")
+		buf.WriteString("`" + name + "`")
+		buf.WriteString("\n")
+		if confBytes != nil {
+			buf.WriteString("\n``` yaml\n")
+			buf.Write(confBytes)
+			buf.WriteString("```\n")
+		}
+		if desc := pluginSpecs[name].description; len(desc) > 0 {
+			buf.WriteString("\n")
+			buf.WriteString(desc)
+			buf.WriteString("\n")
+		}
+		if i != (len(names) - 1) {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+//------------------------------------------------------------------------------
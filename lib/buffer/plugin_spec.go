@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
+)
+
+//------------------------------------------------------------------------------
+
+// SpecPluginConstructor is a func that constructs a buffer plugin from a
+// configuration that has been parsed according to its spec.
+//
+// The returned buffer is driven by a single writer and a single reader with the
+// following semantics:
+//
+// - PushMessage is called for each consumed message, and the message is only
+// acknowledged at the input level once it returns without an error.
+//
+// - NextMessage must return the oldest message that has not been acknowledged,
+// and must continue returning it until ShiftMessage is called. This allows
+// messages that were rejected by the output to be replayed.
+//
+// - ShiftMessage is called once the message returned by NextMessage has been
+// successfully delivered, and acknowledges it within the buffer.
+//
+// - CloseOnceEmpty is called when the pipeline is shutting down and should
+// block until all messages have been read and acknowledged, or until Close is
+// called, after which NextMessage and PushMessage must return
+// types.ErrTypeClosed.
+type SpecPluginConstructor func(
+	conf *plugin.ParsedConfig,
+	manager types.Manager,
+	logger log.Modular,
+	metrics metrics.Type,
+) (Single, error)
+
+// RegisterPluginWithSpec registers a plugin by a unique name along with a spec
+// of its configuration fields, the constructor is given the user configuration
+// parsed according to the spec.
+//
+// The plugin is wrapped in order to implement the full buffer interface,
+// including the same metrics and tracing as standard buffers.
+//
+// An error is returned if the name is already used by a buffer type or another
+// plugin, or if the spec is invalid.
+func RegisterPluginWithSpec(name string, spec *plugin.ConfigSpec, constructor SpecPluginConstructor) error {
+	if _, exists := Constructors[name]; exists {
+		return fmt.Errorf("buffer type '%v' already exists", name)
+	}
+	if _, exists := pluginSpecs[name]; exists {
+		return fmt.Errorf("buffer plugin '%v' already exists", name)
+	}
+	if constructor == nil {
+		return errors.New("a constructor must be provided")
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	pluginSpecs[name] = pluginSpec{
+		constructor: func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			pConf, err := spec.Parse(conf.Plugin)
+			if err != nil {
+				return nil, err
+			}
+			b, err := constructor(pConf, mgr, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewSingleWrapper(conf, b, log, stats), nil
+		},
+		confConstructor: func() interface{} {
+			return spec.Defaults()
+		},
+		description: spec.Document(),
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
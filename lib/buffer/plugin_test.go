@@ -0,0 +1,119 @@
+package buffer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer/single"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+type mockSpecBuffer struct {
+	Single
+	shifted int
+}
+
+func (m *mockSpecBuffer) ShiftMessage() (int, error) {
+	m.shifted++
+	return m.Single.ShiftMessage()
+}
+
+func TestYAMLPluginWithSpec(t *testing.T) {
+	var mock *mockSpecBuffer
+	spec := plugin.NewConfigSpec().
+		Summary("A memory backed buffer.").
+		Field("limit", "The maximum size of the buffer in bytes.", 1024)
+
+	err := RegisterPluginWithSpec("foo_spec", spec, func(conf *plugin.ParsedConfig, mgr types.Manager, logger log.Modular, stats metrics.Type) (Single, error) {
+		limit, err := conf.FieldInt("limit")
+		if err != nil {
+			return nil, err
+		}
+		mock = &mockSpecBuffer{
+			Single: single.NewMemory(single.MemoryConfig{Limit: limit}),
+		}
+		return mock, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(pluginSpecs, "foo_spec")
+
+	if err = RegisterPluginWithSpec("foo_spec", spec, nil); err == nil {
+		t.Error("Expected error from duplicate plugin")
+	}
+	if err = RegisterPluginWithSpec(TypeMemory, spec, nil); err == nil {
+		t.Error("Expected error from plugin with the name of a buffer type")
+	}
+	if !strings.Contains(PluginDescriptions(), "A memory backed buffer.") {
+		t.Errorf("Plugin description missing: %v", PluginDescriptions())
+	}
+
+	conf := NewConfig()
+	if err = yaml.Unmarshal([]byte("type: foo_spec\nplugin:\n  limit: 2048"), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err = b.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("hello world")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case res := <-resChan:
+		if res.Error() != nil {
+			t.Fatal(res.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	// Rejecting the message should cause it to be replayed, and acknowledging
+	// it should shift it from the buffer.
+	for _, resErr := range []error{errors.New("nope"), nil} {
+		var tran types.Transaction
+		select {
+		case tran = <-b.TransactionChan():
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		if exp, act := "hello world", string(tran.Payload.Get(0).Get()); exp != act {
+			t.Errorf("Wrong message: %v != %v", act, exp)
+		}
+		select {
+		case tran.ResponseChan <- response.NewError(resErr):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	b.StopConsuming()
+	if err = b.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 1, mock.shifted; exp != act {
+		t.Errorf("Wrong count of shifted messages: %v != %v", act, exp)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,289 @@
+package single
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+//------------------------------------------------------------------------------
+
+// DiskConfig is config values for a buffer that persists messages to disk.
+type DiskConfig struct {
+	Path       string `json:"path" yaml:"path"`
+	Limit      int64  `json:"limit" yaml:"limit"`
+	FullPolicy string `json:"full_policy" yaml:"full_policy"`
+}
+
+// NewDiskConfig creates a new DiskConfig with default values.
+func NewDiskConfig() DiskConfig {
+	return DiskConfig{
+		Path:       "",
+		Limit:      1024 * 1024 * 1024, // 1GB
+		FullPolicy: "block",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var diskBucket = []byte("benthos_buffer")
+
+// Disk is a buffer that persists messages to an embedded database on disk,
+// messages are only removed once they have been shifted and therefore messages
+// that were not shifted before a crash are read again once the buffer is
+// reopened.
+type Disk struct {
+	config DiskConfig
+	db     *bolt.DB
+
+	log      log.Modular
+	mDropped metrics.StatCounter
+
+	size    int64
+	count   int
+	readKey []byte
+
+	closed bool
+
+	cond *sync.Cond
+}
+
+// NewDisk creates a new disk based buffer, messages stored by a previous
+// instance of the buffer at the same path are read first.
+func NewDisk(config DiskConfig, log log.Modular, stats metrics.Type) (*Disk, error) {
+	if config.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if config.Limit <= 0 {
+		return nil, errors.New("limit must be larger than zero")
+	}
+	switch config.FullPolicy {
+	case "block", "drop_newest", "drop_oldest":
+	default:
+		return nil, fmt.Errorf("unrecognised full policy: %v", config.FullPolicy)
+	}
+
+	db, err := bolt.Open(config.Path, 0600, &bolt.Options{
+		Timeout: time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+
+	d := &Disk{
+		config:   config,
+		db:       db,
+		log:      log,
+		mDropped: stats.GetCounter("dropped"),
+		cond:     sync.NewCond(&sync.Mutex{}),
+	}
+
+	if err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(diskBucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			d.size += int64(len(v))
+			d.count++
+			return nil
+		})
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read database: %v", err)
+	}
+
+	d.log.Infof("Storing messages to file in: %s\n", config.Path)
+	if d.count > 0 {
+		d.log.Infof("Replaying %v messages stored by a previous run\n", d.count)
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// CloseOnceEmpty closes the disk buffer once the backlog reaches 0.
+func (d *Disk) CloseOnceEmpty() {
+	d.cond.L.Lock()
+	for d.count > 0 && !d.closed {
+		d.cond.Wait()
+	}
+	d.cond.L.Unlock()
+	d.Close()
+}
+
+// Close unblocks any blocked calls and closes the underlying database, messages
+// that remain in the buffer are read again once it is reopened.
+func (d *Disk) Close() {
+	d.cond.L.Lock()
+	if !d.closed {
+		d.closed = true
+		if err := d.db.Close(); err != nil {
+			d.log.Errorf("Failed to close database: %v\n", err)
+		}
+	}
+	d.cond.Broadcast()
+	d.cond.L.Unlock()
+}
+
+// ShiftMessage removes the last message read from the buffer. Returns the
+// backlog count.
+func (d *Disk) ShiftMessage() (int, error) {
+	d.cond.L.Lock()
+	defer func() {
+		d.cond.Broadcast()
+		d.cond.L.Unlock()
+	}()
+
+	if d.closed {
+		return 0, types.ErrTypeClosed
+	}
+	if d.readKey == nil {
+		return int(d.size), nil
+	}
+
+	// The message might have already been dropped in order to make space for
+	// newer messages.
+	if _, err := d.remove(d.readKey); err != nil {
+		return int(d.size), err
+	}
+	d.readKey = nil
+	return int(d.size), nil
+}
+
+// NextMessage reads the next message, this call blocks until there's something
+// to read.
+func (d *Disk) NextMessage() (types.Message, error) {
+	d.cond.L.Lock()
+	defer d.cond.L.Unlock()
+
+	for d.count == 0 && !d.closed {
+		d.cond.Wait()
+	}
+	if d.closed {
+		return nil, types.ErrTypeClosed
+	}
+
+	var msg types.Message
+	err := d.db.View(func(tx *bolt.Tx) error {
+		k, v := tx.Bucket(diskBucket).Cursor().First()
+		if k == nil {
+			return types.ErrBlockCorrupted
+		}
+		d.readKey = append([]byte(nil), k...)
+
+		var err error
+		msg, err = decodeMessage(v)
+		return err
+	})
+	return msg, err
+}
+
+// PushMessage pushes a new message onto the buffer, the message is persisted
+// to disk before this call returns. Returns the backlog count.
+func (d *Disk) PushMessage(msg types.Message) (int, error) {
+	block := encodeMessage(msg)
+
+	d.cond.L.Lock()
+	defer func() {
+		d.cond.Broadcast()
+		d.cond.L.Unlock()
+	}()
+
+	if int64(len(block)) > d.config.Limit {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	for d.size+int64(len(block)) > d.config.Limit && !d.closed {
+		switch d.config.FullPolicy {
+		case "drop_newest":
+			d.mDropped.Incr(1)
+			return int(d.size), nil
+		case "drop_oldest":
+			dropped, err := d.dropOldest()
+			if err != nil {
+				return int(d.size), err
+			}
+			if dropped {
+				d.mDropped.Incr(1)
+				continue
+			}
+		}
+		d.cond.Wait()
+	}
+	if d.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	if err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		var key [8]byte
+		binary.BigEndian.PutUint64(key[:], seq)
+		return b.Put(key[:], block)
+	}); err != nil {
+		return int(d.size), err
+	}
+
+	d.size += int64(len(block))
+	d.count++
+	return int(d.size), nil
+}
+
+//------------------------------------------------------------------------------
+
+// remove deletes a message by its key and returns whether it existed.
+func (d *Disk) remove(key []byte) (bool, error) {
+	var removedSize int
+	var removed bool
+	if err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskBucket)
+		v := b.Get(key)
+		if v == nil {
+			return nil
+		}
+		removedSize, removed = len(v), true
+		return b.Delete(key)
+	}); err != nil {
+		return false, err
+	}
+	if removed {
+		d.size -= int64(removedSize)
+		d.count--
+	}
+	return removed, nil
+}
+
+// dropOldest deletes the oldest message that is not currently being read and
+// returns whether a message was deleted.
+func (d *Disk) dropOldest() (bool, error) {
+	var key []byte
+	if err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diskBucket).Cursor()
+		k, _ := c.First()
+		if k != nil && bytes.Equal(k, d.readKey) {
+			k, _ = c.Next()
+		}
+		key = append([]byte(nil), k...)
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	if len(key) == 0 {
+		return false, nil
+	}
+	return d.remove(key)
+}
+
+//------------------------------------------------------------------------------
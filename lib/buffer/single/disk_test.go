@@ -0,0 +1,268 @@
+package single
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDisk(t *testing.T, conf DiskConfig) *Disk {
+	t.Helper()
+	d, err := NewDisk(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return d
+}
+
+func testDiskConfig(t *testing.T) DiskConfig {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "benthos_disk_buffer_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	conf := NewDiskConfig()
+	conf.Path = filepath.Join(dir, "buffer.db")
+	return conf
+}
+
+func TestDiskBadConfig(t *testing.T) {
+	tests := map[string]func(c *DiskConfig){
+		"no path": func(c *DiskConfig) {
+			c.Path = ""
+		},
+		"zero limit": func(c *DiskConfig) {
+			c.Limit = 0
+		},
+		"bad full policy": func(c *DiskConfig) {
+			c.FullPolicy = "nope"
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := testDiskConfig(t)
+			test(&conf)
+			_, err := NewDisk(conf, log.Noop(), metrics.Noop())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDiskBasic(t *testing.T) {
+	d := newTestDisk(t, testDiskConfig(t))
+	defer d.Close()
+
+	for i := 0; i < 10; i++ {
+		msg := message.New([][]byte{
+			[]byte("hello"),
+			[]byte(fmt.Sprintf("test%v", i)),
+		})
+		msg.Get(1).Metadata().Set("index", fmt.Sprintf("%v", i))
+		_, err := d.PushMessage(msg)
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		m, err := d.NextMessage()
+		require.NoError(t, err)
+		require.Equal(t, 2, m.Len())
+		assert.Equal(t, "hello", string(m.Get(0).Get()))
+		assert.Equal(t, fmt.Sprintf("test%v", i), string(m.Get(1).Get()))
+		assert.Equal(t, fmt.Sprintf("%v", i), m.Get(1).Metadata().Get("index"))
+
+		backlog, err := d.ShiftMessage()
+		require.NoError(t, err)
+		if i == 9 {
+			assert.Equal(t, 0, backlog)
+		}
+	}
+}
+
+func TestDiskReplay(t *testing.T) {
+	conf := testDiskConfig(t)
+
+	d := newTestDisk(t, conf)
+	for i := 0; i < 5; i++ {
+		_, err := d.PushMessage(message.New([][]byte{
+			[]byte(fmt.Sprintf("test%v", i)),
+		}))
+		require.NoError(t, err)
+	}
+
+	m, err := d.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test0", string(m.Get(0).Get()))
+	_, err = d.ShiftMessage()
+	require.NoError(t, err)
+
+	// Read without shifting, as if the message was never delivered.
+	m, err = d.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test1", string(m.Get(0).Get()))
+	d.Close()
+
+	d = newTestDisk(t, conf)
+	defer d.Close()
+
+	for i := 1; i < 5; i++ {
+		m, err := d.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("test%v", i), string(m.Get(0).Get()))
+		_, err = d.ShiftMessage()
+		require.NoError(t, err)
+	}
+}
+
+func TestDiskFullBlock(t *testing.T) {
+	conf := testDiskConfig(t)
+
+	block := encodeMessage(message.New([][]byte{[]byte("test0")}))
+	conf.Limit = int64(len(block) * 2)
+
+	d := newTestDisk(t, conf)
+	defer d.Close()
+
+	_, err := d.PushMessage(message.New([][]byte{[]byte("test0")}))
+	require.NoError(t, err)
+	_, err = d.PushMessage(message.New([][]byte{[]byte("test1")}))
+	require.NoError(t, err)
+
+	_, err = d.PushMessage(message.New([][]byte{[]byte(strings.Repeat("x", 100))}))
+	assert.Equal(t, types.ErrMessageTooLarge, err)
+
+	pushed := make(chan error)
+	go func() {
+		_, perr := d.PushMessage(message.New([][]byte{[]byte("test2")}))
+		pushed <- perr
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("Expected push to block")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	m, err := d.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test0", string(m.Get(0).Get()))
+	_, err = d.ShiftMessage()
+	require.NoError(t, err)
+
+	select {
+	case err = <-pushed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	for _, exp := range []string{"test1", "test2"} {
+		m, err := d.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(m.Get(0).Get()))
+		_, err = d.ShiftMessage()
+		require.NoError(t, err)
+	}
+}
+
+func TestDiskFullDrop(t *testing.T) {
+	tests := map[string][]string{
+		"drop_newest": {"test0", "test1"},
+		"drop_oldest": {"test2", "test3"},
+	}
+
+	for policy, exp := range tests {
+		policy, exp := policy, exp
+		t.Run(policy, func(t *testing.T) {
+			conf := testDiskConfig(t)
+
+			block := encodeMessage(message.New([][]byte{[]byte("test0")}))
+			conf.Limit = int64(len(block) * 2)
+			conf.FullPolicy = policy
+
+			d := newTestDisk(t, conf)
+			defer d.Close()
+
+			for i := 0; i < 4; i++ {
+				_, err := d.PushMessage(message.New([][]byte{
+					[]byte(fmt.Sprintf("test%v", i)),
+				}))
+				require.NoError(t, err)
+			}
+
+			for _, e := range exp {
+				m, err := d.NextMessage()
+				require.NoError(t, err)
+				assert.Equal(t, e, string(m.Get(0).Get()))
+				_, err = d.ShiftMessage()
+				require.NoError(t, err)
+			}
+			assert.Equal(t, 0, d.count)
+		})
+	}
+}
+
+func TestDiskDropOldestSkipsReading(t *testing.T) {
+	conf := testDiskConfig(t)
+
+	block := encodeMessage(message.New([][]byte{[]byte("test0")}))
+	conf.Limit = int64(len(block) * 2)
+	conf.FullPolicy = "drop_oldest"
+
+	d := newTestDisk(t, conf)
+	defer d.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := d.PushMessage(message.New([][]byte{
+			[]byte(fmt.Sprintf("test%v", i)),
+		}))
+		require.NoError(t, err)
+	}
+
+	m, err := d.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test0", string(m.Get(0).Get()))
+
+	_, err = d.PushMessage(message.New([][]byte{[]byte("test2")}))
+	require.NoError(t, err)
+
+	_, err = d.ShiftMessage()
+	require.NoError(t, err)
+
+	m, err = d.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test2", string(m.Get(0).Get()))
+}
+
+func TestDiskClose(t *testing.T) {
+	d := newTestDisk(t, testDiskConfig(t))
+
+	read := make(chan error)
+	go func() {
+		_, err := d.NextMessage()
+		read <- err
+	}()
+
+	d.Close()
+	select {
+	case err := <-read:
+		assert.Equal(t, types.ErrTypeClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	_, err := d.PushMessage(message.New([][]byte{[]byte("test")}))
+	assert.Equal(t, types.ErrTypeClosed, err)
+}
@@ -0,0 +1,99 @@
+package single
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// encodeMessage serialises a message along with the metadata of each of its
+// parts.
+func encodeMessage(msg types.Message) []byte {
+	var buf bytes.Buffer
+	var lBytes [4]byte
+
+	writeLen := func(l int) {
+		binary.BigEndian.PutUint32(lBytes[:], uint32(l))
+		buf.Write(lBytes[:])
+	}
+	writeBytes := func(b []byte) {
+		writeLen(len(b))
+		buf.Write(b)
+	}
+
+	writeLen(msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		var meta []string
+		p.Metadata().Iter(func(k, v string) error {
+			meta = append(meta, k, v)
+			return nil
+		})
+		writeLen(len(meta) / 2)
+		for _, s := range meta {
+			writeBytes([]byte(s))
+		}
+		writeBytes(p.Get())
+		return nil
+	})
+	return buf.Bytes()
+}
+
+// decodeMessage parses a message serialised with encodeMessage.
+func decodeMessage(b []byte) (types.Message, error) {
+	readLen := func() (int, error) {
+		if len(b) < 4 {
+			return 0, types.ErrBlockCorrupted
+		}
+		l := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return l, nil
+	}
+	readBytes := func() ([]byte, error) {
+		l, err := readLen()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) < l {
+			return nil, types.ErrBlockCorrupted
+		}
+		v := make([]byte, l)
+		copy(v, b)
+		b = b[l:]
+		return v, nil
+	}
+
+	nParts, err := readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := message.New(nil)
+	for i := 0; i < nParts; i++ {
+		nMeta, err := readLen()
+		if err != nil {
+			return nil, err
+		}
+		meta := make([][]byte, nMeta*2)
+		for j := range meta {
+			if meta[j], err = readBytes(); err != nil {
+				return nil, err
+			}
+		}
+		data, err := readBytes()
+		if err != nil {
+			return nil, err
+		}
+		part := message.NewPart(data)
+		for j := 0; j < len(meta); j += 2 {
+			part.Metadata().Set(string(meta[j]), string(meta[j+1]))
+		}
+		msg.Append(part)
+	}
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
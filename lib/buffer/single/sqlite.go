@@ -0,0 +1,260 @@
+// +build SQLITE
+
+package single
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+
+	// Import the SQLite driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//------------------------------------------------------------------------------
+
+// SQLite is a buffer that persists messages to a SQLite database in write
+// ahead log mode, messages are only removed once they have been shifted and
+// therefore messages that were not shifted before a crash are read again once
+// the buffer is reopened.
+type SQLite struct {
+	config SQLiteConfig
+	db     *sql.DB
+
+	log      log.Modular
+	mDropped metrics.StatCounter
+
+	size   int64
+	count  int
+	readID int64
+
+	closed bool
+
+	cond *sync.Cond
+}
+
+// NewSQLite creates a new SQLite based buffer, messages stored by a previous
+// instance of the buffer at the same path are read first.
+func NewSQLite(config SQLiteConfig, log log.Modular, stats metrics.Type) (*SQLite, error) {
+	if config.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if config.Limit <= 0 {
+		return nil, errors.New("limit must be larger than zero")
+	}
+	switch config.FullPolicy {
+	case "block", "drop_newest", "drop_oldest":
+	default:
+		return nil, fmt.Errorf("unrecognised full policy: %v", config.FullPolicy)
+	}
+
+	// Auto vacuuming returns the space of removed messages to the file system,
+	// and must be set before the table is created.
+	dsn := config.Path + "?_journal_mode=WAL&_synchronous=FULL&_auto_vacuum=full&_busy_timeout=5000"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// Writes are serialised by the buffer, and a single connection prevents
+	// busy errors from concurrent transactions.
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{
+		config:   config,
+		db:       db,
+		log:      log,
+		mDropped: stats.GetCounter("dropped"),
+		readID:   -1,
+		cond:     sync.NewCond(&sync.Mutex{}),
+	}
+
+	if _, err = db.Exec(`CREATE TABLE IF NOT EXISTS benthos_buffer (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  size INTEGER NOT NULL,
+  data BLOB NOT NULL
+)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create table: %v", err)
+	}
+	if err = db.QueryRow(
+		`SELECT COALESCE(SUM(size), 0), COUNT(*) FROM benthos_buffer`,
+	).Scan(&s.size, &s.count); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read database: %v", err)
+	}
+
+	s.log.Infof("Storing messages to file in: %s\n", config.Path)
+	if s.count > 0 {
+		s.log.Infof("Replaying %v messages stored by a previous run\n", s.count)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// CloseOnceEmpty closes the SQLite buffer once the backlog reaches 0.
+func (s *SQLite) CloseOnceEmpty() {
+	s.cond.L.Lock()
+	for s.count > 0 && !s.closed {
+		s.cond.Wait()
+	}
+	s.cond.L.Unlock()
+	s.Close()
+}
+
+// Close unblocks any blocked calls and closes the underlying database, messages
+// that remain in the buffer are read again once it is reopened.
+func (s *SQLite) Close() {
+	s.cond.L.Lock()
+	if !s.closed {
+		s.closed = true
+		if err := s.db.Close(); err != nil {
+			s.log.Errorf("Failed to close database: %v\n", err)
+		}
+	}
+	s.cond.Broadcast()
+	s.cond.L.Unlock()
+}
+
+// ShiftMessage removes the last message read from the buffer. Returns the
+// backlog count.
+func (s *SQLite) ShiftMessage() (int, error) {
+	s.cond.L.Lock()
+	defer func() {
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+	}()
+
+	if s.closed {
+		return 0, types.ErrTypeClosed
+	}
+	if s.readID < 0 {
+		return int(s.size), nil
+	}
+
+	// The message might have already been dropped in order to make space for
+	// newer messages.
+	if _, err := s.remove(s.readID); err != nil {
+		return int(s.size), err
+	}
+	s.readID = -1
+	return int(s.size), nil
+}
+
+// NextMessage reads the next message, this call blocks until there's something
+// to read.
+func (s *SQLite) NextMessage() (types.Message, error) {
+	s.cond.L.Lock()
+	defer s.cond.L.Unlock()
+
+	for s.count == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return nil, types.ErrTypeClosed
+	}
+
+	var id int64
+	var data []byte
+	if err := s.db.QueryRow(
+		`SELECT id, data FROM benthos_buffer ORDER BY id ASC LIMIT 1`,
+	).Scan(&id, &data); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, types.ErrBlockCorrupted
+		}
+		return nil, err
+	}
+	s.readID = id
+	return decodeMessage(data)
+}
+
+// PushMessage pushes a new message onto the buffer, the message is persisted
+// to disk before this call returns. Returns the backlog count.
+func (s *SQLite) PushMessage(msg types.Message) (int, error) {
+	block := encodeMessage(msg)
+
+	s.cond.L.Lock()
+	defer func() {
+		s.cond.Broadcast()
+		s.cond.L.Unlock()
+	}()
+
+	if int64(len(block)) > s.config.Limit {
+		return 0, types.ErrMessageTooLarge
+	}
+
+	for s.size+int64(len(block)) > s.config.Limit && !s.closed {
+		switch s.config.FullPolicy {
+		case "drop_newest":
+			s.mDropped.Incr(1)
+			return int(s.size), nil
+		case "drop_oldest":
+			dropped, err := s.dropOldest()
+			if err != nil {
+				return int(s.size), err
+			}
+			if dropped {
+				s.mDropped.Incr(1)
+				continue
+			}
+		}
+		s.cond.Wait()
+	}
+	if s.closed {
+		return 0, types.ErrTypeClosed
+	}
+
+	if _, err := s.db.Exec(
+		`INSERT INTO benthos_buffer (size, data) VALUES (?, ?)`,
+		len(block), block,
+	); err != nil {
+		return int(s.size), err
+	}
+
+	s.size += int64(len(block))
+	s.count++
+	return int(s.size), nil
+}
+
+//------------------------------------------------------------------------------
+
+// remove deletes a message by its id and returns whether it existed.
+func (s *SQLite) remove(id int64) (bool, error) {
+	var size int64
+	if err := s.db.QueryRow(
+		`SELECT size FROM benthos_buffer WHERE id = ?`, id,
+	).Scan(&size); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := s.db.Exec(`DELETE FROM benthos_buffer WHERE id = ?`, id); err != nil {
+		return false, err
+	}
+	s.size -= size
+	s.count--
+	return true, nil
+}
+
+// dropOldest deletes the oldest message that is not currently being read and
+// returns whether a message was deleted.
+func (s *SQLite) dropOldest() (bool, error) {
+	var id int64
+	if err := s.db.QueryRow(
+		`SELECT id FROM benthos_buffer WHERE id != ? ORDER BY id ASC LIMIT 1`, s.readID,
+	).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return s.remove(id)
+}
+
+//------------------------------------------------------------------------------
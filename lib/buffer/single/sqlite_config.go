@@ -0,0 +1,22 @@
+package single
+
+//------------------------------------------------------------------------------
+
+// SQLiteConfig is config values for a buffer that persists messages to a
+// SQLite database.
+type SQLiteConfig struct {
+	Path       string `json:"path" yaml:"path"`
+	Limit      int64  `json:"limit" yaml:"limit"`
+	FullPolicy string `json:"full_policy" yaml:"full_policy"`
+}
+
+// NewSQLiteConfig creates a new SQLiteConfig with default values.
+func NewSQLiteConfig() SQLiteConfig {
+	return SQLiteConfig{
+		Path:       "",
+		Limit:      1024 * 1024 * 1024, // 1GB
+		FullPolicy: "block",
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,135 @@
+// +build SQLITE
+
+package single
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSQLiteConfig(t *testing.T) SQLiteConfig {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "benthos_sqlite_buffer_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	conf := NewSQLiteConfig()
+	conf.Path = filepath.Join(dir, "buffer.db")
+	return conf
+}
+
+func TestSQLiteBadConfig(t *testing.T) {
+	tests := map[string]func(c *SQLiteConfig){
+		"no path": func(c *SQLiteConfig) {
+			c.Path = ""
+		},
+		"zero limit": func(c *SQLiteConfig) {
+			c.Limit = 0
+		},
+		"bad full policy": func(c *SQLiteConfig) {
+			c.FullPolicy = "nope"
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := testSQLiteConfig(t)
+			test(&conf)
+			_, err := NewSQLite(conf, log.Noop(), metrics.Noop())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSQLiteReplay(t *testing.T) {
+	conf := testSQLiteConfig(t)
+
+	s, err := NewSQLite(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		msg := message.New([][]byte{[]byte(fmt.Sprintf("test%v", i))})
+		msg.Get(0).Metadata().Set("index", fmt.Sprintf("%v", i))
+		_, err := s.PushMessage(msg)
+		require.NoError(t, err)
+	}
+
+	m, err := s.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test0", string(m.Get(0).Get()))
+	_, err = s.ShiftMessage()
+	require.NoError(t, err)
+
+	// Read without shifting, as if the message was never delivered.
+	m, err = s.NextMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "test1", string(m.Get(0).Get()))
+	s.Close()
+
+	s, err = NewSQLite(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer s.Close()
+
+	for i := 1; i < 5; i++ {
+		m, err := s.NextMessage()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("test%v", i), string(m.Get(0).Get()))
+		assert.Equal(t, fmt.Sprintf("%v", i), m.Get(0).Metadata().Get("index"))
+		_, err = s.ShiftMessage()
+		require.NoError(t, err)
+	}
+
+	backlog, err := s.ShiftMessage()
+	require.NoError(t, err)
+	assert.Equal(t, 0, backlog)
+}
+
+func TestSQLiteFullDrop(t *testing.T) {
+	tests := map[string][]string{
+		"drop_newest": {"test0", "test1"},
+		"drop_oldest": {"test2", "test3"},
+	}
+
+	for policy, exp := range tests {
+		policy, exp := policy, exp
+		t.Run(policy, func(t *testing.T) {
+			conf := testSQLiteConfig(t)
+
+			block := encodeMessage(message.New([][]byte{[]byte("test0")}))
+			conf.Limit = int64(len(block) * 2)
+			conf.FullPolicy = policy
+
+			s, err := NewSQLite(conf, log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+			defer s.Close()
+
+			for i := 0; i < 4; i++ {
+				_, err := s.PushMessage(message.New([][]byte{
+					[]byte(fmt.Sprintf("test%v", i)),
+				}))
+				require.NoError(t, err)
+			}
+
+			for _, e := range exp {
+				m, err := s.NextMessage()
+				require.NoError(t, err)
+				assert.Equal(t, e, string(m.Get(0).Get()))
+				_, err = s.ShiftMessage()
+				require.NoError(t, err)
+			}
+			assert.Equal(t, 0, s.count)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// +build SQLITE
+
+package buffer
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer/single"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSQLite] = TypeSpec{
+		constructor: NewSQLite,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Stores consumed messages in an embedded SQLite database and acknowledges them at
+the input level once they are persisted. Messages that were not delivered
+before a crash or shutdown are delivered once Benthos is restarted.`,
+		Description: `
+This buffer is intended for edge deployments with intermittent connectivity,
+where messages need to be kept safe on the local disk of a node until they can
+be delivered, without depending on any external services.
+
+Messages are written to a SQLite database at the configured ` + "`path`" + `
+in write ahead log mode, and are only removed once they have been successfully
+sent by the output. This means that messages are delivered at least once even
+when Benthos crashes, but messages that were in flight during a crash might be
+delivered again once the buffer is reopened. Messages are delivered in the order
+in which they were consumed, including the metadata of each message.
+
+The SQLite driver depends on C bindings. Since this is an annoyance when
+building or using Benthos this buffer is not compiled by default.
+
+There is a specific docker tag postfix ` + "`-cgo`" + ` for C builds containing
+SQLite support.
+
+You can also build it into your project by building with cgo enabled and the
+tag:
+
+` + "```sh" + `
+CGO_ENABLED=1 go install -tags "SQLITE" github.com/Jeffail/benthos/v3/cmd/benthos
+` + "```" + `
+
+### Full Policy
+
+The field ` + "`limit`" + ` caps the total size in bytes of messages stored in
+the buffer, which bounds the disk space used by the database, and
+` + "`full_policy`" + ` determines what happens when a consumed message would
+exceed it:
+
+- ` + "`block`" + ` applies back pressure upstream until space is freed by
+  messages being sent.
+- ` + "`drop_newest`" + ` acknowledges and discards the consumed message.
+- ` + "`drop_oldest`" + ` evicts the oldest messages of the buffer until the
+  consumed message fits.
+
+Discarded messages are counted by the metric ` + "`dropped`" + `.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The path of the database file to store buffered messages in."),
+			docs.FieldCommon("limit", "The maximum buffer size (in bytes) of stored messages."),
+			docs.FieldCommon("full_policy", "What to do with consumed messages when the buffer is full, see [full policy](#full-policy) for more information.").HasOptions("block", "drop_newest", "drop_oldest"),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewSQLite creates a buffer that persists messages to a SQLite database.
+func NewSQLite(config Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	s, err := single.NewSQLite(config.SQLite, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	return NewSingleWrapper(config, s, log, stats), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,589 @@
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWindow] = TypeSpec{
+		constructor: NewWindow,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Groups messages into tumbling, sliding or session windows by their event time,
+and flushes each window as a single batch once it closes.`,
+		Description: `
+Messages are assigned to windows by a timestamp, which defaults to the time at
+which each message is consumed but can be resolved from the contents of the
+message with the field ` + "`timestamp`" + `, which can either be a unix
+timestamp in seconds or an RFC 3339 formatted string. Windows are tracked
+separately for each value of the optional ` + "`key`" + `.
+
+Messages are acknowledged at the input level once they are added to a window.
+When a window closes its messages are flushed as a batch, ordered by their
+timestamps, where each message has the metadata fields ` + "`window_key`" + `,
+` + "`window_start`" + ` and ` + "`window_end`" + ` added. Batches can be
+aggregated into a single message with processors such as
+` + "[`archive`](/docs/components/processors/archive)" + ` or a
+` + "[`bloblang`](/docs/components/processors/bloblang)" + ` mapping.
+
+### Window Types
+
+- ` + "`tumbling`" + ` windows are of a fixed ` + "`size`" + ` and do not
+  overlap, each message belongs to exactly one window.
+- ` + "`sliding`" + ` windows are of a fixed ` + "`size`" + ` and begin at
+  every ` + "`slide`" + ` period, a message belongs to every window that its
+  timestamp falls within and is therefore flushed once per window.
+- ` + "`session`" + ` windows group messages of a key until a ` + "`gap`" + `
+  period passes without any further messages, a window therefore ends at its
+  last message plus the gap.
+
+### Watermarks
+
+The watermark is the point in event time up to which windows are considered
+complete, and is the highest timestamp consumed so far, advanced by the time
+that has since passed. A window closes once the watermark passes its end plus
+the ` + "`allowed_lateness`" + `, which allows messages that arrive out of
+order to still be included. Messages that arrive after their window has closed
+are dropped.
+
+When shutting down all open windows are flushed regardless of the watermark.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("type", "The type of window to group messages into.").HasOptions("tumbling", "sliding", "session"),
+			docs.FieldCommon("size", "The size of `tumbling` and `sliding` windows.", "1m", "1h"),
+			docs.FieldCommon("slide", "The period between the start of each `sliding` window.", "10s"),
+			docs.FieldCommon("gap", "The period of inactivity after which a `session` window ends.", "30s"),
+			docs.FieldCommon("key", "An optional key to group windows by.", `${! meta("kafka_key") }`).SupportsInterpolation(false),
+			docs.FieldCommon("timestamp", "An optional timestamp of each message. When empty the time at which the message was consumed is used.", `${! json("created_at") }`).SupportsInterpolation(false),
+			docs.FieldCommon("allowed_lateness", "A period after the end of a window during which it remains open for messages that arrive out of order.", "10s"),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// WindowConfig contains configuration fields for the window buffer.
+type WindowConfig struct {
+	Type            string `json:"type" yaml:"type"`
+	Size            string `json:"size" yaml:"size"`
+	Slide           string `json:"slide" yaml:"slide"`
+	Gap             string `json:"gap" yaml:"gap"`
+	Key             string `json:"key" yaml:"key"`
+	Timestamp       string `json:"timestamp" yaml:"timestamp"`
+	AllowedLateness string `json:"allowed_lateness" yaml:"allowed_lateness"`
+}
+
+// NewWindowConfig creates a new WindowConfig with default values.
+func NewWindowConfig() WindowConfig {
+	return WindowConfig{
+		Type:            "tumbling",
+		Size:            "1m",
+		Slide:           "",
+		Gap:             "",
+		Key:             "",
+		Timestamp:       "",
+		AllowedLateness: "0s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type windowPart struct {
+	ts   time.Time
+	part types.Part
+}
+
+type windowState struct {
+	key   string
+	start time.Time
+	end   time.Time
+	parts []windowPart
+}
+
+// Window is a buffer that groups messages into windows of event time.
+type Window struct {
+	conf  WindowConfig
+	log   log.Modular
+	stats metrics.Type
+
+	key       field.Expression
+	timestamp field.Expression
+
+	size     time.Duration
+	slide    time.Duration
+	gap      time.Duration
+	lateness time.Duration
+
+	mut       sync.Mutex
+	windows   map[string]*windowState
+	sessions  map[string][]*windowState
+	maxTs     time.Time
+	maxTsSeen time.Time
+
+	errThrottle *throttle.Type
+	tickPeriod  time.Duration
+	nowFn       func() time.Time
+
+	running   int32
+	consuming int32
+
+	messagesIn  <-chan types.Transaction
+	messagesOut chan types.Transaction
+
+	inputDone         chan struct{}
+	stopConsumingChan chan struct{}
+	closeChan         chan struct{}
+	closedChan        chan struct{}
+
+	mWriteCount metrics.StatCounter
+	mWriteErr   metrics.StatCounter
+	mLate       metrics.StatCounter
+	mOpen       metrics.StatGauge
+	mFlushed    metrics.StatCounter
+	mSendErr    metrics.StatCounter
+}
+
+// NewWindow creates a buffer that groups messages into windows.
+func NewWindow(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w := &Window{
+		conf:              conf.Window,
+		log:               log,
+		stats:             stats,
+		windows:           map[string]*windowState{},
+		sessions:          map[string][]*windowState{},
+		tickPeriod:        time.Millisecond * 100,
+		nowFn:             time.Now,
+		running:           1,
+		consuming:         1,
+		messagesOut:       make(chan types.Transaction),
+		inputDone:         make(chan struct{}),
+		stopConsumingChan: make(chan struct{}),
+		closeChan:         make(chan struct{}),
+		closedChan:        make(chan struct{}),
+
+		mWriteCount: stats.GetCounter("write.count"),
+		mWriteErr:   stats.GetCounter("write.error"),
+		mLate:       stats.GetCounter("late"),
+		mOpen:       stats.GetGauge("windows.open"),
+		mFlushed:    stats.GetCounter("windows.flushed"),
+		mSendErr:    stats.GetCounter("send.error"),
+	}
+	w.errThrottle = throttle.New(throttle.OptCloseChan(w.closeChan))
+
+	parseDuration := func(name, v string, required bool) (time.Duration, error) {
+		if v == "" {
+			if required {
+				return 0, fmt.Errorf("field %v is required for %v windows", name, w.conf.Type)
+			}
+			return 0, nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %v: %v", name, err)
+		}
+		if d < 0 || (required && d == 0) {
+			return 0, fmt.Errorf("field %v must be greater than zero", name)
+		}
+		return d, nil
+	}
+
+	var err error
+	switch w.conf.Type {
+	case "tumbling":
+		if w.size, err = parseDuration("size", w.conf.Size, true); err != nil {
+			return nil, err
+		}
+	case "sliding":
+		if w.size, err = parseDuration("size", w.conf.Size, true); err != nil {
+			return nil, err
+		}
+		if w.slide, err = parseDuration("slide", w.conf.Slide, true); err != nil {
+			return nil, err
+		}
+		if w.slide > w.size {
+			return nil, errors.New("field slide must not be greater than size")
+		}
+	case "session":
+		if w.gap, err = parseDuration("gap", w.conf.Gap, true); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("window type not recognised: %v", w.conf.Type)
+	}
+	if w.lateness, err = parseDuration("allowed_lateness", w.conf.AllowedLateness, false); err != nil {
+		return nil, err
+	}
+
+	if w.key, err = bloblang.NewField(w.conf.Key); err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	if w.timestamp, err = bloblang.NewField(w.conf.Timestamp); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp expression: %v", err)
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) partTimestamp(index int, msg types.Message, now time.Time) (time.Time, error) {
+	tsStr := w.timestamp.String(index, msg)
+	if tsStr == "" {
+		return now, nil
+	}
+	if f, err := strconv.ParseFloat(tsStr, 64); err == nil {
+		secs := int64(f)
+		return time.Unix(secs, int64((f-float64(secs))*1e9)), nil
+	}
+	return time.Parse(time.RFC3339Nano, tsStr)
+}
+
+// watermark returns the point in event time up to which windows are complete,
+// which is the highest timestamp seen advanced by the time passed since. Must
+// be called with the mutex held.
+func (w *Window) watermark(now time.Time) time.Time {
+	if w.maxTs.IsZero() {
+		return time.Time{}
+	}
+	return w.maxTs.Add(now.Sub(w.maxTsSeen))
+}
+
+func (w *Window) closed(end time.Time, watermark time.Time) bool {
+	return !watermark.IsZero() && !watermark.Before(end.Add(w.lateness))
+}
+
+func (w *Window) fixedWindow(key string, start time.Time) *windowState {
+	id := key + "\x00" + strconv.FormatInt(start.UnixNano(), 10)
+	win, exists := w.windows[id]
+	if !exists {
+		win = &windowState{
+			key:   key,
+			start: start,
+			end:   start.Add(w.size),
+		}
+		w.windows[id] = win
+	}
+	return win
+}
+
+// addSession adds a part to the session window of a key that it falls within,
+// merging any sessions that the part bridges. Must be called with the mutex
+// held.
+func (w *Window) addSession(key string, p windowPart) {
+	merged := &windowState{
+		key:   key,
+		start: p.ts,
+		end:   p.ts.Add(w.gap),
+		parts: []windowPart{p},
+	}
+	var remaining []*windowState
+	for _, s := range w.sessions[key] {
+		if p.ts.Before(s.start.Add(-w.gap)) || !p.ts.Before(s.end) {
+			remaining = append(remaining, s)
+			continue
+		}
+		if s.start.Before(merged.start) {
+			merged.start = s.start
+		}
+		if s.end.After(merged.end) {
+			merged.end = s.end
+		}
+		merged.parts = append(merged.parts, s.parts...)
+	}
+	w.sessions[key] = append(remaining, merged)
+}
+
+// add assigns each part of a message to its windows, returning the number of
+// parts that were dropped for being late.
+func (w *Window) add(msg types.Message) (late int, err error) {
+	now := w.nowFn()
+
+	keys := make([]string, msg.Len())
+	stamps := make([]time.Time, msg.Len())
+	for i := range keys {
+		keys[i] = w.key.String(i, msg)
+		if stamps[i], err = w.partTimestamp(i, msg, now); err != nil {
+			return 0, fmt.Errorf("failed to parse timestamp: %v", err)
+		}
+	}
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	watermark := w.watermark(now)
+	msg.Iter(func(i int, part types.Part) error {
+		ts, key := stamps[i], keys[i]
+		p := windowPart{ts: ts, part: part.Copy()}
+
+		switch w.conf.Type {
+		case "tumbling":
+			start := ts.Truncate(w.size)
+			if w.closed(start.Add(w.size), watermark) {
+				late++
+				return nil
+			}
+			win := w.fixedWindow(key, start)
+			win.parts = append(win.parts, p)
+		case "sliding":
+			added := false
+			for start := ts.Truncate(w.slide); start.Add(w.size).After(ts); start = start.Add(-w.slide) {
+				if w.closed(start.Add(w.size), watermark) {
+					break
+				}
+				win := w.fixedWindow(key, start)
+				win.parts = append(win.parts, p)
+				added = true
+			}
+			if !added {
+				late++
+				return nil
+			}
+		case "session":
+			if w.closed(ts.Add(w.gap), watermark) {
+				late++
+				return nil
+			}
+			w.addSession(key, p)
+		}
+
+		if ts.After(w.maxTs) {
+			w.maxTs, w.maxTsSeen = ts, now
+		}
+		return nil
+	})
+
+	w.mOpen.Set(int64(len(w.windows) + w.sessionCount()))
+	return late, nil
+}
+
+func (w *Window) sessionCount() (n int) {
+	for _, s := range w.sessions {
+		n += len(s)
+	}
+	return
+}
+
+// takeClosed removes and returns all windows that have closed, or all windows
+// if flushAll is true, ordered by their end.
+func (w *Window) takeClosed(flushAll bool) []*windowState {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	watermark := w.watermark(w.nowFn())
+
+	var closed []*windowState
+	for id, win := range w.windows {
+		if flushAll || w.closed(win.end, watermark) {
+			closed = append(closed, win)
+			delete(w.windows, id)
+		}
+	}
+	for key, sessions := range w.sessions {
+		var remaining []*windowState
+		for _, s := range sessions {
+			if flushAll || w.closed(s.end, watermark) {
+				closed = append(closed, s)
+			} else {
+				remaining = append(remaining, s)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(w.sessions, key)
+		} else {
+			w.sessions[key] = remaining
+		}
+	}
+	w.mOpen.Set(int64(len(w.windows) + w.sessionCount()))
+
+	sort.Slice(closed, func(i, j int) bool {
+		if closed[i].end.Equal(closed[j].end) {
+			return closed[i].key < closed[j].key
+		}
+		return closed[i].end.Before(closed[j].end)
+	})
+	return closed
+}
+
+func (w *Window) windowMessage(win *windowState) types.Message {
+	sort.SliceStable(win.parts, func(i, j int) bool {
+		return win.parts[i].ts.Before(win.parts[j].ts)
+	})
+
+	msg := message.New(nil)
+	start, end := win.start.Format(time.RFC3339Nano), win.end.Format(time.RFC3339Nano)
+	for _, p := range win.parts {
+		part := p.part.Copy()
+		part.Metadata().
+			Set("window_key", win.key).
+			Set("window_start", start).
+			Set("window_end", end)
+		msg.Append(part)
+	}
+	return msg
+}
+
+//------------------------------------------------------------------------------
+
+func (w *Window) inputLoop() {
+	defer close(w.inputDone)
+
+	for atomic.LoadInt32(&w.consuming) == 1 {
+		var tr types.Transaction
+		var open bool
+		select {
+		case tr, open = <-w.messagesIn:
+			if !open {
+				return
+			}
+		case <-w.stopConsumingChan:
+			return
+		}
+
+		late, err := w.add(tr.Payload)
+		if err != nil {
+			w.mWriteErr.Incr(1)
+			w.log.Debugf("Failed to add message to window: %v\n", err)
+		} else {
+			w.mWriteCount.Incr(1)
+			if late > 0 {
+				w.mLate.Incr(int64(late))
+				w.log.Debugf("Dropped %v late messages\n", late)
+			}
+		}
+
+		select {
+		case tr.ResponseChan <- response.NewError(err):
+		case <-w.stopConsumingChan:
+			return
+		}
+	}
+}
+
+// send delivers a window batch downstream until it is acknowledged, returning
+// false if the buffer was closed first.
+func (w *Window) send(msg types.Message) bool {
+	resChan := make(chan types.Response)
+	for {
+		select {
+		case w.messagesOut <- types.NewTransaction(msg, resChan):
+		case <-w.closeChan:
+			return false
+		}
+
+		var res types.Response
+		var open bool
+		select {
+		case res, open = <-resChan:
+			if !open {
+				return false
+			}
+		case <-w.closeChan:
+			return false
+		}
+		if res.Error() == nil {
+			w.errThrottle.Reset()
+			return true
+		}
+
+		w.mSendErr.Incr(1)
+		w.log.Errorf("Failed to send window: %v\n", res.Error())
+		if !w.errThrottle.Retry() {
+			return false
+		}
+	}
+}
+
+func (w *Window) outputLoop() {
+	defer func() {
+		close(w.messagesOut)
+		close(w.closedChan)
+	}()
+
+	ticker := time.NewTicker(w.tickPeriod)
+	defer ticker.Stop()
+
+	flushAll := false
+	for {
+		for _, win := range w.takeClosed(flushAll) {
+			if !w.send(w.windowMessage(win)) {
+				return
+			}
+			w.mFlushed.Incr(1)
+		}
+		if flushAll {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.inputDone:
+			// Once consumption has stopped every remaining window is flushed.
+			flushAll = true
+		case <-w.closeChan:
+			return
+		}
+	}
+}
+
+// Consume assigns a messages channel for the buffer to read.
+func (w *Window) Consume(msgs <-chan types.Transaction) error {
+	if w.messagesIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	w.messagesIn = msgs
+
+	go w.inputLoop()
+	go w.outputLoop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming messages from this
+// buffer.
+func (w *Window) TransactionChan() <-chan types.Transaction {
+	return w.messagesOut
+}
+
+// CloseAsync shuts down the buffer and stops processing messages.
+func (w *Window) CloseAsync() {
+	w.StopConsuming()
+	if atomic.CompareAndSwapInt32(&w.running, 1, 0) {
+		close(w.closeChan)
+	}
+}
+
+// StopConsuming instructs the buffer to stop consuming messages and close once
+// all open windows are flushed.
+func (w *Window) StopConsuming() {
+	if atomic.CompareAndSwapInt32(&w.consuming, 1, 0) {
+		close(w.stopConsumingChan)
+	}
+}
+
+// WaitForClose blocks until the buffer has closed down.
+func (w *Window) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-w.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
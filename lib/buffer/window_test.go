@@ -0,0 +1,293 @@
+package buffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Aligned to minutes so that window boundaries are predictable.
+const windowTestEpoch = 1599999960
+
+func newTestWindow(t *testing.T, conf Config) (*Window, chan types.Transaction) {
+	t.Helper()
+
+	conf.Type = TypeWindow
+	conf.Window.Timestamp = `${! json("ts") }`
+	conf.Window.Key = `${! json("key") }`
+
+	buf, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	w, ok := buf.(*Window)
+	require.True(t, ok)
+
+	// Freeze the passing of time so that the watermark only advances with the
+	// timestamps of messages.
+	now := time.Now()
+	w.nowFn = func() time.Time { return now }
+	w.tickPeriod = time.Millisecond
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, w.Consume(tChan))
+	return w, tChan
+}
+
+func windowSend(t *testing.T, tChan chan types.Transaction, key string, offsets ...int) {
+	t.Helper()
+
+	var parts [][]byte
+	for _, o := range offsets {
+		parts = append(parts, []byte(fmt.Sprintf(`{"key":"%v","ts":%v}`, key, windowTestEpoch+o)))
+	}
+
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New(parts), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		require.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func windowRead(t *testing.T, w *Window) types.Message {
+	t.Helper()
+
+	select {
+	case tr, open := <-w.TransactionChan():
+		require.True(t, open)
+		select {
+		case tr.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return tr.Payload
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func windowExpectNone(t *testing.T, w *Window) {
+	t.Helper()
+
+	select {
+	case tr := <-w.TransactionChan():
+		t.Fatalf("unexpected window: %s", message.GetAllBytes(tr.Payload))
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+// windowOffsets returns the timestamps of a window batch as offsets from the
+// test epoch.
+func windowOffsets(t *testing.T, msg types.Message) []int {
+	t.Helper()
+
+	var res []int
+	msg.Iter(func(i int, p types.Part) error {
+		var doc struct {
+			TS int `json:"ts"`
+		}
+		require.NoError(t, json.Unmarshal(p.Get(), &doc))
+		res = append(res, doc.TS-windowTestEpoch)
+		return nil
+	})
+	return res
+}
+
+func windowTime(offset int) string {
+	return time.Unix(windowTestEpoch+int64(offset), 0).Format(time.RFC3339Nano)
+}
+
+func windowClose(t *testing.T, w *Window) {
+	t.Helper()
+
+	w.StopConsuming()
+	select {
+	case _, open := <-w.TransactionChan():
+		require.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	w.CloseAsync()
+	require.NoError(t, w.WaitForClose(time.Second))
+}
+
+func TestWindowTumbling(t *testing.T) {
+	conf := NewConfig()
+	conf.Window.Size = "1m"
+
+	w, tChan := newTestWindow(t, conf)
+
+	windowSend(t, tChan, "a", 10, 0)
+	windowSend(t, tChan, "b", 30)
+	windowExpectNone(t, w)
+
+	// Advances the watermark past the end of the first windows.
+	windowSend(t, tChan, "a", 65)
+
+	msg := windowRead(t, w)
+	assert.Equal(t, []int{0, 10}, windowOffsets(t, msg))
+	assert.Equal(t, "a", msg.Get(0).Metadata().Get("window_key"))
+	assert.Equal(t, windowTime(0), msg.Get(0).Metadata().Get("window_start"))
+	assert.Equal(t, windowTime(60), msg.Get(0).Metadata().Get("window_end"))
+
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{30}, windowOffsets(t, msg))
+	assert.Equal(t, "b", msg.Get(0).Metadata().Get("window_key"))
+
+	// Late and therefore dropped.
+	windowSend(t, tChan, "a", 5)
+	windowExpectNone(t, w)
+
+	// Remaining windows are flushed on shutdown.
+	w.StopConsuming()
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{65}, windowOffsets(t, msg))
+	windowClose(t, w)
+}
+
+func TestWindowAllowedLateness(t *testing.T) {
+	conf := NewConfig()
+	conf.Window.Size = "1m"
+	conf.Window.AllowedLateness = "10s"
+
+	w, tChan := newTestWindow(t, conf)
+
+	windowSend(t, tChan, "a", 10)
+	windowSend(t, tChan, "a", 65)
+	windowExpectNone(t, w)
+
+	// Out of order but within the allowed lateness.
+	windowSend(t, tChan, "a", 20)
+	windowExpectNone(t, w)
+
+	windowSend(t, tChan, "a", 70)
+
+	msg := windowRead(t, w)
+	assert.Equal(t, []int{10, 20}, windowOffsets(t, msg))
+
+	w.StopConsuming()
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{65, 70}, windowOffsets(t, msg))
+	windowClose(t, w)
+}
+
+func TestWindowSliding(t *testing.T) {
+	conf := NewConfig()
+	conf.Window.Type = "sliding"
+	conf.Window.Size = "20s"
+	conf.Window.Slide = "10s"
+
+	w, tChan := newTestWindow(t, conf)
+
+	windowSend(t, tChan, "a", 5, 15)
+
+	msg := windowRead(t, w)
+	assert.Equal(t, []int{5}, windowOffsets(t, msg))
+	assert.Equal(t, windowTime(-10), msg.Get(0).Metadata().Get("window_start"))
+	assert.Equal(t, windowTime(10), msg.Get(0).Metadata().Get("window_end"))
+
+	windowSend(t, tChan, "a", 20)
+
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{5, 15}, windowOffsets(t, msg))
+	assert.Equal(t, windowTime(0), msg.Get(0).Metadata().Get("window_start"))
+	assert.Equal(t, windowTime(20), msg.Get(0).Metadata().Get("window_end"))
+
+	w.StopConsuming()
+
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{15, 20}, windowOffsets(t, msg))
+	assert.Equal(t, windowTime(10), msg.Get(0).Metadata().Get("window_start"))
+
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{20}, windowOffsets(t, msg))
+	assert.Equal(t, windowTime(20), msg.Get(0).Metadata().Get("window_start"))
+
+	windowClose(t, w)
+}
+
+func TestWindowSession(t *testing.T) {
+	conf := NewConfig()
+	conf.Window.Type = "session"
+	conf.Window.Gap = "10s"
+	conf.Window.AllowedLateness = "10s"
+
+	w, tChan := newTestWindow(t, conf)
+
+	windowSend(t, tChan, "a", 0, 5)
+	windowSend(t, tChan, "a", 20)
+	windowSend(t, tChan, "b", 3)
+
+	// Bridges both sessions of key a.
+	windowSend(t, tChan, "a", 12)
+	windowExpectNone(t, w)
+
+	// Advances the watermark past the session of key b.
+	windowSend(t, tChan, "c", 24)
+
+	msg := windowRead(t, w)
+	assert.Equal(t, []int{3}, windowOffsets(t, msg))
+	assert.Equal(t, "b", msg.Get(0).Metadata().Get("window_key"))
+	assert.Equal(t, windowTime(13), msg.Get(0).Metadata().Get("window_end"))
+	windowExpectNone(t, w)
+
+	w.StopConsuming()
+
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{0, 5, 12, 20}, windowOffsets(t, msg))
+	assert.Equal(t, "a", msg.Get(0).Metadata().Get("window_key"))
+	assert.Equal(t, windowTime(0), msg.Get(0).Metadata().Get("window_start"))
+	assert.Equal(t, windowTime(30), msg.Get(0).Metadata().Get("window_end"))
+
+	msg = windowRead(t, w)
+	assert.Equal(t, []int{24}, windowOffsets(t, msg))
+	assert.Equal(t, "c", msg.Get(0).Metadata().Get("window_key"))
+
+	windowClose(t, w)
+}
+
+func TestWindowBadConfig(t *testing.T) {
+	tests := map[string]func(c *WindowConfig){
+		"bad type": func(c *WindowConfig) {
+			c.Type = "nope"
+		},
+		"sliding without slide": func(c *WindowConfig) {
+			c.Type = "sliding"
+		},
+		"slide larger than size": func(c *WindowConfig) {
+			c.Type = "sliding"
+			c.Slide = "2m"
+		},
+		"session without gap": func(c *WindowConfig) {
+			c.Type = "session"
+		},
+		"bad lateness": func(c *WindowConfig) {
+			c.AllowedLateness = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeWindow
+		fn(&conf.Window)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
@@ -24,8 +24,9 @@ import (
 
 func init() {
 	Constructors[TypeAWSDynamoDB] = TypeSpec{
-		constructor: NewAWSDynamoDB,
-		Version:     "3.36.0",
+		constructor:       NewAWSDynamoDB,
+		Version:           "3.36.0",
+		SupportsPerKeyTTL: true,
 		Summary: `
 Stores key/value pairs as a single document in a DynamoDB table. The key is
 stored as a string value and used as the table hash key. The value is stored as
@@ -35,6 +36,10 @@ A prefix can be specified to allow multiple cache types to share a single
 DynamoDB table. An optional TTL duration (` + "`ttl`" + `) and field
 (` + "`ttl_key`" + `) can be specified if the backing table has TTL enabled.
 
+Since DynamoDB deletes expired items some time after they expire, items with a
+TTL in the past are treated as missing by get operations, and can be replaced
+by add operations.
+
 Strong read consistency can be enabled using the ` + "`consistent_read`" + `
 configuration field.
 
@@ -51,6 +56,7 @@ allowing you to transfer data across accounts. You can find out more
 			docs.FieldAdvanced("consistent_read", "Whether to use strongly consistent reads on Get commands."),
 			docs.FieldAdvanced("ttl", "An optional TTL to set for items, calculated from the moment the item is cached."),
 			docs.FieldAdvanced("ttl_key", "The column key to place the TTL value within."),
+			ttlJitterFieldSpec(),
 		}.Merge(session.FieldSpecs()).Merge(retries.FieldSpecs()),
 	}
 
@@ -86,6 +92,7 @@ allowing you to transfer data across accounts. You can find out more
 			docs.FieldAdvanced("consistent_read", "Whether to use strongly consistent reads on Get commands."),
 			docs.FieldAdvanced("ttl", "An optional TTL to set for items, calculated from the moment the item is cached."),
 			docs.FieldAdvanced("ttl_key", "The column key to place the TTL value within."),
+			ttlJitterFieldSpec(),
 		}.Merge(session.FieldSpecs()).Merge(retries.FieldSpecs()),
 	}
 }
@@ -99,12 +106,13 @@ type sessionConfig struct {
 // DynamoDBConfig contains config fields for the DynamoDB cache type.
 type DynamoDBConfig struct {
 	sessionConfig  `json:",inline" yaml:",inline"`
-	ConsistentRead bool   `json:"consistent_read" yaml:"consistent_read"`
-	DataKey        string `json:"data_key" yaml:"data_key"`
-	HashKey        string `json:"hash_key" yaml:"hash_key"`
-	Table          string `json:"table" yaml:"table"`
-	TTL            string `json:"ttl" yaml:"ttl"`
-	TTLKey         string `json:"ttl_key" yaml:"ttl_key"`
+	ConsistentRead bool    `json:"consistent_read" yaml:"consistent_read"`
+	DataKey        string  `json:"data_key" yaml:"data_key"`
+	HashKey        string  `json:"hash_key" yaml:"hash_key"`
+	Table          string  `json:"table" yaml:"table"`
+	TTL            string  `json:"ttl" yaml:"ttl"`
+	TTLKey         string  `json:"ttl_key" yaml:"ttl_key"`
+	TTLJitter      float64 `json:"ttl_jitter" yaml:"ttl_jitter"`
 	retries.Config `json:",inline" yaml:",inline"`
 }
 
@@ -125,6 +133,7 @@ func NewDynamoDBConfig() DynamoDBConfig {
 		Table:          "",
 		TTL:            "",
 		TTLKey:         "",
+		TTLJitter:      0,
 		Config:         rConf,
 	}
 }
@@ -228,6 +237,9 @@ func newDynamoDB(conf DynamoDBConfig, mgr types.Manager, log log.Modular, stats
 		}
 		d.ttl = ttl
 	}
+	if err := checkTTLJitter(d.conf.TTLJitter); err != nil {
+		return nil, err
+	}
 
 	sess, err := d.conf.GetSession()
 	if err != nil {
@@ -312,16 +324,129 @@ func (d *DynamoDB) get(key string) ([]byte, error) {
 		return nil, err
 	}
 
-	val, ok := res.Item[d.conf.DataKey]
-	if !ok || val.B == nil {
+	val, ok := d.itemValue(res.Item)
+	if !ok {
 		d.log.Debugf("key not found: %s", key)
 		return nil, types.ErrKeyNotFound
 	}
-	return val.B, nil
+	return val, nil
+}
+
+// itemValue returns the value of an item, or false if the item has no value or
+// has expired but not yet been deleted by DynamoDB.
+func (d *DynamoDB) itemValue(item map[string]*dynamodb.AttributeValue) ([]byte, bool) {
+	val, ok := item[d.conf.DataKey]
+	if !ok || val.B == nil {
+		return nil, false
+	}
+	if d.conf.TTLKey != "" {
+		if ttlVal, ok := item[d.conf.TTLKey]; ok && ttlVal.N != nil {
+			if expires, err := strconv.ParseInt(*ttlVal.N, 10, 64); err == nil && expires <= time.Now().Unix() {
+				return nil, false
+			}
+		}
+	}
+	return val.B, true
+}
+
+// The maximum number of keys within a single BatchGetItem request.
+const dynamoDBMaxBatchGet = 100
+
+// GetMulti attempts to locate and return the cached values of multiple keys
+// with BatchGetItem requests, keys that do not exist are omitted from the
+// result.
+func (d *DynamoDB) GetMulti(keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	d.mGetCount.Incr(int64(len(keys)))
+
+	tStarted := time.Now()
+	boff := d.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		d.boffPool.Put(boff)
+	}()
+
+	var err error
+	for i := 0; i < len(keys) && err == nil; i += dynamoDBMaxBatchGet {
+		j := i + dynamoDBMaxBatchGet
+		if j > len(keys) {
+			j = len(keys)
+		}
+
+		reqKeys := make([]map[string]*dynamodb.AttributeValue, 0, j-i)
+		for _, k := range keys[i:j] {
+			reqKeys = append(reqKeys, map[string]*dynamodb.AttributeValue{
+				d.conf.HashKey: {
+					S: aws.String(k),
+				},
+			})
+		}
+
+		for len(reqKeys) > 0 {
+			var res *dynamodb.BatchGetItemOutput
+			if res, err = d.client.BatchGetItem(&dynamodb.BatchGetItemInput{
+				RequestItems: map[string]*dynamodb.KeysAndAttributes{
+					*d.table: {
+						Keys:           reqKeys,
+						ConsistentRead: aws.Bool(d.conf.ConsistentRead),
+					},
+				},
+			}); err == nil {
+				for _, item := range res.Responses[*d.table] {
+					keyVal, ok := item[d.conf.HashKey]
+					if !ok || keyVal.S == nil {
+						continue
+					}
+					if val, ok := d.itemValue(item); ok {
+						values[*keyVal.S] = val
+					}
+				}
+				reqKeys = nil
+				if unproc, ok := res.UnprocessedKeys[*d.table]; ok && len(unproc.Keys) > 0 {
+					reqKeys = unproc.Keys
+					err = fmt.Errorf("failed to get %v items", len(unproc.Keys))
+				}
+			}
+			if err == nil {
+				break
+			}
+
+			d.log.Errorf("Get multi error: %v\n", err)
+			wait := boff.NextBackOff()
+			if wait == backoff.Stop {
+				break
+			}
+			time.Sleep(wait)
+			d.mGetRetry.Incr(1)
+			err = nil
+		}
+	}
+
+	latency := int64(time.Since(tStarted))
+	d.mGetLatency.Timing(latency)
+	d.mLatency.Timing(latency)
+
+	if err != nil {
+		d.mGetFailed.Incr(int64(len(keys)))
+		return nil, err
+	}
+	d.mGetSuccess.Incr(int64(len(values)))
+	d.mGetNotFound.Incr(int64(len(keys) - len(values)))
+	return values, nil
 }
 
 // Set attempts to set the value of a key.
 func (d *DynamoDB) Set(key string, value []byte) error {
+	return d.SetWithTTL(key, value, nil)
+}
+
+// SetWithTTL attempts to set the value of a key with a TTL that overrides the
+// configured TTL.
+func (d *DynamoDB) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	d.mSetCount.Incr(1)
 
 	tStarted := time.Now()
@@ -331,7 +456,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		d.boffPool.Put(boff)
 	}()
 
-	_, err := d.client.PutItem(d.putItemInput(key, value))
+	_, err := d.client.PutItem(d.putItemInput(key, value, ttl))
 	for err != nil {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -339,7 +464,7 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mSetRetry.Incr(1)
-		_, err = d.client.PutItem(d.putItemInput(key, value))
+		_, err = d.client.PutItem(d.putItemInput(key, value, ttl))
 	}
 	if err == nil {
 		d.mSetSuccess.Incr(1)
@@ -357,6 +482,18 @@ func (d *DynamoDB) Set(key string, value []byte) error {
 // SetMulti attempts to set the value of multiple keys, if any keys fail to be
 // set an error is returned.
 func (d *DynamoDB) SetMulti(items map[string][]byte) error {
+	sitems := make(map[string]types.CacheTTLItem, len(items))
+	for k, v := range items {
+		sitems[k] = types.CacheTTLItem{
+			Value: v,
+		}
+	}
+	return d.SetMultiWithTTL(sitems)
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys, each with an
+// optional TTL, if any keys fail to be set an error is returned.
+func (d *DynamoDB) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
 	d.mSetMultiCount.Incr(1)
 
 	tStarted := time.Now()
@@ -370,7 +507,7 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 	for k, v := range items {
 		writeReqs = append(writeReqs, &dynamodb.WriteRequest{
 			PutRequest: &dynamodb.PutRequest{
-				Item: d.putItemInput(k, v).Item,
+				Item: d.putItemInput(k, v.Value, v.TTL).Item,
 			},
 		})
 	}
@@ -418,6 +555,13 @@ func (d *DynamoDB) SetMulti(items map[string][]byte) error {
 // Add attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (d *DynamoDB) Add(key string, value []byte) error {
+	return d.AddWithTTL(key, value, nil)
+}
+
+// AddWithTTL attempts to set the value of a key with a TTL that overrides the
+// configured TTL only if the key does not already exist, and returns an error
+// if the key already exists.
+func (d *DynamoDB) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 	d.mAddCount.Incr(1)
 
 	tStarted := time.Now()
@@ -427,7 +571,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 		d.boffPool.Put(boff)
 	}()
 
-	err := d.add(key, value)
+	err := d.add(key, value, ttl)
 	for err != nil && err != types.ErrKeyAlreadyExists {
 		wait := boff.NextBackOff()
 		if wait == backoff.Stop {
@@ -435,7 +579,7 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 		}
 		time.Sleep(wait)
 		d.mAddRetry.Incr(1)
-		err = d.add(key, value)
+		err = d.add(key, value, ttl)
 	}
 	if err == nil {
 		d.mAddSuccess.Incr(1)
@@ -452,16 +596,24 @@ func (d *DynamoDB) Add(key string, value []byte) error {
 	return err
 }
 
-func (d *DynamoDB) add(key string, value []byte) error {
-	input := d.putItemInput(key, value)
+func (d *DynamoDB) add(key string, value []byte, ttl *time.Duration) error {
+	input := d.putItemInput(key, value, ttl)
+
+	// Items that have expired but are yet to be deleted by DynamoDB are
+	// considered absent.
+	cond := expression.AttributeNotExists(expression.Name(d.conf.HashKey))
+	if d.conf.TTLKey != "" {
+		cond = cond.Or(expression.Name(d.conf.TTLKey).LessThanEqual(expression.Value(time.Now().Unix())))
+	}
 
 	expr, err := expression.NewBuilder().
-		WithCondition(expression.AttributeNotExists(expression.Name(d.conf.HashKey))).
+		WithCondition(cond).
 		Build()
 	if err != nil {
 		return err
 	}
 	input.ExpressionAttributeNames = expr.Names()
+	input.ExpressionAttributeValues = expr.Values()
 	input.ConditionExpression = expr.Condition()
 
 	if _, err = d.client.PutItem(input); err != nil {
@@ -522,8 +674,9 @@ func (d *DynamoDB) delete(key string) error {
 	return err
 }
 
-// putItemInput creates a generic put item input for use in Set and Add operations
-func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput {
+// putItemInput creates a generic put item input for use in Set and Add
+// operations, where a nil TTL results in the configured TTL.
+func (d *DynamoDB) putItemInput(key string, value []byte, ttl *time.Duration) *dynamodb.PutItemInput {
 	input := dynamodb.PutItemInput{
 		Item: map[string]*dynamodb.AttributeValue{
 			d.conf.HashKey: {
@@ -536,9 +689,14 @@ func (d *DynamoDB) putItemInput(key string, value []byte) *dynamodb.PutItemInput
 		TableName: d.table,
 	}
 
-	if d.ttl != 0 && d.conf.TTLKey != "" {
+	itemTTL := d.ttl
+	if ttl != nil {
+		itemTTL = *ttl
+	}
+	itemTTL = jitterTTL(itemTTL, d.conf.TTLJitter)
+	if itemTTL != 0 && d.conf.TTLKey != "" {
 		input.Item[d.conf.TTLKey] = &dynamodb.AttributeValue{
-			N: aws.String(strconv.FormatInt(time.Now().Add(d.ttl).Unix(), 10)),
+			N: aws.String(strconv.FormatInt(time.Now().Add(itemTTL).Unix(), 10)),
 		}
 	}
 
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	items     map[string]map[string]*dynamodb.AttributeValue
+	batchGets [][]string
+	puts      []*dynamodb.PutItemInput
+}
+
+func (m *mockDynamoDB) BatchGetItem(input *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	var keys []string
+	var items []map[string]*dynamodb.AttributeValue
+	var unprocessed []map[string]*dynamodb.AttributeValue
+	for i, k := range input.RequestItems["foo"].Keys {
+		key := *k["id"].S
+		// Leave the last key of the first request unprocessed.
+		if len(m.batchGets) == 0 && i == len(input.RequestItems["foo"].Keys)-1 && i > 0 {
+			unprocessed = append(unprocessed, k)
+			continue
+		}
+		keys = append(keys, key)
+		if item, exists := m.items[key]; exists {
+			items = append(items, item)
+		}
+	}
+	m.batchGets = append(m.batchGets, keys)
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]*dynamodb.AttributeValue{
+			"foo": items,
+		},
+	}
+	if len(unprocessed) > 0 {
+		out.UnprocessedKeys = map[string]*dynamodb.KeysAndAttributes{
+			"foo": {Keys: unprocessed},
+		}
+	}
+	return out, nil
+}
+
+func (m *mockDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	m.puts = append(m.puts, input)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func testDynamoDB(t *testing.T, client dynamodbiface.DynamoDBAPI) *DynamoDB {
+	t.Helper()
+
+	conf := NewDynamoDBConfig()
+	conf.Table = "foo"
+	conf.HashKey = "id"
+	conf.DataKey = "data"
+	conf.TTL = "1h"
+	conf.TTLKey = "expires"
+	conf.Backoff.InitialInterval = "1ms"
+
+	boffCtor, err := conf.Config.GetCtor()
+	require.NoError(t, err)
+
+	d := &DynamoDB{
+		client:      client,
+		conf:        conf,
+		log:         log.Noop(),
+		stats:       metrics.Noop(),
+		table:       aws.String(conf.Table),
+		ttl:         time.Hour,
+		backoffCtor: boffCtor,
+
+		mLatency:     metrics.Noop().GetTimer("latency"),
+		mGetCount:    metrics.Noop().GetCounter("get.count"),
+		mGetRetry:    metrics.Noop().GetCounter("get.retry"),
+		mGetFailed:   metrics.Noop().GetCounter("get.failed.error"),
+		mGetNotFound: metrics.Noop().GetCounter("get.failed.not_found"),
+		mGetSuccess:  metrics.Noop().GetCounter("get.success"),
+		mGetLatency:  metrics.Noop().GetTimer("get.latency"),
+		mSetCount:    metrics.Noop().GetCounter("set.count"),
+		mSetRetry:    metrics.Noop().GetCounter("set.retry"),
+		mSetFailed:   metrics.Noop().GetCounter("set.failed.error"),
+		mSetSuccess:  metrics.Noop().GetCounter("set.success"),
+		mSetLatency:  metrics.Noop().GetTimer("set.latency"),
+	}
+	d.boffPool.New = func() interface{} {
+		return d.backoffCtor()
+	}
+	return d
+}
+
+func dynamoItem(key, value string, expires time.Time) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id":      {S: aws.String(key)},
+		"data":    {B: []byte(value)},
+		"expires": {N: aws.String(strconv.FormatInt(expires.Unix(), 10))},
+	}
+}
+
+func TestDynamoDBGetMulti(t *testing.T) {
+	future, past := time.Now().Add(time.Hour), time.Now().Add(-time.Hour)
+	client := &mockDynamoDB{
+		items: map[string]map[string]*dynamodb.AttributeValue{
+			"a": dynamoItem("a", "foo", future),
+			"b": dynamoItem("b", "bar", past),
+			"c": dynamoItem("c", "baz", future),
+		},
+	}
+	d := testDynamoDB(t, client)
+
+	values, err := d.GetMulti("a", "b", "c", "d")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"a": []byte("foo"),
+		"c": []byte("baz"),
+	}, values)
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d"}}, client.batchGets)
+}
+
+func TestDynamoDBSetWithTTL(t *testing.T) {
+	client := &mockDynamoDB{}
+	d := testDynamoDB(t, client)
+
+	ttl := time.Minute
+	require.NoError(t, d.SetWithTTL("a", []byte("foo"), &ttl))
+	require.NoError(t, d.Set("b", []byte("bar")))
+	require.Len(t, client.puts, 2)
+
+	expires := func(input *dynamodb.PutItemInput) int64 {
+		n, err := strconv.ParseInt(*input.Item["expires"].N, 10, 64)
+		require.NoError(t, err)
+		return n
+	}
+	now := time.Now()
+	assert.InDelta(t, now.Add(time.Minute).Unix(), expires(client.puts[0]), 2)
+	assert.InDelta(t, now.Add(time.Hour).Unix(), expires(client.puts[1]), 2)
+}
+
+func TestDynamoDBAddCondition(t *testing.T) {
+	client := &mockDynamoDB{}
+	d := testDynamoDB(t, client)
+
+	require.NoError(t, d.add("a", []byte("foo"), nil))
+	require.Len(t, client.puts, 1)
+
+	input := client.puts[0]
+	require.NotNil(t, input.ConditionExpression)
+	assert.Contains(t, *input.ConditionExpression, "attribute_not_exists")
+	assert.Contains(t, *input.ConditionExpression, "<=")
+	assert.Len(t, input.ExpressionAttributeValues, 1)
+}
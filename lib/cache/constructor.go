@@ -40,7 +40,9 @@ var Constructors = map[string]TypeSpec{}
 const (
 	TypeAWSDynamoDB = "aws_dynamodb"
 	TypeAWSS3       = "aws_s3"
+	TypeDisk        = "disk"
 	TypeDynamoDB    = "dynamodb"
+	TypeEtcd        = "etcd"
 	TypeFile        = "file"
 	TypeMemcached   = "memcached"
 	TypeMemory      = "memory"
@@ -48,6 +50,7 @@ const (
 	TypeRedis       = "redis"
 	TypeRistretto   = "ristretto"
 	TypeS3          = "s3"
+	TypeSQL         = "sql"
 )
 
 //------------------------------------------------------------------------------
@@ -57,7 +60,9 @@ type Config struct {
 	Type        string           `json:"type" yaml:"type"`
 	AWSDynamoDB DynamoDBConfig   `json:"aws_dynamodb" yaml:"aws_dynamodb"`
 	AWSS3       S3Config         `json:"aws_s3" yaml:"aws_s3"`
+	Disk        DiskConfig       `json:"disk" yaml:"disk"`
 	DynamoDB    DynamoDBConfig   `json:"dynamodb" yaml:"dynamodb"`
+	Etcd        EtcdConfig       `json:"etcd" yaml:"etcd"`
 	File        FileConfig       `json:"file" yaml:"file"`
 	Memcached   MemcachedConfig  `json:"memcached" yaml:"memcached"`
 	Memory      MemoryConfig     `json:"memory" yaml:"memory"`
@@ -66,6 +71,7 @@ type Config struct {
 	Redis       RedisConfig      `json:"redis" yaml:"redis"`
 	Ristretto   RistrettoConfig  `json:"ristretto" yaml:"ristretto"`
 	S3          S3Config         `json:"s3" yaml:"s3"`
+	SQL         SQLConfig        `json:"sql" yaml:"sql"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -74,7 +80,9 @@ func NewConfig() Config {
 		Type:        "memory",
 		AWSDynamoDB: NewDynamoDBConfig(),
 		AWSS3:       NewS3Config(),
+		Disk:        NewDiskConfig(),
 		DynamoDB:    NewDynamoDBConfig(),
+		Etcd:        NewEtcdConfig(),
 		File:        NewFileConfig(),
 		Memcached:   NewMemcachedConfig(),
 		Memory:      NewMemoryConfig(),
@@ -83,6 +91,7 @@ func NewConfig() Config {
 		Redis:       NewRedisConfig(),
 		Ristretto:   NewRistrettoConfig(),
 		S3:          NewS3Config(),
+		SQL:         NewSQLConfig(),
 	}
 }
 
@@ -0,0 +1,517 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDisk] = TypeSpec{
+		constructor:       NewDisk,
+		SupportsPerKeyTTL: true,
+		Status:            docs.StatusExperimental,
+		Version:           "3.39.0",
+		Summary: `
+Stores key/value pairs in a single file on disk using an embedded
+[bbolt](https://github.com/etcd-io/bbolt) database, which persists items
+across restarts.`,
+		Description: `
+This cache is intended for single node deployments that need to keep state such
+as deduplication windows across restarts without an external service. Only one
+process can open a database file at a time.
+
+The add command is atomic, and therefore this cache is suitable for
+deduplication.
+
+### Compaction
+
+Expired items are treated as missing immediately, and are removed from the
+database periodically according to ` + "`compaction_interval`" + `. During
+compaction, if the total size of the items within the database exceeds
+` + "`max_size`" + ` then the oldest items are removed until it no longer does.
+
+Space freed by removed items is reused by new items rather than returned to the
+file system. When the database file is more than twice the size of the items
+within it the file is rewritten during compaction in order to reclaim the
+space, during which operations on the cache are blocked.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("path", "The path of the database file, which is created if it does not exist.", "/var/lib/benthos/dedupe.db"),
+			docs.FieldCommon("ttl", "An optional TTL to set for items, after this period items are expired.", "60s", "72h"),
+			ttlJitterFieldSpec(),
+			docs.FieldAdvanced("compaction_interval", "The period of time between compactions of the database."),
+			docs.FieldAdvanced("max_size", "An optional maximum total size in bytes of the keys and values of items within the database, which is enforced during compaction by removing the oldest items. Set to zero to disable the limit."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DiskConfig contains config fields for the Disk cache type.
+type DiskConfig struct {
+	Path               string  `json:"path" yaml:"path"`
+	TTL                string  `json:"ttl" yaml:"ttl"`
+	TTLJitter          float64 `json:"ttl_jitter" yaml:"ttl_jitter"`
+	CompactionInterval string  `json:"compaction_interval" yaml:"compaction_interval"`
+	MaxSize            int64   `json:"max_size" yaml:"max_size"`
+}
+
+// NewDiskConfig creates a DiskConfig populated with default values.
+func NewDiskConfig() DiskConfig {
+	return DiskConfig{
+		Path:               "",
+		TTL:                "",
+		TTLJitter:          0,
+		CompactionInterval: "5m",
+		MaxSize:            0,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var diskBucket = []byte("benthos_cache")
+
+// Items are stored with a header containing the unix nano timestamps of when
+// the item expires, which is zero for items without a TTL, and when the item
+// was written.
+const diskHeaderLen = 16
+
+func encodeDiskItem(value []byte, ttl time.Duration, now time.Time) []byte {
+	b := make([]byte, diskHeaderLen+len(value))
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(b[0:8], uint64(now.Add(ttl).UnixNano()))
+	}
+	binary.BigEndian.PutUint64(b[8:16], uint64(now.UnixNano()))
+	copy(b[diskHeaderLen:], value)
+	return b
+}
+
+// decodeDiskItem returns the value of an encoded item, or false if the item is
+// malformed or has expired.
+func decodeDiskItem(b []byte, now time.Time) ([]byte, bool) {
+	if len(b) < diskHeaderLen {
+		return nil, false
+	}
+	if expires := int64(binary.BigEndian.Uint64(b[0:8])); expires > 0 && expires <= now.UnixNano() {
+		return nil, false
+	}
+	value := make([]byte, len(b)-diskHeaderLen)
+	copy(value, b[diskHeaderLen:])
+	return value, true
+}
+
+func diskItemWritten(b []byte) int64 {
+	if len(b) < diskHeaderLen {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b[8:16]))
+}
+
+//------------------------------------------------------------------------------
+
+// Disk is a cache implementation that stores items in an embedded database on
+// disk.
+type Disk struct {
+	path      string
+	ttl       time.Duration
+	ttlJitter float64
+	maxSize   int64
+
+	// Operations hold a read lock on the database in order to allow compaction
+	// to replace it.
+	dbMut sync.RWMutex
+	db    *bolt.DB
+
+	log log.Modular
+
+	mKeys      metrics.StatGauge
+	mExpired   metrics.StatCounter
+	mEvicted   metrics.StatCounter
+	mRewritten metrics.StatCounter
+	mCompErr   metrics.StatCounter
+
+	compactionInterval time.Duration
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDisk creates a new Disk cache type.
+func NewDisk(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	if conf.Disk.Path == "" {
+		return nil, errors.New("a path must be specified")
+	}
+
+	var ttl time.Duration
+	if conf.Disk.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(conf.Disk.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl duration: %v", err)
+		}
+	}
+	if err := checkTTLJitter(conf.Disk.TTLJitter); err != nil {
+		return nil, err
+	}
+
+	compactionInterval, err := time.ParseDuration(conf.Disk.CompactionInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compaction interval duration: %v", err)
+	}
+	if compactionInterval <= 0 {
+		return nil, errors.New("compaction interval must be a positive duration")
+	}
+
+	db, err := openDiskDB(conf.Disk.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Disk{
+		path:               conf.Disk.Path,
+		ttl:                ttl,
+		ttlJitter:          conf.Disk.TTLJitter,
+		maxSize:            conf.Disk.MaxSize,
+		db:                 db,
+		log:                log,
+		mKeys:              stats.GetGauge("keys"),
+		mExpired:           stats.GetCounter("compaction.expired"),
+		mEvicted:           stats.GetCounter("compaction.evicted"),
+		mRewritten:         stats.GetCounter("compaction.rewritten"),
+		mCompErr:           stats.GetCounter("compaction.error"),
+		compactionInterval: compactionInterval,
+		closeChan:          make(chan struct{}),
+		closedChan:         make(chan struct{}),
+	}
+
+	go d.loop()
+	return d, nil
+}
+
+func openDiskDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{
+		Timeout: time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	if err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(diskBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create bucket: %v", err)
+	}
+	return db, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (d *Disk) view(fn func(b *bolt.Bucket) error) error {
+	d.dbMut.RLock()
+	defer d.dbMut.RUnlock()
+	return d.db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(diskBucket))
+	})
+}
+
+func (d *Disk) update(fn func(b *bolt.Bucket) error) error {
+	d.dbMut.RLock()
+	defer d.dbMut.RUnlock()
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(diskBucket))
+	})
+}
+
+func (d *Disk) resolveTTL(ttl *time.Duration) time.Duration {
+	if ttl != nil {
+		return jitterTTL(*ttl, d.ttlJitter)
+	}
+	return jitterTTL(d.ttl, d.ttlJitter)
+}
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist.
+func (d *Disk) Get(key string) ([]byte, error) {
+	var value []byte
+	err := d.view(func(b *bolt.Bucket) error {
+		var ok bool
+		if value, ok = decodeDiskItem(b.Get([]byte(key)), time.Now()); !ok {
+			return types.ErrKeyNotFound
+		}
+		return nil
+	})
+	return value, err
+}
+
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (d *Disk) GetMulti(keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	err := d.view(func(b *bolt.Bucket) error {
+		now := time.Now()
+		for _, k := range keys {
+			if value, ok := decodeDiskItem(b.Get([]byte(k)), now); ok {
+				values[k] = value
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// SetWithTTL attempts to set the value of a key.
+func (d *Disk) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return d.update(func(b *bolt.Bucket) error {
+		return b.Put([]byte(key), encodeDiskItem(value, d.resolveTTL(ttl), time.Now()))
+	})
+}
+
+// Set attempts to set the value of a key.
+func (d *Disk) Set(key string, value []byte) error {
+	return d.SetWithTTL(key, value, nil)
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys within a single
+// transaction, returns an error if any keys fail.
+func (d *Disk) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	return d.update(func(b *bolt.Bucket) error {
+		now := time.Now()
+		for k, v := range items {
+			if err := b.Put([]byte(k), encodeDiskItem(v.Value, d.resolveTTL(v.TTL), now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetMulti attempts to set the value of multiple keys within a single
+// transaction, returns an error if any keys fail.
+func (d *Disk) SetMulti(items map[string][]byte) error {
+	sitems := make(map[string]types.CacheTTLItem, len(items))
+	for k, v := range items {
+		sitems[k] = types.CacheTTLItem{
+			Value: v,
+		}
+	}
+	return d.SetMultiWithTTL(sitems)
+}
+
+// AddWithTTL attempts to set the value of a key only if the key does not
+// already exist or has expired, and returns an error if the key already exists.
+func (d *Disk) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	return d.update(func(b *bolt.Bucket) error {
+		now := time.Now()
+		if _, exists := decodeDiskItem(b.Get([]byte(key)), now); exists {
+			return types.ErrKeyAlreadyExists
+		}
+		return b.Put([]byte(key), encodeDiskItem(value, d.resolveTTL(ttl), now))
+	})
+}
+
+// Add attempts to set the value of a key only if the key does not already
+// exist or has expired, and returns an error if the key already exists.
+func (d *Disk) Add(key string, value []byte) error {
+	return d.AddWithTTL(key, value, nil)
+}
+
+// Delete attempts to remove a key.
+func (d *Disk) Delete(key string) error {
+	return d.update(func(b *bolt.Bucket) error {
+		return b.Delete([]byte(key))
+	})
+}
+
+//------------------------------------------------------------------------------
+
+func (d *Disk) loop() {
+	defer close(d.closedChan)
+
+	ticker := time.NewTicker(d.compactionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := d.compact(time.Now()); err != nil {
+				d.mCompErr.Incr(1)
+				d.log.Errorf("Failed to compact database: %v\n", err)
+			}
+		case <-d.closeChan:
+			d.dbMut.Lock()
+			if err := d.db.Close(); err != nil {
+				d.log.Errorf("Failed to close database: %v\n", err)
+			}
+			d.dbMut.Unlock()
+			return
+		}
+	}
+}
+
+type diskItemAge struct {
+	key     []byte
+	size    int64
+	written int64
+}
+
+// compact removes expired items, removes the oldest items when the size limit
+// is exceeded, and rewrites the database file when it is mostly free space.
+func (d *Disk) compact(now time.Time) error {
+	var items []diskItemAge
+	var expired [][]byte
+	var liveSize, fileSize int64
+
+	if err := d.view(func(b *bolt.Bucket) error {
+		fileSize = b.Tx().Size()
+		return b.ForEach(func(k, v []byte) error {
+			key := make([]byte, len(k))
+			copy(key, k)
+			if _, ok := decodeDiskItem(v, now); !ok {
+				expired = append(expired, key)
+				return nil
+			}
+			size := int64(len(k) + len(v) - diskHeaderLen)
+			liveSize += size
+			if d.maxSize > 0 {
+				items = append(items, diskItemAge{
+					key:     key,
+					size:    size,
+					written: diskItemWritten(v),
+				})
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	var evicted [][]byte
+	if d.maxSize > 0 && liveSize > d.maxSize {
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].written < items[j].written
+		})
+		for _, item := range items {
+			if liveSize <= d.maxSize {
+				break
+			}
+			evicted = append(evicted, item.key)
+			liveSize -= item.size
+		}
+	}
+
+	if len(expired) > 0 || len(evicted) > 0 {
+		if err := d.update(func(b *bolt.Bucket) error {
+			for _, keys := range [][][]byte{expired, evicted} {
+				for _, k := range keys {
+					if err := b.Delete(k); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		d.mExpired.Incr(int64(len(expired)))
+		d.mEvicted.Incr(int64(len(evicted)))
+	}
+	d.mKeys.Set(int64(len(items) - len(evicted)))
+
+	// Small databases aren't worth rewriting.
+	const minRewriteSize = 1 << 24
+	if fileSize > minRewriteSize && fileSize > liveSize*2 {
+		return d.rewrite()
+	}
+	return nil
+}
+
+// rewrite copies all items into a new database file and replaces the existing
+// file with it, which blocks all other operations until complete.
+func (d *Disk) rewrite() error {
+	d.dbMut.Lock()
+	defer d.dbMut.Unlock()
+
+	tmpPath := d.path + ".compact"
+	os.Remove(tmpPath)
+
+	tmpDB, err := bolt.Open(tmpPath, 0600, &bolt.Options{
+		Timeout: time.Second,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = d.db.View(func(srcTx *bolt.Tx) error {
+		return tmpDB.Update(func(dstTx *bolt.Tx) error {
+			dst, err := dstTx.CreateBucketIfNotExists(diskBucket)
+			if err != nil {
+				return err
+			}
+			dst.FillPercent = 1
+			return srcTx.Bucket(diskBucket).ForEach(func(k, v []byte) error {
+				return dst.Put(k, v)
+			})
+		})
+	}); err != nil {
+		tmpDB.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = tmpDB.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err = d.db.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpPath, d.path); err != nil {
+		// Fall back to the original file.
+		os.Remove(tmpPath)
+	}
+
+	db, openErr := openDiskDB(d.path)
+	if openErr != nil {
+		return openErr
+	}
+	d.db = db
+	if err == nil {
+		d.mRewritten.Incr(1)
+	}
+	return err
+}
+
+// CloseAsync shuts down the cache.
+func (d *Disk) CloseAsync() {
+	d.closeOnce.Do(func() {
+		close(d.closeChan)
+	})
+}
+
+// WaitForClose blocks until the cache has closed down.
+func (d *Disk) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
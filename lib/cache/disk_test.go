@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDisk(t *testing.T, path string, fn func(c *DiskConfig)) *Disk {
+	t.Helper()
+
+	conf := NewConfig()
+	conf.Type = TypeDisk
+	conf.Disk.Path = path
+	if fn != nil {
+		fn(&conf.Disk)
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return c.(*Disk)
+}
+
+func closeDisk(t *testing.T, d *Disk) {
+	t.Helper()
+	d.CloseAsync()
+	require.NoError(t, d.WaitForClose(time.Second))
+}
+
+func TestDiskCacheStandard(t *testing.T) {
+	d := testDisk(t, filepath.Join(t.TempDir(), "cache.db"), nil)
+	defer closeDisk(t, d)
+
+	_, err := d.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	require.NoError(t, d.Set("foo", []byte("1")))
+	require.NoError(t, d.SetMulti(map[string][]byte{
+		"bar": []byte("2"),
+		"baz": []byte("3"),
+	}))
+
+	v, err := d.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(v))
+
+	values, err := d.GetMulti("foo", "bar", "nope")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("1"),
+		"bar": []byte("2"),
+	}, values)
+
+	assert.Equal(t, types.ErrKeyAlreadyExists, d.Add("foo", []byte("4")))
+	require.NoError(t, d.Add("qux", []byte("5")))
+
+	require.NoError(t, d.Delete("foo"))
+	_, err = d.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+}
+
+func TestDiskCacheExpiry(t *testing.T) {
+	d := testDisk(t, filepath.Join(t.TempDir(), "cache.db"), func(c *DiskConfig) {
+		c.TTL = "1h"
+	})
+	defer closeDisk(t, d)
+
+	ttl := time.Millisecond * 10
+	require.NoError(t, d.SetWithTTL("foo", []byte("1"), &ttl))
+	require.NoError(t, d.Set("bar", []byte("2")))
+
+	_, err := d.Get("foo")
+	require.NoError(t, err)
+
+	<-time.After(ttl * 2)
+
+	_, err = d.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	// Expired keys can be added again.
+	require.NoError(t, d.AddWithTTL("foo", []byte("3"), nil))
+	v, err := d.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "3", string(v))
+}
+
+func TestDiskCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	d := testDisk(t, path, nil)
+	require.NoError(t, d.Set("foo", []byte("1")))
+	closeDisk(t, d)
+
+	d = testDisk(t, path, nil)
+	defer closeDisk(t, d)
+
+	v, err := d.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(v))
+}
+
+func TestDiskCacheCompaction(t *testing.T) {
+	d := testDisk(t, filepath.Join(t.TempDir(), "cache.db"), func(c *DiskConfig) {
+		c.MaxSize = 20
+	})
+	defer closeDisk(t, d)
+
+	ttl := time.Millisecond
+	require.NoError(t, d.SetWithTTL("expired", []byte("0"), &ttl))
+	for _, k := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, d.Set(k, []byte("foobar")))
+		<-time.After(time.Millisecond)
+	}
+
+	require.NoError(t, d.compact(time.Now()))
+
+	values, err := d.GetMulti("a", "b", "c", "d")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"c": []byte("foobar"),
+		"d": []byte("foobar"),
+	}, values)
+
+	require.NoError(t, d.rewrite())
+
+	values, err = d.GetMulti("a", "b", "c", "d")
+	require.NoError(t, err)
+	assert.Len(t, values, 2)
+}
+
+func TestDiskCacheBadConfig(t *testing.T) {
+	tests := map[string]func(c *DiskConfig){
+		"no path": func(c *DiskConfig) {
+			c.Path = ""
+		},
+		"bad ttl": func(c *DiskConfig) {
+			c.TTL = "nope"
+		},
+		"bad compaction interval": func(c *DiskConfig) {
+			c.CompactionInterval = "nope"
+		},
+		"zero compaction interval": func(c *DiskConfig) {
+			c.CompactionInterval = "0s"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeDisk
+		conf.Disk.Path = filepath.Join(t.TempDir(), "cache.db")
+		fn(&conf.Disk)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
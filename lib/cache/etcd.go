@@ -0,0 +1,368 @@
+package cache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeEtcd] = TypeSpec{
+		constructor:       NewEtcd,
+		SupportsPerKeyTTL: true,
+		Status:            docs.StatusExperimental,
+		Version:           "3.39.0",
+		Summary: `
+Stores key/value pairs in an [etcd](https://etcd.io/) cluster.`,
+		Description: `
+This cache is useful where etcd is already the only highly available store
+available, such as within Kubernetes edge clusters. Since etcd is designed for
+small amounts of data that changes infrequently it is not appropriate for high
+volumes of large items.
+
+The add command is performed within a transaction that only succeeds when the
+key does not exist, and is therefore atomic and suitable for deduplication.
+
+### Expiry
+
+Items with a TTL are attached to an etcd lease that is granted when the item is
+set, and are removed by etcd once the lease expires. Leases have a granularity
+of seconds and TTLs are therefore rounded up to the nearest second.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("endpoints", "A list of endpoints of the etcd cluster.", []string{"etcd-0:2379", "etcd-1:2379", "etcd-2:2379"}),
+			docs.FieldCommon("prefix", "An optional string to prefix item keys with in order to prevent collisions with similar services."),
+			docs.FieldCommon("ttl", "An optional TTL to set for items, after this period items are removed.", "60s", "72h"),
+			ttlJitterFieldSpec(),
+			docs.FieldAdvanced("username", "An optional username to authenticate with."),
+			docs.FieldAdvanced("password", "An optional password to authenticate with."),
+			btls.FieldSpec(),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a connection or a request to complete."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// EtcdConfig contains config fields for the etcd cache type.
+type EtcdConfig struct {
+	Endpoints []string    `json:"endpoints" yaml:"endpoints"`
+	Prefix    string      `json:"prefix" yaml:"prefix"`
+	TTL       string      `json:"ttl" yaml:"ttl"`
+	TTLJitter float64     `json:"ttl_jitter" yaml:"ttl_jitter"`
+	Username  string      `json:"username" yaml:"username"`
+	Password  string      `json:"password" yaml:"password"`
+	TLS       btls.Config `json:"tls" yaml:"tls"`
+	Timeout   string      `json:"timeout" yaml:"timeout"`
+}
+
+// NewEtcdConfig creates an EtcdConfig populated with default values.
+func NewEtcdConfig() EtcdConfig {
+	return EtcdConfig{
+		Endpoints: []string{"localhost:2379"},
+		Prefix:    "",
+		TTL:       "",
+		TTLJitter: 0,
+		Username:  "",
+		Password:  "",
+		TLS:       btls.NewConfig(),
+		Timeout:   "5s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Etcd is a cache that stores items in an etcd cluster.
+type Etcd struct {
+	client    *clientv3.Client
+	prefix    string
+	ttl       time.Duration
+	ttlJitter float64
+	timeout   time.Duration
+
+	log log.Modular
+
+	mLatency     metrics.StatTimer
+	mGetCount    metrics.StatCounter
+	mGetNotFound metrics.StatCounter
+	mGetFailed   metrics.StatCounter
+	mSetCount    metrics.StatCounter
+	mSetFailed   metrics.StatCounter
+	mAddCount    metrics.StatCounter
+	mAddDupe     metrics.StatCounter
+	mAddFailed   metrics.StatCounter
+	mDelCount    metrics.StatCounter
+	mDelFailed   metrics.StatCounter
+}
+
+// NewEtcd creates a new etcd cache type.
+func NewEtcd(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+	var endpoints []string
+	for _, e := range conf.Etcd.Endpoints {
+		for _, splitE := range strings.Split(e, ",") {
+			if len(splitE) > 0 {
+				endpoints = append(endpoints, splitE)
+			}
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one endpoint must be specified")
+	}
+
+	var ttl time.Duration
+	if conf.Etcd.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(conf.Etcd.TTL); err != nil {
+			return nil, fmt.Errorf("failed to parse ttl duration: %v", err)
+		}
+	}
+	if err := checkTTLJitter(conf.Etcd.TTLJitter); err != nil {
+		return nil, err
+	}
+
+	timeout, err := time.ParseDuration(conf.Etcd.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout duration: %v", err)
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be a positive duration")
+	}
+
+	var tlsConf *tls.Config
+	if conf.Etcd.TLS.Enabled {
+		if tlsConf, err = conf.Etcd.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: timeout,
+		Username:    conf.Etcd.Username,
+		Password:    conf.Etcd.Password,
+		TLS:         tlsConf,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %v", err)
+	}
+
+	return &Etcd{
+		client:    client,
+		prefix:    conf.Etcd.Prefix,
+		ttl:       ttl,
+		ttlJitter: conf.Etcd.TTLJitter,
+		timeout:   timeout,
+		log:       log,
+
+		mLatency:     stats.GetTimer("latency"),
+		mGetCount:    stats.GetCounter("get.count"),
+		mGetNotFound: stats.GetCounter("get.failed.not_found"),
+		mGetFailed:   stats.GetCounter("get.failed.error"),
+		mSetCount:    stats.GetCounter("set.count"),
+		mSetFailed:   stats.GetCounter("set.failed.error"),
+		mAddCount:    stats.GetCounter("add.count"),
+		mAddDupe:     stats.GetCounter("add.failed.duplicate"),
+		mAddFailed:   stats.GetCounter("add.failed.error"),
+		mDelCount:    stats.GetCounter("delete.count"),
+		mDelFailed:   stats.GetCounter("delete.failed.error"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// etcdLeaseSeconds returns the number of seconds of a lease for a TTL, rounded
+// up to the nearest second, or zero when items do not expire.
+func etcdLeaseSeconds(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(ttl.Seconds()))
+}
+
+// putOptions grants a lease for the TTL of an item when it expires, and returns
+// the options to put the item with along with the ID of the granted lease.
+func (e *Etcd) putOptions(ctx context.Context, ttl *time.Duration) ([]clientv3.OpOption, clientv3.LeaseID, error) {
+	t := e.ttl
+	if ttl != nil {
+		t = *ttl
+	}
+	seconds := etcdLeaseSeconds(jitterTTL(t, e.ttlJitter))
+	if seconds == 0 {
+		return nil, clientv3.NoLease, nil
+	}
+	lease, err := e.client.Grant(ctx, seconds)
+	if err != nil {
+		return nil, clientv3.NoLease, fmt.Errorf("failed to grant lease: %v", err)
+	}
+	return []clientv3.OpOption{clientv3.WithLease(lease.ID)}, lease.ID, nil
+}
+
+// revoke removes a lease that was granted for an item that was not stored.
+func (e *Etcd) revoke(id clientv3.LeaseID) {
+	if id == clientv3.NoLease {
+		return
+	}
+	ctx, done := context.WithTimeout(context.Background(), e.timeout)
+	defer done()
+	if _, err := e.client.Revoke(ctx, id); err != nil {
+		e.log.Debugf("Failed to revoke lease: %v\n", err)
+	}
+}
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist or if the operation failed.
+func (e *Etcd) Get(key string) ([]byte, error) {
+	e.mGetCount.Incr(1)
+	tStarted := time.Now()
+
+	ctx, done := context.WithTimeout(context.Background(), e.timeout)
+	defer done()
+
+	res, err := e.client.Get(ctx, e.prefix+key)
+	e.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		e.mGetFailed.Incr(1)
+		return nil, err
+	}
+	if len(res.Kvs) == 0 {
+		e.mGetNotFound.Incr(1)
+		return nil, types.ErrKeyNotFound
+	}
+	return res.Kvs[0].Value, nil
+}
+
+// SetWithTTL attempts to set the value of a key.
+func (e *Etcd) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	e.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	ctx, done := context.WithTimeout(context.Background(), e.timeout)
+	defer done()
+
+	opts, leaseID, err := e.putOptions(ctx, ttl)
+	if err == nil {
+		if _, err = e.client.Put(ctx, e.prefix+key, string(value), opts...); err != nil {
+			e.revoke(leaseID)
+		}
+	}
+	e.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		e.mSetFailed.Incr(1)
+	}
+	return err
+}
+
+// Set attempts to set the value of a key.
+func (e *Etcd) Set(key string, value []byte) error {
+	return e.SetWithTTL(key, value, nil)
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys, returns an error
+// if any keys fail.
+func (e *Etcd) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := e.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetMulti attempts to set the value of multiple keys, returns an error if any
+// keys fail.
+func (e *Etcd) SetMulti(items map[string][]byte) error {
+	sitems := make(map[string]types.CacheTTLItem, len(items))
+	for k, v := range items {
+		sitems[k] = types.CacheTTLItem{
+			Value: v,
+		}
+	}
+	return e.SetMultiWithTTL(sitems)
+}
+
+// AddWithTTL attempts to set the value of a key only if the key does not
+// already exist, and returns an error if the key already exists or if the
+// operation fails.
+func (e *Etcd) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	e.mAddCount.Incr(1)
+	tStarted := time.Now()
+
+	ctx, done := context.WithTimeout(context.Background(), e.timeout)
+	defer done()
+
+	key = e.prefix + key
+
+	opts, leaseID, err := e.putOptions(ctx, ttl)
+	var res *clientv3.TxnResponse
+	if err == nil {
+		res, err = e.client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, string(value), opts...)).
+			Commit()
+	}
+	e.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		e.revoke(leaseID)
+		e.mAddFailed.Incr(1)
+		return err
+	}
+	if !res.Succeeded {
+		e.revoke(leaseID)
+		e.mAddDupe.Incr(1)
+		return types.ErrKeyAlreadyExists
+	}
+	return nil
+}
+
+// Add attempts to set the value of a key only if the key does not already
+// exist, and returns an error if the key already exists or if the operation
+// fails.
+func (e *Etcd) Add(key string, value []byte) error {
+	return e.AddWithTTL(key, value, nil)
+}
+
+// Delete attempts to remove a key.
+func (e *Etcd) Delete(key string) error {
+	e.mDelCount.Incr(1)
+	tStarted := time.Now()
+
+	ctx, done := context.WithTimeout(context.Background(), e.timeout)
+	defer done()
+
+	_, err := e.client.Delete(ctx, e.prefix+key)
+	e.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		e.mDelFailed.Incr(1)
+	}
+	return err
+}
+
+// CloseAsync shuts down the cache.
+func (e *Etcd) CloseAsync() {
+	if err := e.client.Close(); err != nil {
+		e.log.Errorf("Failed to close client: %v\n", err)
+	}
+}
+
+// WaitForClose blocks until the cache has closed down.
+func (e *Etcd) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEtcdBadConfig(t *testing.T) {
+	tests := map[string]func(c *EtcdConfig){
+		"no endpoints": func(c *EtcdConfig) {
+			c.Endpoints = []string{""}
+		},
+		"bad ttl": func(c *EtcdConfig) {
+			c.TTL = "nope"
+		},
+		"bad ttl jitter": func(c *EtcdConfig) {
+			c.TTLJitter = 2
+		},
+		"zero timeout": func(c *EtcdConfig) {
+			c.Timeout = "0s"
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf := NewConfig()
+			conf.Type = TypeEtcd
+			test(&conf.Etcd)
+			_, err := New(conf, nil, log.Noop(), metrics.Noop())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEtcdLeaseSeconds(t *testing.T) {
+	assert.Equal(t, int64(0), etcdLeaseSeconds(0))
+	assert.Equal(t, int64(1), etcdLeaseSeconds(time.Millisecond*10))
+	assert.Equal(t, int64(60), etcdLeaseSeconds(time.Minute))
+	assert.Equal(t, int64(61), etcdLeaseSeconds(time.Minute+time.Millisecond))
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+)
+
+//------------------------------------------------------------------------------
+
+func ttlJitterFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"ttl_jitter",
+		"A fraction between 0 and 1 by which the TTL of each item is randomly reduced, which spreads out the expiry of items that are cached at the same time and therefore avoids a stampede of requests when they expire. For example, a TTL of `1h` with a jitter of `0.1` results in items expiring between 54 and 60 minutes after being set.",
+		0.1, 0.25,
+	).AtVersion("3.39.0")
+}
+
+func checkTTLJitter(jitter float64) error {
+	if jitter < 0 || jitter > 1 {
+		return errors.New("ttl_jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// jitterTTL reduces a TTL by a random amount of up to a fraction of it. TTLs of
+// zero or less mean that an item does not expire and are returned unchanged.
+func jitterTTL(ttl time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl - time.Duration(rand.Float64()*jitter*float64(ttl))
+}
+
+//------------------------------------------------------------------------------
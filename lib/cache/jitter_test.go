@@ -0,0 +1,31 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterTTL(t *testing.T) {
+	assert.Equal(t, time.Hour, jitterTTL(time.Hour, 0))
+	assert.Equal(t, time.Duration(0), jitterTTL(0, 0.5))
+
+	for i := 0; i < 100; i++ {
+		ttl := jitterTTL(time.Hour, 0.1)
+		assert.True(t, ttl <= time.Hour, ttl)
+		assert.True(t, ttl >= time.Minute*54, ttl)
+	}
+}
+
+func TestTTLJitterBadConfig(t *testing.T) {
+	for _, jitter := range []float64{-0.1, 1.5} {
+		conf := NewConfig()
+		conf.Type = TypeRistretto
+		conf.Ristretto.TTLJitter = jitter
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, jitter)
+	}
+}
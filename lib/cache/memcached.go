@@ -1,9 +1,12 @@
 package cache
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -22,10 +25,34 @@ func init() {
 		Summary: `
 Connects to a cluster of memcached services, a prefix can be specified to allow
 multiple cache types to share a memcached cluster under different namespaces.`,
+		Description: `
+### Distribution
+
+By default keys are distributed across servers by the modulo of their hash,
+which means that most keys move to a different server when a server is added
+or removed. Setting ` + "`distribution`" + ` to ` + "`consistent`" + ` instead
+distributes keys with a consistent hash ring, where only the keys of an added or
+removed server are moved.
+
+### Auto Discovery
+
+When ` + "`auto_discovery.enabled`" + ` is set the ` + "`addresses`" + ` are
+used as [AWS ElastiCache configuration endpoints][elasticache-discovery], which
+are periodically queried for the nodes of the cluster, and the servers in use
+are updated as nodes are added and removed. This is best combined with the
+` + "`consistent`" + ` distribution.
+
+[elasticache-discovery]: https://docs.aws.amazon.com/AmazonElastiCache/latest/mem-ug/AutoDiscovery.html`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("addresses", "A list of addresses of memcached servers to use."),
 			docs.FieldCommon("prefix", "An optional string to prefix item keys with in order to prevent collisions with similar services."),
 			docs.FieldCommon("ttl", "A TTL in seconds to set for items, after this period keys will be removed."),
+			ttlJitterFieldSpec(),
+			docs.FieldAdvanced("distribution", "The method of distributing keys across servers.").HasOptions("modulo", "consistent").AtVersion("3.39.0"),
+			docs.FieldAdvanced("auto_discovery", "Discovers the servers of an AWS ElastiCache cluster from its configuration endpoints.").WithChildren(
+				docs.FieldAdvanced("enabled", "Whether to treat `addresses` as configuration endpoints and discover servers from them."),
+				docs.FieldAdvanced("interval", "The period of time between checks for changes to the nodes of the cluster."),
+			).AtVersion("3.39.0"),
 			docs.FieldAdvanced("retries", "The maximum number of retry attempts to make before abandoning a request."),
 			docs.FieldAdvanced("retry_period", "The duration to wait between retry attempts."),
 		},
@@ -34,21 +61,37 @@ multiple cache types to share a memcached cluster under different namespaces.`,
 
 //------------------------------------------------------------------------------
 
+// MemcachedAutoDiscoveryConfig contains configuration fields for discovering
+// the servers of an AWS ElastiCache memcached cluster.
+type MemcachedAutoDiscoveryConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Interval string `json:"interval" yaml:"interval"`
+}
+
 // MemcachedConfig is a config struct for a memcached connection.
 type MemcachedConfig struct {
-	Addresses   []string `json:"addresses" yaml:"addresses"`
-	Prefix      string   `json:"prefix" yaml:"prefix"`
-	TTL         int32    `json:"ttl" yaml:"ttl"`
-	Retries     int      `json:"retries" yaml:"retries"`
-	RetryPeriod string   `json:"retry_period" yaml:"retry_period"`
+	Addresses     []string                     `json:"addresses" yaml:"addresses"`
+	Prefix        string                       `json:"prefix" yaml:"prefix"`
+	TTL           int32                        `json:"ttl" yaml:"ttl"`
+	TTLJitter     float64                      `json:"ttl_jitter" yaml:"ttl_jitter"`
+	Distribution  string                       `json:"distribution" yaml:"distribution"`
+	AutoDiscovery MemcachedAutoDiscoveryConfig `json:"auto_discovery" yaml:"auto_discovery"`
+	Retries       int                          `json:"retries" yaml:"retries"`
+	RetryPeriod   string                       `json:"retry_period" yaml:"retry_period"`
 }
 
 // NewMemcachedConfig returns a MemcachedConfig with default values.
 func NewMemcachedConfig() MemcachedConfig {
 	return MemcachedConfig{
-		Addresses:   []string{"localhost:11211"},
-		Prefix:      "",
-		TTL:         300,
+		Addresses:    []string{"localhost:11211"},
+		Prefix:       "",
+		TTL:          300,
+		TTLJitter:    0,
+		Distribution: "modulo",
+		AutoDiscovery: MemcachedAutoDiscoveryConfig{
+			Enabled:  false,
+			Interval: "60s",
+		},
 		Retries:     3,
 		RetryPeriod: "500ms",
 	}
@@ -87,7 +130,16 @@ type Memcached struct {
 	mDelLatency    metrics.StatTimer
 
 	mc          *memcache.Client
+	selector    memcachedSelector
 	retryPeriod time.Duration
+
+	endpoints         []string
+	discoveryInterval time.Duration
+	mDiscoveryErr     metrics.StatCounter
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
 }
 
 // NewMemcached returns a Memcached processor.
@@ -109,7 +161,41 @@ func NewMemcached(
 			return nil, fmt.Errorf("failed to parse retry period string: %v", err)
 		}
 	}
-	return &Memcached{
+
+	if err := checkTTLJitter(conf.Memcached.TTLJitter); err != nil {
+		return nil, err
+	}
+
+	var selector memcachedSelector
+	switch conf.Memcached.Distribution {
+	case "modulo", "":
+		selector = &memcache.ServerList{}
+	case "consistent":
+		selector = &memcachedRing{}
+	default:
+		return nil, fmt.Errorf("unrecognised distribution: %v", conf.Memcached.Distribution)
+	}
+
+	var endpoints []string
+	var discoveryInterval time.Duration
+	if conf.Memcached.AutoDiscovery.Enabled {
+		var err error
+		if discoveryInterval, err = time.ParseDuration(conf.Memcached.AutoDiscovery.Interval); err != nil {
+			return nil, fmt.Errorf("failed to parse auto discovery interval string: %v", err)
+		}
+		if discoveryInterval <= 0 {
+			return nil, errors.New("auto discovery interval must be a positive duration")
+		}
+		endpoints = addresses
+		if addresses, err = discoverMemcachedServers(endpoints); err != nil {
+			return nil, fmt.Errorf("failed to discover cluster nodes: %v", err)
+		}
+	}
+	if err := selector.SetServers(addresses...); err != nil {
+		return nil, err
+	}
+
+	m := &Memcached{
 		conf:  conf,
 		log:   log,
 		stats: stats,
@@ -138,20 +224,85 @@ func NewMemcached(
 		mDelSuccess:    stats.GetCounter("delete.success"),
 		mDelLatency:    stats.GetTimer("delete.latency"),
 
+		mc:          memcache.NewFromSelector(selector),
+		selector:    selector,
 		retryPeriod: retryPeriod,
-		mc:          memcache.New(addresses...),
-	}, nil
+
+		endpoints:         endpoints,
+		discoveryInterval: discoveryInterval,
+		mDiscoveryErr:     stats.GetCounter("auto_discovery.error"),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	if len(endpoints) > 0 {
+		go m.discoveryLoop(addresses)
+	} else {
+		close(m.closedChan)
+	}
+	return m, nil
+}
+
+// The timeout of requests to configuration endpoints.
+const memcachedDiscoveryTimeout = time.Second * 5
+
+// discoverMemcachedServers returns the nodes of a cluster from the first
+// configuration endpoint that responds.
+func discoverMemcachedServers(endpoints []string) (servers []string, err error) {
+	for _, endpoint := range endpoints {
+		if servers, err = discoverMemcachedNodes(endpoint, memcachedDiscoveryTimeout); err == nil {
+			return
+		}
+	}
+	return
+}
+
+// discoveryLoop periodically queries the configuration endpoints of a cluster
+// and updates the servers in use when its nodes change.
+func (m *Memcached) discoveryLoop(servers []string) {
+	defer close(m.closedChan)
+
+	ticker := time.NewTicker(m.discoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.closeChan:
+			return
+		}
+
+		latest, err := discoverMemcachedServers(m.endpoints)
+		if err != nil {
+			m.mDiscoveryErr.Incr(1)
+			m.log.Errorf("Failed to discover cluster nodes: %v\n", err)
+			continue
+		}
+		if strings.Join(latest, ",") == strings.Join(servers, ",") {
+			continue
+		}
+		if err = m.selector.SetServers(latest...); err != nil {
+			m.mDiscoveryErr.Incr(1)
+			m.log.Errorf("Failed to update cluster nodes: %v\n", err)
+			continue
+		}
+		m.log.Infof("Cluster nodes changed to: %v\n", strings.Join(latest, ", "))
+		servers = latest
+	}
 }
 
 //------------------------------------------------------------------------------
 
 // getItemFor returns a memcache.Item object ready to be stored in memcache
 func (m *Memcached) getItemFor(key string, value []byte, ttl *time.Duration) *memcache.Item {
-	var expiration int32
+	t := time.Duration(m.conf.Memcached.TTL) * time.Second
 	if ttl != nil {
-		expiration = int32(ttl.Seconds())
-	} else {
-		expiration = m.conf.Memcached.TTL
+		t = *ttl
+	}
+	expiration := int32(jitterTTL(t, m.conf.Memcached.TTLJitter).Seconds())
+	if t >= time.Second && expiration < 1 {
+		// An expiration of zero means that the item never expires.
+		expiration = 1
 	}
 	return &memcache.Item{
 		Key:        m.conf.Memcached.Prefix + key,
@@ -190,6 +341,49 @@ func (m *Memcached) Get(key string) ([]byte, error) {
 	return item.Value, err
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys
+// with a single request per server, keys that do not exist are omitted from
+// the result.
+func (m *Memcached) GetMulti(keys ...string) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return map[string][]byte{}, nil
+	}
+
+	m.mGetCount.Incr(int64(len(keys)))
+	tStarted := time.Now()
+
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = m.conf.Memcached.Prefix + k
+	}
+
+	items, err := m.mc.GetMulti(prefixed)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+		m.log.Errorf("GetMulti command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mGetRetry.Incr(1)
+		items, err = m.mc.GetMulti(prefixed)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mGetLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if err != nil {
+		m.mGetFailed.Incr(int64(len(keys)))
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(items))
+	for i, k := range prefixed {
+		if item, exists := items[k]; exists {
+			values[keys[i]] = item.Value
+		}
+	}
+	m.mGetSuccess.Incr(int64(len(values)))
+	return values, nil
+}
+
 // SetWithTTL attempts to set the value of a key.
 func (m *Memcached) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	m.mSetCount.Incr(1)
@@ -293,6 +487,149 @@ func (m *Memcached) Add(key string, value []byte) error {
 	return m.AddWithTTL(key, value, nil)
 }
 
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old.
+func (m *Memcached) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	m.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	err := m.compareAndSwap(key, old, value, ttl)
+	for i := 0; i < m.conf.Memcached.Retries && m.shouldRetryAtomic(err); i++ {
+		m.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mSetRetry.Incr(1)
+		err = m.compareAndSwap(key, old, value, ttl)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mSetLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if m.shouldRetryAtomic(err) {
+		m.mSetFailed.Incr(1)
+	} else if err == nil {
+		m.mSetSuccess.Incr(1)
+	}
+	return err
+}
+
+func (m *Memcached) compareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	item, err := m.mc.Get(m.conf.Memcached.Prefix + key)
+	if err != nil {
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return types.ErrKeyNotFound
+		}
+		return err
+	}
+	if !bytes.Equal(item.Value, old) {
+		return types.ErrKeyValueMismatch
+	}
+
+	// The retrieved item carries the CAS token of the value that was compared.
+	newItem := m.getItemFor(key, value, ttl)
+	item.Value, item.Expiration = newItem.Value, newItem.Expiration
+	switch err = m.mc.CompareAndSwap(item); {
+	case errors.Is(err, memcache.ErrCASConflict):
+		return types.ErrKeyValueMismatch
+	case errors.Is(err, memcache.ErrNotStored), errors.Is(err, memcache.ErrCacheMiss):
+		return types.ErrKeyNotFound
+	}
+	return err
+}
+
+// shouldRetryAtomic returns whether an error from an atomic operation was a
+// failure of the command rather than an expected outcome.
+func (m *Memcached) shouldRetryAtomic(err error) bool {
+	return err != nil &&
+		err != types.ErrKeyNotFound &&
+		err != types.ErrKeyValueMismatch
+}
+
+// Increment atomically adds delta to the integer value of a key and returns
+// the result. Memcached values are unsigned and therefore decrements stop at
+// zero.
+func (m *Memcached) Increment(key string, delta int64, ttl *time.Duration) (int64, error) {
+	m.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	n, err := m.increment(key, delta, ttl)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil; i++ {
+		m.log.Errorf("Increment command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mSetRetry.Incr(1)
+		n, err = m.increment(key, delta, ttl)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mSetLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if err != nil {
+		m.mSetFailed.Incr(1)
+		return 0, err
+	}
+	m.mSetSuccess.Incr(1)
+	return n, nil
+}
+
+func (m *Memcached) increment(key string, delta int64, ttl *time.Duration) (int64, error) {
+	incr := func() (uint64, error) {
+		if delta < 0 {
+			return m.mc.Decrement(m.conf.Memcached.Prefix+key, uint64(-delta))
+		}
+		return m.mc.Increment(m.conf.Memcached.Prefix+key, uint64(delta))
+	}
+
+	n, err := incr()
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		initial := delta
+		if initial < 0 {
+			initial = 0
+		}
+		err = m.mc.Add(m.getItemFor(key, []byte(strconv.FormatInt(initial, 10)), ttl))
+		if err == nil {
+			return initial, nil
+		}
+		// The key was created by another client in the meantime.
+		if errors.Is(err, memcache.ErrNotStored) {
+			n, err = incr()
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// Touch resets the TTL of a key.
+func (m *Memcached) Touch(key string, ttl *time.Duration) error {
+	m.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	expiration := m.getItemFor(key, nil, ttl).Expiration
+	err := m.mc.Touch(m.conf.Memcached.Prefix+key, expiration)
+	for i := 0; i < m.conf.Memcached.Retries && err != nil && !errors.Is(err, memcache.ErrCacheMiss); i++ {
+		m.log.Errorf("Touch command failed: %v\n", err)
+		<-time.After(m.retryPeriod)
+		m.mSetRetry.Incr(1)
+		err = m.mc.Touch(m.conf.Memcached.Prefix+key, expiration)
+	}
+
+	latency := int64(time.Since(tStarted))
+	m.mSetLatency.Timing(latency)
+	m.mLatency.Timing(latency)
+
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return types.ErrKeyNotFound
+	}
+	if err != nil {
+		m.mSetFailed.Incr(1)
+		return err
+	}
+	m.mSetSuccess.Incr(1)
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (m *Memcached) Delete(key string) error {
 	m.mDelCount.Incr(1)
@@ -325,10 +662,18 @@ func (m *Memcached) Delete(key string) error {
 
 // CloseAsync shuts down the cache.
 func (m *Memcached) CloseAsync() {
+	m.closeOnce.Do(func() {
+		close(m.closeChan)
+	})
 }
 
 // WaitForClose blocks until the cache has closed down.
 func (m *Memcached) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-m.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+//------------------------------------------------------------------------------
+
+// memcachedSelector is a memcache.ServerSelector where the list of servers can
+// be replaced.
+type memcachedSelector interface {
+	memcache.ServerSelector
+	SetServers(servers ...string) error
+}
+
+// The number of points on the hash ring allocated to each server.
+const memcachedRingPoints = 160
+
+type memcachedRingPoint struct {
+	hash uint32
+	addr net.Addr
+}
+
+// memcachedRing is a memcachedSelector that distributes keys across servers
+// with a consistent hash ring, where adding or removing a server only moves
+// the keys of that server.
+type memcachedRing struct {
+	mut    sync.RWMutex
+	points []memcachedRingPoint
+	addrs  []net.Addr
+}
+
+func memcachedHash(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.LittleEndian.Uint32(sum[:4])
+}
+
+func resolveMemcachedAddr(server string) (net.Addr, error) {
+	if strings.Contains(server, "/") {
+		return net.ResolveUnixAddr("unix", server)
+	}
+	return net.ResolveTCPAddr("tcp", server)
+}
+
+// SetServers replaces the servers of the ring.
+func (r *memcachedRing) SetServers(servers ...string) error {
+	addrs := make([]net.Addr, 0, len(servers))
+	points := make([]memcachedRingPoint, 0, len(servers)*memcachedRingPoints)
+	for _, server := range servers {
+		addr, err := resolveMemcachedAddr(server)
+		if err != nil {
+			return err
+		}
+		addrs = append(addrs, addr)
+		for i := 0; i < memcachedRingPoints; i++ {
+			points = append(points, memcachedRingPoint{
+				hash: memcachedHash(server + "-" + strconv.Itoa(i)),
+				addr: addr,
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	r.mut.Lock()
+	r.points, r.addrs = points, addrs
+	r.mut.Unlock()
+	return nil
+}
+
+// PickServer returns the server that owns a key.
+func (r *memcachedRing) PickServer(key string) (net.Addr, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	if len(r.points) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	hash := memcachedHash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].addr, nil
+}
+
+// Each calls a function for each server of the ring.
+func (r *memcachedRing) Each(fn func(net.Addr) error) error {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	for _, addr := range r.addrs {
+		if err := fn(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// discoverMemcachedNodes obtains the list of nodes of an AWS ElastiCache
+// memcached cluster from its configuration endpoint.
+func discoverMemcachedNodes(endpoint string, timeout time.Duration) ([]string, error) {
+	conn, err := net.DialTimeout("tcp", endpoint, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if _, err = conn.Write([]byte("config get cluster\r\n")); err != nil {
+		return nil, err
+	}
+	return parseMemcachedClusterConfig(bufio.NewReader(conn))
+}
+
+// parseMemcachedClusterConfig parses the response of a config get cluster
+// command, which consists of a header line, a version line and a line of space
+// separated nodes in the form host|ip|port.
+func parseMemcachedClusterConfig(r *bufio.Reader) ([]string, error) {
+	readLine := func() (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	header, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(header, "CONFIG cluster") {
+		return nil, fmt.Errorf("unexpected cluster config response: %v", header)
+	}
+	if _, err = readLine(); err != nil {
+		return nil, err
+	}
+	nodesLine, err := readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []string
+	for _, node := range strings.Fields(nodesLine) {
+		parts := strings.Split(node, "|")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unexpected cluster node format: %v", node)
+		}
+		host := parts[1]
+		if host == "" {
+			host = parts[0]
+		}
+		nodes = append(nodes, net.JoinHostPort(host, parts[2]))
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("cluster config contained no nodes")
+	}
+
+	for {
+		line, err := readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "END" {
+			break
+		}
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemcachedRingStability(t *testing.T) {
+	servers := []string{"10.0.0.1:11211", "10.0.0.2:11211", "10.0.0.3:11211"}
+
+	ring := &memcachedRing{}
+	require.NoError(t, ring.SetServers(servers...))
+
+	keys := make([]string, 1000)
+	before := map[string]string{}
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%v", i)
+		addr, err := ring.PickServer(keys[i])
+		require.NoError(t, err)
+		before[keys[i]] = addr.String()
+	}
+
+	require.NoError(t, ring.SetServers(append(servers, "10.0.0.4:11211")...))
+
+	moved := 0
+	for _, k := range keys {
+		addr, err := ring.PickServer(k)
+		require.NoError(t, err)
+		if addr.String() != before[k] {
+			assert.Equal(t, "10.0.0.4:11211", addr.String())
+			moved++
+		}
+	}
+	assert.Greater(t, moved, 100)
+	assert.Less(t, moved, 400)
+
+	var eachAddrs []string
+	require.NoError(t, ring.Each(func(a net.Addr) error {
+		eachAddrs = append(eachAddrs, a.String())
+		return nil
+	}))
+	assert.Len(t, eachAddrs, 4)
+
+	require.NoError(t, ring.SetServers())
+	_, err := ring.PickServer("foo")
+	assert.Error(t, err)
+}
+
+func TestMemcachedParseClusterConfig(t *testing.T) {
+	input := "CONFIG cluster 0 147\r\n" +
+		"12\n" +
+		"myCluster.pc4ldq.0001.use1.cache.amazonaws.com|10.82.235.120|11211 myCluster.pc4ldq.0002.use1.cache.amazonaws.com||11212\n" +
+		"\r\n" +
+		"END\r\n"
+
+	nodes, err := parseMemcachedClusterConfig(bufio.NewReader(strings.NewReader(input)))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"10.82.235.120:11211",
+		"myCluster.pc4ldq.0002.use1.cache.amazonaws.com:11212",
+	}, nodes)
+
+	_, err = parseMemcachedClusterConfig(bufio.NewReader(strings.NewReader("ERROR\r\n")))
+	assert.Error(t, err)
+}
+
+func TestMemcachedAutoDiscovery(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	var nodesMut sync.Mutex
+	nodes := "host1|127.0.0.1|11211"
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+					return
+				}
+				nodesMut.Lock()
+				body := "1\n" + nodes + "\n"
+				nodesMut.Unlock()
+				fmt.Fprintf(conn, "CONFIG cluster 0 %v\r\n%v\r\nEND\r\n", len(body), body)
+			}(conn)
+		}
+	}()
+
+	conf := NewConfig()
+	conf.Type = TypeMemcached
+	conf.Memcached.Addresses = []string{ln.Addr().String()}
+	conf.Memcached.Distribution = "consistent"
+	conf.Memcached.AutoDiscovery.Enabled = true
+	conf.Memcached.AutoDiscovery.Interval = "10ms"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	servers := func() []string {
+		var addrs []string
+		c.(*Memcached).selector.Each(func(a net.Addr) error {
+			addrs = append(addrs, a.String())
+			return nil
+		})
+		return addrs
+	}
+	assert.Equal(t, []string{"127.0.0.1:11211"}, servers())
+
+	nodesMut.Lock()
+	nodes = "host1|127.0.0.1|11211 host2|127.0.0.1|11212"
+	nodesMut.Unlock()
+
+	assert.Eventually(t, func() bool {
+		return len(servers()) == 2
+	}, time.Second, time.Millisecond*10)
+
+	c.CloseAsync()
+	require.NoError(t, c.WaitForClose(time.Second))
+}
+
+func TestMemcachedBadConfig(t *testing.T) {
+	tests := map[string]func(c *MemcachedConfig){
+		"bad distribution": func(c *MemcachedConfig) {
+			c.Distribution = "nope"
+		},
+		"bad discovery interval": func(c *MemcachedConfig) {
+			c.AutoDiscovery.Enabled = true
+			c.AutoDiscovery.Interval = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeMemcached
+		fn(&conf.Memcached)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
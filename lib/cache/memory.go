@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"bytes"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -197,6 +199,22 @@ func (m *Memory) Get(key string) ([]byte, error) {
 	return k.value, nil
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (m *Memory) GetMulti(keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		shard := m.getShard(key)
+		shard.RLock()
+		k, exists := shard.items[key]
+		shard.RUnlock()
+		if exists {
+			values[key] = k.value
+		}
+	}
+	return values, nil
+}
+
 // Set attempts to set the value of a key.
 func (m *Memory) Set(key string, value []byte) error {
 	shard := m.getShard(key)
@@ -235,6 +253,65 @@ func (m *Memory) Add(key string, value []byte) error {
 	return nil
 }
 
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old. The ttl is ignored as items share the TTL of the cache.
+func (m *Memory) CompareAndSwap(key string, old, value []byte, _ *time.Duration) error {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	k, exists := shard.items[key]
+	if !exists {
+		return types.ErrKeyNotFound
+	}
+	if !bytes.Equal(k.value, old) {
+		return types.ErrKeyValueMismatch
+	}
+	shard.compaction()
+	shard.items[key] = item{value: value, ts: time.Now()}
+	shard.mKeys.Set(int64(len(shard.items)))
+	return nil
+}
+
+// Increment atomically adds delta to the integer value of a key and returns
+// the result. The ttl is ignored as items share the TTL of the cache.
+func (m *Memory) Increment(key string, delta int64, _ *time.Duration) (int64, error) {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	n := delta
+	if k, exists := shard.items[key]; exists {
+		current, err := strconv.ParseInt(string(k.value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value is not an integer: %v", err)
+		}
+		n += current
+	}
+	shard.compaction()
+	shard.items[key] = item{value: []byte(strconv.FormatInt(n, 10)), ts: time.Now()}
+	shard.mKeys.Set(int64(len(shard.items)))
+	return n, nil
+}
+
+// Touch resets the TTL of a key. The ttl is ignored as items share the TTL of
+// the cache, and items from init_values remain exempt from TTLs.
+func (m *Memory) Touch(key string, _ *time.Duration) error {
+	shard := m.getShard(key)
+	shard.Lock()
+	defer shard.Unlock()
+
+	k, exists := shard.items[key]
+	if !exists {
+		return types.ErrKeyNotFound
+	}
+	if !k.ts.IsZero() {
+		k.ts = time.Now()
+		shard.items[key] = k
+	}
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (m *Memory) Delete(key string) error {
 	shard := m.getShard(key)
@@ -237,3 +237,65 @@ func BenchmarkMemoryShards100(b *testing.B) {
 		assert.Equal(b, value, res)
 	}
 }
+
+func TestMemoryCacheGetMulti(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+	conf.Memory.InitValues = map[string]string{
+		"foo": "bar",
+		"baz": "buz",
+	}
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mc, ok := c.(types.CacheMultiGetter)
+	require.True(t, ok)
+
+	values, err := mc.GetMulti("foo", "nope", "baz")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+		"baz": []byte("buz"),
+	}, values)
+}
+
+func TestMemoryCacheAtomicOperations(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "memory"
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	cas, ok := c.(types.CacheCompareAndSwapper)
+	require.True(t, ok)
+
+	assert.Equal(t, types.ErrKeyNotFound, cas.CompareAndSwap("foo", []byte("a"), []byte("b"), nil))
+	require.NoError(t, c.Set("foo", []byte("a")))
+	assert.Equal(t, types.ErrKeyValueMismatch, cas.CompareAndSwap("foo", []byte("c"), []byte("b"), nil))
+	require.NoError(t, cas.CompareAndSwap("foo", []byte("a"), []byte("b"), nil))
+
+	v, err := c.Get("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(v))
+
+	incr, ok := c.(types.CacheIncrementer)
+	require.True(t, ok)
+
+	n, err := incr.Increment("counter", 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+
+	n, err = incr.Increment("counter", -7, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(-2), n)
+
+	_, err = incr.Increment("foo", 1, nil)
+	assert.Error(t, err)
+
+	toucher, ok := c.(types.CacheToucher)
+	require.True(t, ok)
+
+	assert.Equal(t, types.ErrKeyNotFound, toucher.Touch("nope", nil))
+	require.NoError(t, toucher.Touch("foo", nil))
+}
@@ -1,12 +1,15 @@
 package cache
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -56,63 +59,259 @@ resources:
 ` + "```" + `
 
 Using this config when a target key already exists in our local memory cache we
-won't bother hitting the remote memcached instance.`,
+won't bother hitting the remote memcached instance.
+
+## Level Options
+
+Options can be set for individual levels by instead configuring the levels
+within the field ` + "`levels`" + `, and the options within the field
+` + "`level_options`" + `, which maps the name of a level to the fields
+` + "`ttl`" + ` and ` + "`write`" + `:
+
+` + "```yaml" + `
+resources:
+  caches:
+    leveled:
+      multilevel:
+        levels: [ hot, cold, archive ]
+        level_options:
+          hot:
+            ttl: 30s
+          archive:
+            write: false
+` + "```" + `
+
+When ` + "`ttl`" + ` is set it overrides the TTL of items written to the
+level, including those populated by reads from lower levels, which allows each
+level to hold items for a different period. Levels with ` + "`write`" + ` set
+to ` + "`false`" + ` are not written to by set and add operations, but are still
+read from and populated by reads from lower levels. For the Add command the last
+level that is written to is the level at which the key is added.`,
 	}
 }
 
 //------------------------------------------------------------------------------
 
 // MultilevelConfig contains config fields for the Multilevel cache type.
-type MultilevelConfig []string
+type MultilevelConfig struct {
+	Levels       []string                         `json:"levels" yaml:"levels"`
+	LevelOptions map[string]MultilevelLevelConfig `json:"level_options" yaml:"level_options"`
+}
 
 // NewMultilevelConfig creates a MultilevelConfig populated with default values.
 func NewMultilevelConfig() MultilevelConfig {
-	return []string{}
+	return MultilevelConfig{
+		Levels:       []string{},
+		LevelOptions: map[string]MultilevelLevelConfig{},
+	}
+}
+
+// UnmarshalJSON parses either a list of levels or an object containing levels
+// and their options.
+func (m *MultilevelConfig) UnmarshalJSON(bytes []byte) error {
+	conf := NewMultilevelConfig()
+	if err := json.Unmarshal(bytes, &conf.Levels); err == nil {
+		*m = conf
+		return nil
+	}
+	type confAlias MultilevelConfig
+	aliased := confAlias(conf)
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+	*m = MultilevelConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML parses either a list of levels or an object containing levels
+// and their options.
+func (m *MultilevelConfig) UnmarshalYAML(value *yaml.Node) error {
+	conf := NewMultilevelConfig()
+	if value.Kind == yaml.SequenceNode {
+		if err := value.Decode(&conf.Levels); err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+		*m = conf
+		return nil
+	}
+	type confAlias MultilevelConfig
+	aliased := confAlias(conf)
+	if err := value.Decode(&aliased); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+	*m = MultilevelConfig(aliased)
+	return nil
+}
+
+// MarshalJSON marshals the config as a list of levels when there are no level
+// options.
+func (m MultilevelConfig) MarshalJSON() ([]byte, error) {
+	if len(m.LevelOptions) == 0 {
+		return json.Marshal(m.levelsList())
+	}
+	type confAlias MultilevelConfig
+	return json.Marshal(confAlias(m))
+}
+
+// MarshalYAML marshals the config as a list of levels when there are no level
+// options.
+func (m MultilevelConfig) MarshalYAML() (interface{}, error) {
+	if len(m.LevelOptions) == 0 {
+		return m.levelsList(), nil
+	}
+	type confAlias MultilevelConfig
+	return confAlias(m), nil
+}
+
+func (m MultilevelConfig) levelsList() []string {
+	if m.Levels == nil {
+		return []string{}
+	}
+	return m.Levels
+}
+
+// MultilevelLevelConfig contains config fields for a single level of the
+// Multilevel cache type.
+type MultilevelLevelConfig struct {
+	TTL   string `json:"ttl" yaml:"ttl"`
+	Write bool   `json:"write" yaml:"write"`
+}
+
+// NewMultilevelLevelConfig creates a MultilevelLevelConfig populated with
+// default values.
+func NewMultilevelLevelConfig() MultilevelLevelConfig {
+	return MultilevelLevelConfig{
+		TTL:   "",
+		Write: true,
+	}
+}
+
+// UnmarshalJSON ensures that when parsing a level the default values are still
+// applied.
+func (l *MultilevelLevelConfig) UnmarshalJSON(bytes []byte) error {
+	type confAlias MultilevelLevelConfig
+	aliased := confAlias(NewMultilevelLevelConfig())
+	if err := json.Unmarshal(bytes, &aliased); err != nil {
+		return err
+	}
+	*l = MultilevelLevelConfig(aliased)
+	return nil
+}
+
+// UnmarshalYAML ensures that when parsing a level the default values are still
+// applied.
+func (l *MultilevelLevelConfig) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias MultilevelLevelConfig
+	aliased := confAlias(NewMultilevelLevelConfig())
+	if err := value.Decode(&aliased); err != nil {
+		return fmt.Errorf("line %v: %v", value.Line, err)
+	}
+	*l = MultilevelLevelConfig(aliased)
+	return nil
 }
 
 //------------------------------------------------------------------------------
 
+type multilevelLevel struct {
+	name  string
+	ttl   *time.Duration
+	write bool
+}
+
 // Multilevel is a file system based cache implementation.
 type Multilevel struct {
 	mgr    types.Manager
 	log    log.Modular
-	caches []string
+	levels []multilevelLevel
+
+	// The index of the level at which keys are added.
+	addLevel int
 }
 
 // NewMultilevel creates a new Multilevel cache type.
 func NewMultilevel(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
-	if len(conf.Multilevel) < 2 {
-		return nil, fmt.Errorf("expected at least two cache levels, found %v", len(conf.Multilevel))
+	if len(conf.Multilevel.Levels) < 2 {
+		return nil, fmt.Errorf("expected at least two cache levels, found %v", len(conf.Multilevel.Levels))
 	}
+	for name := range conf.Multilevel.LevelOptions {
+		found := false
+		for _, c := range conf.Multilevel.Levels {
+			if c == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("options provided for cache '%v', which is not a level", name)
+		}
+	}
+	levels := make([]multilevelLevel, 0, len(conf.Multilevel.Levels))
 	missing := []string{}
-	for _, c := range conf.Multilevel {
+	addLevel := -1
+	for i, c := range conf.Multilevel.Levels {
 		if _, err := mgr.GetCache(c); err != nil {
 			missing = append(missing, c)
 		}
+		levelConf, exists := conf.Multilevel.LevelOptions[c]
+		if !exists {
+			levelConf = NewMultilevelLevelConfig()
+		}
+		level := multilevelLevel{
+			name:  c,
+			write: levelConf.Write,
+		}
+		if levelConf.TTL != "" {
+			ttl, err := time.ParseDuration(levelConf.TTL)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse ttl of level '%v': %v", c, err)
+			}
+			level.ttl = &ttl
+		}
+		if levelConf.Write {
+			addLevel = i
+		}
+		levels = append(levels, level)
 	}
 	if len(missing) > 0 {
 		return nil, fmt.Errorf("caches %v not found", missing)
 	}
+	if addLevel == -1 {
+		return nil, errors.New("at least one cache level must be written to")
+	}
 	return &Multilevel{
-		mgr:    mgr,
-		log:    log,
-		caches: conf.Multilevel,
+		mgr:      mgr,
+		log:      log,
+		levels:   levels,
+		addLevel: addLevel,
 	}, nil
 }
 
 //------------------------------------------------------------------------------
 
+// levelTTL returns the TTL to write an item to a level with, where the TTL of
+// the level takes precedence.
+func (l multilevelLevel) levelTTL(ttl *time.Duration) *time.Duration {
+	if l.ttl != nil {
+		return l.ttl
+	}
+	return ttl
+}
+
+func (l *Multilevel) setLevel(level multilevelLevel, key string, value []byte, ttl *time.Duration) error {
+	c, err := l.mgr.GetCache(level.name)
+	if err != nil {
+		return fmt.Errorf("unable to access cache '%v': %v", level.name, err)
+	}
+	if cttl, ok := c.(types.CacheWithTTL); ok {
+		return cttl.SetWithTTL(key, value, level.levelTTL(ttl))
+	}
+	return c.Set(key, value)
+}
+
 func (l *Multilevel) setUpToLevelPassive(i int, key string, value []byte) {
-	for j, name := range l.caches {
-		if j == i {
-			break
-		}
-		c, err := l.mgr.GetCache(name)
-		if err == nil {
-			err = c.Set(key, value)
-		}
-		if err != nil {
-			l.log.Errorf("Unable to passively set key '%v' for cache '%v': %v\n", key, name, err)
+	for _, level := range l.levels[:i] {
+		if err := l.setLevel(level, key, value, nil); err != nil {
+			l.log.Errorf("Unable to passively set key '%v' for cache '%v': %v\n", key, level.name, err)
 		}
 	}
 }
@@ -120,10 +319,10 @@ func (l *Multilevel) setUpToLevelPassive(i int, key string, value []byte) {
 // Get attempts to locate and return a cached value by its key, returns an error
 // if the key does not exist.
 func (l *Multilevel) Get(key string) ([]byte, error) {
-	for i, name := range l.caches {
-		c, err := l.mgr.GetCache(name)
+	for i, level := range l.levels {
+		c, err := l.mgr.GetCache(level.name)
 		if err != nil {
-			return nil, fmt.Errorf("unable to access cache '%v': %v", name, err)
+			return nil, fmt.Errorf("unable to access cache '%v': %v", level.name, err)
 		}
 		data, err := c.Get(key)
 		if err != nil {
@@ -140,19 +339,12 @@ func (l *Multilevel) Get(key string) ([]byte, error) {
 
 // SetWithTTL attempts to set the value of a key.
 func (l *Multilevel) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
-	for _, name := range l.caches {
-		c, err := l.mgr.GetCache(name)
-		if err != nil {
-			return fmt.Errorf("unable to access cache '%v': %v", name, err)
+	for _, level := range l.levels {
+		if !level.write {
+			continue
 		}
-		if cttl, ok := c.(types.CacheWithTTL); ok {
-			if err = cttl.SetWithTTL(key, value, ttl); err != nil {
-				return err
-			}
-		} else {
-			if err = c.Set(key, value); err != nil {
-				return err
-			}
+		if err := l.setLevel(level, key, value, ttl); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -166,13 +358,26 @@ func (l *Multilevel) Set(key string, value []byte) error {
 // SetMultiWithTTL attempts to set the value of multiple keys, returns an error if any
 // keys fail.
 func (l *Multilevel) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
-	for _, name := range l.caches {
-		c, err := l.mgr.GetCache(name)
+	for _, level := range l.levels {
+		if !level.write {
+			continue
+		}
+		c, err := l.mgr.GetCache(level.name)
 		if err != nil {
-			return fmt.Errorf("unable to access cache '%v': %v", name, err)
+			return fmt.Errorf("unable to access cache '%v': %v", level.name, err)
 		}
 		if cttl, ok := c.(types.CacheWithTTL); ok {
-			if err = cttl.SetMultiWithTTL(items); err != nil {
+			levelItems := items
+			if level.ttl != nil {
+				levelItems = make(map[string]types.CacheTTLItem, len(items))
+				for k, v := range items {
+					levelItems[k] = types.CacheTTLItem{
+						Value: v.Value,
+						TTL:   level.ttl,
+					}
+				}
+			}
+			if err = cttl.SetMultiWithTTL(levelItems); err != nil {
 				return err
 			}
 		} else {
@@ -203,10 +408,10 @@ func (l *Multilevel) SetMulti(items map[string][]byte) error {
 // AddWithTTL attempts to set the value of a key only if the key does not already exist
 // and returns an error if the key already exists.
 func (l *Multilevel) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
-	for i := 0; i < len(l.caches)-1; i++ {
-		c, err := l.mgr.GetCache(l.caches[i])
+	for _, level := range l.levels[:l.addLevel] {
+		c, err := l.mgr.GetCache(level.name)
 		if err != nil {
-			return fmt.Errorf("unable to access cache '%v': %v", l.caches[i], err)
+			return fmt.Errorf("unable to access cache '%v': %v", level.name, err)
 		}
 		if _, err = c.Get(key); err != nil {
 			if err != types.ErrKeyNotFound {
@@ -216,13 +421,15 @@ func (l *Multilevel) AddWithTTL(key string, value []byte, ttl *time.Duration) er
 			return types.ErrKeyAlreadyExists
 		}
 	}
-	c, err := l.mgr.GetCache(l.caches[len(l.caches)-1])
+
+	addLevel := l.levels[l.addLevel]
+	c, err := l.mgr.GetCache(addLevel.name)
 	if err != nil {
-		return fmt.Errorf("unable to access cache '%v': %v", l.caches[len(l.caches)-1], err)
+		return fmt.Errorf("unable to access cache '%v': %v", addLevel.name, err)
 	}
 
 	if cttl, ok := c.(types.CacheWithTTL); ok {
-		if err = cttl.AddWithTTL(key, value, ttl); err != nil {
+		if err = cttl.AddWithTTL(key, value, addLevel.levelTTL(ttl)); err != nil {
 			return err
 		}
 	} else {
@@ -231,12 +438,16 @@ func (l *Multilevel) AddWithTTL(key string, value []byte, ttl *time.Duration) er
 		}
 	}
 
-	for i := len(l.caches) - 2; i >= 0; i-- {
-		if c, err = l.mgr.GetCache(l.caches[i]); err != nil {
-			return fmt.Errorf("unable to access cache '%v': %v", l.caches[i], err)
+	for i := l.addLevel - 1; i >= 0; i-- {
+		level := l.levels[i]
+		if !level.write {
+			continue
+		}
+		if c, err = l.mgr.GetCache(level.name); err != nil {
+			return fmt.Errorf("unable to access cache '%v': %v", level.name, err)
 		}
 		if cttl, ok := c.(types.CacheWithTTL); ok {
-			if err = cttl.AddWithTTL(key, value, ttl); err != nil {
+			if err = cttl.AddWithTTL(key, value, level.levelTTL(ttl)); err != nil {
 				return err
 			}
 		} else {
@@ -256,10 +467,10 @@ func (l *Multilevel) Add(key string, value []byte) error {
 
 // Delete attempts to remove a key.
 func (l *Multilevel) Delete(key string) error {
-	for _, name := range l.caches {
-		c, err := l.mgr.GetCache(name)
+	for _, level := range l.levels {
+		c, err := l.mgr.GetCache(level.name)
 		if err != nil {
-			return fmt.Errorf("unable to access cache '%v': %v", name, err)
+			return fmt.Errorf("unable to access cache '%v': %v", level.name, err)
 		}
 		if err = c.Delete(key); err != nil && err != types.ErrKeyNotFound {
 			return err
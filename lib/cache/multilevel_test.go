@@ -1,13 +1,17 @@
 package cache
 
 import (
+	"encoding/json"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 //------------------------------------------------------------------------------
@@ -55,13 +59,13 @@ func TestMultilevelErrors(t *testing.T) {
 		t.Error("Expected error from empty levels")
 	}
 
-	conf.Multilevel = []string{"foo"}
+	conf.Multilevel.Levels = []string{"foo"}
 
 	if _, err := New(conf, &mgr, log.Noop(), metrics.Noop()); err == nil {
 		t.Error("Expected error from only one level")
 	}
 
-	conf.Multilevel = []string{"foo", "bar"}
+	conf.Multilevel.Levels = []string{"foo", "bar"}
 
 	if _, err := New(conf, &mgr, log.Noop(), metrics.Noop()); err == nil {
 		t.Error("Expected error from not existing level")
@@ -86,7 +90,7 @@ func TestMultilevelCacheGetting(t *testing.T) {
 
 	conf := NewConfig()
 	conf.Type = TypeMultilevel
-	conf.Multilevel = []string{"foo", "bar"}
+	conf.Multilevel.Levels = []string{"foo", "bar"}
 
 	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -138,7 +142,7 @@ func TestMultilevelCacheSet(t *testing.T) {
 
 	conf := NewConfig()
 	conf.Type = TypeMultilevel
-	conf.Multilevel = []string{"foo", "bar"}
+	conf.Multilevel.Levels = []string{"foo", "bar"}
 
 	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -186,7 +190,7 @@ func TestMultilevelCacheMultiSet(t *testing.T) {
 
 	conf := NewConfig()
 	conf.Type = TypeMultilevel
-	conf.Multilevel = []string{"foo", "bar"}
+	conf.Multilevel.Levels = []string{"foo", "bar"}
 
 	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -253,7 +257,7 @@ func TestMultilevelCacheDelete(t *testing.T) {
 
 	conf := NewConfig()
 	conf.Type = TypeMultilevel
-	conf.Multilevel = []string{"foo", "bar"}
+	conf.Multilevel.Levels = []string{"foo", "bar"}
 
 	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -308,7 +312,7 @@ func TestMultilevelCacheAdd(t *testing.T) {
 
 	conf := NewConfig()
 	conf.Type = TypeMultilevel
-	conf.Multilevel = []string{"foo", "bar"}
+	conf.Multilevel.Levels = []string{"foo", "bar"}
 
 	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -387,7 +391,7 @@ func TestMultilevelCacheAddMoreCaches(t *testing.T) {
 
 	conf := NewConfig()
 	conf.Type = TypeMultilevel
-	conf.Multilevel = []string{"foo", "bar", "baz"}
+	conf.Multilevel.Levels = []string{"foo", "bar", "baz"}
 
 	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
 	if err != nil {
@@ -452,4 +456,179 @@ func TestMultilevelCacheAddMoreCaches(t *testing.T) {
 	assert.Equal(t, val, []byte("test value 4"))
 }
 
+type ttlRecordingCache struct {
+	types.Cache
+	ttls map[string]*time.Duration
+}
+
+func (c *ttlRecordingCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	c.ttls[key] = ttl
+	return c.Cache.Set(key, value)
+}
+
+func (c *ttlRecordingCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	for k, v := range items {
+		if err := c.SetWithTTL(k, v.Value, v.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ttlRecordingCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	c.ttls[key] = ttl
+	return c.Cache.Add(key, value)
+}
+
+func TestMultilevelLevelConfigParsing(t *testing.T) {
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+multilevel:
+  levels: [ foo, bar, baz ]
+  level_options:
+    bar:
+      ttl: 10s
+    baz:
+      write: false
+`), &conf))
+
+	assert.Equal(t, TypeMultilevel, conf.Type)
+	assert.Equal(t, []string{"foo", "bar", "baz"}, conf.Multilevel.Levels)
+
+	bar, baz := NewMultilevelLevelConfig(), NewMultilevelLevelConfig()
+	bar.TTL = "10s"
+	baz.Write = false
+	assert.Equal(t, map[string]MultilevelLevelConfig{
+		"bar": bar,
+		"baz": baz,
+	}, conf.Multilevel.LevelOptions)
+
+	sanit, err := conf.Sanitised(false)
+	require.NoError(t, err)
+
+	confBytes, err := yaml.Marshal(sanit)
+	require.NoError(t, err)
+
+	var raw interface{}
+	require.NoError(t, yaml.Unmarshal(confBytes, &raw))
+	assert.Equal(t, map[string]interface{}{
+		"type": "multilevel",
+		"multilevel": map[string]interface{}{
+			"levels": []interface{}{"foo", "bar", "baz"},
+			"level_options": map[string]interface{}{
+				"bar": map[string]interface{}{"ttl": "10s", "write": true},
+				"baz": map[string]interface{}{"ttl": "", "write": false},
+			},
+		},
+	}, raw)
+
+	var jConf Config
+	require.NoError(t, json.Unmarshal([]byte(`{"multilevel":{"levels":["foo","bar"],"level_options":{"bar":{"ttl":"10s"}}}}`), &jConf))
+	assert.Equal(t, []string{"foo", "bar"}, jConf.Multilevel.Levels)
+	assert.Equal(t, map[string]MultilevelLevelConfig{"bar": bar}, jConf.Multilevel.LevelOptions)
+}
+
+func TestMultilevelConfigListParsing(t *testing.T) {
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+multilevel: [ foo, bar ]
+`), &conf))
+	assert.Equal(t, []string{"foo", "bar"}, conf.Multilevel.Levels)
+	assert.Empty(t, conf.Multilevel.LevelOptions)
+
+	sanit, err := conf.Sanitised(false)
+	require.NoError(t, err)
+
+	confBytes, err := yaml.Marshal(sanit)
+	require.NoError(t, err)
+
+	var raw interface{}
+	require.NoError(t, yaml.Unmarshal(confBytes, &raw))
+	assert.Equal(t, map[string]interface{}{
+		"type":       "multilevel",
+		"multilevel": []interface{}{"foo", "bar"},
+	}, raw)
+
+	jBytes, err := json.Marshal(conf.Multilevel)
+	require.NoError(t, err)
+	assert.Equal(t, `["foo","bar"]`, string(jBytes))
+
+	var jConf Config
+	require.NoError(t, json.Unmarshal([]byte(`{"multilevel":["foo","bar"]}`), &jConf))
+	assert.Equal(t, []string{"foo", "bar"}, jConf.Multilevel.Levels)
+}
+
+func TestMultilevelCacheLevelOptions(t *testing.T) {
+	newCache := func() *ttlRecordingCache {
+		memCache, err := NewMemory(NewConfig(), nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+		return &ttlRecordingCache{
+			Cache: memCache,
+			ttls:  map[string]*time.Duration{},
+		}
+	}
+	hot, warm, cold := newCache(), newCache(), newCache()
+
+	mgr := fakeMgr{
+		caches: map[string]types.Cache{
+			"hot":  hot,
+			"warm": warm,
+			"cold": cold,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeMultilevel
+	conf.Multilevel.Levels = []string{"hot", "warm", "cold"}
+
+	hotConf, coldConf := NewMultilevelLevelConfig(), NewMultilevelLevelConfig()
+	hotConf.TTL = "1m"
+	coldConf.Write = false
+	conf.Multilevel.LevelOptions = map[string]MultilevelLevelConfig{
+		"hot":  hotConf,
+		"cold": coldConf,
+	}
+
+	c, err := New(conf, &mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ttl := time.Hour
+	require.NoError(t, c.(types.CacheWithTTL).SetWithTTL("foo", []byte("1"), &ttl))
+
+	assert.Equal(t, time.Minute, *hot.ttls["foo"])
+	assert.Equal(t, time.Hour, *warm.ttls["foo"])
+
+	_, err = cold.Get("foo")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+
+	// Reads populate upper levels with their own TTLs.
+	require.NoError(t, cold.Set("bar", []byte("2")))
+	val, err := c.Get("bar")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("2"), val)
+	assert.Equal(t, time.Minute, *hot.ttls["bar"])
+	assert.Nil(t, warm.ttls["bar"])
+
+	// Keys are added to the last level that is written to.
+	require.NoError(t, c.Add("baz", []byte("3")))
+	_, err = cold.Get("baz")
+	assert.Equal(t, types.ErrKeyNotFound, err)
+	val, err = warm.Get("baz")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("3"), val)
+	assert.Equal(t, types.ErrKeyAlreadyExists, c.Add("baz", []byte("4")))
+
+	hotConf.Write = false
+	conf.Multilevel.LevelOptions["hot"] = hotConf
+	conf.Multilevel.LevelOptions["warm"] = coldConf
+	_, err = New(conf, &mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.Multilevel.LevelOptions = map[string]MultilevelLevelConfig{
+		"nope": NewMultilevelLevelConfig(),
+	}
+	_, err = New(conf, &mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
 //------------------------------------------------------------------------------
@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
+)
+
+//------------------------------------------------------------------------------
+
+// Plugin is the interface implemented by cache plugins registered with
+// RegisterPluginWithSpec. A plugin can support batch and atomic operations by
+// also implementing PluginMultiGetter, PluginMultiSetter and PluginAdder.
+type Plugin interface {
+	// Get returns the value of a key, or types.ErrKeyNotFound if the key does
+	// not exist.
+	Get(key string) ([]byte, error)
+
+	// Set sets the value of a key, with an optional TTL that is nil when the
+	// default of the plugin should be used.
+	Set(key string, value []byte, ttl *time.Duration) error
+
+	// Delete removes a key, keys that do not exist are ignored.
+	Delete(key string) error
+
+	types.Closable
+}
+
+// PluginMultiGetter is a cache plugin that is able to obtain the values of
+// multiple keys in a single request.
+type PluginMultiGetter interface {
+	// GetMulti returns the values of multiple keys, keys that do not exist
+	// are omitted from the result.
+	GetMulti(keys []string) (map[string][]byte, error)
+}
+
+// PluginMultiSetter is a cache plugin that is able to set the values of
+// multiple keys in a single request.
+type PluginMultiSetter interface {
+	// SetMulti sets the values of multiple keys, each with an optional TTL.
+	SetMulti(items map[string]types.CacheTTLItem) error
+}
+
+// PluginAdder is a cache plugin that is able to atomically set the value of a
+// key only if it does not already exist.
+type PluginAdder interface {
+	// Add sets the value of a key with an optional TTL only if the key does
+	// not exist, and returns types.ErrKeyAlreadyExists otherwise.
+	Add(key string, value []byte, ttl *time.Duration) error
+}
+
+// SpecPluginConstructor is a func that constructs a cache plugin from a
+// configuration that has been parsed according to its spec.
+type SpecPluginConstructor func(
+	conf *plugin.ParsedConfig,
+	manager types.Manager,
+	logger log.Modular,
+	metrics metrics.Type,
+) (Plugin, error)
+
+// RegisterPluginWithSpec registers a plugin by a unique name along with a spec
+// of its configuration fields. Unlike RegisterPlugin a configuration struct is
+// not needed, instead the constructor is given the user configuration parsed
+// according to the spec.
+//
+// The plugin is wrapped in order to implement the full cache interface used by
+// processors and other components, including the same metrics as standard
+// caches. Operations that the plugin does not support natively fall back to
+// sequential calls, and therefore the add operation is only atomic, and safe
+// for deduplication, when the plugin implements PluginAdder.
+//
+// An error is returned if the name is already used by a cache type or another
+// plugin, or if the spec is invalid.
+func RegisterPluginWithSpec(name string, spec *plugin.ConfigSpec, constructor SpecPluginConstructor) error {
+	if _, exists := Constructors[name]; exists {
+		return fmt.Errorf("cache type '%v' already exists", name)
+	}
+	if _, exists := pluginSpecs[name]; exists {
+		return fmt.Errorf("cache plugin '%v' already exists", name)
+	}
+	if constructor == nil {
+		return errors.New("a constructor must be provided")
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	pluginSpecs[name] = pluginSpec{
+		constructor: func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.Cache, error) {
+			pConf, err := spec.Parse(conf.Plugin)
+			if err != nil {
+				return nil, err
+			}
+			p, err := constructor(pConf, mgr, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newPluginCache(p, stats), nil
+		},
+		confConstructor: func() interface{} {
+			return spec.Defaults()
+		},
+		description: spec.Document(),
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// pluginCache wraps a cache plugin in order to implement the cache interfaces.
+type pluginCache struct {
+	p Plugin
+
+	mLatency     metrics.StatTimer
+	mGetCount    metrics.StatCounter
+	mGetNotFound metrics.StatCounter
+	mGetFailed   metrics.StatCounter
+	mSetCount    metrics.StatCounter
+	mSetFailed   metrics.StatCounter
+	mAddCount    metrics.StatCounter
+	mAddDupe     metrics.StatCounter
+	mAddFailed   metrics.StatCounter
+	mDelCount    metrics.StatCounter
+	mDelFailed   metrics.StatCounter
+}
+
+func newPluginCache(p Plugin, stats metrics.Type) *pluginCache {
+	return &pluginCache{
+		p: p,
+
+		mLatency:     stats.GetTimer("latency"),
+		mGetCount:    stats.GetCounter("get.count"),
+		mGetNotFound: stats.GetCounter("get.failed.not_found"),
+		mGetFailed:   stats.GetCounter("get.failed.error"),
+		mSetCount:    stats.GetCounter("set.count"),
+		mSetFailed:   stats.GetCounter("set.failed.error"),
+		mAddCount:    stats.GetCounter("add.count"),
+		mAddDupe:     stats.GetCounter("add.failed.duplicate"),
+		mAddFailed:   stats.GetCounter("add.failed.error"),
+		mDelCount:    stats.GetCounter("delete.count"),
+		mDelFailed:   stats.GetCounter("delete.failed.error"),
+	}
+}
+
+// Get attempts to locate and return a cached value by its key, returns an error
+// if the key does not exist or if the operation failed.
+func (c *pluginCache) Get(key string) ([]byte, error) {
+	c.mGetCount.Incr(1)
+	tStarted := time.Now()
+
+	value, err := c.p.Get(key)
+	c.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err == types.ErrKeyNotFound {
+		c.mGetNotFound.Incr(1)
+	} else if err != nil {
+		c.mGetFailed.Incr(1)
+	}
+	return value, err
+}
+
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (c *pluginCache) GetMulti(keys ...string) (map[string][]byte, error) {
+	mg, ok := c.p.(PluginMultiGetter)
+	if !ok {
+		values := make(map[string][]byte, len(keys))
+		for _, k := range keys {
+			v, err := c.Get(k)
+			if err == types.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			values[k] = v
+		}
+		return values, nil
+	}
+
+	c.mGetCount.Incr(int64(len(keys)))
+	tStarted := time.Now()
+
+	values, err := mg.GetMulti(keys)
+	c.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		c.mGetFailed.Incr(int64(len(keys)))
+		return nil, err
+	}
+	c.mGetNotFound.Incr(int64(len(keys) - len(values)))
+	return values, nil
+}
+
+// SetWithTTL attempts to set the value of a key.
+func (c *pluginCache) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	c.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	err := c.p.Set(key, value, ttl)
+	c.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		c.mSetFailed.Incr(1)
+	}
+	return err
+}
+
+// Set attempts to set the value of a key.
+func (c *pluginCache) Set(key string, value []byte) error {
+	return c.SetWithTTL(key, value, nil)
+}
+
+// SetMultiWithTTL attempts to set the value of multiple keys, returns an error
+// if any keys fail.
+func (c *pluginCache) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
+	ms, ok := c.p.(PluginMultiSetter)
+	if !ok {
+		for k, v := range items {
+			if err := c.SetWithTTL(k, v.Value, v.TTL); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	c.mSetCount.Incr(int64(len(items)))
+	tStarted := time.Now()
+
+	err := ms.SetMulti(items)
+	c.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		c.mSetFailed.Incr(int64(len(items)))
+	}
+	return err
+}
+
+// SetMulti attempts to set the value of multiple keys, returns an error if any
+// keys fail.
+func (c *pluginCache) SetMulti(items map[string][]byte) error {
+	sitems := make(map[string]types.CacheTTLItem, len(items))
+	for k, v := range items {
+		sitems[k] = types.CacheTTLItem{
+			Value: v,
+		}
+	}
+	return c.SetMultiWithTTL(sitems)
+}
+
+// AddWithTTL attempts to set the value of a key only if the key does not
+// already exist, and returns an error if the key already exists or if the
+// operation fails.
+func (c *pluginCache) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
+	c.mAddCount.Incr(1)
+	tStarted := time.Now()
+
+	var err error
+	if a, ok := c.p.(PluginAdder); ok {
+		err = a.Add(key, value, ttl)
+	} else if _, err = c.p.Get(key); err == nil {
+		err = types.ErrKeyAlreadyExists
+	} else if err == types.ErrKeyNotFound {
+		err = c.p.Set(key, value, ttl)
+	}
+	c.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err == types.ErrKeyAlreadyExists {
+		c.mAddDupe.Incr(1)
+	} else if err != nil {
+		c.mAddFailed.Incr(1)
+	}
+	return err
+}
+
+// Add attempts to set the value of a key only if the key does not already
+// exist, and returns an error if the key already exists or if the operation
+// fails.
+func (c *pluginCache) Add(key string, value []byte) error {
+	return c.AddWithTTL(key, value, nil)
+}
+
+// Delete attempts to remove a key.
+func (c *pluginCache) Delete(key string) error {
+	c.mDelCount.Incr(1)
+	tStarted := time.Now()
+
+	err := c.p.Delete(key)
+	c.mLatency.Timing(int64(time.Since(tStarted)))
+
+	if err != nil {
+		c.mDelFailed.Incr(1)
+	}
+	return err
+}
+
+// CloseAsync shuts down the cache.
+func (c *pluginCache) CloseAsync() {
+	c.p.CloseAsync()
+}
+
+// WaitForClose blocks until the cache has closed down.
+func (c *pluginCache) WaitForClose(timeout time.Duration) error {
+	return c.p.WaitForClose(timeout)
+}
+
+//------------------------------------------------------------------------------
@@ -3,12 +3,16 @@ package cache
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -178,3 +182,147 @@ func TestJSONPluginNilConf(t *testing.T) {
 		t.Errorf("Wrong error returned: %v != %v", err, errTest)
 	}
 }
+
+type mockSpecCache struct {
+	values map[string][]byte
+	ttls   map[string]*time.Duration
+	multi  int
+	closed bool
+	prefix string
+}
+
+func (m *mockSpecCache) Get(key string) ([]byte, error) {
+	v, exists := m.values[m.prefix+key]
+	if !exists {
+		return nil, types.ErrKeyNotFound
+	}
+	return v, nil
+}
+
+func (m *mockSpecCache) Set(key string, value []byte, ttl *time.Duration) error {
+	m.values[m.prefix+key] = value
+	m.ttls[m.prefix+key] = ttl
+	return nil
+}
+
+func (m *mockSpecCache) Delete(key string) error {
+	delete(m.values, m.prefix+key)
+	return nil
+}
+
+func (m *mockSpecCache) CloseAsync() {
+	m.closed = true
+}
+
+func (m *mockSpecCache) WaitForClose(time.Duration) error {
+	return nil
+}
+
+type mockSpecMultiCache struct {
+	*mockSpecCache
+}
+
+func (m mockSpecMultiCache) GetMulti(keys []string) (map[string][]byte, error) {
+	m.multi++
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if v, err := m.Get(k); err == nil {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+func TestYAMLPluginWithSpec(t *testing.T) {
+	var mock *mockSpecCache
+	spec := plugin.NewConfigSpec().
+		Summary("A map backed cache.").
+		Field("prefix", "A prefix for keys.", "").
+		Field("batched", "Whether to support batch gets.", false)
+
+	err := RegisterPluginWithSpec("foo_spec", spec, func(conf *plugin.ParsedConfig, mgr types.Manager, logger log.Modular, stats metrics.Type) (Plugin, error) {
+		prefix, err := conf.FieldString("prefix")
+		if err != nil {
+			return nil, err
+		}
+		batched, err := conf.FieldBool("batched")
+		if err != nil {
+			return nil, err
+		}
+		mock = &mockSpecCache{
+			values: map[string][]byte{},
+			ttls:   map[string]*time.Duration{},
+			prefix: prefix,
+		}
+		if batched {
+			return mockSpecMultiCache{mock}, nil
+		}
+		return mock, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(pluginSpecs, "foo_spec")
+
+	if err = RegisterPluginWithSpec("foo_spec", spec, nil); err == nil {
+		t.Error("Expected error from duplicate plugin")
+	}
+	if err = RegisterPluginWithSpec(TypeMemory, spec, nil); err == nil {
+		t.Error("Expected error from plugin with the name of a cache type")
+	}
+
+	for _, batched := range []bool{false, true} {
+		confStr := fmt.Sprintf("type: foo_spec\nplugin:\n  prefix: foo_\n  batched: %v", batched)
+
+		conf := NewConfig()
+		if err = yaml.Unmarshal([]byte(confStr), &conf); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = c.Set("a", []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := "1", string(mock.values["foo_a"]); exp != act {
+			t.Errorf("Wrong stored value: %v != %v", act, exp)
+		}
+
+		if err = c.Add("a", []byte("2")); err != types.ErrKeyAlreadyExists {
+			t.Errorf("Wrong error from add of existing key: %v", err)
+		}
+		ttl := time.Minute
+		if err = c.(types.CacheWithTTL).AddWithTTL("b", []byte("2"), &ttl); err != nil {
+			t.Fatal(err)
+		}
+		if mock.ttls["foo_b"] == nil || *mock.ttls["foo_b"] != ttl {
+			t.Errorf("Wrong ttl: %v", mock.ttls["foo_b"])
+		}
+
+		values, err := c.(types.CacheMultiGetter).GetMulti("a", "b", "c")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := map[string][]byte{"a": []byte("1"), "b": []byte("2")}, values; !reflect.DeepEqual(exp, act) {
+			t.Errorf("Wrong values: %s != %s", act, exp)
+		}
+		if batched && mock.multi != 1 {
+			t.Errorf("Expected a single batch get, got %v", mock.multi)
+		}
+
+		if err = c.Delete("a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = c.Get("a"); err != types.ErrKeyNotFound {
+			t.Errorf("Wrong error from get of deleted key: %v", err)
+		}
+
+		c.CloseAsync()
+		if !mock.closed {
+			t.Error("Expected plugin to be closed")
+		}
+	}
+}
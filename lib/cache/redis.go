@@ -24,6 +24,7 @@ string in order to set no expiration.`,
 		FieldSpecs: bredis.ConfigDocs().Add(
 			docs.FieldCommon("prefix", "An optional string to prefix item keys with in order to prevent collisions with similar services."),
 			docs.FieldCommon("expiration", "An optional period after which cached items will expire."),
+			ttlJitterFieldSpec(),
 			docs.FieldAdvanced("retries", "The maximum number of retry attempts to make before abandoning a request."),
 			docs.FieldAdvanced("retry_period", "The duration to wait between retry attempts."),
 		),
@@ -35,10 +36,11 @@ string in order to set no expiration.`,
 // RedisConfig is a config struct for a redis connection.
 type RedisConfig struct {
 	bredis.Config `json:",inline" yaml:",inline"`
-	Prefix        string `json:"prefix" yaml:"prefix"`
-	Expiration    string `json:"expiration" yaml:"expiration"`
-	Retries       int    `json:"retries" yaml:"retries"`
-	RetryPeriod   string `json:"retry_period" yaml:"retry_period"`
+	Prefix        string  `json:"prefix" yaml:"prefix"`
+	Expiration    string  `json:"expiration" yaml:"expiration"`
+	TTLJitter     float64 `json:"ttl_jitter" yaml:"ttl_jitter"`
+	Retries       int     `json:"retries" yaml:"retries"`
+	RetryPeriod   string  `json:"retry_period" yaml:"retry_period"`
 }
 
 // NewRedisConfig returns a RedisConfig with default values.
@@ -47,6 +49,7 @@ func NewRedisConfig() RedisConfig {
 		Config:      bredis.NewConfig(),
 		Prefix:      "",
 		Expiration:  "24h",
+		TTLJitter:   0,
 		Retries:     3,
 		RetryPeriod: "500ms",
 	}
@@ -103,6 +106,9 @@ func NewRedis(
 			return nil, fmt.Errorf("failed to parse expiration: %v", err)
 		}
 	}
+	if err := checkTTLJitter(conf.Redis.TTLJitter); err != nil {
+		return nil, err
+	}
 
 	var retryPeriod time.Duration
 	if tout := conf.Redis.RetryPeriod; len(tout) > 0 {
@@ -195,6 +201,55 @@ func (r *Redis) Get(key string) ([]byte, error) {
 	return []byte(res), nil
 }
 
+// GetMulti attempts to locate and return the cached values of multiple keys
+// with a single MGET command, keys that do not exist are omitted from the
+// result.
+func (r *Redis) GetMulti(keys ...string) (map[string][]byte, error) {
+	if len(keys) == 0 {
+		return map[string][]byte{}, nil
+	}
+
+	r.mGetCount.Incr(int64(len(keys)))
+	tStarted := time.Now()
+
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = r.prefix + k
+	}
+
+	res, err := r.client.MGet(prefixed...).Result()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("MGet command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mGetRetry.Incr(1)
+		res, err = r.client.MGet(prefixed...).Result()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mGetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mGetFailed.Incr(int64(len(keys)))
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for i, v := range res {
+		switch t := v.(type) {
+		case string:
+			values[keys[i]] = []byte(t)
+			r.mGetSuccess.Incr(1)
+		case []byte:
+			values[keys[i]] = t
+			r.mGetSuccess.Incr(1)
+		default:
+			r.mGetNotFound.Incr(1)
+		}
+	}
+	return values, nil
+}
+
 // SetWithTTL attempts to set the value of a key.
 func (r *Redis) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 	r.mSetCount.Incr(1)
@@ -202,12 +257,7 @@ func (r *Redis) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
 
 	key = r.prefix + key
 
-	var t time.Duration
-	if ttl != nil {
-		t = *ttl
-	} else {
-		t = r.ttl
-	}
+	t := r.resolveTTL(ttl)
 	err := r.client.Set(key, value, t).Err()
 	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
 		r.log.Errorf("Set command failed: %v\n", err)
@@ -265,12 +315,7 @@ func (r *Redis) AddWithTTL(key string, value []byte, ttl *time.Duration) error {
 
 	key = r.prefix + key
 
-	var t time.Duration
-	if ttl != nil {
-		t = *ttl
-	} else {
-		t = r.ttl
-	}
+	t := r.resolveTTL(ttl)
 	set, err := r.client.SetNX(key, value, t).Result()
 	if err == nil && !set {
 		r.mAddFailedDupe.Incr(1)
@@ -314,6 +359,147 @@ func (r *Redis) Add(key string, value []byte) error {
 	return r.AddWithTTL(key, value, nil)
 }
 
+func (r *Redis) resolveTTL(ttl *time.Duration) time.Duration {
+	if ttl != nil {
+		return jitterTTL(*ttl, r.conf.Redis.TTLJitter)
+	}
+	return jitterTTL(r.ttl, r.conf.Redis.TTLJitter)
+}
+
+// Returns -1 when the key does not exist, 0 when the value does not match and 1
+// when the value was swapped.
+var redisCASScript = redis.NewScript(`
+local v = redis.call("GET", KEYS[1])
+if not v then
+  return -1
+end
+if v ~= ARGV[1] then
+  return 0
+end
+if tonumber(ARGV[3]) > 0 then
+  redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+else
+  redis.call("SET", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// CompareAndSwap attempts to set the value of a key only if its current value
+// matches old.
+func (r *Redis) CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error {
+	r.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+	ttlMillis := int64(r.resolveTTL(ttl) / time.Millisecond)
+
+	res, err := redisCASScript.Run(r.client, []string{key}, old, value, ttlMillis).Int64()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Compare and swap command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mSetRetry.Incr(1)
+		res, err = redisCASScript.Run(r.client, []string{key}, old, value, ttlMillis).Int64()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mSetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mSetFailed.Incr(1)
+		return err
+	}
+	switch res {
+	case -1:
+		return types.ErrKeyNotFound
+	case 0:
+		return types.ErrKeyValueMismatch
+	}
+	r.mSetSuccess.Incr(1)
+	return nil
+}
+
+// The TTL is only set when the key is created by the increment.
+var redisIncrScript = redis.NewScript(`
+local existed = redis.call("EXISTS", KEYS[1])
+local n = redis.call("INCRBY", KEYS[1], ARGV[1])
+if existed == 0 and tonumber(ARGV[2]) > 0 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return n
+`)
+
+// Increment atomically adds delta to the integer value of a key and returns
+// the result.
+func (r *Redis) Increment(key string, delta int64, ttl *time.Duration) (int64, error) {
+	r.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+	ttlMillis := int64(r.resolveTTL(ttl) / time.Millisecond)
+
+	n, err := redisIncrScript.Run(r.client, []string{key}, delta, ttlMillis).Int64()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Increment command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mSetRetry.Incr(1)
+		n, err = redisIncrScript.Run(r.client, []string{key}, delta, ttlMillis).Int64()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mSetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mSetFailed.Incr(1)
+		return 0, err
+	}
+	r.mSetSuccess.Incr(1)
+	return n, nil
+}
+
+// Touch resets the TTL of a key.
+func (r *Redis) Touch(key string, ttl *time.Duration) error {
+	r.mSetCount.Incr(1)
+	tStarted := time.Now()
+
+	key = r.prefix + key
+	t := r.resolveTTL(ttl)
+
+	touch := func() (bool, error) {
+		if t <= 0 {
+			if _, err := r.client.Persist(key).Result(); err != nil {
+				return false, err
+			}
+			n, err := r.client.Exists(key).Result()
+			return n > 0, err
+		}
+		return r.client.PExpire(key, t).Result()
+	}
+
+	exists, err := touch()
+	for i := 0; i < r.conf.Redis.Retries && err != nil; i++ {
+		r.log.Errorf("Touch command failed: %v\n", err)
+		<-time.After(r.retryPeriod)
+		r.mSetRetry.Incr(1)
+		exists, err = touch()
+	}
+
+	latency := int64(time.Since(tStarted))
+	r.mSetLatency.Timing(latency)
+	r.mLatency.Timing(latency)
+
+	if err != nil {
+		r.mSetFailed.Incr(1)
+		return err
+	}
+	if !exists {
+		return types.ErrKeyNotFound
+	}
+	r.mSetSuccess.Incr(1)
+	return nil
+}
+
 // Delete attempts to remove a key.
 func (r *Redis) Delete(key string) error {
 	r.mDelCount.Incr(1)
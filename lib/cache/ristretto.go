@@ -23,13 +23,29 @@ func init() {
 Stores key/value pairs in a map held in the memory-bound
 [Ristretto cache](https://github.com/dgraph-io/ristretto).`,
 		Description: `
-This cache is more efficient and appropriate for high-volume use cases than the standard memory cache. However, the add command is non-atomic, and therefore this cache is not suitable for deduplication.`,
+This cache is more efficient and appropriate for high-volume use cases than the standard memory cache. However, the add command is non-atomic, and therefore this cache is not suitable for deduplication.
+
+### Eviction
+
+When the total cost of the items within the cache exceeds ` + "`max_cost`" + `
+items are evicted based on how frequently they have been accessed. By default
+each item has a cost of one, which makes ` + "`max_cost`" + ` the maximum
+number of items, and setting ` + "`item_cost`" + ` to ` + "`bytes`" + ` instead
+uses the size of each value, which makes ` + "`max_cost`" + ` the maximum
+number of bytes of values held in memory.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
 				"ttl",
 				"The TTL of each item as a duration string. After this period an item will be eligible for removal during the next compaction.",
 				"60s", "5m", "36h",
 			),
+			ttlJitterFieldSpec(),
+			docs.FieldAdvanced("max_cost", "The maximum total cost of the items within the cache, after which items are evicted.").AtVersion("3.39.0"),
+			docs.FieldAdvanced("item_cost", "The cost of each item within the cache.").HasAnnotatedOptions(
+				"count", "Each item has a cost of one.",
+				"bytes", "Each item has a cost of the size of its value in bytes.",
+			).AtVersion("3.39.0"),
+			docs.FieldAdvanced("num_counters", "The number of keys to track the access frequency of, which should be around ten times the number of items expected to be held within the cache when it is full.").AtVersion("3.39.0"),
 			docs.FieldAdvanced("retries", "The maximum number of retry attempts to make before abandoning a request."),
 			docs.FieldAdvanced("retry_period", "The duration to wait between retry attempts."),
 		},
@@ -40,15 +56,23 @@ This cache is more efficient and appropriate for high-volume use cases than the
 
 // RistrettoConfig contains config fields for the Ristretto cache type.
 type RistrettoConfig struct {
-	TTL         string `json:"ttl" yaml:"ttl"`
-	Retries     int    `json:"retries" yaml:"retries"`
-	RetryPeriod string `json:"retry_period" yaml:"retry_period"`
+	TTL         string  `json:"ttl" yaml:"ttl"`
+	TTLJitter   float64 `json:"ttl_jitter" yaml:"ttl_jitter"`
+	MaxCost     int64   `json:"max_cost" yaml:"max_cost"`
+	ItemCost    string  `json:"item_cost" yaml:"item_cost"`
+	NumCounters int64   `json:"num_counters" yaml:"num_counters"`
+	Retries     int     `json:"retries" yaml:"retries"`
+	RetryPeriod string  `json:"retry_period" yaml:"retry_period"`
 }
 
 // NewRistrettoConfig creates a RistrettoConfig populated with default values.
 func NewRistrettoConfig() RistrettoConfig {
 	return RistrettoConfig{
 		TTL:         "",
+		TTLJitter:   0,
+		MaxCost:     1 << 30,
+		ItemCost:    "count",
+		NumCounters: 1e7,
 		Retries:     0,
 		RetryPeriod: "50ms",
 	}
@@ -58,8 +82,10 @@ func NewRistrettoConfig() RistrettoConfig {
 
 // Ristretto is a memory based cache implementation.
 type Ristretto struct {
-	ttl   time.Duration
-	cache *ristretto.Cache
+	ttl         time.Duration
+	ttlJitter   float64
+	cache       *ristretto.Cache
+	costByBytes bool
 
 	retries     int
 	retryPeriod time.Duration
@@ -75,6 +101,9 @@ func NewRistretto(conf Config, mgr types.Manager, log log.Modular, stats metrics
 			return nil, fmt.Errorf("failed to parse ttl duration: %w", err)
 		}
 	}
+	if err = checkTTLJitter(conf.Ristretto.TTLJitter); err != nil {
+		return nil, err
+	}
 
 	var retryPeriod time.Duration
 	if tout := conf.Ristretto.RetryPeriod; len(tout) > 0 {
@@ -84,17 +113,34 @@ func NewRistretto(conf Config, mgr types.Manager, log log.Modular, stats metrics
 		}
 	}
 
+	var costByBytes bool
+	switch conf.Ristretto.ItemCost {
+	case "count", "":
+	case "bytes":
+		costByBytes = true
+	default:
+		return nil, fmt.Errorf("unrecognised item cost: %v", conf.Ristretto.ItemCost)
+	}
+	if conf.Ristretto.MaxCost <= 0 {
+		return nil, errors.New("max_cost must be greater than zero")
+	}
+	if conf.Ristretto.NumCounters <= 0 {
+		return nil, errors.New("num_counters must be greater than zero")
+	}
+
 	cache, err := ristretto.NewCache(&ristretto.Config{
-		NumCounters: 1e7,     // number of keys to track frequency of (10M).
-		MaxCost:     1 << 30, // maximum cost of cache (1GB).
-		BufferItems: 64,      // number of keys per Get buffer.
+		NumCounters: conf.Ristretto.NumCounters,
+		MaxCost:     conf.Ristretto.MaxCost,
+		BufferItems: 64, // number of keys per Get buffer.
 	})
 	if err != nil {
 		return nil, err
 	}
 	r := &Ristretto{
 		ttl:         ttl,
+		ttlJitter:   conf.Ristretto.TTLJitter,
 		cache:       cache,
+		costByBytes: costByBytes,
 		retries:     conf.Ristretto.Retries,
 		retryPeriod: retryPeriod,
 	}
@@ -118,15 +164,35 @@ func (r *Ristretto) Get(key string) ([]byte, error) {
 	return res.([]byte), nil
 }
 
-// SetWithTTL attempts to set the value of a key.
-func (r *Ristretto) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
-	var t time.Duration
+// GetMulti attempts to locate and return the cached values of multiple keys,
+// keys that do not exist are omitted from the result.
+func (r *Ristretto) GetMulti(keys ...string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		if res, ok := r.cache.Get(k); ok {
+			values[k] = res.([]byte)
+		}
+	}
+	return values, nil
+}
+
+func (r *Ristretto) cost(value []byte) int64 {
+	if r.costByBytes {
+		return int64(len(value))
+	}
+	return 1
+}
+
+func (r *Ristretto) resolveTTL(ttl *time.Duration) time.Duration {
 	if ttl != nil {
-		t = *ttl
-	} else {
-		t = r.ttl
+		return jitterTTL(*ttl, r.ttlJitter)
 	}
-	if !r.cache.SetWithTTL(key, value, 1, t) {
+	return jitterTTL(r.ttl, r.ttlJitter)
+}
+
+// SetWithTTL attempts to set the value of a key.
+func (r *Ristretto) SetWithTTL(key string, value []byte, ttl *time.Duration) error {
+	if !r.cache.SetWithTTL(key, value, r.cost(value), r.resolveTTL(ttl)) {
 		return errors.New("set operation was dropped")
 	}
 	return nil
@@ -141,13 +207,7 @@ func (r *Ristretto) Set(key string, value []byte) error {
 // keys fail.
 func (r *Ristretto) SetMultiWithTTL(items map[string]types.CacheTTLItem) error {
 	for k, v := range items {
-		var t time.Duration
-		if v.TTL != nil {
-			t = *v.TTL
-		} else {
-			t = r.ttl
-		}
-		if !r.cache.SetWithTTL(k, v.Value, 1, t) {
+		if !r.cache.SetWithTTL(k, v.Value, r.cost(v.Value), r.resolveTTL(v.TTL)) {
 			return errors.New("set operation was dropped")
 		}
 	}
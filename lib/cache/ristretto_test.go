@@ -66,3 +66,48 @@ func TestRistrettoCacheWithTTL(t *testing.T) {
 		assert.Fail(t, "ristretto should implement CacheWithTTL interface")
 	}
 }
+
+func TestRistrettoCacheGetMulti(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeRistretto
+	conf.Ristretto.ItemCost = "bytes"
+	conf.Ristretto.MaxCost = 1 << 20
+
+	c, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set("foo", []byte("1")))
+	require.NoError(t, c.Set("bar", []byte("2")))
+
+	mc, ok := c.(types.CacheMultiGetter)
+	require.True(t, ok)
+
+	assert.Eventually(t, func() bool {
+		res, err := mc.GetMulti("foo", "bar", "baz")
+		require.NoError(t, err)
+		return len(res) == 2 && string(res["foo"]) == "1" && string(res["bar"]) == "2"
+	}, time.Second, time.Millisecond)
+}
+
+func TestRistrettoCacheBadConfig(t *testing.T) {
+	tests := map[string]func(c *RistrettoConfig){
+		"bad item cost": func(c *RistrettoConfig) {
+			c.ItemCost = "nope"
+		},
+		"zero max cost": func(c *RistrettoConfig) {
+			c.MaxCost = 0
+		},
+		"zero num counters": func(c *RistrettoConfig) {
+			c.NumCounters = 0
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeRistretto
+		fn(&conf.Ristretto)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
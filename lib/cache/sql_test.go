@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLCacheQueries(t *testing.T) {
+	pq, err := newSQLCacheQueries("postgres", "foo")
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "value" FROM foo WHERE "key" = $1 AND ("expires_at" IS NULL OR "expires_at" > $2)`, pq.get)
+	assert.Contains(t, pq.set, `ON CONFLICT ("key") DO UPDATE`)
+	assert.Contains(t, pq.add, `WHERE c."expires_at" IS NOT NULL AND c."expires_at" <= $4`)
+
+	my, err := newSQLCacheQueries("mysql", "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT `value` FROM foo WHERE `key` = ? AND (`expires_at` IS NULL OR `expires_at` > ?)", my.get)
+	assert.Contains(t, my.set, "ON DUPLICATE KEY UPDATE")
+	assert.Equal(t, 5, strings.Count(my.add, "?"))
+
+	_, err = newSQLCacheQueries("clickhouse", "foo")
+	assert.Error(t, err)
+}
+
+func TestSQLCacheExpiresAt(t *testing.T) {
+	s := &SQL{ttl: time.Minute}
+	now := time.Unix(1600000000, 0)
+
+	assert.Equal(t, int64(1600000060000), s.expiresAt(nil, now))
+
+	ttl := time.Second
+	assert.Equal(t, int64(1600000001000), s.expiresAt(&ttl, now))
+
+	ttl = 0
+	assert.Nil(t, s.expiresAt(&ttl, now))
+}
+
+func TestSQLCacheBadConfig(t *testing.T) {
+	tests := map[string]func(c *SQLConfig){
+		"no data source name": func(c *SQLConfig) {
+			c.DataSourceName = ""
+		},
+		"no table": func(c *SQLConfig) {
+			c.Table = ""
+		},
+		"bad driver": func(c *SQLConfig) {
+			c.Driver = "clickhouse"
+		},
+		"bad ttl": func(c *SQLConfig) {
+			c.TTL = "nope"
+		},
+		"bad cleanup interval": func(c *SQLConfig) {
+			c.CleanupInterval = "nope"
+		},
+		"zero cleanup interval": func(c *SQLConfig) {
+			c.CleanupInterval = "0s"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeSQL
+		conf.SQL.DataSourceName = "postgres://localhost:5432/foodb"
+		fn(&conf.SQL)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
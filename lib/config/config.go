@@ -10,6 +10,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/shedding"
 	"github.com/Jeffail/benthos/v3/lib/stream"
 	"github.com/Jeffail/benthos/v3/lib/tracer"
 	"gopkg.in/yaml.v3"
@@ -21,12 +22,14 @@ import (
 type Type struct {
 	HTTP               api.Config `json:"http" yaml:"http"`
 	stream.Config      `json:",inline" yaml:",inline"`
-	Manager            manager.Config `json:"resources" yaml:"resources"`
-	Logger             log.Config     `json:"logger" yaml:"logger"`
-	Metrics            metrics.Config `json:"metrics" yaml:"metrics"`
-	Tracer             tracer.Config  `json:"tracer" yaml:"tracer"`
-	SystemCloseTimeout string         `json:"shutdown_timeout" yaml:"shutdown_timeout"`
-	Tests              interface{}    `json:"tests,omitempty" yaml:"tests,omitempty"`
+	Manager            manager.Config  `json:"resources" yaml:"resources"`
+	Logger             log.Config      `json:"logger" yaml:"logger"`
+	Metrics            metrics.Config  `json:"metrics" yaml:"metrics"`
+	Tracer             tracer.Config   `json:"tracer" yaml:"tracer"`
+	LoadShedding       shedding.Config `json:"load_shedding" yaml:"load_shedding"`
+	SystemCloseTimeout string          `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	DrainTimeout       string          `json:"shutdown_drain_timeout" yaml:"shutdown_drain_timeout"`
+	Tests              interface{}     `json:"tests,omitempty" yaml:"tests,omitempty"`
 }
 
 // New returns a new configuration with default values.
@@ -38,7 +41,9 @@ func New() Type {
 		Logger:             log.NewConfig(),
 		Metrics:            metrics.NewConfig(),
 		Tracer:             tracer.NewConfig(),
+		LoadShedding:       shedding.NewConfig(),
 		SystemCloseTimeout: "20s",
+		DrainTimeout:       "",
 		Tests:              nil,
 	}
 }
@@ -51,11 +56,15 @@ type SanitisedConfig struct {
 	Buffer             interface{} `json:"buffer" yaml:"buffer"`
 	Pipeline           interface{} `json:"pipeline" yaml:"pipeline"`
 	Output             interface{} `json:"output" yaml:"output"`
+	SLO                interface{} `json:"slo,omitempty" yaml:"slo,omitempty"`
+	Limits             interface{} `json:"limits,omitempty" yaml:"limits,omitempty"`
 	Manager            interface{} `json:"resources" yaml:"resources"`
 	Logger             interface{} `json:"logger" yaml:"logger"`
 	Metrics            interface{} `json:"metrics" yaml:"metrics"`
 	Tracer             interface{} `json:"tracer" yaml:"tracer"`
+	LoadShedding       interface{} `json:"load_shedding" yaml:"load_shedding"`
 	SystemCloseTimeout interface{} `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	DrainTimeout       interface{} `json:"shutdown_drain_timeout" yaml:"shutdown_drain_timeout"`
 	Tests              interface{} `json:"tests,omitempty" yaml:"tests,omitempty"`
 }
 
@@ -126,11 +135,15 @@ func (c Type) sanitised(skipDeprecated bool) (*SanitisedConfig, error) {
 		Buffer:             bufConf,
 		Pipeline:           pipeConf,
 		Output:             outConf,
+		SLO:                c.SLO.Sanitised(),
+		Limits:             c.Limits.Sanitised(),
 		Manager:            mgrConf,
 		Logger:             logConf,
 		Metrics:            metConf,
 		Tracer:             tracConf,
+		LoadShedding:       c.LoadShedding,
 		SystemCloseTimeout: c.SystemCloseTimeout,
+		DrainTimeout:       c.DrainTimeout,
 		Tests:              c.Tests,
 	}, nil
 }
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// appendTag is a YAML tag that can be added to an array within an overlay in
+// order to append its elements to the array of the config beneath it rather
+// than replace it.
+const appendTag = "!append"
+
+// ReadLayered reads a base config file followed by any number of overlay
+// files, which are deep merged in order, and parses the result into a config.
+// Returns a slice of lint results from the merged config.
+func ReadLayered(paths []string, replaceEnvs bool, config *Type) ([]string, error) {
+	if len(paths) == 1 {
+		return Read(paths[0], replaceEnvs, config)
+	}
+
+	configBytes, err := ReadMerged(paths, replaceEnvs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(configBytes, config); err != nil {
+		return nil, err
+	}
+
+	return Lint(configBytes, *config)
+}
+
+// ReadMerged reads config files, resolving any JSON Pointers within them, and
+// deep merges each file onto those that precede it. Objects are merged key by
+// key, whereas arrays and all other values of a later file replace those of
+// earlier files. A key set to null within a later file removes it, and an
+// array tagged with !append is appended to the array beneath it.
+func ReadMerged(paths []string, replaceEnvs bool) ([]byte, error) {
+	var merged *yaml.Node
+	for _, path := range paths {
+		configBytes, err := ReadWithJSONPointers(path, replaceEnvs)
+		if err != nil {
+			return nil, err
+		}
+
+		var doc yaml.Node
+		if err = yaml.Unmarshal(configBytes, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config '%v': %v", path, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("config '%v' must be an object", path)
+		}
+		if merged == nil {
+			clearAppendTags(root)
+			merged = root
+			continue
+		}
+		merged = mergeNodes(merged, root)
+	}
+	if merged == nil {
+		return []byte{}, nil
+	}
+	return yaml.Marshal(merged)
+}
+
+//------------------------------------------------------------------------------
+
+// mergeNodes deep merges an overlay node onto a base node and returns the
+// result, which may modify the base node.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	if base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode {
+		for i := 0; i < len(overlay.Content)-1; i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			index := mappingIndex(base, key.Value)
+			if value.Tag == "!!null" {
+				if index != -1 {
+					base.Content = append(base.Content[:index], base.Content[index+2:]...)
+				}
+				continue
+			}
+			if index == -1 {
+				clearAppendTags(value)
+				base.Content = append(base.Content, key, value)
+				continue
+			}
+			base.Content[index+1] = mergeNodes(base.Content[index+1], value)
+		}
+		return base
+	}
+
+	if base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && overlay.Tag == appendTag {
+		clearAppendTags(overlay)
+		base.Content = append(base.Content, overlay.Content...)
+		return base
+	}
+
+	clearAppendTags(overlay)
+	return overlay
+}
+
+// mappingIndex returns the index of a key within a mapping node, or -1 if it
+// does not exist.
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// clearAppendTags removes append tags from a node and its children so that the
+// node can be decoded.
+func clearAppendTags(node *yaml.Node) {
+	if node.Tag == appendTag {
+		node.Tag = "!!seq"
+		node.Style &^= yaml.TaggedStyle
+	}
+	for _, c := range node.Content {
+		clearAppendTags(c)
+	}
+}
+
+//------------------------------------------------------------------------------
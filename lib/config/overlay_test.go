@@ -0,0 +1,133 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+func writeOverlayFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	tmpDir, err := ioutil.TempDir("", "benthos_config_overlay_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tmpDir
+}
+
+func TestConfigReadMerged(t *testing.T) {
+	tmpDir := writeOverlayFiles(t, map[string]string{
+		"base.yaml": `
+a:
+  b: foo
+  c: [ 1, 2 ]
+  d: [ 1, 2 ]
+  e: bar
+f: baz
+`,
+		"overlay.yaml": `
+a:
+  b: qux
+  c: [ 3 ]
+  d: !append [ 3 ]
+  e: null
+g: [ 4 ]
+`,
+		"empty.yaml": ``,
+	})
+	defer os.RemoveAll(tmpDir)
+
+	res, err := ReadMerged([]string{
+		filepath.Join(tmpDir, "base.yaml"),
+		filepath.Join(tmpDir, "empty.yaml"),
+		filepath.Join(tmpDir, "overlay.yaml"),
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var act interface{}
+	if err = yaml.Unmarshal(res, &act); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": "qux",
+			"c": []interface{}{3},
+			"d": []interface{}{1, 2, 3},
+		},
+		"f": "baz",
+		"g": []interface{}{4},
+	}
+	if !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+}
+
+func TestConfigReadLayered(t *testing.T) {
+	tmpDir := writeOverlayFiles(t, map[string]string{
+		"base.yaml": `
+input:
+  type: stdin
+pipeline:
+  processors:
+    - bloblang: 'root = this'
+output:
+  type: file
+  file:
+    path: ./foo.txt
+`,
+		"prod.yaml": `
+pipeline:
+  processors: !append
+    - bloblang: 'root.prod = true'
+output:
+  file:
+    path: ./bar.txt
+    nope: true
+`,
+	})
+	defer os.RemoveAll(tmpDir)
+
+	conf := New()
+	lints, err := ReadLayered([]string{
+		filepath.Join(tmpDir, "base.yaml"),
+		filepath.Join(tmpDir, "prod.yaml"),
+	}, true, &conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := "stdin", conf.Input.Type; exp != act {
+		t.Errorf("Wrong input type: %v != %v", act, exp)
+	}
+	if exp, act := 2, len(conf.Pipeline.Processors); exp != act {
+		t.Fatalf("Wrong count of processors: %v != %v", act, exp)
+	}
+	if exp, act := "root.prod = true", string(conf.Pipeline.Processors[1].Bloblang); exp != act {
+		t.Errorf("Wrong processor mapping: %v != %v", act, exp)
+	}
+	if exp, act := "file", conf.Output.Type; exp != act {
+		t.Errorf("Wrong output type: %v != %v", act, exp)
+	}
+	if exp, act := "./bar.txt", conf.Output.File.Path; exp != act {
+		t.Errorf("Wrong output path: %v != %v", act, exp)
+	}
+	if exp, act := 1, len(lints); exp != act {
+		t.Errorf("Wrong count of lints: %v != %v: %v", act, exp, lints)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
 	"github.com/Jeffail/benthos/v3/lib/util/text"
 	"gopkg.in/yaml.v3"
 )
@@ -25,6 +26,9 @@ func ReadWithJSONPointers(path string, replaceEnvs bool) ([]byte, error) {
 	}
 
 	if replaceEnvs {
+		if configBytes, err = secrets.ReplaceWithTimeout(configBytes, secrets.DefaultTimeout); err != nil {
+			return nil, err
+		}
 		configBytes = text.ReplaceEnvVariables(configBytes)
 	}
 
@@ -151,6 +155,9 @@ func expandRefVal(path string, level int, root, v interface{}) (interface{}, err
 		if err != nil {
 			return nil, fmt.Errorf("failed to read relative $ref path '%v' in config '%v': %v", rPath, path, err)
 		}
+		if configBytes, err = secrets.ReplaceWithTimeout(configBytes, secrets.DefaultTimeout); err != nil {
+			return nil, fmt.Errorf("failed to read relative $ref path '%v' in config '%v': %v", rPath, path, err)
+		}
 		configBytes = text.ReplaceEnvVariables(configBytes)
 
 		var gen interface{}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// Severities of custom lint rules.
+const (
+	LintRuleSeverityError   = "error"
+	LintRuleSeverityWarning = "warning"
+)
+
+// LintRule is a custom lint rule, which is a Bloblang query that must return
+// true for a config to satisfy the rule. When a component is specified the
+// query is executed against every component of that kind (and optionally
+// type) within the config, otherwise it's executed against the whole config.
+type LintRule struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Severity    string `json:"severity" yaml:"severity"`
+	Component   string `json:"component" yaml:"component"`
+	Type        string `json:"type" yaml:"type"`
+	Check       string `json:"check" yaml:"check"`
+}
+
+// LintRuleViolation describes a config that fails to satisfy a custom lint
+// rule.
+type LintRuleViolation struct {
+	Rule     string
+	Severity string
+	Path     string
+	Message  string
+}
+
+// String returns a human readable description of the violation.
+func (v LintRuleViolation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("rule '%v': %v", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("path '%v': rule '%v': %v", v.Path, v.Rule, v.Message)
+}
+
+// componentKinds maps config keys to the kind of components that their values
+// contain.
+var componentKinds = map[string]string{
+	"input":       "input",
+	"inputs":      "input",
+	"buffer":      "buffer",
+	"processor":   "processor",
+	"processors":  "processor",
+	"condition":   "condition",
+	"conditions":  "condition",
+	"output":      "output",
+	"outputs":     "output",
+	"cache":       "cache",
+	"caches":      "cache",
+	"rate_limit":  "rate_limit",
+	"rate_limits": "rate_limit",
+	"metrics":     "metrics",
+	"tracer":      "tracer",
+}
+
+type compiledLintRule struct {
+	LintRule
+	exec *mapping.Executor
+}
+
+// LintRuleSet is a set of compiled custom lint rules.
+type LintRuleSet struct {
+	rules []compiledLintRule
+}
+
+// NewLintRuleSet compiles a set of custom lint rules.
+func NewLintRuleSet(rules []LintRule) (*LintRuleSet, error) {
+	s := &LintRuleSet{}
+	for i, r := range rules {
+		if r.Name == "" {
+			r.Name = strconv.Itoa(i)
+		}
+		switch r.Severity {
+		case "":
+			r.Severity = LintRuleSeverityError
+		case LintRuleSeverityError, LintRuleSeverityWarning:
+		default:
+			return nil, fmt.Errorf("rule '%v': severity '%v' not recognised", r.Name, r.Severity)
+		}
+		if r.Component != "" {
+			if _, exists := componentKinds[r.Component]; !exists {
+				return nil, fmt.Errorf("rule '%v': component '%v' not recognised", r.Name, r.Component)
+			}
+		} else if r.Type != "" {
+			return nil, fmt.Errorf("rule '%v': a type can only be specified along with a component", r.Name)
+		}
+		if r.Check == "" {
+			return nil, fmt.Errorf("rule '%v': a check must be specified", r.Name)
+		}
+
+		exec, err := bloblang.NewMapping("", r.Check)
+		if err != nil {
+			if perr, ok := err.(*parser.Error); ok {
+				err = errors.New(perr.ErrorAtPosition([]rune(r.Check)))
+			}
+			return nil, fmt.Errorf("rule '%v': failed to parse check: %v", r.Name, err)
+		}
+		s.rules = append(s.rules, compiledLintRule{
+			LintRule: r,
+			exec:     exec,
+		})
+	}
+	return s, nil
+}
+
+// ReadLintRules reads a file of custom lint rules, which is an object with a
+// `rules` field containing an array of rules.
+func ReadLintRules(path string) (*LintRuleSet, error) {
+	rulesBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rulesFile struct {
+		Rules []LintRule `yaml:"rules"`
+	}
+	if err = yaml.Unmarshal(rulesBytes, &rulesFile); err != nil {
+		return nil, fmt.Errorf("failed to parse lint rules '%v': %v", path, err)
+	}
+	s, err := NewLintRuleSet(rulesFile.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lint rules '%v': %v", path, err)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Lint executes each rule against a sanitised version of a config and returns
+// any violations.
+func (s *LintRuleSet) Lint(conf Type) ([]LintRuleViolation, error) {
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return nil, err
+	}
+	var root interface{}
+	jBytes, err := json.Marshal(sanit)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(jBytes, &root); err != nil {
+		return nil, err
+	}
+
+	var violations []LintRuleViolation
+	for _, r := range s.rules {
+		if r.Component == "" {
+			if v, failed := r.check("", root); failed {
+				violations = append(violations, v)
+			}
+			continue
+		}
+		walkComponents("", "", root, func(path, kind, cType string, value interface{}) {
+			if kind != r.Component || (r.Type != "" && r.Type != cType) {
+				return
+			}
+			if v, failed := r.check(path, value); failed {
+				violations = append(violations, v)
+			}
+		})
+	}
+	return violations, nil
+}
+
+// check executes the rule against a value and returns a violation if the
+// result is anything other than true.
+func (r compiledLintRule) check(path string, value interface{}) (LintRuleViolation, bool) {
+	v := LintRuleViolation{
+		Rule:     r.Name,
+		Severity: r.Severity,
+		Path:     path,
+		Message:  r.Description,
+	}
+	if v.Message == "" {
+		v.Message = "check failed"
+	}
+
+	msg := message.New(nil)
+	res, err := r.exec.Exec(query.FunctionContext{
+		Maps:     map[string]query.Function{},
+		Vars:     map[string]interface{}{},
+		MsgBatch: msg,
+	}.WithValue(value))
+	if err != nil {
+		v.Message = fmt.Sprintf("%v: failed to execute check: %v", v.Message, err)
+		return v, true
+	}
+	if pass, _ := res.(bool); pass {
+		return v, false
+	}
+	return v, true
+}
+
+// walkComponents walks a generic config and calls fn for each component, which
+// is an object with a `type` field that is the value of a key describing a
+// kind of component, an element of an array of such values, or a value of an
+// object of named components such as resources.
+func walkComponents(path, kind string, value interface{}, fn func(path, kind, cType string, value interface{})) {
+	childPath := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch t := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		if kind != "" {
+			if cType, ok := t["type"].(string); ok {
+				fn(path, kind, cType, t)
+			} else {
+				for _, k := range keys {
+					walkComponents(childPath(k), kind, t[k], fn)
+				}
+				return
+			}
+		}
+		for _, k := range keys {
+			walkComponents(childPath(k), componentKinds[k], t[k], fn)
+		}
+	case []interface{}:
+		for i, v := range t {
+			walkComponents(childPath(strconv.Itoa(i)), kind, v, fn)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
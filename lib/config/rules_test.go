@@ -0,0 +1,114 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLintRulesErrors(t *testing.T) {
+	tests := map[string]struct {
+		rule LintRule
+		err  string
+	}{
+		"bad severity": {
+			rule: LintRule{Name: "foo", Severity: "nope", Check: "true"},
+			err:  "rule 'foo': severity 'nope' not recognised",
+		},
+		"bad component": {
+			rule: LintRule{Name: "foo", Component: "nope", Check: "true"},
+			err:  "rule 'foo': component 'nope' not recognised",
+		},
+		"type without component": {
+			rule: LintRule{Name: "foo", Type: "kafka", Check: "true"},
+			err:  "rule 'foo': a type can only be specified along with a component",
+		},
+		"no check": {
+			rule: LintRule{Name: "foo"},
+			err:  "rule 'foo': a check must be specified",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := NewLintRuleSet([]LintRule{test.rule})
+		assert.EqualError(t, err, test.err, name)
+	}
+
+	_, err := NewLintRuleSet([]LintRule{{Name: "foo", Check: "this.foo >"}})
+	assert.Error(t, err)
+}
+
+func TestLintRules(t *testing.T) {
+	conf := New()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+pipeline:
+  processors:
+    - bloblang: 'root = this'
+output:
+  broker:
+    outputs:
+      - kafka:
+          addresses: [ localhost:9092 ]
+          topic: foo
+          max_in_flight: 10
+      - kafka:
+          addresses: [ localhost:9092 ]
+          topic: bar
+resources:
+  outputs:
+    baz:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: baz
+`), &conf))
+
+	rules, err := NewLintRuleSet([]LintRule{
+		{
+			Name:        "kafka_max_in_flight",
+			Description: "kafka outputs must set max_in_flight",
+			Component:   "output",
+			Type:        "kafka",
+			Check:       "this.kafka.max_in_flight > 1",
+		},
+		{
+			Name:        "no_stdout",
+			Description: "stdout must not be used",
+			Severity:    LintRuleSeverityWarning,
+			Component:   "output",
+			Check:       `this.type != "stdout"`,
+		},
+		{
+			Name:        "has_processors",
+			Description: "pipelines must have processors",
+			Severity:    LintRuleSeverityWarning,
+			Check:       "this.pipeline.processors.length() > 0",
+		},
+		{
+			Name:        "no_kafka_input",
+			Description: "kafka inputs are banned",
+			Severity:    LintRuleSeverityWarning,
+			Component:   "input",
+			Check:       `this.type != "kafka"`,
+		},
+	})
+	require.NoError(t, err)
+
+	violations, err := rules.Lint(conf)
+	require.NoError(t, err)
+
+	var act []string
+	for _, v := range violations {
+		act = append(act, v.Severity+": "+v.String())
+	}
+	assert.Equal(t, []string{
+		"error: path 'output.broker.outputs.1': rule 'kafka_max_in_flight': kafka outputs must set max_in_flight",
+		"error: path 'resources.outputs.baz': rule 'kafka_max_in_flight': kafka outputs must set max_in_flight",
+		"warning: path 'input': rule 'no_kafka_input': kafka inputs are banned",
+	}, act)
+}
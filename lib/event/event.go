@@ -0,0 +1,144 @@
+package event
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// Type is the type of a lifecycle event.
+type Type string
+
+// Lifecycle event types.
+const (
+	TypeInputConnected     Type = "input_connected"
+	TypeInputDisconnected  Type = "input_disconnected"
+	TypeOutputConnected    Type = "output_connected"
+	TypeOutputDisconnected Type = "output_disconnected"
+	TypeOutputWriteFailed  Type = "output_write_failed"
+	TypeProcessorError     Type = "processor_error"
+	TypeShutdownStarted    Type = "shutdown_started"
+	TypeSheddingStarted    Type = "load_shedding_started"
+	TypeSheddingStopped    Type = "load_shedding_stopped"
+)
+
+// Event describes a lifecycle event of a component.
+type Event struct {
+	// Type is the type of event.
+	Type Type
+
+	// Component is the type of component that emitted the event, e.g.
+	// `kafka`, or empty if it is not known.
+	Component string
+
+	// Err is the error associated with the event, if any.
+	Err error
+
+	// Timestamp is the time at which the event occurred.
+	Timestamp time.Time
+}
+
+// Hook is a function that is called for each lifecycle event. Hooks are
+// called synchronously by the component emitting the event and therefore must
+// not block.
+type Hook func(e Event)
+
+//------------------------------------------------------------------------------
+
+type registeredHook struct {
+	id int64
+	fn Hook
+}
+
+var (
+	hooksMut sync.Mutex
+	hooks    atomic.Value // []registeredHook
+	nextID   int64
+)
+
+// AddHook registers a hook to be called for every lifecycle event emitted by
+// components within this process. The returned function removes the hook.
+func AddHook(fn Hook) (remove func()) {
+	hooksMut.Lock()
+	defer hooksMut.Unlock()
+
+	nextID++
+	id := nextID
+
+	current, _ := hooks.Load().([]registeredHook)
+	updated := make([]registeredHook, 0, len(current)+1)
+	updated = append(updated, current...)
+	hooks.Store(append(updated, registeredHook{id: id, fn: fn}))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			hooksMut.Lock()
+			defer hooksMut.Unlock()
+
+			current, _ := hooks.Load().([]registeredHook)
+			updated := make([]registeredHook, 0, len(current))
+			for _, h := range current {
+				if h.id != id {
+					updated = append(updated, h)
+				}
+			}
+			hooks.Store(updated)
+		})
+	}
+}
+
+// Subscribe returns a channel that receives every lifecycle event emitted by
+// components within this process, and a function that ends the subscription
+// and closes the channel. Events are dropped rather than blocking components
+// when the buffer of the channel is full.
+func Subscribe(bufferSize int) (events <-chan Event, cancel func()) {
+	eventsChan := make(chan Event, bufferSize)
+
+	var mut sync.Mutex
+	closed := false
+
+	remove := AddHook(func(e Event) {
+		mut.Lock()
+		defer mut.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case eventsChan <- e:
+		default:
+		}
+	})
+
+	return eventsChan, func() {
+		remove()
+		mut.Lock()
+		if !closed {
+			closed = true
+			close(eventsChan)
+		}
+		mut.Unlock()
+	}
+}
+
+// Emit calls all registered hooks with an event. When no hooks are registered
+// this is a cheap no-op.
+func Emit(t Type, component string, err error) {
+	current, _ := hooks.Load().([]registeredHook)
+	if len(current) == 0 {
+		return
+	}
+	e := Event{
+		Type:      t,
+		Component: component,
+		Err:       err,
+		Timestamp: time.Now(),
+	}
+	for _, h := range current {
+		h.fn(e)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,56 @@
+package event
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	var fooEvents, barEvents []Event
+
+	removeFoo := AddHook(func(e Event) {
+		fooEvents = append(fooEvents, e)
+	})
+	removeBar := AddHook(func(e Event) {
+		barEvents = append(barEvents, e)
+	})
+	defer removeBar()
+
+	errNope := errors.New("nope")
+	Emit(TypeInputConnected, "kafka", nil)
+	removeFoo()
+	removeFoo()
+	Emit(TypeOutputWriteFailed, "http_client", errNope)
+
+	require.Len(t, fooEvents, 1)
+	assert.Equal(t, TypeInputConnected, fooEvents[0].Type)
+	assert.Equal(t, "kafka", fooEvents[0].Component)
+	assert.NoError(t, fooEvents[0].Err)
+	assert.False(t, fooEvents[0].Timestamp.IsZero())
+
+	require.Len(t, barEvents, 2)
+	assert.Equal(t, TypeOutputWriteFailed, barEvents[1].Type)
+	assert.Equal(t, "http_client", barEvents[1].Component)
+	assert.Equal(t, errNope, barEvents[1].Err)
+}
+
+func TestSubscribe(t *testing.T) {
+	events, cancel := Subscribe(2)
+
+	Emit(TypeInputConnected, "foo", nil)
+	Emit(TypeInputDisconnected, "foo", nil)
+	Emit(TypeShutdownStarted, "", nil)
+
+	cancel()
+	cancel()
+	Emit(TypeShutdownStarted, "", nil)
+
+	var types []Type
+	for e := range events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []Type{TypeInputConnected, TypeInputDisconnected}, types)
+}
@@ -0,0 +1,5 @@
+// Package event provides a way to register hooks for the lifecycle events of
+// Benthos components, such as inputs connecting or outputs failing to write,
+// which allows services embedding Benthos to wire up alerting and custom
+// bookkeeping without parsing logs.
+package event
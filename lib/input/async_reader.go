@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/event"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
@@ -32,6 +33,7 @@ type AsyncReader struct {
 	log   log.Modular
 
 	connThrot *throttle.Type
+	activity  types.ConnectionActivity
 
 	transactions chan types.Transaction
 
@@ -68,7 +70,11 @@ func NewAsyncReader(
 		closedChan:    make(chan struct{}),
 	}
 
-	rdr.connThrot = throttle.New(throttle.OptCloseChan(ctx.Done()))
+	rdr.connThrot = throttle.New(
+		throttle.OptCloseChan(ctx.Done()),
+		throttle.OptOnStateChange(retryStateGauges(stats)),
+	)
+	rdr.activity.SetBackoff(rdr.connThrot)
 
 	go rdr.loop()
 	return rdr, nil
@@ -116,6 +122,7 @@ func (r *AsyncReader) loop() {
 				return
 			}
 			r.log.Errorf("Failed to connect to %v: %v\n", r.typeStr, err)
+			r.activity.SetError(err)
 			mFailedConn.Incr(1)
 			if !r.connThrot.Retry() {
 				return
@@ -126,6 +133,7 @@ func (r *AsyncReader) loop() {
 		}
 	}
 	mConn.Incr(1)
+	event.Emit(event.TypeInputConnected, r.typeStr, nil)
 	atomic.StoreInt32(&r.connected, 1)
 
 	for atomic.LoadInt32(&r.running) == 1 {
@@ -134,6 +142,7 @@ func (r *AsyncReader) loop() {
 		// If our reader says it is not connected.
 		if err == types.ErrNotConnected {
 			mLostConn.Incr(1)
+			event.Emit(event.TypeInputDisconnected, r.typeStr, nil)
 			atomic.StoreInt32(&r.connected, 0)
 
 			// Continue to try to reconnect while still active.
@@ -145,9 +154,11 @@ func (r *AsyncReader) loop() {
 					}
 
 					r.log.Errorf("Failed to reconnect to %v: %v\n", r.typeStr, err)
+					r.activity.SetError(err)
 					mFailedConn.Incr(1)
 				} else if msg, ackFn, err = r.reader.ReadWithContext(r.ctx); err != types.ErrNotConnected {
 					mConn.Incr(1)
+					event.Emit(event.TypeInputConnected, r.typeStr, nil)
 					atomic.StoreInt32(&r.connected, 1)
 					r.connThrot.Reset()
 					break
@@ -166,6 +177,7 @@ func (r *AsyncReader) loop() {
 		if err != nil || msg == nil {
 			if err != nil && err != types.ErrTimeout && err != types.ErrNotConnected {
 				r.log.Errorf("Failed to read message: %v\n", err)
+				r.activity.SetError(err)
 			}
 			if !r.connThrot.Retry() {
 				return
@@ -173,6 +185,7 @@ func (r *AsyncReader) loop() {
 			continue
 		} else {
 			r.connThrot.Reset()
+			r.activity.SetActive()
 			mCount.Incr(1)
 			mPartsRcvd.Incr(int64(msg.Len()))
 			mRcvd.Incr(1)
@@ -180,7 +193,7 @@ func (r *AsyncReader) loop() {
 		}
 
 		resChan := make(chan types.Response)
-		tracing.InitSpans("input_"+r.typeStr, msg)
+		tracing.InitSpansFromMetadata("input_"+r.typeStr, msg, tracing.ComponentTags("input", r.typeStr, msg.Len()))
 		select {
 		case r.transactions <- types.NewTransaction(msg, resChan):
 		case <-r.ctx.Done():
@@ -216,7 +229,9 @@ func (r *AsyncReader) loop() {
 				res = response.NewNoack()
 				r.CloseAsync()
 			}
-			mLatency.Timing(time.Since(m.CreatedAt()).Nanoseconds())
+			metrics.TimingWithTraceID(mLatency, time.Since(m.CreatedAt()).Nanoseconds(), func() string {
+				return tracing.BatchTraceID(m)
+			})
 			tracing.FinishSpans(m)
 			if err = aFn(r.fullyCloseCtx, res); err != nil {
 				r.log.Errorf("Failed to acknowledge message: %v\n", err)
@@ -237,6 +252,11 @@ func (r *AsyncReader) Connected() bool {
 	return atomic.LoadInt32(&r.connected) == 1
 }
 
+// ConnectionStatuses returns the connection status of this input.
+func (r *AsyncReader) ConnectionStatuses() []types.ConnectionStatus {
+	return []types.ConnectionStatus{r.activity.Status(r.typeStr, r.Connected())}
+}
+
 // CloseAsync shuts down the AsyncReader input and stops processing requests.
 func (r *AsyncReader) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
@@ -178,6 +178,11 @@ func (m *Batcher) Connected() bool {
 	return m.child.Connected()
 }
 
+// ConnectionStatuses returns the connection status of the underlying input.
+func (m *Batcher) ConnectionStatuses() []types.ConnectionStatus {
+	return types.ConnectionStatusesOf("", m.child)
+}
+
 // TransactionChan returns the channel used for consuming messages from this
 // buffer.
 func (m *Batcher) TransactionChan() <-chan types.Transaction {
@@ -6,6 +6,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/pipeline"
 	"github.com/Jeffail/benthos/v3/lib/processor"
@@ -76,7 +77,9 @@ func constructProcessors(
 				}
 				*i++
 			}
-			return pipeline.NewProcessor(log, stats, processors...), nil
+			proc := pipeline.NewProcessor(log, stats, processors...)
+			proc.SetJourneyTap(journey.FromManager(mgr))
+			return proc, nil
 		}}, pipelines...)
 	}
 	return hasBatchProc, pipelines
@@ -5,6 +5,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/event"
 	"github.com/Jeffail/benthos/v3/lib/input/reader"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
@@ -27,6 +28,7 @@ type Reader struct {
 	log   log.Modular
 
 	connThrot *throttle.Type
+	activity  types.ConnectionActivity
 
 	transactions chan types.Transaction
 	responses    chan types.Response
@@ -58,7 +60,11 @@ func NewReader(
 		closedChan:     make(chan struct{}),
 	}
 
-	rdr.connThrot = throttle.New(throttle.OptCloseChan(rdr.closeChan))
+	rdr.connThrot = throttle.New(
+		throttle.OptCloseChan(rdr.closeChan),
+		throttle.OptOnStateChange(retryStateGauges(stats)),
+	)
+	rdr.activity.SetBackoff(rdr.connThrot)
 
 	go rdr.loop()
 	return rdr, nil
@@ -97,6 +103,7 @@ func (r *Reader) loop() {
 				return
 			}
 			r.log.Errorf("Failed to connect to %v: %v\n", r.typeStr, err)
+			r.activity.SetError(err)
 			mFailedConn.Incr(1)
 			if !r.connThrot.Retry() {
 				return
@@ -107,6 +114,7 @@ func (r *Reader) loop() {
 		}
 	}
 	mConn.Incr(1)
+	event.Emit(event.TypeInputConnected, r.typeStr, nil)
 	atomic.StoreInt32(&r.connected, 1)
 
 	for atomic.LoadInt32(&r.running) == 1 {
@@ -115,6 +123,7 @@ func (r *Reader) loop() {
 		// If our reader says it is not connected.
 		if err == types.ErrNotConnected {
 			mLostConn.Incr(1)
+			event.Emit(event.TypeInputDisconnected, r.typeStr, nil)
 			atomic.StoreInt32(&r.connected, 0)
 
 			// Continue to try to reconnect while still active.
@@ -126,9 +135,11 @@ func (r *Reader) loop() {
 					}
 
 					r.log.Errorf("Failed to reconnect to %v: %v\n", r.typeStr, err)
+					r.activity.SetError(err)
 					mFailedConn.Incr(1)
 				} else if msg, err = r.reader.Read(); err != types.ErrNotConnected {
 					mConn.Incr(1)
+					event.Emit(event.TypeInputConnected, r.typeStr, nil)
 					atomic.StoreInt32(&r.connected, 1)
 					r.connThrot.Reset()
 					break
@@ -147,6 +158,7 @@ func (r *Reader) loop() {
 		if err != nil || msg == nil {
 			if err != types.ErrTimeout && err != types.ErrNotConnected {
 				r.log.Errorf("Failed to read message: %v\n", err)
+				r.activity.SetError(err)
 			}
 			if !r.connThrot.Retry() {
 				return
@@ -154,13 +166,14 @@ func (r *Reader) loop() {
 			continue
 		} else {
 			r.connThrot.Reset()
+			r.activity.SetActive()
 			mCount.Incr(1)
 			mPartsRcvd.Incr(int64(msg.Len()))
 			mRcvd.Incr(1)
 			r.log.Tracef("Consumed %v messages from '%v'.\n", msg.Len(), r.typeStr)
 		}
 
-		tracing.InitSpans("input_"+r.typeStr, msg)
+		tracing.InitSpansFromMetadata("input_"+r.typeStr, msg, tracing.ComponentTags("input", r.typeStr, msg.Len()))
 		select {
 		case r.transactions <- types.NewTransaction(msg, r.responses):
 		case <-r.closeChan:
@@ -188,7 +201,9 @@ func (r *Reader) loop() {
 				r.log.Errorf("Failed to acknowledge message: %v\n", err)
 			}
 			tTaken := time.Since(msg.CreatedAt()).Nanoseconds()
-			mLatency.Timing(tTaken)
+			metrics.TimingWithTraceID(mLatency, tTaken, func() string {
+				return tracing.BatchTraceID(msg)
+			})
 		}
 		tracing.FinishSpans(msg)
 	}
@@ -206,6 +221,11 @@ func (r *Reader) Connected() bool {
 	return atomic.LoadInt32(&r.connected) == 1
 }
 
+// ConnectionStatuses returns the connection status of this input.
+func (r *Reader) ConnectionStatuses() []types.ConnectionStatus {
+	return []types.ConnectionStatus{r.activity.Status(r.typeStr, r.Connected())}
+}
+
 // CloseAsync shuts down the Reader input and stops processing requests.
 func (r *Reader) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&r.running, 1, 0) {
@@ -622,3 +622,76 @@ func TestReaderSkipAcks(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestReaderConnectionStatuses(t *testing.T) {
+	readerImpl := newMockReader()
+	readerImpl.msgToSnd = message.New([][]byte{[]byte("foo")})
+
+	r, err := NewReader(
+		"foo", readerImpl,
+		log.Noop(), metrics.Noop(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case readerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case readerImpl.readChan <- errors.New("nope"):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case readerImpl.readChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	var ts types.Transaction
+	select {
+	case ts = <-r.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	statuses := r.(types.ConnectionStatuser).ConnectionStatuses()
+	if exp, act := 1, len(statuses); exp != act {
+		t.Fatalf("Wrong count of statuses: %v != %v", act, exp)
+	}
+	if exp, act := "foo", statuses[0].Type; exp != act {
+		t.Errorf("Wrong type: %v != %v", act, exp)
+	}
+	if !statuses[0].Connected {
+		t.Error("Expected connected status")
+	}
+	if statuses[0].LastError == nil || statuses[0].LastError.Error() != "nope" {
+		t.Errorf("Wrong last error: %v", statuses[0].LastError)
+	}
+	if statuses[0].LastActive.IsZero() || statuses[0].LastActive.Before(statuses[0].LastErrorAt) {
+		t.Errorf("Wrong last active time: %v", statuses[0].LastActive)
+	}
+
+	go func() {
+		select {
+		case readerImpl.ackChan <- nil:
+		case <-time.After(time.Second):
+		}
+	}()
+	select {
+	case ts.ResponseChan <- response.NewAck():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	r.CloseAsync()
+	close(readerImpl.readChan)
+	close(readerImpl.connChan)
+
+	if err = r.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -59,6 +59,11 @@ func (i *WithPipeline) Connected() bool {
 	return i.in.Connected()
 }
 
+// ConnectionStatuses returns the connection status of the wrapped input.
+func (i *WithPipeline) ConnectionStatuses() []types.ConnectionStatus {
+	return types.ConnectionStatusesOf("", i.in)
+}
+
 //------------------------------------------------------------------------------
 
 // CloseAsync triggers a closure of this object but does not block.
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+//------------------------------------------------------------------------------
+
+// journaldSocket is the path of the socket of the native journal protocol.
+const journaldSocket = "/run/systemd/journal/socket"
+
+// journaldWriter writes log lines to the systemd journal using the native
+// journal protocol, where each line is sent as a single datagram.
+type journaldWriter struct {
+	identifier string
+	facility   string
+
+	mut  sync.Mutex
+	conn *net.UnixConn
+	addr *net.UnixAddr
+}
+
+func newJournaldWriter(socketPath string, conf JournaldConfig) (*journaldWriter, error) {
+	facility, err := facilityToInt(conf.Facility)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &journaldWriter{
+		identifier: conf.Identifier,
+		facility:   strconv.Itoa(facility),
+		addr:       &net.UnixAddr{Name: socketPath, Net: "unixgram"},
+	}
+	if w.conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"}); err != nil {
+		return nil, fmt.Errorf("failed to open journald socket: %v", err)
+	}
+	return w, nil
+}
+
+// appendField appends a field of the native journal protocol, values that
+// contain newlines are encoded with an explicit length.
+func appendField(buf *bytes.Buffer, key string, value []byte) {
+	buf.WriteString(key)
+	if bytes.IndexByte(value, '\n') == -1 {
+		buf.WriteByte('=')
+		buf.Write(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.Write(size[:])
+	buf.Write(value)
+	buf.WriteByte('\n')
+}
+
+// WriteLevel writes a log line with the priority of its level.
+func (w *journaldWriter) WriteLevel(level string, p []byte) (int, error) {
+	var buf bytes.Buffer
+	appendField(&buf, "MESSAGE", bytes.TrimSuffix(p, []byte("\n")))
+	appendField(&buf, "PRIORITY", []byte(strconv.Itoa(levelToSeverity(level))))
+	appendField(&buf, "SYSLOG_FACILITY", []byte(w.facility))
+	if w.identifier != "" {
+		appendField(&buf, "SYSLOG_IDENTIFIER", []byte(w.identifier))
+	}
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	if _, err := w.conn.WriteToUnix(buf.Bytes(), w.addr); err != nil {
+		return 0, fmt.Errorf("failed to write to journald: %v", err)
+	}
+	return len(p), nil
+}
+
+// Write writes a log line with the info priority.
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel("INFO", p)
+}
+
+//------------------------------------------------------------------------------
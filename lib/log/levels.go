@@ -0,0 +1,127 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//------------------------------------------------------------------------------
+
+// LevelSetter is implemented by loggers that support changing the level of
+// logs at runtime.
+type LevelSetter interface {
+	// SetLevel overrides the level of logs emitted by a component and all of
+	// its children, where a component is identified by the name it is logged
+	// with, e.g. `benthos.output`. An empty component sets the level of all
+	// components. An empty level removes an existing override.
+	SetLevel(component, level string) error
+
+	// LevelOverrides returns a map of components to the level of logs that
+	// they have been overridden with, where an empty key is the override for
+	// all components.
+	LevelOverrides() map[string]string
+}
+
+//------------------------------------------------------------------------------
+
+// levelOverrides is shared by a logger and all of the loggers derived from it,
+// and allows the level of logs to be overridden for any of them at runtime.
+type levelOverrides struct {
+	active    int32
+	mut       sync.RWMutex
+	overrides map[string]int
+}
+
+func newLevelOverrides() *levelOverrides {
+	return &levelOverrides{
+		overrides: map[string]int{},
+	}
+}
+
+// levelFor returns the level of logs for a component, which is the override
+// with the longest matching component name, or the provided default level if
+// there are no overrides that match.
+func (o *levelOverrides) levelFor(component string, defaultLevel int) int {
+	if atomic.LoadInt32(&o.active) == 0 {
+		return defaultLevel
+	}
+
+	o.mut.RLock()
+	defer o.mut.RUnlock()
+
+	level, matchedLen := defaultLevel, -1
+	for k, v := range o.overrides {
+		if len(k) <= matchedLen {
+			continue
+		}
+		if k == "" || k == component || strings.HasPrefix(component, k+".") {
+			level, matchedLen = v, len(k)
+		}
+	}
+	return level
+}
+
+func (o *levelOverrides) set(component, level string) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if level == "" {
+		delete(o.overrides, component)
+	} else {
+		l := logLevelToInt(level)
+		if l < 0 {
+			return fmt.Errorf("log level not recognised: %v", level)
+		}
+		o.overrides[component] = l
+	}
+	atomic.StoreInt32(&o.active, int32(len(o.overrides)))
+	return nil
+}
+
+func (o *levelOverrides) get() map[string]string {
+	o.mut.RLock()
+	defer o.mut.RUnlock()
+
+	levels := make(map[string]string, len(o.overrides))
+	for k, v := range o.overrides {
+		levels[k] = intToLogLevel(v)
+	}
+	return levels
+}
+
+//------------------------------------------------------------------------------
+
+// getLevel returns the current level of logs for this logger.
+func (l *Logger) getLevel() int {
+	if l.overrides == nil {
+		return l.level
+	}
+	return l.overrides.levelFor(l.config.Prefix, l.level)
+}
+
+// SetLevel overrides the level of logs emitted by a component and all of its
+// children, where a component is identified by the name it is logged with,
+// e.g. `benthos.output`. An empty component sets the level of all components.
+// An empty level removes an existing override.
+//
+// Overrides are shared by all loggers derived from the same root logger.
+func (l *Logger) SetLevel(component, level string) error {
+	if l.overrides == nil {
+		return fmt.Errorf("this logger does not support runtime log levels")
+	}
+	return l.overrides.set(component, level)
+}
+
+// LevelOverrides returns a map of components to the level of logs that they
+// have been overridden with, where an empty key is the override for all
+// components.
+func (l *Logger) LevelOverrides() map[string]string {
+	if l.overrides == nil {
+		return map[string]string{}
+	}
+	return l.overrides.get()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelOverrides(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "logfmt"
+	loggerConfig.Prefix = "root"
+	loggerConfig.StaticFields = nil
+	loggerConfig.LogLevel = "WARN"
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	fooLogger := logger.NewModule(".foo")
+	fooBarLogger := fooLogger.NewModule(".bar")
+	fooBazLogger := logger.NewModule(".foobaz")
+
+	ls, ok := logger.(LevelSetter)
+	require.True(t, ok)
+
+	require.NoError(t, ls.SetLevel("root.foo", "DEBUG"))
+	require.NoError(t, ls.SetLevel("root.foo.bar", "ERROR"))
+	require.Error(t, ls.SetLevel("root", "nope"))
+
+	assert.Equal(t, map[string]string{
+		"root.foo":     "DEBUG",
+		"root.foo.bar": "ERROR",
+	}, ls.LevelOverrides())
+
+	logger.Infoln("root info")
+	fooLogger.Debugln("foo debug")
+	fooBarLogger.Warnln("foo bar warn")
+	fooBazLogger.Infoln("foobaz info")
+
+	require.NoError(t, ls.SetLevel("", "INFO"))
+	require.NoError(t, ls.SetLevel("root.foo", ""))
+
+	logger.Infoln("root info")
+	fooLogger.Debugln("foo debug")
+	fooBarLogger.Errorln("foo bar error")
+	fooBazLogger.Infoln("foobaz info")
+
+	expected := `level=DEBUG component=root.foo msg="foo debug"
+level=INFO component=root msg="root info"
+level=ERROR component=root.foo.bar msg="foo bar error"
+level=INFO component=root.foobaz msg="foobaz info"
+`
+
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func getMessageFieldsMapping(conf Config) (*mapping.Executor, error) {
+	if len(conf.MessageFieldsMapping) == 0 {
+		return nil, nil
+	}
+	m, err := bloblang.NewMapping("", conf.MessageFieldsMapping)
+	if err != nil {
+		if perr, ok := err.(*parser.Error); ok {
+			return nil, fmt.Errorf("failed to parse message fields mapping: %v", perr.ErrorAtPosition([]rune(conf.MessageFieldsMapping)))
+		}
+		return nil, fmt.Errorf("failed to parse message fields mapping: %v", err)
+	}
+	return m, nil
+}
+
+// WithMessageFields returns a logger with fields derived from a message part
+// added to its output, as described by the message_fields_mapping of the
+// logger config. The mapping must result in an object, the values of which are
+// converted into strings. If the logger does not have a mapping configured, or
+// the mapping fails, the logger is returned unchanged.
+func WithMessageFields(l Modular, index int, msg types.Message) Modular {
+	logger, ok := l.(*Logger)
+	if !ok || logger.messageFields == nil {
+		return l
+	}
+
+	p, err := logger.messageFields.MapPart(index, msg)
+	if err != nil || p == nil {
+		return l
+	}
+
+	jObj, err := p.JSON()
+	if err != nil {
+		return l
+	}
+	obj, ok := jObj.(map[string]interface{})
+	if !ok || len(obj) == 0 {
+		return l
+	}
+
+	fields := make(map[string]string, len(obj))
+	for k, v := range obj {
+		fields[k] = query.IToString(v)
+	}
+	return l.WithFields(fields)
+}
+
+//------------------------------------------------------------------------------
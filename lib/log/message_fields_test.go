@@ -0,0 +1,50 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageFields(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Prefix = "root"
+	loggerConfig.LogLevel = "WARN"
+	loggerConfig.StaticFields = map[string]string{
+		"@service": "benthos_service",
+	}
+	loggerConfig.MessageFieldsMapping = `root.tenant_id = meta("tenant_id")
+root.size = content().length()`
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("hello")})
+	msg.Get(1).Metadata().Set("tenant_id", "acme")
+
+	logger = logger.NewModule(".foo")
+	WithMessageFields(logger, 1, msg).Errorln("Failed to process message")
+	WithMessageFields(logger, 0, msg).Errorln("Failed to process message")
+	logger.Errorln("Not a message error")
+
+	expected := `{"@service":"benthos_service","size":"5","tenant_id":"acme","level":"ERROR","component":"root.foo","message":"Failed to process message"}
+{"@service":"benthos_service","level":"ERROR","component":"root.foo","message":"Failed to process message"}
+{"@service":"benthos_service","level":"ERROR","component":"root.foo","message":"Not a message error"}
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestMessageFieldsBadMapping(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.MessageFieldsMapping = `root.foo = this.`
+
+	_, err := NewV2(&bytes.Buffer{}, loggerConfig)
+	assert.Error(t, err)
+}
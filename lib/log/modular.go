@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"gopkg.in/yaml.v3"
 )
 
@@ -85,6 +86,14 @@ type Config struct {
 	AddTimeStamp bool              `json:"add_timestamp" yaml:"add_timestamp"`
 	JSONFormat   bool              `json:"json_format" yaml:"json_format"`
 	StaticFields map[string]string `json:"static_fields" yaml:"static_fields"`
+
+	// MessageFieldsMapping is a Bloblang mapping executed against messages
+	// that fail processing, the resulting object is added as fields to the
+	// error logs of processors.
+	MessageFieldsMapping string `json:"message_fields_mapping" yaml:"message_fields_mapping"`
+
+	Sampling SamplingConfig `json:"sampling" yaml:"sampling"`
+	Target   TargetConfig   `json:"target" yaml:"target"`
 }
 
 // NewConfig returns a config struct with the default values for each field.
@@ -98,6 +107,8 @@ func NewConfig() Config {
 		StaticFields: map[string]string{
 			"@service": "benthos",
 		},
+		Sampling: NewSamplingConfig(),
+		Target:   NewTargetConfig(),
 	}
 }
 
@@ -118,6 +129,15 @@ func (conf Config) Sanitised(removeDeprecated bool) (interface{}, error) {
 	if removeDeprecated && conf.JSONFormat {
 		delete(hashMap, "json_format")
 	}
+	if conf.MessageFieldsMapping == "" {
+		delete(hashMap, "message_fields_mapping")
+	}
+	if !conf.Sampling.Enabled {
+		delete(hashMap, "sampling")
+	}
+	if conf.Target.Type == "" || conf.Target.Type == "stdout" {
+		delete(hashMap, "target")
+	}
 
 	return hashMap, nil
 }
@@ -169,10 +189,13 @@ func (conf *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // Logger is an object with support for levelled logging and modular components.
 type Logger struct {
-	stream    io.Writer
-	config    Config
-	level     int
-	formatter logFormatter
+	stream        io.Writer
+	config        Config
+	level         int
+	formatter     logFormatter
+	messageFields *mapping.Executor
+	sampler       *logSampler
+	overrides     *levelOverrides
 }
 
 // New creates and returns a new logger object.
@@ -183,15 +206,21 @@ func New(stream io.Writer, config Config) Modular {
 	}
 
 	logger := Logger{
-		stream: stream,
-		config: config,
-		level:  logLevelToInt(config.LogLevel),
+		stream:    stream,
+		config:    config,
+		level:     logLevelToInt(config.LogLevel),
+		overrides: newLevelOverrides(),
 	}
 
 	logger.formatter, _ = getFormatter(config)
 	if logger.formatter == nil {
 		logger.formatter = deprecatedFormatter(config)
 	}
+	logger.messageFields, _ = getMessageFieldsMapping(config)
+	logger.sampler, _ = newLogSampler(config.Sampling)
+	if target, _ := newTarget(config.Target, stream); target != nil {
+		logger.stream = target
+	}
 	return &logger
 }
 
@@ -203,15 +232,25 @@ func NewV2(stream io.Writer, config Config) (Modular, error) {
 	}
 
 	logger := Logger{
-		stream: stream,
-		config: config,
-		level:  logLevelToInt(config.LogLevel),
+		stream:    stream,
+		config:    config,
+		level:     logLevelToInt(config.LogLevel),
+		overrides: newLevelOverrides(),
 	}
 
 	var err error
 	if logger.formatter, err = getFormatter(config); err != nil {
 		return nil, err
 	}
+	if logger.messageFields, err = getMessageFieldsMapping(config); err != nil {
+		return nil, err
+	}
+	if logger.sampler, err = newLogSampler(config.Sampling); err != nil {
+		return nil, err
+	}
+	if logger.stream, err = newTarget(config.Target, stream); err != nil {
+		return nil, err
+	}
 	return &logger, nil
 }
 
@@ -239,10 +278,13 @@ func (l *Logger) NewModule(prefix string) Modular {
 	}
 
 	return &Logger{
-		stream:    l.stream,
-		config:    config,
-		level:     l.level,
-		formatter: formatter,
+		stream:        l.stream,
+		config:        config,
+		level:         l.level,
+		formatter:     formatter,
+		messageFields: l.messageFields,
+		sampler:       l.sampler,
+		overrides:     l.overrides,
 	}
 }
 
@@ -263,10 +305,13 @@ func (l *Logger) WithFields(fields map[string]string) Modular {
 	}
 
 	return &Logger{
-		stream:    l.stream,
-		config:    newConfig,
-		level:     l.level,
-		formatter: formatter,
+		stream:        l.stream,
+		config:        newConfig,
+		level:         l.level,
+		formatter:     formatter,
+		messageFields: l.messageFields,
+		sampler:       l.sampler,
+		overrides:     l.overrides,
 	}
 }
 
@@ -365,49 +410,60 @@ func getFormatter(conf Config) (logFormatter, error) {
 
 // write prints a log message with any configured extras prepended.
 func (l *Logger) write(message string, level string, other ...interface{}) {
-	l.formatter(l.stream, message, level, other...)
+	if l.sampler != nil && !l.sampler.allow(l, message, level, other...) {
+		return
+	}
+	l.formatter(l.output(level), message, level, other...)
+}
+
+// output returns the writer for a log message of a given level.
+func (l *Logger) output(level string) io.Writer {
+	if lw, ok := l.stream.(levelledWriter); ok {
+		return levelWriter{level: level, w: lw}
+	}
+	return l.stream
 }
 
 //------------------------------------------------------------------------------
 
 // Fatalf prints a fatal message to the console. Does NOT cause panic.
 func (l *Logger) Fatalf(format string, v ...interface{}) {
-	if LogFatal <= l.level {
+	if LogFatal <= l.getLevel() {
 		l.write(format, "FATAL", v...)
 	}
 }
 
 // Errorf prints an error message to the console.
 func (l *Logger) Errorf(format string, v ...interface{}) {
-	if LogError <= l.level {
+	if LogError <= l.getLevel() {
 		l.write(format, "ERROR", v...)
 	}
 }
 
 // Warnf prints a warning message to the console.
 func (l *Logger) Warnf(format string, v ...interface{}) {
-	if LogWarn <= l.level {
+	if LogWarn <= l.getLevel() {
 		l.write(format, "WARN", v...)
 	}
 }
 
 // Infof prints an information message to the console.
 func (l *Logger) Infof(format string, v ...interface{}) {
-	if LogInfo <= l.level {
+	if LogInfo <= l.getLevel() {
 		l.write(format, "INFO", v...)
 	}
 }
 
 // Debugf prints a debug message to the console.
 func (l *Logger) Debugf(format string, v ...interface{}) {
-	if LogDebug <= l.level {
+	if LogDebug <= l.getLevel() {
 		l.write(format, "DEBUG", v...)
 	}
 }
 
 // Tracef prints a trace message to the console.
 func (l *Logger) Tracef(format string, v ...interface{}) {
-	if LogTrace <= l.level {
+	if LogTrace <= l.getLevel() {
 		l.write(format, "TRACE", v...)
 	}
 }
@@ -416,42 +472,42 @@ func (l *Logger) Tracef(format string, v ...interface{}) {
 
 // Fatalln prints a fatal message to the console. Does NOT cause panic.
 func (l *Logger) Fatalln(message string) {
-	if LogFatal <= l.level {
+	if LogFatal <= l.getLevel() {
 		l.write(message, "FATAL")
 	}
 }
 
 // Errorln prints an error message to the console.
 func (l *Logger) Errorln(message string) {
-	if LogError <= l.level {
+	if LogError <= l.getLevel() {
 		l.write(message, "ERROR")
 	}
 }
 
 // Warnln prints a warning message to the console.
 func (l *Logger) Warnln(message string) {
-	if LogWarn <= l.level {
+	if LogWarn <= l.getLevel() {
 		l.write(message, "WARN")
 	}
 }
 
 // Infoln prints an information message to the console.
 func (l *Logger) Infoln(message string) {
-	if LogInfo <= l.level {
+	if LogInfo <= l.getLevel() {
 		l.write(message, "INFO")
 	}
 }
 
 // Debugln prints a debug message to the console.
 func (l *Logger) Debugln(message string) {
-	if LogDebug <= l.level {
+	if LogDebug <= l.getLevel() {
 		l.write(message, "DEBUG")
 	}
 }
 
 // Traceln prints a trace message to the console.
 func (l *Logger) Traceln(message string) {
-	if LogTrace <= l.level {
+	if LogTrace <= l.getLevel() {
 		l.write(message, "TRACE")
 	}
 }
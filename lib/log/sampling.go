@@ -0,0 +1,130 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// SamplingConfig contains configuration fields for limiting the rate at which
+// identical log messages are printed.
+type SamplingConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	Interval       string `json:"interval" yaml:"interval"`
+	MaxPerInterval int    `json:"max_per_interval" yaml:"max_per_interval"`
+}
+
+// NewSamplingConfig returns a SamplingConfig with default values.
+func NewSamplingConfig() SamplingConfig {
+	return SamplingConfig{
+		Enabled:        false,
+		Interval:       "1m",
+		MaxPerInterval: 10,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sampleEntry struct {
+	logger      *Logger
+	level       string
+	message     string
+	windowStart time.Time
+	count       int
+	suppressed  int
+}
+
+// logSampler tracks identical log messages, which are messages of the same
+// level and component with the same formatted content, and suppresses them
+// once they exceed a maximum count within an interval. When the interval of a
+// suppressed message ends a summary of the number of suppressed messages is
+// printed.
+type logSampler struct {
+	interval       time.Duration
+	maxPerInterval int
+
+	mut       sync.Mutex
+	entries   map[string]*sampleEntry
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+func newLogSampler(conf SamplingConfig) (*logSampler, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(conf.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sampling interval: %v", err)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("sampling interval must be greater than zero, got: %v", conf.Interval)
+	}
+	if conf.MaxPerInterval < 1 {
+		return nil, fmt.Errorf("sampling max_per_interval must be at least one, got: %v", conf.MaxPerInterval)
+	}
+	return &logSampler{
+		interval:       interval,
+		maxPerInterval: conf.MaxPerInterval,
+		entries:        map[string]*sampleEntry{},
+		now:            time.Now,
+	}, nil
+}
+
+// allow returns true if a log message should be printed. Summaries of messages
+// that were suppressed during an interval that has since ended are printed
+// before returning.
+func (s *logSampler) allow(l *Logger, message, level string, other ...interface{}) bool {
+	formatted := strings.TrimSuffix(fmt.Sprintf(message, other...), "\n")
+	key := level + "|" + l.config.Prefix + "|" + formatted
+
+	var summaries []*sampleEntry
+
+	s.mut.Lock()
+	now := s.now()
+	if !now.Before(s.nextSweep) {
+		for k, e := range s.entries {
+			if now.Sub(e.windowStart) >= s.interval {
+				if e.suppressed > 0 {
+					summaries = append(summaries, e)
+				}
+				delete(s.entries, k)
+			}
+		}
+		s.nextSweep = now.Add(s.interval)
+	}
+
+	allowed := true
+	e, exists := s.entries[key]
+	if !exists || now.Sub(e.windowStart) >= s.interval {
+		if exists && e.suppressed > 0 {
+			summaries = append(summaries, e)
+		}
+		s.entries[key] = &sampleEntry{
+			logger:      l,
+			level:       level,
+			message:     formatted,
+			windowStart: now,
+			count:       1,
+		}
+	} else if e.count >= s.maxPerInterval {
+		e.suppressed++
+		allowed = false
+	} else {
+		e.count++
+	}
+	s.mut.Unlock()
+
+	for _, e := range summaries {
+		e.logger.formatter(
+			e.logger.output(e.level), "Suppressed %v similar log messages within %v: %v", e.level,
+			e.suppressed, s.interval, e.message,
+		)
+	}
+	return allowed
+}
+
+//------------------------------------------------------------------------------
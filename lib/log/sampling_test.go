@@ -0,0 +1,68 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSamplingBadConfig(t *testing.T) {
+	for _, fn := range []func(c *SamplingConfig){
+		func(c *SamplingConfig) { c.Interval = "nope" },
+		func(c *SamplingConfig) { c.Interval = "0s" },
+		func(c *SamplingConfig) { c.MaxPerInterval = 0 },
+	} {
+		loggerConfig := NewConfig()
+		loggerConfig.Sampling.Enabled = true
+		fn(&loggerConfig.Sampling)
+
+		_, err := NewV2(&bytes.Buffer{}, loggerConfig)
+		assert.Error(t, err)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.AddTimeStamp = false
+	loggerConfig.Format = "logfmt"
+	loggerConfig.Prefix = "root"
+	loggerConfig.StaticFields = nil
+	loggerConfig.Sampling.Enabled = true
+	loggerConfig.Sampling.Interval = "1m"
+	loggerConfig.Sampling.MaxPerInterval = 2
+
+	var buf bytes.Buffer
+
+	logger, err := NewV2(&buf, loggerConfig)
+	require.NoError(t, err)
+
+	now := time.Unix(0, 0)
+	logger.(*Logger).sampler.now = func() time.Time { return now }
+
+	fooLogger := logger.NewModule(".foo")
+	for i := 0; i < 5; i++ {
+		fooLogger.Errorf("Failed to send: %v\n", "nope")
+		fooLogger.Infof("Different message %v\n", i)
+	}
+	logger.Errorf("Failed to send: %v\n", "nope")
+
+	now = now.Add(time.Minute)
+	fooLogger.Warnln("Next interval")
+
+	expected := `level=ERROR component=root.foo msg="Failed to send: nope"
+level=INFO component=root.foo msg="Different message 0"
+level=ERROR component=root.foo msg="Failed to send: nope"
+level=INFO component=root.foo msg="Different message 1"
+level=INFO component=root.foo msg="Different message 2"
+level=INFO component=root.foo msg="Different message 3"
+level=INFO component=root.foo msg="Different message 4"
+level=ERROR component=root msg="Failed to send: nope"
+level=ERROR component=root.foo msg="Suppressed 3 similar log messages within 1m0s: Failed to send: nope"
+level=WARN component=root.foo msg="Next interval"
+`
+
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,141 @@
+package log
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// syslogWriter writes log lines as RFC5424 messages to a syslog daemon. Over
+// UDP each message is sent as a single datagram, and over TCP messages are
+// framed with octet counting as described in RFC6587.
+type syslogWriter struct {
+	network  string
+	address  string
+	tlsConf  *tls.Config
+	facility int
+	appName  string
+	hostname string
+	procID   string
+
+	mut  sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogWriter(conf SyslogConfig) (*syslogWriter, error) {
+	if conf.Network != "udp" && conf.Network != "tcp" {
+		return nil, fmt.Errorf("syslog network '%v' not recognized, expected udp or tcp", conf.Network)
+	}
+	if conf.Address == "" {
+		return nil, errors.New("syslog address must not be empty")
+	}
+	facility, err := facilityToInt(conf.Facility)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &syslogWriter{
+		network:  conf.Network,
+		address:  conf.Address,
+		facility: facility,
+		appName:  conf.AppName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+	if w.appName == "" {
+		w.appName = "-"
+	}
+	if w.hostname, _ = os.Hostname(); w.hostname == "" {
+		w.hostname = "-"
+	}
+	if conf.TLS.Enabled {
+		if conf.Network != "tcp" {
+			return nil, errors.New("syslog tls can only be enabled with the tcp network")
+		}
+		if w.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Connect eagerly in order to catch configuration errors on start up, the
+	// connection is reestablished on subsequent write failures.
+	if err = w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect establishes a connection, the lock must be held.
+func (w *syslogWriter) connect() error {
+	var err error
+	if w.tlsConf != nil {
+		dialer := &net.Dialer{Timeout: time.Second * 5}
+		w.conn, err = tls.DialWithDialer(dialer, w.network, w.address, w.tlsConf)
+	} else {
+		w.conn, err = net.DialTimeout(w.network, w.address, time.Second*5)
+	}
+	if err != nil {
+		w.conn = nil
+		return fmt.Errorf("failed to connect to syslog: %v", err)
+	}
+	return nil
+}
+
+func (w *syslogWriter) format(level string, p []byte) []byte {
+	p = bytes.TrimSuffix(p, []byte("\n"))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(
+		&buf, "<%d>1 %v %v %v %v - - ",
+		w.facility*8+levelToSeverity(level),
+		time.Now().Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname, w.appName, w.procID,
+	)
+	buf.Write(p)
+
+	if w.network == "udp" {
+		return buf.Bytes()
+	}
+	framed := make([]byte, 0, buf.Len()+8)
+	framed = strconv.AppendInt(framed, int64(buf.Len()), 10)
+	framed = append(framed, ' ')
+	return append(framed, buf.Bytes()...)
+}
+
+// WriteLevel writes a log line with the severity of its level. When a write
+// fails the connection is reestablished and the write attempted once more.
+func (w *syslogWriter) WriteLevel(level string, p []byte) (int, error) {
+	msg := w.format(level, p)
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err := w.connect(); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.conn.Write(msg); err != nil {
+			w.conn.Close()
+			w.conn = nil
+			continue
+		}
+		return len(p), nil
+	}
+	return 0, errors.New("failed to write to syslog")
+}
+
+// Write writes a log line with the info severity.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel("INFO", p)
+}
+
+//------------------------------------------------------------------------------
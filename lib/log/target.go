@@ -0,0 +1,134 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+// TargetConfig contains configuration fields for the destination of logs.
+type TargetConfig struct {
+	Type     string         `json:"type" yaml:"type"`
+	Syslog   SyslogConfig   `json:"syslog" yaml:"syslog"`
+	Journald JournaldConfig `json:"journald" yaml:"journald"`
+}
+
+// SyslogConfig contains configuration fields for writing logs to a syslog
+// daemon in the RFC5424 format.
+type SyslogConfig struct {
+	Network  string      `json:"network" yaml:"network"`
+	Address  string      `json:"address" yaml:"address"`
+	Facility string      `json:"facility" yaml:"facility"`
+	AppName  string      `json:"app_name" yaml:"app_name"`
+	TLS      btls.Config `json:"tls" yaml:"tls"`
+}
+
+// JournaldConfig contains configuration fields for writing logs to the
+// systemd journal.
+type JournaldConfig struct {
+	Identifier string `json:"identifier" yaml:"identifier"`
+	Facility   string `json:"facility" yaml:"facility"`
+}
+
+// NewTargetConfig returns a TargetConfig with default values.
+func NewTargetConfig() TargetConfig {
+	return TargetConfig{
+		Type: "stdout",
+		Syslog: SyslogConfig{
+			Network:  "udp",
+			Address:  "localhost:514",
+			Facility: "local0",
+			AppName:  "benthos",
+			TLS:      btls.NewConfig(),
+		},
+		Journald: JournaldConfig{
+			Identifier: "benthos",
+			Facility:   "local0",
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// levelledWriter is implemented by log targets that record the severity of
+// each log line.
+type levelledWriter interface {
+	WriteLevel(level string, p []byte) (int, error)
+}
+
+type levelWriter struct {
+	level string
+	w     levelledWriter
+}
+
+func (l levelWriter) Write(p []byte) (int, error) {
+	return l.w.WriteLevel(l.level, p)
+}
+
+// newTarget returns the writer that logs should be written to. The provided
+// stream is used when the target type is stdout.
+func newTarget(conf TargetConfig, stream io.Writer) (io.Writer, error) {
+	switch conf.Type {
+	case "", "stdout":
+		return stream, nil
+	case "syslog":
+		return newSyslogWriter(conf.Syslog)
+	case "journald":
+		return newJournaldWriter(journaldSocket, conf.Journald)
+	}
+	return nil, fmt.Errorf("log target type '%v' not recognized", conf.Type)
+}
+
+//------------------------------------------------------------------------------
+
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+func facilityToInt(facility string) (int, error) {
+	f, exists := facilities[strings.ToLower(facility)]
+	if !exists {
+		return 0, fmt.Errorf("syslog facility '%v' not recognized", facility)
+	}
+	return f, nil
+}
+
+// levelToSeverity maps a log level onto a syslog severity.
+func levelToSeverity(level string) int {
+	switch level {
+	case "FATAL":
+		return 2 // crit
+	case "ERROR":
+		return 3 // err
+	case "WARN":
+		return 4 // warning
+	case "INFO":
+		return 6 // info
+	}
+	return 7 // debug
+}
+
+//------------------------------------------------------------------------------
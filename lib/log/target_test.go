@@ -0,0 +1,158 @@
+package log
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Target.Type = "nope"
+	_, err := NewV2(ioutil.Discard, conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Target.Type = "syslog"
+	conf.Target.Syslog.Facility = "nope"
+	_, err = NewV2(ioutil.Discard, conf)
+	assert.Error(t, err)
+
+	conf = NewConfig()
+	conf.Target.Type = "syslog"
+	conf.Target.Syslog.Network = "udp"
+	conf.Target.Syslog.TLS.Enabled = true
+	_, err = NewV2(ioutil.Discard, conf)
+	assert.Error(t, err)
+}
+
+func TestTargetSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	conf := NewConfig()
+	conf.Format = "logfmt"
+	conf.AddTimeStamp = false
+	conf.StaticFields = map[string]string{}
+	conf.Target.Type = "syslog"
+	conf.Target.Syslog.Address = pc.LocalAddr().String()
+	conf.Target.Syslog.Facility = "local1"
+	conf.Target.Syslog.AppName = "foo"
+
+	logger, err := NewV2(ioutil.Discard, conf)
+	require.NoError(t, err)
+
+	logger.Warnln("hello world")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+
+	// local1 (17) * 8 + warning (4)
+	assert.Regexp(t, regexp.MustCompile(
+		`^<140>1 \S+ \S+ foo `+strconv.Itoa(os.Getpid())+` - - level=WARN component=benthos msg="hello world"$`,
+	), string(buf[:n]))
+}
+
+func TestTargetSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			lenStr, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(lenStr))
+			msg := make([]byte, size)
+			if _, err = io.ReadFull(r, msg); err != nil {
+				return
+			}
+			lines <- string(msg)
+		}
+	}()
+
+	w, err := newSyslogWriter(SyslogConfig{
+		Network:  "tcp",
+		Address:  ln.Addr().String(),
+		Facility: "daemon",
+		AppName:  "bar",
+	})
+	require.NoError(t, err)
+
+	_, err = w.WriteLevel("ERROR", []byte("first\n"))
+	require.NoError(t, err)
+	_, err = w.WriteLevel("DEBUG", []byte("second\n"))
+	require.NoError(t, err)
+
+	for _, exp := range []string{
+		`^<27>1 \S+ \S+ bar \d+ - - first$`,
+		`^<31>1 \S+ \S+ bar \d+ - - second$`,
+	} {
+		select {
+		case line := <-lines:
+			assert.Regexp(t, regexp.MustCompile(exp), line)
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestTargetJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	w, err := newJournaldWriter(socketPath, JournaldConfig{
+		Identifier: "benthos",
+		Facility:   "local0",
+	})
+	require.NoError(t, err)
+
+	_, err = w.WriteLevel("WARN", []byte("hello world\n"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=hello world\nPRIORITY=4\nSYSLOG_FACILITY=16\nSYSLOG_IDENTIFIER=benthos\n", string(buf[:n]))
+
+	_, err = w.WriteLevel("INFO", []byte("foo\nbar"))
+	require.NoError(t, err)
+
+	n, err = conn.Read(buf)
+	require.NoError(t, err)
+
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, 7)
+	assert.Equal(t, "MESSAGE\n"+string(size)+"foo\nbar\nPRIORITY=6\nSYSLOG_FACILITY=16\nSYSLOG_IDENTIFIER=benthos\n", string(buf[:n]))
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/condition"
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/processor"
@@ -223,6 +224,8 @@ type Type struct {
 
 	pipes    map[string]<-chan types.Transaction
 	pipeLock sync.RWMutex
+
+	journeys *journey.Tap
 }
 
 // New returns an instance of manager.Type, which can be shared amongst
@@ -243,6 +246,7 @@ func New(
 		rateLimits: map[string]types.RateLimit{},
 		plugins:    map[string]interface{}{},
 		pipes:      map[string]<-chan types.Transaction{},
+		journeys:   journey.NewTap(),
 	}
 
 	// Sometimes resources of a type might refer to other resources of the same
@@ -458,6 +462,12 @@ func (t *Type) GetPlugin(name string) (interface{}, error) {
 	return nil, types.ErrPluginNotFound
 }
 
+// GetJourneyTap returns the tap that samples the journeys of messages through
+// the processors of pipelines sharing this manager.
+func (t *Type) GetJourneyTap() *journey.Tap {
+	return t.journeys
+}
+
 //------------------------------------------------------------------------------
 
 // CloseAsync triggers the shut down of all resource types that implement the
@@ -0,0 +1,283 @@
+package journey
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	// maxSnippetLen is the maximum number of bytes of message content recorded
+	// for each step of a journey.
+	maxSnippetLen = 256
+
+	// maxJourneys is the number of most recent journeys kept.
+	maxJourneys = 100
+
+	// maxSteps is the maximum number of steps recorded for a journey.
+	maxSteps = 100
+)
+
+// Step describes the execution of a single processor on a message part.
+type Step struct {
+	Processor string        `json:"processor"`
+	Offset    time.Duration `json:"offset_ns"`
+	Duration  time.Duration `json:"duration_ns"`
+	Before    string        `json:"before"`
+	After     []string      `json:"after"`
+	Dropped   bool          `json:"dropped,omitempty"`
+	Failed    bool          `json:"failed,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Journey describes each processor that a sampled message part passed
+// through.
+type Journey struct {
+	ID      int64     `json:"id"`
+	Started time.Time `json:"started"`
+	Input   string    `json:"input"`
+	Steps   []Step    `json:"steps"`
+}
+
+// recorder records the steps of a journey, and is shared by all parts derived
+// from a sampled part.
+type recorder struct {
+	mut     sync.Mutex
+	journey Journey
+}
+
+func (r *recorder) addStep(s Step) {
+	r.mut.Lock()
+	if len(r.journey.Steps) < maxSteps {
+		r.journey.Steps = append(r.journey.Steps, s)
+	}
+	r.mut.Unlock()
+}
+
+// snapshot returns a copy of the journey with steps sorted by the time at
+// which they started, as nested processors complete before their parents.
+func (r *recorder) snapshot() Journey {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	j := r.journey
+	j.Steps = make([]Step, len(r.journey.Steps))
+	copy(j.Steps, r.journey.Steps)
+	sort.SliceStable(j.Steps, func(a, b int) bool {
+		return j.Steps[a].Offset < j.Steps[b].Offset
+	})
+	return j
+}
+
+//------------------------------------------------------------------------------
+
+type journeyKey struct{}
+
+func getRecorder(p types.Part) *recorder {
+	r, _ := message.GetContext(p).Value(journeyKey{}).(*recorder)
+	return r
+}
+
+func snippet(p types.Part) string {
+	b := p.Get()
+	if len(b) > maxSnippetLen {
+		return string(b[:maxSnippetLen]) + "..."
+	}
+	return string(b)
+}
+
+//------------------------------------------------------------------------------
+
+// Tap samples message parts up to a rate per second and keeps their journeys.
+type Tap struct {
+	rate   int64
+	nextID int64
+
+	mut         sync.Mutex
+	windowStart time.Time
+	windowCount int64
+	journeys    []*recorder
+}
+
+// NewTap creates a tap with sampling disabled.
+func NewTap() *Tap {
+	return &Tap{}
+}
+
+// FromManager returns the tap of a manager, or nil if the manager does not have
+// one.
+func FromManager(mgr types.Manager) *Tap {
+	if tapProv, ok := mgr.(interface {
+		GetJourneyTap() *Tap
+	}); ok {
+		return tapProv.GetJourneyTap()
+	}
+	return nil
+}
+
+// SetRate sets the maximum number of message parts sampled per second, where
+// zero disables sampling.
+func (t *Tap) SetRate(perSecond int64) {
+	if perSecond < 0 {
+		perSecond = 0
+	}
+	atomic.StoreInt64(&t.rate, perSecond)
+}
+
+// Rate returns the maximum number of message parts sampled per second.
+func (t *Tap) Rate() int64 {
+	return atomic.LoadInt64(&t.rate)
+}
+
+// Recent returns the most recently sampled journeys, newest first.
+func (t *Tap) Recent() []Journey {
+	t.mut.Lock()
+	journeys := make([]*recorder, len(t.journeys))
+	copy(journeys, t.journeys)
+	t.mut.Unlock()
+
+	snapshots := make([]Journey, 0, len(journeys))
+	for i := len(journeys) - 1; i >= 0; i-- {
+		snapshots = append(snapshots, journeys[i].snapshot())
+	}
+	return snapshots
+}
+
+func (t *Tap) take() bool {
+	rate := atomic.LoadInt64(&t.rate)
+	if rate == 0 {
+		return false
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	if now := time.Now(); now.Sub(t.windowStart) >= time.Second {
+		t.windowStart = now
+		t.windowCount = 0
+	}
+	if t.windowCount >= rate {
+		return false
+	}
+	t.windowCount++
+	return true
+}
+
+func (t *Tap) keep(r *recorder) {
+	t.mut.Lock()
+	t.journeys = append(t.journeys, r)
+	if len(t.journeys) > maxJourneys {
+		t.journeys = t.journeys[len(t.journeys)-maxJourneys:]
+	}
+	t.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// Sample attaches a journey to message parts of a batch up to the rate of the
+// tap, after which each processor they pass through is recorded.
+func (t *Tap) Sample(msg types.Message) {
+	if atomic.LoadInt64(&t.rate) == 0 {
+		return
+	}
+	var parts []types.Part
+	msg.Iter(func(i int, p types.Part) error {
+		if getRecorder(p) != nil || !t.take() {
+			return nil
+		}
+		if parts == nil {
+			parts = make([]types.Part, msg.Len())
+			msg.Iter(func(k int, existing types.Part) error {
+				parts[k] = existing
+				return nil
+			})
+		}
+		r := &recorder{
+			journey: Journey{
+				ID:      atomic.AddInt64(&t.nextID, 1),
+				Started: time.Now(),
+				Input:   snippet(p),
+				Steps:   []Step{},
+			},
+		}
+		t.keep(r)
+		parts[i] = message.WithContext(context.WithValue(message.GetContext(p), journeyKey{}, r), p)
+		return nil
+	})
+	if parts != nil {
+		msg.SetAll(parts)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Execute runs a processor on a message and records a step for each part of
+// the message that was sampled by a tap.
+func Execute(proc types.Processor, msg types.Message) ([]types.Message, types.Response) {
+	type sampled struct {
+		rec    *recorder
+		before string
+	}
+	var samples []sampled
+	msg.Iter(func(i int, p types.Part) error {
+		if r := getRecorder(p); r != nil {
+			samples = append(samples, sampled{rec: r, before: snippet(p)})
+		}
+		return nil
+	})
+	if len(samples) == 0 {
+		return proc.ProcessMessage(msg)
+	}
+
+	started := time.Now()
+	msgs, res := proc.ProcessMessage(msg)
+	took := time.Since(started)
+
+	name := processorName(proc)
+	for _, s := range samples {
+		step := Step{
+			Processor: name,
+			Offset:    started.Sub(s.rec.journey.Started),
+			Duration:  took,
+			Before:    s.before,
+			After:     []string{},
+		}
+		for _, m := range msgs {
+			m.Iter(func(i int, p types.Part) error {
+				if getRecorder(p) == s.rec {
+					step.After = append(step.After, snippet(p))
+					if p.Metadata().Get(types.FailFlagKey) != "" {
+						step.Failed = true
+					}
+				}
+				return nil
+			})
+		}
+		if len(step.After) == 0 {
+			step.Dropped = true
+		}
+		if res != nil && res.Error() != nil {
+			step.Error = res.Error().Error()
+		}
+		s.rec.addStep(step)
+	}
+	return msgs, res
+}
+
+// processorName returns a readable name of a processor derived from its type,
+// e.g. `bloblang`.
+func processorName(proc types.Processor) string {
+	name := fmt.Sprintf("%T", proc)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(name)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,98 @@
+package journey
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fnProcessor func(msg types.Message) ([]types.Message, types.Response)
+
+func (f fnProcessor) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	return f(msg)
+}
+
+func (f fnProcessor) CloseAsync() {}
+
+func (f fnProcessor) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestJourneyDisabled(t *testing.T) {
+	tap := NewTap()
+
+	msg := message.New([][]byte{[]byte("foo")})
+	tap.Sample(msg)
+
+	upper := fnProcessor(func(msg types.Message) ([]types.Message, types.Response) {
+		newMsg := msg.Copy()
+		newMsg.Get(0).Set(bytes.ToUpper(msg.Get(0).Get()))
+		return []types.Message{newMsg}, nil
+	})
+	msgs, res := Execute(upper, msg)
+	require.Nil(t, res)
+	assert.Equal(t, "FOO", string(msgs[0].Get(0).Get()))
+	assert.Empty(t, tap.Recent())
+}
+
+func TestJourneySteps(t *testing.T) {
+	tap := NewTap()
+	tap.SetRate(1)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	tap.Sample(msg)
+
+	upper := fnProcessor(func(msg types.Message) ([]types.Message, types.Response) {
+		newMsg := msg.Copy()
+		newMsg.Iter(func(i int, p types.Part) error {
+			p.Set(bytes.ToUpper(p.Get()))
+			return nil
+		})
+		return []types.Message{newMsg}, nil
+	})
+	dropFirst := fnProcessor(func(msg types.Message) ([]types.Message, types.Response) {
+		newMsg := message.New(nil)
+		newMsg.Append(msg.Get(1).Copy())
+		return []types.Message{newMsg}, nil
+	})
+
+	msgs, res := Execute(upper, msg)
+	require.Nil(t, res)
+	msgs, res = Execute(dropFirst, msgs[0])
+	require.Nil(t, res)
+	assert.Equal(t, "BAR", string(msgs[0].Get(0).Get()))
+
+	// Only one part is sampled per second.
+	journeys := tap.Recent()
+	require.Len(t, journeys, 1)
+
+	j := journeys[0]
+	assert.Equal(t, "foo", j.Input)
+	require.Len(t, j.Steps, 2)
+
+	assert.Equal(t, "fnprocessor", j.Steps[0].Processor)
+	assert.Equal(t, "foo", j.Steps[0].Before)
+	assert.Equal(t, []string{"FOO"}, j.Steps[0].After)
+	assert.False(t, j.Steps[0].Dropped)
+
+	assert.Equal(t, "FOO", j.Steps[1].Before)
+	assert.Empty(t, j.Steps[1].After)
+	assert.True(t, j.Steps[1].Dropped)
+}
+
+func TestJourneySnippetTruncated(t *testing.T) {
+	tap := NewTap()
+	tap.SetRate(10)
+
+	msg := message.New([][]byte{bytes.Repeat([]byte("a"), maxSnippetLen*2)})
+	tap.Sample(msg)
+
+	journeys := tap.Recent()
+	require.Len(t, journeys, 1)
+	assert.Equal(t, string(bytes.Repeat([]byte("a"), maxSnippetLen))+"...", journeys[0].Input)
+}
@@ -0,0 +1,4 @@
+// Package journey provides a tap that samples message parts and records each
+// processor that they pass through, along with snippets of their contents
+// before and after each step, for debugging pipelines in production.
+package journey
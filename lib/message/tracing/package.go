@@ -19,17 +19,29 @@ func GetSpan(p types.Part) opentracing.Span {
 	return opentracing.SpanFromContext(message.GetContext(p))
 }
 
+// ComponentTags returns span tags that describe the kind of component that
+// created a span (input, output, etc), its type and the size of the batch that
+// it is processing.
+func ComponentTags(component, typeStr string, batchSize int) opentracing.Tags {
+	return opentracing.Tags{
+		"benthos.component":      component,
+		"benthos.component.type": typeStr,
+		"benthos.batch.size":     batchSize,
+	}
+}
+
 // CreateChildSpan takes a message part, extracts an existing span if there is
-// one and returns child span.
-func CreateChildSpan(operationName string, part types.Part) opentracing.Span {
+// one and returns child span. Optional span options such as tags are applied
+// to the new span.
+func CreateChildSpan(operationName string, part types.Part, opts ...opentracing.StartSpanOption) opentracing.Span {
 	span := GetSpan(part)
 	if span == nil {
-		span = opentracing.StartSpan(operationName)
+		span = opentracing.StartSpan(operationName, opts...)
 	} else {
-		span = opentracing.StartSpan(
-			operationName,
-			opentracing.ChildOf(span.Context()),
-		)
+		spanOpts := make([]opentracing.StartSpanOption, 0, len(opts)+1)
+		spanOpts = append(spanOpts, opentracing.ChildOf(span.Context()))
+		spanOpts = append(spanOpts, opts...)
+		span = opentracing.StartSpan(operationName, spanOpts...)
 	}
 	return span
 }
@@ -37,10 +49,10 @@ func CreateChildSpan(operationName string, part types.Part) opentracing.Span {
 // CreateChildSpans takes a message, extracts spans per message part and returns
 // a slice of child spans. The length of the returned slice is guaranteed to
 // match the message size.
-func CreateChildSpans(operationName string, msg types.Message) []opentracing.Span {
+func CreateChildSpans(operationName string, msg types.Message, opts ...opentracing.StartSpanOption) []opentracing.Span {
 	spans := make([]opentracing.Span, msg.Len())
 	msg.Iter(func(i int, part types.Part) error {
-		spans[i] = CreateChildSpan(operationName, part)
+		spans[i] = CreateChildSpan(operationName, part, opts...)
 		return nil
 	})
 	return spans
@@ -131,15 +143,15 @@ func IterateWithChildSpans(operationName string, msg types.Message, iter func(in
 }
 
 // InitSpans sets up OpenTracing spans on each message part if one does not
-// already exist.
-func InitSpans(operationName string, msg types.Message) {
+// already exist. Optional span options such as tags are applied to new spans.
+func InitSpans(operationName string, msg types.Message, opts ...opentracing.StartSpanOption) {
 	tracedParts := make([]types.Part, msg.Len())
 	msg.Iter(func(i int, p types.Part) error {
 		if GetSpan(p) != nil {
 			tracedParts[i] = p
 			return nil
 		}
-		span := opentracing.StartSpan(operationName)
+		span := opentracing.StartSpan(operationName, opts...)
 		ctx := opentracing.ContextWithSpan(message.GetContext(p), span)
 		tracedParts[i] = message.WithContext(ctx, p)
 		return nil
@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+// MetadataCarrier adapts the metadata of a message part so that it can be used
+// as an opentracing TextMap carrier for both injecting and extracting span
+// contexts.
+type MetadataCarrier struct {
+	Metadata types.Metadata
+}
+
+// Set sets a metadata key to a value.
+func (m MetadataCarrier) Set(key, val string) {
+	m.Metadata.Set(key, val)
+}
+
+// ForeachKey calls a handler for each metadata key/value pair, returning early
+// if the handler returns an error.
+func (m MetadataCarrier) ForeachKey(handler func(key, val string) error) error {
+	return m.Metadata.Iter(handler)
+}
+
+//------------------------------------------------------------------------------
+
+// ExtractFromMetadata attempts to extract a span context from the metadata of
+// a message part, which is where inputs such as Kafka and AMQP place the
+// headers of consumed messages. Returns nil if a span context was not found.
+func ExtractFromMetadata(part types.Part) opentracing.SpanContext {
+	sc, err := opentracing.GlobalTracer().Extract(opentracing.TextMap, MetadataCarrier{Metadata: part.Metadata()})
+	if err != nil {
+		return nil
+	}
+	return sc
+}
+
+// InitSpansFromMetadata sets up OpenTracing spans on each message part if one
+// does not already exist. When the metadata of a part contains a propagated
+// span context the new span is created as a child of it, otherwise a new trace
+// is started. Optional span options such as tags are applied to new spans.
+func InitSpansFromMetadata(operationName string, msg types.Message, opts ...opentracing.StartSpanOption) {
+	tracedParts := make([]types.Part, msg.Len())
+	msg.Iter(func(i int, p types.Part) error {
+		if GetSpan(p) != nil {
+			tracedParts[i] = p
+			return nil
+		}
+		spanOpts := opts
+		if parent := ExtractFromMetadata(p); parent != nil {
+			spanOpts = make([]opentracing.StartSpanOption, 0, len(opts)+1)
+			spanOpts = append(spanOpts, opentracing.ChildOf(parent))
+			spanOpts = append(spanOpts, opts...)
+		}
+		span := opentracing.StartSpan(operationName, spanOpts...)
+		ctx := opentracing.ContextWithSpan(message.GetContext(p), span)
+		tracedParts[i] = message.WithContext(ctx, p)
+		return nil
+	})
+	msg.SetAll(tracedParts)
+}
+
+// InjectPartSpan writes the context of the span attached to a message part
+// into a carrier using the global tracer, allowing downstream services to
+// continue the trace. Nothing is written if the part does not have a span.
+func InjectPartSpan(part types.Part, format interface{}, carrier interface{}) error {
+	span := GetSpan(part)
+	if span == nil {
+		return nil
+	}
+	return opentracing.GlobalTracer().Inject(span.Context(), format, carrier)
+}
+
+//------------------------------------------------------------------------------
+
+// TraceID returns the ID of the trace that the span attached to a message part
+// belongs to. An empty string is returned if the part does not have a span,
+// the trace is not sampled, or the ID cannot be determined from the
+// propagation format of the global tracer.
+func TraceID(part types.Part) string {
+	carrier := opentracing.TextMapCarrier{}
+	if err := InjectPartSpan(part, opentracing.TextMap, carrier); err != nil {
+		return ""
+	}
+	// W3C trace context: version-traceid-spanid-flags
+	if parts := strings.Split(carrier["traceparent"], "-"); len(parts) == 4 && isSampled(parts[3]) {
+		return parts[1]
+	}
+	// Jaeger: traceid:spanid:parentid:flags
+	if parts := strings.Split(carrier["uber-trace-id"], ":"); len(parts) == 4 && isSampled(parts[3]) {
+		return parts[0]
+	}
+	return ""
+}
+
+func isSampled(hexFlags string) bool {
+	flags, err := strconv.ParseUint(hexFlags, 16, 8)
+	return err == nil && flags&1 == 1
+}
+
+// BatchTraceID returns the ID of the trace of the first message of a batch,
+// following the same rules as TraceID.
+func BatchTraceID(msg types.Message) string {
+	if msg.Len() == 0 {
+		return ""
+	}
+	return TraceID(msg.Get(0))
+}
+
+//------------------------------------------------------------------------------
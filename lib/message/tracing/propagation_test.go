@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataPropagation(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	upstream := tracer.StartSpan("upstream")
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	require.NoError(t, tracer.Inject(upstream.Context(), opentracing.TextMap, MetadataCarrier{
+		Metadata: msg.Get(0).Metadata(),
+	}))
+
+	InitSpansFromMetadata("input_foo", msg)
+
+	first := GetSpan(msg.Get(0)).(*mocktracer.MockSpan)
+	assert.Equal(t, upstream.Context().(mocktracer.MockSpanContext).TraceID, first.SpanContext.TraceID)
+	assert.Equal(t, upstream.Context().(mocktracer.MockSpanContext).SpanID, first.ParentID)
+
+	second := GetSpan(msg.Get(1)).(*mocktracer.MockSpan)
+	assert.NotEqual(t, first.SpanContext.TraceID, second.SpanContext.TraceID)
+	assert.Equal(t, 0, second.ParentID)
+
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, InjectPartSpan(msg.Get(0), opentracing.TextMap, carrier))
+
+	extracted, err := tracer.Extract(opentracing.TextMap, carrier)
+	require.NoError(t, err)
+	assert.Equal(t, first.SpanContext.SpanID, extracted.(mocktracer.MockSpanContext).SpanID)
+
+	carrier = opentracing.TextMapCarrier{}
+	require.NoError(t, InjectPartSpan(message.NewPart(nil), opentracing.TextMap, carrier))
+	assert.Empty(t, carrier)
+}
@@ -69,6 +69,13 @@ func (c *combinedTimer) Timing(delta int64) error {
 	return c.c2.Timing(delta)
 }
 
+func (c *combinedTimer) TimingWithExemplar(delta int64, exemplar map[string]string) error {
+	if err := timingWithExemplar(c.c1, delta, exemplar); err != nil {
+		return err
+	}
+	return timingWithExemplar(c.c2, delta, exemplar)
+}
+
 type combinedGauge struct {
 	c1 StatGauge
 	c2 StatGauge
@@ -177,6 +184,20 @@ func (c *combinedWrapper) GetGaugeVec(path string, n []string) StatGaugeVec {
 	}
 }
 
+func (c *combinedWrapper) GetHistogramVec(path string, n []string, buckets []float64) StatObserverVec {
+	return &combinedObserverVec{
+		c1: HistogramVecOf(c.t1, path, n, buckets),
+		c2: HistogramVecOf(c.t2, path, n, buckets),
+	}
+}
+
+func (c *combinedWrapper) GetSummaryVec(path string, n []string, objectives map[float64]float64) StatObserverVec {
+	return &combinedObserverVec{
+		c1: SummaryVecOf(c.t1, path, n, objectives),
+		c2: SummaryVecOf(c.t2, path, n, objectives),
+	}
+}
+
 func (c *combinedWrapper) SetLogger(log log.Modular) {
 	c.t1.SetLogger(log)
 	c.t2.SetLogger(log)
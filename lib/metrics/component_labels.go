@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// The names of labels added to metrics when component labels are enabled.
+var componentLabelNames = []string{"stream", "label", "path"}
+
+// parseComponentPath breaks a dotted metric path down into a metric name made
+// of the kind of component and the name of the metric, and a set of label
+// values that identify the component that emitted it.
+//
+// For example, the path `foo.resource.output.bar.broker.outputs.1.sent` would
+// result in the name `output.sent` and the labels stream `foo`, label `bar`
+// and path `resource.output.bar.broker.outputs.1`.
+//
+// If the path does not contain a component the labels returned are nil.
+func parseComponentPath(path string) (name string, labelValues []string) {
+	segments := strings.Split(path, ".")
+
+	root := -1
+	for i, s := range segments {
+		if s == "input" || s == "buffer" || s == "pipeline" || s == "output" || s == "resource" {
+			root = i
+			break
+		}
+	}
+	if root < 0 || root >= len(segments)-1 {
+		return path, nil
+	}
+
+	stream := strings.Join(segments[:root], ".")
+
+	var label string
+	kind, i := segments[root], root+1
+	if kind == "resource" {
+		if len(segments)-root < 4 {
+			return path, nil
+		}
+		kind, label, i = segments[root+1], segments[root+2], root+3
+	}
+
+	// The component path ends at the last index within the path, e.g. the
+	// index of a processor or the child of a broker.
+	end := i
+	for j := i; j < len(segments)-1; j++ {
+		if _, err := strconv.Atoi(segments[j]); err != nil {
+			continue
+		}
+		end = j + 1
+		switch segments[j-1] {
+		case "processor", "processors":
+			kind = "processor"
+		case "inputs":
+			kind = "input"
+		case "outputs":
+			kind = "output"
+		}
+	}
+	// Children of brokers such as switch are namespaced by their kind.
+	for end > i && end < len(segments)-1 {
+		if s := segments[end]; s != "input" && s != "output" && s != "condition" {
+			break
+		}
+		kind = segments[end]
+		end++
+	}
+
+	name = kind + "." + strings.Join(segments[end:], ".")
+	return name, []string{stream, label, strings.Join(segments[root:end], ".")}
+}
+
+//------------------------------------------------------------------------------
+
+// componentLabels wraps a metrics type and converts the dotted paths of
+// component metrics into a metric name and a set of labels identifying the
+// component.
+type componentLabels struct {
+	t Type
+}
+
+// withComponentLabels wraps a metrics type so that component identity is
+// exposed as labels rather than embedded within metric names.
+func withComponentLabels(t Type) Type {
+	c := &componentLabels{t: t}
+	if _, ok := t.(WithHandlerFunc); ok {
+		return &componentLabelsWithHandler{componentLabels: c}
+	}
+	return c
+}
+
+// Unwrap to the underlying metrics type.
+func (c *componentLabels) Unwrap() Type {
+	return unwrapMetric(c.t)
+}
+
+//------------------------------------------------------------------------------
+
+// GetCounter returns an editable counter stat for a given path.
+func (c *componentLabels) GetCounter(path string) StatCounter {
+	name, labelValues := parseComponentPath(path)
+	if labelValues == nil {
+		return c.t.GetCounter(path)
+	}
+	return c.t.GetCounterVec(name, componentLabelNames).With(labelValues...)
+}
+
+// GetCounterVec returns an editable counter stat for a given path with labels.
+func (c *componentLabels) GetCounterVec(path string, n []string) StatCounterVec {
+	name, labelValues := parseComponentPath(path)
+	if labelValues == nil {
+		return c.t.GetCounterVec(path, n)
+	}
+	vec := c.t.GetCounterVec(name, append(append([]string{}, componentLabelNames...), n...))
+	return fakeCounterVec(func(l []string) StatCounter {
+		return vec.With(append(append([]string{}, labelValues...), l...)...)
+	})
+}
+
+// GetTimer returns an editable timer stat for a given path.
+func (c *componentLabels) GetTimer(path string) StatTimer {
+	name, labelValues := parseComponentPath(path)
+	if labelValues == nil {
+		return c.t.GetTimer(path)
+	}
+	return c.t.GetTimerVec(name, componentLabelNames).With(labelValues...)
+}
+
+// GetTimerVec returns an editable timer stat for a given path with labels.
+func (c *componentLabels) GetTimerVec(path string, n []string) StatTimerVec {
+	name, labelValues := parseComponentPath(path)
+	if labelValues == nil {
+		return c.t.GetTimerVec(path, n)
+	}
+	vec := c.t.GetTimerVec(name, append(append([]string{}, componentLabelNames...), n...))
+	return fakeTimerVec(func(l []string) StatTimer {
+		return vec.With(append(append([]string{}, labelValues...), l...)...)
+	})
+}
+
+// GetGauge returns an editable gauge stat for a given path.
+func (c *componentLabels) GetGauge(path string) StatGauge {
+	name, labelValues := parseComponentPath(path)
+	if labelValues == nil {
+		return c.t.GetGauge(path)
+	}
+	return c.t.GetGaugeVec(name, componentLabelNames).With(labelValues...)
+}
+
+// GetGaugeVec returns an editable gauge stat for a given path with labels.
+func (c *componentLabels) GetGaugeVec(path string, n []string) StatGaugeVec {
+	name, labelValues := parseComponentPath(path)
+	if labelValues == nil {
+		return c.t.GetGaugeVec(path, n)
+	}
+	vec := c.t.GetGaugeVec(name, append(append([]string{}, componentLabelNames...), n...))
+	return fakeGaugeVec(func(l []string) StatGauge {
+		return vec.With(append(append([]string{}, labelValues...), l...)...)
+	})
+}
+
+// SetLogger sets the logger used by the underlying metrics type.
+func (c *componentLabels) SetLogger(log log.Modular) {
+	c.t.SetLogger(log)
+}
+
+// Close stops the underlying metrics type.
+func (c *componentLabels) Close() error {
+	return c.t.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// componentLabelsWithHandler is a componentLabels wrapper of a metrics type
+// that exposes metrics through an HTTP endpoint.
+type componentLabelsWithHandler struct {
+	*componentLabels
+}
+
+// HandlerFunc returns the http.HandlerFunc of the underlying metrics type.
+func (c *componentLabelsWithHandler) HandlerFunc() http.HandlerFunc {
+	return c.t.(WithHandlerFunc).HandlerFunc()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/util/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseComponentPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		name   string
+		labels []string
+	}{
+		{
+			path:   "input.received",
+			name:   "input.received",
+			labels: []string{"", "", "input"},
+		},
+		{
+			path:   "input.broker.inputs.1.batch.received",
+			name:   "input.batch.received",
+			labels: []string{"", "", "input.broker.inputs.1"},
+		},
+		{
+			path:   "foo.pipeline.processor.2.count",
+			name:   "processor.count",
+			labels: []string{"foo", "", "pipeline.processor.2"},
+		},
+		{
+			path:   "output.processor.0.catch.1.error",
+			name:   "processor.error",
+			labels: []string{"", "", "output.processor.0.catch.1"},
+		},
+		{
+			path:   "output.switch.outputs.0.output.sent",
+			name:   "output.sent",
+			labels: []string{"", "", "output.switch.outputs.0.output"},
+		},
+		{
+			path:   "resource.cache.bar.latency",
+			name:   "cache.latency",
+			labels: []string{"", "bar", "resource.cache.bar"},
+		},
+		{
+			path:   "foo.resource.output.bar.broker.outputs.1.sent",
+			name:   "output.sent",
+			labels: []string{"foo", "bar", "resource.output.bar.broker.outputs.1"},
+		},
+		{
+			path: "http.requests",
+			name: "http.requests",
+		},
+	}
+
+	for _, test := range tests {
+		name, labels := parseComponentPath(test.path)
+		assert.Equal(t, test.name, name, test.path)
+		assert.Equal(t, test.labels, labels, test.path)
+	}
+}
+
+func TestComponentLabels(t *testing.T) {
+	local := NewLocal()
+	stats := withComponentLabels(local)
+
+	Namespaced(stats, "input").GetCounter("received").Incr(2)
+	Namespaced(stats, "resource.processor.foo").GetCounterVec("count", []string{"bar"}).With("baz").Incr(1)
+	stats.GetCounter("http.requests").Incr(3)
+
+	counters := local.GetCountersWithLabels()
+
+	received, ok := counters["input.received"]
+	require.True(t, ok)
+	assert.Equal(t, int64(2), *received.Value)
+	assert.True(t, received.HasLabelWithValue("path", "input"))
+
+	count, ok := counters["processor.count"]
+	require.True(t, ok)
+	assert.Equal(t, int64(1), *count.Value)
+	assert.True(t, count.HasLabelWithValue("label", "foo"))
+	assert.True(t, count.HasLabelWithValue("bar", "baz"))
+
+	assert.Equal(t, int64(3), local.GetCounters()["http.requests"])
+}
+
+func TestComponentLabelsConfig(t *testing.T) {
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+component_labels: true
+prometheus:
+  prefix: foo
+`), &conf))
+
+	assert.Equal(t, TypePrometheus, conf.Type)
+	assert.True(t, conf.ComponentLabels)
+
+	sanit, err := conf.Sanitised(false)
+	require.NoError(t, err)
+	assert.Equal(t, true, sanit.(config.Sanitised)["component_labels"])
+}
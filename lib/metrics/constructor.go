@@ -49,6 +49,7 @@ const (
 	TypeCloudWatch    = "cloudwatch"
 	TypeHTTPServer    = "http_server"
 	TypeInfluxDB      = "influxdb"
+	TypeOTLP          = "otlp"
 	TypePrometheus    = "prometheus"
 	TypeRename        = "rename"
 	TypeStatsd        = "statsd"
@@ -61,33 +62,41 @@ const (
 // Config is the all encompassing configuration struct for all metric output
 // types.
 type Config struct {
-	Type          string           `json:"type" yaml:"type"`
-	AWSCloudWatch CloudWatchConfig `json:"aws_cloudwatch" yaml:"aws_cloudwatch"`
-	Blacklist     BlacklistConfig  `json:"blacklist" yaml:"blacklist"`
-	CloudWatch    CloudWatchConfig `json:"cloudwatch" yaml:"cloudwatch"`
-	HTTP          HTTPConfig       `json:"http_server" yaml:"http_server"`
-	InfluxDB      InfluxDBConfig   `json:"influxdb" yaml:"influxdb"`
-	Prometheus    PrometheusConfig `json:"prometheus" yaml:"prometheus"`
-	Rename        RenameConfig     `json:"rename" yaml:"rename"`
-	Statsd        StatsdConfig     `json:"statsd" yaml:"statsd"`
-	Stdout        StdoutConfig     `json:"stdout" yaml:"stdout"`
-	Whitelist     WhitelistConfig  `json:"whitelist" yaml:"whitelist"`
+	Type            string           `json:"type" yaml:"type"`
+	ComponentLabels bool             `json:"component_labels" yaml:"component_labels"`
+	StreamLayers    bool             `json:"stream_layers" yaml:"stream_layers"`
+	AWSCloudWatch   CloudWatchConfig `json:"aws_cloudwatch" yaml:"aws_cloudwatch"`
+	Blacklist       BlacklistConfig  `json:"blacklist" yaml:"blacklist"`
+	CloudWatch      CloudWatchConfig `json:"cloudwatch" yaml:"cloudwatch"`
+	HTTP            HTTPConfig       `json:"http_server" yaml:"http_server"`
+	InfluxDB        InfluxDBConfig   `json:"influxdb" yaml:"influxdb"`
+	OTLP            OTLPConfig       `json:"otlp" yaml:"otlp"`
+	Plugin          interface{}      `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Prometheus      PrometheusConfig `json:"prometheus" yaml:"prometheus"`
+	Rename          RenameConfig     `json:"rename" yaml:"rename"`
+	Statsd          StatsdConfig     `json:"statsd" yaml:"statsd"`
+	Stdout          StdoutConfig     `json:"stdout" yaml:"stdout"`
+	Whitelist       WhitelistConfig  `json:"whitelist" yaml:"whitelist"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:          "http_server",
-		AWSCloudWatch: NewCloudWatchConfig(),
-		Blacklist:     NewBlacklistConfig(),
-		CloudWatch:    NewCloudWatchConfig(),
-		HTTP:          NewHTTPConfig(),
-		InfluxDB:      NewInfluxDBConfig(),
-		Prometheus:    NewPrometheusConfig(),
-		Rename:        NewRenameConfig(),
-		Statsd:        NewStatsdConfig(),
-		Stdout:        NewStdoutConfig(),
-		Whitelist:     NewWhitelistConfig(),
+		Type:            "http_server",
+		ComponentLabels: false,
+		StreamLayers:    false,
+		AWSCloudWatch:   NewCloudWatchConfig(),
+		Blacklist:       NewBlacklistConfig(),
+		CloudWatch:      NewCloudWatchConfig(),
+		HTTP:            NewHTTPConfig(),
+		InfluxDB:        NewInfluxDBConfig(),
+		OTLP:            NewOTLPConfig(),
+		Plugin:          nil,
+		Prometheus:      NewPrometheusConfig(),
+		Rename:          NewRenameConfig(),
+		Statsd:          NewStatsdConfig(),
+		Stdout:          NewStdoutConfig(),
+		Whitelist:       NewWhitelistConfig(),
 	}
 }
 
@@ -113,6 +122,12 @@ func (conf Config) Sanitised(removeDeprecated bool) (interface{}, error) {
 	if removeDeprecated {
 		Constructors[conf.Type].FieldSpecs.RemoveDeprecated(outputMap)
 	}
+	if conf.ComponentLabels {
+		outputMap["component_labels"] = true
+	}
+	if conf.StreamLayers {
+		outputMap["stream_layers"] = true
+	}
 	return outputMap, nil
 }
 
@@ -146,6 +161,11 @@ func (conf *Config) UnmarshalYAML(value *yaml.Node) error {
 	if err := value.Decode(&raw); err != nil {
 		return fmt.Errorf("line %v: %v", value.Line, err)
 	}
+	if rawMap, ok := raw.(map[string]interface{}); ok {
+		// Not types, and therefore not candidates for type inference.
+		delete(rawMap, "component_labels")
+		delete(rawMap, "stream_layers")
+	}
 	if typeCandidates := config.GetInferenceCandidates(raw); len(typeCandidates) > 0 {
 		var inferredType string
 		for _, tc := range typeCandidates {
@@ -161,8 +181,24 @@ func (conf *Config) UnmarshalYAML(value *yaml.Node) error {
 		}
 		aliased.Type = inferredType
 	}
-	if _, exists := Constructors[aliased.Type]; !exists {
-		return fmt.Errorf("line %v: type '%v' was not recognised", value.Line, aliased.Type)
+	if spec, exists := pluginSpecs[aliased.Type]; exists && spec.confConstructor != nil {
+		confBytes, err := yaml.Marshal(aliased.Plugin)
+		if err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+
+		conf := spec.confConstructor()
+		if err = yaml.Unmarshal(confBytes, conf); err != nil {
+			return fmt.Errorf("line %v: %v", value.Line, err)
+		}
+		aliased.Plugin = conf
+	} else {
+		if !exists {
+			if _, exists = Constructors[aliased.Type]; !exists {
+				return fmt.Errorf("line %v: type '%v' was not recognised", value.Line, aliased.Type)
+			}
+		}
+		aliased.Plugin = nil
 	}
 
 	*conf = Config(aliased)
@@ -249,10 +285,22 @@ func New(conf Config, opts ...func(Type)) (Type, error) {
 	if conf.Type == "none" {
 		return DudType{}, nil
 	}
+	var t Type
+	var err error
 	if c, ok := Constructors[conf.Type]; ok {
-		return c.constructor(conf, opts...)
+		t, err = c.constructor(conf, opts...)
+	} else if c, ok := pluginSpecs[conf.Type]; ok {
+		t, err = c.constructor(conf, opts...)
+	} else {
+		return nil, ErrInvalidMetricOutputType
+	}
+	if err != nil {
+		return nil, err
+	}
+	if conf.ComponentLabels {
+		t = withComponentLabels(t)
 	}
-	return nil, ErrInvalidMetricOutputType
+	return t, nil
 }
 
 //------------------------------------------------------------------------------
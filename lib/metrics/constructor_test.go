@@ -93,3 +93,29 @@ func TestConstructorConfigYAMLInference(t *testing.T) {
 		t.Errorf("Wrong value: %v != %v", act, exp)
 	}
 }
+
+func TestConstructorConfigStreamLayers(t *testing.T) {
+	conf := NewConfig()
+	if err := yaml.Unmarshal([]byte(`
+stream_layers: true
+prometheus:
+  prefix: foo
+`), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp, act := TypePrometheus, conf.Type; exp != act {
+		t.Errorf("Wrong inferred type: %v != %v", act, exp)
+	}
+	if !conf.StreamLayers {
+		t.Error("Expected stream layers to be enabled")
+	}
+
+	sanit, err := conf.Sanitised(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := true, sanit.(config.Sanitised)["stream_layers"]; exp != act {
+		t.Errorf("Wrong sanitised value: %v != %v", act, exp)
+	}
+}
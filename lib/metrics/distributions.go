@@ -0,0 +1,70 @@
+package metrics
+
+//------------------------------------------------------------------------------
+
+// HistogramVecOf returns a histogram stat for a given path from a metrics type
+// if it supports distributions, otherwise observations are recorded as
+// timings.
+func HistogramVecOf(t Type, path string, labelNames []string, buckets []float64) StatObserverVec {
+	if d, ok := t.(WithDistributions); ok {
+		return d.GetHistogramVec(path, labelNames, buckets)
+	}
+	return timerObserverVec{t.GetTimerVec(path, labelNames)}
+}
+
+// SummaryVecOf returns a summary stat for a given path from a metrics type if
+// it supports distributions, otherwise observations are recorded as timings.
+func SummaryVecOf(t Type, path string, labelNames []string, objectives map[float64]float64) StatObserverVec {
+	if d, ok := t.(WithDistributions); ok {
+		return d.GetSummaryVec(path, labelNames, objectives)
+	}
+	return timerObserverVec{t.GetTimerVec(path, labelNames)}
+}
+
+//------------------------------------------------------------------------------
+
+// timerObserverVec records observations as timings for metrics types that do
+// not support histograms or summaries.
+type timerObserverVec struct {
+	timers StatTimerVec
+}
+
+func (t timerObserverVec) With(labelValues ...string) StatObserver {
+	return timerObserver{t.timers.With(labelValues...)}
+}
+
+type timerObserver struct {
+	timer StatTimer
+}
+
+func (t timerObserver) Observe(value float64) error {
+	return t.timer.Timing(int64(value))
+}
+
+//------------------------------------------------------------------------------
+
+type combinedObserver struct {
+	c1 StatObserver
+	c2 StatObserver
+}
+
+func (c *combinedObserver) Observe(value float64) error {
+	if err := c.c1.Observe(value); err != nil {
+		return err
+	}
+	return c.c2.Observe(value)
+}
+
+type combinedObserverVec struct {
+	c1 StatObserverVec
+	c2 StatObserverVec
+}
+
+func (c *combinedObserverVec) With(labelValues ...string) StatObserver {
+	return &combinedObserver{
+		c1: c.c1.With(labelValues...),
+		c2: c.c2.With(labelValues...),
+	}
+}
+
+//------------------------------------------------------------------------------
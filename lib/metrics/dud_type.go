@@ -19,6 +19,9 @@ func (d DudStat) Timing(delta int64) error { return nil }
 // Set does nothing.
 func (d DudStat) Set(value int64) error { return nil }
 
+// Observe does nothing.
+func (d DudStat) Observe(value float64) error { return nil }
+
 //------------------------------------------------------------------------------
 
 // DudType implements the Type interface but doesn't actual do anything.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -40,7 +41,47 @@ like this:
 		"baz": 3
 	}
 }
-` + "```" + ``,
+` + "```" + `
+
+## Live Stats
+
+Adding the query parameter ` + "`" + `live=true` + "`" + ` to a request (e.g. ` + "`" + `/stats?live=true` + "`" + `)
+returns a summary of recent activity grouped by component instead. For each
+counter of a component the rate per second over the last one and five minutes
+is shown. For each timer the percentiles p50, p90 and p99 are also shown,
+calculated from a sample of recent observations. Counters such as ` + "`" + `error` + "`" + ` can
+therefore be used to inspect error rates.
+
+` + "``` json" + `
+{
+	"components": {
+		"input": {
+			"received": {
+				"1m": { "rate": 120.5 },
+				"5m": { "rate": 98.2 }
+			}
+		},
+		"output": {
+			"batch.latency": {
+				"1m": {
+					"rate": 12.1,
+					"p50": 1203000,
+					"p50_readable": "1.203ms",
+					"p90": 3401000,
+					"p90_readable": "3.401ms",
+					"p99": 9120000,
+					"p99_readable": "9.12ms"
+				},
+				"5m": { "rate": 10.4, "p50": 1187000, "p50_readable": "1.187ms", "p90": 3299000, "p90_readable": "3.299ms", "p99": 8710000, "p99_readable": "8.71ms" }
+			}
+		}
+	},
+	"goroutines": 42,
+	"uptime": "5m3.2s"
+}
+` + "```" + `
+
+Rates are calculated over buckets of ten seconds and are therefore approximate.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("prefix", "A string prefix to add to all metrics."),
 			pathMappingDocs(false),
@@ -76,6 +117,7 @@ func NewHTTPConfig() HTTPConfig {
 // HTTP is an object with capability to hold internal stats as a JSON endpoint.
 type HTTP struct {
 	local       *Local
+	rolling     *rollingStats
 	log         log.Modular
 	timestamp   time.Time
 	pathPrefix  string
@@ -86,6 +128,7 @@ type HTTP struct {
 func NewHTTP(config Config, opts ...func(Type)) (Type, error) {
 	t := &HTTP{
 		local:      NewLocal(),
+		rolling:    newRollingStats(),
 		timestamp:  time.Now(),
 		pathPrefix: config.HTTP.Prefix,
 	}
@@ -109,12 +152,32 @@ func (h *HTTP) getPath(path string) string {
 	return path
 }
 
+// rollingPath returns a metric path without the configured prefix, which is
+// how rolling stats are tracked in order to identify components.
+func (h *HTTP) rollingPath(path string) string {
+	if len(h.pathPrefix) > 0 {
+		return strings.TrimPrefix(path, h.pathPrefix+".")
+	}
+	return path
+}
+
 // HandlerFunc returns an http.HandlerFunc for accessing metrics as a JSON blob.
 func (h *HTTP) HandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		uptime := time.Since(h.timestamp).String()
 		goroutines := runtime.NumGoroutine()
 
+		if r.URL.Query().Get("live") == "true" {
+			obj := gabs.New()
+			obj.Set(h.rolling.byComponent(), "components")
+			obj.Set(uptime, "uptime")
+			obj.Set(goroutines, "goroutines")
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(obj.Bytes())
+			return
+		}
+
 		counters := h.local.GetCounters()
 		timings := h.local.GetTimings()
 
@@ -139,7 +202,7 @@ func (h *HTTP) GetCounter(path string) StatCounter {
 	if path = h.getPath(path); len(path) == 0 {
 		return DudStat{}
 	}
-	return h.local.GetCounter(path)
+	return h.rolling.wrapCounter(h.rollingPath(path), h.local.GetCounter(path))
 }
 
 // GetCounterVec returns a stat counter object for a path with the labels
@@ -151,7 +214,7 @@ func (h *HTTP) GetCounterVec(path string, n []string) StatCounterVec {
 		})
 	}
 	return fakeCounterVec(func([]string) StatCounter {
-		return h.local.GetCounter(path)
+		return h.rolling.wrapCounter(h.rollingPath(path), h.local.GetCounter(path))
 	})
 }
 
@@ -160,7 +223,7 @@ func (h *HTTP) GetTimer(path string) StatTimer {
 	if path = h.getPath(path); len(path) == 0 {
 		return DudStat{}
 	}
-	return h.local.GetTimer(path)
+	return h.rolling.wrapTimer(h.rollingPath(path), h.local.GetTimer(path))
 }
 
 // GetTimerVec returns a stat timer object for a path with the labels
@@ -172,7 +235,7 @@ func (h *HTTP) GetTimerVec(path string, n []string) StatTimerVec {
 		})
 	}
 	return fakeTimerVec(func([]string) StatTimer {
-		return h.local.GetTimer(path)
+		return h.rolling.wrapTimer(h.rollingPath(path), h.local.GetTimer(path))
 	})
 }
 
@@ -54,6 +54,14 @@ func (d namespacedWrapper) GetGaugeVec(path string, labelNames []string) StatGau
 	return d.t.GetGaugeVec(d.ns+"."+path, labelNames)
 }
 
+func (d namespacedWrapper) GetHistogramVec(path string, labelNames []string, buckets []float64) StatObserverVec {
+	return HistogramVecOf(d.t, d.ns+"."+path, labelNames, buckets)
+}
+
+func (d namespacedWrapper) GetSummaryVec(path string, labelNames []string, objectives map[float64]float64) StatObserverVec {
+	return SummaryVecOf(d.t, d.ns+"."+path, labelNames, objectives)
+}
+
 func (d namespacedWrapper) SetLogger(log log.Modular) {
 	d.t.SetLogger(log.NewModule(d.ns))
 }
@@ -0,0 +1,692 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOTLP] = TypeSpec{
+		constructor: NewOTLP,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Pushes metrics to an [OpenTelemetry](https://opentelemetry.io/) collector, or
+any other service that supports the OTLP protocol, over gRPC or HTTP.`,
+		Description: `
+Metrics are aggregated in memory and exported in batches each
+` + "`push_interval`" + `, as well as once more when Benthos shuts down.
+Counters are exported as monotonic sums, gauges as gauges and timings as
+histograms with values in seconds. Labels of metrics are exported as
+attributes of their data points.
+
+### Temporality
+
+The field ` + "`temporality`" + ` determines whether exported counters and
+histograms contain the ` + "`cumulative`" + ` values since Benthos started,
+or the ` + "`delta`" + ` since the previous export. When using delta
+temporality the values of an export that fails are lost, whereas cumulative
+values are recovered by the next successful export.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("protocol", "The protocol to export metrics with.").HasOptions("grpc", "http"),
+			docs.FieldCommon(
+				"endpoint", "The endpoint of the collector, which is a `host:port` address when using gRPC or a URL when using HTTP.",
+				"localhost:4317", "http://localhost:4318/v1/metrics",
+			),
+			btls.FieldSpec(),
+			docs.FieldAdvanced("headers", "A map of headers, or gRPC metadata, to add to each export request."),
+			docs.FieldCommon(
+				"resource_attributes", "A map of attributes that describe the resource producing the metrics.",
+				map[string]string{
+					"service.name":           "benthos",
+					"deployment.environment": "production",
+				},
+			),
+			docs.FieldAdvanced("temporality", "The aggregation temporality of exported counters and histograms, see [temporality](#temporality) for more information.").HasOptions("cumulative", "delta"),
+			docs.FieldCommon("push_interval", "The period of time between each export of metrics."),
+			docs.FieldAdvanced("timeout", "The maximum period to wait for an export request to complete."),
+			docs.FieldAdvanced("max_batch_size", "The maximum number of metrics to send in a single export request, where larger sets of metrics are split across several requests. Set to 0 for no limit."),
+			docs.FieldAdvanced("timing_buckets", "A list of upper bounds, in seconds, of the buckets of histograms exported for timings. Leave empty to use a default set of buckets.", []float64{0.001, 0.01, 0.1, 1}),
+			pathMappingDocs(true),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OTLPConfig contains configuration fields for the OTLP metrics type.
+type OTLPConfig struct {
+	Protocol           string            `json:"protocol" yaml:"protocol"`
+	Endpoint           string            `json:"endpoint" yaml:"endpoint"`
+	TLS                btls.Config       `json:"tls" yaml:"tls"`
+	Headers            map[string]string `json:"headers" yaml:"headers"`
+	ResourceAttributes map[string]string `json:"resource_attributes" yaml:"resource_attributes"`
+	Temporality        string            `json:"temporality" yaml:"temporality"`
+	PushInterval       string            `json:"push_interval" yaml:"push_interval"`
+	Timeout            string            `json:"timeout" yaml:"timeout"`
+	MaxBatchSize       int               `json:"max_batch_size" yaml:"max_batch_size"`
+	TimingBuckets      []float64         `json:"timing_buckets" yaml:"timing_buckets"`
+	PathMapping        string            `json:"path_mapping" yaml:"path_mapping"`
+}
+
+// NewOTLPConfig creates an OTLPConfig struct with default values.
+func NewOTLPConfig() OTLPConfig {
+	return OTLPConfig{
+		Protocol: "grpc",
+		Endpoint: "localhost:4317",
+		TLS:      btls.NewConfig(),
+		Headers:  map[string]string{},
+		ResourceAttributes: map[string]string{
+			"service.name": "benthos",
+		},
+		Temporality:   "cumulative",
+		PushInterval:  "10s",
+		Timeout:       "5s",
+		MaxBatchSize:  1000,
+		TimingBuckets: []float64{},
+		PathMapping:   "",
+	}
+}
+
+// Default buckets of timing histograms in seconds.
+var otlpDefaultTimingBuckets = []float64{
+	0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+//------------------------------------------------------------------------------
+
+type otlpSeries struct {
+	name  string
+	attrs []*commonpb.KeyValue
+}
+
+func newOTLPSeries(name string, labels, values []string) otlpSeries {
+	s := otlpSeries{name: name}
+	for i, l := range labels {
+		var v string
+		if i < len(values) {
+			v = values[i]
+		}
+		s.attrs = append(s.attrs, &commonpb.KeyValue{
+			Key: l,
+			Value: &commonpb.AnyValue{
+				Value: &commonpb.AnyValue_StringValue{StringValue: v},
+			},
+		})
+	}
+	return s
+}
+
+type otlpCounter struct {
+	otlpSeries
+	value    int64
+	exported int64
+}
+
+func (c *otlpCounter) Incr(count int64) error {
+	atomic.AddInt64(&c.value, count)
+	return nil
+}
+
+type otlpGauge struct {
+	otlpSeries
+	value int64
+}
+
+func (g *otlpGauge) Set(value int64) error {
+	atomic.StoreInt64(&g.value, value)
+	return nil
+}
+
+func (g *otlpGauge) Incr(count int64) error {
+	atomic.AddInt64(&g.value, count)
+	return nil
+}
+
+func (g *otlpGauge) Decr(count int64) error {
+	atomic.AddInt64(&g.value, -count)
+	return nil
+}
+
+type otlpTimer struct {
+	otlpSeries
+	bounds []float64
+
+	mut    sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (t *otlpTimer) Timing(delta int64) error {
+	secs := float64(delta) / float64(time.Second)
+	i := sort.SearchFloat64s(t.bounds, secs)
+
+	t.mut.Lock()
+	t.counts[i]++
+	t.count++
+	t.sum += secs
+	t.mut.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// OTLP is a metrics type that pushes metrics to an OTLP collector.
+type OTLP struct {
+	conf  OTLPConfig
+	delta bool
+
+	log         log.Modular
+	pathMapping *pathMapping
+
+	timeout  time.Duration
+	interval time.Duration
+	buckets  []float64
+	resource *resourcepb.Resource
+
+	grpcConn   *grpc.ClientConn
+	grpcClient colmetricspb.MetricsServiceClient
+	httpClient *http.Client
+
+	mut      sync.Mutex
+	counters map[string]*otlpCounter
+	gauges   map[string]*otlpGauge
+	timers   map[string]*otlpTimer
+
+	exportMut  sync.Mutex
+	startTime  time.Time
+	lastExport time.Time
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewOTLP creates and returns a new OTLP metrics type.
+func NewOTLP(config Config, opts ...func(Type)) (Type, error) {
+	conf := config.OTLP
+	o := &OTLP{
+		conf:       conf,
+		log:        log.Noop(),
+		counters:   map[string]*otlpCounter{},
+		gauges:     map[string]*otlpGauge{},
+		timers:     map[string]*otlpTimer{},
+		startTime:  time.Now(),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	o.lastExport = o.startTime
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var err error
+	if o.pathMapping, err = newPathMapping(conf.PathMapping, o.log); err != nil {
+		return nil, fmt.Errorf("failed to init path mapping: %v", err)
+	}
+	if o.interval, err = time.ParseDuration(conf.PushInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse push interval: %v", err)
+	}
+	if o.interval <= 0 {
+		return nil, errors.New("push interval must be greater than zero")
+	}
+	if o.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+	if conf.MaxBatchSize < 0 {
+		return nil, errors.New("max batch size must not be negative")
+	}
+
+	switch conf.Temporality {
+	case "cumulative":
+	case "delta":
+		o.delta = true
+	default:
+		return nil, fmt.Errorf("temporality not recognised: %v", conf.Temporality)
+	}
+
+	o.buckets = otlpDefaultTimingBuckets
+	if len(conf.TimingBuckets) > 0 {
+		o.buckets = append([]float64{}, conf.TimingBuckets...)
+		sort.Float64s(o.buckets)
+	}
+
+	o.resource = &resourcepb.Resource{}
+	attrKeys := make([]string, 0, len(conf.ResourceAttributes))
+	for k := range conf.ResourceAttributes {
+		attrKeys = append(attrKeys, k)
+	}
+	sort.Strings(attrKeys)
+	for _, k := range attrKeys {
+		o.resource.Attributes = append(o.resource.Attributes, &commonpb.KeyValue{
+			Key: k,
+			Value: &commonpb.AnyValue{
+				Value: &commonpb.AnyValue_StringValue{StringValue: conf.ResourceAttributes[k]},
+			},
+		})
+	}
+
+	switch conf.Protocol {
+	case "grpc":
+		var dialOpts []grpc.DialOption
+		if conf.TLS.Enabled {
+			tlsConf, err := conf.TLS.Get()
+			if err != nil {
+				return nil, err
+			}
+			dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+		} else {
+			dialOpts = append(dialOpts, grpc.WithInsecure())
+		}
+		if o.grpcConn, err = grpc.Dial(conf.Endpoint, dialOpts...); err != nil {
+			return nil, fmt.Errorf("failed to dial collector: %v", err)
+		}
+		o.grpcClient = colmetricspb.NewMetricsServiceClient(o.grpcConn)
+	case "http":
+		o.httpClient = &http.Client{
+			Timeout: o.timeout,
+		}
+		if conf.TLS.Enabled {
+			tlsConf, err := conf.TLS.Get()
+			if err != nil {
+				return nil, err
+			}
+			o.httpClient.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	default:
+		return nil, fmt.Errorf("protocol not recognised: %v", conf.Protocol)
+	}
+
+	go o.loop()
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+func otlpSeriesKey(name string, values []string) string {
+	return name + "\x00" + strings.Join(values, "\x00")
+}
+
+func (o *OTLP) getCounter(name string, labels, values []string) StatCounter {
+	key := otlpSeriesKey(name, values)
+
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	c, exists := o.counters[key]
+	if !exists {
+		c = &otlpCounter{otlpSeries: newOTLPSeries(name, labels, values)}
+		o.counters[key] = c
+	}
+	return c
+}
+
+func (o *OTLP) getGauge(name string, labels, values []string) StatGauge {
+	key := otlpSeriesKey(name, values)
+
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	g, exists := o.gauges[key]
+	if !exists {
+		g = &otlpGauge{otlpSeries: newOTLPSeries(name, labels, values)}
+		o.gauges[key] = g
+	}
+	return g
+}
+
+func (o *OTLP) getTimer(name string, labels, values []string) StatTimer {
+	key := otlpSeriesKey(name, values)
+
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	t, exists := o.timers[key]
+	if !exists {
+		t = &otlpTimer{
+			otlpSeries: newOTLPSeries(name, labels, values),
+			bounds:     o.buckets,
+			counts:     make([]uint64, len(o.buckets)+1),
+		}
+		o.timers[key] = t
+	}
+	return t
+}
+
+func joinLabels(a, b []string) []string {
+	joined := make([]string, 0, len(a)+len(b))
+	joined = append(joined, a...)
+	return append(joined, b...)
+}
+
+// GetCounter returns a stat counter object for a path.
+func (o *OTLP) GetCounter(path string) StatCounter {
+	name, labels, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return DudStat{}
+	}
+	return o.getCounter(name, labels, values)
+}
+
+// GetCounterVec returns a stat counter object for a path with labels.
+func (o *OTLP) GetCounterVec(path string, n []string) StatCounterVec {
+	name, labels, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return fakeCounterVec(func([]string) StatCounter {
+			return DudStat{}
+		})
+	}
+	labels = joinLabels(labels, n)
+	return fakeCounterVec(func(l []string) StatCounter {
+		return o.getCounter(name, labels, joinLabels(values, l))
+	})
+}
+
+// GetTimer returns a stat timer object for a path.
+func (o *OTLP) GetTimer(path string) StatTimer {
+	name, labels, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return DudStat{}
+	}
+	return o.getTimer(name, labels, values)
+}
+
+// GetTimerVec returns a stat timer object for a path with labels.
+func (o *OTLP) GetTimerVec(path string, n []string) StatTimerVec {
+	name, labels, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return fakeTimerVec(func([]string) StatTimer {
+			return DudStat{}
+		})
+	}
+	labels = joinLabels(labels, n)
+	return fakeTimerVec(func(l []string) StatTimer {
+		return o.getTimer(name, labels, joinLabels(values, l))
+	})
+}
+
+// GetGauge returns a stat gauge object for a path.
+func (o *OTLP) GetGauge(path string) StatGauge {
+	name, labels, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return DudStat{}
+	}
+	return o.getGauge(name, labels, values)
+}
+
+// GetGaugeVec returns a stat gauge object for a path with labels.
+func (o *OTLP) GetGaugeVec(path string, n []string) StatGaugeVec {
+	name, labels, values := o.pathMapping.mapPathWithTags(path)
+	if len(name) == 0 {
+		return fakeGaugeVec(func([]string) StatGauge {
+			return DudStat{}
+		})
+	}
+	labels = joinLabels(labels, n)
+	return fakeGaugeVec(func(l []string) StatGauge {
+		return o.getGauge(name, labels, joinLabels(values, l))
+	})
+}
+
+//------------------------------------------------------------------------------
+
+// snapshot returns the current state of all metrics, grouped by name and
+// ordered by name, and in delta mode resets counters and histograms.
+func (o *OTLP) snapshot() []*metricspb.Metric {
+	now := time.Now()
+	startTime := o.startTime
+	if o.delta {
+		startTime = o.lastExport
+	}
+	o.lastExport = now
+
+	nowNano, startNano := uint64(now.UnixNano()), uint64(startTime.UnixNano())
+	temporality := metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+	if o.delta {
+		temporality = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	}
+
+	o.mut.Lock()
+	counters := make([]*otlpCounter, 0, len(o.counters))
+	for _, c := range o.counters {
+		counters = append(counters, c)
+	}
+	gauges := make([]*otlpGauge, 0, len(o.gauges))
+	for _, g := range o.gauges {
+		gauges = append(gauges, g)
+	}
+	timers := make([]*otlpTimer, 0, len(o.timers))
+	for _, t := range o.timers {
+		timers = append(timers, t)
+	}
+	o.mut.Unlock()
+
+	byName := map[string]*metricspb.Metric{}
+	getMetric := func(name string, init func() *metricspb.Metric) *metricspb.Metric {
+		m, exists := byName[name]
+		if !exists {
+			m = init()
+			m.Name = name
+			byName[name] = m
+		}
+		return m
+	}
+
+	for _, c := range counters {
+		value := atomic.LoadInt64(&c.value)
+		if o.delta {
+			value, c.exported = value-c.exported, value
+		}
+		m := getMetric(c.name, func() *metricspb.Metric {
+			return &metricspb.Metric{Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				AggregationTemporality: temporality,
+				IsMonotonic:            true,
+			}}}
+		})
+		if sum, ok := m.Data.(*metricspb.Metric_Sum); ok {
+			sum.Sum.DataPoints = append(sum.Sum.DataPoints, &metricspb.NumberDataPoint{
+				Attributes:        c.attrs,
+				StartTimeUnixNano: startNano,
+				TimeUnixNano:      nowNano,
+				Value:             &metricspb.NumberDataPoint_AsInt{AsInt: value},
+			})
+		}
+	}
+
+	for _, g := range gauges {
+		m := getMetric(g.name, func() *metricspb.Metric {
+			return &metricspb.Metric{Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}}
+		})
+		if gauge, ok := m.Data.(*metricspb.Metric_Gauge); ok {
+			gauge.Gauge.DataPoints = append(gauge.Gauge.DataPoints, &metricspb.NumberDataPoint{
+				Attributes:   g.attrs,
+				TimeUnixNano: nowNano,
+				Value:        &metricspb.NumberDataPoint_AsInt{AsInt: atomic.LoadInt64(&g.value)},
+			})
+		}
+	}
+
+	for _, t := range timers {
+		t.mut.Lock()
+		point := &metricspb.HistogramDataPoint{
+			Attributes:        t.attrs,
+			StartTimeUnixNano: startNano,
+			TimeUnixNano:      nowNano,
+			Count:             t.count,
+			Sum:               t.sum,
+			BucketCounts:      append([]uint64{}, t.counts...),
+			ExplicitBounds:    t.bounds,
+		}
+		if o.delta {
+			t.count, t.sum = 0, 0
+			for i := range t.counts {
+				t.counts[i] = 0
+			}
+		}
+		t.mut.Unlock()
+
+		m := getMetric(t.name, func() *metricspb.Metric {
+			return &metricspb.Metric{Unit: "s", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+				AggregationTemporality: temporality,
+			}}}
+		})
+		if hist, ok := m.Data.(*metricspb.Metric_Histogram); ok {
+			hist.Histogram.DataPoints = append(hist.Histogram.DataPoints, point)
+		}
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]*metricspb.Metric, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, byName[name])
+	}
+	return metrics
+}
+
+// batchRequests splits metrics into export requests of the max batch size.
+func (o *OTLP) batchRequests(metrics []*metricspb.Metric) []*colmetricspb.ExportMetricsServiceRequest {
+	var reqs []*colmetricspb.ExportMetricsServiceRequest
+	for len(metrics) > 0 {
+		batch := metrics
+		if o.conf.MaxBatchSize > 0 && len(batch) > o.conf.MaxBatchSize {
+			batch = batch[:o.conf.MaxBatchSize]
+		}
+		metrics = metrics[len(batch):]
+
+		reqs = append(reqs, &colmetricspb.ExportMetricsServiceRequest{
+			ResourceMetrics: []*metricspb.ResourceMetrics{{
+				Resource: o.resource,
+				InstrumentationLibraryMetrics: []*metricspb.InstrumentationLibraryMetrics{{
+					InstrumentationLibrary: &commonpb.InstrumentationLibrary{
+						Name: "benthos",
+					},
+					Metrics: batch,
+				}},
+			}},
+		})
+	}
+	return reqs
+}
+
+func (o *OTLP) exportRequest(req *colmetricspb.ExportMetricsServiceRequest) error {
+	ctx, done := context.WithTimeout(context.Background(), o.timeout)
+	defer done()
+
+	if o.grpcClient != nil {
+		if len(o.conf.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.conf.Headers))
+		}
+		_, err := o.grpcClient.Export(ctx, req)
+		return err
+	}
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hReq, err := http.NewRequestWithContext(ctx, "POST", o.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range o.conf.Headers {
+		hReq.Header.Set(k, v)
+	}
+
+	res, err := o.httpClient.Do(hReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, resBody)
+	}
+	return nil
+}
+
+func (o *OTLP) export() error {
+	o.exportMut.Lock()
+	defer o.exportMut.Unlock()
+
+	for _, req := range o.batchRequests(o.snapshot()) {
+		if err := o.exportRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *OTLP) loop() {
+	defer close(o.closedChan)
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := o.export(); err != nil {
+				o.log.Errorf("Failed to export metrics: %v\n", err)
+			}
+		case <-o.closeChan:
+			return
+		}
+	}
+}
+
+// SetLogger sets the logger used to print export errors.
+func (o *OTLP) SetLogger(log log.Modular) {
+	o.log = log
+}
+
+// Close exports metrics one last time and closes the connection to the
+// collector.
+func (o *OTLP) Close() error {
+	o.closeOnce.Do(func() {
+		close(o.closeChan)
+	})
+	<-o.closedChan
+
+	if err := o.export(); err != nil {
+		o.log.Errorf("Failed to export metrics: %v\n", err)
+	}
+	if o.grpcConn != nil {
+		return o.grpcConn.Close()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func otlpTestServer(t *testing.T) (*httptest.Server, func() []*colmetricspb.ExportMetricsServiceRequest) {
+	t.Helper()
+
+	var reqsMut sync.Mutex
+	var reqs []*colmetricspb.ExportMetricsServiceRequest
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "bar", r.Header.Get("foo"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := &colmetricspb.ExportMetricsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+
+		reqsMut.Lock()
+		reqs = append(reqs, req)
+		reqsMut.Unlock()
+	}))
+	return srv, func() []*colmetricspb.ExportMetricsServiceRequest {
+		reqsMut.Lock()
+		defer reqsMut.Unlock()
+		r := reqs
+		reqs = nil
+		return r
+	}
+}
+
+func otlpTestMetrics(reqs []*colmetricspb.ExportMetricsServiceRequest) map[string]*metricspb.Metric {
+	metrics := map[string]*metricspb.Metric{}
+	for _, req := range reqs {
+		for _, rm := range req.ResourceMetrics {
+			for _, ilm := range rm.InstrumentationLibraryMetrics {
+				for _, m := range ilm.Metrics {
+					metrics[m.Name] = m
+				}
+			}
+		}
+	}
+	return metrics
+}
+
+func TestOTLPBadConfig(t *testing.T) {
+	for _, fn := range []func(c *OTLPConfig){
+		func(c *OTLPConfig) { c.Protocol = "carrier_pigeon" },
+		func(c *OTLPConfig) { c.Temporality = "nope" },
+		func(c *OTLPConfig) { c.PushInterval = "0s" },
+		func(c *OTLPConfig) { c.MaxBatchSize = -1 },
+	} {
+		conf := NewConfig()
+		conf.Type = TypeOTLP
+		fn(&conf.OTLP)
+		_, err := New(conf)
+		assert.Error(t, err)
+	}
+}
+
+func TestOTLPHTTPCumulative(t *testing.T) {
+	srv, getReqs := otlpTestServer(t)
+	defer srv.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeOTLP
+	conf.OTLP.Protocol = "http"
+	conf.OTLP.Endpoint = srv.URL
+	conf.OTLP.Headers = map[string]string{"foo": "bar"}
+	conf.OTLP.PushInterval = "1h"
+	conf.OTLP.TimingBuckets = []float64{0.1, 1}
+
+	m, err := New(conf)
+	require.NoError(t, err)
+	o := m.(*OTLP)
+
+	m.GetCounter("foo").Incr(2)
+	m.GetCounterVec("bar", []string{"label"}).With("a").Incr(1)
+	m.GetCounterVec("bar", []string{"label"}).With("b").Incr(3)
+	m.GetGauge("baz").Set(5)
+	m.GetTimer("buz").Timing(int64(time.Millisecond * 500))
+
+	require.NoError(t, o.export())
+	m.GetCounter("foo").Incr(1)
+	require.NoError(t, m.Close())
+
+	reqs := getReqs()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "service.name", reqs[0].ResourceMetrics[0].Resource.Attributes[0].Key)
+
+	metrics := otlpTestMetrics(reqs[1:])
+	require.Len(t, metrics, 4)
+
+	fooSum := metrics["foo"].GetSum()
+	require.NotNil(t, fooSum)
+	assert.True(t, fooSum.IsMonotonic)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, fooSum.AggregationTemporality)
+	assert.Equal(t, int64(3), fooSum.DataPoints[0].GetAsInt())
+
+	barSum := metrics["bar"].GetSum()
+	require.NotNil(t, barSum)
+	assert.Len(t, barSum.DataPoints, 2)
+	for _, dp := range barSum.DataPoints {
+		require.Len(t, dp.Attributes, 1)
+		assert.Equal(t, "label", dp.Attributes[0].Key)
+	}
+
+	bazGauge := metrics["baz"].GetGauge()
+	require.NotNil(t, bazGauge)
+	assert.Equal(t, int64(5), bazGauge.DataPoints[0].GetAsInt())
+
+	buzHist := metrics["buz"].GetHistogram()
+	require.NotNil(t, buzHist)
+	assert.Equal(t, uint64(1), buzHist.DataPoints[0].Count)
+	assert.Equal(t, []uint64{0, 1, 0}, buzHist.DataPoints[0].BucketCounts)
+	assert.Equal(t, []float64{0.1, 1}, buzHist.DataPoints[0].ExplicitBounds)
+}
+
+func TestOTLPHTTPDeltaBatched(t *testing.T) {
+	srv, getReqs := otlpTestServer(t)
+	defer srv.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeOTLP
+	conf.OTLP.Protocol = "http"
+	conf.OTLP.Endpoint = srv.URL
+	conf.OTLP.Headers = map[string]string{"foo": "bar"}
+	conf.OTLP.PushInterval = "1h"
+	conf.OTLP.Temporality = "delta"
+	conf.OTLP.MaxBatchSize = 1
+
+	m, err := New(conf)
+	require.NoError(t, err)
+	o := m.(*OTLP)
+
+	m.GetCounter("foo").Incr(2)
+	m.GetTimer("bar").Timing(int64(time.Millisecond))
+
+	require.NoError(t, o.export())
+	reqs := getReqs()
+	assert.Len(t, reqs, 2)
+
+	m.GetCounter("foo").Incr(1)
+	require.NoError(t, m.Close())
+
+	metrics := otlpTestMetrics(getReqs())
+	fooSum := metrics["foo"].GetSum()
+	require.NotNil(t, fooSum)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, fooSum.AggregationTemporality)
+	assert.Equal(t, int64(1), fooSum.DataPoints[0].GetAsInt())
+
+	barHist := metrics["bar"].GetHistogram()
+	require.NotNil(t, barHist)
+	assert.Equal(t, uint64(0), barHist.DataPoints[0].Count)
+}
@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
+)
+
+//------------------------------------------------------------------------------
+
+// Plugin is the interface implemented by metrics plugins registered with
+// RegisterPluginWithSpec. Each call identifies a metric by its path and labels,
+// where labels are nil for metrics registered without any. Calls are made
+// concurrently and therefore implementations must be thread safe.
+//
+// A plugin that also implements SetLogger(log.Modular) is given the logger of
+// the service once it is available.
+type Plugin interface {
+	// IncrCounter increments a counter by an amount.
+	IncrCounter(path string, labels map[string]string, count int64)
+
+	// Timing records a timing in nanoseconds.
+	Timing(path string, labels map[string]string, delta int64)
+
+	// SetGauge sets the value of a gauge. Increments and decrements of gauges
+	// are tracked by Benthos and result in a call to SetGauge with the new
+	// value.
+	SetGauge(path string, labels map[string]string, value int64)
+
+	// Close flushes any pending metrics and cleans up resources.
+	Close() error
+}
+
+// SpecPluginConstructor is a func that constructs a metrics plugin from a
+// configuration that has been parsed according to its spec.
+type SpecPluginConstructor func(conf *plugin.ParsedConfig) (Plugin, error)
+
+// RegisterPluginWithSpec registers a plugin by a unique name along with a spec
+// of its configuration fields, the constructor is given the user configuration
+// parsed according to the spec.
+//
+// An error is returned if the name is already used by a metrics type or
+// another plugin, or if the spec is invalid.
+func RegisterPluginWithSpec(name string, spec *plugin.ConfigSpec, constructor SpecPluginConstructor) error {
+	if _, exists := Constructors[name]; exists || name == "none" {
+		return fmt.Errorf("metrics type '%v' already exists", name)
+	}
+	if _, exists := pluginSpecs[name]; exists {
+		return fmt.Errorf("metrics plugin '%v' already exists", name)
+	}
+	if constructor == nil {
+		return errors.New("a constructor must be provided")
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	pluginSpecs[name] = pluginSpec{
+		constructor: func(conf Config, opts ...func(Type)) (Type, error) {
+			pConf, err := spec.Parse(conf.Plugin)
+			if err != nil {
+				return nil, err
+			}
+			p, err := constructor(pConf)
+			if err != nil {
+				return nil, err
+			}
+			t := newPluginMetrics(p)
+			for _, opt := range opts {
+				opt(t)
+			}
+			return t, nil
+		},
+		confConstructor: func() interface{} {
+			return spec.Defaults()
+		},
+		description: spec.Document(),
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type pluginStat struct {
+	path   string
+	labels map[string]string
+	p      Plugin
+}
+
+func (s *pluginStat) Incr(count int64) error {
+	s.p.IncrCounter(s.path, s.labels, count)
+	return nil
+}
+
+func (s *pluginStat) Timing(delta int64) error {
+	s.p.Timing(s.path, s.labels, delta)
+	return nil
+}
+
+type pluginGauge struct {
+	pluginStat
+	value int64
+}
+
+func (g *pluginGauge) Set(value int64) error {
+	atomic.StoreInt64(&g.value, value)
+	g.p.SetGauge(g.path, g.labels, value)
+	return nil
+}
+
+func (g *pluginGauge) Incr(count int64) error {
+	g.p.SetGauge(g.path, g.labels, atomic.AddInt64(&g.value, count))
+	return nil
+}
+
+func (g *pluginGauge) Decr(count int64) error {
+	g.p.SetGauge(g.path, g.labels, atomic.AddInt64(&g.value, -count))
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// pluginMetrics wraps a metrics plugin in order to implement Type.
+type pluginMetrics struct {
+	p Plugin
+
+	gaugesMut sync.Mutex
+	gauges    map[string]*pluginGauge
+}
+
+func newPluginMetrics(p Plugin) *pluginMetrics {
+	return &pluginMetrics{
+		p:      p,
+		gauges: map[string]*pluginGauge{},
+	}
+}
+
+func zipLabels(names, values []string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			labels[name] = values[i]
+		} else {
+			labels[name] = ""
+		}
+	}
+	return labels
+}
+
+// getGauge returns the gauge of a path and label values, which is shared
+// across calls in order to track its value.
+func (m *pluginMetrics) getGauge(path string, names, values []string) *pluginGauge {
+	key := path + "\x00" + strings.Join(values, "\x00")
+
+	m.gaugesMut.Lock()
+	defer m.gaugesMut.Unlock()
+
+	g, exists := m.gauges[key]
+	if !exists {
+		g = &pluginGauge{
+			pluginStat: pluginStat{
+				path:   path,
+				labels: zipLabels(names, values),
+				p:      m.p,
+			},
+		}
+		m.gauges[key] = g
+	}
+	return g
+}
+
+// GetCounter returns a stat counter object for a path.
+func (m *pluginMetrics) GetCounter(path string) StatCounter {
+	return &pluginStat{path: path, p: m.p}
+}
+
+// GetCounterVec returns a stat counter object for a path with labels.
+func (m *pluginMetrics) GetCounterVec(path string, n []string) StatCounterVec {
+	return fakeCounterVec(func(v []string) StatCounter {
+		return &pluginStat{path: path, labels: zipLabels(n, v), p: m.p}
+	})
+}
+
+// GetTimer returns a stat timer object for a path.
+func (m *pluginMetrics) GetTimer(path string) StatTimer {
+	return &pluginStat{path: path, p: m.p}
+}
+
+// GetTimerVec returns a stat timer object for a path with labels.
+func (m *pluginMetrics) GetTimerVec(path string, n []string) StatTimerVec {
+	return fakeTimerVec(func(v []string) StatTimer {
+		return &pluginStat{path: path, labels: zipLabels(n, v), p: m.p}
+	})
+}
+
+// GetGauge returns a stat gauge object for a path.
+func (m *pluginMetrics) GetGauge(path string) StatGauge {
+	return m.getGauge(path, nil, nil)
+}
+
+// GetGaugeVec returns a stat gauge object for a path with labels.
+func (m *pluginMetrics) GetGaugeVec(path string, n []string) StatGaugeVec {
+	return fakeGaugeVec(func(v []string) StatGauge {
+		return m.getGauge(path, n, v)
+	})
+}
+
+// SetLogger sets the logger of the plugin if it supports one.
+func (m *pluginMetrics) SetLogger(log log.Modular) {
+	if l, ok := m.p.(interface {
+		SetLogger(log.Modular)
+	}); ok {
+		l.SetLogger(log)
+	}
+}
+
+// Close stops the plugin from aggregating metrics and cleans up resources.
+func (m *pluginMetrics) Close() error {
+	return m.p.Close()
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+type mockSpecMetrics struct {
+	prefix string
+
+	mut    sync.Mutex
+	values map[string]int64
+	closed bool
+}
+
+func (m *mockSpecMetrics) key(path string, labels map[string]string) string {
+	return fmt.Sprintf("%v%v%v", m.prefix, path, labels)
+}
+
+func (m *mockSpecMetrics) IncrCounter(path string, labels map[string]string, count int64) {
+	m.mut.Lock()
+	m.values[m.key(path, labels)] += count
+	m.mut.Unlock()
+}
+
+func (m *mockSpecMetrics) Timing(path string, labels map[string]string, delta int64) {
+	m.mut.Lock()
+	m.values[m.key(path, labels)] = delta
+	m.mut.Unlock()
+}
+
+func (m *mockSpecMetrics) SetGauge(path string, labels map[string]string, value int64) {
+	m.mut.Lock()
+	m.values[m.key(path, labels)] = value
+	m.mut.Unlock()
+}
+
+func (m *mockSpecMetrics) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestYAMLPluginWithSpec(t *testing.T) {
+	var mock *mockSpecMetrics
+	spec := plugin.NewConfigSpec().
+		Summary("A map backed metrics target.").
+		Field("prefix", "A prefix for metric paths.", "")
+
+	err := RegisterPluginWithSpec("foo_spec", spec, func(conf *plugin.ParsedConfig) (Plugin, error) {
+		prefix, err := conf.FieldString("prefix")
+		if err != nil {
+			return nil, err
+		}
+		mock = &mockSpecMetrics{
+			prefix: prefix,
+			values: map[string]int64{},
+		}
+		return mock, nil
+	})
+	require.NoError(t, err)
+	defer delete(pluginSpecs, "foo_spec")
+
+	assert.Error(t, RegisterPluginWithSpec("foo_spec", spec, nil))
+	assert.Error(t, RegisterPluginWithSpec(TypeStatsd, spec, nil))
+	assert.Contains(t, PluginDescriptions(), "A map backed metrics target.")
+
+	conf := NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte("type: foo_spec\nplugin:\n  prefix: foo."), &conf))
+
+	m, err := New(conf)
+	require.NoError(t, err)
+
+	m.GetCounter("a").Incr(2)
+	m.GetCounterVec("b", []string{"label"}).With("value").Incr(3)
+	m.GetTimerVec("c", []string{"label"}).With("value").Timing(10)
+	m.GetGaugeVec("d", []string{"label"}).With("value").Set(5)
+	m.GetGaugeVec("d", []string{"label"}).With("value").Incr(2)
+	m.GetGauge("e").Decr(1)
+
+	assert.Equal(t, map[string]int64{
+		"foo.amap[]":            2,
+		"foo.bmap[label:value]": 3,
+		"foo.cmap[label:value]": 10,
+		"foo.dmap[label:value]": 7,
+		"foo.emap[]":            -1,
+	}, mock.values)
+
+	require.NoError(t, m.Close())
+	assert.True(t, mock.closed)
+}
@@ -57,12 +57,43 @@ func (p *PromCounter) Incr(count int64) error {
 // PromTiming is a representation of a single metric stat. Interactions with
 // this stat are thread safe.
 type PromTiming struct {
-	sum prometheus.Observer
+	sum     prometheus.Observer
+	seconds bool
+}
+
+func (p *PromTiming) value(val int64) float64 {
+	if p.seconds {
+		return float64(val) / float64(time.Second)
+	}
+	return float64(val)
 }
 
 // Timing sets a timing metric.
 func (p *PromTiming) Timing(val int64) error {
-	p.sum.Observe(float64(val))
+	p.sum.Observe(p.value(val))
+	return nil
+}
+
+// TimingWithExemplar sets a timing metric along with an exemplar, the exemplar
+// is only recorded when timings are exported as histograms.
+func (p *PromTiming) TimingWithExemplar(val int64, exemplar map[string]string) error {
+	if eo, ok := p.sum.(prometheus.ExemplarObserver); ok {
+		eo.ObserveWithExemplar(p.value(val), prometheus.Labels(exemplar))
+		return nil
+	}
+	p.sum.Observe(p.value(val))
+	return nil
+}
+
+// PromObserver is a representation of a single histogram or summary metric
+// stat. Interactions with this stat are thread safe.
+type PromObserver struct {
+	obs prometheus.Observer
+}
+
+// Observe adds a single observation to the metric.
+func (p *PromObserver) Observe(val float64) error {
+	p.obs.Observe(val)
 	return nil
 }
 
@@ -82,13 +113,15 @@ func (p *PromCounterVec) With(labelValues ...string) StatCounter {
 
 // PromTimingVec creates StatTimers with dynamic labels.
 type PromTimingVec struct {
-	sum *prometheus.SummaryVec
+	sum     prometheus.ObserverVec
+	seconds bool
 }
 
 // With returns a StatTimer with a set of label values.
 func (p *PromTimingVec) With(labelValues ...string) StatTimer {
 	return &PromTiming{
-		sum: p.sum.WithLabelValues(labelValues...),
+		sum:     p.sum.WithLabelValues(labelValues...),
+		seconds: p.seconds,
 	}
 }
 
@@ -104,6 +137,18 @@ func (p *PromGaugeVec) With(labelValues ...string) StatGauge {
 	}
 }
 
+// PromObserverVec creates StatObservers with dynamic labels.
+type PromObserverVec struct {
+	obs prometheus.ObserverVec
+}
+
+// With returns a StatObserver with a set of label values.
+func (p *PromObserverVec) With(labelValues ...string) StatObserver {
+	return &PromObserver{
+		obs: p.obs.WithLabelValues(labelValues...),
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Prometheus is a stats object with capability to hold internal stats as a JSON
@@ -121,7 +166,10 @@ type Prometheus struct {
 
 	counters map[string]*prometheus.CounterVec
 	gauges   map[string]*prometheus.GaugeVec
-	timers   map[string]*prometheus.SummaryVec
+	timers   map[string]prometheus.ObserverVec
+
+	histograms map[string]*prometheus.HistogramVec
+	summaries  map[string]*prometheus.SummaryVec
 
 	sync.Mutex
 }
@@ -136,7 +184,9 @@ func NewPrometheus(config Config, opts ...func(Type)) (Type, error) {
 		prefix:     config.Prometheus.Prefix,
 		counters:   map[string]*prometheus.CounterVec{},
 		gauges:     map[string]*prometheus.GaugeVec{},
-		timers:     map[string]*prometheus.SummaryVec{},
+		timers:     map[string]prometheus.ObserverVec{},
+		histograms: map[string]*prometheus.HistogramVec{},
+		summaries:  map[string]*prometheus.SummaryVec{},
 	}
 
 	for _, opt := range opts {
@@ -180,10 +230,18 @@ func NewPrometheus(config Config, opts ...func(Type)) (Type, error) {
 
 //------------------------------------------------------------------------------
 
-// HandlerFunc returns an http.HandlerFunc for scraping metrics.
+// HandlerFunc returns an http.HandlerFunc for scraping metrics. The OpenMetrics
+// format is served to scrapers that request it, which is required in order to
+// expose exemplars.
 func (p *Prometheus) HandlerFunc() http.HandlerFunc {
+	handler := promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+		}),
+	)
 	return func(w http.ResponseWriter, r *http.Request) {
-		promhttp.Handler().ServeHTTP(w, r)
+		handler.ServeHTTP(w, r)
 	}
 }
 
@@ -229,24 +287,13 @@ func (p *Prometheus) GetTimer(path string) StatTimer {
 		return DudStat{}
 	}
 
-	var tmr *prometheus.SummaryVec
-
 	p.Lock()
-	var exists bool
-	if tmr, exists = p.timers[stat]; !exists {
-		tmr = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-			Namespace:  p.prefix,
-			Name:       stat,
-			Help:       "Benthos Timing metric",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
-		}, labels)
-		prometheus.MustRegister(tmr)
-		p.timers[stat] = tmr
-	}
+	tmr := p.getTimerVec(stat, labels)
 	p.Unlock()
 
 	return &PromTiming{
-		sum: tmr.WithLabelValues(values...),
+		sum:     tmr.WithLabelValues(values...),
+		seconds: p.config.UseHistogramTiming,
 	}
 }
 
@@ -334,20 +381,8 @@ func (p *Prometheus) GetTimerVec(path string, labelNames []string) StatTimerVec
 		labelNames = append(labels, labelNames...)
 	}
 
-	var tmr *prometheus.SummaryVec
-
 	p.Lock()
-	var exists bool
-	if tmr, exists = p.timers[stat]; !exists {
-		tmr = prometheus.NewSummaryVec(prometheus.SummaryOpts{
-			Namespace:  p.prefix,
-			Name:       stat,
-			Help:       "Benthos Timing metric",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
-		}, labelNames)
-		prometheus.MustRegister(tmr)
-		p.timers[stat] = tmr
-	}
+	tmr := p.getTimerVec(stat, labelNames)
 	p.Unlock()
 
 	if len(labels) > 0 {
@@ -355,15 +390,53 @@ func (p *Prometheus) GetTimerVec(path string, labelNames []string) StatTimerVec
 			fvs := append([]string{}, values...)
 			fvs = append(fvs, vs...)
 			return (&PromTimingVec{
-				sum: tmr,
+				sum:     tmr,
+				seconds: p.config.UseHistogramTiming,
 			}).With(fvs...)
 		})
 	}
 	return &PromTimingVec{
-		sum: tmr,
+		sum:     tmr,
+		seconds: p.config.UseHistogramTiming,
 	}
 }
 
+// getTimerVec returns a registered timer vec, which is either a summary or a
+// histogram depending on config, registering a new one if it does not exist.
+// Must be called with the lock held.
+func (p *Prometheus) getTimerVec(stat string, labelNames []string) prometheus.ObserverVec {
+	if tmr, exists := p.timers[stat]; exists {
+		return tmr
+	}
+
+	var tmr prometheus.ObserverVec
+	if p.config.UseHistogramTiming {
+		buckets := p.config.HistogramBuckets
+		if len(buckets) == 0 {
+			buckets = prometheus.DefBuckets
+		}
+		hist := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: p.prefix,
+			Name:      stat,
+			Help:      "Benthos Timing metric",
+			Buckets:   buckets,
+		}, labelNames)
+		prometheus.MustRegister(hist)
+		tmr = hist
+	} else {
+		sum := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  p.prefix,
+			Name:       stat,
+			Help:       "Benthos Timing metric",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, labelNames)
+		prometheus.MustRegister(sum)
+		tmr = sum
+	}
+	p.timers[stat] = tmr
+	return tmr
+}
+
 // GetGaugeVec returns an editable gauge stat for a given path with labels,
 // these labels must be consistent with any other metrics registered on the same
 // path.
@@ -407,6 +480,93 @@ func (p *Prometheus) GetGaugeVec(path string, labelNames []string) StatGaugeVec
 	}
 }
 
+// GetHistogramVec returns an editable histogram stat for a given path with
+// labels and bucket upper bounds, these labels and buckets must be consistent
+// with any other metrics registered on the same path.
+func (p *Prometheus) GetHistogramVec(path string, labelNames []string, buckets []float64) StatObserverVec {
+	stat, labels, values := p.toPromName(path)
+	if len(stat) == 0 {
+		return fakeObserverVec(func([]string) StatObserver {
+			return DudStat{}
+		})
+	}
+	if len(labels) > 0 {
+		labelNames = append(labels, labelNames...)
+	}
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	var hist *prometheus.HistogramVec
+
+	p.Lock()
+	var exists bool
+	if hist, exists = p.histograms[stat]; !exists {
+		hist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: p.prefix,
+			Name:      stat,
+			Help:      "Benthos Histogram metric",
+			Buckets:   buckets,
+		}, labelNames)
+		prometheus.MustRegister(hist)
+		p.histograms[stat] = hist
+	}
+	p.Unlock()
+
+	return p.observerVec(hist, labels, values)
+}
+
+// GetSummaryVec returns an editable summary stat for a given path with labels
+// and quantile objectives, these labels and objectives must be consistent with
+// any other metrics registered on the same path.
+func (p *Prometheus) GetSummaryVec(path string, labelNames []string, objectives map[float64]float64) StatObserverVec {
+	stat, labels, values := p.toPromName(path)
+	if len(stat) == 0 {
+		return fakeObserverVec(func([]string) StatObserver {
+			return DudStat{}
+		})
+	}
+	if len(labels) > 0 {
+		labelNames = append(labels, labelNames...)
+	}
+	if len(objectives) == 0 {
+		objectives = map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001}
+	}
+
+	var sum *prometheus.SummaryVec
+
+	p.Lock()
+	var exists bool
+	if sum, exists = p.summaries[stat]; !exists {
+		sum = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  p.prefix,
+			Name:       stat,
+			Help:       "Benthos Summary metric",
+			Objectives: objectives,
+		}, labelNames)
+		prometheus.MustRegister(sum)
+		p.summaries[stat] = sum
+	}
+	p.Unlock()
+
+	return p.observerVec(sum, labels, values)
+}
+
+func (p *Prometheus) observerVec(obs prometheus.ObserverVec, labels, values []string) StatObserverVec {
+	if len(labels) > 0 {
+		return fakeObserverVec(func(vs []string) StatObserver {
+			fvs := append([]string{}, values...)
+			fvs = append(fvs, vs...)
+			return (&PromObserverVec{
+				obs: obs,
+			}).With(fvs...)
+		})
+	}
+	return &PromObserverVec{
+		obs: obs,
+	}
+}
+
 // SetLogger does nothing.
 func (p *Prometheus) SetLogger(log log.Modular) {
 	p.log = log
@@ -15,6 +15,8 @@ instead be underscores.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("prefix", "A string prefix to add to all metrics."),
 			pathMappingDocs(true),
+			docs.FieldAdvanced("use_histogram_timing", "Whether to export timing metrics as a histogram, if `false` a summary is used instead. When exporting histogram timings the delta values are converted from nanoseconds into seconds in order to better fit within bucket definitions, and [exemplars](#exemplars) are attached to observations when tracing is enabled.").AtVersion("3.39.0"),
+			docs.FieldAdvanced("histogram_buckets", "A list of upper bounds, in seconds, of the buckets of histograms exported for timings when `use_histogram_timing` is enabled. Leave empty to use the [default buckets](https://pkg.go.dev/github.com/prometheus/client_golang/prometheus#pkg-variables).", []float64{0.001, 0.01, 0.1, 1}).AtVersion("3.39.0"),
 			docs.FieldAdvanced("push_url", "An optional [Push Gateway URL](#push-gateway) to push metrics to."),
 			docs.FieldAdvanced("push_interval", "The period of time between each push when sending metrics to a Push Gateway."),
 			docs.FieldAdvanced("push_job_name", "An identifier for push jobs."),
@@ -35,7 +37,16 @@ The Push Gateway is useful for when Benthos instances are short lived. Do not
 include the "/metrics/jobs/..." path in the push URL.
 
 If the Push Gateway requires HTTP Basic Authentication it can be configured with
-` + "`push_basic_auth`.",
+` + "`push_basic_auth`." + `
+
+## Exemplars
+
+When ` + "`use_histogram_timing`" + ` is set to ` + "`true`" + ` and a
+[tracer](/docs/components/tracers/about) is configured the latency metrics of
+inputs and outputs are recorded with an exemplar containing the ID of a trace
+that the timing belongs to, allowing you to jump from a latency spike on a
+dashboard straight to representative traces. Exemplars are only exposed when
+metrics are scraped using the OpenMetrics format.`,
 	}
 }
 
@@ -43,12 +54,14 @@ If the Push Gateway requires HTTP Basic Authentication it can be configured with
 
 // PrometheusConfig is config for the Prometheus metrics type.
 type PrometheusConfig struct {
-	Prefix        string                        `json:"prefix" yaml:"prefix"`
-	PathMapping   string                        `json:"path_mapping" yaml:"path_mapping"`
-	PushURL       string                        `json:"push_url" yaml:"push_url"`
-	PushBasicAuth PrometheusPushBasicAuthConfig `json:"push_basic_auth" yaml:"push_basic_auth"`
-	PushInterval  string                        `json:"push_interval" yaml:"push_interval"`
-	PushJobName   string                        `json:"push_job_name" yaml:"push_job_name"`
+	Prefix             string                        `json:"prefix" yaml:"prefix"`
+	PathMapping        string                        `json:"path_mapping" yaml:"path_mapping"`
+	UseHistogramTiming bool                          `json:"use_histogram_timing" yaml:"use_histogram_timing"`
+	HistogramBuckets   []float64                     `json:"histogram_buckets" yaml:"histogram_buckets"`
+	PushURL            string                        `json:"push_url" yaml:"push_url"`
+	PushBasicAuth      PrometheusPushBasicAuthConfig `json:"push_basic_auth" yaml:"push_basic_auth"`
+	PushInterval       string                        `json:"push_interval" yaml:"push_interval"`
+	PushJobName        string                        `json:"push_job_name" yaml:"push_job_name"`
 }
 
 // PrometheusPushBasicAuthConfig contains parameters for establishing basic
@@ -69,12 +82,14 @@ func NewPrometheusPushBasicAuthConfig() PrometheusPushBasicAuthConfig {
 // NewPrometheusConfig creates an PrometheusConfig struct with default values.
 func NewPrometheusConfig() PrometheusConfig {
 	return PrometheusConfig{
-		Prefix:        "benthos",
-		PathMapping:   "",
-		PushURL:       "",
-		PushBasicAuth: NewPrometheusPushBasicAuthConfig(),
-		PushInterval:  "",
-		PushJobName:   "benthos_push",
+		Prefix:             "benthos",
+		PathMapping:        "",
+		UseHistogramTiming: false,
+		HistogramBuckets:   []float64{},
+		PushURL:            "",
+		PushBasicAuth:      NewPrometheusPushBasicAuthConfig(),
+		PushInterval:       "",
+		PushJobName:        "benthos_push",
 	}
 }
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPrometheusNoPushGateway(t *testing.T) {
@@ -82,3 +83,57 @@ func TestPrometheusWithPushGatewayAndPushInterval(t *testing.T) {
 		assert.Fail(t, "PushGateway did not receive expected messages after close")
 	}
 }
+
+func TestPrometheusDistributions(t *testing.T) {
+	config := NewConfig()
+	config.Prometheus.Prefix = "dist_test"
+
+	p, err := NewPrometheus(config)
+	require.NoError(t, err)
+
+	dp, ok := p.(WithDistributions)
+	require.True(t, ok)
+
+	dp.GetHistogramVec("foo.hist", []string{"label"}, []float64{1, 10}).With("a").Observe(5)
+	dp.GetSummaryVec("foo.sum", nil, nil).With().Observe(3)
+
+	rec := httptest.NewRecorder()
+	p.(WithHandlerFunc).HandlerFunc()(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `dist_test_foo_hist_bucket{label="a",le="1"} 0`)
+	assert.Contains(t, body, `dist_test_foo_hist_bucket{label="a",le="10"} 1`)
+	assert.Contains(t, body, `dist_test_foo_sum_sum 3`)
+	assert.Contains(t, body, `dist_test_foo_sum{quantile="0.99"} 3`)
+
+	require.NoError(t, p.Close())
+}
+
+func TestPrometheusHistogramTimingExemplars(t *testing.T) {
+	config := NewConfig()
+	config.Prometheus.Prefix = "exemplar_test"
+	config.Prometheus.UseHistogramTiming = true
+	config.Prometheus.HistogramBuckets = []float64{0.1, 1}
+
+	p, err := NewPrometheus(config)
+	require.NoError(t, err)
+
+	require.NoError(t, TimingWithTraceID(p.GetTimer("foo.latency"), int64(time.Millisecond*500), func() string {
+		return "4bf92f3577b34da6a3ce929d0e0e4736"
+	}))
+	require.NoError(t, TimingWithTraceID(p.GetTimerVec("bar.latency", []string{"label"}).With("a"), int64(time.Millisecond*50), func() string {
+		return ""
+	}))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	rec := httptest.NewRecorder()
+	p.(WithHandlerFunc).HandlerFunc()(rec, req)
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `exemplar_test_foo_latency_bucket{le="0.1"} 0`)
+	assert.Contains(t, body, `exemplar_test_foo_latency_bucket{le="1"} 1 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 0.5`)
+	assert.Contains(t, body, `exemplar_test_bar_latency_bucket{label="a",le="0.1"} 1`)
+
+	require.NoError(t, p.Close())
+}
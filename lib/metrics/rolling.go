@@ -0,0 +1,262 @@
+package metrics
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	rollingBucketPeriod = 10 * time.Second
+	rollingBuckets      = 30
+	rollingMaxSamples   = 100
+)
+
+// The windows over which rolling stats are summarised.
+var rollingWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+}
+
+type rollingBucket struct {
+	epoch   int64
+	count   int64
+	seen    int64
+	samples []int64
+}
+
+// rollingStat records the events of a counter or the observations of a timer
+// within buckets of time so that rates and percentiles can be calculated over
+// recent windows.
+type rollingStat struct {
+	mut     sync.Mutex
+	started time.Time
+	buckets [rollingBuckets]rollingBucket
+	now     func() time.Time
+}
+
+func newRollingStat(now func() time.Time) *rollingStat {
+	return &rollingStat{
+		started: now(),
+		now:     now,
+	}
+}
+
+// bucket returns the bucket for the current period, the lock must be held.
+func (r *rollingStat) bucket() *rollingBucket {
+	epoch := r.now().UnixNano() / int64(rollingBucketPeriod)
+	b := &r.buckets[epoch%rollingBuckets]
+	if b.epoch != epoch {
+		b.epoch = epoch
+		b.count = 0
+		b.seen = 0
+		b.samples = b.samples[:0]
+	}
+	return b
+}
+
+func (r *rollingStat) incr(count int64) {
+	r.mut.Lock()
+	r.bucket().count += count
+	r.mut.Unlock()
+}
+
+func (r *rollingStat) observe(value int64) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	b := r.bucket()
+	b.count++
+	b.seen++
+
+	// Reservoir sampling keeps the memory used by each bucket bounded whilst
+	// giving every observation an equal chance of being kept.
+	if len(b.samples) < rollingMaxSamples {
+		b.samples = append(b.samples, value)
+	} else if i := rand.Int63n(b.seen); i < rollingMaxSamples {
+		b.samples[i] = value
+	}
+}
+
+// summary returns the rate per second of events within a window and, if
+// percentiles are requested, the percentiles of sampled observations.
+func (r *rollingStat) summary(window time.Duration, percentiles []float64) (rate float64, values []int64) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := r.now()
+	epoch := now.UnixNano() / int64(rollingBucketPeriod)
+	nBuckets := int64(window / rollingBucketPeriod)
+
+	var count int64
+	var samples []int64
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if b.epoch <= epoch-nBuckets || b.epoch > epoch {
+			continue
+		}
+		count += b.count
+		if len(percentiles) > 0 {
+			samples = append(samples, b.samples...)
+		}
+	}
+
+	elapsed := time.Duration(nBuckets-1)*rollingBucketPeriod + time.Duration(now.UnixNano()%int64(rollingBucketPeriod))
+	if sinceStart := now.Sub(r.started); sinceStart < elapsed {
+		elapsed = sinceStart
+	}
+	if elapsed > 0 {
+		rate = float64(count) / elapsed.Seconds()
+	}
+
+	if len(percentiles) == 0 || len(samples) == 0 {
+		return rate, nil
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	for _, p := range percentiles {
+		i := int(p*float64(len(samples))+0.5) - 1
+		if i < 0 {
+			i = 0
+		} else if i >= len(samples) {
+			i = len(samples) - 1
+		}
+		values = append(values, samples[i])
+	}
+	return rate, values
+}
+
+//------------------------------------------------------------------------------
+
+// rollingStats is a registry of rolling stats for counters and timers, which
+// can be summarised per component.
+type rollingStats struct {
+	mut      sync.Mutex
+	counters map[string]*rollingStat
+	timers   map[string]*rollingStat
+	now      func() time.Time
+}
+
+func newRollingStats() *rollingStats {
+	return &rollingStats{
+		counters: map[string]*rollingStat{},
+		timers:   map[string]*rollingStat{},
+		now:      time.Now,
+	}
+}
+
+func (r *rollingStats) get(m map[string]*rollingStat, path string) *rollingStat {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	s, exists := m[path]
+	if !exists {
+		s = newRollingStat(r.now)
+		m[path] = s
+	}
+	return s
+}
+
+// wrapCounter returns a counter that increments both the provided counter and
+// a rolling stat for the path.
+func (r *rollingStats) wrapCounter(path string, c StatCounter) StatCounter {
+	return &rollingCounter{StatCounter: c, r: r.get(r.counters, path)}
+}
+
+// wrapTimer returns a timer that records timings with both the provided timer
+// and a rolling stat for the path.
+func (r *rollingStats) wrapTimer(path string, t StatTimer) StatTimer {
+	return &rollingTimer{StatTimer: t, r: r.get(r.timers, path)}
+}
+
+// byComponent returns a summary of all rolling stats that belong to a
+// component, grouped by the path of that component.
+func (r *rollingStats) byComponent() map[string]map[string]interface{} {
+	r.mut.Lock()
+	counters := make(map[string]*rollingStat, len(r.counters))
+	for k, v := range r.counters {
+		counters[k] = v
+	}
+	timers := make(map[string]*rollingStat, len(r.timers))
+	for k, v := range r.timers {
+		timers[k] = v
+	}
+	r.mut.Unlock()
+
+	components := map[string]map[string]interface{}{}
+	add := func(path string, summary map[string]interface{}) {
+		name, labels := parseComponentPath(path)
+		if labels == nil {
+			return
+		}
+		component := labels[2]
+		if labels[0] != "" {
+			component = labels[0] + "." + component
+		}
+		if _, exists := components[component]; !exists {
+			components[component] = map[string]interface{}{}
+		}
+		components[component][strings.SplitN(name, ".", 2)[1]] = summary
+	}
+
+	for path, s := range counters {
+		summary := map[string]interface{}{}
+		for _, w := range rollingWindows {
+			rate, _ := s.summary(w.duration, nil)
+			summary[w.name] = map[string]interface{}{
+				"rate": rate,
+			}
+		}
+		add(path, summary)
+	}
+
+	percentiles := []float64{0.5, 0.9, 0.99}
+	percentileNames := []string{"p50", "p90", "p99"}
+	for path, s := range timers {
+		summary := map[string]interface{}{}
+		for _, w := range rollingWindows {
+			rate, values := s.summary(w.duration, percentiles)
+			obj := map[string]interface{}{
+				"rate": rate,
+			}
+			for i, v := range values {
+				obj[percentileNames[i]] = v
+				obj[percentileNames[i]+"_readable"] = time.Duration(v).String()
+			}
+			summary[w.name] = obj
+		}
+		add(path, summary)
+	}
+
+	return components
+}
+
+//------------------------------------------------------------------------------
+
+type rollingCounter struct {
+	StatCounter
+	r *rollingStat
+}
+
+func (c *rollingCounter) Incr(count int64) error {
+	c.r.incr(count)
+	return c.StatCounter.Incr(count)
+}
+
+type rollingTimer struct {
+	StatTimer
+	r *rollingStat
+}
+
+func (t *rollingTimer) Timing(delta int64) error {
+	t.r.observe(delta)
+	return t.StatTimer.Timing(delta)
+}
+
+//------------------------------------------------------------------------------
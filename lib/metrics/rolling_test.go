@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollingStatRates(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := newRollingStat(func() time.Time { return now })
+
+	for i := 0; i < 6; i++ {
+		s.incr(30)
+		now = now.Add(10 * time.Second)
+	}
+
+	// The first bucket has fallen out of the window.
+	rate, _ := s.summary(time.Minute, nil)
+	assert.InDelta(t, 3, rate, 0.01)
+
+	// Only a minute has elapsed so the rate is over that minute.
+	rate, _ = s.summary(5*time.Minute, nil)
+	assert.InDelta(t, 3, rate, 0.01)
+
+	now = now.Add(2 * time.Minute)
+	s.incr(60)
+
+	rate, _ = s.summary(time.Minute, nil)
+	assert.InDelta(t, 1.2, rate, 0.01)
+
+	rate, _ = s.summary(5*time.Minute, nil)
+	assert.InDelta(t, 1.33, rate, 0.01)
+}
+
+func TestRollingStatPercentiles(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := newRollingStat(func() time.Time { return now })
+
+	now = now.Add(5 * time.Second)
+	for i := int64(1); i <= 100; i++ {
+		s.observe(i)
+	}
+
+	_, values := s.summary(time.Minute, []float64{0.5, 0.9, 0.99})
+	assert.Equal(t, []int64{50, 90, 99}, values)
+
+	now = now.Add(5 * time.Minute)
+	_, values = s.summary(time.Minute, []float64{0.5})
+	assert.Empty(t, values)
+}
+
+func TestHTTPLiveStats(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeHTTPServer
+
+	m, err := NewHTTP(conf)
+	require.NoError(t, err)
+
+	m.GetCounter("input.received").Incr(10)
+	m.GetTimer("output.batch.latency").Timing(int64(time.Millisecond))
+	m.GetCounter("http.requests").Incr(1)
+
+	res := httptest.NewRecorder()
+	m.(WithHandlerFunc).HandlerFunc()(res, httptest.NewRequest("GET", "/stats?live=true", nil))
+
+	var obj struct {
+		Components map[string]map[string]map[string]map[string]interface{} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &obj))
+
+	assert.Len(t, obj.Components, 2)
+	assert.Greater(t, obj.Components["input"]["received"]["1m"]["rate"], float64(0))
+	assert.Equal(t, "1ms", obj.Components["output"]["batch.latency"]["5m"]["p99_readable"])
+}
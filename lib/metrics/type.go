@@ -22,6 +22,34 @@ type StatTimer interface {
 	Timing(delta int64) error
 }
 
+// StatTimerWithExemplar is an optional interface implemented by StatTimers that
+// are able to attach exemplars, such as the ID of a trace, to timings.
+type StatTimerWithExemplar interface {
+	// TimingWithExemplar sets a timing metric along with an exemplar made of
+	// a set of labels.
+	TimingWithExemplar(delta int64, exemplar map[string]string) error
+}
+
+// TimingWithTraceID sets a timing metric and, if the timer supports exemplars
+// and the provided func returns a non-empty trace ID, attaches an exemplar
+// with the label trace_id. The func is only called when exemplars are
+// supported.
+func TimingWithTraceID(t StatTimer, delta int64, traceID func() string) error {
+	if _, ok := t.(StatTimerWithExemplar); ok {
+		if id := traceID(); len(id) > 0 {
+			return timingWithExemplar(t, delta, map[string]string{"trace_id": id})
+		}
+	}
+	return t.Timing(delta)
+}
+
+func timingWithExemplar(t StatTimer, delta int64, exemplar map[string]string) error {
+	if et, ok := t.(StatTimerWithExemplar); ok {
+		return et.TimingWithExemplar(delta, exemplar)
+	}
+	return t.Timing(delta)
+}
+
 // StatGauge is a representation of a single gauge metric stat. Interactions
 // with this stat are thread safe.
 type StatGauge interface {
@@ -35,6 +63,13 @@ type StatGauge interface {
 	Decr(count int64) error
 }
 
+// StatObserver is a representation of a single histogram or summary metric
+// stat. Interactions with this stat are thread safe.
+type StatObserver interface {
+	// Observe adds a single observation to the metric.
+	Observe(value float64) error
+}
+
 //------------------------------------------------------------------------------
 
 // StatCounterVec creates StatCounters with dynamic labels.
@@ -55,6 +90,12 @@ type StatGaugeVec interface {
 	With(labelValues ...string) StatGauge
 }
 
+// StatObserverVec creates StatObservers with dynamic labels.
+type StatObserverVec interface {
+	// With returns a StatObserver with a set of label values.
+	With(labelValues ...string) StatObserver
+}
+
 //------------------------------------------------------------------------------
 
 // Type is an interface for metrics aggregation.
@@ -100,3 +141,20 @@ type WithHandlerFunc interface {
 }
 
 //------------------------------------------------------------------------------
+
+// WithDistributions is an interface for metrics types that natively support
+// histogram and summary metrics. If a Type cannot be cast into
+// WithDistributions then observations should be recorded as timings instead.
+type WithDistributions interface {
+	// GetHistogramVec returns an editable histogram stat for a given path with
+	// labels and bucket upper bounds, where empty buckets results in a default
+	// set.
+	GetHistogramVec(path string, labelNames []string, buckets []float64) StatObserverVec
+
+	// GetSummaryVec returns an editable summary stat for a given path with
+	// labels and quantile objectives mapped to their absolute errors, where
+	// empty objectives results in a default set.
+	GetSummaryVec(path string, labelNames []string, objectives map[float64]float64) StatObserverVec
+}
+
+//------------------------------------------------------------------------------
@@ -49,3 +49,19 @@ func fakeGaugeVec(f func([]string) StatGauge) StatGaugeVec {
 }
 
 //------------------------------------------------------------------------------
+
+type fObserverVec struct {
+	f func([]string) StatObserver
+}
+
+func (f *fObserverVec) With(labels ...string) StatObserver {
+	return f.f(labels)
+}
+
+func fakeObserverVec(f func([]string) StatObserver) StatObserverVec {
+	return &fObserverVec{
+		f: f,
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -7,12 +7,14 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/event"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aimd"
 	"github.com/Jeffail/benthos/v3/lib/util/throttle"
 )
 
@@ -42,11 +44,14 @@ type AsyncWriter struct {
 
 	typeStr     string
 	maxInflight int
+	limiter     *aimd.Limiter
 	writer      AsyncSink
 
 	log   log.Modular
 	stats metrics.Type
 
+	activity types.ConnectionActivity
+
 	transactions <-chan types.Transaction
 
 	ctx           context.Context
@@ -57,6 +62,14 @@ type AsyncWriter struct {
 	closedChan chan struct{}
 }
 
+// OptAsyncWriterSetLimiter sets an adaptive limiter that tunes the number of
+// messages in flight of an AsyncWriter, up to its maximum.
+func OptAsyncWriterSetLimiter(l *aimd.Limiter) func(*AsyncWriter) {
+	return func(w *AsyncWriter) {
+		w.limiter = l
+	}
+}
+
 // NewAsyncWriter creates a new AsyncWriter output type.
 func NewAsyncWriter(
 	typeStr string,
@@ -64,6 +77,7 @@ func NewAsyncWriter(
 	w AsyncSink,
 	log log.Modular,
 	stats metrics.Type,
+	opts ...func(*AsyncWriter),
 ) (Type, error) {
 	aWriter := &AsyncWriter{
 		running:      1,
@@ -76,6 +90,10 @@ func NewAsyncWriter(
 		closedChan:   make(chan struct{}),
 	}
 
+	for _, opt := range opts {
+		opt(aWriter)
+	}
+
 	aWriter.ctx, aWriter.close = context.WithCancel(context.Background())
 	aWriter.fullyCloseCtx, aWriter.fullyClose = context.WithCancel(context.Background())
 
@@ -115,16 +133,24 @@ func (w *AsyncWriter) loop() {
 		close(w.closedChan)
 	}()
 
-	throt := throttle.New(throttle.OptCloseChan(w.ctx.Done()))
+	throt := throttle.New(
+		throttle.OptCloseChan(w.ctx.Done()),
+		throttle.OptOnStateChange(retryStateGauges(w.stats)),
+	)
+	w.activity.SetBackoff(throt)
 
 	for {
 		if err := w.writer.ConnectWithContext(w.ctx); err != nil {
 			// Close immediately if our writer is closed.
 			if err == types.ErrTypeClosed {
+				if w.limiter != nil {
+					w.limiter.Cancel()
+				}
 				return
 			}
 
 			w.log.Errorf("Failed to connect to %v: %v\n", w.typeStr, err)
+			w.activity.SetError(err)
 			mFailedConn.Incr(1)
 			if !throt.Retry() {
 				return
@@ -134,6 +160,7 @@ func (w *AsyncWriter) loop() {
 		}
 	}
 	mConn.Incr(1)
+	event.Emit(event.TypeOutputConnected, w.typeStr, nil)
 	atomic.StoreInt32(&w.isConnected, 1)
 
 	wg := sync.WaitGroup{}
@@ -154,6 +181,7 @@ func (w *AsyncWriter) loop() {
 			}
 		}
 		mLostConn.Incr(1)
+		event.Emit(event.TypeOutputDisconnected, w.typeStr, nil)
 
 		// Continue to try to reconnect while still active.
 		for atomic.LoadInt32(&w.running) == 1 {
@@ -164,6 +192,7 @@ func (w *AsyncWriter) loop() {
 				}
 
 				w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
+				w.activity.SetError(err)
 				mFailedConn.Incr(1)
 				if !throt.Retry() {
 					return
@@ -171,6 +200,7 @@ func (w *AsyncWriter) loop() {
 			} else if latency, err = w.latencyMeasuringWrite(msg); err != types.ErrNotConnected {
 				atomic.StoreInt32(&w.isConnected, 1)
 				mConn.Incr(1)
+				event.Emit(event.TypeOutputConnected, w.typeStr, nil)
 				break
 			} else if !throt.Retry() {
 				return
@@ -183,20 +213,30 @@ func (w *AsyncWriter) loop() {
 		defer wg.Done()
 
 		for atomic.LoadInt32(&w.running) == 1 {
+			if w.limiter != nil && !w.limiter.Acquire(w.ctx) {
+				return
+			}
+
 			var ts types.Transaction
 			var open bool
 			select {
 			case ts, open = <-w.transactions:
 				if !open {
+					if w.limiter != nil {
+						w.limiter.Cancel()
+					}
 					return
 				}
 				mCount.Incr(1)
 			case <-w.ctx.Done():
+				if w.limiter != nil {
+					w.limiter.Cancel()
+				}
 				return
 			}
 
 			w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
-			spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
+			spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload, tracing.ComponentTags("output", w.typeStr, ts.Payload.Len()))
 			latency, err := w.latencyMeasuringWrite(ts.Payload)
 
 			// If our writer says it is not connected.
@@ -206,12 +246,21 @@ func (w *AsyncWriter) loop() {
 
 			// Close immediately if our writer is closed.
 			if err == types.ErrTypeClosed {
+				if w.limiter != nil {
+					w.limiter.Cancel()
+				}
 				return
 			}
 
+			if w.limiter != nil {
+				w.limiter.Release(time.Duration(latency), err)
+			}
+
 			if err != nil {
 				if w.typeStr != TypeReject {
 					w.log.Errorf("Failed to send message to %v: %v\n", w.typeStr, err)
+					w.activity.SetError(err)
+					event.Emit(event.TypeOutputWriteFailed, w.typeStr, err)
 				} else {
 					w.log.Infof("Rejecting message: %v\n", err)
 				}
@@ -219,10 +268,13 @@ func (w *AsyncWriter) loop() {
 					return
 				}
 			} else {
+				w.activity.SetActive()
 				mSent.Incr(1)
 				mPartsSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
 				mBytesSent.Incr(int64(message.GetAllBytesLen(ts.Payload)))
-				mLatency.Timing(latency)
+				metrics.TimingWithTraceID(mLatency, latency, func() string {
+					return tracing.BatchTraceID(ts.Payload)
+				})
 				w.log.Tracef("Successfully wrote %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 				throt.Reset() // TODO BAD PAYLOAD NAUGHTY RESETS
 			}
@@ -267,6 +319,11 @@ func (w *AsyncWriter) Connected() bool {
 	return atomic.LoadInt32(&w.isConnected) == 1
 }
 
+// ConnectionStatuses returns the connection status of this output.
+func (w *AsyncWriter) ConnectionStatuses() []types.ConnectionStatus {
+	return []types.ConnectionStatus{w.activity.Status(w.typeStr, w.Connected())}
+}
+
 // CloseAsync shuts down the File output and stops processing messages.
 func (w *AsyncWriter) CloseAsync() {
 	w.close()
@@ -11,6 +11,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aimd"
 )
 
 //------------------------------------------------------------------------------
@@ -635,3 +636,132 @@ func TestAsyncWriterSadPath(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestAsyncWriterAdaptiveLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := aimd.New(aimd.NewConfig(), 3, metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writerImpl := newAsyncMockWriter()
+	w, err := NewAsyncWriter(
+		"foo", 3, writerImpl,
+		log.Noop(), metrics.Noop(),
+		OptAsyncWriterSetLimiter(limiter),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	if err = w.Consume(msgChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	sendMsg := func() bool {
+		select {
+		case msgChan <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+			return true
+		case <-time.After(time.Millisecond * 50):
+			return false
+		}
+	}
+	ackMsgs := func(n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case writerImpl.writeChan <- nil:
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+		}
+		for i := 0; i < n; i++ {
+			select {
+			case res := <-resChan:
+				if res.Error() != nil {
+					t.Error(res.Error())
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Timed out")
+			}
+		}
+	}
+
+	// The limit starts at one message in flight.
+	if !sendMsg() {
+		t.Fatal("Failed to send first message")
+	}
+	if sendMsg() {
+		t.Fatal("Expected second message to be blocked by the limit")
+	}
+	ackMsgs(1)
+
+	// A successful write increases the limit to two.
+	for i := 0; i < 2; i++ {
+		if !sendMsg() {
+			t.Fatalf("Failed to send message %v", i)
+		}
+	}
+	if sendMsg() {
+		t.Fatal("Expected third message to be blocked by the limit")
+	}
+	ackMsgs(2)
+
+	w.CloseAsync()
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAsyncWriterAdaptiveLimitClosed(t *testing.T) {
+	t.Parallel()
+
+	limiter, err := aimd.New(aimd.NewConfig(), 3, metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writerImpl := newAsyncMockWriter()
+	w, err := NewAsyncWriter(
+		"foo", 3, writerImpl,
+		log.Noop(), metrics.Noop(),
+		OptAsyncWriterSetLimiter(limiter),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgChan := make(chan types.Transaction)
+	if err = w.Consume(msgChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	close(msgChan)
+	if err = w.WaitForClose(time.Second); err != nil {
+		t.Error(err)
+	}
+
+	// The place acquired whilst waiting for a transaction must be freed.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	if !limiter.Acquire(ctx) {
+		t.Error("Expected limiter to have a free place")
+	}
+	if exp, act := 1, limiter.Limit(); exp != act {
+		t.Errorf("Wrong limit: %v != %v", act, exp)
+	}
+}
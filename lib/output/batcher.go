@@ -182,6 +182,11 @@ func (m *Batcher) Connected() bool {
 	return m.child.Connected()
 }
 
+// ConnectionStatuses returns the connection status of the underlying output.
+func (m *Batcher) ConnectionStatuses() []types.ConnectionStatus {
+	return types.ConnectionStatusesOf("", m.child)
+}
+
 // Consume assigns a messages channel for the output to read.
 func (m *Batcher) Consume(msgs <-chan types.Transaction) error {
 	if m.messagesIn != nil {
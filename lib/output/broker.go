@@ -3,6 +3,7 @@ package output
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/broker"
@@ -86,17 +87,35 @@ potentially disproportionate message allocations to those outputs. Each message
 is sent to a single output, which is determined by allowing outputs to claim
 messages as soon as they are able to process them. This results in certain
 faster outputs potentially processing more messages at the cost of slower
-outputs.`,
+outputs.
+
+### ` + "`failover`" + `
+
+The failover pattern sends each message to a single active output, which is
+initially the first output of the list (the primary). If the active output fails
+to send a message then the subsequent outputs are attempted in order, and the
+first to succeed becomes the new active output.
+
+Whilst the primary output isn't active it is periodically probed, according to
+the field ` + "`failover.probe_interval`" + `, by attempting to send a message to
+it first. Once this succeeds the primary becomes the active output again.
+
+The index of the active output is exposed with the gauge metric
+` + "`failover.active`" + `, and switches between outputs are counted with the
+metric ` + "`failover.switches`" + `.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldAdvanced("copies", "The number of copies of each configured output to spawn."),
 			docs.FieldCommon("pattern", "The brokering pattern to use.").HasOptions(
-				"fan_out", "fan_out_sequential", "round_robin", "greedy", "try",
+				"fan_out", "fan_out_sequential", "round_robin", "greedy", "try", "failover",
 			),
 			docs.FieldCommon(
 				"max_in_flight",
-				"The maximum number of messages to dispatch at any given time. Only relevant for `fan_out`, `fan_out_sequential` brokers.",
+				"The maximum number of messages to dispatch at any given time. Only relevant for `fan_out`, `fan_out_sequential` and `failover` brokers.",
 			),
 			docs.FieldCommon("outputs", "A list of child outputs to broker."),
+			docs.FieldAdvanced("failover", "Configuration specific to the `failover` pattern.").WithChildren(
+				docs.FieldAdvanced("probe_interval", "The period to wait between attempts to send messages to the primary output whilst it is not active."),
+			).AtVersion("3.39.0"),
 			batch.FieldSpec(),
 		},
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
@@ -118,6 +137,7 @@ outputs.`,
 				"pattern":       conf.Broker.Pattern,
 				"max_in_flight": conf.Broker.MaxInFlight,
 				"outputs":       outSlice,
+				"failover":      conf.Broker.Failover,
 				"batching":      batchSanit,
 			}, nil
 		},
@@ -129,13 +149,20 @@ outputs.`,
 
 //------------------------------------------------------------------------------
 
+// BrokerFailoverConfig contains configuration fields specific to the failover
+// broker pattern.
+type BrokerFailoverConfig struct {
+	ProbeInterval string `json:"probe_interval" yaml:"probe_interval"`
+}
+
 // BrokerConfig contains configuration fields for the Broker output type.
 type BrokerConfig struct {
-	Copies      int                `json:"copies" yaml:"copies"`
-	Pattern     string             `json:"pattern" yaml:"pattern"`
-	MaxInFlight int                `json:"max_in_flight" yaml:"max_in_flight"`
-	Outputs     brokerOutputList   `json:"outputs" yaml:"outputs"`
-	Batching    batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Copies      int                  `json:"copies" yaml:"copies"`
+	Pattern     string               `json:"pattern" yaml:"pattern"`
+	MaxInFlight int                  `json:"max_in_flight" yaml:"max_in_flight"`
+	Outputs     brokerOutputList     `json:"outputs" yaml:"outputs"`
+	Failover    BrokerFailoverConfig `json:"failover" yaml:"failover"`
+	Batching    batch.PolicyConfig   `json:"batching" yaml:"batching"`
 }
 
 // NewBrokerConfig creates a new BrokerConfig with default values.
@@ -145,7 +172,10 @@ func NewBrokerConfig() BrokerConfig {
 		Pattern:     "fan_out",
 		MaxInFlight: 1,
 		Outputs:     brokerOutputList{},
-		Batching:    batch.NewPolicyConfig(),
+		Failover: BrokerFailoverConfig{
+			ProbeInterval: "30s",
+		},
+		Batching: batch.NewPolicyConfig(),
 	}
 }
 
@@ -224,6 +254,15 @@ func NewBroker(
 		b, err = broker.NewGreedy(outputs)
 	case "try":
 		b, err = broker.NewTry(outputs, stats)
+	case "failover":
+		var probeInterval time.Duration
+		if probeInterval, err = time.ParseDuration(conf.Broker.Failover.ProbeInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse failover probe interval: %v", err)
+		}
+		var bTmp *broker.Failover
+		if bTmp, err = broker.NewFailover(outputs, log, stats); err == nil {
+			b = bTmp.WithMaxInFlight(conf.Broker.MaxInFlight).WithProbeInterval(probeInterval)
+		}
 	default:
 		return nil, fmt.Errorf("broker pattern was not recognised: %v", conf.Broker.Pattern)
 	}
@@ -0,0 +1,189 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// chatWebhookFieldSpecs returns the field specs common to chat notification
+// outputs.
+func chatWebhookFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldAdvanced("timeout", "The maximum period of time to wait for each request to complete."),
+		docs.FieldAdvanced("max_retries", "The maximum number of times to retry a request that was rejected due to rate limiting, set to `0` in order to retry indefinitely."),
+		docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput at the risk of being rate limited."),
+	}
+}
+
+const chatWebhookRateLimitDocs = `
+### Rate Limits
+
+When the service responds with a status code ` + "`429`" + ` the request is
+retried after the period given by the ` + "`Retry-After`" + ` response header,
+up to ` + "`max_retries`" + ` times. Other failed requests are not retried by
+this output and are instead handled by the pipeline like any other output
+error.`
+
+//------------------------------------------------------------------------------
+
+// chatWebhookClient posts JSON payloads to chat services and handles the rate
+// limiting responses that they have in common.
+type chatWebhookClient struct {
+	log        log.Modular
+	client     *http.Client
+	maxRetries uint64
+
+	// checkResponse is an optional function for services that report errors
+	// within successful responses.
+	checkResponse func(body []byte) error
+
+	mRateLimited metrics.StatCounter
+}
+
+func newChatWebhookClient(timeoutStr string, maxRetries uint64, log log.Modular, stats metrics.Type) (*chatWebhookClient, error) {
+	var timeout time.Duration
+	if timeoutStr != "" {
+		var err error
+		if timeout, err = time.ParseDuration(timeoutStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	return &chatWebhookClient{
+		log:          log,
+		client:       &http.Client{Timeout: timeout},
+		maxRetries:   maxRetries,
+		mRateLimited: stats.GetCounter("rate_limited"),
+	}, nil
+}
+
+// retryAfter parses the period to wait before retrying a rate limited
+// request, which services express in either whole or fractional seconds.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return time.Second
+	}
+	secs, err := strconv.ParseFloat(header, 64)
+	if err != nil || secs < 0 {
+		return time.Second
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// post marshals a payload as JSON and sends it to a URL, retrying whilst the
+// request is rate limited.
+func (c *chatWebhookClient) post(ctx context.Context, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := uint64(0); ; attempt++ {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		res, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resBody, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			c.mRateLimited.Incr(1)
+			if c.maxRetries > 0 && attempt >= c.maxRetries {
+				return fmt.Errorf("request was rate limited after %v retries", attempt)
+			}
+			wait := retryAfter(res.Header.Get("Retry-After"))
+			c.log.Debugf("Request was rate limited, retrying in %v\n", wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("request returned unexpected status code %v: %s", res.StatusCode, resBody)
+		}
+		if c.checkResponse != nil {
+			return c.checkResponse(resBody)
+		}
+		return nil
+	}
+}
+
+// parseChatJSONField parses the resolved value of an optional interpolated
+// field that contains a raw JSON structure, returning nil when it is empty.
+func parseChatJSONField(name, value string) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return nil, fmt.Errorf("failed to parse %v as JSON: %v", name, err)
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
+// chatWriter is a writer that sends each message of a batch as an individual
+// chat notification.
+type chatWriter struct {
+	service string
+	url     string
+	headers map[string]string
+	client  *chatWebhookClient
+	log     log.Modular
+
+	payload func(index int, msg types.Message) (interface{}, error)
+}
+
+func (c *chatWriter) ConnectWithContext(ctx context.Context) error {
+	c.log.Infof("Sending messages as %v notifications\n", c.service)
+	return nil
+}
+
+func (c *chatWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return writer.IterateBatchedSend(msg, func(i int, _ types.Part) error {
+		payload, err := c.payload(i, msg)
+		if err != nil {
+			return err
+		}
+		return c.client.post(ctx, c.url, c.headers, payload)
+	})
+}
+
+func (c *chatWriter) CloseAsync() {
+}
+
+func (c *chatWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
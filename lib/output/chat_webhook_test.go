@@ -0,0 +1,203 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testChatServer struct {
+	mut      sync.Mutex
+	payloads []map[string]interface{}
+	headers  []http.Header
+	handler  func(w http.ResponseWriter, attempt int) bool
+}
+
+func startTestChatServer(t *testing.T) (*testChatServer, string) {
+	t.Helper()
+
+	s := &testChatServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+
+		if s.handler != nil && !s.handler(w, len(s.headers)) {
+			s.headers = append(s.headers, r.Header)
+			return
+		}
+
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(b, &payload))
+
+		s.headers = append(s.headers, r.Header)
+		s.payloads = append(s.payloads, payload)
+	}))
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func (s *testChatServer) received() []map[string]interface{} {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]map[string]interface{}(nil), s.payloads...)
+}
+
+func TestSlackConfigErrs(t *testing.T) {
+	conf := NewSlackConfig()
+	_, err := newSlackWriter(conf, "", log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.WebhookURL = "http://localhost"
+	conf.Token = "foo"
+	_, err = newSlackWriter(conf, "", log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.WebhookURL = ""
+	_, err = newSlackWriter(conf, "", log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestSlackWebhook(t *testing.T) {
+	server, url := startTestChatServer(t)
+
+	conf := NewSlackConfig()
+	conf.WebhookURL = url
+	conf.Text = `${! json("text") }`
+	conf.Blocks = `${! json("blocks") }`
+
+	w, err := newSlackWriter(conf, "", log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"text":"foo","blocks":[{"type":"divider"}]}`),
+		[]byte(`{"text":"bar"}`),
+	})))
+
+	assert.Equal(t, []map[string]interface{}{
+		{"text": "foo", "blocks": []interface{}{map[string]interface{}{"type": "divider"}}},
+		{"text": "bar"},
+	}, server.received())
+}
+
+func TestSlackPostMessage(t *testing.T) {
+	server, url := startTestChatServer(t)
+	server.handler = func(w http.ResponseWriter, attempt int) bool {
+		if attempt == 1 {
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return false
+		}
+		w.Write([]byte(`{"ok":true}`))
+		return true
+	}
+
+	conf := NewSlackConfig()
+	conf.Token = "xoxb-foo"
+	conf.Channel = `${! meta("channel") }`
+	conf.ThreadTS = `${! meta("ts") }`
+
+	w, err := newSlackWriter(conf, url, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{[]byte("hello world")})
+	msg.Get(0).Metadata().Set("channel", "#alerts")
+	msg.Get(0).Metadata().Set("ts", "1234.5678")
+	require.NoError(t, w.WriteWithContext(context.Background(), msg))
+
+	err = w.WriteWithContext(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel_not_found")
+
+	assert.Equal(t, []map[string]interface{}{
+		{"text": "hello world", "channel": "#alerts", "thread_ts": "1234.5678"},
+	}, server.received())
+	assert.Equal(t, "Bearer xoxb-foo", server.headers[0].Get("Authorization"))
+}
+
+func TestDiscordRateLimited(t *testing.T) {
+	server, url := startTestChatServer(t)
+	server.handler = func(w http.ResponseWriter, attempt int) bool {
+		if attempt < 2 {
+			w.Header().Set("Retry-After", "0.01")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return false
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+
+	conf := NewDiscordConfig()
+	conf.WebhookURL = url
+	conf.Username = "benthos"
+
+	w, err := newDiscordWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("hello world"),
+	})))
+	assert.Equal(t, []map[string]interface{}{
+		{"content": "hello world", "username": "benthos"},
+	}, server.received())
+
+	conf.MaxRetries = 1
+	server.mut.Lock()
+	server.headers = nil
+	server.mut.Unlock()
+
+	w, err = newDiscordWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.Error(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("hello world"),
+	})))
+}
+
+func TestMSTeams(t *testing.T) {
+	server, url := startTestChatServer(t)
+
+	conf := NewMSTeamsConfig()
+	conf.WebhookURL = url
+	conf.Title = `${! meta("title") }`
+	conf.Card = `${! meta("card") }`
+	conf.ThemeColor = "FF0000"
+
+	w, err := newMSTeamsWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(0).Metadata().Set("title", "Alert")
+	msg.Get(1).Metadata().Set("card", `{"type":"AdaptiveCard"}`)
+	require.NoError(t, w.WriteWithContext(context.Background(), msg))
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"title":      "Alert",
+			"summary":    "Alert",
+			"text":       "foo",
+			"themeColor": "FF0000",
+		},
+		{
+			"type": "message",
+			"attachments": []interface{}{
+				map[string]interface{}{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content":     map[string]interface{}{"type": "AdaptiveCard"},
+				},
+			},
+		},
+	}, server.received())
+}
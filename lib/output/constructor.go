@@ -5,6 +5,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/pipeline"
@@ -75,7 +76,9 @@ func constructProcessors(
 				}
 				*i++
 			}
-			return pipeline.NewProcessor(log, stats, processors...), nil
+			proc := pipeline.NewProcessor(log, stats, processors...)
+			proc.SetJourneyTap(journey.FromManager(mgr))
+			return proc, nil
 		}}...)
 	}
 	return pipelines
@@ -131,37 +134,52 @@ const (
 	TypeBroker             = "broker"
 	TypeCache              = "cache"
 	TypeCassandra          = "cassandra"
+	TypeDiscord            = "discord"
+	TypeDLQ                = "dlq"
 	TypeDrop               = "drop"
 	TypeDropOn             = "drop_on"
 	TypeDropOnError        = "drop_on_error"
 	TypeDynamic            = "dynamic"
+	TypeDynamicRoute       = "dynamic_route"
 	TypeDynamoDB           = "dynamodb"
 	TypeElasticsearch      = "elasticsearch"
 	TypeFile               = "file"
 	TypeFiles              = "files"
 	TypeGCPPubSub          = "gcp_pubsub"
+	TypeGRPCClient         = "grpc_client"
 	TypeHDFS               = "hdfs"
 	TypeHTTPClient         = "http_client"
 	TypeHTTPServer         = "http_server"
+	TypeInfluxDB           = "influxdb"
 	TypeInproc             = "inproc"
 	TypeKafka              = "kafka"
 	TypeKinesis            = "kinesis"
 	TypeKinesisFirehose    = "kinesis_firehose"
+	TypeLoki               = "loki"
+	TypeMongoDB            = "mongodb"
 	TypeMQTT               = "mqtt"
+	TypeMSTeams            = "ms_teams"
 	TypeNanomsg            = "nanomsg"
 	TypeNATS               = "nats"
 	TypeNATSStream         = "nats_stream"
 	TypeNSQ                = "nsq"
+	TypePromRemoteWrite    = "prometheus_remote_write"
 	TypeRedisHash          = "redis_hash"
+	TypeRedisJSON          = "redis_json"
 	TypeRedisList          = "redis_list"
 	TypeRedisPubSub        = "redis_pubsub"
 	TypeRedisStreams       = "redis_streams"
+	TypeRedisTimeSeries    = "redis_timeseries"
 	TypeReject             = "reject"
+	TypeReplicate          = "replicate"
 	TypeResource           = "resource"
 	TypeRetry              = "retry"
 	TypeS3                 = "s3"
 	TypeSFTP               = "sftp"
+	TypeSlack              = "slack"
+	TypeSMTP               = "smtp"
 	TypeSNS                = "sns"
+	TypeSplunkHEC          = "splunk_hec"
 	TypeSQL                = "sql"
 	TypeSQS                = "sqs"
 	TypeSTDOUT             = "stdout"
@@ -198,38 +216,53 @@ type Config struct {
 	Broker             BrokerConfig                   `json:"broker" yaml:"broker"`
 	Cache              writer.CacheConfig             `json:"cache" yaml:"cache"`
 	Cassandra          CassandraConfig                `json:"cassandra" yaml:"cassandra"`
+	Discord            DiscordConfig                  `json:"discord" yaml:"discord"`
+	DLQ                DLQConfig                      `json:"dlq" yaml:"dlq"`
 	Drop               writer.DropConfig              `json:"drop" yaml:"drop"`
 	DropOn             DropOnConfig                   `json:"drop_on" yaml:"drop_on"`
 	DropOnError        DropOnErrorConfig              `json:"drop_on_error" yaml:"drop_on_error"`
 	Dynamic            DynamicConfig                  `json:"dynamic" yaml:"dynamic"`
+	DynamicRoute       DynamicRouteConfig             `json:"dynamic_route" yaml:"dynamic_route"`
 	DynamoDB           writer.DynamoDBConfig          `json:"dynamodb" yaml:"dynamodb"`
 	Elasticsearch      writer.ElasticsearchConfig     `json:"elasticsearch" yaml:"elasticsearch"`
 	File               FileConfig                     `json:"file" yaml:"file"`
 	Files              writer.FilesConfig             `json:"files" yaml:"files"`
 	GCPPubSub          writer.GCPPubSubConfig         `json:"gcp_pubsub" yaml:"gcp_pubsub"`
+	GRPCClient         GRPCClientConfig               `json:"grpc_client" yaml:"grpc_client"`
 	HDFS               writer.HDFSConfig              `json:"hdfs" yaml:"hdfs"`
 	HTTPClient         writer.HTTPClientConfig        `json:"http_client" yaml:"http_client"`
 	HTTPServer         HTTPServerConfig               `json:"http_server" yaml:"http_server"`
+	InfluxDB           InfluxDBConfig                 `json:"influxdb" yaml:"influxdb"`
 	Inproc             InprocConfig                   `json:"inproc" yaml:"inproc"`
 	Kafka              writer.KafkaConfig             `json:"kafka" yaml:"kafka"`
 	Kinesis            writer.KinesisConfig           `json:"kinesis" yaml:"kinesis"`
 	KinesisFirehose    writer.KinesisFirehoseConfig   `json:"kinesis_firehose" yaml:"kinesis_firehose"`
+	Loki               LokiConfig                     `json:"loki" yaml:"loki"`
+	MongoDB            MongoDBConfig                  `json:"mongodb" yaml:"mongodb"`
 	MQTT               writer.MQTTConfig              `json:"mqtt" yaml:"mqtt"`
+	MSTeams            MSTeamsConfig                  `json:"ms_teams" yaml:"ms_teams"`
 	Nanomsg            writer.NanomsgConfig           `json:"nanomsg" yaml:"nanomsg"`
 	NATS               writer.NATSConfig              `json:"nats" yaml:"nats"`
 	NATSStream         writer.NATSStreamConfig        `json:"nats_stream" yaml:"nats_stream"`
 	NSQ                writer.NSQConfig               `json:"nsq" yaml:"nsq"`
 	Plugin             interface{}                    `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	PromRemoteWrite    PromRemoteWriteConfig          `json:"prometheus_remote_write" yaml:"prometheus_remote_write"`
 	RedisHash          writer.RedisHashConfig         `json:"redis_hash" yaml:"redis_hash"`
+	RedisJSON          writer.RedisJSONConfig         `json:"redis_json" yaml:"redis_json"`
 	RedisList          writer.RedisListConfig         `json:"redis_list" yaml:"redis_list"`
 	RedisPubSub        writer.RedisPubSubConfig       `json:"redis_pubsub" yaml:"redis_pubsub"`
 	RedisStreams       writer.RedisStreamsConfig      `json:"redis_streams" yaml:"redis_streams"`
+	RedisTimeSeries    writer.RedisTimeSeriesConfig   `json:"redis_timeseries" yaml:"redis_timeseries"`
 	Reject             RejectConfig                   `json:"reject" yaml:"reject"`
+	Replicate          ReplicateConfig                `json:"replicate" yaml:"replicate"`
 	Resource           string                         `json:"resource" yaml:"resource"`
 	Retry              RetryConfig                    `json:"retry" yaml:"retry"`
 	S3                 writer.AmazonS3Config          `json:"s3" yaml:"s3"`
 	SFTP               SFTPConfig                     `json:"sftp" yaml:"sftp"`
+	Slack              SlackConfig                    `json:"slack" yaml:"slack"`
+	SMTP               SMTPConfig                     `json:"smtp" yaml:"smtp"`
 	SNS                writer.SNSConfig               `json:"sns" yaml:"sns"`
+	SplunkHEC          SplunkHECConfig                `json:"splunk_hec" yaml:"splunk_hec"`
 	SQL                SQLConfig                      `json:"sql" yaml:"sql"`
 	SQS                writer.AmazonSQSConfig         `json:"sqs" yaml:"sqs"`
 	STDOUT             STDOUTConfig                   `json:"stdout" yaml:"stdout"`
@@ -266,38 +299,53 @@ func NewConfig() Config {
 		Broker:             NewBrokerConfig(),
 		Cache:              writer.NewCacheConfig(),
 		Cassandra:          NewCassandraConfig(),
+		Discord:            NewDiscordConfig(),
+		DLQ:                NewDLQConfig(),
 		Drop:               writer.NewDropConfig(),
 		DropOn:             NewDropOnConfig(),
 		DropOnError:        NewDropOnErrorConfig(),
 		Dynamic:            NewDynamicConfig(),
+		DynamicRoute:       NewDynamicRouteConfig(),
 		DynamoDB:           writer.NewDynamoDBConfig(),
 		Elasticsearch:      writer.NewElasticsearchConfig(),
 		File:               NewFileConfig(),
 		Files:              writer.NewFilesConfig(),
 		GCPPubSub:          writer.NewGCPPubSubConfig(),
+		GRPCClient:         NewGRPCClientConfig(),
 		HDFS:               writer.NewHDFSConfig(),
 		HTTPClient:         writer.NewHTTPClientConfig(),
 		HTTPServer:         NewHTTPServerConfig(),
+		InfluxDB:           NewInfluxDBConfig(),
 		Inproc:             NewInprocConfig(),
 		Kafka:              writer.NewKafkaConfig(),
 		Kinesis:            writer.NewKinesisConfig(),
 		KinesisFirehose:    writer.NewKinesisFirehoseConfig(),
+		Loki:               NewLokiConfig(),
+		MongoDB:            NewMongoDBConfig(),
 		MQTT:               writer.NewMQTTConfig(),
+		MSTeams:            NewMSTeamsConfig(),
 		Nanomsg:            writer.NewNanomsgConfig(),
 		NATS:               writer.NewNATSConfig(),
 		NATSStream:         writer.NewNATSStreamConfig(),
 		NSQ:                writer.NewNSQConfig(),
 		Plugin:             nil,
+		PromRemoteWrite:    NewPromRemoteWriteConfig(),
 		RedisHash:          writer.NewRedisHashConfig(),
+		RedisJSON:          writer.NewRedisJSONConfig(),
 		RedisList:          writer.NewRedisListConfig(),
 		RedisPubSub:        writer.NewRedisPubSubConfig(),
 		RedisStreams:       writer.NewRedisStreamsConfig(),
+		RedisTimeSeries:    writer.NewRedisTimeSeriesConfig(),
 		Reject:             NewRejectConfig(),
+		Replicate:          NewReplicateConfig(),
 		Resource:           "",
 		Retry:              NewRetryConfig(),
 		S3:                 writer.NewAmazonS3Config(),
 		SFTP:               NewSFTPConfig(),
+		Slack:              NewSlackConfig(),
+		SMTP:               NewSMTPConfig(),
 		SNS:                writer.NewSNSConfig(),
+		SplunkHEC:          NewSplunkHECConfig(),
 		SQL:                NewSQLConfig(),
 		SQS:                writer.NewAmazonSQSConfig(),
 		STDOUT:             NewSTDOUTConfig(),
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDiscord] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			d, err := newDiscordWriter(conf.Discord, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncWriter(TypeDiscord, conf.Discord.MaxInFlight, d, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Async:   true,
+		Summary: `
+Posts messages to a Discord channel via a webhook.`,
+		Description: `
+Messages are posted to the channel of a
+[webhook](https://discord.com/developers/docs/resources/webhook#execute-webhook).
+The content of each post is limited by Discord to 2000 characters, and posts
+that exceed this limit are rejected.
+
+The field ` + "`embeds`" + ` can be used in order to post
+[rich embeds](https://discord.com/developers/docs/resources/channel#embed-object),
+in which case it must resolve to a JSON array of embed objects.
+` + chatWebhookRateLimitDocs,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Alerts",
+				Summary: `
+Here we post an alert for each message with a custom username:`,
+				Config: `
+output:
+  discord:
+    webhook_url: ${DISCORD_WEBHOOK_URL}
+    username: Benthos
+    content: 'Job ${! json("job.id") } failed: ${! json("error") }'
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("webhook_url", "The URL of the webhook to post messages to."),
+			docs.FieldCommon("content", "The content of each post.").SupportsInterpolation(false),
+			docs.FieldCommon("username", "An optional username that overrides the default username of the webhook.").SupportsInterpolation(false),
+			docs.FieldAdvanced("embeds", "An optional JSON array of embed objects of each post.").SupportsInterpolation(false),
+		}.Merge(chatWebhookFieldSpecs()),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DiscordConfig contains configuration fields for the Discord output type.
+type DiscordConfig struct {
+	WebhookURL  string `json:"webhook_url" yaml:"webhook_url"`
+	Content     string `json:"content" yaml:"content"`
+	Username    string `json:"username" yaml:"username"`
+	Embeds      string `json:"embeds" yaml:"embeds"`
+	Timeout     string `json:"timeout" yaml:"timeout"`
+	MaxRetries  uint64 `json:"max_retries" yaml:"max_retries"`
+	MaxInFlight int    `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewDiscordConfig creates a new DiscordConfig with default values.
+func NewDiscordConfig() DiscordConfig {
+	return DiscordConfig{
+		WebhookURL:  "",
+		Content:     "${! content() }",
+		Username:    "",
+		Embeds:      "",
+		Timeout:     "5s",
+		MaxRetries:  3,
+		MaxInFlight: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newDiscordWriter(conf DiscordConfig, log log.Modular, stats metrics.Type) (*chatWriter, error) {
+	if conf.WebhookURL == "" {
+		return nil, errors.New("a webhook_url must be specified")
+	}
+
+	client, err := newChatWebhookClient(conf.Timeout, conf.MaxRetries, log, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	var content, username, embeds field.Expression
+	for _, f := range []struct {
+		name string
+		expr string
+		dst  *field.Expression
+	}{
+		{"content", conf.Content, &content},
+		{"username", conf.Username, &username},
+		{"embeds", conf.Embeds, &embeds},
+	} {
+		if *f.dst, err = bloblang.NewField(f.expr); err != nil {
+			return nil, fmt.Errorf("failed to parse %v expression: %v", f.name, err)
+		}
+	}
+
+	return &chatWriter{
+		service: "Discord",
+		url:     conf.WebhookURL,
+		client:  client,
+		log:     log,
+		payload: func(index int, msg types.Message) (interface{}, error) {
+			payload := map[string]interface{}{}
+			c := content.String(index, msg)
+			if c != "" {
+				payload["content"] = c
+			}
+			if u := username.String(index, msg); u != "" {
+				payload["username"] = u
+			}
+			e, err := parseChatJSONField("embeds", embeds.String(index, msg))
+			if err != nil {
+				return nil, err
+			}
+			if e != nil {
+				payload["embeds"] = e
+			} else if c == "" {
+				return nil, errors.New("posts require either content or embeds")
+			}
+			return payload, nil
+		},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
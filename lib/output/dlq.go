@@ -0,0 +1,420 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDLQ] = TypeSpec{
+		constructor: fromSimpleConstructor(NewDLQ),
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Attempts to write messages to a child output, retrying failed sends, and once
+retries are exhausted routes the messages to a dead letter output with metadata
+describing the failure.`,
+		Description: `
+This output replaces the common pattern of combining ` + "[`retry`](/docs/components/outputs/retry)" + `
+and ` + "[`try`](/docs/components/outputs/try)" + ` outputs in order to
+deliver messages that fail to a dead letter queue.
+
+Messages are retried with the child ` + "`output`" + ` according to the fields
+` + "`max_retries`" + ` and ` + "`backoff`" + `. Once these are exhausted the
+messages are sent to the ` + "`dead_letter`" + ` output with the following
+metadata fields added:
+
+` + "``` text" + `
+- dlq_error: The last error returned by the output
+- dlq_attempts: The number of attempts made to send the message
+- dlq_first_attempt: The time of the first attempt in RFC3339 format
+- dlq_last_attempt: The time of the last attempt in RFC3339 format
+` + "```" + `
+
+If the dead letter output also fails then the error is propagated back to the
+input, and the message is reattempted from the beginning.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "HTTP with a Dead Letter Queue",
+				Summary: `
+Here we deliver messages to an HTTP endpoint, and messages that fail to be
+delivered after three retries are written to a file along with the error:`,
+				Config: `
+output:
+  dlq:
+    max_retries: 3
+    output:
+      http_client:
+        url: http://localhost:4195/post
+        retries: 0
+    dead_letter:
+      file:
+        path: ./dead_letters.jsonl
+        codec: lines
+      processors:
+        - bloblang: |
+            root.content = content().string()
+            root.error = meta("dlq_error")
+            root.attempts = meta("dlq_attempts").number()
+`,
+			},
+		},
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DLQ)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DLQ.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DLQ.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+
+			var deadLetterSanit interface{} = struct{}{}
+			if conf.DLQ.DeadLetter != nil {
+				if deadLetterSanit, err = SanitiseConfig(*conf.DLQ.DeadLetter); err != nil {
+					return nil, err
+				}
+			}
+			confMap["dead_letter"] = deadLetterSanit
+			return confMap, nil
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("output", "A child output to write messages to."),
+			docs.FieldCommon("dead_letter", "An output to write messages to once attempts to write them to the child output are exhausted."),
+		}.Merge(retries.FieldSpecs()),
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DLQConfig contains configuration values for the DLQ output type.
+type DLQConfig struct {
+	Output         *Config `json:"output" yaml:"output"`
+	DeadLetter     *Config `json:"dead_letter" yaml:"dead_letter"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+// NewDLQConfig creates a new DLQConfig with default values.
+func NewDLQConfig() DLQConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "500ms"
+	rConf.Backoff.MaxInterval = "3s"
+	rConf.Backoff.MaxElapsedTime = "0s"
+	return DLQConfig{
+		Output:     nil,
+		DeadLetter: nil,
+		Config:     rConf,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDLQConfig struct {
+	Output         interface{} `json:"output" yaml:"output"`
+	DeadLetter     interface{} `json:"dead_letter" yaml:"dead_letter"`
+	retries.Config `json:",inline" yaml:",inline"`
+}
+
+func (d DLQConfig) dummy() dummyDLQConfig {
+	dummy := dummyDLQConfig{
+		Output:     d.Output,
+		DeadLetter: d.DeadLetter,
+		Config:     d.Config,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	if d.DeadLetter == nil {
+		dummy.DeadLetter = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints empty objects instead of nil.
+func (d DLQConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints empty objects instead of nil.
+func (d DLQConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// DLQ is an output type that writes messages to a child output, and once
+// attempts to do so are exhausted writes them to a dead letter output.
+type DLQ struct {
+	running int32
+
+	wrapped     Type
+	deadLetter  Type
+	backoffCtor func() backoff.BackOff
+
+	stats metrics.Type
+	log   log.Modular
+
+	transactionsIn  <-chan types.Transaction
+	transactionsOut chan types.Transaction
+	deadLettersOut  chan types.Transaction
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewDLQ creates a new DLQ output type.
+func NewDLQ(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	if conf.DLQ.Output == nil {
+		return nil, errors.New("cannot create dlq output without a child output")
+	}
+	if conf.DLQ.DeadLetter == nil {
+		return nil, errors.New("cannot create dlq output without a dead letter output")
+	}
+
+	wrapped, err := New(*conf.DLQ.Output, mgr, log.NewModule(".output"), metrics.Namespaced(stats, "output"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output '%v': %v", conf.DLQ.Output.Type, err)
+	}
+
+	deadLetter, err := New(*conf.DLQ.DeadLetter, mgr, log.NewModule(".dead_letter"), metrics.Namespaced(stats, "dead_letter"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dead letter output '%v': %v", conf.DLQ.DeadLetter.Type, err)
+	}
+
+	var boffCtor func() backoff.BackOff
+	if boffCtor, err = conf.DLQ.GetCtor(); err != nil {
+		return nil, err
+	}
+
+	return &DLQ{
+		running: 1,
+
+		log:             log,
+		stats:           stats,
+		wrapped:         wrapped,
+		deadLetter:      deadLetter,
+		backoffCtor:     boffCtor,
+		transactionsOut: make(chan types.Transaction),
+		deadLettersOut:  make(chan types.Transaction),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// deadLetterMsg returns a copy of a message that failed to be sent with
+// metadata describing the failure.
+func deadLetterMsg(msg types.Message, err error, attempts int, firstAttempt, lastAttempt time.Time) types.Message {
+	partErrs := map[int]error{}
+	var bErr *batch.Error
+	if errors.As(err, &bErr) && bErr.IndexedErrors() > 0 {
+		bErr.WalkParts(func(i int, _ types.Part, pErr error) bool {
+			if pErr != nil {
+				partErrs[i] = pErr
+			}
+			return true
+		})
+	}
+
+	attemptsStr := strconv.Itoa(attempts)
+	firstStr := firstAttempt.Format(time.RFC3339)
+	lastStr := lastAttempt.Format(time.RFC3339)
+
+	dlMsg := msg.Copy()
+	dlMsg.Iter(func(i int, p types.Part) error {
+		pErr, exists := partErrs[i]
+		if !exists {
+			pErr = err
+		}
+		meta := p.Metadata()
+		meta.Set("dlq_error", pErr.Error())
+		meta.Set("dlq_attempts", attemptsStr)
+		meta.Set("dlq_first_attempt", firstStr)
+		meta.Set("dlq_last_attempt", lastStr)
+		return nil
+	})
+	return dlMsg
+}
+
+func (d *DLQ) loop() {
+	var (
+		mCount      = d.stats.GetCounter("dlq.count")
+		mSuccess    = d.stats.GetCounter("dlq.send.success")
+		mError      = d.stats.GetCounter("dlq.send.error")
+		mDeadLetter = d.stats.GetCounter("dlq.dead_letter.count")
+		mDLError    = d.stats.GetCounter("dlq.dead_letter.error")
+	)
+
+	wg := sync.WaitGroup{}
+
+	defer func() {
+		wg.Wait()
+		close(d.transactionsOut)
+		close(d.deadLettersOut)
+		d.wrapped.CloseAsync()
+		d.deadLetter.CloseAsync()
+		for err := d.wrapped.WaitForClose(time.Second); err != nil; err = d.wrapped.WaitForClose(time.Second) {
+		}
+		for err := d.deadLetter.WaitForClose(time.Second); err != nil; err = d.deadLetter.WaitForClose(time.Second) {
+		}
+		close(d.closedChan)
+	}()
+
+	for atomic.LoadInt32(&d.running) == 1 {
+		var tran types.Transaction
+		var open bool
+		select {
+		case tran, open = <-d.transactionsIn:
+			if !open {
+				return
+			}
+			mCount.Incr(1)
+		case <-d.closeChan:
+			return
+		}
+
+		wg.Add(1)
+		go func(ts types.Transaction) {
+			defer wg.Done()
+
+			var boff backoff.BackOff
+			var res types.Response
+
+			resChan := make(chan types.Response)
+			firstAttempt := time.Now()
+			lastAttempt := firstAttempt
+
+			for attempts := 1; ; attempts++ {
+				select {
+				case d.transactionsOut <- types.NewTransaction(ts.Payload, resChan):
+				case <-d.closeChan:
+					return
+				}
+				select {
+				case res = <-resChan:
+				case <-d.closeChan:
+					return
+				}
+				if res.Error() == nil {
+					mSuccess.Incr(1)
+					break
+				}
+
+				mError.Incr(1)
+				if boff == nil {
+					boff = d.backoffCtor()
+				}
+				nextBackoff := boff.NextBackOff()
+				if nextBackoff == backoff.Stop {
+					d.log.Warnf("Failed to send message after %v attempts, sending to dead letter output: %v\n", attempts, res.Error())
+
+					mDeadLetter.Incr(1)
+					dlMsg := deadLetterMsg(ts.Payload, res.Error(), attempts, firstAttempt, lastAttempt)
+					select {
+					case d.deadLettersOut <- types.NewTransaction(dlMsg, resChan):
+					case <-d.closeChan:
+						return
+					}
+					select {
+					case res = <-resChan:
+					case <-d.closeChan:
+						return
+					}
+					if res.Error() != nil {
+						mDLError.Incr(1)
+						d.log.Errorf("Failed to send message to dead letter output: %v\n", res.Error())
+					}
+					break
+				}
+
+				select {
+				case <-time.After(nextBackoff):
+				case <-d.closeChan:
+					return
+				}
+				lastAttempt = time.Now()
+			}
+
+			select {
+			case ts.ResponseChan <- res:
+			case <-d.closeChan:
+			}
+		}(tran)
+	}
+}
+
+// Consume assigns a messages channel for the output to read.
+func (d *DLQ) Consume(ts <-chan types.Transaction) error {
+	if d.transactionsIn != nil {
+		return types.ErrAlreadyStarted
+	}
+	if err := d.wrapped.Consume(d.transactionsOut); err != nil {
+		return err
+	}
+	if err := d.deadLetter.Consume(d.deadLettersOut); err != nil {
+		return err
+	}
+	d.transactionsIn = ts
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (d *DLQ) Connected() bool {
+	return d.wrapped.Connected()
+}
+
+// CloseAsync shuts down the DLQ output and stops processing requests.
+func (d *DLQ) CloseAsync() {
+	if atomic.CompareAndSwapInt32(&d.running, 1, 0) {
+		close(d.closeChan)
+	}
+}
+
+// WaitForClose blocks until the DLQ output has closed down.
+func (d *DLQ) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
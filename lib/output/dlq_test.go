@@ -0,0 +1,178 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDLQConfigErrs(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeDLQ
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	oConf := NewConfig()
+	conf.DLQ.Output = &oConf
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	dConf := NewConfig()
+	conf.DLQ.DeadLetter = &dConf
+	conf.DLQ.Backoff.InitialInterval = "not a time period"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func newTestDLQ(t *testing.T, maxRetries uint64) (*DLQ, *mockOutput, *mockOutput, chan types.Transaction) {
+	t.Helper()
+
+	conf := NewConfig()
+	oConf, dConf := NewConfig(), NewConfig()
+	conf.DLQ.Output = &oConf
+	conf.DLQ.DeadLetter = &dConf
+	conf.DLQ.MaxRetries = maxRetries
+	conf.DLQ.Backoff.InitialInterval = "1ms"
+	conf.DLQ.Backoff.MaxInterval = "1ms"
+
+	output, err := NewDLQ(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	d, ok := output.(*DLQ)
+	require.True(t, ok)
+
+	mOut, mDead := &mockOutput{}, &mockOutput{}
+	d.wrapped, d.deadLetter = mOut, mDead
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, d.Consume(tChan))
+
+	t.Cleanup(func() {
+		d.CloseAsync()
+		require.NoError(t, d.WaitForClose(time.Second))
+	})
+	return d, mOut, mDead, tChan
+}
+
+func receiveTran(t *testing.T, ts <-chan types.Transaction) types.Transaction {
+	t.Helper()
+	select {
+	case tran := <-ts:
+		return tran
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return types.Transaction{}
+}
+
+func sendRes(t *testing.T, resChan chan<- types.Response, res types.Response) {
+	t.Helper()
+	select {
+	case resChan <- res:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestDLQHappyPath(t *testing.T) {
+	_, mOut, _, tChan := newTestDLQ(t, 3)
+
+	resChan := make(chan types.Response)
+	testMsg := message.New([][]byte{[]byte("hello world")})
+	go func() {
+		tChan <- types.NewTransaction(testMsg, resChan)
+	}()
+
+	tran := receiveTran(t, mOut.ts)
+	assert.Equal(t, testMsg, tran.Payload)
+	sendRes(t, tran.ResponseChan, response.NewAck())
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestDLQDeadLetter(t *testing.T) {
+	_, mOut, mDead, tChan := newTestDLQ(t, 2)
+
+	resChan := make(chan types.Response)
+	testMsg := message.New([][]byte{[]byte("hello"), []byte("world")})
+	go func() {
+		tChan <- types.NewTransaction(testMsg, resChan)
+	}()
+
+	for i := 0; i < 2; i++ {
+		tran := receiveTran(t, mOut.ts)
+		sendRes(t, tran.ResponseChan, response.NewError(errors.New("first failure")))
+	}
+
+	tran := receiveTran(t, mOut.ts)
+	sendRes(t, tran.ResponseChan, response.NewError(
+		batch.NewError(tran.Payload, errors.New("last failure")).Failed(1, errors.New("world failure")),
+	))
+
+	tran = receiveTran(t, mDead.ts)
+	require.Equal(t, 2, tran.Payload.Len())
+
+	assert.Equal(t, "hello", string(tran.Payload.Get(0).Get()))
+	assert.Equal(t, "last failure", tran.Payload.Get(0).Metadata().Get("dlq_error"))
+	assert.Equal(t, "3", tran.Payload.Get(0).Metadata().Get("dlq_attempts"))
+	assert.Equal(t, "world failure", tran.Payload.Get(1).Metadata().Get("dlq_error"))
+
+	first, err := time.Parse(time.RFC3339, tran.Payload.Get(0).Metadata().Get("dlq_first_attempt"))
+	require.NoError(t, err)
+	last, err := time.Parse(time.RFC3339, tran.Payload.Get(0).Metadata().Get("dlq_last_attempt"))
+	require.NoError(t, err)
+	assert.False(t, last.Before(first))
+
+	// The original message must not be modified.
+	assert.Equal(t, "", testMsg.Get(0).Metadata().Get("dlq_error"))
+
+	sendRes(t, tran.ResponseChan, response.NewAck())
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestDLQDeadLetterFailure(t *testing.T) {
+	_, mOut, mDead, tChan := newTestDLQ(t, 1)
+
+	resChan := make(chan types.Response)
+	go func() {
+		tChan <- types.NewTransaction(message.New([][]byte{[]byte("hello")}), resChan)
+	}()
+
+	for i := 0; i < 2; i++ {
+		tran := receiveTran(t, mOut.ts)
+		sendRes(t, tran.ResponseChan, response.NewError(errors.New("nope")))
+	}
+
+	tran := receiveTran(t, mDead.ts)
+	assert.Equal(t, "2", tran.Payload.Get(0).Metadata().Get("dlq_attempts"))
+	sendRes(t, tran.ResponseChan, response.NewError(errors.New("dead letter nope")))
+
+	select {
+	case res := <-resChan:
+		require.Error(t, res.Error())
+		assert.Equal(t, "dead letter nope", res.Error().Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
@@ -0,0 +1,651 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeDynamicRoute] = TypeSpec{
+		constructor: fromSimpleConstructor(NewDynamicRoute),
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Routes each message to a destination resolved at runtime, such as a Kafka topic,
+an SQS queue URL or an S3 bucket, by creating an instance of a child output for
+each destination.`,
+		Description: `
+The destination of each message is resolved by executing the
+[Bloblang mapping](/docs/guides/bloblang/about) in the field ` + "`route`" + `,
+which must result in a string. Each occurrence of the ` + "`placeholder`" + `
+within the child ` + "`output`" + ` config is then replaced with that
+destination, and a new instance of the child output is created the first time a
+destination is seen.
+
+Child outputs are cached so that connections are reused between messages. When
+the number of cached outputs exceeds ` + "`max_outputs`" + ` the least recently
+used output is closed, and outputs that have not been used for the duration of
+` + "`idle_timeout`" + ` are also closed.
+
+### Validation
+
+Resolved destinations must match the regular expression ` + "`pattern`" + `,
+which prevents messages from injecting arbitrary config values. Messages where
+the mapping fails, or results in an empty or invalid destination, are sent to
+the ` + "`fallback`" + ` destination when set, otherwise the batch is rejected
+with an error and reprocessed.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Kafka Topic per Tenant",
+				Summary: `
+Here we route each message to a Kafka topic derived from its tenant, with
+messages that lack a valid tenant going to a shared topic:`,
+				Config: `
+output:
+  dynamic_route:
+    route: 'root = "events." + this.tenant'
+    fallback: events.unknown
+    output:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: '{{route}}'
+`,
+			},
+		},
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			confBytes, err := json.Marshal(conf.DynamicRoute)
+			if err != nil {
+				return nil, err
+			}
+
+			confMap := map[string]interface{}{}
+			if err = json.Unmarshal(confBytes, &confMap); err != nil {
+				return nil, err
+			}
+
+			var outputSanit interface{} = struct{}{}
+			if conf.DynamicRoute.Output != nil {
+				if outputSanit, err = SanitiseConfig(*conf.DynamicRoute.Output); err != nil {
+					return nil, err
+				}
+			}
+			confMap["output"] = outputSanit
+			return confMap, nil
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon(
+				"route", "A [Bloblang mapping](/docs/guides/bloblang/about) that results in the destination of each message.",
+				`root = this.topic`, `root = meta("kafka_topic") + ".processed"`,
+			),
+			docs.FieldCommon("pattern", "A regular expression that resolved destinations must match. Set this to an empty string in order to disable validation."),
+			docs.FieldCommon("fallback", "An optional destination to route messages to when a destination cannot be resolved or is invalid."),
+			docs.FieldAdvanced("placeholder", "A string within the child output config to replace with the destination of each instance."),
+			docs.FieldCommon("output", "A child output config to create for each destination."),
+			docs.FieldAdvanced("max_outputs", "The maximum number of child outputs to keep open at a given time."),
+			docs.FieldAdvanced("idle_timeout", "The period of time after which a child output that has not been used is closed. Set this to an empty string in order to keep outputs open until evicted by `max_outputs`."),
+			docs.FieldCommon("max_in_flight", "The maximum number of parallel message batches to have in flight at any given time."),
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// DynamicRouteConfig contains configuration fields for the DynamicRoute output
+// type.
+type DynamicRouteConfig struct {
+	Route       string  `json:"route" yaml:"route"`
+	Pattern     string  `json:"pattern" yaml:"pattern"`
+	Fallback    string  `json:"fallback" yaml:"fallback"`
+	Placeholder string  `json:"placeholder" yaml:"placeholder"`
+	Output      *Config `json:"output" yaml:"output"`
+	MaxOutputs  int     `json:"max_outputs" yaml:"max_outputs"`
+	IdleTimeout string  `json:"idle_timeout" yaml:"idle_timeout"`
+	MaxInFlight int     `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewDynamicRouteConfig creates a new DynamicRouteConfig with default values.
+func NewDynamicRouteConfig() DynamicRouteConfig {
+	return DynamicRouteConfig{
+		Route:       "",
+		Pattern:     `^[\w\-.:/]+$`,
+		Fallback:    "",
+		Placeholder: "{{route}}",
+		Output:      nil,
+		MaxOutputs:  32,
+		IdleTimeout: "10m",
+		MaxInFlight: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type dummyDynamicRouteConfig struct {
+	Route       string      `json:"route" yaml:"route"`
+	Pattern     string      `json:"pattern" yaml:"pattern"`
+	Fallback    string      `json:"fallback" yaml:"fallback"`
+	Placeholder string      `json:"placeholder" yaml:"placeholder"`
+	Output      interface{} `json:"output" yaml:"output"`
+	MaxOutputs  int         `json:"max_outputs" yaml:"max_outputs"`
+	IdleTimeout string      `json:"idle_timeout" yaml:"idle_timeout"`
+	MaxInFlight int         `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+func (d DynamicRouteConfig) dummy() dummyDynamicRouteConfig {
+	dummy := dummyDynamicRouteConfig{
+		Route:       d.Route,
+		Pattern:     d.Pattern,
+		Fallback:    d.Fallback,
+		Placeholder: d.Placeholder,
+		Output:      d.Output,
+		MaxOutputs:  d.MaxOutputs,
+		IdleTimeout: d.IdleTimeout,
+		MaxInFlight: d.MaxInFlight,
+	}
+	if d.Output == nil {
+		dummy.Output = struct{}{}
+	}
+	return dummy
+}
+
+// MarshalJSON prints an empty object instead of nil.
+func (d DynamicRouteConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.dummy())
+}
+
+// MarshalYAML prints an empty object instead of nil.
+func (d DynamicRouteConfig) MarshalYAML() (interface{}, error) {
+	return d.dummy(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// dynamicRouteOutputConfig returns a copy of an output config where all
+// occurrences of a placeholder within string values are replaced with a
+// destination.
+func dynamicRouteOutputConfig(template Config, placeholder, dest string) (Config, error) {
+	sanit, err := SanitiseConfig(template)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var node yaml.Node
+	if err = node.Encode(sanit); err != nil {
+		return Config{}, err
+	}
+
+	var replace func(n *yaml.Node)
+	replace = func(n *yaml.Node) {
+		switch n.Kind {
+		case yaml.ScalarNode:
+			n.Value = strings.ReplaceAll(n.Value, placeholder, dest)
+		case yaml.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				replace(n.Content[i])
+			}
+		default:
+			for _, c := range n.Content {
+				replace(c)
+			}
+		}
+	}
+	replace(&node)
+
+	conf := NewConfig()
+	if err = node.Decode(&conf); err != nil {
+		return Config{}, err
+	}
+	return conf, nil
+}
+
+//------------------------------------------------------------------------------
+
+type dynamicRouteOutput struct {
+	dest     string
+	output   types.Output
+	tsChan   chan types.Transaction
+	inFlight int
+	lastUsed time.Time
+}
+
+// DynamicRoute is an output type that routes messages to instances of a child
+// output created for each destination resolved from the messages.
+type DynamicRoute struct {
+	log   log.Modular
+	stats metrics.Type
+
+	route       *mapping.Executor
+	pattern     *regexp.Regexp
+	fallback    string
+	maxOutputs  int
+	idleTimeout time.Duration
+	maxInFlight int
+
+	newOutput func(dest string) (types.Output, error)
+
+	outputsMut sync.Mutex
+	outputs    map[string]*dynamicRouteOutput
+	closingWG  sync.WaitGroup
+
+	transactions <-chan types.Transaction
+
+	mCreated  metrics.StatCounter
+	mEvicted  metrics.StatCounter
+	mFallback metrics.StatCounter
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+// NewDynamicRoute creates a new DynamicRoute output type.
+func NewDynamicRoute(
+	conf Config,
+	mgr types.Manager,
+	log log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	rConf := conf.DynamicRoute
+	if rConf.Output == nil {
+		return nil, errors.New("cannot create dynamic_route output without a child")
+	}
+	if rConf.Placeholder == "" {
+		return nil, errors.New("a placeholder must be specified")
+	}
+	if rConf.MaxInFlight < 1 {
+		return nil, fmt.Errorf("invalid max_in_flight: %v", rConf.MaxInFlight)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	d := &DynamicRoute{
+		log:         log,
+		stats:       stats,
+		fallback:    rConf.Fallback,
+		maxOutputs:  rConf.MaxOutputs,
+		maxInFlight: rConf.MaxInFlight,
+		outputs:     map[string]*dynamicRouteOutput{},
+		mCreated:    stats.GetCounter("dynamic_route.outputs.created"),
+		mEvicted:    stats.GetCounter("dynamic_route.outputs.evicted"),
+		mFallback:   stats.GetCounter("dynamic_route.fallback"),
+		ctx:         ctx,
+		close:       done,
+		closedChan:  make(chan struct{}),
+	}
+
+	var err error
+	if d.route, err = bloblang.NewMapping("", rConf.Route); err != nil {
+		return nil, fmt.Errorf("failed to parse route mapping: %v", err)
+	}
+	if rConf.Pattern != "" {
+		if d.pattern, err = regexp.Compile(rConf.Pattern); err != nil {
+			return nil, fmt.Errorf("failed to compile pattern: %v", err)
+		}
+		if d.fallback != "" && !d.pattern.MatchString(d.fallback) {
+			return nil, fmt.Errorf("fallback destination '%v' does not match pattern", d.fallback)
+		}
+	}
+	if rConf.IdleTimeout != "" {
+		if d.idleTimeout, err = time.ParseDuration(rConf.IdleTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse idle_timeout: %v", err)
+		}
+	}
+
+	sanit, err := SanitiseConfig(*rConf.Output)
+	if err != nil {
+		return nil, err
+	}
+	sanitBytes, err := yaml.Marshal(sanit)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(sanitBytes), rConf.Placeholder) {
+		return nil, fmt.Errorf("placeholder '%v' was not found within the child output config", rConf.Placeholder)
+	}
+
+	childLog := log.NewModule(".dynamic_route.output")
+	childStats := metrics.Combine(stats, metrics.Namespaced(stats, "dynamic_route.output"))
+	d.newOutput = func(dest string) (types.Output, error) {
+		oConf, err := dynamicRouteOutputConfig(*rConf.Output, rConf.Placeholder, dest)
+		if err != nil {
+			return nil, err
+		}
+		return New(oConf, mgr, childLog, childStats)
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+// resolve returns the destination of a message.
+func (d *DynamicRoute) resolve(index int, msg types.Message) (string, error) {
+	var valuePtr *interface{}
+	var parseErr error
+	lazyValue := func() *interface{} {
+		if valuePtr == nil && parseErr == nil {
+			if jObj, err := msg.Get(index).JSON(); err == nil {
+				valuePtr = &jObj
+			} else {
+				parseErr = err
+			}
+		}
+		return valuePtr
+	}
+
+	res, err := d.route.Exec(query.FunctionContext{
+		Maps:     d.route.Maps(),
+		Vars:     map[string]interface{}{},
+		Index:    index,
+		MsgBatch: msg,
+	}.WithValueFunc(lazyValue))
+	if err != nil {
+		return "", err
+	}
+
+	var dest string
+	switch t := res.(type) {
+	case string:
+		dest = t
+	case []byte:
+		dest = string(t)
+	default:
+		return "", fmt.Errorf("expected route mapping to result in a string, got %T", res)
+	}
+	dest = strings.TrimSpace(dest)
+	if dest == "" {
+		return "", errors.New("route mapping resulted in an empty destination")
+	}
+	if d.pattern != nil && !d.pattern.MatchString(dest) {
+		return "", fmt.Errorf("destination '%v' does not match pattern", dest)
+	}
+	return dest, nil
+}
+
+// acquire obtains the output of a destination, creating it if necessary. The
+// output must be released once a transaction has completed.
+func (d *DynamicRoute) acquire(dest string) (*dynamicRouteOutput, error) {
+	d.outputsMut.Lock()
+	defer d.outputsMut.Unlock()
+
+	if o, exists := d.outputs[dest]; exists {
+		o.inFlight++
+		o.lastUsed = time.Now()
+		return o, nil
+	}
+
+	output, err := d.newOutput(dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output for destination '%v': %v", dest, err)
+	}
+	o := &dynamicRouteOutput{
+		dest:     dest,
+		output:   output,
+		tsChan:   make(chan types.Transaction),
+		inFlight: 1,
+		lastUsed: time.Now(),
+	}
+	if err = output.Consume(o.tsChan); err != nil {
+		output.CloseAsync()
+		return nil, err
+	}
+	d.outputs[dest] = o
+	d.mCreated.Incr(1)
+
+	// Evict the least recently used outputs that are not in use until we're
+	// within our limit.
+	for d.maxOutputs > 0 && len(d.outputs) > d.maxOutputs {
+		var lru *dynamicRouteOutput
+		for _, c := range d.outputs {
+			if c.inFlight > 0 {
+				continue
+			}
+			if lru == nil || c.lastUsed.Before(lru.lastUsed) {
+				lru = c
+			}
+		}
+		if lru == nil {
+			break
+		}
+		d.closeOutput(lru)
+		d.mEvicted.Incr(1)
+	}
+	return o, nil
+}
+
+func (d *DynamicRoute) release(o *dynamicRouteOutput) {
+	d.outputsMut.Lock()
+	o.inFlight--
+	o.lastUsed = time.Now()
+	d.outputsMut.Unlock()
+}
+
+// closeOutput removes an output from the cache and shuts it down, the outputs
+// mutex must be held by the caller.
+func (d *DynamicRoute) closeOutput(o *dynamicRouteOutput) {
+	delete(d.outputs, o.dest)
+	d.log.Debugf("Closing output for destination: %v\n", o.dest)
+
+	o.output.CloseAsync()
+	close(o.tsChan)
+
+	d.closingWG.Add(1)
+	go func() {
+		defer d.closingWG.Done()
+		for err := o.output.WaitForClose(time.Second); err != nil; err = o.output.WaitForClose(time.Second) {
+		}
+	}()
+}
+
+func (d *DynamicRoute) closeIdle() {
+	d.outputsMut.Lock()
+	defer d.outputsMut.Unlock()
+
+	for _, o := range d.outputs {
+		if o.inFlight == 0 && time.Since(o.lastUsed) >= d.idleTimeout {
+			d.closeOutput(o)
+			d.mEvicted.Incr(1)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the output to read.
+func (d *DynamicRoute) Consume(transactions <-chan types.Transaction) error {
+	if d.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	d.transactions = transactions
+	go d.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether all cached child outputs are
+// currently connected to their targets.
+func (d *DynamicRoute) Connected() bool {
+	d.outputsMut.Lock()
+	defer d.outputsMut.Unlock()
+	for _, o := range d.outputs {
+		if !o.output.Connected() {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *DynamicRoute) send(o *dynamicRouteOutput, msg types.Message) error {
+	resChan := make(chan types.Response)
+	select {
+	case o.tsChan <- types.NewTransaction(msg, resChan):
+	case <-d.ctx.Done():
+		return types.ErrTypeClosed
+	}
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-d.ctx.Done():
+		return types.ErrTypeClosed
+	}
+}
+
+func (d *DynamicRoute) loop() {
+	var (
+		wg         = sync.WaitGroup{}
+		mMsgRcvd   = d.stats.GetCounter("dynamic_route.messages.received")
+		mMsgSnt    = d.stats.GetCounter("dynamic_route.messages.sent")
+		mRouteErr  = d.stats.GetCounter("dynamic_route.route.error")
+		mOutputErr = d.stats.GetCounter("dynamic_route.output.error")
+	)
+
+	defer func() {
+		wg.Wait()
+		d.outputsMut.Lock()
+		for _, o := range d.outputs {
+			d.closeOutput(o)
+		}
+		d.outputsMut.Unlock()
+		d.closingWG.Wait()
+		close(d.closedChan)
+	}()
+
+	sendLoop := func() {
+		defer wg.Done()
+		for {
+			var ts types.Transaction
+			var open bool
+
+			select {
+			case ts, open = <-d.transactions:
+				if !open {
+					return
+				}
+			case <-d.ctx.Done():
+				return
+			}
+			mMsgRcvd.Incr(1)
+
+			var dests []string
+			routed := map[string][]types.Part{}
+			if routeErr := ts.Payload.Iter(func(i int, p types.Part) error {
+				dest, err := d.resolve(i, ts.Payload)
+				if err != nil {
+					if d.fallback == "" {
+						return fmt.Errorf("failed to resolve destination: %v", err)
+					}
+					d.log.Debugf("Routing message to fallback destination: %v\n", err)
+					d.mFallback.Incr(1)
+					dest = d.fallback
+				}
+				if _, exists := routed[dest]; !exists {
+					dests = append(dests, dest)
+				}
+				routed[dest] = append(routed[dest], p.Copy())
+				return nil
+			}); routeErr != nil {
+				d.log.Errorf("Failed to route message: %v\n", routeErr)
+				mRouteErr.Incr(1)
+				select {
+				case ts.ResponseChan <- response.NewError(routeErr):
+				case <-d.ctx.Done():
+					return
+				}
+				continue
+			}
+
+			var owg errgroup.Group
+			for _, dest := range dests {
+				msgCopy := message.New(nil)
+				msgCopy.SetAll(routed[dest])
+				dest := dest
+				owg.Go(func() error {
+					o, err := d.acquire(dest)
+					if err != nil {
+						return err
+					}
+					defer d.release(o)
+					if err = d.send(o, msgCopy); err != nil {
+						return err
+					}
+					mMsgSnt.Incr(1)
+					return nil
+				})
+			}
+
+			var oResponse types.Response = response.NewAck()
+			if resErr := owg.Wait(); resErr != nil {
+				d.log.Errorf("Failed to dispatch routed message: %v\n", resErr)
+				mOutputErr.Incr(1)
+				oResponse = response.NewError(resErr)
+			}
+			select {
+			case ts.ResponseChan <- oResponse:
+			case <-d.ctx.Done():
+				return
+			}
+		}
+	}
+
+	for i := 0; i < d.maxInFlight; i++ {
+		wg.Add(1)
+		go sendLoop()
+	}
+
+	sendersDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(sendersDone)
+	}()
+
+	var idleChan <-chan time.Time
+	if d.idleTimeout > 0 {
+		idleTicker := time.NewTicker(d.idleTimeout)
+		defer idleTicker.Stop()
+		idleChan = idleTicker.C
+	}
+	for {
+		select {
+		case <-idleChan:
+			d.closeIdle()
+		case <-sendersDone:
+			return
+		}
+	}
+}
+
+// CloseAsync shuts down the DynamicRoute output and stops processing messages.
+func (d *DynamicRoute) CloseAsync() {
+	d.close()
+}
+
+// WaitForClose blocks until the DynamicRoute output has closed down.
+func (d *DynamicRoute) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-d.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,234 @@
+package output
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRouteOutputs struct {
+	mut     sync.Mutex
+	created []string
+	closed  []string
+	routed  map[string][]string
+}
+
+type testRouteOutput struct {
+	dest string
+	rec  *testRouteOutputs
+	done chan struct{}
+}
+
+func (t *testRouteOutput) Consume(ts <-chan types.Transaction) error {
+	go func() {
+		defer close(t.done)
+		for tran := range ts {
+			t.rec.mut.Lock()
+			_ = tran.Payload.Iter(func(i int, p types.Part) error {
+				t.rec.routed[t.dest] = append(t.rec.routed[t.dest], string(p.Get()))
+				return nil
+			})
+			t.rec.mut.Unlock()
+			tran.ResponseChan <- response.NewAck()
+		}
+		t.rec.mut.Lock()
+		t.rec.closed = append(t.rec.closed, t.dest)
+		t.rec.mut.Unlock()
+	}()
+	return nil
+}
+
+func (t *testRouteOutput) Connected() bool {
+	return true
+}
+
+func (t *testRouteOutput) CloseAsync() {
+}
+
+func (t *testRouteOutput) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-t.done:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+func newTestDynamicRoute(t *testing.T, conf Config) (*DynamicRoute, *testRouteOutputs) {
+	t.Helper()
+
+	conf.Type = TypeDynamicRoute
+	genType, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	d, ok := genType.(*DynamicRoute)
+	require.True(t, ok)
+
+	rec := &testRouteOutputs{routed: map[string][]string{}}
+	d.newOutput = func(dest string) (types.Output, error) {
+		rec.mut.Lock()
+		rec.created = append(rec.created, dest)
+		rec.mut.Unlock()
+		return &testRouteOutput{dest: dest, rec: rec, done: make(chan struct{})}, nil
+	}
+	return d, rec
+}
+
+func sendDynamicRoute(t *testing.T, tChan chan types.Transaction, parts ...string) error {
+	t.Helper()
+
+	var content [][]byte
+	for _, p := range parts {
+		content = append(content, []byte(p))
+	}
+	resChan := make(chan types.Response)
+	select {
+	case tChan <- types.NewTransaction(message.New(content), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestDynamicRouteOutputConfig(t *testing.T) {
+	tmpl := NewConfig()
+	tmpl.Type = TypeKafka
+	tmpl.Kafka.Topic = "{{route}}"
+	tmpl.Kafka.ClientID = "benthos_{{route}}"
+
+	conf, err := dynamicRouteOutputConfig(tmpl, "{{route}}", "foo.bar")
+	require.NoError(t, err)
+
+	assert.Equal(t, TypeKafka, conf.Type)
+	assert.Equal(t, "foo.bar", conf.Kafka.Topic)
+	assert.Equal(t, "benthos_foo.bar", conf.Kafka.ClientID)
+	assert.Equal(t, tmpl.Kafka.Addresses, conf.Kafka.Addresses)
+}
+
+func TestDynamicRouteValidation(t *testing.T) {
+	child := NewConfig()
+	child.Type = TypeKafka
+
+	conf := NewConfig()
+	conf.Type = TypeDynamicRoute
+	conf.DynamicRoute.Route = "root = this.topic"
+	conf.DynamicRoute.Output = &child
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "placeholder")
+
+	child.Kafka.Topic = "{{route}}"
+	conf.DynamicRoute.Fallback = "not valid!"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match pattern")
+}
+
+func TestDynamicRouteRouting(t *testing.T) {
+	child := NewConfig()
+	child.Type = TypeKafka
+	child.Kafka.Topic = "{{route}}"
+
+	conf := NewConfig()
+	conf.DynamicRoute.Route = "root = this.topic"
+	conf.DynamicRoute.Fallback = "unknown"
+	conf.DynamicRoute.Output = &child
+
+	d, rec := newTestDynamicRoute(t, conf)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, d.Consume(tChan))
+
+	require.NoError(t, sendDynamicRoute(t, tChan,
+		`{"topic":"foo","id":1}`,
+		`{"topic":"bar","id":2}`,
+		`{"topic":"foo","id":3}`,
+	))
+	require.NoError(t, sendDynamicRoute(t, tChan,
+		`{"topic":"foo","id":4}`,
+		`{"topic":"bad topic","id":5}`,
+		`{"id":6}`,
+	))
+
+	d.CloseAsync()
+	require.NoError(t, d.WaitForClose(time.Second*5))
+
+	rec.mut.Lock()
+	defer rec.mut.Unlock()
+
+	assert.Equal(t, []string{"foo", "bar", "unknown"}, rec.created)
+	assert.Equal(t, map[string][]string{
+		"foo":     {`{"topic":"foo","id":1}`, `{"topic":"foo","id":3}`, `{"topic":"foo","id":4}`},
+		"bar":     {`{"topic":"bar","id":2}`},
+		"unknown": {`{"topic":"bad topic","id":5}`, `{"id":6}`},
+	}, rec.routed)
+	assert.ElementsMatch(t, []string{"foo", "bar", "unknown"}, rec.closed)
+}
+
+func TestDynamicRouteNoFallback(t *testing.T) {
+	child := NewConfig()
+	child.Type = TypeKafka
+	child.Kafka.Topic = "{{route}}"
+
+	conf := NewConfig()
+	conf.DynamicRoute.Route = "root = this.topic"
+	conf.DynamicRoute.Output = &child
+
+	d, rec := newTestDynamicRoute(t, conf)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, d.Consume(tChan))
+
+	err := sendDynamicRoute(t, tChan, `{"topic":"foo"}`, `{"topic":"bad topic"}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to resolve destination")
+
+	d.CloseAsync()
+	require.NoError(t, d.WaitForClose(time.Second*5))
+
+	rec.mut.Lock()
+	defer rec.mut.Unlock()
+	assert.Empty(t, rec.created)
+}
+
+func TestDynamicRouteEviction(t *testing.T) {
+	child := NewConfig()
+	child.Type = TypeKafka
+	child.Kafka.Topic = "{{route}}"
+
+	conf := NewConfig()
+	conf.DynamicRoute.Route = "root = content()"
+	conf.DynamicRoute.MaxOutputs = 2
+	conf.DynamicRoute.Output = &child
+
+	d, rec := newTestDynamicRoute(t, conf)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, d.Consume(tChan))
+
+	for _, dest := range []string{"a", "b", "a", "c", "a", "b"} {
+		require.NoError(t, sendDynamicRoute(t, tChan, dest))
+	}
+
+	d.CloseAsync()
+	require.NoError(t, d.WaitForClose(time.Second*5))
+
+	rec.mut.Lock()
+	defer rec.mut.Unlock()
+	assert.Equal(t, []string{"a", "b", "c", "b"}, rec.created)
+}
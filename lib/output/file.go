@@ -1,8 +1,11 @@
 package output
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -12,9 +15,11 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/bloblang"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/klauspost/compress/zstd"
 )
 
 //------------------------------------------------------------------------------
@@ -25,7 +30,26 @@ func init() {
 		Summary: `
 Writes messages to files on disk based on a chosen codec.`,
 		Description: `
-Messages can be written to different files by using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the path field. However, only one file is ever open at a given time, and therefore when the path changes the previously open file is closed.`,
+Messages can be written to different files by using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the path field. However, only one file is ever open at a given time, and therefore when the path changes the previously open file is closed.
+
+### Rotation
+
+The currently open file can be rotated once it reaches a maximum size and/or after it has been open for a given interval by configuring the ` + "`rotation`" + ` field. Rotation is evaluated before each message is written, and therefore a file is never rotated whilst the output is idle. When a file is rotated it is closed, renamed and optionally compressed, and subsequent messages are written to a fresh file at the original path.
+
+By default the rotated file is renamed to the original path suffixed with a UTC timestamp, this can be customised with a [Bloblang mapping](/docs/guides/bloblang/about) in the field ` + "`rotation.path_mapping`" + `, which is executed on a document of the form:
+
+` + "```json" + `
+{
+  "path": "/tmp/data.txt",
+  "size": 1048576,
+  "opened_at": "2020-11-10T15:04:05.000000000Z",
+  "rotated_at": "2020-11-10T16:04:05.000000000Z"
+}
+` + "```" + `
+
+The result of the mapping must be a string, which is the path to rename the file to before compression. When compression is enabled the extension ` + "`.gz`" + ` or ` + "`.zst`" + ` is appended to the final file name.
+
+Rotation is only supported with codecs that keep a file open between messages (` + "`lines`, `append` and `delim:x`" + `).`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon(
 				"path", "The file to write to, if the file does not yet exist it will be created.",
@@ -35,6 +59,15 @@ Messages can be written to different files by using [interpolation functions](/d
 			).SupportsInterpolation(true).AtVersion("3.33.0"),
 			codec.WriterDocs.AtVersion("3.33.0"),
 			docs.FieldDeprecated("delimiter"),
+			docs.FieldAdvanced("rotation", "Allows you to rotate the written file by size and/or time.").WithChildren(
+				docs.FieldCommon("max_size", "The maximum size in bytes of a file before it is rotated, set to `0` in order to disable size based rotation."),
+				docs.FieldCommon("interval", "The maximum period of time that a file is kept open before it is rotated, set to an empty string in order to disable time based rotation.", "1h", "24h"),
+				docs.FieldCommon("compression", "A compression algorithm to apply to files once they are rotated.").HasOptions("none", "gzip", "zstd"),
+				docs.FieldAdvanced(
+					"path_mapping", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that determines the path to rename a rotated file to.",
+					`root = this.path + "." + this.rotated_at.format_timestamp("2006-01-02T15-04-05")`,
+				),
+			).AtVersion("3.39.0"),
 		},
 		Categories: []Category{
 			CategoryLocal,
@@ -44,19 +77,40 @@ Messages can be written to different files by using [interpolation functions](/d
 
 //------------------------------------------------------------------------------
 
+// FileRotationConfig contains configuration fields for rotating files written
+// by the file output type.
+type FileRotationConfig struct {
+	MaxSize     int64  `json:"max_size" yaml:"max_size"`
+	Interval    string `json:"interval" yaml:"interval"`
+	Compression string `json:"compression" yaml:"compression"`
+	PathMapping string `json:"path_mapping" yaml:"path_mapping"`
+}
+
+// NewFileRotationConfig creates a new FileRotationConfig with default values.
+func NewFileRotationConfig() FileRotationConfig {
+	return FileRotationConfig{
+		MaxSize:     0,
+		Interval:    "",
+		Compression: "none",
+		PathMapping: "",
+	}
+}
+
 // FileConfig contains configuration fields for the file based output type.
 type FileConfig struct {
-	Path  string `json:"path" yaml:"path"`
-	Codec string `json:"codec" yaml:"codec"`
-	Delim string `json:"delimiter" yaml:"delimiter"`
+	Path     string             `json:"path" yaml:"path"`
+	Codec    string             `json:"codec" yaml:"codec"`
+	Delim    string             `json:"delimiter" yaml:"delimiter"`
+	Rotation FileRotationConfig `json:"rotation" yaml:"rotation"`
 }
 
 // NewFileConfig creates a new FileConfig with default values.
 func NewFileConfig() FileConfig {
 	return FileConfig{
-		Path:  "",
-		Codec: "lines",
-		Delim: "",
+		Path:     "",
+		Codec:    "lines",
+		Delim:    "",
+		Rotation: NewFileRotationConfig(),
 	}
 }
 
@@ -71,6 +125,9 @@ func NewFile(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type
 	if err != nil {
 		return nil, err
 	}
+	if err = f.setRotation(conf.File.Rotation); err != nil {
+		return nil, err
+	}
 	return NewAsyncWriter(TypeFile, 1, f, log, stats)
 }
 
@@ -84,9 +141,18 @@ type fileWriter struct {
 	codec     codec.WriterConstructor
 	codecConf codec.WriterConfig
 
-	handleMut  sync.Mutex
-	handlePath string
-	handle     codec.Writer
+	rotateSize     int64
+	rotateInterval time.Duration
+	rotateCompress string
+	rotatePath     bloblang.Mapping
+	mRotated       metrics.StatCounter
+	mRotateErr     metrics.StatCounter
+
+	handleMut    sync.Mutex
+	handlePath   string
+	handle       codec.Writer
+	handleFile   *os.File
+	handleOpened time.Time
 }
 
 func newFileWriter(pathStr string, codecStr string, log log.Modular, stats metrics.Type) (*fileWriter, error) {
@@ -107,6 +173,168 @@ func newFileWriter(pathStr string, codecStr string, log log.Modular, stats metri
 	}, nil
 }
 
+func (w *fileWriter) setRotation(conf FileRotationConfig) error {
+	w.rotateSize = conf.MaxSize
+	if conf.Interval != "" {
+		var err error
+		if w.rotateInterval, err = time.ParseDuration(conf.Interval); err != nil {
+			return fmt.Errorf("failed to parse rotation interval: %w", err)
+		}
+	}
+	switch conf.Compression {
+	case "", "none", "gzip", "zstd":
+		w.rotateCompress = conf.Compression
+	default:
+		return fmt.Errorf("rotation compression not recognised: %v", conf.Compression)
+	}
+	if conf.PathMapping != "" {
+		var err error
+		if w.rotatePath, err = bloblang.NewMapping(conf.PathMapping); err != nil {
+			return fmt.Errorf("failed to parse rotation path mapping: %w", err)
+		}
+	}
+	if w.rotationEnabled() && w.codecConf.CloseAfter {
+		return errors.New("rotation is not supported with codecs that close the file after each write")
+	}
+	w.mRotated = w.stats.GetCounter("rotation.success")
+	w.mRotateErr = w.stats.GetCounter("rotation.error")
+	return nil
+}
+
+func (w *fileWriter) rotationEnabled() bool {
+	return w.rotateSize > 0 || w.rotateInterval > 0
+}
+
+// shouldRotate returns whether the currently open file has exceeded its
+// rotation thresholds. Must be called with handleMut held.
+func (w *fileWriter) shouldRotate() (bool, error) {
+	if w.handle == nil || !w.rotationEnabled() {
+		return false, nil
+	}
+	if w.rotateInterval > 0 && time.Since(w.handleOpened) >= w.rotateInterval {
+		return true, nil
+	}
+	if w.rotateSize > 0 {
+		info, err := w.handleFile.Stat()
+		if err != nil {
+			return false, err
+		}
+		if info.Size() >= w.rotateSize {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rotate closes the currently open file, renames it and applies compression.
+// Must be called with handleMut held.
+func (w *fileWriter) rotate(ctx context.Context) error {
+	info, err := w.handleFile.Stat()
+	if err != nil {
+		return err
+	}
+	if err = w.handle.Close(ctx); err != nil {
+		return err
+	}
+	w.handle, w.handleFile = nil, nil
+
+	rotatedAt := time.Now().UTC()
+	target, err := w.rotatedPath(info.Size(), rotatedAt)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(target), os.FileMode(0777)); err != nil {
+		return err
+	}
+	if err = os.Rename(w.handlePath, target); err != nil {
+		return err
+	}
+	if err = compressRotatedFile(target, w.rotateCompress); err != nil {
+		return err
+	}
+	w.log.Debugf("Rotated file '%v' to '%v'\n", w.handlePath, target)
+	return nil
+}
+
+func (w *fileWriter) rotatedPath(size int64, rotatedAt time.Time) (string, error) {
+	if w.rotatePath == nil {
+		return w.handlePath + "." + rotatedAt.Format("20060102T150405.000000000"), nil
+	}
+
+	msg := message.New(nil)
+	part := message.NewPart(nil)
+	if err := part.SetJSON(map[string]interface{}{
+		"path":       w.handlePath,
+		"size":       size,
+		"opened_at":  w.handleOpened.UTC().Format(time.RFC3339Nano),
+		"rotated_at": rotatedAt.Format(time.RFC3339Nano),
+	}); err != nil {
+		return "", err
+	}
+	msg.Append(part)
+
+	res, err := w.rotatePath.MapPart(0, msg)
+	if err != nil {
+		return "", fmt.Errorf("rotation path mapping failed: %w", err)
+	}
+	target := filepath.Clean(string(res.Get()))
+	if target == "." || target == w.handlePath {
+		return "", fmt.Errorf("rotation path mapping resulted in invalid path: %q", target)
+	}
+	return target, nil
+}
+
+func compressRotatedFile(path, algorithm string) (err error) {
+	var ext string
+	var newWriter func(io.Writer) (io.WriteCloser, error)
+	switch algorithm {
+	case "gzip":
+		ext = ".gz"
+		newWriter = func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}
+	case "zstd":
+		ext = ".zst"
+		newWriter = func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		}
+	default:
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+ext, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0666))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := dst.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path + ext)
+		}
+	}()
+
+	cw, err := newWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(cw, src); err != nil {
+		cw.Close()
+		return err
+	}
+	if err = cw.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
 //------------------------------------------------------------------------------
 
 func (w *fileWriter) ConnectWithContext(ctx context.Context) error {
@@ -121,12 +349,25 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 		defer w.handleMut.Unlock()
 
 		if w.handle != nil && path == w.handlePath {
-			return w.handle.Write(ctx, p)
+			rotate, err := w.shouldRotate()
+			if err != nil {
+				return err
+			}
+			if !rotate {
+				return w.handle.Write(ctx, p)
+			}
+			if err = w.rotate(ctx); err != nil {
+				w.mRotateErr.Incr(1)
+				w.log.Errorf("Failed to rotate file '%v': %v\n", w.handlePath, err)
+				return err
+			}
+			w.mRotated.Incr(1)
 		}
 		if w.handle != nil {
 			if err := w.handle.Close(ctx); err != nil {
 				return err
 			}
+			w.handle, w.handleFile = nil, nil
 		}
 
 		flag := os.O_CREATE | os.O_RDWR
@@ -159,6 +400,8 @@ func (w *fileWriter) WriteWithContext(ctx context.Context, msg types.Message) er
 
 		if !w.codecConf.CloseAfter {
 			w.handle = handle
+			w.handleFile = file
+			w.handleOpened = time.Now()
 		} else {
 			handle.Close(ctx)
 		}
@@ -184,7 +427,7 @@ func (w *fileWriter) CloseAsync() {
 		w.handleMut.Lock()
 		if w.handle != nil {
 			w.handle.Close(context.Background())
-			w.handle = nil
+			w.handle, w.handleFile = nil, nil
 		}
 		w.handleMut.Unlock()
 	}()
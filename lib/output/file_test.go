@@ -0,0 +1,117 @@
+package output
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFileWriter(t *testing.T, path string, rConf FileRotationConfig) *fileWriter {
+	t.Helper()
+
+	w, err := newFileWriter(path, "lines", log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.setRotation(rConf))
+
+	t.Cleanup(func() {
+		w.handleMut.Lock()
+		if w.handle != nil {
+			w.handle.Close(context.Background())
+		}
+		w.handleMut.Unlock()
+	})
+	return w
+}
+
+func TestFileRotationConfigErrs(t *testing.T) {
+	w, err := newFileWriter("/tmp/foo.txt", "all-bytes", log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	rConf := NewFileRotationConfig()
+	rConf.MaxSize = 10
+	assert.Error(t, w.setRotation(rConf))
+
+	w, err = newFileWriter("/tmp/foo.txt", "lines", log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	rConf = NewFileRotationConfig()
+	rConf.Interval = "not a duration"
+	assert.Error(t, w.setRotation(rConf))
+
+	rConf = NewFileRotationConfig()
+	rConf.Compression = "nope"
+	assert.Error(t, w.setRotation(rConf))
+
+	rConf = NewFileRotationConfig()
+	rConf.PathMapping = "root = ("
+	assert.Error(t, w.setRotation(rConf))
+}
+
+func TestFileRotationSize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+
+	rConf := NewFileRotationConfig()
+	rConf.MaxSize = 10
+	rConf.PathMapping = `root = this.path + "." + this.size.string()`
+	w := newTestFileWriter(t, path, rConf)
+
+	for _, content := range []string{"hello", "world", "foo", "bar"} {
+		require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{[]byte(content)})))
+	}
+
+	b, err := ioutil.ReadFile(path + ".12")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\nworld\n", string(b))
+
+	b, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "foo\nbar\n", string(b))
+}
+
+func TestFileRotationGzip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.txt")
+
+	rConf := NewFileRotationConfig()
+	rConf.MaxSize = 1
+	rConf.Compression = "gzip"
+	w := newTestFileWriter(t, path, rConf)
+
+	for _, content := range []string{"hello", "world"} {
+		require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{[]byte(content)})))
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	require.Len(t, names, 2)
+	assert.Equal(t, "data.txt", names[0])
+	assert.Regexp(t, `^data\.txt\.[0-9T.]+\.gz$`, names[1])
+
+	f, err := os.Open(filepath.Join(dir, names[1]))
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(b))
+}
@@ -0,0 +1,443 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGRPCClient] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			g, err := newGRPCClientWriter(conf.GRPCClient, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeGRPCClient, conf.GRPCClient.MaxInFlight, g, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.GRPCClient.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Batches: true,
+		Async:   true,
+		Summary: `
+Invokes a unary or client streaming gRPC method for messages, where the method
+is described by a compiled protobuf descriptor set.`,
+		Description: `
+Messages are expected to be JSON documents, which are converted into the
+request message type of the method using the
+[protobuf JSON mapping](https://developers.google.com/protocol-buffers/docs/proto3#json).
+Responses from the server are discarded.
+
+The descriptor set can be generated with ` + "`protoc`" + ` and must include
+all imported files:
+
+` + "```sh" + `
+protoc --include_imports --descriptor_set_out=./service.pb ./service.proto
+` + "```" + `
+
+### Unary Methods
+
+For unary methods each message of a batch results in its own call, and calls
+that fail are individually marked as failed.
+
+### Client Streaming Methods
+
+For client streaming methods an entire batch of messages is sent over a single
+stream, which is closed once the batch has been sent. When the stream fails the
+whole batch is considered failed. Metadata values are resolved from the first
+message of the batch.
+
+### Retries
+
+Calls that fail with the status codes ` + "`UNAVAILABLE`" + `,
+` + "`RESOURCE_EXHAUSTED`" + `, ` + "`ABORTED`" + ` or
+` + "`DEADLINE_EXCEEDED`" + ` are retried according to the ` + "`backoff`" + `
+and ` + "`max_retries`" + ` fields, and each attempt is given its own deadline
+according to the ` + "`timeout`" + ` field. All other errors are returned
+immediately.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Unary Calls",
+				Summary: `
+Given a compiled descriptor set for a service ` + "`acme.Users`" + ` with a
+unary method ` + "`CreateUser`" + `, we can call it for each message, passing
+a request ID as metadata, with the following config:`,
+				Config: `
+output:
+  grpc_client:
+    address: localhost:50051
+    descriptor_set: ./schemas/users.pb
+    method: acme.Users/CreateUser
+    metadata:
+      x-request-id: ${! meta("request_id") }
+    timeout: 2s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "The address of the server to connect to.", "localhost:50051"),
+			docs.FieldCommon("descriptor_set", "A path to a compiled protobuf descriptor set that describes the target method and all message types it depends on."),
+			docs.FieldCommon("method", "The fully qualified name of the method to invoke, in the form `<package>.<service>/<method>`.", "acme.Users/CreateUser"),
+			docs.FieldCommon("metadata", "A map of metadata keys and values to attach to each call.").SupportsInterpolation(true),
+			docs.FieldCommon("timeout", "The deadline of each call attempt. Set to an empty string in order to disable deadlines."),
+			btls.FieldSpec(),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		}.Merge(retries.FieldSpecs()),
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GRPCClientConfig contains configuration fields for the gRPC client output
+// type.
+type GRPCClientConfig struct {
+	Address        string            `json:"address" yaml:"address"`
+	DescriptorSet  string            `json:"descriptor_set" yaml:"descriptor_set"`
+	Method         string            `json:"method" yaml:"method"`
+	Metadata       map[string]string `json:"metadata" yaml:"metadata"`
+	Timeout        string            `json:"timeout" yaml:"timeout"`
+	TLS            btls.Config       `json:"tls" yaml:"tls"`
+	retries.Config `json:",inline" yaml:",inline"`
+	MaxInFlight    int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching       batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewGRPCClientConfig creates a new GRPCClientConfig with default values.
+func NewGRPCClientConfig() GRPCClientConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 3
+	rConf.Backoff.InitialInterval = "1s"
+	rConf.Backoff.MaxInterval = "5s"
+	rConf.Backoff.MaxElapsedTime = "30s"
+
+	return GRPCClientConfig{
+		Address:       "",
+		DescriptorSet: "",
+		Method:        "",
+		Metadata:      map[string]string{},
+		Timeout:       "5s",
+		TLS:           btls.NewConfig(),
+		Config:        rConf,
+		MaxInFlight:   1,
+		Batching:      batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// loadGRPCMethod reads a compiled descriptor set from a file and returns the
+// descriptor of a method in the form `<package>.<service>/<method>`.
+func loadGRPCMethod(descriptorSetPath, method string) (*desc.MethodDescriptor, error) {
+	if descriptorSetPath == "" {
+		return nil, errors.New("a descriptor set must be specified")
+	}
+
+	var serviceName, methodName string
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		serviceName, methodName = method[:i], method[i+1:]
+	} else if i = strings.LastIndex(method, "."); i > 0 {
+		serviceName, methodName = method[:i], method[i+1:]
+	}
+	if serviceName == "" || methodName == "" {
+		return nil, fmt.Errorf("method '%v' is not of the form <package>.<service>/<method>", method)
+	}
+
+	setBytes, err := ioutil.ReadFile(descriptorSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+	}
+
+	var set dpb.FileDescriptorSet
+	if err = proto.Unmarshal(setBytes, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set: %v", err)
+	}
+
+	files, err := desc.CreateFileDescriptorsFromSet(&set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link descriptor set: %v", err)
+	}
+
+	for _, fd := range files {
+		svc := fd.FindService(serviceName)
+		if svc == nil {
+			continue
+		}
+		md := svc.FindMethodByName(methodName)
+		if md == nil {
+			return nil, fmt.Errorf("service '%v' does not have a method '%v'", serviceName, methodName)
+		}
+		if md.IsServerStreaming() {
+			return nil, fmt.Errorf("method '%v' is server streaming, which is not supported", method)
+		}
+		return md, nil
+	}
+	return nil, fmt.Errorf("unable to find service '%v' within descriptor set '%v'", serviceName, descriptorSetPath)
+}
+
+func grpcErrIsRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+//------------------------------------------------------------------------------
+
+type grpcClientMetadata struct {
+	key   string
+	value field.Expression
+}
+
+type grpcClientWriter struct {
+	conf  GRPCClientConfig
+	log   log.Modular
+	stats metrics.Type
+
+	method   *desc.MethodDescriptor
+	metadata []grpcClientMetadata
+	timeout  time.Duration
+	boffCtor func() backoff.BackOff
+	dialOpts []grpc.DialOption
+	mRetries metrics.StatCounter
+	mLatency metrics.StatTimer
+	conn     *grpc.ClientConn
+	stub     grpcdynamic.Stub
+	connMut  sync.RWMutex
+}
+
+func newGRPCClientWriter(conf GRPCClientConfig, log log.Modular, stats metrics.Type) (*grpcClientWriter, error) {
+	if conf.Address == "" {
+		return nil, errors.New("an address must be specified")
+	}
+
+	g := &grpcClientWriter{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		mRetries: stats.GetCounter("retries"),
+		mLatency: stats.GetTimer("call.latency"),
+	}
+
+	var err error
+	if g.method, err = loadGRPCMethod(conf.DescriptorSet, conf.Method); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(conf.Metadata))
+	for k := range conf.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		expr, err := bloblang.NewField(conf.Metadata[k])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse metadata '%v' expression: %v", k, err)
+		}
+		g.metadata = append(g.metadata, grpcClientMetadata{
+			key:   strings.ToLower(k),
+			value: expr,
+		})
+	}
+
+	if conf.Timeout != "" {
+		if g.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	if g.boffCtor, err = conf.Config.GetCtor(); err != nil {
+		return nil, err
+	}
+
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		g.dialOpts = append(g.dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+	} else {
+		g.dialOpts = append(g.dialOpts, grpc.WithInsecure())
+	}
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *grpcClientWriter) ConnectWithContext(ctx context.Context) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+	if g.conn != nil {
+		return nil
+	}
+
+	conn, err := grpc.DialContext(ctx, g.conf.Address, append(g.dialOpts, grpc.WithBlock())...)
+	if err != nil {
+		return err
+	}
+
+	g.conn = conn
+	g.stub = grpcdynamic.NewStub(conn)
+	g.log.Infof("Sending messages to gRPC method %v at: %v\n", g.method.GetFullyQualifiedName(), g.conf.Address)
+	return nil
+}
+
+func (g *grpcClientWriter) callContext(ctx context.Context, index int, msg types.Message) context.Context {
+	if len(g.metadata) == 0 {
+		return ctx
+	}
+	md := make(metadata.MD, len(g.metadata))
+	for _, m := range g.metadata {
+		md.Append(m.key, m.value.String(index, msg))
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (g *grpcClientWriter) requestMessage(p types.Part) (*dynamic.Message, error) {
+	req := dynamic.NewMessage(g.method.GetInputType())
+	if err := req.UnmarshalJSON(p.Get()); err != nil {
+		return nil, fmt.Errorf("failed to convert message to %v: %w", g.method.GetInputType().GetFullyQualifiedName(), err)
+	}
+	return req, nil
+}
+
+// invoke attempts a call until it either succeeds, fails with an error that
+// isn't retryable, or the backoff is exhausted.
+func (g *grpcClientWriter) invoke(ctx context.Context, call func(context.Context) error) error {
+	boff := g.boffCtor()
+	for {
+		callCtx, done := ctx, func() {}
+		if g.timeout > 0 {
+			callCtx, done = context.WithTimeout(ctx, g.timeout)
+		}
+		t0 := time.Now()
+		err := call(callCtx)
+		done()
+		if err == nil {
+			g.mLatency.Timing(time.Since(t0).Nanoseconds())
+			return nil
+		}
+		if !grpcErrIsRetryable(err) {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		g.log.Warnf("Retrying failed call to %v: %v\n", g.method.GetFullyQualifiedName(), err)
+		g.mRetries.Incr(1)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (g *grpcClientWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	g.connMut.RLock()
+	conn, stub := g.conn, g.stub
+	g.connMut.RUnlock()
+
+	if conn == nil {
+		return types.ErrNotConnected
+	}
+
+	if g.method.IsClientStreaming() {
+		return g.writeStream(ctx, stub, msg)
+	}
+	return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+		req, err := g.requestMessage(p)
+		if err != nil {
+			return err
+		}
+		callCtx := g.callContext(ctx, i, msg)
+		return g.invoke(callCtx, func(ctx context.Context) error {
+			_, err := stub.InvokeRpc(ctx, g.method, req)
+			return err
+		})
+	})
+}
+
+func (g *grpcClientWriter) writeStream(ctx context.Context, stub grpcdynamic.Stub, msg types.Message) error {
+	reqs := make([]proto.Message, 0, msg.Len())
+	if err := msg.Iter(func(i int, p types.Part) error {
+		req, err := g.requestMessage(p)
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, req)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	callCtx := g.callContext(ctx, 0, msg)
+	return g.invoke(callCtx, func(ctx context.Context) error {
+		stream, err := stub.InvokeRpcClientStream(ctx, g.method)
+		if err != nil {
+			return err
+		}
+		for _, req := range reqs {
+			if err = stream.SendMsg(req); err != nil {
+				// The actual status of the stream is obtained when it is
+				// closed.
+				break
+			}
+		}
+		_, err = stream.CloseAndReceive()
+		return err
+	})
+}
+
+func (g *grpcClientWriter) CloseAsync() {
+	go func() {
+		g.connMut.Lock()
+		if g.conn != nil {
+			g.conn.Close()
+			g.conn = nil
+		}
+		g.connMut.Unlock()
+	}()
+}
+
+func (g *grpcClientWriter) WaitForClose(time.Duration) error {
+	return nil
+}
@@ -0,0 +1,294 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const grpcClientTestProto = `
+syntax = "proto3";
+package testing;
+
+message Person {
+  string name = 1;
+  int32 age = 2;
+}
+
+message Ack {
+  int32 count = 1;
+}
+
+service People {
+  rpc Add(Person) returns (Ack);
+  rpc AddMany(stream Person) returns (Ack);
+  rpc List(Ack) returns (stream Person);
+}
+`
+
+type grpcClientTestCall struct {
+	method   string
+	metadata metadata.MD
+	messages []string
+}
+
+type grpcClientTestServer struct {
+	address string
+	fd      *desc.FileDescriptor
+
+	handler func(method string, msgs []string) error
+
+	mut   sync.Mutex
+	calls []grpcClientTestCall
+}
+
+func newGRPCClientTestServer(t *testing.T) (*grpcClientTestServer, string) {
+	t.Helper()
+
+	fds, err := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{
+			"people.proto": grpcClientTestProto,
+		}),
+	}.ParseFiles("people.proto")
+	require.NoError(t, err)
+
+	setBytes, err := proto.Marshal(&dpb.FileDescriptorSet{
+		File: []*dpb.FileDescriptorProto{fds[0].AsFileDescriptorProto()},
+	})
+	require.NoError(t, err)
+
+	tmpDir, err := ioutil.TempDir("", "benthos_grpc_client_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	setPath := filepath.Join(tmpDir, "people.pb")
+	require.NoError(t, ioutil.WriteFile(setPath, setBytes, 0644))
+
+	s := &grpcClientTestServer{fd: fds[0]}
+	personDesc := s.fd.FindMessage("testing.Person")
+	ackDesc := s.fd.FindMessage("testing.Ack")
+
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		md, _ := metadata.FromIncomingContext(stream.Context())
+
+		var msgs []string
+		for {
+			person := dynamic.NewMessage(personDesc)
+			if err := stream.RecvMsg(person); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return err
+			}
+			msgs = append(msgs, person.GetFieldByName("name").(string))
+		}
+
+		s.mut.Lock()
+		s.calls = append(s.calls, grpcClientTestCall{
+			method:   method,
+			metadata: md,
+			messages: msgs,
+		})
+		handler := s.handler
+		s.mut.Unlock()
+
+		if handler != nil {
+			if err := handler(method, msgs); err != nil {
+				return err
+			}
+		}
+
+		ack := dynamic.NewMessage(ackDesc)
+		ack.SetFieldByName("count", int32(len(msgs)))
+		return stream.SendMsg(ack)
+	}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	s.address = lis.Addr().String()
+	return s, setPath
+}
+
+func (s *grpcClientTestServer) setHandler(fn func(method string, msgs []string) error) {
+	s.mut.Lock()
+	s.handler = fn
+	s.mut.Unlock()
+}
+
+func (s *grpcClientTestServer) getCalls() []grpcClientTestCall {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]grpcClientTestCall{}, s.calls...)
+}
+
+func TestGRPCClientMethodErrors(t *testing.T) {
+	_, setPath := newGRPCClientTestServer(t)
+
+	tests := map[string]string{
+		"People":                 "is not of the form",
+		"testing.People":         "unable to find service",
+		"testing.Nope/Add":       "unable to find service",
+		"testing.People/Nope":    "does not have a method",
+		"testing.People/List":    "server streaming",
+		"/testing.People/Add/":   "is not of the form",
+		"testing.People.Missing": "does not have a method",
+	}
+
+	for method, errContains := range tests {
+		_, err := loadGRPCMethod(setPath, method)
+		require.Error(t, err, method)
+		assert.Contains(t, err.Error(), errContains, method)
+	}
+
+	md, err := loadGRPCMethod(setPath, "testing.People.Add")
+	require.NoError(t, err)
+	assert.Equal(t, "testing.People.Add", md.GetFullyQualifiedName())
+}
+
+func TestGRPCClientUnary(t *testing.T) {
+	s, setPath := newGRPCClientTestServer(t)
+
+	var attempts int
+	s.setHandler(func(method string, msgs []string) error {
+		if msgs[0] == "bad" {
+			return status.Error(codes.InvalidArgument, "bad person")
+		}
+		if msgs[0] == "flaky" {
+			if attempts++; attempts < 3 {
+				return status.Error(codes.Unavailable, "try again")
+			}
+		}
+		return nil
+	})
+
+	conf := NewGRPCClientConfig()
+	conf.Address = s.address
+	conf.DescriptorSet = setPath
+	conf.Method = "testing.People/Add"
+	conf.Metadata = map[string]string{
+		"X-Person": `${! json("name") }`,
+	}
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	w, err := newGRPCClientWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.ConnectWithContext(ctx))
+	t.Cleanup(func() {
+		w.CloseAsync()
+		require.NoError(t, w.WaitForClose(time.Second))
+	})
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"name":"foo","age":20}`),
+		[]byte(`{"name":"bad"}`),
+		[]byte(`{"name":"flaky"}`),
+		[]byte(`not json`),
+	}))
+	require.Error(t, err)
+
+	var bErr *batch.Error
+	require.True(t, errors.As(err, &bErr))
+
+	failed := map[int]error{}
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed[i] = err
+		}
+		return true
+	})
+	require.Len(t, failed, 2)
+	assert.Equal(t, codes.InvalidArgument, status.Code(failed[1]))
+	assert.Contains(t, failed[3].Error(), "failed to convert message")
+
+	calls := s.getCalls()
+	require.Len(t, calls, 5)
+
+	var names []string
+	for _, c := range calls {
+		assert.Equal(t, "/testing.People/Add", c.method)
+		require.Len(t, c.messages, 1)
+		names = append(names, c.messages[0])
+		assert.Equal(t, []string{c.messages[0]}, c.metadata.Get("x-person"))
+	}
+	assert.Equal(t, []string{"foo", "bad", "flaky", "flaky", "flaky"}, names)
+}
+
+func TestGRPCClientStreaming(t *testing.T) {
+	s, setPath := newGRPCClientTestServer(t)
+
+	conf := NewGRPCClientConfig()
+	conf.Address = s.address
+	conf.DescriptorSet = setPath
+	conf.Method = "testing.People/AddMany"
+	conf.Metadata = map[string]string{
+		"batch": `${! batch_size() }`,
+	}
+
+	w, err := newGRPCClientWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.ConnectWithContext(ctx))
+	t.Cleanup(func() {
+		w.CloseAsync()
+		require.NoError(t, w.WaitForClose(time.Second))
+	})
+
+	require.NoError(t, w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"name":"foo"}`),
+		[]byte(`{"name":"bar"}`),
+		[]byte(`{"name":"baz"}`),
+	})))
+
+	s.setHandler(func(method string, msgs []string) error {
+		return status.Error(codes.PermissionDenied, "nope")
+	})
+	err = w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte(`{"name":"qux"}`),
+	}))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	calls := s.getCalls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, "/testing.People/AddMany", calls[0].method)
+	assert.Equal(t, []string{"foo", "bar", "baz"}, calls[0].messages)
+	assert.Equal(t, []string{"3"}, calls[0].metadata.Get("batch"))
+	assert.Equal(t, []string{"qux"}, calls[1].messages)
+}
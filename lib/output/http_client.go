@@ -1,12 +1,16 @@
 package output
 
 import (
+	"fmt"
+
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output/writer"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aimd"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
 	"github.com/Jeffail/benthos/v3/lib/util/http/client"
 )
 
@@ -35,15 +39,32 @@ behaviour can be overridden by [archiving your batches](/docs/configuration/batc
 It's possible to propagate the response from each HTTP request back to the input
 source by setting ` + "`propagate_response` to `true`" + `. Only inputs that
 support [synchronous responses](/docs/guides/sync_responses) are able to make use of
-these propagated responses.`,
+these propagated responses.
+
+### Signing Requests
+
+Requests can be signed with an HMAC of their body by configuring the field
+` + "`signing`" + `, which saves having to compute signatures with
+[Bloblang](/docs/guides/bloblang/about) when delivering webhooks. Signatures are
+computed for each attempt, and therefore timestamps are refreshed for retries.
+
+### Adaptive Concurrency
+
+When ` + "`adaptive_in_flight.enabled`" + ` is set the number of messages in
+flight is tuned automatically up to ` + "`max_in_flight`" + `, where requests
+that fail or take longer than ` + "`adaptive_in_flight.latency_target`" + `
+cause the number to be reduced. This saves having to tune
+` + "`max_in_flight`" + ` for each environment that a config is deployed to.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			return sanitiseWithBatch(conf.HTTPClient, conf.HTTPClient.Batching)
 		},
 		Async:   true,
 		Batches: true,
 		FieldSpecs: client.FieldSpecs().Add(
+			auth.HMACSigningFieldSpec(),
 			docs.FieldAdvanced("propagate_response", "Whether responses from the server should be [propagated back](/docs/guides/sync_responses) to the input."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			aimd.FieldSpec("adaptive_in_flight", "max_in_flight"),
 		).Add(batch.FieldSpec()),
 		Categories: []Category{
 			CategoryNetwork,
@@ -57,11 +78,19 @@ func NewHTTPClient(conf Config, mgr types.Manager, log log.Modular, stats metric
 	if err != nil {
 		return nil, err
 	}
+	var opts []func(*AsyncWriter)
+	if conf.HTTPClient.AdaptiveInFlight.Enabled {
+		limiter, err := aimd.New(conf.HTTPClient.AdaptiveInFlight, conf.HTTPClient.MaxInFlight, stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create adaptive limiter: %v", err)
+		}
+		opts = append(opts, OptAsyncWriterSetLimiter(limiter))
+	}
 	var w Type
 	if conf.HTTPClient.MaxInFlight == 1 {
 		w, err = NewWriter(TypeHTTPClient, h, log, stats)
 	} else {
-		w, err = NewAsyncWriter(TypeHTTPClient, conf.HTTPClient.MaxInFlight, h, log, stats)
+		w, err = NewAsyncWriter(TypeHTTPClient, conf.HTTPClient.MaxInFlight, h, log, stats, opts...)
 	}
 	if err != nil {
 		return w, err
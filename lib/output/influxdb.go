@@ -0,0 +1,518 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	mbatch "github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeInfluxDB] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			i, err := newInfluxDBWriter(conf.InfluxDB, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeInfluxDB, conf.InfluxDB.MaxInFlight, i, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.InfluxDB.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Batches: true,
+		Async:   true,
+		Summary: `
+Writes messages as points to an InfluxDB bucket using the v2 write API.`,
+		Description: `
+Each message is converted into a point of the
+[line protocol](https://docs.influxdata.com/influxdb/v2.0/reference/syntax/line-protocol/),
+and each batch of messages is written with a single request. The structure of
+a point is obtained by executing the [Bloblang mapping](/docs/guides/bloblang/about)
+in the field ` + "`mapping`" + `, which must result in an object of the form:
+
+` + "```json" + `
+{
+  "measurement": "cpu",
+  "tags": { "host": "server01" },
+  "fields": { "usage": 0.64, "cores": 4 },
+  "timestamp": 1605000000.123
+}
+` + "```" + `
+
+Tag values are converted to strings. Field values can be strings, booleans or
+numbers, where numbers without a fractional part in the original document are
+written as integers and all other numbers are written as floats. The timestamp
+is optional and can either be a unix timestamp in seconds, with an optional
+fractional part, or a string in RFC 3339 format. When omitted the server
+assigns the time of the write.
+
+### Partial Writes
+
+When InfluxDB rejects some of the points of a batch the remaining points are
+still written. In this case any point that can be identified from the error
+returned by the server is marked as failed individually, allowing only those
+messages to be retried or routed to a dead letter output. Messages that cannot
+be converted into points are also marked as failed individually, and are not
+sent.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Sensor Readings",
+				Summary: `
+Here we convert sensor readings of the form
+` + "`{\"sensor\":\"a\",\"site\":\"b\",\"temp\":20.5,\"ts\":\"2020-11-10T15:04:05Z\"}`" + `
+into points:`,
+				Config: `
+output:
+  influxdb:
+    url: http://localhost:8086
+    org: acme
+    bucket: sensors
+    token: ${INFLUX_TOKEN}
+    mapping: |
+      root.measurement = "temperature"
+      root.tags.sensor = this.sensor
+      root.tags.site = this.site
+      root.fields.value = this.temp
+      root.timestamp = this.ts
+    batching:
+      count: 500
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The base URL of the InfluxDB server.", "http://localhost:8086"),
+			docs.FieldCommon("org", "The organization to write to."),
+			docs.FieldCommon("bucket", "The bucket to write to."),
+			docs.FieldCommon("token", "An authentication token to write with."),
+			docs.FieldCommon("mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that converts each message into a point. When empty messages are expected to already be of the point structure."),
+			docs.FieldAdvanced("precision", "The precision of timestamps written to the server.").HasOptions("ns", "us", "ms", "s"),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a write request to complete."),
+			btls.FieldSpec(),
+			docs.FieldCommon("max_in_flight", "The maximum number of batches to have in flight at a given time. Increase this to improve throughput."),
+			mbatch.FieldSpec(),
+		},
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// InfluxDBConfig contains configuration fields for the InfluxDB output type.
+type InfluxDBConfig struct {
+	URL         string              `json:"url" yaml:"url"`
+	Org         string              `json:"org" yaml:"org"`
+	Bucket      string              `json:"bucket" yaml:"bucket"`
+	Token       string              `json:"token" yaml:"token"`
+	Mapping     string              `json:"mapping" yaml:"mapping"`
+	Precision   string              `json:"precision" yaml:"precision"`
+	Timeout     string              `json:"timeout" yaml:"timeout"`
+	TLS         btls.Config         `json:"tls" yaml:"tls"`
+	MaxInFlight int                 `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching    mbatch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewInfluxDBConfig creates a new InfluxDBConfig with default values.
+func NewInfluxDBConfig() InfluxDBConfig {
+	return InfluxDBConfig{
+		URL:         "http://localhost:8086",
+		Org:         "",
+		Bucket:      "",
+		Token:       "",
+		Mapping:     "",
+		Precision:   "ns",
+		Timeout:     "5s",
+		TLS:         btls.NewConfig(),
+		MaxInFlight: 1,
+		Batching:    mbatch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var influxPrecisions = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	influxStringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+// influxLine encodes a point structure as a single line of the line protocol.
+func influxLine(v interface{}, precision time.Duration) (string, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("expected object, got %T", v)
+	}
+
+	measurement, _ := obj["measurement"].(string)
+	if measurement == "" {
+		return "", errors.New("a point must have a non-empty string measurement")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(influxMeasurementEscaper.Replace(measurement))
+
+	if tagsV, exists := obj["tags"]; exists && tagsV != nil {
+		tags, ok := tagsV.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("expected tags to be an object, got %T", tagsV)
+		}
+		keys := make([]string, 0, len(tags))
+		for k := range tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			value := influxTagValue(tags[k])
+			if value == "" {
+				continue
+			}
+			buf.WriteByte(',')
+			buf.WriteString(influxKeyEscaper.Replace(k))
+			buf.WriteByte('=')
+			buf.WriteString(influxKeyEscaper.Replace(value))
+		}
+	}
+
+	fields, ok := obj["fields"].(map[string]interface{})
+	if !ok || len(fields) == 0 {
+		return "", errors.New("a point must have at least one field")
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		value, err := influxFieldValue(fields[k])
+		if err != nil {
+			return "", fmt.Errorf("field '%v': %v", k, err)
+		}
+		if i == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(influxKeyEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(value)
+	}
+
+	if tsV, exists := obj["timestamp"]; exists && tsV != nil {
+		ts, err := parsePointTimestamp(tsV)
+		if err != nil {
+			return "", fmt.Errorf("timestamp: %v", err)
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(ts.UnixNano()/int64(precision), 10))
+	}
+	return buf.String(), nil
+}
+
+func influxTagValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case json.Number:
+		return t.String()
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func influxFieldValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return `"` + influxStringEscaper.Replace(t) + `"`, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return strconv.FormatInt(i, 10) + "i", nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return "", err
+		}
+		return influxFieldValue(f)
+	case int:
+		return strconv.Itoa(t) + "i", nil
+	case int64:
+		return strconv.FormatInt(t, 10) + "i", nil
+	case uint64:
+		return strconv.FormatUint(t, 10) + "u", nil
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return "", fmt.Errorf("unsupported float value %v", t)
+		}
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", v)
+}
+
+// parsePointTimestamp parses the timestamp of a metric point, which is either a
+// unix timestamp in seconds or an RFC 3339 string.
+func parsePointTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case json.Number:
+		f, err := t.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return parsePointTimestamp(f)
+	case int64:
+		return time.Unix(t, 0), nil
+	case uint64:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		secs, frac := math.Modf(t)
+		return time.Unix(int64(secs), int64(math.Round(frac*1e9))), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported value type %T", v)
+}
+
+//------------------------------------------------------------------------------
+
+type influxDBWriter struct {
+	conf  InfluxDBConfig
+	log   log.Modular
+	stats metrics.Type
+
+	writeURL  string
+	mapping   *mapping.Executor
+	precision time.Duration
+	client    *http.Client
+
+	mPartial metrics.StatCounter
+	mInvalid metrics.StatCounter
+}
+
+func newInfluxDBWriter(conf InfluxDBConfig, log log.Modular, stats metrics.Type) (*influxDBWriter, error) {
+	if conf.Org == "" || conf.Bucket == "" {
+		return nil, errors.New("both an org and a bucket must be specified")
+	}
+
+	i := &influxDBWriter{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		mPartial: stats.GetCounter("partial_write"),
+		mInvalid: stats.GetCounter("invalid_point"),
+	}
+
+	var ok bool
+	if i.precision, ok = influxPrecisions[conf.Precision]; !ok {
+		return nil, fmt.Errorf("precision not recognised: %v", conf.Precision)
+	}
+
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %v", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+	u.RawQuery = url.Values{
+		"org":       []string{conf.Org},
+		"bucket":    []string{conf.Bucket},
+		"precision": []string{conf.Precision},
+	}.Encode()
+	i.writeURL = u.String()
+
+	if conf.Mapping != "" {
+		if i.mapping, err = bloblang.NewMapping("", conf.Mapping); err != nil {
+			return nil, fmt.Errorf("failed to parse mapping: %v", err)
+		}
+	}
+
+	var timeout time.Duration
+	if conf.Timeout != "" {
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	i.client = &http.Client{Timeout: timeout}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		i.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return i, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (i *influxDBWriter) ConnectWithContext(ctx context.Context) error {
+	i.log.Infof("Writing points to InfluxDB bucket '%v' at: %v\n", i.conf.Bucket, i.conf.URL)
+	return nil
+}
+
+func (i *influxDBWriter) point(index int, msg types.Message) (string, bool, error) {
+	p := msg.Get(index)
+	if i.mapping != nil {
+		var err error
+		if p, err = i.mapping.MapPart(index, msg); err != nil {
+			return "", false, fmt.Errorf("mapping failed: %w", err)
+		}
+		if p == nil {
+			return "", false, nil
+		}
+	}
+	v, err := p.JSON()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse point: %w", err)
+	}
+	line, err := influxLine(v, i.precision)
+	if err != nil {
+		return "", false, err
+	}
+	return line, true, nil
+}
+
+func (i *influxDBWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	var bErr *batch.Error
+	failed := func(index int, err error) {
+		if bErr == nil {
+			bErr = batch.NewError(msg, err)
+		}
+		bErr.Failed(index, err)
+	}
+
+	var body bytes.Buffer
+	var lines []string
+	var lineIndexes []int
+	msg.Iter(func(index int, _ types.Part) error {
+		line, ok, err := i.point(index, msg)
+		if err != nil {
+			i.mInvalid.Incr(1)
+			i.log.Debugf("Failed to convert message to point: %v\n", err)
+			failed(index, err)
+			return nil
+		}
+		if ok {
+			body.WriteString(line)
+			body.WriteByte('\n')
+			lines = append(lines, line)
+			lineIndexes = append(lineIndexes, index)
+		}
+		return nil
+	})
+
+	if len(lines) > 0 {
+		if err := i.write(ctx, &body, lines, lineIndexes, failed); err != nil {
+			return err
+		}
+	}
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+// write sends the encoded points to the server. Points that are rejected and
+// can be identified from the response are reported with the failed func.
+func (i *influxDBWriter) write(ctx context.Context, body *bytes.Buffer, lines []string, lineIndexes []int, failed func(int, error)) error {
+	req, err := http.NewRequest("POST", i.writeURL, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.conf.Token != "" {
+		req.Header.Set("Authorization", "Token "+i.conf.Token)
+	}
+
+	res, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+
+	var resErr struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if jerr := json.Unmarshal(resBody, &resErr); jerr != nil || resErr.Message == "" {
+		resErr.Message = string(resBody)
+	}
+	err = fmt.Errorf("write request returned status code %v: %v", res.StatusCode, resErr.Message)
+
+	if res.StatusCode != http.StatusBadRequest && res.StatusCode != http.StatusUnprocessableEntity {
+		return err
+	}
+
+	// The points that were rejected are only identifiable by their quoted
+	// contents within the error message, in which case all other points were
+	// written.
+	identified := false
+	for j, line := range lines {
+		if strings.Contains(resErr.Message, "'"+line+"'") {
+			failed(lineIndexes[j], err)
+			identified = true
+		}
+	}
+	if !identified {
+		return err
+	}
+	i.mPartial.Incr(1)
+	i.log.Warnf("Points of batch were rejected: %v\n", resErr.Message)
+	return nil
+}
+
+func (i *influxDBWriter) CloseAsync() {
+}
+
+func (i *influxDBWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,145 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfluxLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		precision time.Duration
+		output    string
+		err       string
+	}{
+		{
+			name:      "basic",
+			input:     `{"measurement":"cpu","tags":{"host":"a","region":"eu west"},"fields":{"usage":0.5,"cores":4,"up":true,"name":"foo \"bar\""}}`,
+			precision: time.Nanosecond,
+			output:    `cpu,host=a,region=eu\ west cores=4i,name="foo \"bar\"",up=true,usage=0.5`,
+		},
+		{
+			name:      "timestamp number",
+			input:     `{"measurement":"cpu,total","fields":{"usage":1.5},"timestamp":1605000000.5}`,
+			precision: time.Millisecond,
+			output:    `cpu\,total usage=1.5 1605000000500`,
+		},
+		{
+			name:      "timestamp string",
+			input:     `{"measurement":"cpu","fields":{"usage":1.5},"timestamp":"2020-11-10T09:20:00Z"}`,
+			precision: time.Second,
+			output:    `cpu usage=1.5 1605000000`,
+		},
+		{
+			name:  "no fields",
+			input: `{"measurement":"cpu","fields":{}}`,
+			err:   "a point must have at least one field",
+		},
+		{
+			name:  "no measurement",
+			input: `{"fields":{"usage":1}}`,
+			err:   "a point must have a non-empty string measurement",
+		},
+		{
+			name:  "bad field",
+			input: `{"measurement":"cpu","fields":{"usage":[1]}}`,
+			err:   "field 'usage': unsupported value type []interface {}",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			v, err := message.NewPart([]byte(test.input)).JSON()
+			require.NoError(t, err)
+
+			line, err := influxLine(v, test.precision)
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Equal(t, test.err, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, line)
+		})
+	}
+}
+
+func TestInfluxDBWrite(t *testing.T) {
+	var reqBody, reqQuery, reqAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		reqBody, reqQuery, reqAuth = string(b), r.URL.RawQuery, r.Header.Get("Authorization")
+
+		assert.Equal(t, "/api/v2/write", r.URL.Path)
+		if strings.Contains(reqBody, "bad") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{
+				"code":    "invalid",
+				"message": "partial write: unable to parse 'cpu,host=bad value=2i': type conflict",
+			})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	conf := NewInfluxDBConfig()
+	conf.URL = server.URL
+	conf.Org = "acme"
+	conf.Bucket = "foo"
+	conf.Token = "bar"
+	conf.Mapping = `
+root.measurement = "cpu"
+root.tags.host = this.host
+root.fields.value = this.value
+`
+
+	w, err := newInfluxDBWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"host":"a","value":1}`),
+		[]byte(`{"host":"b","value":2.5}`),
+	})))
+	assert.Equal(t, "cpu,host=a value=1i\ncpu,host=b value=2.5\n", reqBody)
+	assert.Equal(t, "bucket=foo&org=acme&precision=ns", reqQuery)
+	assert.Equal(t, "Token bar", reqAuth)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"host":"a","value":1}`),
+		[]byte(`{"host":"bad","value":2}`),
+		[]byte(`not a point`),
+	}))
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+
+	var failed []int
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, failed)
+	assert.Equal(t, "cpu,host=a value=1i\ncpu,host=bad value=2i\n", reqBody)
+}
@@ -99,7 +99,7 @@ func (w *LineWriter) loop() {
 		}
 
 		w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
-		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
+		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload, tracing.ComponentTags("output", w.typeStr, ts.Payload.Len()))
 
 		var err error
 		t0 := time.Now()
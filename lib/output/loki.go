@@ -0,0 +1,435 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	mbatch "github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/cenkalti/backoff/v4"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeLoki] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			l, err := newLokiWriter(conf.Loki, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeLoki, conf.Loki.MaxInFlight, l, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.Loki.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Batches: true,
+		Async:   true,
+		Summary: `
+Pushes messages as log lines to Grafana Loki.`,
+		Description: `
+The labels of each message are obtained by executing the
+[Bloblang mapping](/docs/guides/bloblang/about) in the field
+` + "`labels`" + `, which must result in an object of label names to values.
+Each batch of messages is sent as a single push request, where messages that
+share the same labels are grouped into the same stream. Messages for which
+labels cannot be resolved are marked as failed individually and are not sent.
+
+The timestamp of each log line is resolved with the field
+` + "`timestamp`" + `, which can either be a unix timestamp in seconds, with
+an optional fractional part, or a string in RFC 3339 format. When empty the
+time at which the batch is sent is used.
+
+### Ordering
+
+Loki rejects log lines that are older than the most recent line of a stream.
+Therefore the lines of each stream are sorted by their timestamps before they
+are pushed. Ordering between batches is only preserved when
+` + "`max_in_flight`" + ` is ` + "`1`" + `.
+
+### Retries
+
+Requests that are rate limited (status code ` + "`429`" + `) or that fail due
+to server errors (status codes ` + "`5xx`" + `) are retried according to the
+` + "`backoff`" + ` and ` + "`max_retries`" + ` fields. All other errors are
+returned immediately.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Application Logs",
+				Summary: `
+Here we push structured application logs to Loki, labelled by application and
+level, where each log line is the message of the log:`,
+				Config: `
+output:
+  loki:
+    url: http://localhost:3100
+    labels: |
+      root.app = this.app
+      root.level = this.level.lowercase()
+    line: ${! json("message") }
+    timestamp: ${! json("time") }
+    batching:
+      count: 1000
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The base URL of the Loki server.", "http://localhost:3100"),
+			docs.FieldCommon("labels", "A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of labels for each message."),
+			docs.FieldCommon("line", "The log line of each message.").SupportsInterpolation(false),
+			docs.FieldCommon("timestamp", "An optional timestamp of each log line.").SupportsInterpolation(false),
+			docs.FieldAdvanced("tenant_id", "An optional tenant ID to push log lines as, for multi-tenant deployments."),
+			docs.FieldAdvanced("headers", "A map of headers to add to each request."),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for each request to complete."),
+			btls.FieldSpec(),
+			docs.FieldCommon("max_in_flight", "The maximum number of batches to have in flight at a given time. Increase this to improve throughput."),
+			mbatch.FieldSpec(),
+		}.Merge(retries.FieldSpecs()),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// LokiConfig contains configuration fields for the Loki output type.
+type LokiConfig struct {
+	URL            string            `json:"url" yaml:"url"`
+	Labels         string            `json:"labels" yaml:"labels"`
+	Line           string            `json:"line" yaml:"line"`
+	Timestamp      string            `json:"timestamp" yaml:"timestamp"`
+	TenantID       string            `json:"tenant_id" yaml:"tenant_id"`
+	Headers        map[string]string `json:"headers" yaml:"headers"`
+	Timeout        string            `json:"timeout" yaml:"timeout"`
+	TLS            btls.Config       `json:"tls" yaml:"tls"`
+	retries.Config `json:",inline" yaml:",inline"`
+	MaxInFlight    int                 `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching       mbatch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewLokiConfig creates a new LokiConfig with default values.
+func NewLokiConfig() LokiConfig {
+	rConf := retries.NewConfig()
+	rConf.MaxRetries = 5
+	rConf.Backoff.InitialInterval = "500ms"
+	rConf.Backoff.MaxInterval = "10s"
+	rConf.Backoff.MaxElapsedTime = "1m"
+
+	return LokiConfig{
+		URL:         "http://localhost:3100",
+		Labels:      `root.source = "benthos"`,
+		Line:        "${! content() }",
+		Timestamp:   "",
+		TenantID:    "",
+		Headers:     map[string]string{},
+		Timeout:     "5s",
+		TLS:         btls.NewConfig(),
+		Config:      rConf,
+		MaxInFlight: 1,
+		Batching:    mbatch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type lokiEntry struct {
+	ts   int64
+	line string
+}
+
+type lokiStream struct {
+	labels  map[string]string
+	entries []lokiEntry
+}
+
+// lokiLabels resolves the labels of a message into a map and a key that
+// uniquely identifies the label set.
+func lokiLabels(v interface{}) (map[string]string, string, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("expected labels to be an object, got %T", v)
+	}
+	labels := make(map[string]string, len(obj))
+	keys := make([]string, 0, len(obj))
+	for k, lv := range obj {
+		if lv == nil {
+			continue
+		}
+		value := query.IToString(lv)
+		if value == "" {
+			continue
+		}
+		labels[k] = value
+		keys = append(keys, k)
+	}
+	if len(labels) == 0 {
+		return nil, "", errors.New("at least one label must be set")
+	}
+	sort.Strings(keys)
+
+	var key strings.Builder
+	for _, k := range keys {
+		key.WriteString(strconv.Quote(k))
+		key.WriteByte('=')
+		key.WriteString(strconv.Quote(labels[k]))
+		key.WriteByte(',')
+	}
+	return labels, key.String(), nil
+}
+
+//------------------------------------------------------------------------------
+
+type lokiWriter struct {
+	conf  LokiConfig
+	log   log.Modular
+	stats metrics.Type
+
+	pushURL   string
+	labels    *mapping.Executor
+	line      field.Expression
+	timestamp field.Expression
+	boffCtor  func() backoff.BackOff
+	client    *http.Client
+
+	mRetries metrics.StatCounter
+}
+
+func newLokiWriter(conf LokiConfig, log log.Modular, stats metrics.Type) (*lokiWriter, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+
+	l := &lokiWriter{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		pushURL:  strings.TrimSuffix(conf.URL, "/") + "/loki/api/v1/push",
+		mRetries: stats.GetCounter("retries"),
+	}
+
+	var err error
+	if l.labels, err = bloblang.NewMapping("", conf.Labels); err != nil {
+		return nil, fmt.Errorf("failed to parse labels mapping: %v", err)
+	}
+	if l.line, err = bloblang.NewField(conf.Line); err != nil {
+		return nil, fmt.Errorf("failed to parse line expression: %v", err)
+	}
+	if l.timestamp, err = bloblang.NewField(conf.Timestamp); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp expression: %v", err)
+	}
+	if l.boffCtor, err = conf.Config.GetCtor(); err != nil {
+		return nil, err
+	}
+
+	var timeout time.Duration
+	if conf.Timeout != "" {
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	l.client = &http.Client{Timeout: timeout}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		l.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return l, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (l *lokiWriter) ConnectWithContext(ctx context.Context) error {
+	l.log.Infof("Pushing log lines to Loki at: %v\n", l.conf.URL)
+	return nil
+}
+
+func (l *lokiWriter) entry(index int, msg types.Message, now time.Time) (map[string]string, string, lokiEntry, error) {
+	labelsPart, err := l.labels.MapPart(index, msg)
+	if err != nil {
+		return nil, "", lokiEntry{}, fmt.Errorf("labels mapping failed: %w", err)
+	}
+	if labelsPart == nil {
+		return nil, "", lokiEntry{}, errors.New("labels mapping deleted the message")
+	}
+	labelsV, err := labelsPart.JSON()
+	if err != nil {
+		return nil, "", lokiEntry{}, fmt.Errorf("failed to parse labels: %w", err)
+	}
+	labels, key, err := lokiLabels(labelsV)
+	if err != nil {
+		return nil, "", lokiEntry{}, err
+	}
+
+	ts := now
+	if tsStr := l.timestamp.String(index, msg); tsStr != "" {
+		var tsV interface{} = tsStr
+		if f, err := strconv.ParseFloat(tsStr, 64); err == nil {
+			tsV = f
+		}
+		if ts, err = parsePointTimestamp(tsV); err != nil {
+			return nil, "", lokiEntry{}, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+	}
+	return labels, key, lokiEntry{
+		ts:   ts.UnixNano(),
+		line: l.line.String(index, msg),
+	}, nil
+}
+
+func (l *lokiWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	var bErr *batch.Error
+	now := time.Now()
+
+	streamIndex := map[string]int{}
+	var streams []lokiStream
+
+	msg.Iter(func(i int, _ types.Part) error {
+		labels, key, entry, err := l.entry(i, msg, now)
+		if err != nil {
+			l.log.Debugf("Failed to resolve log line: %v\n", err)
+			if bErr == nil {
+				bErr = batch.NewError(msg, err)
+			}
+			bErr.Failed(i, err)
+			return nil
+		}
+		if existing, exists := streamIndex[key]; exists {
+			streams[existing].entries = append(streams[existing].entries, entry)
+		} else {
+			streamIndex[key] = len(streams)
+			streams = append(streams, lokiStream{
+				labels:  labels,
+				entries: []lokiEntry{entry},
+			})
+		}
+		return nil
+	})
+
+	if len(streams) > 0 {
+		if err := l.push(ctx, streams); err != nil {
+			return err
+		}
+	}
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+func (l *lokiWriter) push(ctx context.Context, streams []lokiStream) error {
+	type pushStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	req := struct {
+		Streams []pushStream `json:"streams"`
+	}{}
+	for _, s := range streams {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].ts < s.entries[j].ts
+		})
+		ps := pushStream{Stream: s.labels}
+		for _, e := range s.entries {
+			ps.Values = append(ps.Values, [2]string{strconv.FormatInt(e.ts, 10), e.line})
+		}
+		req.Streams = append(req.Streams, ps)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	boff := l.boffCtor()
+	for {
+		retryable, err := l.do(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		l.log.Warnf("Retrying failed push: %v\n", err)
+		l.mRetries.Incr(1)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// do sends a push request and returns an error along with whether the request
+// should be retried.
+func (l *lokiWriter) do(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", l.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if l.conf.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.conf.TenantID)
+	}
+	for k, v := range l.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := l.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return true, err
+	}
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return false, nil
+	}
+	err = fmt.Errorf("push request returned status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500, err
+}
+
+func (l *lokiWriter) CloseAsync() {
+}
+
+func (l *lokiWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLokiPush(t *testing.T) {
+	var attempts int
+	var pushed interface{}
+	var tenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&pushed))
+		tenant = r.Header.Get("X-Scope-OrgID")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	conf := NewLokiConfig()
+	conf.URL = server.URL
+	conf.TenantID = "acme"
+	conf.Labels = `
+root.app = this.app
+root.level = this.level
+`
+	conf.Line = `${! json("msg") }`
+	conf.Timestamp = `${! json("ts") }`
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+
+	w, err := newLokiWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"app":"foo","level":"info","msg":"second","ts":1605000001}`),
+		[]byte(`{"app":"foo","level":"error","msg":"boom","ts":"2020-11-10T09:20:00Z"}`),
+		[]byte(`{"app":"foo","level":"info","msg":"first","ts":1605000000.5}`),
+		[]byte(`{"msg":"no labels"}`),
+	}))
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, map[string]interface{}{
+		"streams": []interface{}{
+			map[string]interface{}{
+				"stream": map[string]interface{}{"app": "foo", "level": "info"},
+				"values": []interface{}{
+					[]interface{}{"1605000000500000000", "first"},
+					[]interface{}{"1605000001000000000", "second"},
+				},
+			},
+			map[string]interface{}{
+				"stream": map[string]interface{}{"app": "foo", "level": "error"},
+				"values": []interface{}{
+					[]interface{}{"1605000000000000000", "boom"},
+				},
+			},
+		},
+	}, pushed)
+}
+
+func TestLokiPushClientError(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("entry out of order"))
+	}))
+	defer server.Close()
+
+	conf := NewLokiConfig()
+	conf.URL = server.URL
+
+	w, err := newLokiWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{[]byte("hello")}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "entry out of order")
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,444 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	batchPolicy "github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMongoDB] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			m, err := newMongoDBWriter(conf.MongoDB, log)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeMongoDB, conf.MongoDB.MaxInFlight, m, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.MongoDB.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Batches: true,
+		Async:   true,
+		Version: "3.39.0",
+		Categories: []Category{
+			CategoryServices,
+		},
+		Summary: `
+Inserts, updates and deletes documents in a MongoDB collection.`,
+		Description: `
+Each batch of messages is written using a single unordered ` + "`bulkWrite`" + ` call, where the operation of each message is determined by the field ` + "`operation`" + `. Since this field supports [interpolation functions](/docs/configuration/interpolation#bloblang-queries) a single batch can mix inserts, updates and deletes.
+
+The document and filter of each operation are created with the [Bloblang mappings](/docs/guides/bloblang/about) ` + "`document_map`" + ` and ` + "`filter_map`" + `. Documents are parsed as [extended JSON](https://docs.mongodb.com/manual/reference/mongodb-extended-json/), and therefore values such as ` + "`{\"$oid\":\"...\"}`" + ` are converted to their native BSON types.
+
+The document of an ` + "`update-one`" + ` or ` + "`update-many`" + ` operation must consist solely of update operators such as ` + "`$set`" + ` and ` + "`$inc`" + `.
+
+When a bulk write partially fails only the messages of the failed operations are reported as errors, which means only those messages are retried.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Counting Events",
+				Summary: `
+The following example increments a counter for each user, creating the document
+when it does not yet exist, and deletes users marked as removed:`,
+				Config: `
+output:
+  mongodb:
+    url: mongodb://localhost:27017
+    database: foodb
+    collection: users
+    operation: '${! if this.removed == true { "delete-one" } else { "update-one" } }'
+    filter_map: 'root._id = this.user_id'
+    document_map: |
+      root."$set".last_seen = this.timestamp
+      root."$inc".events = 1
+    upsert: true
+    batching:
+      count: 100
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The URL of the target MongoDB server.", "mongodb://localhost:27017"),
+			docs.FieldCommon("database", "The name of the target database."),
+			docs.FieldCommon("collection", "The name of the target collection."),
+			docs.FieldAdvanced("username", "The username to connect to the database."),
+			docs.FieldAdvanced("password", "The password to connect to the database."),
+			docs.FieldCommon(
+				"operation", "The operation to perform for each message.",
+			).HasOptions(
+				"insert-one", "delete-one", "delete-many", "replace-one", "update-one", "update-many",
+			).SupportsInterpolation(false),
+			docs.FieldCommon(
+				"document_map", "A Bloblang mapping that creates the document of an insert, replace or update operation. When left empty the raw message is used.",
+				`root = this`,
+				`root."$set".name = this.name
+root."$inc".count = 1`,
+			),
+			docs.FieldCommon(
+				"filter_map", "A Bloblang mapping that creates the filter of a delete, replace or update operation.",
+				`root._id = this.id`,
+			),
+			docs.FieldCommon("upsert", "Whether replace and update operations should insert a new document when no document matches the filter."),
+			docs.FieldAdvanced("write_concern", "The write concern settings for the collection.").WithChildren(
+				docs.FieldAdvanced("w", "The number of instances, or `majority`, that must acknowledge a write. When empty the server default is used."),
+				docs.FieldAdvanced("j", "Whether writes must be written to the on-disk journal before being acknowledged."),
+				docs.FieldAdvanced("w_timeout", "The maximum period to wait for a write concern to be satisfied."),
+			),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+			batchPolicy.FieldSpec(),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MongoDBWriteConcern describes the write concern of a MongoDB collection.
+type MongoDBWriteConcern struct {
+	W        string `json:"w" yaml:"w"`
+	J        bool   `json:"j" yaml:"j"`
+	WTimeout string `json:"w_timeout" yaml:"w_timeout"`
+}
+
+// MongoDBConfig contains configuration fields for the MongoDB output type.
+type MongoDBConfig struct {
+	URL          string                   `json:"url" yaml:"url"`
+	Database     string                   `json:"database" yaml:"database"`
+	Collection   string                   `json:"collection" yaml:"collection"`
+	Username     string                   `json:"username" yaml:"username"`
+	Password     string                   `json:"password" yaml:"password"`
+	Operation    string                   `json:"operation" yaml:"operation"`
+	DocumentMap  string                   `json:"document_map" yaml:"document_map"`
+	FilterMap    string                   `json:"filter_map" yaml:"filter_map"`
+	Upsert       bool                     `json:"upsert" yaml:"upsert"`
+	WriteConcern MongoDBWriteConcern      `json:"write_concern" yaml:"write_concern"`
+	MaxInFlight  int                      `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching     batchPolicy.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewMongoDBConfig returns a MongoDBConfig with default values.
+func NewMongoDBConfig() MongoDBConfig {
+	return MongoDBConfig{
+		URL:         "",
+		Database:    "",
+		Collection:  "",
+		Username:    "",
+		Password:    "",
+		Operation:   "insert-one",
+		DocumentMap: "",
+		FilterMap:   "",
+		Upsert:      false,
+		WriteConcern: MongoDBWriteConcern{
+			W:        "",
+			J:        false,
+			WTimeout: "",
+		},
+		MaxInFlight: 1,
+		Batching:    batchPolicy.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mongoDBOperation string
+
+const (
+	mongoDBInsertOne  mongoDBOperation = "insert-one"
+	mongoDBDeleteOne  mongoDBOperation = "delete-one"
+	mongoDBDeleteMany mongoDBOperation = "delete-many"
+	mongoDBReplaceOne mongoDBOperation = "replace-one"
+	mongoDBUpdateOne  mongoDBOperation = "update-one"
+	mongoDBUpdateMany mongoDBOperation = "update-many"
+)
+
+func (o mongoDBOperation) needsDocument() bool {
+	switch o {
+	case mongoDBInsertOne, mongoDBReplaceOne, mongoDBUpdateOne, mongoDBUpdateMany:
+		return true
+	}
+	return false
+}
+
+func (o mongoDBOperation) needsFilter() bool {
+	return o != mongoDBInsertOne
+}
+
+func parseMongoDBOperation(s string) (mongoDBOperation, error) {
+	switch o := mongoDBOperation(s); o {
+	case mongoDBInsertOne, mongoDBDeleteOne, mongoDBDeleteMany,
+		mongoDBReplaceOne, mongoDBUpdateOne, mongoDBUpdateMany:
+		return o, nil
+	}
+	return "", fmt.Errorf("operation not recognised: %v", s)
+}
+
+//------------------------------------------------------------------------------
+
+type mongoDBWriter struct {
+	log  log.Modular
+	conf MongoDBConfig
+
+	operation   field.Expression
+	documentMap *mapping.Executor
+	filterMap   *mapping.Executor
+	wc          *writeconcern.WriteConcern
+
+	client     *mongo.Client
+	collection *mongo.Collection
+	connMut    sync.RWMutex
+}
+
+func newMongoDBWriter(conf MongoDBConfig, log log.Modular) (*mongoDBWriter, error) {
+	m := &mongoDBWriter{
+		log:  log,
+		conf: conf,
+	}
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+	if conf.Database == "" || conf.Collection == "" {
+		return nil, errors.New("both a database and collection must be specified")
+	}
+
+	var err error
+	if m.operation, err = bloblang.NewField(conf.Operation); err != nil {
+		return nil, fmt.Errorf("failed to parse operation expression: %v", err)
+	}
+	if conf.DocumentMap != "" {
+		if m.documentMap, err = bloblang.NewMapping("", conf.DocumentMap); err != nil {
+			return nil, fmt.Errorf("failed to parse document_map: %v", err)
+		}
+	}
+	if conf.FilterMap != "" {
+		if m.filterMap, err = bloblang.NewMapping("", conf.FilterMap); err != nil {
+			return nil, fmt.Errorf("failed to parse filter_map: %v", err)
+		}
+	}
+
+	var wcOpts []writeconcern.Option
+	if conf.WriteConcern.W != "" {
+		if conf.WriteConcern.W == "majority" {
+			wcOpts = append(wcOpts, writeconcern.WMajority())
+		} else {
+			var w int
+			if _, err = fmt.Sscanf(conf.WriteConcern.W, "%d", &w); err != nil {
+				return nil, fmt.Errorf("failed to parse write_concern.w: %v", err)
+			}
+			wcOpts = append(wcOpts, writeconcern.W(w))
+		}
+	}
+	if conf.WriteConcern.J {
+		wcOpts = append(wcOpts, writeconcern.J(true))
+	}
+	if conf.WriteConcern.WTimeout != "" {
+		var wTimeout time.Duration
+		if wTimeout, err = time.ParseDuration(conf.WriteConcern.WTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse write_concern.w_timeout: %v", err)
+		}
+		wcOpts = append(wcOpts, writeconcern.WTimeout(wTimeout))
+	}
+	if len(wcOpts) > 0 {
+		m.wc = writeconcern.New(wcOpts...)
+	}
+	return m, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext attempts to establish a connection to the target MongoDB
+// server.
+func (m *mongoDBWriter) ConnectWithContext(ctx context.Context) error {
+	m.connMut.Lock()
+	defer m.connMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	opts := options.Client().ApplyURI(m.conf.URL)
+	if m.conf.Username != "" {
+		opts = opts.SetAuth(options.Credential{
+			Username: m.conf.Username,
+			Password: m.conf.Password,
+		})
+	}
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
+		return err
+	}
+
+	collOpts := options.Collection()
+	if m.wc != nil {
+		collOpts = collOpts.SetWriteConcern(m.wc)
+	}
+
+	m.client = client
+	m.collection = client.Database(m.conf.Database).Collection(m.conf.Collection, collOpts)
+
+	m.log.Infof("Writing messages to MongoDB collection: %v.%v\n", m.conf.Database, m.conf.Collection)
+	return nil
+}
+
+func (m *mongoDBWriter) mapBSON(exec *mapping.Executor, index int, msg types.Message) (bson.M, error) {
+	var raw []byte
+	if exec == nil {
+		raw = msg.Get(index).Get()
+	} else {
+		p, err := exec.MapPart(index, msg)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, errors.New("mapping resulted in a deleted message")
+		}
+		raw = p.Get()
+	}
+	var doc bson.M
+	if err := bson.UnmarshalExtJSON(raw, true, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse document as extended JSON: %w", err)
+	}
+	return doc, nil
+}
+
+func (m *mongoDBWriter) writeModel(index int, msg types.Message) (mongo.WriteModel, error) {
+	op, err := parseMongoDBOperation(m.operation.String(index, msg))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc, filter bson.M
+	if op.needsDocument() {
+		if doc, err = m.mapBSON(m.documentMap, index, msg); err != nil {
+			return nil, fmt.Errorf("failed to create document: %w", err)
+		}
+	}
+	if op.needsFilter() {
+		if m.filterMap == nil {
+			return nil, fmt.Errorf("operation %v requires a filter_map", op)
+		}
+		if filter, err = m.mapBSON(m.filterMap, index, msg); err != nil {
+			return nil, fmt.Errorf("failed to create filter: %w", err)
+		}
+	}
+	if op == mongoDBUpdateOne || op == mongoDBUpdateMany {
+		for k := range doc {
+			if !strings.HasPrefix(k, "$") {
+				return nil, fmt.Errorf("update document key '%v' is not an update operator", k)
+			}
+		}
+	}
+
+	switch op {
+	case mongoDBInsertOne:
+		return mongo.NewInsertOneModel().SetDocument(doc), nil
+	case mongoDBDeleteOne:
+		return mongo.NewDeleteOneModel().SetFilter(filter), nil
+	case mongoDBDeleteMany:
+		return mongo.NewDeleteManyModel().SetFilter(filter), nil
+	case mongoDBReplaceOne:
+		return mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(doc).SetUpsert(m.conf.Upsert), nil
+	case mongoDBUpdateOne:
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(doc).SetUpsert(m.conf.Upsert), nil
+	}
+	return mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(doc).SetUpsert(m.conf.Upsert), nil
+}
+
+// WriteWithContext attempts to write a batch of messages to the collection.
+func (m *mongoDBWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	m.connMut.RLock()
+	collection := m.collection
+	m.connMut.RUnlock()
+
+	if collection == nil {
+		return types.ErrNotConnected
+	}
+
+	var batchErr *batch.Error
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = batch.NewError(msg, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	var models []mongo.WriteModel
+	var modelIndexes []int
+	msg.Iter(func(i int, p types.Part) error {
+		model, err := m.writeModel(i, msg)
+		if err != nil {
+			m.log.Debugf("Failed to create write model for message: %v\n", err)
+			failed(i, err)
+			return nil
+		}
+		models = append(models, model)
+		modelIndexes = append(modelIndexes, i)
+		return nil
+	})
+
+	if len(models) > 0 {
+		_, err := collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			var bulkErr mongo.BulkWriteException
+			if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+				return err
+			}
+			for _, wErr := range bulkErr.WriteErrors {
+				if wErr.Index >= 0 && wErr.Index < len(modelIndexes) {
+					failed(modelIndexes[wErr.Index], wErr)
+				}
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// CloseAsync begins cleaning up resources used by this writer asynchronously.
+func (m *mongoDBWriter) CloseAsync() {
+	go func() {
+		m.connMut.Lock()
+		if m.client != nil {
+			m.client.Disconnect(context.Background())
+			m.client = nil
+			m.collection = nil
+		}
+		m.connMut.Unlock()
+	}()
+}
+
+// WaitForClose will block until either the writer is closed or a specified
+// timeout occurs.
+func (m *mongoDBWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
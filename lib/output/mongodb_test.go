@@ -0,0 +1,97 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoDBWriteModels(t *testing.T) {
+	conf := NewMongoDBConfig()
+	conf.URL = "mongodb://localhost:27017"
+	conf.Database = "foodb"
+	conf.Collection = "foocollection"
+	conf.Operation = `${! meta("operation") }`
+	conf.FilterMap = `root._id = this.id`
+	conf.DocumentMap = `root."$set".name = this.name
+root."$inc".count = 1`
+	conf.Upsert = true
+
+	m, err := newMongoDBWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo","name":"first"}`),
+		[]byte(`{"id":"bar","name":"second"}`),
+		[]byte(`{"id":"baz","name":"third"}`),
+	})
+	msg.Get(0).Metadata().Set("operation", "update-one")
+	msg.Get(1).Metadata().Set("operation", "delete-one")
+	msg.Get(2).Metadata().Set("operation", "nope")
+
+	model, err := m.writeModel(0, msg)
+	require.NoError(t, err)
+
+	update, ok := model.(*mongo.UpdateOneModel)
+	require.True(t, ok)
+	assert.Equal(t, bson.M{"_id": "foo"}, update.Filter)
+	assert.Equal(t, bson.M{"name": "first"}, update.Update.(bson.M)["$set"])
+	assert.True(t, *update.Upsert)
+
+	model, err = m.writeModel(1, msg)
+	require.NoError(t, err)
+
+	del, ok := model.(*mongo.DeleteOneModel)
+	require.True(t, ok)
+	assert.Equal(t, bson.M{"_id": "bar"}, del.Filter)
+
+	_, err = m.writeModel(2, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "operation not recognised")
+}
+
+func TestMongoDBUpdateRequiresOperators(t *testing.T) {
+	conf := NewMongoDBConfig()
+	conf.URL = "mongodb://localhost:27017"
+	conf.Database = "foodb"
+	conf.Collection = "foocollection"
+	conf.Operation = "update-one"
+	conf.FilterMap = `root._id = this.id`
+
+	m, err := newMongoDBWriter(conf, log.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo","name":"first"}`),
+	})
+
+	_, err = m.writeModel(0, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not an update operator")
+}
+
+func TestMongoDBConfigErrors(t *testing.T) {
+	conf := NewMongoDBConfig()
+	_, err := newMongoDBWriter(conf, log.Noop())
+	require.Error(t, err)
+
+	conf.URL = "mongodb://localhost:27017"
+	_, err = newMongoDBWriter(conf, log.Noop())
+	require.Error(t, err)
+
+	conf.Database = "foodb"
+	conf.Collection = "foocollection"
+	conf.WriteConcern.W = "nope"
+	_, err = newMongoDBWriter(conf, log.Noop())
+	require.Error(t, err)
+
+	conf.WriteConcern.W = "majority"
+	conf.WriteConcern.WTimeout = "10s"
+	_, err = newMongoDBWriter(conf, log.Noop())
+	require.NoError(t, err)
+}
@@ -0,0 +1,167 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeMSTeams] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			t, err := newMSTeamsWriter(conf.MSTeams, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncWriter(TypeMSTeams, conf.MSTeams.MaxInFlight, t, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Async:   true,
+		Summary: `
+Posts messages to a Microsoft Teams channel via an incoming webhook.`,
+		Description: `
+By default messages are posted as
+[message cards](https://docs.microsoft.com/en-us/outlook/actionable-messages/message-card-reference)
+composed of the fields ` + "`title`" + `, ` + "`text`" + ` and
+` + "`theme_color`" + `, where the text supports a subset of markdown.
+
+Alternatively, the field ` + "`card`" + ` can be used in order to post an
+[adaptive card](https://adaptivecards.io/), in which case it must resolve to a
+JSON object of the card and all other content fields are ignored.
+` + chatWebhookRateLimitDocs,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Alerts",
+				Summary: `
+Here we post an alert card for each message, coloured red:`,
+				Config: `
+output:
+  ms_teams:
+    webhook_url: ${TEAMS_WEBHOOK_URL}
+    title: 'Job ${! json("job.id") } failed'
+    text: ${! json("error") }
+    theme_color: FF0000
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("webhook_url", "The URL of the incoming webhook to post messages to."),
+			docs.FieldCommon("title", "An optional title of each message card.").SupportsInterpolation(false),
+			docs.FieldCommon("text", "The text of each message card.").SupportsInterpolation(false),
+			docs.FieldAdvanced("theme_color", "An optional hex color code of the accent of each message card.", "FF0000").SupportsInterpolation(false),
+			docs.FieldAdvanced("card", "An optional JSON object of an adaptive card to post instead of a message card.").SupportsInterpolation(false),
+		}.Merge(chatWebhookFieldSpecs()),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// MSTeamsConfig contains configuration fields for the Microsoft Teams output
+// type.
+type MSTeamsConfig struct {
+	WebhookURL  string `json:"webhook_url" yaml:"webhook_url"`
+	Title       string `json:"title" yaml:"title"`
+	Text        string `json:"text" yaml:"text"`
+	ThemeColor  string `json:"theme_color" yaml:"theme_color"`
+	Card        string `json:"card" yaml:"card"`
+	Timeout     string `json:"timeout" yaml:"timeout"`
+	MaxRetries  uint64 `json:"max_retries" yaml:"max_retries"`
+	MaxInFlight int    `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewMSTeamsConfig creates a new MSTeamsConfig with default values.
+func NewMSTeamsConfig() MSTeamsConfig {
+	return MSTeamsConfig{
+		WebhookURL:  "",
+		Title:       "",
+		Text:        "${! content() }",
+		ThemeColor:  "",
+		Card:        "",
+		Timeout:     "5s",
+		MaxRetries:  3,
+		MaxInFlight: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newMSTeamsWriter(conf MSTeamsConfig, log log.Modular, stats metrics.Type) (*chatWriter, error) {
+	if conf.WebhookURL == "" {
+		return nil, errors.New("a webhook_url must be specified")
+	}
+
+	client, err := newChatWebhookClient(conf.Timeout, conf.MaxRetries, log, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	var title, text, themeColor, card field.Expression
+	for _, f := range []struct {
+		name string
+		expr string
+		dst  *field.Expression
+	}{
+		{"title", conf.Title, &title},
+		{"text", conf.Text, &text},
+		{"theme_color", conf.ThemeColor, &themeColor},
+		{"card", conf.Card, &card},
+	} {
+		if *f.dst, err = bloblang.NewField(f.expr); err != nil {
+			return nil, fmt.Errorf("failed to parse %v expression: %v", f.name, err)
+		}
+	}
+
+	return &chatWriter{
+		service: "Microsoft Teams",
+		url:     conf.WebhookURL,
+		client:  client,
+		log:     log,
+		payload: func(index int, msg types.Message) (interface{}, error) {
+			c, err := parseChatJSONField("card", card.String(index, msg))
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				return map[string]interface{}{
+					"type": "message",
+					"attachments": []interface{}{
+						map[string]interface{}{
+							"contentType": "application/vnd.microsoft.card.adaptive",
+							"content":     c,
+						},
+					},
+				}, nil
+			}
+
+			payload := map[string]interface{}{
+				"@type":    "MessageCard",
+				"@context": "https://schema.org/extensions",
+				"text":     text.String(index, msg),
+			}
+			if t := title.String(index, msg); t != "" {
+				payload["title"] = t
+				payload["summary"] = t
+			} else {
+				payload["summary"] = payload["text"]
+			}
+			if tc := themeColor.String(index, msg); tc != "" {
+				payload["themeColor"] = tc
+			}
+			return payload, nil
+		},
+	}, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,437 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/query"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	mbatch "github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypePromRemoteWrite] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			p, err := newPromRemoteWriteWriter(conf.PromRemoteWrite, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypePromRemoteWrite, conf.PromRemoteWrite.MaxInFlight, p, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.PromRemoteWrite.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Batches: true,
+		Async:   true,
+		Summary: `
+Pushes samples extracted from messages to a
+[Prometheus remote write](https://prometheus.io/docs/prometheus/latest/storage/#remote-storage-integrations)
+endpoint, such as those provided by Cortex, Mimir, Thanos or VictoriaMetrics.`,
+		Description: `
+Each message is converted into one or more samples by executing the
+[Bloblang mapping](/docs/guides/bloblang/about) in the field
+` + "`mapping`" + `, which must result in either an object or an array of
+objects of the form:
+
+` + "```json" + `
+{
+  "name": "http_requests_total",
+  "labels": { "method": "GET", "status": "200" },
+  "value": 1027,
+  "timestamp": 1605000000.123
+}
+` + "```" + `
+
+Label values are converted to strings. The timestamp is optional and can
+either be a unix timestamp in seconds, with an optional fractional part, or a
+string in RFC 3339 format. When omitted the time at which the sample was
+converted is used.
+
+Each batch of messages is sent as a single snappy compressed write request,
+where samples that share the same name and labels are grouped into the same
+series. Messages that cannot be converted into samples are marked as failed
+individually and are not sent.
+
+Remote write endpoints reject samples that are out of order or too old, which
+results in the whole request failing with a ` + "`400`" + ` status code.
+Therefore it is recommended to keep ` + "`max_in_flight`" + ` at ` + "`1`" + `
+for ordered streams.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Request Metrics",
+				Summary: `
+Here we extract a latency sample from HTTP access logs, and push them to a
+multi-tenant Mimir cluster:`,
+				Config: `
+output:
+  prometheus_remote_write:
+    url: http://mimir:9009/api/v1/push
+    headers:
+      X-Scope-OrgID: acme
+    mapping: |
+      root.name = "http_request_duration_seconds"
+      root.labels.path = this.path
+      root.labels.status = this.status.string()
+      root.value = this.duration_ms / 1000
+      root.timestamp = this.ts
+    batching:
+      count: 1000
+      period: 5s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The URL of the remote write endpoint.", "http://localhost:9090/api/v1/write"),
+			docs.FieldCommon("mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that converts each message into one or more samples. When empty messages are expected to already be of the sample structure."),
+			docs.FieldAdvanced("headers", "A map of headers to add to each request.", map[string]string{"X-Scope-OrgID": "acme"}),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for a write request to complete."),
+			btls.FieldSpec(),
+			docs.FieldCommon("max_in_flight", "The maximum number of batches to have in flight at a given time. Increase this to improve throughput."),
+			mbatch.FieldSpec(),
+		},
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// PromRemoteWriteConfig contains configuration fields for the Prometheus
+// remote write output type.
+type PromRemoteWriteConfig struct {
+	URL         string              `json:"url" yaml:"url"`
+	Mapping     string              `json:"mapping" yaml:"mapping"`
+	Headers     map[string]string   `json:"headers" yaml:"headers"`
+	Timeout     string              `json:"timeout" yaml:"timeout"`
+	TLS         btls.Config         `json:"tls" yaml:"tls"`
+	MaxInFlight int                 `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching    mbatch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewPromRemoteWriteConfig creates a new PromRemoteWriteConfig with default
+// values.
+func NewPromRemoteWriteConfig() PromRemoteWriteConfig {
+	return PromRemoteWriteConfig{
+		URL:         "",
+		Mapping:     "",
+		Headers:     map[string]string{},
+		Timeout:     "5s",
+		TLS:         btls.NewConfig(),
+		MaxInFlight: 1,
+		Batching:    mbatch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	promMetricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	promLabelNameRegexp  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+type promLabel struct {
+	name, value string
+}
+
+type promSample struct {
+	value     float64
+	timestamp int64
+}
+
+type promSeries struct {
+	labels  []promLabel
+	samples []promSample
+}
+
+// promSamples converts the result of a mapping into a list of series, each
+// containing a single sample.
+func promSamples(v interface{}, now time.Time) ([]promSeries, error) {
+	if arr, ok := v.([]interface{}); ok {
+		var series []promSeries
+		for i, ele := range arr {
+			s, err := promSamples(ele, now)
+			if err != nil {
+				return nil, fmt.Errorf("sample %v: %w", i, err)
+			}
+			series = append(series, s...)
+		}
+		return series, nil
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected object or array, got %T", v)
+	}
+
+	name, _ := obj["name"].(string)
+	if !promMetricNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid metric name: %q", name)
+	}
+	labels := []promLabel{{name: "__name__", value: name}}
+
+	if labelsV, exists := obj["labels"]; exists && labelsV != nil {
+		labelsObj, ok := labelsV.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected labels to be an object, got %T", labelsV)
+		}
+		for k, lv := range labelsObj {
+			if !promLabelNameRegexp.MatchString(k) || strings.HasPrefix(k, "__") {
+				return nil, fmt.Errorf("invalid label name: %q", k)
+			}
+			if lv == nil {
+				continue
+			}
+			if value := query.IToString(lv); value != "" {
+				labels = append(labels, promLabel{name: k, value: value})
+			}
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+
+	value, err := query.IGetNumber(obj["value"])
+	if err != nil {
+		return nil, fmt.Errorf("value: %w", err)
+	}
+
+	ts := now
+	if tsV, exists := obj["timestamp"]; exists && tsV != nil {
+		if ts, err = parsePointTimestamp(tsV); err != nil {
+			return nil, fmt.Errorf("timestamp: %w", err)
+		}
+	}
+
+	return []promSeries{{
+		labels: labels,
+		samples: []promSample{{
+			value:     value,
+			timestamp: ts.UnixNano() / int64(time.Millisecond),
+		}},
+	}}, nil
+}
+
+// promWriteRequest encodes series as a remote write protobuf WriteRequest.
+func promWriteRequest(series []promSeries) []byte {
+	var req, ts, sub []byte
+	for _, s := range series {
+		ts = ts[:0]
+		for _, l := range s.labels {
+			sub = sub[:0]
+			sub = protowire.AppendTag(sub, 1, protowire.BytesType)
+			sub = protowire.AppendString(sub, l.name)
+			sub = protowire.AppendTag(sub, 2, protowire.BytesType)
+			sub = protowire.AppendString(sub, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sub)
+		}
+		for _, sample := range s.samples {
+			sub = sub[:0]
+			sub = protowire.AppendTag(sub, 1, protowire.Fixed64Type)
+			sub = protowire.AppendFixed64(sub, math.Float64bits(sample.value))
+			sub = protowire.AppendTag(sub, 2, protowire.VarintType)
+			sub = protowire.AppendVarint(sub, uint64(sample.timestamp))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, sub)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return req
+}
+
+//------------------------------------------------------------------------------
+
+type promRemoteWriteWriter struct {
+	conf  PromRemoteWriteConfig
+	log   log.Modular
+	stats metrics.Type
+
+	mapping *mapping.Executor
+	client  *http.Client
+
+	mSamples metrics.StatCounter
+	mInvalid metrics.StatCounter
+}
+
+func newPromRemoteWriteWriter(conf PromRemoteWriteConfig, log log.Modular, stats metrics.Type) (*promRemoteWriteWriter, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a url must be specified")
+	}
+
+	p := &promRemoteWriteWriter{
+		conf:     conf,
+		log:      log,
+		stats:    stats,
+		mSamples: stats.GetCounter("samples.sent"),
+		mInvalid: stats.GetCounter("samples.invalid"),
+	}
+
+	var err error
+	if conf.Mapping != "" {
+		if p.mapping, err = bloblang.NewMapping("", conf.Mapping); err != nil {
+			return nil, fmt.Errorf("failed to parse mapping: %v", err)
+		}
+	}
+
+	var timeout time.Duration
+	if conf.Timeout != "" {
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	p.client = &http.Client{Timeout: timeout}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		p.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *promRemoteWriteWriter) ConnectWithContext(ctx context.Context) error {
+	p.log.Infof("Pushing samples to Prometheus remote write endpoint: %v\n", p.conf.URL)
+	return nil
+}
+
+func (p *promRemoteWriteWriter) samples(index int, msg types.Message, now time.Time) ([]promSeries, error) {
+	part := msg.Get(index)
+	if p.mapping != nil {
+		var err error
+		if part, err = p.mapping.MapPart(index, msg); err != nil {
+			return nil, fmt.Errorf("mapping failed: %w", err)
+		}
+		if part == nil {
+			return nil, nil
+		}
+	}
+	v, err := part.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sample: %w", err)
+	}
+	return promSamples(v, now)
+}
+
+func (p *promRemoteWriteWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	var bErr *batch.Error
+	now := time.Now()
+
+	seriesIndex := map[string]int{}
+	var series []promSeries
+	var sampleCount int
+
+	msg.Iter(func(i int, _ types.Part) error {
+		msgSeries, err := p.samples(i, msg, now)
+		if err != nil {
+			p.mInvalid.Incr(1)
+			p.log.Debugf("Failed to convert message to samples: %v\n", err)
+			if bErr == nil {
+				bErr = batch.NewError(msg, err)
+			}
+			bErr.Failed(i, err)
+			return nil
+		}
+		for _, s := range msgSeries {
+			var key strings.Builder
+			for _, l := range s.labels {
+				key.WriteString(l.name)
+				key.WriteByte(0xff)
+				key.WriteString(l.value)
+				key.WriteByte(0xff)
+			}
+			if existing, exists := seriesIndex[key.String()]; exists {
+				series[existing].samples = append(series[existing].samples, s.samples...)
+			} else {
+				seriesIndex[key.String()] = len(series)
+				series = append(series, s)
+			}
+			sampleCount += len(s.samples)
+		}
+		return nil
+	})
+
+	if sampleCount > 0 {
+		for _, s := range series {
+			sort.SliceStable(s.samples, func(i, j int) bool {
+				return s.samples[i].timestamp < s.samples[j].timestamp
+			})
+		}
+		if err := p.write(ctx, promWriteRequest(series)); err != nil {
+			return err
+		}
+		p.mSamples.Incr(int64(sampleCount))
+	}
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+func (p *promRemoteWriteWriter) write(ctx context.Context, reqBytes []byte) error {
+	req, err := http.NewRequest("POST", p.conf.URL, bytes.NewReader(snappy.Encode(nil, reqBytes)))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "Benthos")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	for k, v := range p.conf.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("write request returned status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	return nil
+}
+
+func (p *promRemoteWriteWriter) CloseAsync() {
+}
+
+func (p *promRemoteWriteWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
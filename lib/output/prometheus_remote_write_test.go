@@ -0,0 +1,150 @@
+package output
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodePromWriteRequest decodes a WriteRequest into a human readable list of
+// series of the form `{labels} value@timestamp ...`.
+func decodePromWriteRequest(t *testing.T, b []byte) []string {
+	t.Helper()
+
+	consumeMessages := func(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.True(t, n > 0)
+			b = b[n:]
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			require.True(t, n > 0)
+			fn(num, typ, b[:n])
+			b = b[n:]
+		}
+	}
+
+	var series []string
+	consumeMessages(b, func(_ protowire.Number, _ protowire.Type, v []byte) {
+		tsBytes, n := protowire.ConsumeBytes(v)
+		require.True(t, n > 0)
+
+		var labels, samples []string
+		consumeMessages(tsBytes, func(num protowire.Number, _ protowire.Type, v []byte) {
+			inner, n := protowire.ConsumeBytes(v)
+			require.True(t, n > 0)
+
+			if num == 1 {
+				var name, value string
+				consumeMessages(inner, func(num protowire.Number, _ protowire.Type, v []byte) {
+					s, _ := protowire.ConsumeString(v)
+					if num == 1 {
+						name = s
+					} else {
+						value = s
+					}
+				})
+				labels = append(labels, name+"="+value)
+				return
+			}
+
+			var value float64
+			var ts int64
+			consumeMessages(inner, func(num protowire.Number, _ protowire.Type, v []byte) {
+				if num == 1 {
+					bits, _ := protowire.ConsumeFixed64(v)
+					value = math.Float64frombits(bits)
+				} else {
+					u, _ := protowire.ConsumeVarint(v)
+					ts = int64(u)
+				}
+			})
+			samples = append(samples, fmt.Sprintf("%v@%v", value, ts))
+		})
+		series = append(series, "{"+strings.Join(labels, ",")+"} "+strings.Join(samples, " "))
+	})
+	return series
+}
+
+func TestPromSamplesErrors(t *testing.T) {
+	tests := map[string]string{
+		`{"name":"9bad","value":1}`:                         `invalid metric name: "9bad"`,
+		`{"name":"foo","labels":{"__bar":"baz"},"value":1}`: `invalid label name: "__bar"`,
+		`{"name":"foo","value":"nope"}`:                     `value: `,
+		`[{"name":"foo","value":1},{"name":"foo"}]`:         `sample 1: value: `,
+		`{"name":"foo","value":1,"timestamp":"not a time"}`: `timestamp: `,
+	}
+
+	for input, exp := range tests {
+		v, err := message.NewPart([]byte(input)).JSON()
+		require.NoError(t, err)
+
+		_, err = promSamples(v, time.Now())
+		require.Error(t, err, input)
+		assert.True(t, strings.HasPrefix(err.Error(), exp), err.Error())
+	}
+}
+
+func TestPromRemoteWrite(t *testing.T) {
+	var reqSeries []string
+	var reqHeaders http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		decoded, err := snappy.Decode(nil, b)
+		require.NoError(t, err)
+
+		reqSeries = decodePromWriteRequest(t, decoded)
+		reqHeaders = r.Header
+	}))
+	defer server.Close()
+
+	conf := NewPromRemoteWriteConfig()
+	conf.URL = server.URL
+	conf.Headers["X-Scope-OrgID"] = "acme"
+	conf.Mapping = `
+root.name = "requests"
+root.labels.path = this.path
+root.labels.empty = null
+root.value = this.count
+root.timestamp = this.ts
+`
+
+	w, err := newPromRemoteWriteWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"path":"/b","count":3,"ts":1605000001}`),
+		[]byte(`{"path":"/a","count":1.5,"ts":1605000000.5}`),
+		[]byte(`{"path":"/b","count":2,"ts":1605000000}`),
+		[]byte(`{"path":"/c"}`),
+	}))
+	require.Error(t, err)
+
+	bErr, ok := err.(*batch.Error)
+	require.True(t, ok)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	assert.Equal(t, []string{
+		"{__name__=requests,path=/b} 2@1605000000000 3@1605000001000",
+		"{__name__=requests,path=/a} 1.5@1605000000500",
+	}, reqSeries)
+	assert.Equal(t, "snappy", reqHeaders.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", reqHeaders.Get("Content-Type"))
+	assert.Equal(t, "acme", reqHeaders.Get("X-Scope-OrgID"))
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/redis"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedisJSON] = TypeSpec{
+		constructor: fromSimpleConstructor(NewRedisJSON),
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Sets [RedisJSON](https://oss.redislabs.com/redisjson/) documents using the
+JSON.SET command.`,
+		Description: `
+The fields ` + "`key`" + `, ` + "`path`" + ` and ` + "`value`" + ` support
+[interpolation functions](/docs/configuration/interpolation#bloblang-queries),
+allowing you to set a different document or a subsection of a document for each
+message.
+
+The ` + "`value`" + ` of each message must be valid JSON.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Updating Document Fields",
+				Summary: `
+The following example sets the status field of a document identified by the id
+of each message, only when the document already exists:`,
+				Config: `
+output:
+  redis_json:
+    url: tcp://localhost:6379
+    key: users:${! json("id") }
+    path: .status
+    value: ${! json("status").format_json() }
+    condition: XX
+`,
+			},
+		},
+		Async: true,
+		FieldSpecs: redis.ConfigDocs().Add(
+			docs.FieldCommon(
+				"key", "The key for each message, function interpolations should be used to create a unique key per message.",
+				"${!meta(\"kafka_key\")}", "${!json(\"doc.id\")}",
+			).SupportsInterpolation(false),
+			docs.FieldCommon("path", "The path within the document to set.", ".", ".foo.bar").SupportsInterpolation(false),
+			docs.FieldCommon("value", "The JSON value to set at the path.").SupportsInterpolation(false),
+			docs.FieldAdvanced("condition", "An optional condition where `NX` only sets the path when it does not already exist, and `XX` only sets the path when it already exists.").HasOptions("", "NX", "XX"),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+		),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRedisJSON creates a new RedisJSON output type.
+func NewRedisJSON(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewRedisJSON(conf.RedisJSON, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	if conf.RedisJSON.MaxInFlight == 1 {
+		return NewWriter(TypeRedisJSON, w, log, stats)
+	}
+	return NewAsyncWriter(TypeRedisJSON, conf.RedisJSON.MaxInFlight, w, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -20,7 +20,11 @@ already exist) using the XADD command.`,
 		Description: `
 It's possible to specify a maximum length of the target stream by setting it to
 a value greater than 0, in which case this cap is applied only when Redis is
-able to remove a whole macro node, for efficiency.
+able to remove a whole macro node, for efficiency. Setting ` + "`max_length_exact`" + `
+to ` + "`true`" + ` enforces the cap precisely at the cost of performance.
+
+When ` + "`no_mkstream`" + ` is set to ` + "`true`" + ` messages are only added to
+streams that already exist, this requires Redis v6.2 or later.
 
 Redis stream entries are key/value pairs, as such it is necessary to specify the
 key to be set to the body of the message. All metadata fields of the message
@@ -28,9 +32,11 @@ will also be set as key/value pairs, if there is a key collision between
 a metadata item and the body then the body takes precedence.`,
 		Async: true,
 		FieldSpecs: redis.ConfigDocs().Add(
-			docs.FieldCommon("stream", "The stream to add messages to."),
+			docs.FieldCommon("stream", "The stream to add messages to.").SupportsInterpolation(false),
 			docs.FieldCommon("body_key", "A key to set the raw body of the message to."),
 			docs.FieldCommon("max_length", "When greater than zero enforces a rough cap on the length of the target stream."),
+			docs.FieldAdvanced("max_length_exact", "Whether the `max_length` cap should be enforced exactly rather than approximately."),
+			docs.FieldAdvanced("no_mkstream", "Whether to avoid creating the target stream when it does not already exist."),
 			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
 		),
 		Categories: []Category{
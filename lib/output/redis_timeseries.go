@@ -0,0 +1,84 @@
+package output
+
+import (
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/internal/service/redis"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedisTimeSeries] = TypeSpec{
+		constructor: fromSimpleConstructor(NewRedisTimeSeries),
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Adds samples to [RedisTimeSeries](https://oss.redislabs.com/redistimeseries/)
+keys using the TS.ADD command.`,
+		Description: `
+The fields ` + "`key`" + `, ` + "`timestamp`" + `, ` + "`value`" + ` and the
+values of ` + "`labels`" + ` support
+[interpolation functions](/docs/configuration/interpolation#bloblang-queries),
+which are resolved for each message.
+
+The ` + "`timestamp`" + ` must resolve to either a unix timestamp in
+milliseconds, or ` + "`*`" + ` in order to use the server time. The ` + "`value`" + `
+must resolve to a number.
+
+The ` + "`retention`" + ` and ` + "`labels`" + ` fields are only applied when a
+key is created by the command.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Sensor Readings",
+				Summary: `
+The following example adds a temperature sample for each sensor reading:`,
+				Config: `
+output:
+  redis_timeseries:
+    url: tcp://localhost:6379
+    key: temperature:${! json("sensor_id") }
+    timestamp: ${! json("ts_ms") }
+    value: ${! json("celsius") }
+    labels:
+      sensor: ${! json("sensor_id") }
+      site: ${! meta("site") }
+    retention: 168h
+    on_duplicate: LAST
+`,
+			},
+		},
+		Async: true,
+		FieldSpecs: redis.ConfigDocs().Add(
+			docs.FieldCommon("key", "The key of the time series to add a sample to.").SupportsInterpolation(false),
+			docs.FieldCommon("timestamp", "The timestamp of the sample in unix milliseconds, or `*` to use the server time.", "*", "${! json(\"ts_ms\") }").SupportsInterpolation(false),
+			docs.FieldCommon("value", "The numerical value of the sample.", "${! json(\"value\") }").SupportsInterpolation(false),
+			docs.FieldAdvanced("labels", "A map of labels to apply to newly created time series.").SupportsInterpolation(false),
+			docs.FieldAdvanced("retention", "An optional maximum age of samples of newly created time series.", "24h"),
+			docs.FieldAdvanced("on_duplicate", "An optional policy for handling samples with a timestamp that already exists.").HasOptions("", "BLOCK", "FIRST", "LAST", "MIN", "MAX", "SUM"),
+			docs.FieldCommon("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput."),
+		),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// NewRedisTimeSeries creates a new RedisTimeSeries output type.
+func NewRedisTimeSeries(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+	w, err := writer.NewRedisTimeSeries(conf.RedisTimeSeries, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	if conf.RedisTimeSeries.MaxInFlight == 1 {
+		return NewWriter(TypeRedisTimeSeries, w, log, stats)
+	}
+	return NewAsyncWriter(TypeRedisTimeSeries, conf.RedisTimeSeries.MaxInFlight, w, log, stats)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,526 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+var (
+	// ErrReplicateQuorumNotMet is returned when a message could not be written
+	// to enough destinations of a replicate output.
+	ErrReplicateQuorumNotMet = errors.New("replication quorum was not met")
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeReplicate] = TypeSpec{
+		constructor: fromSimpleConstructor(NewReplicate),
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Writes each message to multiple destinations, such as the same service hosted in
+different regions, and acknowledges the message once a quorum of the
+destinations have succeeded.`,
+		Description: `
+Messages are written to all destinations in parallel, and once the number of
+successful writes reaches ` + "`quorum`" + ` the message is acknowledged
+upstream. If enough destinations fail that the quorum can no longer be met then
+the message is rejected and will be reprocessed, in which case destinations that
+succeeded will receive the message again. A quorum of ` + "`0`" + ` requires
+all destinations to succeed.
+
+Child outputs are not retried by this output, in order to retry a destination
+until success wrap it within a ` + "[`retry`](/docs/components/outputs/retry)" + `
+output.
+
+### Conflict Metadata
+
+In order to help active-active deployments resolve conflicts, each message
+written to a destination is given the following metadata fields:
+
+` + "``` text" + `
+- replication_origin
+- replication_destination
+- replication_timestamp
+` + "```" + `
+
+Where ` + "`replication_origin`" + ` is the value of the field
+` + "`origin`" + ` (omitted when empty), ` + "`replication_destination`" + ` is
+the name of the destination and ` + "`replication_timestamp`" + ` is the time
+in RFC 3339 format at which the message was received by this output, which is
+identical for all destinations.
+
+### Outcomes
+
+When the field ` + "`outcomes`" + ` is set, once all destinations have
+completed a copy of each message is written to that output with the following
+metadata fields:
+
+` + "``` text" + `
+- replication_quorum_met
+- replication_outcome_<name>
+- replication_error_<name>
+` + "```" + `
+
+Where ` + "`replication_outcome_<name>`" + ` is either ` + "`success`" + ` or
+` + "`error`" + ` for each destination, and ` + "`replication_error_<name>`" + `
+contains the error of each failed destination.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Active-Active Kafka",
+				Summary: `
+Here we replicate messages to Kafka clusters in two regions, acknowledging
+messages once either has succeeded, and record failed replications to a file:`,
+				Config: `
+output:
+  replicate:
+    origin: eu-west-1
+    quorum: 1
+    destinations:
+      - name: eu_west_1
+        output:
+          kafka:
+            addresses: [ kafka.eu-west-1.example.com:9092 ]
+            topic: events
+      - name: us_east_1
+        output:
+          kafka:
+            addresses: [ kafka.us-east-1.example.com:9092 ]
+            topic: events
+    outcomes:
+      file:
+        path: ./replication_outcomes.jsonl
+        codec: lines
+      processors:
+        - bloblang: |
+            root = if meta("replication_outcome_us_east_1") == "success" &&
+              meta("replication_outcome_eu_west_1") == "success" { deleted() }
+`,
+			},
+		},
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			destSlice := []interface{}{}
+			for _, d := range conf.Replicate.Destinations {
+				sanOutput, err := SanitiseConfig(d.Output)
+				if err != nil {
+					return nil, err
+				}
+				destSlice = append(destSlice, map[string]interface{}{
+					"name":   d.Name,
+					"output": sanOutput,
+				})
+			}
+			var outcomesSanit interface{} = struct{}{}
+			if conf.Replicate.Outcomes != nil {
+				var err error
+				if outcomesSanit, err = SanitiseConfig(*conf.Replicate.Outcomes); err != nil {
+					return nil, err
+				}
+			}
+			return map[string]interface{}{
+				"origin":        conf.Replicate.Origin,
+				"quorum":        conf.Replicate.Quorum,
+				"destinations":  destSlice,
+				"outcomes":      outcomesSanit,
+				"max_in_flight": conf.Replicate.MaxInFlight,
+			}, nil
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("origin", "An optional identifier of where messages originate from, such as the region of this deployment, which is added to messages as metadata.", "eu-west-1"),
+			docs.FieldCommon("quorum", "The number of destinations that must succeed before a message is acknowledged. Set to `0` in order to require all destinations."),
+			docs.FieldCommon(
+				"destinations", "A list of named destinations to write messages to.",
+			).HasType(docs.FieldArray).WithChildren(
+				docs.FieldCommon("name", "A unique name of the destination, which is used within metadata fields.").HasDefault(""),
+				docs.FieldCommon("output", "An [output](/docs/components/outputs/about/) to write messages to.").HasDefault(map[string]interface{}{}),
+			),
+			docs.FieldAdvanced("outcomes", "An optional output to write messages to along with the outcome of each destination."),
+			docs.FieldCommon("max_in_flight", "The maximum number of parallel message batches to have in flight at any given time."),
+		},
+		Categories: []Category{
+			CategoryUtility,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ReplicateConfig contains configuration fields for the Replicate output type.
+type ReplicateConfig struct {
+	Origin       string                 `json:"origin" yaml:"origin"`
+	Quorum       int                    `json:"quorum" yaml:"quorum"`
+	Destinations []ReplicateDestination `json:"destinations" yaml:"destinations"`
+	Outcomes     *Config                `json:"outcomes" yaml:"outcomes"`
+	MaxInFlight  int                    `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewReplicateConfig creates a new ReplicateConfig with default values.
+func NewReplicateConfig() ReplicateConfig {
+	return ReplicateConfig{
+		Origin:       "",
+		Quorum:       0,
+		Destinations: []ReplicateDestination{},
+		Outcomes:     nil,
+		MaxInFlight:  1,
+	}
+}
+
+// UnmarshalYAML ensures that when parsing configs the default values are still
+// applied, and that an empty outcomes object, which is how configs without
+// outcomes are printed, leaves the outcomes output unset.
+func (r *ReplicateConfig) UnmarshalYAML(value *yaml.Node) error {
+	type confAlias ReplicateConfig
+	aliased := confAlias(NewReplicateConfig())
+	if err := value.Decode(&aliased); err != nil {
+		return err
+	}
+	for i := 0; i < len(value.Content)-1; i += 2 {
+		k, v := value.Content[i], value.Content[i+1]
+		if k.Value == "outcomes" && v.Kind == yaml.MappingNode && len(v.Content) == 0 {
+			aliased.Outcomes = nil
+		}
+	}
+	*r = ReplicateConfig(aliased)
+	return nil
+}
+
+// ReplicateDestination contains configuration fields for a destination of a
+// replicate output.
+type ReplicateDestination struct {
+	Name   string `json:"name" yaml:"name"`
+	Output Config `json:"output" yaml:"output"`
+}
+
+// NewReplicateDestination creates a new ReplicateDestination with default
+// values.
+func NewReplicateDestination() ReplicateDestination {
+	return ReplicateDestination{
+		Name:   "",
+		Output: NewConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Replicate is an output type that writes messages to multiple destinations
+// and acknowledges them once a quorum of destinations have succeeded.
+type Replicate struct {
+	logger log.Modular
+	stats  metrics.Type
+
+	origin      string
+	quorum      int
+	maxInFlight int
+
+	names         []string
+	outputs       []types.Output
+	outputTsChans []chan types.Transaction
+
+	outcomes       types.Output
+	outcomesTsChan chan types.Transaction
+
+	transactions <-chan types.Transaction
+
+	ctx        context.Context
+	close      func()
+	closedChan chan struct{}
+}
+
+// NewReplicate creates a new Replicate output type.
+func NewReplicate(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (Type, error) {
+	rConf := conf.Replicate
+	if len(rConf.Destinations) < 2 {
+		return nil, errors.New("attempting to create replicate output with fewer than 2 destinations")
+	}
+	if rConf.Quorum < 0 || rConf.Quorum > len(rConf.Destinations) {
+		return nil, fmt.Errorf("quorum %v must be between 0 and the number of destinations", rConf.Quorum)
+	}
+	if rConf.MaxInFlight < 1 {
+		return nil, fmt.Errorf("invalid max_in_flight: %v", rConf.MaxInFlight)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	r := &Replicate{
+		logger:      logger,
+		stats:       stats,
+		origin:      rConf.Origin,
+		quorum:      rConf.Quorum,
+		maxInFlight: rConf.MaxInFlight,
+		ctx:         ctx,
+		close:       done,
+		closedChan:  make(chan struct{}),
+	}
+	if r.quorum == 0 {
+		r.quorum = len(rConf.Destinations)
+	}
+
+	seen := map[string]struct{}{}
+	for i, dConf := range rConf.Destinations {
+		if dConf.Name == "" {
+			return nil, fmt.Errorf("destination %v must have a name", i)
+		}
+		if _, exists := seen[dConf.Name]; exists {
+			return nil, fmt.Errorf("destination name '%v' is not unique", dConf.Name)
+		}
+		seen[dConf.Name] = struct{}{}
+
+		ns := fmt.Sprintf("replicate.%v", dConf.Name)
+		output, err := New(
+			dConf.Output, mgr,
+			logger.NewModule("."+ns+".output"),
+			metrics.Combine(stats, metrics.Namespaced(stats, ns+".output")),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create destination '%v' output type '%v': %v", dConf.Name, dConf.Output.Type, err)
+		}
+		r.names = append(r.names, dConf.Name)
+		r.outputs = append(r.outputs, output)
+	}
+
+	r.outputTsChans = make([]chan types.Transaction, len(r.outputs))
+	for i := range r.outputTsChans {
+		r.outputTsChans[i] = make(chan types.Transaction)
+		if err := r.outputs[i].Consume(r.outputTsChans[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if rConf.Outcomes != nil {
+		var err error
+		if r.outcomes, err = New(
+			*rConf.Outcomes, mgr,
+			logger.NewModule(".replicate.outcomes"),
+			metrics.Combine(stats, metrics.Namespaced(stats, "replicate.outcomes")),
+		); err != nil {
+			return nil, fmt.Errorf("failed to create outcomes output type '%v': %v", rConf.Outcomes.Type, err)
+		}
+		r.outcomesTsChan = make(chan types.Transaction)
+		if err = r.outcomes.Consume(r.outcomesTsChan); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Consume assigns a new transactions channel for the output to read.
+func (r *Replicate) Consume(transactions <-chan types.Transaction) error {
+	if r.transactions != nil {
+		return types.ErrAlreadyStarted
+	}
+	r.transactions = transactions
+	go r.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether enough destinations to meet
+// the quorum are currently connected to their targets.
+func (r *Replicate) Connected() bool {
+	connected := 0
+	for _, out := range r.outputs {
+		if out.Connected() {
+			connected++
+		}
+	}
+	return connected >= r.quorum
+}
+
+//------------------------------------------------------------------------------
+
+type replicateResult struct {
+	index int
+	err   error
+}
+
+func (r *Replicate) send(tsChan chan<- types.Transaction, msg types.Message) error {
+	resChan := make(chan types.Response)
+	select {
+	case tsChan <- types.NewTransaction(msg, resChan):
+	case <-r.ctx.Done():
+		return types.ErrTypeClosed
+	}
+	select {
+	case res := <-resChan:
+		return res.Error()
+	case <-r.ctx.Done():
+		return types.ErrTypeClosed
+	}
+}
+
+func (r *Replicate) loop() {
+	var (
+		wg            = sync.WaitGroup{}
+		mMsgRcvd      = r.stats.GetCounter("replicate.messages.received")
+		mQuorumMet    = r.stats.GetCounter("replicate.quorum.met")
+		mQuorumNotMet = r.stats.GetCounter("replicate.quorum.not_met")
+		mOutcomeErr   = r.stats.GetCounter("replicate.outcomes.error")
+		mDestSuccess  = make([]metrics.StatCounter, len(r.names))
+		mDestErr      = make([]metrics.StatCounter, len(r.names))
+	)
+	for i, name := range r.names {
+		mDestSuccess[i] = r.stats.GetCounter("replicate." + name + ".success")
+		mDestErr[i] = r.stats.GetCounter("replicate." + name + ".error")
+	}
+
+	defer func() {
+		wg.Wait()
+		for i, output := range r.outputs {
+			output.CloseAsync()
+			close(r.outputTsChans[i])
+		}
+		if r.outcomes != nil {
+			r.outcomes.CloseAsync()
+			close(r.outcomesTsChan)
+		}
+		for _, output := range r.outputs {
+			for err := output.WaitForClose(time.Second); err != nil; err = output.WaitForClose(time.Second) {
+			}
+		}
+		if r.outcomes != nil {
+			for err := r.outcomes.WaitForClose(time.Second); err != nil; err = r.outcomes.WaitForClose(time.Second) {
+			}
+		}
+		close(r.closedChan)
+	}()
+
+	sendLoop := func() {
+		defer wg.Done()
+		for {
+			var ts types.Transaction
+			var open bool
+
+			select {
+			case ts, open = <-r.transactions:
+				if !open {
+					return
+				}
+			case <-r.ctx.Done():
+				return
+			}
+			mMsgRcvd.Incr(1)
+
+			timestamp := time.Now().Format(time.RFC3339Nano)
+			resultChan := make(chan replicateResult, len(r.outputs))
+			for i := range r.outputs {
+				msgCopy := ts.Payload.Copy()
+				msgCopy.Iter(func(_ int, p types.Part) error {
+					meta := p.Metadata()
+					if r.origin != "" {
+						meta.Set("replication_origin", r.origin)
+					}
+					meta.Set("replication_destination", r.names[i])
+					meta.Set("replication_timestamp", timestamp)
+					return nil
+				})
+				go func(i int) {
+					resultChan <- replicateResult{
+						index: i,
+						err:   r.send(r.outputTsChans[i], msgCopy),
+					}
+				}(i)
+			}
+
+			errs := make([]error, len(r.outputs))
+			successes, failures, responded := 0, 0, false
+			for range r.outputs {
+				res := <-resultChan
+				if errs[res.index] = res.err; res.err != nil {
+					failures++
+					mDestErr[res.index].Incr(1)
+					if res.err != types.ErrTypeClosed {
+						r.logger.Errorf("Failed to replicate message to destination '%v': %v\n", r.names[res.index], res.err)
+					}
+				} else {
+					successes++
+					mDestSuccess[res.index].Incr(1)
+				}
+
+				if responded {
+					continue
+				}
+				var oResponse types.Response
+				if successes >= r.quorum {
+					mQuorumMet.Incr(1)
+					oResponse = response.NewAck()
+				} else if failures > len(r.outputs)-r.quorum {
+					mQuorumNotMet.Incr(1)
+					oResponse = response.NewError(ErrReplicateQuorumNotMet)
+				} else {
+					continue
+				}
+				responded = true
+				select {
+				case ts.ResponseChan <- oResponse:
+				case <-r.ctx.Done():
+					return
+				}
+			}
+
+			if r.outcomes == nil {
+				continue
+			}
+			outcomeMsg := ts.Payload.Copy()
+			outcomeMsg.Iter(func(_ int, p types.Part) error {
+				meta := p.Metadata()
+				meta.Set("replication_quorum_met", strconv.FormatBool(successes >= r.quorum))
+				for i, name := range r.names {
+					if errs[i] != nil {
+						meta.Set("replication_outcome_"+name, "error")
+						meta.Set("replication_error_"+name, errs[i].Error())
+					} else {
+						meta.Set("replication_outcome_"+name, "success")
+					}
+				}
+				return nil
+			})
+			if err := r.send(r.outcomesTsChan, outcomeMsg); err != nil {
+				mOutcomeErr.Incr(1)
+				if err != types.ErrTypeClosed {
+					r.logger.Errorf("Failed to write replication outcome: %v\n", err)
+				}
+			}
+		}
+	}
+
+	for i := 0; i < r.maxInFlight; i++ {
+		wg.Add(1)
+		go sendLoop()
+	}
+}
+
+// CloseAsync shuts down the Replicate output and stops processing messages.
+func (r *Replicate) CloseAsync() {
+	r.close()
+}
+
+// WaitForClose blocks until the Replicate output has closed down.
+func (r *Replicate) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-r.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,204 @@
+package output
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func newReplicate(t *testing.T, conf Config, mockOutputs []*MockOutputType, mockOutcomes *MockOutputType) *Replicate {
+	t.Helper()
+
+	conf.Type = TypeReplicate
+	for i := range mockOutputs {
+		dest := NewReplicateDestination()
+		dest.Name = string(rune('a' + i))
+		dest.Output.Type = TypeDrop
+		conf.Replicate.Destinations = append(conf.Replicate.Destinations, dest)
+	}
+	if mockOutcomes != nil {
+		outcomes := NewConfig()
+		outcomes.Type = TypeDrop
+		conf.Replicate.Outcomes = &outcomes
+	}
+
+	genType, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	r, ok := genType.(*Replicate)
+	require.True(t, ok)
+
+	for i := 0; i < len(mockOutputs); i++ {
+		close(r.outputTsChans[i])
+		r.outputs[i] = mockOutputs[i]
+		r.outputTsChans[i] = make(chan types.Transaction)
+		mockOutputs[i].Consume(r.outputTsChans[i])
+	}
+	if mockOutcomes != nil {
+		close(r.outcomesTsChan)
+		r.outcomes = mockOutcomes
+		r.outcomesTsChan = make(chan types.Transaction)
+		mockOutcomes.Consume(r.outcomesTsChan)
+	}
+	return r
+}
+
+func replicateRespond(t *testing.T, output *MockOutputType, err error) types.Message {
+	t.Helper()
+
+	select {
+	case ts := <-output.TChan:
+		select {
+		case ts.ResponseChan <- response.NewError(err):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return ts.Payload
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestReplicateQuorum(t *testing.T) {
+	conf := NewConfig()
+	conf.Replicate.Origin = "foo"
+	conf.Replicate.Quorum = 2
+
+	outputs := []*MockOutputType{{}, {}, {}}
+	outcomes := &MockOutputType{}
+	r := newReplicate(t, conf, outputs, outcomes)
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	require.NoError(t, r.Consume(tChan))
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("hello")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	msgA := replicateRespond(t, outputs[0], nil)
+	assert.Equal(t, "hello", string(msgA.Get(0).Get()))
+	assert.Equal(t, "foo", msgA.Get(0).Metadata().Get("replication_origin"))
+	assert.Equal(t, "a", msgA.Get(0).Metadata().Get("replication_destination"))
+
+	msgB := replicateRespond(t, outputs[1], errors.New("nope"))
+	assert.Equal(t, "b", msgB.Get(0).Metadata().Get("replication_destination"))
+	assert.Equal(t,
+		msgA.Get(0).Metadata().Get("replication_timestamp"),
+		msgB.Get(0).Metadata().Get("replication_timestamp"),
+	)
+
+	replicateRespond(t, outputs[2], nil)
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	outcome := replicateRespond(t, outcomes, nil)
+	meta := outcome.Get(0).Metadata()
+	assert.Equal(t, "true", meta.Get("replication_quorum_met"))
+	assert.Equal(t, "success", meta.Get("replication_outcome_a"))
+	assert.Equal(t, "error", meta.Get("replication_outcome_b"))
+	assert.Equal(t, "nope", meta.Get("replication_error_b"))
+	assert.Equal(t, "success", meta.Get("replication_outcome_c"))
+	assert.Equal(t, "", outcome.Get(0).Metadata().Get("replication_destination"))
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second*5))
+}
+
+func TestReplicateQuorumNotMet(t *testing.T) {
+	conf := NewConfig()
+
+	outputs := []*MockOutputType{{}, {}}
+	r := newReplicate(t, conf, outputs, nil)
+
+	tChan := make(chan types.Transaction)
+	resChan := make(chan types.Response)
+	require.NoError(t, r.Consume(tChan))
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte("hello")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	msg := replicateRespond(t, outputs[0], errors.New("nope"))
+	assert.Equal(t, "", msg.Get(0).Metadata().Get("replication_origin"))
+
+	// The quorum of all destinations can no longer be met, so the response
+	// must arrive before the remaining destination completes.
+	select {
+	case res := <-resChan:
+		assert.Equal(t, ErrReplicateQuorumNotMet, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	replicateRespond(t, outputs[1], nil)
+
+	r.CloseAsync()
+	require.NoError(t, r.WaitForClose(time.Second*5))
+}
+
+func TestReplicateConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeReplicate
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	for i := 0; i < 2; i++ {
+		dest := NewReplicateDestination()
+		dest.Name = "foo"
+		dest.Output.Type = TypeDrop
+		conf.Replicate.Destinations = append(conf.Replicate.Destinations, dest)
+	}
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not unique")
+
+	conf.Replicate.Destinations[1].Name = "bar"
+	conf.Replicate.Quorum = 3
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "quorum")
+}
+
+func TestReplicateConfigOutcomesRoundTrip(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeReplicate
+
+	sanit, err := SanitiseConfig(conf)
+	require.NoError(t, err)
+
+	confBytes, err := yaml.Marshal(sanit)
+	require.NoError(t, err)
+	assert.Contains(t, string(confBytes), "outcomes: {}")
+
+	parsed := NewConfig()
+	require.NoError(t, yaml.Unmarshal(confBytes, &parsed))
+	assert.Nil(t, parsed.Replicate.Outcomes)
+
+	require.NoError(t, yaml.Unmarshal([]byte(`
+replicate:
+  outcomes:
+    drop: {}
+`), &parsed))
+	require.NotNil(t, parsed.Replicate.Outcomes)
+	assert.Equal(t, TypeDrop, parsed.Replicate.Outcomes.Type)
+}
@@ -0,0 +1,29 @@
+package output
+
+import (
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// retryStateGauges returns a closure for reporting changes to the state of a
+// retry throttle as gauges, where the circuit of a component is open whilst
+// it is backing off.
+func retryStateGauges(stats metrics.Type) func(consecutiveRetries int64, backoff time.Duration) {
+	mFailures := stats.GetGauge("connection.consecutive_failures")
+	mBackoff := stats.GetGauge("connection.backoff_ms")
+	mCircuitOpen := stats.GetGauge("connection.circuit_open")
+	return func(consecutiveRetries int64, backoff time.Duration) {
+		mFailures.Set(consecutiveRetries)
+		mBackoff.Set(int64(backoff / time.Millisecond))
+		if backoff > 0 {
+			mCircuitOpen.Set(1)
+		} else {
+			mCircuitOpen.Set(0)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,203 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+func init() {
+	Constructors[TypeSlack] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			s, err := newSlackWriter(conf.Slack, slackPostMessageURL, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return NewAsyncWriter(TypeSlack, conf.Slack.MaxInFlight, s, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Async:   true,
+		Summary: `
+Posts messages to a Slack channel, either via an incoming webhook or the
+` + "`chat.postMessage`" + ` API method.`,
+		Description: `
+When ` + "`webhook_url`" + ` is set messages are posted to the channel of an
+[incoming webhook](https://api.slack.com/messaging/webhooks). Alternatively,
+when ` + "`token`" + ` is set messages are posted with the
+[` + "`chat.postMessage`" + `](https://api.slack.com/methods/chat.postMessage)
+API method to the channel given by the field ` + "`channel`" + `, which also
+allows replying within threads. Exactly one of ` + "`webhook_url`" + ` and
+` + "`token`" + ` must be set.
+
+The field ` + "`blocks`" + ` can be used in order to post messages with
+[layout blocks](https://api.slack.com/block-kit), in which case it must resolve
+to a JSON array of blocks and the field ` + "`text`" + ` is used as a fallback
+for notifications.
+` + chatWebhookRateLimitDocs,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Alerts With Blocks",
+				Summary: `
+Here we generate layout blocks for each message with a
+[Bloblang](/docs/guides/bloblang/about) mapping, and post them as alerts to a
+channel via a webhook:`,
+				Config: `
+pipeline:
+  processors:
+    - bloblang: |
+        meta alert_text = "Job %v failed".format(this.job.id)
+        root = [
+          {
+            "type": "section",
+            "text": {
+              "type": "mrkdwn",
+              "text": "*Job %v failed*\n%v".format(this.job.id, this.error)
+            }
+          }
+        ]
+
+output:
+  slack:
+    webhook_url: ${SLACK_WEBHOOK_URL}
+    text: ${! meta("alert_text") }
+    blocks: ${! content() }
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("webhook_url", "The URL of an incoming webhook to post messages to."),
+			docs.FieldCommon("token", "A bot or user token to post messages with via the `chat.postMessage` API method."),
+			docs.FieldCommon("channel", "The channel to post messages to when using a token.", "#alerts", "C1234567890").SupportsInterpolation(false),
+			docs.FieldCommon("text", "The text of each message, or the fallback text for notifications when blocks are set.").SupportsInterpolation(false),
+			docs.FieldCommon("blocks", "An optional JSON array of layout blocks of each message.").SupportsInterpolation(false),
+			docs.FieldAdvanced("thread_ts", "The timestamp of a parent message to reply to within a thread when using a token.").SupportsInterpolation(false),
+		}.Merge(chatWebhookFieldSpecs()),
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SlackConfig contains configuration fields for the Slack output type.
+type SlackConfig struct {
+	WebhookURL  string `json:"webhook_url" yaml:"webhook_url"`
+	Token       string `json:"token" yaml:"token"`
+	Channel     string `json:"channel" yaml:"channel"`
+	Text        string `json:"text" yaml:"text"`
+	Blocks      string `json:"blocks" yaml:"blocks"`
+	ThreadTS    string `json:"thread_ts" yaml:"thread_ts"`
+	Timeout     string `json:"timeout" yaml:"timeout"`
+	MaxRetries  uint64 `json:"max_retries" yaml:"max_retries"`
+	MaxInFlight int    `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewSlackConfig creates a new SlackConfig with default values.
+func NewSlackConfig() SlackConfig {
+	return SlackConfig{
+		WebhookURL:  "",
+		Token:       "",
+		Channel:     "",
+		Text:        "${! content() }",
+		Blocks:      "",
+		ThreadTS:    "",
+		Timeout:     "5s",
+		MaxRetries:  3,
+		MaxInFlight: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newSlackWriter(conf SlackConfig, apiURL string, log log.Modular, stats metrics.Type) (*chatWriter, error) {
+	if (conf.WebhookURL == "") == (conf.Token == "") {
+		return nil, errors.New("exactly one of webhook_url or token must be specified")
+	}
+	if conf.Token != "" && conf.Channel == "" {
+		return nil, errors.New("a channel must be specified when using a token")
+	}
+
+	client, err := newChatWebhookClient(conf.Timeout, conf.MaxRetries, log, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	var channel, text, blocks, threadTS field.Expression
+	for _, f := range []struct {
+		name string
+		expr string
+		dst  *field.Expression
+	}{
+		{"channel", conf.Channel, &channel},
+		{"text", conf.Text, &text},
+		{"blocks", conf.Blocks, &blocks},
+		{"thread_ts", conf.ThreadTS, &threadTS},
+	} {
+		if *f.dst, err = bloblang.NewField(f.expr); err != nil {
+			return nil, fmt.Errorf("failed to parse %v expression: %v", f.name, err)
+		}
+	}
+
+	w := &chatWriter{
+		service: "Slack",
+		url:     conf.WebhookURL,
+		client:  client,
+		log:     log,
+	}
+	if conf.Token != "" {
+		w.url = apiURL
+		w.headers = map[string]string{
+			"Authorization": "Bearer " + conf.Token,
+		}
+		// The Web API reports errors within responses with a 200 status code.
+		client.checkResponse = func(body []byte) error {
+			var res struct {
+				OK    bool   `json:"ok"`
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("failed to parse response: %v", err)
+			}
+			if !res.OK {
+				return fmt.Errorf("chat.postMessage failed: %v", res.Error)
+			}
+			return nil
+		}
+	}
+
+	w.payload = func(index int, msg types.Message) (interface{}, error) {
+		payload := map[string]interface{}{
+			"text": text.String(index, msg),
+		}
+		b, err := parseChatJSONField("blocks", blocks.String(index, msg))
+		if err != nil {
+			return nil, err
+		}
+		if b != nil {
+			payload["blocks"] = b
+		}
+		if conf.Token != "" {
+			payload["channel"] = channel.String(index, msg)
+			if ts := threadTS.String(index, msg); ts != "" {
+				payload["thread_ts"] = ts
+			}
+		}
+		return payload, nil
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
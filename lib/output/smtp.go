@@ -0,0 +1,589 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output/writer"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSMTP] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			s, err := newSMTPWriter(conf.SMTP, mgr, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeSMTP, conf.SMTP.MaxInFlight, s, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.SMTP.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Batches: true,
+		Async:   true,
+		Summary: `
+Sends messages as emails to an SMTP server.`,
+		Description: `
+The sender, recipients, subject and body of each email can be set with
+[interpolation functions](/docs/configuration/interpolation#bloblang-queries),
+which makes it possible to compose alerts from the contents of messages.
+
+By default each message of a batch is sent as its own email. When
+` + "`attachments.enabled`" + ` is set to ` + "`true`" + ` a single email is
+sent for each batch instead, where the interpolations of all fields other than
+the attachment fields are resolved from the first message of the batch, and
+the raw contents of every message of the batch are added to the email as
+attachments.
+
+### Rate Limiting
+
+Alerting pipelines can produce bursts of emails, which can be limited by
+referencing a [rate limit resource](/docs/components/rate_limits/about) with
+the field ` + "`rate_limit`" + `. Each email sent consumes a single access of
+the rate limit, and emails are delayed until access is granted.
+
+### Encryption and Authentication
+
+When ` + "`tls.enabled`" + ` is ` + "`true`" + ` the connection is encrypted
+from the start (implicit TLS, usually on port 465). Otherwise, when
+` + "`starttls`" + ` is ` + "`true`" + ` and the server advertises the
+` + "`STARTTLS`" + ` extension, the connection is upgraded before
+authenticating, using the remaining ` + "`tls`" + ` fields.
+
+Authentication is performed when ` + "`auth.username`" + ` is set. The
+` + "`plain`" + ` mechanism is refused over connections that are not encrypted
+unless the server is running on localhost.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Alerting",
+				Summary: `
+In this example we send an email for each message describing a failed job,
+where at most one email is sent every ten seconds:`,
+				Config: `
+output:
+  smtp:
+    address: smtp.example.com:587
+    from: Benthos Alerts <alerts@example.com>
+    to: [ oncall@example.com ]
+    subject: 'Job ${! json("job.id") } failed'
+    body: |
+      Job ${! json("job.id") } failed at ${! json("timestamp") }:
+      ${! json("error") }
+    auth:
+      username: alerts@example.com
+      password: ${SMTP_PASSWORD}
+    rate_limit: email_limit
+
+resources:
+  rate_limits:
+    email_limit:
+      local:
+        count: 1
+        interval: 10s
+`,
+			},
+			{
+				Title: "Batched Reports",
+				Summary: `
+Here we collect messages into batches of up to a hundred over an hour, and send
+each batch as a single email where each message is attached as its own file:`,
+				Config: `
+output:
+  smtp:
+    address: smtp.example.com:587
+    from: reports@example.com
+    to: [ team@example.com ]
+    subject: 'Hourly report (${! batch_size() } documents)'
+    body: Please find the documents of the last hour attached.
+    attachments:
+      enabled: true
+      filename: '${! json("id") }.json'
+      content_type: application/json
+    batching:
+      count: 100
+      period: 1h
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("address", "The address of the SMTP server to connect to, including the port.", "localhost:587", "smtp.example.com:465"),
+			docs.FieldCommon("from", "The sender of emails.", "alerts@example.com", "Benthos Alerts <alerts@example.com>").SupportsInterpolation(false),
+			docs.FieldCommon("to", "A list of recipients of emails, each of which may resolve to a comma separated list of addresses.", []string{"oncall@example.com"}).SupportsInterpolation(false),
+			docs.FieldCommon("subject", "The subject of emails.").SupportsInterpolation(false),
+			docs.FieldCommon("body", "The body of emails.").SupportsInterpolation(false),
+			docs.FieldAdvanced("content_type", "The content type of email bodies.", "text/plain; charset=utf-8", "text/html; charset=utf-8"),
+			docs.FieldAdvanced("attachments", "Send each batch as a single email where each message is attached as a file.").WithChildren(
+				docs.FieldCommon("enabled", "Whether to send batches with messages as attachments."),
+				docs.FieldCommon("filename", "The file name of each attachment.").SupportsInterpolation(false),
+				docs.FieldCommon("content_type", "The content type of each attachment.").SupportsInterpolation(false),
+			),
+			docs.FieldAdvanced("starttls", "Whether to upgrade the connection with `STARTTLS` when it is supported by the server and `tls.enabled` is `false`."),
+			btls.FieldSpec(),
+			docs.FieldAdvanced("auth", "Optional authentication with the server.").WithChildren(
+				docs.FieldCommon("mechanism", "The authentication mechanism to use.").HasOptions("plain", "cram-md5"),
+				docs.FieldCommon("username", "A username to authenticate with, authentication is disabled when empty."),
+				docs.FieldCommon("password", "A password to authenticate with, or the secret for `cram-md5`."),
+			),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for an email to be sent."),
+			docs.FieldAdvanced("rate_limit", "An optional [rate limit resource](/docs/components/rate_limits/about) to throttle emails by."),
+			docs.FieldCommon("max_in_flight", "The maximum number of emails to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		},
+		Categories: []Category{
+			CategoryNetwork,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SMTPAttachmentsConfig contains configuration fields for sending batches as
+// attachments of a single email.
+type SMTPAttachmentsConfig struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	Filename    string `json:"filename" yaml:"filename"`
+	ContentType string `json:"content_type" yaml:"content_type"`
+}
+
+// SMTPAuthConfig contains configuration fields for authenticating with an
+// SMTP server.
+type SMTPAuthConfig struct {
+	Mechanism string `json:"mechanism" yaml:"mechanism"`
+	Username  string `json:"username" yaml:"username"`
+	Password  string `json:"password" yaml:"password"`
+}
+
+// SMTPConfig contains configuration fields for the SMTP output type.
+type SMTPConfig struct {
+	Address     string                `json:"address" yaml:"address"`
+	From        string                `json:"from" yaml:"from"`
+	To          []string              `json:"to" yaml:"to"`
+	Subject     string                `json:"subject" yaml:"subject"`
+	Body        string                `json:"body" yaml:"body"`
+	ContentType string                `json:"content_type" yaml:"content_type"`
+	Attachments SMTPAttachmentsConfig `json:"attachments" yaml:"attachments"`
+	StartTLS    bool                  `json:"starttls" yaml:"starttls"`
+	TLS         btls.Config           `json:"tls" yaml:"tls"`
+	Auth        SMTPAuthConfig        `json:"auth" yaml:"auth"`
+	Timeout     string                `json:"timeout" yaml:"timeout"`
+	RateLimit   string                `json:"rate_limit" yaml:"rate_limit"`
+	MaxInFlight int                   `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching    batch.PolicyConfig    `json:"batching" yaml:"batching"`
+}
+
+// NewSMTPConfig creates a new SMTPConfig with default values.
+func NewSMTPConfig() SMTPConfig {
+	return SMTPConfig{
+		Address:     "localhost:587",
+		From:        "",
+		To:          []string{},
+		Subject:     "",
+		Body:        "${! content() }",
+		ContentType: "text/plain; charset=utf-8",
+		Attachments: SMTPAttachmentsConfig{
+			Enabled:     false,
+			Filename:    "${! batch_index() }.txt",
+			ContentType: "application/octet-stream",
+		},
+		StartTLS: true,
+		TLS:      btls.NewConfig(),
+		Auth: SMTPAuthConfig{
+			Mechanism: "plain",
+			Username:  "",
+			Password:  "",
+		},
+		Timeout:     "10s",
+		RateLimit:   "",
+		MaxInFlight: 1,
+		Batching:    batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type smtpWriter struct {
+	conf  SMTPConfig
+	log   log.Modular
+	stats metrics.Type
+
+	host      string
+	from      field.Expression
+	to        []field.Expression
+	subject   field.Expression
+	body      field.Expression
+	attName   field.Expression
+	attType   field.Expression
+	timeout   time.Duration
+	tlsConf   *tls.Config
+	auth      smtp.Auth
+	rateLimit types.RateLimit
+
+	mLimited  metrics.StatCounter
+	mLimitFor metrics.StatCounter
+	mLimitErr metrics.StatCounter
+	mSent     metrics.StatCounter
+}
+
+func newSMTPWriter(conf SMTPConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*smtpWriter, error) {
+	if conf.Address == "" {
+		return nil, errors.New("an address must be specified")
+	}
+	if len(conf.To) == 0 {
+		return nil, errors.New("at least one recipient must be specified")
+	}
+
+	s := &smtpWriter{
+		conf:      conf,
+		log:       log,
+		stats:     stats,
+		mLimited:  stats.GetCounter("rate_limit.count"),
+		mLimitFor: stats.GetCounter("rate_limit.total_ms"),
+		mLimitErr: stats.GetCounter("rate_limit.error"),
+		mSent:     stats.GetCounter("emails.sent"),
+	}
+
+	var err error
+	if s.host, _, err = net.SplitHostPort(conf.Address); err != nil {
+		return nil, fmt.Errorf("failed to parse address: %v", err)
+	}
+
+	parse := func(name, expr string) (field.Expression, error) {
+		e, err := bloblang.NewField(expr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v expression: %v", name, err)
+		}
+		return e, nil
+	}
+	if s.from, err = parse("from", conf.From); err != nil {
+		return nil, err
+	}
+	for _, t := range conf.To {
+		e, err := parse("to", t)
+		if err != nil {
+			return nil, err
+		}
+		s.to = append(s.to, e)
+	}
+	if s.subject, err = parse("subject", conf.Subject); err != nil {
+		return nil, err
+	}
+	if s.body, err = parse("body", conf.Body); err != nil {
+		return nil, err
+	}
+	if s.attName, err = parse("attachment filename", conf.Attachments.Filename); err != nil {
+		return nil, err
+	}
+	if s.attType, err = parse("attachment content type", conf.Attachments.ContentType); err != nil {
+		return nil, err
+	}
+
+	if conf.Timeout != "" {
+		if s.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+
+	if conf.TLS.Enabled || conf.StartTLS {
+		if s.tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+		if s.tlsConf.ServerName == "" {
+			s.tlsConf.ServerName = s.host
+		}
+	}
+
+	if conf.Auth.Username != "" {
+		switch conf.Auth.Mechanism {
+		case "plain":
+			s.auth = smtp.PlainAuth("", conf.Auth.Username, conf.Auth.Password, s.host)
+		case "cram-md5":
+			s.auth = smtp.CRAMMD5Auth(conf.Auth.Username, conf.Auth.Password)
+		default:
+			return nil, fmt.Errorf("auth mechanism not recognised: %v", conf.Auth.Mechanism)
+		}
+	}
+
+	if conf.RateLimit != "" {
+		if s.rateLimit, err = mgr.GetRateLimit(conf.RateLimit); err != nil {
+			return nil, fmt.Errorf("failed to obtain rate limit resource: %v", err)
+		}
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *smtpWriter) ConnectWithContext(ctx context.Context) error {
+	s.log.Infof("Sending messages as emails to SMTP server: %v\n", s.conf.Address)
+	return nil
+}
+
+func (s *smtpWriter) waitForAccess(ctx context.Context) error {
+	if s.rateLimit == nil {
+		return nil
+	}
+	for {
+		period, err := s.rateLimit.Access()
+		if err != nil {
+			s.log.Errorf("Rate limit error: %v\n", err)
+			s.mLimitErr.Incr(1)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		if err == nil {
+			s.mLimited.Incr(1)
+			s.mLimitFor.Incr(period.Nanoseconds() / 1000000)
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+type smtpAttachment struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+type smtpEmail struct {
+	from        *mail.Address
+	to          []*mail.Address
+	subject     string
+	body        string
+	attachments []smtpAttachment
+}
+
+func (s *smtpWriter) newEmail(index int, msg types.Message) (*smtpEmail, error) {
+	from, err := mail.ParseAddress(s.from.String(index, msg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse from address: %w", err)
+	}
+	e := &smtpEmail{
+		from:    from,
+		subject: s.subject.String(index, msg),
+		body:    s.body.String(index, msg),
+	}
+	for _, t := range s.to {
+		toStr := strings.TrimSpace(t.String(index, msg))
+		if toStr == "" {
+			continue
+		}
+		to, err := mail.ParseAddressList(toStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse to address: %w", err)
+		}
+		e.to = append(e.to, to...)
+	}
+	if len(e.to) == 0 {
+		return nil, errors.New("email has no recipients")
+	}
+	return e, nil
+}
+
+// bytes encodes the email as a MIME message.
+func (e *smtpEmail) bytes(contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	toStrs := make([]string, len(e.to))
+	for i, t := range e.to {
+		toStrs[i] = t.String()
+	}
+
+	fmt.Fprintf(&buf, "From: %v\r\n", e.from.String())
+	fmt.Fprintf(&buf, "To: %v\r\n", strings.Join(toStrs, ", "))
+	fmt.Fprintf(&buf, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", e.subject))
+	fmt.Fprintf(&buf, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	writeBody := func(w io.Writer) error {
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(e.body)); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+
+	if len(e.attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %v\r\n", contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeBody(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%v\r\n\r\n", mw.Boundary())
+
+	bodyHeader := textproto.MIMEHeader{}
+	bodyHeader.Set("Content-Type", contentType)
+	bodyHeader.Set("Content-Transfer-Encoding", "quoted-printable")
+	pw, err := mw.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeBody(pw); err != nil {
+		return nil, err
+	}
+
+	for _, a := range e.attachments {
+		attHeader := textproto.MIMEHeader{}
+		attHeader.Set("Content-Type", a.contentType)
+		attHeader.Set("Content-Transfer-Encoding", "base64")
+		attHeader.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": a.filename,
+		}))
+		if pw, err = mw.CreatePart(attHeader); err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(a.data)
+		for len(encoded) > 76 {
+			if _, err = pw.Write([]byte(encoded[:76] + "\r\n")); err != nil {
+				return nil, err
+			}
+			encoded = encoded[76:]
+		}
+		if _, err = pw.Write([]byte(encoded + "\r\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err = mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *smtpWriter) send(ctx context.Context, e *smtpEmail) error {
+	data, err := e.bytes(s.conf.ContentType)
+	if err != nil {
+		return err
+	}
+	if err = s.waitForAccess(ctx); err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: s.timeout}
+	var conn net.Conn
+	if s.conf.TLS.Enabled {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.conf.Address, s.tlsConf)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.conf.Address)
+	}
+	if err != nil {
+		return err
+	}
+	if s.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if !s.conf.TLS.Enabled && s.conf.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err = client.StartTLS(s.tlsConf); err != nil {
+				return fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+	if s.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("server does not support authentication")
+		}
+		if err = client.Auth(s.auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err = client.Mail(e.from.Address); err != nil {
+		return err
+	}
+	for _, t := range e.to {
+		if err = client.Rcpt(t.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	s.mSent.Incr(1)
+	return client.Quit()
+}
+
+func (s *smtpWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	if !s.conf.Attachments.Enabled {
+		return writer.IterateBatchedSend(msg, func(i int, p types.Part) error {
+			e, err := s.newEmail(i, msg)
+			if err != nil {
+				return err
+			}
+			return s.send(ctx, e)
+		})
+	}
+
+	e, err := s.newEmail(0, msg)
+	if err != nil {
+		return err
+	}
+	msg.Iter(func(i int, p types.Part) error {
+		e.attachments = append(e.attachments, smtpAttachment{
+			filename:    s.attName.String(i, msg),
+			contentType: s.attType.String(i, msg),
+			data:        p.Get(),
+		})
+		return nil
+	})
+	return s.send(ctx, e)
+}
+
+func (s *smtpWriter) CloseAsync() {
+}
+
+func (s *smtpWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
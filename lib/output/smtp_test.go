@@ -0,0 +1,211 @@
+package output
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSMTPEmail struct {
+	from string
+	to   []string
+	data []byte
+}
+
+type testSMTPServer struct {
+	mut    sync.Mutex
+	emails []testSMTPEmail
+}
+
+func (s *testSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+
+	var current testSMTPEmail
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "MAIL":
+			current = testSMTPEmail{from: strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")}
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			current.to = append(current.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			if current.data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.emails = append(s.emails, current)
+			s.mut.Unlock()
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func startTestSMTPServer(t *testing.T) (*testSMTPServer, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ln.Close()
+	})
+
+	s := &testSMTPServer{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s, ln.Addr().String()
+}
+
+func (s *testSMTPServer) received() []testSMTPEmail {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]testSMTPEmail(nil), s.emails...)
+}
+
+func TestSMTPConfigErrs(t *testing.T) {
+	conf := NewSMTPConfig()
+	_, err := newSMTPWriter(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.To = []string{"foo@example.com"}
+	conf.Auth.Username = "foo"
+	conf.Auth.Mechanism = "nope"
+	_, err = newSMTPWriter(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Auth.Mechanism = "plain"
+	conf.RateLimit = "does not exist"
+	_, err = newSMTPWriter(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestSMTPSendEach(t *testing.T) {
+	server, addr := startTestSMTPServer(t)
+
+	conf := NewSMTPConfig()
+	conf.Address = addr
+	conf.From = "Benthos <benthos@example.com>"
+	conf.To = []string{`${! meta("to") }`, "ops@example.com"}
+	conf.Subject = `Alert: ${! json("name") }`
+	conf.Body = `${! json("desc") }`
+
+	w, err := newSMTPWriter(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	require.NoError(t, w.ConnectWithContext(context.Background()))
+
+	msg := message.New([][]byte{
+		[]byte(`{"name":"foo","desc":"foo is broken"}`),
+		[]byte(`{"name":"bar","desc":"bar is broken"}`),
+	})
+	msg.Get(0).Metadata().Set("to", "a@example.com, b@example.com")
+	msg.Get(1).Metadata().Set("to", "c@example.com")
+	require.NoError(t, w.WriteWithContext(context.Background(), msg))
+
+	emails := server.received()
+	require.Len(t, emails, 2)
+
+	assert.Equal(t, "benthos@example.com", emails[0].from)
+	assert.Equal(t, []string{"a@example.com", "b@example.com", "ops@example.com"}, emails[0].to)
+	assert.Equal(t, []string{"c@example.com", "ops@example.com"}, emails[1].to)
+
+	m, err := mail.ReadMessage(strings.NewReader(string(emails[1].data)))
+	require.NoError(t, err)
+	assert.Equal(t, "Alert: bar", m.Header.Get("Subject"))
+	assert.Equal(t, `"Benthos" <benthos@example.com>`, m.Header.Get("From"))
+
+	body, err := ioutil.ReadAll(m.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "bar is broken", strings.TrimSpace(string(body)))
+}
+
+func TestSMTPSendAttachments(t *testing.T) {
+	server, addr := startTestSMTPServer(t)
+
+	conf := NewSMTPConfig()
+	conf.Address = addr
+	conf.From = "benthos@example.com"
+	conf.To = []string{"ops@example.com"}
+	conf.Subject = `Report of ${! batch_size() }`
+	conf.Body = "See attached"
+	conf.Attachments.Enabled = true
+	conf.Attachments.Filename = `${! json("id") }.json`
+	conf.Attachments.ContentType = "application/json"
+
+	w, err := newSMTPWriter(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte(`{"id":"foo"}`),
+		[]byte(`{"id":"bar"}`),
+	})))
+
+	emails := server.received()
+	require.Len(t, emails, 1)
+
+	m, err := mail.ReadMessage(strings.NewReader(string(emails[0].data)))
+	require.NoError(t, err)
+	assert.Equal(t, "Report of 2", m.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	p, err := mr.NextPart()
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(p)
+	require.NoError(t, err)
+	assert.Equal(t, "See attached", string(body))
+
+	for _, exp := range []string{"foo", "bar"} {
+		p, err = mr.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, exp+".json", p.FileName())
+		assert.Equal(t, "application/json", p.Header.Get("Content-Type"))
+
+		encoded, err := ioutil.ReadAll(p)
+		require.NoError(t, err)
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+		require.NoError(t, err)
+		assert.Equal(t, `{"id":"`+exp+`"}`, string(decoded))
+	}
+}
@@ -0,0 +1,457 @@
+package output
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSplunkHEC] = TypeSpec{
+		constructor: fromSimpleConstructor(func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (Type, error) {
+			s, err := newSplunkHECWriter(conf.SplunkHEC, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			w, err := NewAsyncWriter(TypeSplunkHEC, conf.SplunkHEC.MaxInFlight, s, log, stats)
+			if err != nil {
+				return nil, err
+			}
+			return newBatcherFromConf(conf.SplunkHEC.Batching, w, mgr, log, stats)
+		}),
+		Status:  docs.StatusExperimental,
+		Version: "3.39.0",
+		Batches: true,
+		Async:   true,
+		Summary: `
+Sends messages to a Splunk HTTP Event Collector (HEC).`,
+		Description: `
+Each batch of messages is sent as a single request to either the
+` + "`event`" + ` or the ` + "`raw`" + ` endpoint of the collector.
+
+With the ` + "`event`" + ` endpoint each message becomes an event, where
+messages that are valid JSON documents are sent as structured events and all
+other messages are sent as strings. The fields ` + "`index`" + `,
+` + "`source`" + `, ` + "`sourcetype`" + ` and ` + "`host`" + ` are resolved
+for each message individually.
+
+With the ` + "`raw`" + ` endpoint the messages of a batch are joined with
+line breaks and sent as a single payload, which Splunk then breaks into events
+according to the configuration of the source type. In this case the fields
+` + "`index`" + `, ` + "`source`" + `, ` + "`sourcetype`" + ` and
+` + "`host`" + ` are resolved once for each batch from the first message.
+
+### Acknowledgements
+
+By default a batch is considered delivered once the collector accepts the
+request. However, an accepted request is not a guarantee that the events have
+been indexed. When ` + "`ack.enabled`" + ` is ` + "`true`" + `, and indexer
+acknowledgement is enabled for the token, the output polls the
+acknowledgement endpoint of the collector after each request and only
+considers a batch delivered once Splunk confirms that it has been indexed. If
+confirmation is not received within ` + "`ack.timeout`" + ` the batch is
+considered failed, and will be sent again.
+
+Acknowledgements are tracked by a channel, which must be a UUID that
+identifies this output, and when left empty a random one is generated at
+start up.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Guaranteed Delivery",
+				Summary: `
+Here we send structured logs as events, compressed in batches, where each
+batch must be acknowledged as indexed before it's considered delivered:`,
+				Config: `
+output:
+  splunk_hec:
+    url: https://splunk.example.com:8088
+    token: ${SPLUNK_HEC_TOKEN}
+    index: logs_${! json("env") }
+    sourcetype: _json
+    gzip: true
+    ack:
+      enabled: true
+    batching:
+      count: 500
+      period: 1s
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("url", "The base URL of the HTTP Event Collector.", "https://localhost:8088"),
+			docs.FieldCommon("token", "The token to authenticate with."),
+			docs.FieldCommon("endpoint", "The endpoint to send messages to.").HasOptions("event", "raw"),
+			docs.FieldCommon("index", "An optional index to send events to, which must be allowed by the token.").SupportsInterpolation(false),
+			docs.FieldAdvanced("source", "An optional source value of events.").SupportsInterpolation(false),
+			docs.FieldCommon("sourcetype", "An optional source type of events.").SupportsInterpolation(false),
+			docs.FieldAdvanced("host", "An optional host value of events.").SupportsInterpolation(false),
+			docs.FieldCommon("gzip", "Whether to compress requests with gzip."),
+			docs.FieldAdvanced("ack", "Configure indexer acknowledgements for guaranteed delivery.").WithChildren(
+				docs.FieldCommon("enabled", "Whether to wait for events to be acknowledged as indexed before a batch is considered delivered."),
+				docs.FieldCommon("channel", "A UUID that identifies the channel of acknowledgements, when empty a random one is generated."),
+				docs.FieldCommon("poll_interval", "The period to wait between polls of the acknowledgement endpoint."),
+				docs.FieldCommon("timeout", "The maximum period of time to wait for a batch to be acknowledged."),
+			),
+			docs.FieldAdvanced("timeout", "The maximum period of time to wait for each request to complete."),
+			btls.FieldSpec(),
+			docs.FieldCommon("max_in_flight", "The maximum number of batches to have in flight at a given time. Increase this to improve throughput."),
+			batch.FieldSpec(),
+		},
+		Categories: []Category{
+			CategoryServices,
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SplunkHECAckConfig contains configuration fields for indexer
+// acknowledgements of the Splunk HEC output type.
+type SplunkHECAckConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	Channel      string `json:"channel" yaml:"channel"`
+	PollInterval string `json:"poll_interval" yaml:"poll_interval"`
+	Timeout      string `json:"timeout" yaml:"timeout"`
+}
+
+// SplunkHECConfig contains configuration fields for the Splunk HEC output
+// type.
+type SplunkHECConfig struct {
+	URL         string             `json:"url" yaml:"url"`
+	Token       string             `json:"token" yaml:"token"`
+	Endpoint    string             `json:"endpoint" yaml:"endpoint"`
+	Index       string             `json:"index" yaml:"index"`
+	Source      string             `json:"source" yaml:"source"`
+	SourceType  string             `json:"sourcetype" yaml:"sourcetype"`
+	Host        string             `json:"host" yaml:"host"`
+	Gzip        bool               `json:"gzip" yaml:"gzip"`
+	Ack         SplunkHECAckConfig `json:"ack" yaml:"ack"`
+	Timeout     string             `json:"timeout" yaml:"timeout"`
+	TLS         btls.Config        `json:"tls" yaml:"tls"`
+	MaxInFlight int                `json:"max_in_flight" yaml:"max_in_flight"`
+	Batching    batch.PolicyConfig `json:"batching" yaml:"batching"`
+}
+
+// NewSplunkHECConfig creates a new SplunkHECConfig with default values.
+func NewSplunkHECConfig() SplunkHECConfig {
+	return SplunkHECConfig{
+		URL:        "https://localhost:8088",
+		Token:      "",
+		Endpoint:   "event",
+		Index:      "",
+		Source:     "",
+		SourceType: "",
+		Host:       "",
+		Gzip:       false,
+		Ack: SplunkHECAckConfig{
+			Enabled:      false,
+			Channel:      "",
+			PollInterval: "1s",
+			Timeout:      "60s",
+		},
+		Timeout:     "5s",
+		TLS:         btls.NewConfig(),
+		MaxInFlight: 1,
+		Batching:    batch.NewPolicyConfig(),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type splunkHECWriter struct {
+	conf  SplunkHECConfig
+	log   log.Modular
+	stats metrics.Type
+
+	baseURL    string
+	channel    string
+	index      field.Expression
+	source     field.Expression
+	sourceType field.Expression
+	host       field.Expression
+	client     *http.Client
+
+	ackPoll    time.Duration
+	ackTimeout time.Duration
+
+	mAcked      metrics.StatCounter
+	mAckTimeout metrics.StatCounter
+}
+
+func newSplunkHECWriter(conf SplunkHECConfig, log log.Modular, stats metrics.Type) (*splunkHECWriter, error) {
+	if conf.Token == "" {
+		return nil, errors.New("a token must be specified")
+	}
+	if conf.Endpoint != "event" && conf.Endpoint != "raw" {
+		return nil, fmt.Errorf("endpoint not recognised: %v", conf.Endpoint)
+	}
+
+	s := &splunkHECWriter{
+		conf:        conf,
+		log:         log,
+		stats:       stats,
+		baseURL:     strings.TrimSuffix(conf.URL, "/"),
+		mAcked:      stats.GetCounter("ack.success"),
+		mAckTimeout: stats.GetCounter("ack.timeout"),
+	}
+
+	var err error
+	for _, f := range []struct {
+		name string
+		expr string
+		dst  *field.Expression
+	}{
+		{"index", conf.Index, &s.index},
+		{"source", conf.Source, &s.source},
+		{"sourcetype", conf.SourceType, &s.sourceType},
+		{"host", conf.Host, &s.host},
+	} {
+		if *f.dst, err = bloblang.NewField(f.expr); err != nil {
+			return nil, fmt.Errorf("failed to parse %v expression: %v", f.name, err)
+		}
+	}
+
+	var timeout time.Duration
+	if conf.Timeout != "" {
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+	s.client = &http.Client{Timeout: timeout}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		s.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+
+	if conf.Ack.Enabled {
+		if s.ackPoll, err = time.ParseDuration(conf.Ack.PollInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse ack poll interval: %v", err)
+		}
+		if s.ackTimeout, err = time.ParseDuration(conf.Ack.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse ack timeout: %v", err)
+		}
+	}
+
+	if s.channel = conf.Ack.Channel; s.channel == "" {
+		u4, err := uuid.NewV4()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate channel: %v", err)
+		}
+		s.channel = u4.String()
+	} else if _, err = uuid.FromString(s.channel); err != nil {
+		return nil, fmt.Errorf("channel must be a UUID: %v", err)
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *splunkHECWriter) ConnectWithContext(ctx context.Context) error {
+	s.log.Infof("Sending messages to Splunk HEC at: %v\n", s.conf.URL)
+	return nil
+}
+
+// eventBody encodes a batch of messages as a series of JSON events.
+func (s *splunkHECWriter) eventBody(msg types.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	err := msg.Iter(func(i int, p types.Part) error {
+		event := map[string]interface{}{}
+		if v, err := p.JSON(); err == nil {
+			event["event"] = v
+		} else {
+			event["event"] = string(p.Get())
+		}
+		for k, f := range map[string]field.Expression{
+			"index":      s.index,
+			"source":     s.source,
+			"sourcetype": s.sourceType,
+			"host":       s.host,
+		} {
+			if v := f.String(i, msg); v != "" {
+				event[k] = v
+			}
+		}
+		return enc.Encode(event)
+	})
+	return buf.Bytes(), err
+}
+
+// rawBody joins a batch of messages with line breaks.
+func (s *splunkHECWriter) rawBody(msg types.Message) []byte {
+	var buf bytes.Buffer
+	msg.Iter(func(i int, p types.Part) error {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(p.Get())
+		return nil
+	})
+	return buf.Bytes()
+}
+
+func (s *splunkHECWriter) do(ctx context.Context, path string, query url.Values, body []byte) ([]byte, error) {
+	u := s.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody bytes.Buffer
+	if s.conf.Gzip {
+		zw := gzip.NewWriter(&reqBody)
+		if _, err := zw.Write(body); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	} else {
+		reqBody.Write(body)
+	}
+
+	req, err := http.NewRequest("POST", u, &reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Splunk "+s.conf.Token)
+	req.Header.Set("X-Splunk-Request-Channel", s.channel)
+	if s.conf.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("request returned status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	return resBody, nil
+}
+
+func (s *splunkHECWriter) WriteWithContext(ctx context.Context, msg types.Message) error {
+	var path string
+	var query url.Values
+	var body []byte
+
+	if s.conf.Endpoint == "raw" {
+		path = "/services/collector/raw"
+		query = url.Values{}
+		for k, f := range map[string]field.Expression{
+			"index":      s.index,
+			"source":     s.source,
+			"sourcetype": s.sourceType,
+			"host":       s.host,
+		} {
+			if v := f.String(0, msg); v != "" {
+				query.Set(k, v)
+			}
+		}
+		body = s.rawBody(msg)
+	} else {
+		path = "/services/collector/event"
+		var err error
+		if body, err = s.eventBody(msg); err != nil {
+			return err
+		}
+	}
+
+	resBody, err := s.do(ctx, path, query, body)
+	if err != nil {
+		return err
+	}
+	if !s.conf.Ack.Enabled {
+		return nil
+	}
+
+	var res struct {
+		AckID *int64 `json:"ackId"`
+	}
+	if err = json.Unmarshal(resBody, &res); err != nil {
+		return fmt.Errorf("failed to parse response: %v", err)
+	}
+	if res.AckID == nil {
+		return errors.New("response did not contain an ackId, indexer acknowledgement might not be enabled for the token")
+	}
+	return s.waitForAck(ctx, *res.AckID)
+}
+
+// waitForAck polls the acknowledgement endpoint until an ack ID has been
+// indexed or the ack timeout is reached.
+func (s *splunkHECWriter) waitForAck(ctx context.Context, ackID int64) error {
+	reqBody, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(s.ackTimeout)
+	for {
+		select {
+		case <-time.After(s.ackPoll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		resBody, err := s.do(ctx, "/services/collector/ack", url.Values{"channel": []string{s.channel}}, reqBody)
+		if err != nil {
+			s.log.Warnf("Failed to poll acknowledgements: %v\n", err)
+		} else {
+			var res struct {
+				Acks map[string]bool `json:"acks"`
+			}
+			if err = json.Unmarshal(resBody, &res); err != nil {
+				s.log.Warnf("Failed to parse acknowledgements: %v\n", err)
+			} else if res.Acks[strconv.FormatInt(ackID, 10)] {
+				s.mAcked.Incr(1)
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			s.mAckTimeout.Incr(1)
+			return fmt.Errorf("timed out waiting for ack %v to be indexed", ackID)
+		}
+	}
+}
+
+func (s *splunkHECWriter) CloseAsync() {
+}
+
+func (s *splunkHECWriter) WaitForClose(time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSplunkHEC struct {
+	mut      sync.Mutex
+	requests map[string][]string
+	queries  map[string][]string
+	ackPolls int
+	ackAfter int
+}
+
+func startTestSplunkHEC(t *testing.T) (*testSplunkHEC, string) {
+	t.Helper()
+
+	s := &testSplunkHEC{
+		requests: map[string][]string{},
+		queries:  map[string][]string{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Splunk foo", r.Header.Get("Authorization"))
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		b, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+
+		s.mut.Lock()
+		defer s.mut.Unlock()
+
+		s.requests[r.URL.Path] = append(s.requests[r.URL.Path], string(b))
+		s.queries[r.URL.Path] = append(s.queries[r.URL.Path], r.URL.RawQuery)
+
+		if r.URL.Path == "/services/collector/ack" {
+			s.ackPolls++
+			if s.ackPolls >= s.ackAfter {
+				w.Write([]byte(`{"acks":{"7":true}}`))
+			} else {
+				w.Write([]byte(`{"acks":{"7":false}}`))
+			}
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+	}))
+	t.Cleanup(server.Close)
+	return s, server.URL
+}
+
+func TestSplunkHECEvents(t *testing.T) {
+	server, url := startTestSplunkHEC(t)
+
+	conf := NewSplunkHECConfig()
+	conf.URL = url
+	conf.Token = "foo"
+	conf.Index = `${! meta("index") }`
+	conf.SourceType = "_json"
+	conf.Gzip = true
+
+	w, err := newSplunkHECWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"hello":"world"}`),
+		[]byte(`not json`),
+	})
+	msg.Get(0).Metadata().Set("index", "main")
+	require.NoError(t, w.WriteWithContext(context.Background(), msg))
+
+	assert.Equal(t, map[string][]string{
+		"/services/collector/event": {
+			`{"event":{"hello":"world"},"index":"main","sourcetype":"_json"}` + "\n" +
+				`{"event":"not json","sourcetype":"_json"}` + "\n",
+		},
+	}, server.requests)
+}
+
+func TestSplunkHECRawWithAcks(t *testing.T) {
+	server, url := startTestSplunkHEC(t)
+	server.ackAfter = 2
+
+	conf := NewSplunkHECConfig()
+	conf.URL = url
+	conf.Token = "foo"
+	conf.Endpoint = "raw"
+	conf.SourceType = "syslog"
+	conf.Ack.Enabled = true
+	conf.Ack.Channel = "b0c7f6a4-6f2b-4c9d-9cb4-6c2d2a7f1c11"
+	conf.Ack.PollInterval = "1ms"
+
+	w, err := newSplunkHECWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteWithContext(context.Background(), message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+	})))
+
+	server.mut.Lock()
+	defer server.mut.Unlock()
+
+	assert.Equal(t, []string{"foo\nbar"}, server.requests["/services/collector/raw"])
+	assert.Equal(t, []string{"sourcetype=syslog"}, server.queries["/services/collector/raw"])
+	assert.Equal(t, 2, server.ackPolls)
+	assert.Equal(t, `{"acks":[7]}`, server.requests["/services/collector/ack"][0])
+	assert.Equal(t, "channel=b0c7f6a4-6f2b-4c9d-9cb4-6c2d2a7f1c11", server.queries["/services/collector/ack"][0])
+}
+
+func TestSplunkHECAckTimeout(t *testing.T) {
+	server, url := startTestSplunkHEC(t)
+	server.ackAfter = 1000
+
+	conf := NewSplunkHECConfig()
+	conf.URL = url
+	conf.Token = "foo"
+	conf.Ack.Enabled = true
+	conf.Ack.PollInterval = "1ms"
+	conf.Ack.Timeout = "10ms"
+
+	w, err := newSplunkHECWriter(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	err = w.WriteWithContext(context.Background(), message.New([][]byte{[]byte("foo")}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out waiting for ack")
+}
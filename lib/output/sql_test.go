@@ -0,0 +1,115 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLUpsertQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		driver      string
+		conf        SQLUpsertConfig
+		expected    string
+		errContains string
+	}{
+		{
+			name:   "mysql all columns",
+			driver: "mysql",
+			conf: SQLUpsertConfig{
+				Table:   "footable",
+				Columns: []string{"foo", "bar", "baz"},
+			},
+			expected: "INSERT INTO footable (foo, bar, baz) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE foo = VALUES(foo), bar = VALUES(bar), baz = VALUES(baz)",
+		},
+		{
+			name:   "mysql explicit update columns",
+			driver: "mysql",
+			conf: SQLUpsertConfig{
+				Table:           "footable",
+				Columns:         []string{"foo", "bar", "baz"},
+				ConflictColumns: []string{"foo"},
+				UpdateColumns:   []string{"baz"},
+			},
+			expected: "INSERT INTO footable (foo, bar, baz) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE baz = VALUES(baz)",
+		},
+		{
+			name:   "postgres conflict columns",
+			driver: "postgres",
+			conf: SQLUpsertConfig{
+				Table:           "footable",
+				Columns:         []string{"foo", "bar", "baz"},
+				ConflictColumns: []string{"foo"},
+			},
+			expected: "INSERT INTO footable (foo, bar, baz) VALUES ($1, $2, $3) ON CONFLICT (foo) DO UPDATE SET bar = EXCLUDED.bar, baz = EXCLUDED.baz",
+		},
+		{
+			name:   "postgres nothing to update",
+			driver: "postgres",
+			conf: SQLUpsertConfig{
+				Table:           "footable",
+				Columns:         []string{"foo"},
+				ConflictColumns: []string{"foo"},
+			},
+			expected: "INSERT INTO footable (foo) VALUES ($1) ON CONFLICT (foo) DO NOTHING",
+		},
+		{
+			name:   "postgres no conflict columns",
+			driver: "postgres",
+			conf: SQLUpsertConfig{
+				Table:   "footable",
+				Columns: []string{"foo"},
+			},
+			errContains: "conflict column",
+		},
+		{
+			name:   "clickhouse",
+			driver: "clickhouse",
+			conf: SQLUpsertConfig{
+				Table:   "footable",
+				Columns: []string{"foo"},
+			},
+			errContains: "does not support upserts",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			query, err := sqlUpsertQuery(test.driver, test.conf)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, query)
+		})
+	}
+}
+
+func TestSQLUpsertConfigErrors(t *testing.T) {
+	conf := NewSQLConfig()
+	conf.Driver = "postgres"
+	conf.Query = "INSERT INTO footable (foo) VALUES ($1);"
+	conf.Upsert.Table = "footable"
+	conf.Upsert.Columns = []string{"foo"}
+	conf.Upsert.ConflictColumns = []string{"foo"}
+	conf.Args = []string{"${! content() }"}
+
+	_, err := newSQLWriter(conf, nil)
+	require.Error(t, err)
+
+	conf.Query = ""
+	conf.Args = nil
+	_, err = newSQLWriter(conf, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must match")
+
+	conf.Args = []string{"${! content() }"}
+	w, err := newSQLWriter(conf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO footable (foo) VALUES ($1) ON CONFLICT (foo) DO NOTHING", w.queryStr)
+}
@@ -389,6 +389,16 @@ func (o *Switch) Connected() bool {
 	return true
 }
 
+// ConnectionStatuses returns the connection status of each output of the
+// switch.
+func (o *Switch) ConnectionStatuses() []types.ConnectionStatus {
+	var statuses []types.ConnectionStatus
+	for i, out := range o.outputs {
+		statuses = append(statuses, types.ConnectionStatusesOf(fmt.Sprintf("switch.%v.output", i), out)...)
+	}
+	return statuses
+}
+
 //------------------------------------------------------------------------------
 
 // loop is an internal loop that brokers incoming messages to many outputs.
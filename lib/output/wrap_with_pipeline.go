@@ -59,6 +59,11 @@ func (i *WithPipeline) Connected() bool {
 	return i.out.Connected()
 }
 
+// ConnectionStatuses returns the connection status of the wrapped output.
+func (i *WithPipeline) ConnectionStatuses() []types.ConnectionStatus {
+	return types.ConnectionStatusesOf("", i.out)
+}
+
 //------------------------------------------------------------------------------
 
 // CloseAsync triggers a closure of this object but does not block.
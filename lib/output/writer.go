@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/event"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
@@ -30,6 +31,8 @@ type Writer struct {
 	log   log.Modular
 	stats metrics.Type
 
+	activity types.ConnectionActivity
+
 	transactions <-chan types.Transaction
 
 	closeChan      chan struct{}
@@ -90,7 +93,11 @@ func (w *Writer) loop() {
 		close(w.closedChan)
 	}()
 
-	throt := throttle.New(throttle.OptCloseChan(w.closeChan))
+	throt := throttle.New(
+		throttle.OptCloseChan(w.closeChan),
+		throttle.OptOnStateChange(retryStateGauges(w.stats)),
+	)
+	w.activity.SetBackoff(throt)
 
 	for {
 		if err := w.writer.Connect(); err != nil {
@@ -100,6 +107,7 @@ func (w *Writer) loop() {
 			}
 
 			w.log.Errorf("Failed to connect to %v: %v\n", w.typeStr, err)
+			w.activity.SetError(err)
 			mFailedConn.Incr(1)
 			if !throt.Retry() {
 				return
@@ -109,6 +117,7 @@ func (w *Writer) loop() {
 		}
 	}
 	mConn.Incr(1)
+	event.Emit(event.TypeOutputConnected, w.typeStr, nil)
 	atomic.StoreInt32(&w.isConnected, 1)
 
 	for atomic.LoadInt32(&w.running) == 1 {
@@ -125,12 +134,13 @@ func (w *Writer) loop() {
 		}
 
 		w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
-		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload)
+		spans := tracing.CreateChildSpans("output_"+w.typeStr, ts.Payload, tracing.ComponentTags("output", w.typeStr, ts.Payload.Len()))
 		latency, err := w.latencyMeasuringWrite(ts.Payload)
 
 		// If our writer says it is not connected.
 		if errors.Is(err, types.ErrNotConnected) {
 			mLostConn.Incr(1)
+			event.Emit(event.TypeOutputDisconnected, w.typeStr, nil)
 			atomic.StoreInt32(&w.isConnected, 0)
 
 			// Continue to try to reconnect while still active.
@@ -142,6 +152,7 @@ func (w *Writer) loop() {
 					}
 
 					w.log.Errorf("Failed to reconnect to %v: %v\n", w.typeStr, err)
+					w.activity.SetError(err)
 					mFailedConn.Incr(1)
 					if !throt.Retry() {
 						return
@@ -149,6 +160,7 @@ func (w *Writer) loop() {
 				} else if latency, err = w.latencyMeasuringWrite(ts.Payload); !errors.Is(err, types.ErrNotConnected) {
 					atomic.StoreInt32(&w.isConnected, 1)
 					mConn.Incr(1)
+					event.Emit(event.TypeOutputConnected, w.typeStr, nil)
 					break
 				} else if !throt.Retry() {
 					return
@@ -163,14 +175,19 @@ func (w *Writer) loop() {
 
 		if err != nil {
 			w.log.Errorf("Failed to send message to %v: %v\n", w.typeStr, err)
+			w.activity.SetError(err)
+			event.Emit(event.TypeOutputWriteFailed, w.typeStr, err)
 			if !throt.Retry() {
 				return
 			}
 		} else {
+			w.activity.SetActive()
 			mSent.Incr(1)
 			mPartsSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
 			mBytesSent.Incr(int64(message.GetAllBytesLen(ts.Payload)))
-			mLatency.Timing(latency)
+			metrics.TimingWithTraceID(mLatency, latency, func() string {
+				return tracing.BatchTraceID(ts.Payload)
+			})
 			w.log.Tracef("Successfully wrote %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
 			throt.Reset()
 		}
@@ -209,6 +226,11 @@ func (w *Writer) Connected() bool {
 	return atomic.LoadInt32(&w.isConnected) == 1
 }
 
+// ConnectionStatuses returns the connection status of this output.
+func (w *Writer) ConnectionStatuses() []types.ConnectionStatus {
+	return []types.ConnectionStatus{w.activity.Status(w.typeStr, w.Connected())}
+}
+
 // CloseAsync shuts down the File output and stops processing messages.
 func (w *Writer) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&w.running, 1, 0) {
@@ -11,9 +11,11 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/opentracing/opentracing-go"
 	"github.com/streadway/amqp"
 )
 
@@ -237,6 +239,7 @@ func (a *AMQP) Write(msg types.Message) error {
 			headers[strings.Replace(k, "_", "-", -1)] = v
 			return nil
 		})
+		_ = tracing.InjectPartSpan(p, opentracing.TextMap, amqpHeadersCarrier(headers))
 
 		err := amqpChan.Publish(
 			a.conf.Exchange,  // publish to an exchange
@@ -290,3 +293,12 @@ func (a *AMQP) WaitForClose(timeout time.Duration) error {
 }
 
 //------------------------------------------------------------------------------
+
+// amqpHeadersCarrier allows span contexts to be injected into AMQP headers.
+type amqpHeadersCarrier amqp.Table
+
+func (a amqpHeadersCarrier) Set(key, val string) {
+	a[key] = val
+}
+
+//------------------------------------------------------------------------------
@@ -2,6 +2,7 @@ package writer
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -9,6 +10,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aimd"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
 	"github.com/Jeffail/benthos/v3/lib/util/http/client"
 )
 
@@ -18,16 +21,20 @@ import (
 // type.
 type HTTPClientConfig struct {
 	client.Config     `json:",inline" yaml:",inline"`
-	MaxInFlight       int                `json:"max_in_flight" yaml:"max_in_flight"`
-	PropagateResponse bool               `json:"propagate_response" yaml:"propagate_response"`
-	Batching          batch.PolicyConfig `json:"batching" yaml:"batching"`
+	Signing           auth.HMACSigningConfig `json:"signing" yaml:"signing"`
+	MaxInFlight       int                    `json:"max_in_flight" yaml:"max_in_flight"`
+	AdaptiveInFlight  aimd.Config            `json:"adaptive_in_flight" yaml:"adaptive_in_flight"`
+	PropagateResponse bool                   `json:"propagate_response" yaml:"propagate_response"`
+	Batching          batch.PolicyConfig     `json:"batching" yaml:"batching"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
 func NewHTTPClientConfig() HTTPClientConfig {
 	return HTTPClientConfig{
 		Config:            client.NewConfig(),
+		Signing:           auth.NewHMACSigningConfig(),
 		MaxInFlight:       1, // TODO: Increase this default?
+		AdaptiveInFlight:  aimd.NewConfig(),
 		PropagateResponse: false,
 		Batching:          batch.NewPolicyConfig(),
 	}
@@ -60,13 +67,17 @@ func NewHTTPClient(
 		conf:      conf,
 		closeChan: make(chan struct{}),
 	}
-	var err error
+	signer, err := conf.Signing.Signer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialise request signing: %w", err)
+	}
 	if h.client, err = client.New(
 		conf.Config,
 		client.OptSetCloseChan(h.closeChan),
 		client.OptSetLogger(h.log),
 		client.OptSetManager(mgr),
 		client.OptSetStats(metrics.Namespaced(h.stats, "client")),
+		client.OptSetRequestSigner(signer),
 	); err != nil {
 		return nil, err
 	}
@@ -1,6 +1,9 @@
 package writer
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -18,6 +21,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message/roundtrip"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -298,3 +302,122 @@ func TestHTTPClientMultipart(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestHTTPClientSigning(t *testing.T) {
+	hmacHex := func(key, msg string) string {
+		h := hmac.New(sha256.New, []byte(key))
+		h.Write([]byte(msg))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	tests := map[string]struct {
+		conf   func(c *auth.HMACSigningConfig)
+		verify func(t *testing.T, header http.Header, body string)
+	}{
+		"custom": {
+			conf: func(c *auth.HMACSigningConfig) {
+				c.Header = "X-Benthos-Signature"
+				c.Prefix = "sha256="
+			},
+			verify: func(t *testing.T, header http.Header, body string) {
+				assert.Equal(t, "sha256="+hmacHex("foosecret", body), header.Get("X-Benthos-Signature"))
+			},
+		},
+		"custom with timestamp": {
+			conf: func(c *auth.HMACSigningConfig) {
+				c.Header = "X-Benthos-Signature"
+				c.TimestampHeader = "X-Benthos-Timestamp"
+			},
+			verify: func(t *testing.T, header http.Header, body string) {
+				ts := header.Get("X-Benthos-Timestamp")
+				require.NotEmpty(t, ts)
+				assert.Equal(t, hmacHex("foosecret", ts+"."+body), header.Get("X-Benthos-Signature"))
+			},
+		},
+		"github": {
+			conf: func(c *auth.HMACSigningConfig) {
+				c.Style = "github"
+			},
+			verify: func(t *testing.T, header http.Header, body string) {
+				assert.Equal(t, "sha256="+hmacHex("foosecret", body), header.Get("X-Hub-Signature-256"))
+			},
+		},
+		"stripe": {
+			conf: func(c *auth.HMACSigningConfig) {
+				c.Style = "stripe"
+			},
+			verify: func(t *testing.T, header http.Header, body string) {
+				parts := strings.Split(header.Get("Stripe-Signature"), ",")
+				require.Len(t, parts, 2)
+				require.True(t, strings.HasPrefix(parts[0], "t="))
+				ts := strings.TrimPrefix(parts[0], "t=")
+				assert.Equal(t, "v1="+hmacHex("foosecret", ts+"."+body), parts[1])
+			},
+		},
+		"slack": {
+			conf: func(c *auth.HMACSigningConfig) {
+				c.Style = "slack"
+			},
+			verify: func(t *testing.T, header http.Header, body string) {
+				ts := header.Get("X-Slack-Request-Timestamp")
+				require.NotEmpty(t, ts)
+				assert.Equal(t, "v0="+hmacHex("foosecret", "v0:"+ts+":"+body), header.Get("X-Slack-Signature"))
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			resultChan := make(chan http.Header, 1)
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, "hello world", string(b))
+				resultChan <- r.Header
+			}))
+			defer ts.Close()
+
+			conf := NewHTTPClientConfig()
+			conf.URL = ts.URL + "/testpost"
+			conf.Signing.Enabled = true
+			conf.Signing.Secret = "foosecret"
+			test.conf(&conf.Signing)
+
+			h, err := NewHTTPClient(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+			require.NoError(t, err)
+
+			require.NoError(t, h.Write(message.New([][]byte{[]byte("hello world")})))
+
+			select {
+			case header := <-resultChan:
+				test.verify(t, header, "hello world")
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+
+			h.CloseAsync()
+			require.NoError(t, h.WaitForClose(time.Second))
+		})
+	}
+}
+
+func TestHTTPClientSigningErrors(t *testing.T) {
+	tests := map[string]func(c *auth.HMACSigningConfig){
+		"no secret":        func(c *auth.HMACSigningConfig) { c.Secret = "" },
+		"bad style":        func(c *auth.HMACSigningConfig) { c.Style = "nope" },
+		"bad algorithm":    func(c *auth.HMACSigningConfig) { c.Algorithm = "md5" },
+		"bad encoding":     func(c *auth.HMACSigningConfig) { c.Encoding = "base32" },
+		"no custom header": func(c *auth.HMACSigningConfig) { c.Header = "" },
+	}
+
+	for name, fn := range tests {
+		conf := NewHTTPClientConfig()
+		conf.Signing.Enabled = true
+		conf.Signing.Secret = "foosecret"
+		fn(&conf.Signing)
+
+		_, err := NewHTTPClient(conf, types.NoopMgr(), log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message/batch"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/Jeffail/benthos/v3/lib/util/hash/murmur2"
@@ -21,6 +22,7 @@ import (
 	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
 	"github.com/Shopify/sarama"
 	"github.com/cenkalti/backoff/v4"
+	"github.com/opentracing/opentracing-go"
 )
 
 //------------------------------------------------------------------------------
@@ -212,6 +214,25 @@ func strToPartitioner(str string) (sarama.PartitionerConstructor, error) {
 
 //------------------------------------------------------------------------------
 
+// kafkaHeadersCarrier allows span contexts to be injected into record headers,
+// replacing any existing headers of the same key.
+type kafkaHeadersCarrier struct {
+	headers *[]sarama.RecordHeader
+}
+
+func (k kafkaHeadersCarrier) Set(key, val string) {
+	for i, h := range *k.headers {
+		if string(h.Key) == key {
+			(*k.headers)[i].Value = []byte(val)
+			return
+		}
+	}
+	*k.headers = append(*k.headers, sarama.RecordHeader{
+		Key:   []byte(key),
+		Value: []byte(val),
+	})
+}
+
 func buildSystemHeaders(version sarama.KafkaVersion, part types.Part) []sarama.RecordHeader {
 	if version.IsAtLeast(sarama.V0_11_0_0) {
 		out := []sarama.RecordHeader{}
@@ -223,6 +244,7 @@ func buildSystemHeaders(version sarama.KafkaVersion, part types.Part) []sarama.R
 			})
 			return nil
 		})
+		_ = tracing.InjectPartSpan(part, opentracing.TextMap, kafkaHeadersCarrier{headers: &out})
 		return out
 	}
 
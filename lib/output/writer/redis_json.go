@@ -0,0 +1,189 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	bredis "github.com/Jeffail/benthos/v3/internal/service/redis"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-redis/redis/v7"
+)
+
+//------------------------------------------------------------------------------
+
+// RedisJSONConfig contains configuration fields for the RedisJSON output type.
+type RedisJSONConfig struct {
+	bredis.Config `json:",inline" yaml:",inline"`
+	Key           string `json:"key" yaml:"key"`
+	Path          string `json:"path" yaml:"path"`
+	Value         string `json:"value" yaml:"value"`
+	Condition     string `json:"condition" yaml:"condition"`
+	MaxInFlight   int    `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewRedisJSONConfig creates a new RedisJSONConfig with default values.
+func NewRedisJSONConfig() RedisJSONConfig {
+	return RedisJSONConfig{
+		Config:      bredis.NewConfig(),
+		Key:         "",
+		Path:        ".",
+		Value:       `${! content() }`,
+		Condition:   "",
+		MaxInFlight: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedisJSON is an output type that sets RedisJSON documents.
+type RedisJSON struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf RedisJSONConfig
+
+	key   field.Expression
+	path  field.Expression
+	value field.Expression
+
+	client  redis.UniversalClient
+	connMut sync.RWMutex
+}
+
+// NewRedisJSON creates a new RedisJSON output type.
+func NewRedisJSON(
+	conf RedisJSONConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*RedisJSON, error) {
+	r := &RedisJSON{
+		log:   log,
+		stats: stats,
+		conf:  conf,
+	}
+
+	switch conf.Condition {
+	case "", "NX", "XX":
+	default:
+		return nil, fmt.Errorf("unrecognised condition: %v", conf.Condition)
+	}
+
+	var err error
+	if r.key, err = bloblang.NewField(conf.Key); err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	if r.path, err = bloblang.NewField(conf.Path); err != nil {
+		return nil, fmt.Errorf("failed to parse path expression: %v", err)
+	}
+	if r.value, err = bloblang.NewField(conf.Value); err != nil {
+		return nil, fmt.Errorf("failed to parse value expression: %v", err)
+	}
+	if _, err := conf.Config.Client(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext establishes a connection to a RedisJSON server.
+func (r *RedisJSON) ConnectWithContext(ctx context.Context) error {
+	return r.Connect()
+}
+
+// Connect establishes a connection to a RedisJSON server.
+func (r *RedisJSON) Connect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	client, err := r.conf.Config.Client()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping().Result(); err != nil {
+		return err
+	}
+
+	r.log.Infoln("Setting messages as RedisJSON documents.")
+
+	r.client = client
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *RedisJSON) setArgs(index int, msg types.Message) []interface{} {
+	args := []interface{}{
+		"JSON.SET",
+		r.key.String(index, msg),
+		r.path.String(index, msg),
+		r.value.Bytes(index, msg),
+	}
+	if r.conf.Condition != "" {
+		args = append(args, r.conf.Condition)
+	}
+	return args
+}
+
+// WriteWithContext attempts to write a message by setting it as a RedisJSON
+// document.
+func (r *RedisJSON) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return r.Write(msg)
+}
+
+// Write attempts to write a message by setting it as a RedisJSON document.
+func (r *RedisJSON) Write(msg types.Message) error {
+	r.connMut.RLock()
+	client := r.client
+	r.connMut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	return IterateBatchedSend(msg, func(i int, p types.Part) error {
+		if err := client.Do(r.setArgs(i, msg)...).Err(); err != nil {
+			if err == redis.Nil {
+				// A nil reply means the NX or XX condition was not met.
+				return nil
+			}
+			if _, isReplyErr := err.(redis.Error); isReplyErr {
+				return err
+			}
+			r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return types.ErrNotConnected
+		}
+		return nil
+	})
+}
+
+// disconnect safely closes a connection to a RedisJSON server.
+func (r *RedisJSON) disconnect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+	if r.client != nil {
+		err := r.client.Close()
+		r.client = nil
+		return err
+	}
+	return nil
+}
+
+// CloseAsync shuts down the RedisJSON output and stops processing messages.
+func (r *RedisJSON) CloseAsync() {
+	go r.disconnect()
+}
+
+// WaitForClose blocks until the RedisJSON output has closed down.
+func (r *RedisJSON) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -2,9 +2,12 @@ package writer
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	bredis "github.com/Jeffail/benthos/v3/internal/service/redis"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -20,6 +23,8 @@ type RedisStreamsConfig struct {
 	Stream        string `json:"stream" yaml:"stream"`
 	BodyKey       string `json:"body_key" yaml:"body_key"`
 	MaxLenApprox  int64  `json:"max_length" yaml:"max_length"`
+	MaxLenExact   bool   `json:"max_length_exact" yaml:"max_length_exact"`
+	NoMkStream    bool   `json:"no_mkstream" yaml:"no_mkstream"`
 	MaxInFlight   int    `json:"max_in_flight" yaml:"max_in_flight"`
 }
 
@@ -30,6 +35,8 @@ func NewRedisStreamsConfig() RedisStreamsConfig {
 		Stream:       "benthos_stream",
 		BodyKey:      "body",
 		MaxLenApprox: 0,
+		MaxLenExact:  false,
+		NoMkStream:   false,
 		MaxInFlight:  1,
 	}
 }
@@ -43,6 +50,8 @@ type RedisStreams struct {
 
 	conf RedisStreamsConfig
 
+	stream field.Expression
+
 	client  redis.UniversalClient
 	connMut sync.RWMutex
 }
@@ -60,6 +69,10 @@ func NewRedisStreams(
 		conf:  conf,
 	}
 
+	var err error
+	if r.stream, err = bloblang.NewField(conf.Stream); err != nil {
+		return nil, fmt.Errorf("failed to parse stream expression: %v", err)
+	}
 	if _, err := conf.Config.Client(); err != nil {
 		return nil, err
 	}
@@ -116,12 +129,10 @@ func (r *RedisStreams) Write(msg types.Message) error {
 			return nil
 		})
 		values[r.conf.BodyKey] = p.Get()
-		if err := client.XAdd(&redis.XAddArgs{
-			ID:           "*",
-			Stream:       r.conf.Stream,
-			MaxLenApprox: r.conf.MaxLenApprox,
-			Values:       values,
-		}).Err(); err != nil {
+		if err := client.Do(r.xaddArgs(r.stream.String(i, msg), values)...).Err(); err != nil {
+			if _, isReplyErr := err.(redis.Error); isReplyErr {
+				return err
+			}
 			r.disconnect()
 			r.log.Errorf("Error from redis: %v\n", err)
 			return types.ErrNotConnected
@@ -130,6 +141,26 @@ func (r *RedisStreams) Write(msg types.Message) error {
 	})
 }
 
+func (r *RedisStreams) xaddArgs(stream string, values map[string]interface{}) []interface{} {
+	args := make([]interface{}, 0, 7+len(values)*2)
+	args = append(args, "XADD", stream)
+	if r.conf.NoMkStream {
+		args = append(args, "NOMKSTREAM")
+	}
+	if r.conf.MaxLenApprox > 0 {
+		if r.conf.MaxLenExact {
+			args = append(args, "MAXLEN", r.conf.MaxLenApprox)
+		} else {
+			args = append(args, "MAXLEN", "~", r.conf.MaxLenApprox)
+		}
+	}
+	args = append(args, "*")
+	for k, v := range values {
+		args = append(args, k, v)
+	}
+	return args
+}
+
 // disconnect safely closes a connection to an RedisStreams server.
 func (r *RedisStreams) disconnect() error {
 	r.connMut.Lock()
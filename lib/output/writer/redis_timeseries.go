@@ -0,0 +1,244 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	bredis "github.com/Jeffail/benthos/v3/internal/service/redis"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-redis/redis/v7"
+)
+
+//------------------------------------------------------------------------------
+
+// RedisTimeSeriesConfig contains configuration fields for the RedisTimeSeries
+// output type.
+type RedisTimeSeriesConfig struct {
+	bredis.Config `json:",inline" yaml:",inline"`
+	Key           string            `json:"key" yaml:"key"`
+	Timestamp     string            `json:"timestamp" yaml:"timestamp"`
+	Value         string            `json:"value" yaml:"value"`
+	Labels        map[string]string `json:"labels" yaml:"labels"`
+	Retention     string            `json:"retention" yaml:"retention"`
+	OnDuplicate   string            `json:"on_duplicate" yaml:"on_duplicate"`
+	MaxInFlight   int               `json:"max_in_flight" yaml:"max_in_flight"`
+}
+
+// NewRedisTimeSeriesConfig creates a new RedisTimeSeriesConfig with default
+// values.
+func NewRedisTimeSeriesConfig() RedisTimeSeriesConfig {
+	return RedisTimeSeriesConfig{
+		Config:      bredis.NewConfig(),
+		Key:         "",
+		Timestamp:   "*",
+		Value:       "",
+		Labels:      map[string]string{},
+		Retention:   "",
+		OnDuplicate: "",
+		MaxInFlight: 1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type redisTSLabel struct {
+	name  string
+	value field.Expression
+}
+
+// RedisTimeSeries is an output type that adds samples to RedisTimeSeries keys.
+type RedisTimeSeries struct {
+	log   log.Modular
+	stats metrics.Type
+
+	conf RedisTimeSeriesConfig
+
+	key         field.Expression
+	timestamp   field.Expression
+	value       field.Expression
+	labels      []redisTSLabel
+	retentionMS int64
+
+	client  redis.UniversalClient
+	connMut sync.RWMutex
+}
+
+// NewRedisTimeSeries creates a new RedisTimeSeries output type.
+func NewRedisTimeSeries(
+	conf RedisTimeSeriesConfig,
+	log log.Modular,
+	stats metrics.Type,
+) (*RedisTimeSeries, error) {
+	r := &RedisTimeSeries{
+		log:   log,
+		stats: stats,
+		conf:  conf,
+	}
+
+	switch strings.ToUpper(conf.OnDuplicate) {
+	case "", "BLOCK", "FIRST", "LAST", "MIN", "MAX", "SUM":
+	default:
+		return nil, fmt.Errorf("unrecognised on_duplicate policy: %v", conf.OnDuplicate)
+	}
+
+	var err error
+	if r.key, err = bloblang.NewField(conf.Key); err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	if r.timestamp, err = bloblang.NewField(conf.Timestamp); err != nil {
+		return nil, fmt.Errorf("failed to parse timestamp expression: %v", err)
+	}
+	if r.value, err = bloblang.NewField(conf.Value); err != nil {
+		return nil, fmt.Errorf("failed to parse value expression: %v", err)
+	}
+
+	labelNames := make([]string, 0, len(conf.Labels))
+	for k := range conf.Labels {
+		labelNames = append(labelNames, k)
+	}
+	sort.Strings(labelNames)
+	for _, k := range labelNames {
+		expr, err := bloblang.NewField(conf.Labels[k])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse label '%v' expression: %v", k, err)
+		}
+		r.labels = append(r.labels, redisTSLabel{name: k, value: expr})
+	}
+
+	if conf.Retention != "" {
+		retention, err := time.ParseDuration(conf.Retention)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse retention: %v", err)
+		}
+		r.retentionMS = int64(retention / time.Millisecond)
+	}
+
+	if _, err := conf.Config.Client(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ConnectWithContext establishes a connection to a RedisTimeSeries server.
+func (r *RedisTimeSeries) ConnectWithContext(ctx context.Context) error {
+	return r.Connect()
+}
+
+// Connect establishes a connection to a RedisTimeSeries server.
+func (r *RedisTimeSeries) Connect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	client, err := r.conf.Config.Client()
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping().Result(); err != nil {
+		return err
+	}
+
+	r.log.Infoln("Adding messages as RedisTimeSeries samples.")
+
+	r.client = client
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *RedisTimeSeries) addArgs(index int, msg types.Message) ([]interface{}, error) {
+	timestamp := r.timestamp.String(index, msg)
+	if timestamp != "*" {
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp: %v", err)
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(r.value.String(index, msg)), 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value: %v", err)
+	}
+
+	args := []interface{}{"TS.ADD", r.key.String(index, msg), timestamp, value}
+	if r.retentionMS > 0 {
+		args = append(args, "RETENTION", r.retentionMS)
+	}
+	if r.conf.OnDuplicate != "" {
+		args = append(args, "ON_DUPLICATE", strings.ToUpper(r.conf.OnDuplicate))
+	}
+	if len(r.labels) > 0 {
+		args = append(args, "LABELS")
+		for _, l := range r.labels {
+			args = append(args, l.name, l.value.String(index, msg))
+		}
+	}
+	return args, nil
+}
+
+// WriteWithContext attempts to write a message by adding it as a
+// RedisTimeSeries sample.
+func (r *RedisTimeSeries) WriteWithContext(ctx context.Context, msg types.Message) error {
+	return r.Write(msg)
+}
+
+// Write attempts to write a message by adding it as a RedisTimeSeries sample.
+func (r *RedisTimeSeries) Write(msg types.Message) error {
+	r.connMut.RLock()
+	client := r.client
+	r.connMut.RUnlock()
+
+	if client == nil {
+		return types.ErrNotConnected
+	}
+
+	return IterateBatchedSend(msg, func(i int, p types.Part) error {
+		args, err := r.addArgs(i, msg)
+		if err != nil {
+			return err
+		}
+		if err = client.Do(args...).Err(); err != nil {
+			if _, isReplyErr := err.(redis.Error); isReplyErr {
+				return err
+			}
+			r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return types.ErrNotConnected
+		}
+		return nil
+	})
+}
+
+// disconnect safely closes a connection to a RedisTimeSeries server.
+func (r *RedisTimeSeries) disconnect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+	if r.client != nil {
+		err := r.client.Close()
+		r.client = nil
+		return err
+	}
+	return nil
+}
+
+// CloseAsync shuts down the RedisTimeSeries output and stops processing
+// messages.
+func (r *RedisTimeSeries) CloseAsync() {
+	go r.disconnect()
+}
+
+// WaitForClose blocks until the RedisTimeSeries output has closed down.
+func (r *RedisTimeSeries) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,100 @@
+package writer
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisTimeSeriesArgs(t *testing.T) {
+	conf := NewRedisTimeSeriesConfig()
+	conf.Key = `temp:${! json("id") }`
+	conf.Timestamp = `${! json("ts") }`
+	conf.Value = `${! json("value") }`
+	conf.Labels = map[string]string{
+		"site":   `${! meta("site") }`,
+		"sensor": `${! json("id") }`,
+	}
+	conf.Retention = "1h"
+	conf.OnDuplicate = "last"
+
+	r, err := NewRedisTimeSeries(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo","ts":1610000000000,"value":21.5}`),
+		[]byte(`{"id":"bar","ts":"nope","value":20}`),
+		[]byte(`{"id":"baz","ts":1610000000000,"value":"nope"}`),
+	})
+	msg.Get(0).Metadata().Set("site", "london")
+
+	args, err := r.addArgs(0, msg)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		"TS.ADD", "temp:foo", "1610000000000", 21.5,
+		"RETENTION", int64(3600000),
+		"ON_DUPLICATE", "LAST",
+		"LABELS", "sensor", "foo", "site", "london",
+	}, args)
+
+	_, err = r.addArgs(1, msg)
+	assert.Error(t, err)
+
+	_, err = r.addArgs(2, msg)
+	assert.Error(t, err)
+}
+
+func TestRedisTimeSeriesBadConfig(t *testing.T) {
+	conf := NewRedisTimeSeriesConfig()
+	conf.OnDuplicate = "nope"
+
+	_, err := NewRedisTimeSeries(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestRedisJSONArgs(t *testing.T) {
+	conf := NewRedisJSONConfig()
+	conf.Key = `doc:${! json("id") }`
+	conf.Path = `.status`
+	conf.Value = `${! json("status").format_json() }`
+	conf.Condition = "XX"
+
+	r, err := NewRedisJSON(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msg := message.New([][]byte{
+		[]byte(`{"id":"foo","status":"active"}`),
+	})
+
+	assert.Equal(t, []interface{}{
+		"JSON.SET", "doc:foo", ".status", []byte(`"active"`), "XX",
+	}, r.setArgs(0, msg))
+}
+
+func TestRedisStreamsArgs(t *testing.T) {
+	conf := NewRedisStreamsConfig()
+	conf.Stream = `events:${! meta("tenant") }`
+	conf.MaxLenApprox = 100
+	conf.NoMkStream = true
+
+	r, err := NewRedisStreams(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{
+		"XADD", "events:foo", "NOMKSTREAM", "MAXLEN", "~", int64(100), "*", "body", []byte("hello"),
+	}, r.xaddArgs("events:foo", map[string]interface{}{
+		"body": []byte("hello"),
+	}))
+
+	r.conf.MaxLenExact = true
+	r.conf.NoMkStream = false
+	assert.Equal(t, []interface{}{
+		"XADD", "events:foo", "MAXLEN", int64(100), "*", "body", []byte("hello"),
+	}, r.xaddArgs("events:foo", map[string]interface{}{
+		"body": []byte("hello"),
+	}))
+}
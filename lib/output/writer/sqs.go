@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	batchInternal "github.com/Jeffail/benthos/v3/internal/batch"
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -21,6 +22,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/cenkalti/backoff/v4"
 )
 
@@ -71,7 +73,7 @@ type AmazonSQS struct {
 	conf AmazonSQSConfig
 
 	session *session.Session
-	sqs     *sqs.SQS
+	sqs     sqsiface.SQSAPI
 
 	backoffCtor func() backoff.BackOff
 
@@ -208,22 +210,35 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 	backOff := a.backoffCtor()
 
 	entries := []*sqs.SendMessageBatchRequestEntry{}
-	attrMap := map[string]sqsAttributes{}
+	entryMap := map[string]*sqs.SendMessageBatchRequestEntry{}
 	msg.Iter(func(i int, p types.Part) error {
 		id := strconv.FormatInt(int64(i), 10)
 		attrs := a.getSQSAttributes(msg, i)
-		attrMap[id] = attrs
 
-		entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+		entry := &sqs.SendMessageBatchRequestEntry{
 			Id:                     aws.String(id),
 			MessageBody:            aws.String(string(p.Get())),
 			MessageAttributes:      attrs.attrMap,
 			MessageGroupId:         attrs.groupID,
 			MessageDeduplicationId: attrs.dedupeID,
-		})
+		}
+		entryMap[id] = entry
+		entries = append(entries, entry)
 		return nil
 	})
 
+	// Entries that are rejected due to a sender fault are not retried here,
+	// instead they're flagged within a batch error so that only those messages
+	// are reattempted.
+	var batchErr *batchInternal.Error
+	failed := func(id string, err error) {
+		if batchErr == nil {
+			batchErr = batchInternal.NewError(msg, err)
+		}
+		index, _ := strconv.Atoi(id)
+		batchErr.Failed(index, err)
+	}
+
 	input := &sqs.SendMessageBatchInput{
 		QueueUrl: aws.String(a.conf.URL),
 		Entries:  entries,
@@ -236,47 +251,60 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 		entries = nil
 	}
 
+	// When the write is abandoned any entries already rejected as sender faults
+	// must still be reported, therefore the remaining entries are flagged with
+	// the given error and the batch error is returned instead.
+	abandon := func(err error) error {
+		if batchErr == nil {
+			return err
+		}
+		for _, e := range input.Entries {
+			failed(aws.StringValue(e.Id), err)
+		}
+		for _, e := range entries {
+			failed(aws.StringValue(e.Id), err)
+		}
+		return batchErr
+	}
+
 	var err error
 	for len(input.Entries) > 0 {
 		wait := backOff.NextBackOff()
 
 		var batchResult *sqs.SendMessageBatchOutput
-		if batchResult, err = a.sqs.SendMessageBatch(input); err != nil {
+		if batchResult, err = a.sqs.SendMessageBatchWithContext(ctx, input); err != nil {
 			a.log.Warnf("SQS error: %v\n", err)
 			// bail if a message is too large or all retry attempts expired
 			if wait == backoff.Stop {
-				return err
+				break
 			}
 			select {
 			case <-time.After(wait):
 			case <-ctx.Done():
-				return types.ErrTimeout
+				return abandon(types.ErrTimeout)
 			case <-a.closeChan:
 				return err
 			}
 			continue
 		}
 
+		input.Entries = nil
+		err = nil
 		if unproc := batchResult.Failed; len(unproc) > 0 {
-			input.Entries = []*sqs.SendMessageBatchRequestEntry{}
 			for _, v := range unproc {
-				if *v.SenderFault {
-					err = fmt.Errorf("record failed with code: %v, message: %v", *v.Code, *v.Message)
-					a.log.Errorf("SQS record error: %v\n", err)
-					return err
+				if aws.BoolValue(v.SenderFault) {
+					rErr := fmt.Errorf("record failed with code: %v, message: %v", aws.StringValue(v.Code), aws.StringValue(v.Message))
+					a.log.Errorf("SQS record error: %v\n", rErr)
+					failed(aws.StringValue(v.Id), rErr)
+					continue
+				}
+				if entry, exists := entryMap[aws.StringValue(v.Id)]; exists {
+					input.Entries = append(input.Entries, entry)
 				}
-				aMap := attrMap[*v.Id]
-				input.Entries = append(input.Entries, &sqs.SendMessageBatchRequestEntry{
-					Id:                     v.Id,
-					MessageBody:            v.Message,
-					MessageAttributes:      aMap.attrMap,
-					MessageGroupId:         aMap.groupID,
-					MessageDeduplicationId: aMap.dedupeID,
-				})
 			}
-			err = fmt.Errorf("failed to send %v messages", len(unproc))
-		} else {
-			input.Entries = nil
+			if len(input.Entries) > 0 {
+				err = fmt.Errorf("failed to send %v messages", len(input.Entries))
+			}
 		}
 
 		if err != nil {
@@ -286,7 +314,7 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 			select {
 			case <-time.After(wait):
 			case <-ctx.Done():
-				return types.ErrTimeout
+				return abandon(types.ErrTimeout)
 			case <-a.closeChan:
 				return err
 			}
@@ -303,7 +331,20 @@ func (a *AmazonSQS) WriteWithContext(ctx context.Context, msg types.Message) err
 		}
 	}
 
-	return err
+	if err != nil {
+		// Retries were exhausted, therefore every entry that has not yet been
+		// delivered is flagged as failed.
+		for _, e := range input.Entries {
+			failed(aws.StringValue(e.Id), err)
+		}
+		for _, e := range entries {
+			failed(aws.StringValue(e.Id), err)
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
 }
 
 // CloseAsync begins cleaning up resources used by this reader asynchronously.
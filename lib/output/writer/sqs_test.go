@@ -1,6 +1,25 @@
 package writer
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/batch"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
 
 func TestSQSHeaderCheck(t *testing.T) {
 	type testCase struct {
@@ -93,3 +112,145 @@ func TestSQSHeaderCheck(t *testing.T) {
 		}
 	}
 }
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	fn func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error)
+}
+
+func (m *mockSQS) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	return m.fn(input)
+}
+
+func TestSQSPartialBatchFailure(t *testing.T) {
+	conf := NewAmazonSQSConfig()
+	conf.Backoff.InitialInterval = "1ms"
+	conf.Backoff.MaxInterval = "1ms"
+	conf.Backoff.MaxElapsedTime = "100ms"
+
+	w, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	var reqMut sync.Mutex
+	var requests [][]string
+	w.session = session.Must(session.NewSession())
+	w.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			reqMut.Lock()
+			defer reqMut.Unlock()
+
+			var ids []string
+			for _, e := range input.Entries {
+				ids = append(ids, *e.Id)
+			}
+			requests = append(requests, ids)
+
+			output := &sqs.SendMessageBatchOutput{}
+			for _, e := range input.Entries {
+				switch *e.Id {
+				case "1":
+					output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+						Id:          e.Id,
+						Code:        aws.String("InvalidMessageContents"),
+						Message:     aws.String("bad message"),
+						SenderFault: aws.Bool(true),
+					})
+				case "11":
+					if len(requests) == 2 {
+						output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+							Id:          e.Id,
+							Code:        aws.String("InternalError"),
+							Message:     aws.String("try again"),
+							SenderFault: aws.Bool(false),
+						})
+					}
+				}
+			}
+			return output, nil
+		},
+	}
+
+	var parts [][]byte
+	for i := 0; i < 12; i++ {
+		parts = append(parts, []byte("hello world"))
+	}
+	msg := message.New(parts)
+
+	err = w.WriteWithContext(context.Background(), msg)
+	require.Error(t, err)
+
+	var bErr *batch.Error
+	require.True(t, errors.As(err, &bErr))
+
+	failed := map[int]error{}
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed[i] = err
+		}
+		return true
+	})
+	assert.Len(t, failed, 1)
+	assert.Contains(t, failed[1].Error(), "bad message")
+
+	reqMut.Lock()
+	assert.Equal(t, [][]string{
+		{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"},
+		{"10", "11"},
+		{"11"},
+	}, requests)
+	reqMut.Unlock()
+}
+
+func TestSQSPartialBatchFailureTimeout(t *testing.T) {
+	conf := NewAmazonSQSConfig()
+	conf.Backoff.InitialInterval = "10s"
+	conf.Backoff.MaxInterval = "10s"
+	conf.Backoff.MaxElapsedTime = "1m"
+
+	w, err := NewAmazonSQS(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	w.session = session.Must(session.NewSession())
+	w.sqs = &mockSQS{
+		fn: func(input *sqs.SendMessageBatchInput) (*sqs.SendMessageBatchOutput, error) {
+			return &sqs.SendMessageBatchOutput{
+				Failed: []*sqs.BatchResultErrorEntry{
+					{
+						Id:          aws.String("0"),
+						Code:        aws.String("InvalidMessageContents"),
+						Message:     aws.String("bad message"),
+						SenderFault: aws.Bool(true),
+					},
+					{
+						Id:          aws.String("1"),
+						Code:        aws.String("InternalError"),
+						Message:     aws.String("try again"),
+						SenderFault: aws.Bool(false),
+					},
+				},
+			}, nil
+		},
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	err = w.WriteWithContext(ctx, message.New([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("baz"),
+	}))
+	require.Error(t, err)
+
+	var bErr *batch.Error
+	require.True(t, errors.As(err, &bErr))
+
+	failed := map[int]error{}
+	bErr.WalkParts(func(i int, _ types.Part, err error) bool {
+		if err != nil {
+			failed[i] = err
+		}
+		return true
+	})
+	assert.Len(t, failed, 2)
+	assert.Contains(t, failed[0].Error(), "bad message")
+	assert.Equal(t, types.ErrTimeout, failed[1])
+}
@@ -3,7 +3,9 @@ package pipeline
 import (
 	"fmt"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -20,15 +22,19 @@ import (
 // number of parallel inputs that matches or surpasses the number of pipeline
 // threads, or use a memory buffer.
 type Config struct {
-	Threads    int                `json:"threads" yaml:"threads"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	Threads      int                 `json:"threads" yaml:"threads"`
+	OrderingKey  string              `json:"ordering_key" yaml:"ordering_key"`
+	Processors   []processor.Config  `json:"processors" yaml:"processors"`
+	ErrorStreams []ErrorStreamConfig `json:"error_streams" yaml:"error_streams"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Threads:    1,
-		Processors: []processor.Config{},
+		Threads:      1,
+		OrderingKey:  "",
+		Processors:   []processor.Config{},
+		ErrorStreams: []ErrorStreamConfig{},
 	}
 }
 
@@ -49,10 +55,17 @@ func (conf Config) Sanitised(removeDeprecated bool) (interface{}, error) {
 			return nil, err
 		}
 	}
-	return map[string]interface{}{
+	sanit := map[string]interface{}{
 		"threads":    conf.Threads,
 		"processors": procConfs,
-	}, nil
+	}
+	if len(conf.OrderingKey) > 0 {
+		sanit["ordering_key"] = conf.OrderingKey
+	}
+	if len(conf.ErrorStreams) > 0 {
+		sanit["error_streams"] = conf.ErrorStreams
+	}
+	return sanit, nil
 }
 
 //------------------------------------------------------------------------------
@@ -65,6 +78,16 @@ func New(
 	stats metrics.Type,
 	processorCtors ...types.ProcessorConstructorFunc,
 ) (Type, error) {
+	var errStreams *errorStreams
+	if len(conf.ErrorStreams) > 0 {
+		var err error
+		if errStreams, err = newErrorStreams(conf.ErrorStreams, mgr, log, stats); err != nil {
+			return nil, err
+		}
+	}
+
+	journeys := journey.FromManager(mgr)
+
 	procs := 0
 	procCtor := func(i *int) (types.Pipeline, error) {
 		processors := make([]types.Processor, len(conf.Processors)+len(processorCtors))
@@ -84,7 +107,17 @@ func New(
 				return nil, fmt.Errorf("failed to create processor: %v", err)
 			}
 		}
-		return NewProcessor(log, stats, processors...), nil
+		proc := NewProcessor(log, stats, processors...)
+		proc.errStreams = errStreams
+		proc.SetJourneyTap(journeys)
+		return proc, nil
+	}
+	if len(conf.OrderingKey) > 0 {
+		key, err := bloblang.NewField(conf.OrderingKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ordering key expression: %v", err)
+		}
+		return NewPool(procCtor, conf.Threads, log, stats, OptPoolSetOrderingKey(key))
 	}
 	if conf.Threads == 1 {
 		return procCtor(&procs)
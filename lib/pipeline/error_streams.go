@@ -0,0 +1,259 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/throttle"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrorStreamConfig is a configuration struct for a named stream that messages
+// which have failed processing are routed to.
+type ErrorStreamConfig struct {
+	Name     string `json:"name" yaml:"name"`
+	Check    string `json:"check" yaml:"check"`
+	Resource string `json:"resource" yaml:"resource"`
+}
+
+// NewErrorStreamConfig returns an ErrorStreamConfig with default values.
+func NewErrorStreamConfig() ErrorStreamConfig {
+	return ErrorStreamConfig{
+		Name:     "",
+		Check:    "",
+		Resource: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type outputProvider interface {
+	GetOutput(name string) (types.OutputWriter, error)
+}
+
+type errorStream struct {
+	name     string
+	check    *mapping.Executor
+	resource string
+
+	mRouted metrics.StatCounter
+	mSent   metrics.StatCounter
+	mErr    metrics.StatCounter
+}
+
+// errorStreams removes messages that have failed processing from batches and
+// writes them to the output resource of the first error stream that matches
+// them.
+type errorStreams struct {
+	mgr     outputProvider
+	log     log.Modular
+	streams []*errorStream
+}
+
+func newErrorStreams(
+	confs []ErrorStreamConfig, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (*errorStreams, error) {
+	// TODO: V4 Remove this
+	provider, ok := mgr.(outputProvider)
+	if !ok {
+		return nil, errors.New("manager does not support output resources")
+	}
+
+	e, err := parseErrorStreams(confs, log, stats)
+	if err != nil {
+		return nil, err
+	}
+	for _, conf := range confs {
+		if _, err := provider.GetOutput(conf.Resource); err != nil {
+			return nil, fmt.Errorf("failed to obtain output resource '%v' for error stream '%v': %v", conf.Resource, conf.Name, err)
+		}
+	}
+	e.mgr = provider
+	return e, nil
+}
+
+// parseErrorStreams creates error streams from their configs without
+// obtaining their output resources.
+func parseErrorStreams(confs []ErrorStreamConfig, log log.Modular, stats metrics.Type) (*errorStreams, error) {
+	e := &errorStreams{
+		log: log,
+	}
+	names := map[string]struct{}{}
+	for i, conf := range confs {
+		if conf.Name == "" {
+			return nil, fmt.Errorf("error stream %v requires a name", i)
+		}
+		if _, exists := names[conf.Name]; exists {
+			return nil, fmt.Errorf("error stream name '%v' is not unique", conf.Name)
+		}
+		names[conf.Name] = struct{}{}
+
+		prefix := "error_stream." + conf.Name
+		s := &errorStream{
+			name:     conf.Name,
+			resource: conf.Resource,
+			mRouted:  stats.GetCounter(prefix + ".routed"),
+			mSent:    stats.GetCounter(prefix + ".sent"),
+			mErr:     stats.GetCounter(prefix + ".error"),
+		}
+		if len(conf.Check) > 0 {
+			var err error
+			if s.check, err = bloblang.NewMapping("", conf.Check); err != nil {
+				return nil, fmt.Errorf("failed to parse error stream '%v' check mapping: %v", conf.Name, err)
+			}
+		}
+		e.streams = append(e.streams, s)
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+// match returns the index of the first error stream that matches a failed
+// message, or -1 if none match.
+func (e *errorStreams) match(index int, msg types.Message) int {
+	for i, s := range e.streams {
+		if s.check == nil {
+			return i
+		}
+		test, err := s.check.QueryPart(index, msg)
+		if err != nil {
+			e.log.Errorf("Failed to test error stream '%v': %v\n", s.name, err)
+			continue
+		}
+		if test {
+			return i
+		}
+	}
+	return -1
+}
+
+// RouteErrors removes failed messages from a slice of batches that match the
+// error streams of a pipeline config, and returns the remaining batches along
+// with a batch of the messages routed to each error stream by its name. The
+// routed messages are not written to the output resources of the error
+// streams, which makes this useful for testing error stream configs.
+func RouteErrors(confs []ErrorStreamConfig, msgs []types.Message) ([]types.Message, map[string]types.Message, error) {
+	e, err := parseErrorStreams(confs, log.Noop(), metrics.Noop())
+	if err != nil {
+		return nil, nil, err
+	}
+	remaining, diverted := e.split(msgs)
+	routed := map[string]types.Message{}
+	for i, parts := range diverted {
+		if len(parts) == 0 {
+			continue
+		}
+		msg := message.New(nil)
+		msg.SetAll(parts)
+		routed[e.streams[i].name] = msg
+	}
+	return remaining, routed, nil
+}
+
+// divert writes failed messages from a slice of batches to the error streams
+// they match, blocking until each write has been acknowledged, and returns the
+// remaining batches with empty batches removed. If the provided channel is
+// closed before the writes are acknowledged false is returned.
+func (e *errorStreams) divert(msgs []types.Message, closeChan <-chan struct{}) ([]types.Message, bool) {
+	remaining, diverted := e.split(msgs)
+	for i, parts := range diverted {
+		if len(parts) == 0 {
+			continue
+		}
+		e.streams[i].mRouted.Incr(int64(len(parts)))
+		msg := message.New(nil)
+		msg.SetAll(parts)
+		if !e.write(e.streams[i], msg, closeChan) {
+			return nil, false
+		}
+	}
+	return remaining, true
+}
+
+// split removes failed messages from a slice of batches that match an error
+// stream, and returns the remaining batches with empty batches removed along
+// with the messages matched by each error stream.
+func (e *errorStreams) split(msgs []types.Message) ([]types.Message, [][]types.Part) {
+	diverted := make([][]types.Part, len(e.streams))
+	remaining := make([]types.Message, 0, len(msgs))
+
+	for _, msg := range msgs {
+		kept := make([]types.Part, 0, msg.Len())
+		msg.Iter(func(i int, p types.Part) error {
+			if !processor.HasFailed(p) {
+				kept = append(kept, p)
+				return nil
+			}
+			j := e.match(i, msg)
+			if j == -1 {
+				kept = append(kept, p)
+				return nil
+			}
+			p = p.Copy()
+			p.Metadata().
+				Set("error", processor.GetFail(p)).
+				Set("error_stream", e.streams[j].name)
+			diverted[j] = append(diverted[j], p)
+			return nil
+		})
+		if len(kept) == msg.Len() {
+			remaining = append(remaining, msg)
+		} else if len(kept) > 0 {
+			newMsg := message.New(nil)
+			newMsg.SetAll(kept)
+			remaining = append(remaining, newMsg)
+		}
+	}
+	return remaining, diverted
+}
+
+// write sends a batch to the output resource of an error stream and retries
+// until it is acknowledged.
+func (e *errorStreams) write(s *errorStream, msg types.Message, closeChan <-chan struct{}) bool {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	go func() {
+		select {
+		case <-closeChan:
+			done()
+		case <-ctx.Done():
+		}
+	}()
+
+	throt := throttle.New(throttle.OptCloseChan(closeChan))
+	resChan := make(chan types.Response)
+	for {
+		out, err := e.mgr.GetOutput(s.resource)
+		if err == nil {
+			if err = out.WriteTransaction(ctx, types.NewTransaction(msg, resChan)); err == nil {
+				select {
+				case res := <-resChan:
+					err = res.Error()
+				case <-closeChan:
+					return false
+				}
+			}
+		}
+		if err == nil {
+			s.mSent.Incr(int64(msg.Len()))
+			return true
+		}
+		s.mErr.Incr(1)
+		e.log.Errorf("Failed to send messages to error stream '%v': %v\n", s.name, err)
+		if !throt.Retry() {
+			return false
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
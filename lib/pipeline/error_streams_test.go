@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutputWriter struct {
+	tranChan chan types.Transaction
+}
+
+func (f *fakeOutputWriter) WriteTransaction(ctx context.Context, t types.Transaction) error {
+	select {
+	case f.tranChan <- t:
+	case <-ctx.Done():
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+func (f *fakeOutputWriter) Connected() bool {
+	return true
+}
+
+func (f *fakeOutputWriter) CloseAsync() {
+}
+
+func (f *fakeOutputWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+type fakeMgr struct {
+	outputs map[string]types.OutputWriter
+}
+
+func (f *fakeMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+}
+func (f *fakeMgr) GetCache(name string) (types.Cache, error) {
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (f *fakeMgr) GetOutput(name string) (types.OutputWriter, error) {
+	if o, exists := f.outputs[name]; exists {
+		return o, nil
+	}
+	return nil, types.ErrOutputNotFound
+}
+func (f *fakeMgr) GetPlugin(name string) (interface{}, error) {
+	return nil, types.ErrPluginNotFound
+}
+func (f *fakeMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func TestErrorStreams(t *testing.T) {
+	fooOut := &fakeOutputWriter{tranChan: make(chan types.Transaction)}
+	barOut := &fakeOutputWriter{tranChan: make(chan types.Transaction)}
+	mgr := &fakeMgr{
+		outputs: map[string]types.OutputWriter{
+			"foo": fooOut,
+			"bar": barOut,
+		},
+	}
+
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = `root = if content().has_prefix("bad") { throw(content()) } else { content().uppercase() }`
+
+	conf := NewConfig()
+	conf.Processors = append(conf.Processors, procConf)
+
+	fooConf := NewErrorStreamConfig()
+	fooConf.Name = "foo_errors"
+	fooConf.Check = `error().contains("foo")`
+	fooConf.Resource = "foo"
+
+	barConf := NewErrorStreamConfig()
+	barConf.Name = "bar_errors"
+	barConf.Resource = "bar"
+
+	conf.ErrorStreams = append(conf.ErrorStreams, fooConf, barConf)
+
+	pipe, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	require.NoError(t, pipe.Consume(tChan))
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{
+		[]byte("hello"),
+		[]byte("bad foo"),
+		[]byte("bad bar"),
+		[]byte("world"),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	for _, test := range []struct {
+		out    *fakeOutputWriter
+		stream string
+		exp    string
+	}{
+		{out: fooOut, stream: "foo_errors", exp: "bad foo"},
+		{out: barOut, stream: "bar_errors", exp: "bad bar"},
+	} {
+		select {
+		case tran := <-test.out.tranChan:
+			require.Equal(t, 1, tran.Payload.Len())
+			part := tran.Payload.Get(0)
+			assert.Equal(t, test.exp, string(part.Get()))
+			assert.Equal(t, test.stream, part.Metadata().Get("error_stream"))
+			assert.Contains(t, part.Metadata().Get("error"), test.exp)
+			select {
+			case tran.ResponseChan <- response.NewAck():
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case tran := <-pipe.TransactionChan():
+		assert.Equal(t, [][]byte{
+			[]byte("HELLO"),
+			[]byte("WORLD"),
+		}, message.GetAllBytes(tran.Payload))
+		select {
+		case tran.ResponseChan <- response.NewAck():
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// A batch where every message fails is acknowledged once the error
+	// stream has received it.
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{
+		[]byte("bad bar"),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// The first attempt is rejected and therefore retried.
+	for _, res := range []types.Response{
+		response.NewError(errMockProc),
+		response.NewAck(),
+	} {
+		select {
+		case tran := <-barOut.tranChan:
+			assert.Equal(t, [][]byte{[]byte("bad bar")}, message.GetAllBytes(tran.Payload))
+			select {
+			case tran.ResponseChan <- res:
+			case <-time.After(time.Second):
+				t.Fatal("timed out")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case res := <-resChan:
+		assert.NoError(t, res.Error())
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	pipe.CloseAsync()
+	require.NoError(t, pipe.WaitForClose(time.Second))
+}
+
+func TestErrorStreamsBadConfig(t *testing.T) {
+	mgr := &fakeMgr{
+		outputs: map[string]types.OutputWriter{
+			"foo": &fakeOutputWriter{},
+		},
+	}
+
+	tests := map[string]func(c *ErrorStreamConfig){
+		"no name": func(c *ErrorStreamConfig) {
+			c.Name = ""
+		},
+		"duplicate name": func(c *ErrorStreamConfig) {
+			c.Name = "first"
+		},
+		"missing resource": func(c *ErrorStreamConfig) {
+			c.Resource = "bar"
+		},
+		"bad check": func(c *ErrorStreamConfig) {
+			c.Check = "this is not bloblang"
+		},
+	}
+
+	for name, fn := range tests {
+		first := NewErrorStreamConfig()
+		first.Name = "first"
+		first.Resource = "foo"
+
+		second := NewErrorStreamConfig()
+		second.Name = "second"
+		second.Resource = "foo"
+		fn(&second)
+
+		conf := NewConfig()
+		conf.ErrorStreams = append(conf.ErrorStreams, first, second)
+
+		_, err := New(conf, mgr, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	fooConf := NewErrorStreamConfig()
+	fooConf.Name = "foo"
+	fooConf.Check = `error().contains("foo")`
+
+	barConf := NewErrorStreamConfig()
+	barConf.Name = "bar"
+
+	msg := message.New([][]byte{[]byte("good"), []byte("bad foo"), []byte("bad bar")})
+	processor.FlagErr(msg.Get(1), errors.New("foo failed"))
+	processor.FlagErr(msg.Get(2), errors.New("bar failed"))
+
+	remaining, routed, err := RouteErrors([]ErrorStreamConfig{fooConf, barConf}, []types.Message{msg})
+	require.NoError(t, err)
+
+	require.Len(t, remaining, 1)
+	assert.Equal(t, [][]byte{[]byte("good")}, message.GetAllBytes(remaining[0]))
+
+	require.Len(t, routed, 2)
+	assert.Equal(t, [][]byte{[]byte("bad foo")}, message.GetAllBytes(routed["foo"]))
+	assert.Equal(t, "foo", routed["foo"].Get(0).Metadata().Get("error_stream"))
+	assert.Equal(t, [][]byte{[]byte("bad bar")}, message.GetAllBytes(routed["bar"]))
+
+	_, _, err = RouteErrors([]ErrorStreamConfig{NewErrorStreamConfig()}, nil)
+	assert.EqualError(t, err, "error stream 0 requires a name")
+}
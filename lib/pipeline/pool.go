@@ -5,8 +5,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -18,7 +21,8 @@ import (
 type Pool struct {
 	running uint32
 
-	workers []types.Pipeline
+	workers     []types.Pipeline
+	orderingKey field.Expression
 
 	log   log.Modular
 	stats metrics.Type
@@ -30,12 +34,22 @@ type Pool struct {
 	closed    chan struct{}
 }
 
+// OptPoolSetOrderingKey sets an expression that resolves a key for each
+// message, where batches are split by key and each portion is only processed
+// once the previous portion of the same key has been delivered.
+func OptPoolSetOrderingKey(key field.Expression) func(*Pool) {
+	return func(p *Pool) {
+		p.orderingKey = key
+	}
+}
+
 // NewPool returns a new pipeline pool that utilises multiple processor threads.
 func NewPool(
 	constructor types.PipelineConstructorFunc,
 	threads int,
 	log log.Modular,
 	stats metrics.Type,
+	opts ...func(*Pool),
 ) (*Pool, error) {
 	if threads <= 0 {
 		threads = runtime.NumCPU()
@@ -51,6 +65,10 @@ func NewPool(
 		closed:      make(chan struct{}),
 	}
 
+	for _, opt := range opts {
+		opt(p)
+	}
+
 	for i := range p.workers {
 		procs := 0
 		var err error
@@ -90,8 +108,16 @@ func (p *Pool) loop() {
 	internalMessages := make(chan types.Transaction)
 	remainingWorkers := int64(len(p.workers))
 
-	for _, worker := range p.workers {
-		if err := worker.Consume(p.messagesIn); err != nil {
+	workerInputs := make([]<-chan types.Transaction, len(p.workers))
+	for i := range workerInputs {
+		workerInputs[i] = p.messagesIn
+	}
+	if p.orderingKey != nil {
+		workerInputs = p.dispatchOrdered()
+	}
+
+	for i, worker := range p.workers {
+		if err := worker.Consume(workerInputs[i]); err != nil {
 			p.log.Errorf("Failed to start pipeline worker: %v\n", err)
 			atomic.AddInt64(&remainingWorkers, -1)
 			continue
@@ -139,6 +165,176 @@ func (p *Pool) loop() {
 	}
 }
 
+// keyedTransaction is a transaction, or the portion of a transaction batch
+// that shares an ordering key, waiting to be dispatched to a worker.
+type keyedTransaction struct {
+	key      string
+	payload  types.Message
+	upstream chan<- types.Response
+}
+
+// splitByKey resolves the ordering key of each message of a transaction and
+// splits the batch into a transaction per key, preserving the order of
+// messages within each key. When the batch spans multiple keys the response
+// of the original transaction is only sent once all portions have been
+// delivered, and is an error if any of them failed.
+func (p *Pool) splitByKey(t types.Transaction) []keyedTransaction {
+	var keys []string
+	parts := map[string]types.Message{}
+	t.Payload.Iter(func(i int, part types.Part) error {
+		key := p.orderingKey.String(i, t.Payload)
+		msg, exists := parts[key]
+		if !exists {
+			msg = message.New(nil)
+			parts[key] = msg
+			keys = append(keys, key)
+		}
+		msg.Append(part)
+		return nil
+	})
+
+	if len(keys) <= 1 {
+		var key string
+		if len(keys) == 1 {
+			key = keys[0]
+		}
+		return []keyedTransaction{{
+			key:      key,
+			payload:  t.Payload,
+			upstream: t.ResponseChan,
+		}}
+	}
+
+	resChan := make(chan types.Response, len(keys))
+	go func() {
+		var err error
+		for range keys {
+			select {
+			case res := <-resChan:
+				if res.Error() != nil {
+					err = res.Error()
+				}
+			case <-p.closeChan:
+				return
+			}
+		}
+		var res types.Response = response.NewAck()
+		if err != nil {
+			res = response.NewError(err)
+		}
+		select {
+		case t.ResponseChan <- res:
+		case <-p.closeChan:
+		}
+	}()
+
+	split := make([]keyedTransaction, len(keys))
+	for i, key := range keys {
+		split[i] = keyedTransaction{
+			key:      key,
+			payload:  parts[key],
+			upstream: resChan,
+		}
+	}
+	return split
+}
+
+// dispatchOrdered routes transactions from the input channel to the workers,
+// holding back each transaction in a queue of its ordering key until the
+// previous transaction of the same key has been delivered. Transactions are
+// read ahead of the workers, so a key that is blocked does not prevent
+// transactions of other keys from being processed, but reading stops whilst
+// the number of transactions queued or in flight reaches twice the number of
+// workers in order to preserve back pressure.
+func (p *Pool) dispatchOrdered() []<-chan types.Transaction {
+	workerInput := make(chan types.Transaction)
+	workerInputs := make([]<-chan types.Transaction, len(p.workers))
+	for i := range workerInputs {
+		workerInputs[i] = workerInput
+	}
+
+	go func() {
+		defer close(workerInput)
+
+		// Keys with a transaction in flight, mapped to the transactions of the
+		// same key queued behind it.
+		pending := map[string][]keyedTransaction{}
+		var ready []keyedTransaction
+		inFlight, held := 0, 0
+		maxHeld := len(p.workers) * 2
+
+		delivered := make(chan string)
+		msgsIn := p.messagesIn
+
+		for msgsIn != nil || len(ready) > 0 || inFlight > 0 {
+			var dispatchChan chan<- types.Transaction
+			var next types.Transaction
+			var resChan chan types.Response
+			if len(ready) > 0 {
+				dispatchChan = workerInput
+				resChan = make(chan types.Response)
+				next = types.NewTransaction(ready[0].payload, resChan)
+			}
+
+			readChan := msgsIn
+			if held >= maxHeld {
+				readChan = nil
+			}
+
+			select {
+			case t, open := <-readChan:
+				if !open {
+					msgsIn = nil
+					continue
+				}
+				for _, kt := range p.splitByKey(t) {
+					held++
+					if queue, active := pending[kt.key]; active {
+						pending[kt.key] = append(queue, kt)
+					} else {
+						pending[kt.key] = nil
+						ready = append(ready, kt)
+					}
+				}
+			case dispatchChan <- next:
+				kt := ready[0]
+				ready = ready[1:]
+				inFlight++
+				go func() {
+					var res types.Response
+					select {
+					case res = <-resChan:
+					case <-p.closeChan:
+						return
+					}
+					select {
+					case kt.upstream <- res:
+					case <-p.closeChan:
+						return
+					}
+					select {
+					case delivered <- kt.key:
+					case <-p.closeChan:
+					}
+				}()
+			case key := <-delivered:
+				inFlight--
+				held--
+				if queue := pending[key]; len(queue) > 0 {
+					ready = append(ready, queue[0])
+					pending[key] = queue[1:]
+				} else {
+					delete(pending, key)
+				}
+			case <-p.closeChan:
+				return
+			}
+		}
+	}()
+
+	return workerInputs
+}
+
 //------------------------------------------------------------------------------
 
 // Consume assigns a messages channel for the pipeline to read.
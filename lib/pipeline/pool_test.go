@@ -1,6 +1,7 @@
 package pipeline
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -12,6 +13,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPoolBasic(t *testing.T) {
@@ -323,3 +326,207 @@ func TestPoolMultiNaturalClose(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestPoolOrderingKey(t *testing.T) {
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = `root = this.value.uppercase()`
+
+	conf := NewConfig()
+	conf.Threads = 2
+	conf.OrderingKey = `${! json("key") }`
+	conf.Processors = append(conf.Processors, procConf)
+
+	pipe, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, pipe.Consume(tChan))
+
+	sendMsg := func(key, value string) <-chan types.Response {
+		t.Helper()
+		resChan := make(chan types.Response, 1)
+		select {
+		case tChan <- types.NewTransaction(message.New([][]byte{
+			[]byte(fmt.Sprintf(`{"key":"%v","value":"%v"}`, key, value)),
+		}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return resChan
+	}
+
+	readMsg := func() types.Transaction {
+		t.Helper()
+		select {
+		case tran := <-pipe.TransactionChan():
+			return tran
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return types.Transaction{}
+	}
+
+	awaitRes := func(resChan <-chan types.Response) types.Response {
+		t.Helper()
+		select {
+		case res := <-resChan:
+			return res
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return nil
+	}
+
+	resA1 := sendMsg("a", "first")
+	tranA1 := readMsg()
+	assert.Equal(t, `"FIRST"`, string(tranA1.Payload.Get(0).Get()))
+
+	resA2 := sendMsg("a", "second")
+	select {
+	case <-pipe.TransactionChan():
+		t.Fatal("message processed before the previous message of its key was delivered")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// Key b must not be held back behind the pending message of key a.
+	resB := sendMsg("b", "third")
+	tranB := readMsg()
+	assert.Equal(t, `"THIRD"`, string(tranB.Payload.Get(0).Get()))
+
+	tranB.ResponseChan <- response.NewAck()
+	assert.NoError(t, awaitRes(resB).Error())
+
+	tranA1.ResponseChan <- response.NewAck()
+	assert.NoError(t, awaitRes(resA1).Error())
+
+	tranA2 := readMsg()
+	assert.Equal(t, `"SECOND"`, string(tranA2.Payload.Get(0).Get()))
+	tranA2.ResponseChan <- response.NewAck()
+	assert.NoError(t, awaitRes(resA2).Error())
+
+	pipe.CloseAsync()
+	require.NoError(t, pipe.WaitForClose(time.Second))
+}
+
+func TestPoolOrderingKeyMixedBatch(t *testing.T) {
+	procConf := processor.NewConfig()
+	procConf.Type = processor.TypeBloblang
+	procConf.Bloblang = `root = this.value.uppercase()`
+
+	conf := NewConfig()
+	conf.Threads = 2
+	conf.OrderingKey = `${! json("key") }`
+	conf.Processors = append(conf.Processors, procConf)
+
+	pipe, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, pipe.Consume(tChan))
+
+	resChan := make(chan types.Response, 1)
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{
+		[]byte(`{"key":"a","value":"foo"}`),
+		[]byte(`{"key":"b","value":"bar"}`),
+		[]byte(`{"key":"a","value":"baz"}`),
+	}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	trans := map[string]types.Transaction{}
+	for i := 0; i < 2; i++ {
+		select {
+		case tran := <-pipe.TransactionChan():
+			trans[string(tran.Payload.Get(0).Get())] = tran
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	tranA, tranB := trans[`"FOO"`], trans[`"BAR"`]
+	require.NotNil(t, tranA.Payload)
+	require.NotNil(t, tranB.Payload)
+	assert.Equal(t, [][]byte{[]byte(`"FOO"`), []byte(`"BAZ"`)}, message.GetAllBytes(tranA.Payload))
+	assert.Equal(t, [][]byte{[]byte(`"BAR"`)}, message.GetAllBytes(tranB.Payload))
+
+	tranA.ResponseChan <- response.NewAck()
+	select {
+	case <-resChan:
+		t.Fatal("response received before all keys of the batch were delivered")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	tranB.ResponseChan <- response.NewError(errors.New("nope"))
+	select {
+	case res := <-resChan:
+		assert.EqualError(t, res.Error(), "nope")
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	pipe.CloseAsync()
+	require.NoError(t, pipe.WaitForClose(time.Second))
+}
+
+func TestPoolOrderingKeyBackPressure(t *testing.T) {
+	conf := NewConfig()
+	conf.Threads = 1
+	conf.OrderingKey = `${! json("key") }`
+
+	pipe, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	tChan := make(chan types.Transaction)
+	require.NoError(t, pipe.Consume(tChan))
+
+	newTran := func(value string) types.Transaction {
+		return types.NewTransaction(message.New([][]byte{
+			[]byte(fmt.Sprintf(`{"key":"a","value":"%v"}`, value)),
+		}), make(chan types.Response, 1))
+	}
+
+	for _, v := range []string{"first", "second"} {
+		select {
+		case tChan <- newTran(v):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	var tranA1 types.Transaction
+	select {
+	case tranA1 = <-pipe.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// With one transaction in flight and another queued behind it the limit
+	// is reached, and therefore further transactions are not read.
+	select {
+	case tChan <- newTran("third"):
+		t.Fatal("transaction read beyond the limit of queued transactions")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	tranA1.ResponseChan <- response.NewAck()
+	select {
+	case tChan <- newTran("third"):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	pipe.CloseAsync()
+	require.NoError(t, pipe.WaitForClose(time.Second))
+}
+
+func TestPoolBadOrderingKey(t *testing.T) {
+	conf := NewConfig()
+	conf.Threads = 2
+	conf.OrderingKey = `${! json( }`
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
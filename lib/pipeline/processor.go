@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/response"
@@ -25,6 +26,8 @@ type Processor struct {
 	stats metrics.Type
 
 	msgProcessors []types.Processor
+	errStreams    *errorStreams
+	journeys      *journey.Tap
 
 	messagesOut chan types.Transaction
 	responsesIn chan types.Response
@@ -43,6 +46,7 @@ func NewProcessor(
 ) *Processor {
 	return &Processor{
 		running:       1,
+		log:           log,
 		msgProcessors: msgProcessors,
 		stats:         stats,
 		messagesOut:   make(chan types.Transaction),
@@ -52,6 +56,12 @@ func NewProcessor(
 	}
 }
 
+// SetJourneyTap sets a tap that samples the journeys of messages through the
+// processors of the pipeline, where nil disables sampling.
+func (p *Processor) SetJourneyTap(tap *journey.Tap) {
+	p.journeys = tap
+}
+
 //------------------------------------------------------------------------------
 
 // loop is the processing loop of this pipeline.
@@ -78,6 +88,9 @@ func (p *Processor) loop() {
 			return
 		}
 
+		if p.journeys != nil && p.journeys.Rate() > 0 {
+			p.journeys.Sample(tran.Payload)
+		}
 		resultMsgs, resultRes := processor.ExecuteAll(p.msgProcessors, tran.Payload)
 		if len(resultMsgs) == 0 {
 			if resultRes == nil {
@@ -92,6 +105,21 @@ func (p *Processor) loop() {
 			continue
 		}
 
+		if p.errStreams != nil {
+			var ok bool
+			if resultMsgs, ok = p.errStreams.divert(resultMsgs, p.closeChan); !ok {
+				return
+			}
+			if len(resultMsgs) == 0 {
+				select {
+				case tran.ResponseChan <- response.NewAck():
+				case <-p.closeChan:
+					return
+				}
+				continue
+			}
+		}
+
 		if len(resultMsgs) > 1 {
 			p.dispatchMessages(resultMsgs, tran.ResponseChan)
 		} else {
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -403,3 +404,48 @@ func TestProcessorMultiMsgsOddSync(t *testing.T) {
 		t.Error("Expected mockproc to have waited for close")
 	}
 }
+
+func TestProcessorJourneys(t *testing.T) {
+	mockProc := &mockMsgProcessor{dropChan: make(chan bool)}
+	go func() {
+		mockProc.dropChan <- false
+	}()
+
+	tap := journey.NewTap()
+	tap.SetRate(1)
+
+	proc := NewProcessor(log.Noop(), metrics.Noop(), mockProc)
+	proc.SetJourneyTap(tap)
+
+	tChan, resChan := make(chan types.Transaction), make(chan types.Response)
+	if err := proc.Consume(tChan); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case tChan <- types.NewTransaction(message.New([][]byte{[]byte(`one`)}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case <-proc.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	journeys := tap.Recent()
+	if len(journeys) != 1 {
+		t.Fatalf("Wrong count of journeys: %v", len(journeys))
+	}
+	if exp, act := "one", journeys[0].Input; exp != act {
+		t.Errorf("Wrong journey input: %v != %v", act, exp)
+	}
+	if exp, act := 1, len(journeys[0].Steps); exp != act {
+		t.Errorf("Wrong count of journey steps: %v != %v", act, exp)
+	}
+
+	proc.CloseAsync()
+	if err := proc.WaitForClose(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
@@ -44,11 +44,15 @@ of the batch.`,
 		},
 		UsesBatches: true,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("format", "The archiving [format](#formats) to apply.").HasOptions("tar", "zip", "binary", "lines", "json_array"),
+			docs.FieldCommon("format", "The archiving [format](#formats) to apply.").HasOptions("tar", "zip", "binary", "lines", "json_array", "parquet"),
 			docs.FieldCommon(
 				"path", "The path to set for each message in the archive (when applicable).",
 				"${!count(\"files\")}-${!timestamp_unix_nano()}.txt", "${!meta(\"kafka_key\")}-${!json(\"id\")}.json",
 			).SupportsInterpolation(false),
+			docs.FieldAdvanced(
+				"parquet_schema", "An optional schema to use when archiving with the `parquet` format, in the JSON schema format of [parquet-go](https://github.com/xitongsys/parquet-go#json). When empty a schema is inferred from the messages of each batch.",
+				`{"Tag":"name=root, repetitiontype=REQUIRED","Fields":[{"Tag":"name=id, type=INT64, repetitiontype=REQUIRED"},{"Tag":"name=name, type=UTF8, repetitiontype=OPTIONAL"}]}`,
+			).AtVersion("3.39.0"),
 		},
 		Footnotes: `
 ## Formats
@@ -79,6 +83,20 @@ Join the raw contents of each message and insert a line break between each one.
 Attempt to parse each message as a JSON document and append the result to an
 array, which becomes the contents of the resulting message.
 
+### ` + "`parquet`" + `
+
+Parse each message as a JSON object and write them as the rows of a
+[Parquet](https://parquet.apache.org/) file, which becomes the contents of the
+resulting message.
+
+The schema of the file can be set with the field ` + "`parquet_schema`" + `,
+otherwise it is inferred from the messages of the batch: strings, booleans,
+numbers, objects and arrays become ` + "`UTF8`" + `, ` + "`BOOLEAN`" + `,
+` + "`INT64`" + ` or ` + "`DOUBLE`" + `, group and ` + "`LIST`" + ` columns
+respectively, and all columns are optional. Since an inferred schema can differ
+between batches it is recommended to set a schema explicitly when the files are
+consumed by other systems.
+
 ## Examples
 
 If we had JSON messages in a batch each of the form:
@@ -103,16 +121,18 @@ archive:
 
 // ArchiveConfig contains configuration fields for the Archive processor.
 type ArchiveConfig struct {
-	Format string `json:"format" yaml:"format"`
-	Path   string `json:"path" yaml:"path"`
+	Format        string `json:"format" yaml:"format"`
+	Path          string `json:"path" yaml:"path"`
+	ParquetSchema string `json:"parquet_schema" yaml:"parquet_schema"`
 }
 
 // NewArchiveConfig returns a ArchiveConfig with default values.
 func NewArchiveConfig() ArchiveConfig {
 	return ArchiveConfig{
 		// TODO: V4 change this default
-		Format: "binary",
-		Path:   `${!count("files")}-${!timestamp_unix_nano()}.txt`,
+		Format:        "binary",
+		Path:          `${!count("files")}-${!timestamp_unix_nano()}.txt`,
+		ParquetSchema: "",
 	}
 }
 
@@ -221,8 +241,8 @@ func jsonArrayArchive(hFunc headerFunc, msg types.Message) (types.Part, error) {
 	return newPart, nil
 }
 
-func strToArchiver(str string) (archiveFunc, error) {
-	switch str {
+func strToArchiver(conf ArchiveConfig) (archiveFunc, error) {
+	switch conf.Format {
 	case "tar":
 		return tarArchive, nil
 	case "zip":
@@ -233,8 +253,15 @@ func strToArchiver(str string) (archiveFunc, error) {
 		return linesArchive, nil
 	case "json_array":
 		return jsonArrayArchive, nil
+	case "parquet":
+		if conf.ParquetSchema != "" {
+			if err := validateParquetSchema(conf.ParquetSchema); err != nil {
+				return nil, fmt.Errorf("failed to parse parquet schema: %v", err)
+			}
+		}
+		return newParquetArchiver(conf.ParquetSchema), nil
 	}
-	return nil, fmt.Errorf("archive format not recognised: %v", str)
+	return nil, fmt.Errorf("archive format not recognised: %v", conf.Format)
 }
 
 //------------------------------------------------------------------------------
@@ -265,7 +292,7 @@ func NewArchive(
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse path expression: %v", err)
 	}
-	archiver, err := strToArchiver(conf.Archive.Format)
+	archiver, err := strToArchiver(conf.Archive)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +39,33 @@ specifies how the source documents are encoded.
 ### ` + "`from_json`" + `
 
 Attempts to convert JSON documents into Avro documents according to the
-specified encoding.`,
+specified encoding.
+
+## Schema Registry
+
+When ` + "`schema_registry`" + ` is enabled messages are encoded and decoded
+in the Confluent wire format, where the Avro binary encoding of a document is
+prefixed with the ID of its schema, and the field ` + "`encoding`" + ` is
+ignored.
+
+When decoding with ` + "`to_json`" + ` the writer schema of each message is
+obtained from the registry by its ID, and schemas are cached so that each is
+only fetched once. If a ` + "`schema`" + ` or ` + "`schema_path`" + ` is also
+provided then it is used as a reader schema, where documents are projected onto
+it by removing fields that it lacks and adding defaults for fields that the
+writer schema lacks.
+
+When encoding with ` + "`from_json`" + ` a ` + "`schema`" + ` or
+` + "`schema_path`" + ` must be provided, and the ID of the schema is obtained
+from the registry under a subject determined by the
+` + "`subject_name_strategy`" + `:
+
+- ` + "`topic_name`" + ` uses the subject ` + "`<topic>-value`" + `, or ` + "`<topic>-key`" + ` when ` + "`key`" + ` is ` + "`true`" + `.
+- ` + "`record_name`" + ` uses the fully qualified name of the schema.
+- ` + "`topic_record_name`" + ` uses the subject ` + "`<topic>-<fully qualified name>`" + `.
+
+Schemas are registered under their subject when ` + "`auto_register`" + ` is
+enabled, otherwise they must already be registered.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
 			docs.FieldCommon("encoding", "An Avro encoding format to use for conversions to and from a schema.").HasOptions("textual", "binary", "single"),
@@ -49,6 +75,7 @@ specified encoding.`,
 				"file://path/to/spec.avsc",
 				"http://localhost:8081/path/to/spec/versions/1",
 			),
+			avroSchemaRegistryFieldSpec(),
 			partsFieldSpec,
 		},
 	}
@@ -58,21 +85,23 @@ specified encoding.`,
 
 // AvroConfig contains configuration fields for the Avro processor.
 type AvroConfig struct {
-	Parts      []int  `json:"parts" yaml:"parts"`
-	Operator   string `json:"operator" yaml:"operator"`
-	Encoding   string `json:"encoding" yaml:"encoding"`
-	Schema     string `json:"schema" yaml:"schema"`
-	SchemaPath string `json:"schema_path" yaml:"schema_path"`
+	Parts          []int                    `json:"parts" yaml:"parts"`
+	Operator       string                   `json:"operator" yaml:"operator"`
+	Encoding       string                   `json:"encoding" yaml:"encoding"`
+	Schema         string                   `json:"schema" yaml:"schema"`
+	SchemaPath     string                   `json:"schema_path" yaml:"schema_path"`
+	SchemaRegistry AvroSchemaRegistryConfig `json:"schema_registry" yaml:"schema_registry"`
 }
 
 // NewAvroConfig returns a AvroConfig with default values.
 func NewAvroConfig() AvroConfig {
 	return AvroConfig{
-		Parts:      []int{},
-		Operator:   "to_json",
-		Encoding:   "textual",
-		Schema:     "",
-		SchemaPath: "",
+		Parts:          []int{},
+		Operator:       "to_json",
+		Encoding:       "textual",
+		Schema:         "",
+		SchemaPath:     "",
+		SchemaRegistry: NewAvroSchemaRegistryConfig(),
 	}
 }
 
@@ -80,6 +109,8 @@ func NewAvroConfig() AvroConfig {
 
 type avroOperator func(part types.Part) error
 
+type avroRegistryOperator func(index int, msg types.Message, part types.Part) error
+
 func newAvroToJSONOperator(encoding string, codec *goavro.Codec) (avroOperator, error) {
 	switch encoding {
 	case "textual":
@@ -200,8 +231,9 @@ func loadSchema(schemaPath string) (string, error) {
 
 // Avro is a processor that performs an operation on an Avro payload.
 type Avro struct {
-	parts    []int
-	operator avroOperator
+	parts            []int
+	operator         avroOperator
+	registryOperator avroRegistryOperator
 
 	conf  Config
 	log   log.Modular
@@ -244,6 +276,13 @@ func NewAvro(
 		schema = conf.Avro.Schema
 	}
 
+	if conf.Avro.SchemaRegistry.Enabled {
+		if a.registryOperator, err = newAvroSchemaRegistryOperator(conf.Avro.Operator, conf.Avro.SchemaRegistry, schema); err != nil {
+			return nil, err
+		}
+		return a, nil
+	}
+
 	codec, err := goavro.NewCodec(schema)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse schema: %v", err)
@@ -264,7 +303,13 @@ func (p *Avro) ProcessMessage(msg types.Message) ([]types.Message, types.Respons
 	newMsg := msg.Copy()
 
 	proc := func(index int, span opentracing.Span, part types.Part) error {
-		if err := p.operator(part); err != nil {
+		var err error
+		if p.registryOperator != nil {
+			err = p.registryOperator(index, newMsg, part)
+		} else {
+			err = p.operator(part)
+		}
+		if err != nil {
 			p.mErr.Incr(1)
 			p.log.Debugf("Operator failed: %v\n", err)
 			return err
@@ -0,0 +1,435 @@
+package processor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/linkedin/goavro/v2"
+)
+
+//------------------------------------------------------------------------------
+
+// AvroSchemaRegistryConfig contains configuration fields for encoding and
+// decoding Avro messages with schemas from a schema registry.
+type AvroSchemaRegistryConfig struct {
+	Enabled              bool `json:"enabled" yaml:"enabled"`
+	SchemaRegistryConfig `json:",inline" yaml:",inline"`
+	SubjectNameStrategy  string `json:"subject_name_strategy" yaml:"subject_name_strategy"`
+	Topic                string `json:"topic" yaml:"topic"`
+	Key                  bool   `json:"key" yaml:"key"`
+	AutoRegister         bool   `json:"auto_register" yaml:"auto_register"`
+}
+
+// NewAvroSchemaRegistryConfig returns a AvroSchemaRegistryConfig with default
+// values.
+func NewAvroSchemaRegistryConfig() AvroSchemaRegistryConfig {
+	return AvroSchemaRegistryConfig{
+		Enabled:              false,
+		SchemaRegistryConfig: NewSchemaRegistryConfig(),
+		SubjectNameStrategy:  "topic_name",
+		Topic:                `${! meta("kafka_topic") }`,
+		Key:                  false,
+		AutoRegister:         true,
+	}
+}
+
+func avroSchemaRegistryFieldSpec() docs.FieldSpec {
+	children := docs.FieldSpecs{
+		docs.FieldCommon("enabled", "Whether to encode and decode messages using the schema registry wire format."),
+	}
+	children = append(children, schemaRegistryFieldSpecs()...)
+	children = append(children,
+		docs.FieldCommon("subject_name_strategy", "The strategy used in order to determine the subject to register schemas under when encoding.").HasOptions(
+			"topic_name", "record_name", "topic_record_name",
+		),
+		docs.FieldCommon("topic", "The topic used by the subject name strategies `topic_name` and `topic_record_name`.").SupportsInterpolation(false),
+		docs.FieldAdvanced("key", "Whether schemas describe message keys rather than values, which changes the subject suffix of the `topic_name` strategy from `-value` to `-key`."),
+		docs.FieldAdvanced("auto_register", "Whether to register schemas when encoding. When disabled the schema must already be registered under the subject."),
+	)
+	return docs.FieldAdvanced(
+		"schema_registry", "Allows you to encode and decode messages in the Confluent wire format, where writer schemas are obtained from a schema registry by their ID.",
+	).WithChildren(children...).AtVersion("3.39.0")
+}
+
+//------------------------------------------------------------------------------
+
+// avroSchemaFullName returns the fully qualified name of a named Avro schema.
+func avroSchemaFullName(schema map[string]interface{}, enclosingNamespace string) string {
+	name, _ := schema["name"].(string)
+	if strings.Contains(name, ".") {
+		return name
+	}
+	namespace := enclosingNamespace
+	if ns, exists := schema["namespace"].(string); exists {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name
+	}
+	return namespace + "." + name
+}
+
+func avroNamespaceOf(fullName string) string {
+	if i := strings.LastIndex(fullName, "."); i >= 0 {
+		return fullName[:i]
+	}
+	return ""
+}
+
+// avroProjector projects values decoded with a writer schema onto a reader
+// schema, where record fields that the writer lacks are populated with the
+// defaults of the reader and fields that the reader lacks are removed.
+type avroProjector struct {
+	named map[string]interface{}
+}
+
+func (a *avroProjector) unionBranchName(schema interface{}, namespace string) string {
+	switch t := schema.(type) {
+	case string:
+		if _, exists := a.named[t]; !exists && !strings.Contains(t, ".") && namespace != "" {
+			if _, exists := a.named[namespace+"."+t]; exists {
+				return namespace + "." + t
+			}
+		}
+		return t
+	case map[string]interface{}:
+		switch t["type"] {
+		case "record", "enum", "fixed":
+			return avroSchemaFullName(t, namespace)
+		}
+		typeStr, _ := t["type"].(string)
+		return typeStr
+	}
+	return ""
+}
+
+func (a *avroProjector) project(schema interface{}, namespace string, value interface{}) (interface{}, error) {
+	switch t := schema.(type) {
+	case string:
+		if named, exists := a.named[t]; exists {
+			return a.project(named, avroNamespaceOf(t), value)
+		}
+		if namespace != "" {
+			if named, exists := a.named[namespace+"."+t]; exists {
+				return a.project(named, namespace, value)
+			}
+		}
+		return value, nil
+	case []interface{}:
+		if value == nil {
+			return nil, nil
+		}
+		wrapped, ok := value.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return value, nil
+		}
+		for branchName, branchValue := range wrapped {
+			for _, branch := range t {
+				if a.unionBranchName(branch, namespace) != branchName {
+					continue
+				}
+				projected, err := a.project(branch, namespace, branchValue)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{branchName: projected}, nil
+			}
+			return nil, fmt.Errorf("union branch '%v' does not exist in reader schema", branchName)
+		}
+	case map[string]interface{}:
+		switch t["type"] {
+		case "record", "error":
+			fullName := avroSchemaFullName(t, namespace)
+			a.named[fullName] = t
+			return a.projectRecord(t, avroNamespaceOf(fullName), value)
+		case "enum", "fixed":
+			a.named[avroSchemaFullName(t, namespace)] = t
+		case "array":
+			values, ok := value.([]interface{})
+			if !ok {
+				return value, nil
+			}
+			projected := make([]interface{}, len(values))
+			for i, v := range values {
+				var err error
+				if projected[i], err = a.project(t["items"], namespace, v); err != nil {
+					return nil, err
+				}
+			}
+			return projected, nil
+		case "map":
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return value, nil
+			}
+			projected := make(map[string]interface{}, len(values))
+			for k, v := range values {
+				var err error
+				if projected[k], err = a.project(t["values"], namespace, v); err != nil {
+					return nil, err
+				}
+			}
+			return projected, nil
+		default:
+			if _, isObj := t["type"].(map[string]interface{}); isObj {
+				return a.project(t["type"], namespace, value)
+			}
+		}
+	}
+	return value, nil
+}
+
+func (a *avroProjector) projectRecord(schema map[string]interface{}, namespace string, value interface{}) (interface{}, error) {
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+	fields, _ := schema["fields"].([]interface{})
+	projected := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		fieldObj, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := fieldObj["name"].(string)
+		if v, exists := record[name]; exists {
+			var err error
+			if projected[name], err = a.project(fieldObj["type"], namespace, v); err != nil {
+				return nil, fmt.Errorf("field '%v': %w", name, err)
+			}
+			continue
+		}
+		def, hasDefault := fieldObj["default"]
+		if !hasDefault {
+			return nil, fmt.Errorf("field '%v' is missing from the writer schema and has no default in the reader schema", name)
+		}
+		// Defaults of unions are of the type of the first branch, which must
+		// be wrapped in order to match the native form of union values.
+		if union, isUnion := fieldObj["type"].([]interface{}); isUnion && def != nil && len(union) > 0 {
+			def = map[string]interface{}{a.unionBranchName(union[0], namespace): def}
+		}
+		projected[name] = def
+	}
+	return projected, nil
+}
+
+//------------------------------------------------------------------------------
+
+type avroSchemaRegistry struct {
+	client *schemaRegistryClient
+	conf   AvroSchemaRegistryConfig
+	topic  field.Expression
+
+	// Used for decoding
+	readerSchema   interface{}
+	writerCodecs   map[int]*goavro.Codec
+	writerCodecMut sync.RWMutex
+
+	// Used for encoding
+	schema        string
+	codec         *goavro.Codec
+	recordName    string
+	subjectIDs    map[string]int
+	subjectIDsMut sync.RWMutex
+}
+
+func newAvroSchemaRegistry(conf AvroSchemaRegistryConfig, schema string) (*avroSchemaRegistry, error) {
+	client, err := newSchemaRegistryClient(conf.SchemaRegistryConfig)
+	if err != nil {
+		return nil, err
+	}
+	r := &avroSchemaRegistry{
+		client:       client,
+		conf:         conf,
+		schema:       schema,
+		writerCodecs: map[int]*goavro.Codec{},
+		subjectIDs:   map[string]int{},
+	}
+	if r.topic, err = bloblang.NewField(conf.Topic); err != nil {
+		return nil, fmt.Errorf("failed to parse topic expression: %v", err)
+	}
+	switch conf.SubjectNameStrategy {
+	case "topic_name", "record_name", "topic_record_name":
+	default:
+		return nil, fmt.Errorf("subject name strategy not recognised: %v", conf.SubjectNameStrategy)
+	}
+
+	if schema != "" {
+		if err = json.Unmarshal([]byte(schema), &r.readerSchema); err != nil {
+			// Primitive schemas can be provided as plain strings.
+			r.readerSchema = schema
+		}
+		if r.codec, err = goavro.NewCodec(schema); err != nil {
+			return nil, fmt.Errorf("failed to parse schema: %v", err)
+		}
+		if obj, ok := r.readerSchema.(map[string]interface{}); ok {
+			switch obj["type"] {
+			case "record", "enum", "fixed":
+				r.recordName = avroSchemaFullName(obj, "")
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *avroSchemaRegistry) writerCodec(id int) (*goavro.Codec, error) {
+	r.writerCodecMut.RLock()
+	codec, exists := r.writerCodecs[id]
+	r.writerCodecMut.RUnlock()
+	if exists {
+		return codec, nil
+	}
+
+	r.writerCodecMut.Lock()
+	defer r.writerCodecMut.Unlock()
+	if codec, exists = r.writerCodecs[id]; exists {
+		return codec, nil
+	}
+
+	s, err := r.client.GetSchemaByID(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema %v: %w", id, err)
+	}
+	if s.SchemaType != "" && s.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("expected schema %v to be of type AVRO, got %v", id, s.SchemaType)
+	}
+	if codec, err = goavro.NewCodec(s.Schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %v: %w", id, err)
+	}
+	r.writerCodecs[id] = codec
+	return codec, nil
+}
+
+func (r *avroSchemaRegistry) subject(index int, msg types.Message) (string, error) {
+	var topic string
+	if r.conf.SubjectNameStrategy != "record_name" {
+		if topic = r.topic.String(index, msg); topic == "" {
+			return "", errors.New("topic resolved to an empty string")
+		}
+	}
+	if r.conf.SubjectNameStrategy != "topic_name" && r.recordName == "" {
+		return "", fmt.Errorf("subject name strategy %v requires a named schema", r.conf.SubjectNameStrategy)
+	}
+
+	switch r.conf.SubjectNameStrategy {
+	case "record_name":
+		return r.recordName, nil
+	case "topic_record_name":
+		return topic + "-" + r.recordName, nil
+	}
+	if r.conf.Key {
+		return topic + "-key", nil
+	}
+	return topic + "-value", nil
+}
+
+func (r *avroSchemaRegistry) schemaID(subject string) (int, error) {
+	r.subjectIDsMut.RLock()
+	id, exists := r.subjectIDs[subject]
+	r.subjectIDsMut.RUnlock()
+	if exists {
+		return id, nil
+	}
+
+	r.subjectIDsMut.Lock()
+	defer r.subjectIDsMut.Unlock()
+	if id, exists = r.subjectIDs[subject]; exists {
+		return id, nil
+	}
+
+	var err error
+	if r.conf.AutoRegister {
+		id, err = r.client.RegisterSchema(context.Background(), subject, r.schema, "AVRO")
+	} else {
+		id, err = r.client.LookupSchema(context.Background(), subject, r.schema, "AVRO")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain schema ID for subject '%v': %w", subject, err)
+	}
+	r.subjectIDs[subject] = id
+	return id, nil
+}
+
+func (r *avroSchemaRegistry) decode(index int, msg types.Message, part types.Part) error {
+	b := part.Get()
+	if len(b) < 5 || b[0] != 0 {
+		return errors.New("message is not in the schema registry wire format")
+	}
+	id := int(binary.BigEndian.Uint32(b[1:5]))
+
+	codec, err := r.writerCodec(id)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := codec.NativeFromBinary(b[5:])
+	if err != nil {
+		return fmt.Errorf("failed to convert Avro document to JSON: %v", err)
+	}
+	if r.readerSchema != nil {
+		projector := avroProjector{named: map[string]interface{}{}}
+		if native, err = projector.project(r.readerSchema, "", native); err != nil {
+			return fmt.Errorf("failed to project document onto reader schema: %v", err)
+		}
+	}
+	if err = part.SetJSON(native); err != nil {
+		return fmt.Errorf("failed to set JSON: %v", err)
+	}
+	return nil
+}
+
+func (r *avroSchemaRegistry) encode(index int, msg types.Message, part types.Part) error {
+	subject, err := r.subject(index, msg)
+	if err != nil {
+		return err
+	}
+	id, err := r.schemaID(subject)
+	if err != nil {
+		return err
+	}
+
+	native, _, err := r.codec.NativeFromTextual(part.Get())
+	if err != nil {
+		return fmt.Errorf("failed to convert JSON to Avro schema: %v", err)
+	}
+
+	encoded := make([]byte, 5, 5+len(part.Get()))
+	binary.BigEndian.PutUint32(encoded[1:], uint32(id))
+	if encoded, err = r.codec.BinaryFromNative(encoded, native); err != nil {
+		return fmt.Errorf("failed to convert JSON to Avro schema: %v", err)
+	}
+	part.Set(encoded)
+	return nil
+}
+
+func newAvroSchemaRegistryOperator(opStr string, conf AvroSchemaRegistryConfig, schema string) (avroRegistryOperator, error) {
+	switch opStr {
+	case "to_json":
+	case "from_json":
+		if schema == "" {
+			return nil, errors.New("a schema must be provided in order to encode messages")
+		}
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", opStr)
+	}
+
+	r, err := newAvroSchemaRegistry(conf, schema)
+	if err != nil {
+		return nil, err
+	}
+	if opStr == "to_json" {
+		return r.decode, nil
+	}
+	return r.encode, nil
+}
+
+//------------------------------------------------------------------------------
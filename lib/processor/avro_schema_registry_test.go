@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAvroWriterSchema = `{
+	"namespace": "foo.namespace.com",
+	"type": "record",
+	"name": "identity",
+	"fields": [
+		{ "name": "Name", "type": "string" },
+		{ "name": "Age", "type": "int" }
+	]
+}`
+
+type testAvroRegistry struct {
+	mut        sync.Mutex
+	schemas    map[int]string
+	registered map[string]string
+	requests   map[string]int
+}
+
+func startTestAvroRegistry(t *testing.T) (*testAvroRegistry, string) {
+	t.Helper()
+
+	reg := &testAvroRegistry{
+		schemas:    map[int]string{3: testAvroWriterSchema},
+		registered: map[string]string{},
+		requests:   map[string]int{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reg.mut.Lock()
+		defer reg.mut.Unlock()
+
+		reg.requests[r.Method+" "+r.URL.Path]++
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/schemas/ids/3":
+			require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+				"schema": reg.schemas[3],
+			}))
+		case r.Method == "POST" && r.URL.Path == "/subjects/foo-value/versions":
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			var req struct {
+				Schema string `json:"schema"`
+			}
+			require.NoError(t, json.Unmarshal(body, &req))
+			reg.registered["foo-value"] = req.Schema
+			w.Write([]byte(`{"id":7}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40403,"message":"Schema not found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return reg, server.URL
+}
+
+func testAvroWireFormat(t *testing.T, id uint32, schema, doc string) []byte {
+	t.Helper()
+
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	native, _, err := codec.NativeFromTextual([]byte(doc))
+	require.NoError(t, err)
+
+	b := make([]byte, 5)
+	binary.BigEndian.PutUint32(b[1:], id)
+	b, err = codec.BinaryFromNative(b, native)
+	require.NoError(t, err)
+	return b
+}
+
+func TestAvroSchemaRegistryDecode(t *testing.T) {
+	reg, url := startTestAvroRegistry(t)
+
+	conf := NewConfig()
+	conf.Type = TypeAvro
+	conf.Avro.Operator = "to_json"
+	conf.Avro.SchemaRegistry.Enabled = true
+	conf.Avro.SchemaRegistry.URL = url
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		testAvroWireFormat(t, 3, testAvroWriterSchema, `{"Name":"foo","Age":10}`),
+		testAvroWireFormat(t, 3, testAvroWriterSchema, `{"Name":"bar","Age":20}`),
+		[]byte(`not avro`),
+	})
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, `{"Age":10,"Name":"foo"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, `{"Age":20,"Name":"bar"}`, string(msgs[0].Get(1).Get()))
+	assert.Equal(t, "message is not in the schema registry wire format", msgs[0].Get(2).Metadata().Get(FailFlagKey))
+
+	reg.mut.Lock()
+	assert.Equal(t, 1, reg.requests["GET /schemas/ids/3"])
+	reg.mut.Unlock()
+}
+
+func TestAvroSchemaRegistryReaderSchema(t *testing.T) {
+	_, url := startTestAvroRegistry(t)
+
+	conf := NewConfig()
+	conf.Type = TypeAvro
+	conf.Avro.Operator = "to_json"
+	conf.Avro.Schema = `{
+	"namespace": "foo.namespace.com",
+	"type": "record",
+	"name": "identity",
+	"fields": [
+		{ "name": "Name", "type": "string" },
+		{ "name": "Email", "type": ["string", "null"], "default": "none" },
+		{ "name": "Nickname", "type": ["null", "string"], "default": null }
+	]
+}`
+	conf.Avro.SchemaRegistry.Enabled = true
+	conf.Avro.SchemaRegistry.URL = url
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		testAvroWireFormat(t, 3, testAvroWriterSchema, `{"Name":"foo","Age":10}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "", msgs[0].Get(0).Metadata().Get(FailFlagKey))
+	assert.Equal(t, `{"Email":{"string":"none"},"Name":"foo","Nickname":null}`, string(msgs[0].Get(0).Get()))
+}
+
+func TestAvroSchemaRegistryEncode(t *testing.T) {
+	reg, url := startTestAvroRegistry(t)
+
+	conf := NewConfig()
+	conf.Type = TypeAvro
+	conf.Avro.Operator = "from_json"
+	conf.Avro.Schema = testAvroWriterSchema
+	conf.Avro.SchemaRegistry.Enabled = true
+	conf.Avro.SchemaRegistry.URL = url
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"Name":"foo","Age":10}`),
+		[]byte(`{"Name":"bar","Age":20}`),
+		[]byte(`{"Name":"baz","Age":30}`),
+	})
+	input.Get(0).Metadata().Set("kafka_topic", "foo")
+	input.Get(1).Metadata().Set("kafka_topic", "foo")
+	input.Get(2).Metadata().Set("kafka_topic", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, testAvroWireFormat(t, 7, testAvroWriterSchema, `{"Name":"foo","Age":10}`), msgs[0].Get(0).Get())
+	assert.Equal(t, testAvroWireFormat(t, 7, testAvroWriterSchema, `{"Name":"bar","Age":20}`), msgs[0].Get(1).Get())
+	assert.Contains(t, msgs[0].Get(2).Metadata().Get(FailFlagKey), "failed to obtain schema ID for subject 'bar-value'")
+
+	reg.mut.Lock()
+	defer reg.mut.Unlock()
+	assert.Equal(t, 1, reg.requests["POST /subjects/foo-value/versions"])
+	assert.Equal(t, testAvroWriterSchema, reg.registered["foo-value"])
+}
+
+func TestAvroSchemaRegistrySubjects(t *testing.T) {
+	tests := []struct {
+		strategy string
+		key      bool
+		subject  string
+	}{
+		{strategy: "topic_name", subject: "foo-value"},
+		{strategy: "topic_name", key: true, subject: "foo-key"},
+		{strategy: "record_name", subject: "foo.namespace.com.identity"},
+		{strategy: "topic_record_name", subject: "foo-foo.namespace.com.identity"},
+	}
+
+	for _, test := range tests {
+		conf := NewAvroSchemaRegistryConfig()
+		conf.URL = "http://localhost:8081"
+		conf.SubjectNameStrategy = test.strategy
+		conf.Key = test.key
+
+		r, err := newAvroSchemaRegistry(conf, testAvroWriterSchema)
+		require.NoError(t, err)
+
+		msg := message.New([][]byte{[]byte(`{}`)})
+		msg.Get(0).Metadata().Set("kafka_topic", "foo")
+
+		subject, err := r.subject(0, msg)
+		require.NoError(t, err)
+		assert.Equal(t, test.subject, subject, test.strategy)
+	}
+}
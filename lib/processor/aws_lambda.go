@@ -268,7 +268,7 @@ func (l *Lambda) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 				if err != nil {
 					l.mErr.Incr(1)
 					l.mErrLambda.Incr(1)
-					l.log.Errorf("Lambda parallel request to '%v' failed: %v\n", l.conf.Config.Function, err)
+					log.WithMessageFields(l.log, index, msg).Errorf("Lambda parallel request to '%v' failed: %v\n", l.conf.Config.Function, err)
 					FlagErr(parts[index], err)
 				} else {
 					parts[index] = result.Get(0)
@@ -196,7 +196,7 @@ func (b *Bloblang) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 		if err != nil {
 			p = part.Copy()
 			b.mErr.Incr(1)
-			b.log.Errorf("%v\n", err)
+			log.WithMessageFields(b.log, i, msg).Errorf("%v\n", err)
 			FlagErr(p, err)
 			span.SetTag("error", true)
 			span.LogFields(
@@ -1,13 +1,17 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/opentracing/opentracing-go"
@@ -32,15 +36,25 @@ find a list of functions [here](/docs/configuration/interpolation#bloblang-queri
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("resource", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
 			docs.FieldDeprecated("cache"),
-			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete"),
+			docs.FieldCommon("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete", "mget", "mset", "cas", "incr", "decr", "touch"),
 			docs.FieldCommon("key", "A key to use with the cache.").SupportsInterpolation(false),
 			docs.FieldCommon("value", "A value to use with the cache (when applicable).").SupportsInterpolation(false),
+			docs.FieldAdvanced("old_value", "The value that a key must currently have for the `cas` operator to replace it.").SupportsInterpolation(false).AtVersion("3.39.0"),
 			docs.FieldAdvanced(
 				"ttl", "The TTL of each individual item as a duration string. After this period an item will be eligible for removal during the next compaction. Not all caches support per-key TTLs, and those that do not will fall back to their generally configured TTL setting.",
 				"60s", "5m", "36h",
 			).SupportsInterpolation(false).AtVersion("3.33.0"),
+			docs.FieldAdvanced(
+				"stale_while_revalidate", "Serve cached values of the `get` operator that are older than a maximum age whilst refreshing them in the background, see [stale while revalidate](#stale-while-revalidate) for more information.",
+			).WithChildren(
+				docs.FieldCommon("max_age", "The period after which a cached value is considered stale. Leave empty in order to disable stale while revalidate.", "30s", "1h"),
+				docs.FieldCommon("processors", "A list of processors that compute the value of a key from a copy of the message being processed. The payload of the resulting message is stored in the cache."),
+			).AtVersion("3.39.0"),
 			partsFieldSpec,
 		},
+		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
+			return conf.Cache.Sanitise()
+		},
 		Examples: []docs.AnnotatedExample{
 			{
 				Title: "Deduplication",
@@ -113,7 +127,90 @@ can be detected with [processor error handling](/docs/configuration/error_handli
 ### ` + "`delete`" + `
 
 Delete a key and its contents from the cache.  If the key does not exist the
-action is a no-op and will not fail with an error.`,
+action is a no-op and will not fail with an error.
+
+### ` + "`mget`" + `
+
+Retrieve the contents of the cached keys of all messages of a batch in a single
+request, where supported by the cache, and replace the payload of each message
+with its result. Messages with keys that do not exist fail with an error, which
+can be detected with [processor error handling](/docs/configuration/error_handling).
+
+### ` + "`mset`" + `
+
+Set the keys of all messages of a batch to their values in a single request. If
+the request fails then all messages of the batch are flagged as having failed.
+
+### ` + "`cas`" + `
+
+Set a key in the cache to a value only if its current value matches the
+` + "`old_value`" + ` field, which allows optimistic locking. If the key does
+not exist or its value does not match the action fails with an error, which can
+be detected with [processor error handling](/docs/configuration/error_handling).
+
+### ` + "`incr`" + `
+
+Atomically increment the integer value of a key by the amount in the
+` + "`value`" + ` field, or by one when the field is empty, and replace the
+original message payload with the result. Keys that do not exist are created
+with the amount as their value.
+
+### ` + "`decr`" + `
+
+Atomically decrement the integer value of a key by the amount in the
+` + "`value`" + ` field, or by one when the field is empty, and replace the
+original message payload with the result.
+
+### ` + "`touch`" + `
+
+Reset the TTL of a key without modifying its value, using the ` + "`ttl`" + `
+field when set. If the key does not exist the action fails with an error.
+
+The ` + "`cas`" + `, ` + "`incr`" + `, ` + "`decr`" + ` and ` + "`touch`" + `
+operators are only supported by the ` + "`memcached`" + `, ` + "`memory`" + `
+and ` + "`redis`" + ` caches. The ` + "`memcached`" + ` cache stores unsigned
+integers and therefore decrements stop at zero, and the ` + "`memory`" + `
+cache ignores per-key TTLs.
+
+## Stale While Revalidate
+
+When ` + "`stale_while_revalidate.max_age`" + ` is set the ` + "`get`" + `
+operator no longer fails for keys that do not exist. Instead the processors of
+` + "`stale_while_revalidate.processors`" + ` are executed on a copy of the
+message, and the resulting payload is stored in the cache (using the
+` + "`ttl`" + ` field when set) and used as the result.
+
+Values older than ` + "`max_age`" + ` are considered stale, and are returned
+immediately whilst the processors are executed in the background in order to
+repopulate the key. Only one refresh runs at a time for each key, and a refresh
+that fails leaves the stale value in place.
+
+The age of values is tracked with a marker key, which is the original key with
+the suffix ` + "`:fresh`" + ` and expires after ` + "`max_age`" + `, and
+therefore stale while revalidate requires a cache that supports per-key TTLs.
+
+` + "```yaml" + `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - cache:
+              resource: foocache
+              operator: get
+              key: '${! json("user.id") }'
+              ttl: 24h
+              stale_while_revalidate:
+                max_age: 5m
+                processors:
+                  - http:
+                      url: http://example.com/users/${! json("user.id") }
+                      verb: GET
+        result_map: 'root.user.profile = this'
+` + "```" + `
+
+The metric ` + "`stale`" + ` counts stale values that were returned, and the
+metrics ` + "`refresh`" + ` and ` + "`refresh.error`" + ` count the refreshes
+that succeeded and failed respectively.`,
 	}
 }
 
@@ -127,7 +224,17 @@ type CacheConfig struct {
 	Operator string `json:"operator" yaml:"operator"`
 	Key      string `json:"key" yaml:"key"`
 	Value    string `json:"value" yaml:"value"`
+	OldValue string `json:"old_value" yaml:"old_value"`
 	TTL      string `json:"ttl" yaml:"ttl"`
+
+	StaleWhileRevalidate CacheStaleConfig `json:"stale_while_revalidate" yaml:"stale_while_revalidate"`
+}
+
+// CacheStaleConfig contains configuration fields for returning stale values
+// from the cache processor whilst refreshing them in the background.
+type CacheStaleConfig struct {
+	MaxAge     string   `json:"max_age" yaml:"max_age"`
+	Processors []Config `json:"processors" yaml:"processors"`
 }
 
 // NewCacheConfig returns a CacheConfig with default values.
@@ -139,10 +246,42 @@ func NewCacheConfig() CacheConfig {
 		Operator: "set",
 		Key:      "",
 		Value:    "",
+		OldValue: "",
 		TTL:      "",
+
+		StaleWhileRevalidate: CacheStaleConfig{
+			MaxAge:     "",
+			Processors: []Config{},
+		},
 	}
 }
 
+// Sanitise the configuration into a minimal structure that can be printed
+// without changing the intent.
+func (c CacheConfig) Sanitise() (map[string]interface{}, error) {
+	var err error
+	procConfs := make([]interface{}, len(c.StaleWhileRevalidate.Processors))
+	for i, pConf := range c.StaleWhileRevalidate.Processors {
+		if procConfs[i], err = SanitiseConfig(pConf); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{
+		"cache":     c.Cache,
+		"resource":  c.Resource,
+		"parts":     c.Parts,
+		"operator":  c.Operator,
+		"key":       c.Key,
+		"value":     c.Value,
+		"old_value": c.OldValue,
+		"ttl":       c.TTL,
+		"stale_while_revalidate": map[string]interface{}{
+			"max_age":    c.StaleWhileRevalidate.MaxAge,
+			"processors": procConfs,
+		},
+	}, nil
+}
+
 //------------------------------------------------------------------------------
 
 // Cache is a processor that stores or retrieves data from a cache for each
@@ -154,18 +293,30 @@ type Cache struct {
 
 	parts []int
 
-	key   field.Expression
-	value field.Expression
-	ttl   field.Expression
+	key      field.Expression
+	value    field.Expression
+	oldValue field.Expression
+	ttl      field.Expression
 
 	cache    types.Cache
 	operator cacheOperator
+	lookup   enrichLookup
+
+	maxAge       time.Duration
+	refreshProcs []types.Processor
+	refreshing   map[string]struct{}
+	refreshMut   sync.Mutex
+	refreshWG    sync.WaitGroup
 
 	mCount            metrics.StatCounter
 	mErr              metrics.StatCounter
 	mKeyAlreadyExists metrics.StatCounter
+	mValueMismatch    metrics.StatCounter
 	mSent             metrics.StatCounter
 	mBatchSent        metrics.StatCounter
+	mStale            metrics.StatCounter
+	mRefresh          metrics.StatCounter
+	mRefreshErr       metrics.StatCounter
 }
 
 // NewCache returns a Cache processor.
@@ -183,9 +334,16 @@ func NewCache(
 		return nil, err
 	}
 
-	op, err := cacheOperatorFromString(conf.Cache.Operator, c)
-	if err != nil {
-		return nil, err
+	var op cacheOperator
+	var lookup enrichLookup
+	switch conf.Cache.Operator {
+	case "mget":
+		lookup = newEnrichCacheLookup(c)
+	case "mset":
+	default:
+		if op, err = cacheOperatorFromString(conf.Cache.Operator, c); err != nil {
+			return nil, err
+		}
 	}
 
 	key, err := bloblang.NewField(conf.Cache.Key)
@@ -198,6 +356,11 @@ func NewCache(
 		return nil, fmt.Errorf("failed to parse value expression: %v", err)
 	}
 
+	oldValue, err := bloblang.NewField(conf.Cache.OldValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse old value expression: %v", err)
+	}
+
 	if conf.Cache.TTL != "" {
 		if _, ok := c.(types.CacheWithTTL); !ok {
 			return nil, fmt.Errorf("this cache type does not support per-key ttl")
@@ -209,6 +372,34 @@ func NewCache(
 		return nil, fmt.Errorf("failed to parse ttl expression: %v", err)
 	}
 
+	var maxAge time.Duration
+	var refreshProcs []types.Processor
+	if conf.Cache.StaleWhileRevalidate.MaxAge != "" {
+		if conf.Cache.Operator != "get" {
+			return nil, errors.New("stale while revalidate is only supported by the get operator")
+		}
+		if _, ok := c.(types.CacheWithTTL); !ok {
+			return nil, errors.New("stale while revalidate requires a cache type that supports per-key ttl")
+		}
+		if maxAge, err = time.ParseDuration(conf.Cache.StaleWhileRevalidate.MaxAge); err != nil {
+			return nil, fmt.Errorf("failed to parse max age duration: %v", err)
+		}
+		if maxAge <= 0 {
+			return nil, errors.New("max age must be larger than zero")
+		}
+		for i, pconf := range conf.Cache.StaleWhileRevalidate.Processors {
+			prefix := fmt.Sprintf("stale_while_revalidate.processor.%v", i)
+			proc, err := New(pconf, mgr, log.NewModule("."+prefix), metrics.Namespaced(stats, prefix))
+			if err != nil {
+				return nil, fmt.Errorf("failed to init processor %v: %w", i, err)
+			}
+			refreshProcs = append(refreshProcs, proc)
+		}
+		if len(refreshProcs) == 0 {
+			return nil, errors.New("stale while revalidate requires at least one processor")
+		}
+	}
+
 	return &Cache{
 		conf:  conf,
 		log:   log,
@@ -216,27 +407,37 @@ func NewCache(
 
 		parts: conf.Cache.Parts,
 
-		key:   key,
-		value: value,
-		ttl:   ttl,
+		key:      key,
+		value:    value,
+		oldValue: oldValue,
+		ttl:      ttl,
 
 		cache:    c,
 		operator: op,
+		lookup:   lookup,
+
+		maxAge:       maxAge,
+		refreshProcs: refreshProcs,
+		refreshing:   map[string]struct{}{},
 
 		mCount:            stats.GetCounter("count"),
 		mErr:              stats.GetCounter("error"),
 		mKeyAlreadyExists: stats.GetCounter("key_already_exists"),
+		mValueMismatch:    stats.GetCounter("key_value_mismatch"),
 		mSent:             stats.GetCounter("sent"),
 		mBatchSent:        stats.GetCounter("batch.sent"),
+		mStale:            stats.GetCounter("stale"),
+		mRefresh:          stats.GetCounter("refresh"),
+		mRefreshErr:       stats.GetCounter("refresh.error"),
 	}, nil
 }
 
 //------------------------------------------------------------------------------
 
-type cacheOperator func(key string, value []byte, ttl *time.Duration) ([]byte, bool, error)
+type cacheOperator func(key string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error)
 
 func newCacheSetOperator(cache types.Cache) cacheOperator {
-	return func(key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
 		var err error
 		if cttl, ok := cache.(types.CacheWithTTL); ok {
 			err = cttl.SetWithTTL(key, value, ttl)
@@ -248,7 +449,7 @@ func newCacheSetOperator(cache types.Cache) cacheOperator {
 }
 
 func newCacheAddOperator(cache types.Cache) cacheOperator {
-	return func(key string, value []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
 		var err error
 		if cttl, ok := cache.(types.CacheWithTTL); ok {
 			err = cttl.AddWithTTL(key, value, ttl)
@@ -260,19 +461,62 @@ func newCacheAddOperator(cache types.Cache) cacheOperator {
 }
 
 func newCacheGetOperator(cache types.Cache) cacheOperator {
-	return func(key string, _ []byte, _ *time.Duration) ([]byte, bool, error) {
+	return func(key string, _, _ []byte, _ *time.Duration) ([]byte, bool, error) {
 		result, err := cache.Get(key)
 		return result, true, err
 	}
 }
 
 func newCacheDeleteOperator(cache types.Cache) cacheOperator {
-	return func(key string, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+	return func(key string, _, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
 		err := cache.Delete(key)
 		return nil, false, err
 	}
 }
 
+func newCacheCASOperator(cache types.Cache) (cacheOperator, error) {
+	cas, ok := cache.(types.CacheCompareAndSwapper)
+	if !ok {
+		return nil, errors.New("this cache type does not support compare and swap")
+	}
+	return func(key string, value, oldValue []byte, ttl *time.Duration) ([]byte, bool, error) {
+		err := cas.CompareAndSwap(key, oldValue, value, ttl)
+		return nil, false, err
+	}, nil
+}
+
+func newCacheIncrOperator(cache types.Cache, sign int64) (cacheOperator, error) {
+	incr, ok := cache.(types.CacheIncrementer)
+	if !ok {
+		return nil, errors.New("this cache type does not support increments")
+	}
+	return func(key string, value, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+		delta := int64(1)
+		if len(value) > 0 {
+			var err error
+			if delta, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return nil, false, fmt.Errorf("value must be an integer: %v", err)
+			}
+		}
+		n, err := incr.Increment(key, sign*delta, ttl)
+		if err != nil {
+			return nil, false, err
+		}
+		return []byte(strconv.FormatInt(n, 10)), true, nil
+	}, nil
+}
+
+func newCacheTouchOperator(cache types.Cache) (cacheOperator, error) {
+	toucher, ok := cache.(types.CacheToucher)
+	if !ok {
+		return nil, errors.New("this cache type does not support touch")
+	}
+	return func(key string, _, _ []byte, ttl *time.Duration) ([]byte, bool, error) {
+		err := toucher.Touch(key, ttl)
+		return nil, false, err
+	}, nil
+}
+
 func cacheOperatorFromString(operator string, cache types.Cache) (cacheOperator, error) {
 	switch operator {
 	case "set":
@@ -283,41 +527,184 @@ func cacheOperatorFromString(operator string, cache types.Cache) (cacheOperator,
 		return newCacheGetOperator(cache), nil
 	case "delete":
 		return newCacheDeleteOperator(cache), nil
+	case "cas":
+		return newCacheCASOperator(cache)
+	case "incr":
+		return newCacheIncrOperator(cache, 1)
+	case "decr":
+		return newCacheIncrOperator(cache, -1)
+	case "touch":
+		return newCacheTouchOperator(cache)
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", operator)
 }
 
 //------------------------------------------------------------------------------
 
+func (c *Cache) getTTL(index int, msg types.Message) (*time.Duration, error) {
+	ttls := c.ttl.String(index, msg)
+	if ttls == "" {
+		return nil, nil
+	}
+	td, err := time.ParseDuration(ttls)
+	if err != nil {
+		c.mErr.Incr(1)
+		c.log.Debugf("TTL must be a duration: %v\n", err)
+		return nil, err
+	}
+	return &td, nil
+}
+
+// batchKeys returns the keys of each targeted message of a batch by index.
+func (c *Cache) batchKeys(msg types.Message) map[int]string {
+	keys := map[int]string{}
+	if len(c.parts) == 0 {
+		for i := 0; i < msg.Len(); i++ {
+			keys[i] = c.key.String(i, msg)
+		}
+	} else {
+		for _, i := range c.parts {
+			keys[i] = c.key.String(i, msg)
+		}
+	}
+	return keys
+}
+
+func (c *Cache) processMultiGet(msg, newMsg types.Message) {
+	keys := c.batchKeys(msg)
+
+	uniqueKeys := make([]string, 0, len(keys))
+	seen := map[string]struct{}{}
+	for _, k := range keys {
+		if _, exists := seen[k]; !exists {
+			seen[k] = struct{}{}
+			uniqueKeys = append(uniqueKeys, k)
+		}
+	}
+
+	var values map[string][]byte
+	var err error
+	if len(uniqueKeys) > 0 {
+		if values, err = c.lookup(uniqueKeys); err != nil {
+			c.mErr.Incr(1)
+			c.log.Debugf("Operator failed for %v keys: %v\n", len(uniqueKeys), err)
+		}
+	}
+
+	IteratePartsWithSpan(TypeCache, c.parts, newMsg, func(index int, span opentracing.Span, part types.Part) error {
+		if err != nil {
+			return err
+		}
+		value, exists := values[keys[index]]
+		if !exists {
+			return types.ErrKeyNotFound
+		}
+		part.Set(value)
+		return nil
+	})
+}
+
+func (c *Cache) processMultiSet(msg, newMsg types.Message) {
+	keys := c.batchKeys(msg)
+
+	items := map[string]types.CacheTTLItem{}
+	ttlErrs := map[int]error{}
+	for index, key := range keys {
+		ttl, err := c.getTTL(index, msg)
+		if err != nil {
+			ttlErrs[index] = err
+			continue
+		}
+		items[key] = types.CacheTTLItem{
+			Value: c.value.Bytes(index, msg),
+			TTL:   ttl,
+		}
+	}
+
+	var err error
+	if len(items) > 0 {
+		if cttl, ok := c.cache.(types.CacheWithTTL); ok {
+			err = cttl.SetMultiWithTTL(items)
+		} else {
+			values := make(map[string][]byte, len(items))
+			for k, item := range items {
+				values[k] = item.Value
+			}
+			err = c.cache.SetMulti(values)
+		}
+		if err != nil {
+			c.mErr.Incr(1)
+			c.log.Debugf("Operator failed for %v keys: %v\n", len(items), err)
+		}
+	}
+
+	IteratePartsWithSpan(TypeCache, c.parts, newMsg, func(index int, span opentracing.Span, part types.Part) error {
+		if ttlErr, exists := ttlErrs[index]; exists {
+			return ttlErr
+		}
+		return err
+	})
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	c.mCount.Incr(1)
 	newMsg := msg.Copy()
 
-	proc := func(index int, span opentracing.Span, part types.Part) error {
+	switch c.conf.Cache.Operator {
+	case "mget":
+		c.processMultiGet(msg, newMsg)
+	case "mset":
+		c.processMultiSet(msg, newMsg)
+	default:
+		IteratePartsWithSpan(TypeCache, c.parts, newMsg, c.processPart(msg))
+	}
+
+	c.mBatchSent.Incr(1)
+	c.mSent.Incr(int64(newMsg.Len()))
+	msgs := [1]types.Message{newMsg}
+	return msgs[:], nil
+}
+
+func (c *Cache) processPart(msg types.Message) func(index int, span opentracing.Span, part types.Part) error {
+	return func(index int, span opentracing.Span, part types.Part) error {
 		key := c.key.String(index, msg)
 		value := c.value.Bytes(index, msg)
 
-		var ttl *time.Duration
-		if ttls := c.ttl.String(index, msg); ttls != "" {
-			td, err := time.ParseDuration(ttls)
+		ttl, err := c.getTTL(index, msg)
+		if err != nil {
+			return err
+		}
+
+		if c.maxAge > 0 {
+			result, err := c.getOrRefresh(key, msg.Get(index), ttl)
 			if err != nil {
 				c.mErr.Incr(1)
-				c.log.Debugf("TTL must be a duration: %v\n", err)
+				c.log.Debugf("Operator failed for key '%s': %v\n", key, err)
 				return err
 			}
-			ttl = &td
+			part.Set(result)
+			return nil
 		}
 
-		result, useResult, err := c.operator(key, value, ttl)
+		var oldValue []byte
+		if c.conf.Cache.Operator == "cas" {
+			oldValue = c.oldValue.Bytes(index, msg)
+		}
+
+		result, useResult, err := c.operator(key, value, oldValue, ttl)
 		if err != nil {
-			if err != types.ErrKeyAlreadyExists {
-				c.mErr.Incr(1)
-				c.log.Debugf("Operator failed for key '%s': %v\n", key, err)
-			} else {
+			switch err {
+			case types.ErrKeyAlreadyExists:
 				c.mKeyAlreadyExists.Incr(1)
 				c.log.Debugf("Key already exists: %v\n", key)
+			case types.ErrKeyValueMismatch:
+				c.mValueMismatch.Incr(1)
+				c.log.Debugf("Key value does not match: %v\n", key)
+			default:
+				c.mErr.Incr(1)
+				c.log.Debugf("Operator failed for key '%s': %v\n", key, err)
 			}
 			return err
 		}
@@ -327,21 +714,118 @@ func (c *Cache) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 		}
 		return nil
 	}
+}
 
-	IteratePartsWithSpan(TypeCache, c.parts, newMsg, proc)
+// cacheFreshSuffix is appended to keys in order to store a marker that expires
+// once the value of the key becomes stale.
+const cacheFreshSuffix = ":fresh"
 
-	c.mBatchSent.Incr(1)
-	c.mSent.Incr(int64(newMsg.Len()))
-	msgs := [1]types.Message{newMsg}
-	return msgs[:], nil
+// getOrRefresh returns the cached value of a key, refreshing it in the
+// background when it is stale, or in the foreground when it does not exist.
+func (c *Cache) getOrRefresh(key string, part types.Part, ttl *time.Duration) ([]byte, error) {
+	value, err := c.cache.Get(key)
+	if err == types.ErrKeyNotFound {
+		return c.refresh(key, part.Copy(), ttl)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = c.cache.Get(key + cacheFreshSuffix); err == types.ErrKeyNotFound {
+		c.mStale.Incr(1)
+		c.refreshAsync(key, part.Copy(), ttl)
+	} else if err != nil {
+		c.log.Debugf("Failed to read freshness of key '%s': %v\n", key, err)
+	}
+	return value, nil
+}
+
+func (c *Cache) refreshAsync(key string, part types.Part, ttl *time.Duration) {
+	c.refreshMut.Lock()
+	if _, exists := c.refreshing[key]; exists {
+		c.refreshMut.Unlock()
+		return
+	}
+	c.refreshing[key] = struct{}{}
+	c.refreshMut.Unlock()
+
+	c.refreshWG.Add(1)
+	go func() {
+		defer func() {
+			c.refreshMut.Lock()
+			delete(c.refreshing, key)
+			c.refreshMut.Unlock()
+			c.refreshWG.Done()
+		}()
+		if _, err := c.refresh(key, part, ttl); err != nil {
+			c.log.Debugf("Failed to refresh stale key '%s': %v\n", key, err)
+		}
+	}()
+}
+
+// refresh executes the refresh processors on a message part and stores the
+// result as the value of a key.
+func (c *Cache) refresh(key string, part types.Part, ttl *time.Duration) ([]byte, error) {
+	value, err := c.computeValue(part)
+	if err == nil {
+		cttl := c.cache.(types.CacheWithTTL)
+		if err = cttl.SetWithTTL(key, value, ttl); err == nil {
+			err = cttl.SetWithTTL(key+cacheFreshSuffix, []byte("1"), &c.maxAge)
+		}
+	}
+	if err != nil {
+		c.mRefreshErr.Incr(1)
+		return nil, err
+	}
+	c.mRefresh.Incr(1)
+	return value, nil
+}
+
+func (c *Cache) computeValue(part types.Part) ([]byte, error) {
+	reqMsg := message.New(nil)
+	reqMsg.Append(part)
+
+	msgs, res := ExecuteAll(c.refreshProcs, reqMsg)
+	if res != nil && res.Error() != nil {
+		return nil, fmt.Errorf("refresh processors failed: %v", res.Error())
+	}
+	if len(msgs) == 0 || msgs[0].Len() == 0 {
+		return nil, errors.New("refresh processors resulted in zero messages")
+	}
+	result := msgs[0].Get(0)
+	if HasFailed(result) {
+		return nil, fmt.Errorf("refresh processors failed: %v", GetFail(result))
+	}
+	return result.Get(), nil
 }
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (c *Cache) CloseAsync() {
+	for _, p := range c.refreshProcs {
+		p.CloseAsync()
+	}
 }
 
 // WaitForClose blocks until the processor has closed down.
-func (c *Cache) WaitForClose(_ time.Duration) error {
+func (c *Cache) WaitForClose(timeout time.Duration) error {
+	stopBy := time.Now().Add(timeout)
+
+	refreshed := make(chan struct{})
+	go func() {
+		c.refreshWG.Wait()
+		close(refreshed)
+	}()
+	select {
+	case <-refreshed:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+
+	for _, p := range c.refreshProcs {
+		if err := p.WaitForClose(time.Until(stopBy)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -1,8 +1,10 @@
 package processor
 
 import (
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/cache"
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -382,3 +384,329 @@ func TestCacheDelete(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", err, types.ErrKeyNotFound)
 	}
 }
+
+func TestCacheMultiGet(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	memCache.Set("1", []byte("foo 1"))
+	memCache.Set("2", []byte("foo 2"))
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "mget"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"1"}`),
+	})
+	expParts := [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`foo 2`),
+		[]byte(`foo 1`),
+	}
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	if exp, act := expParts, message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+
+	for i, exp := range []bool{false, true, false, false} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCacheMultiSet(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.Value = "${!json(\"value\")}"
+	conf.Cache.TTL = "${!json(\"ttl\")}"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "mset"
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := message.New([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"2","value":"foo 2","ttl":"nope"}`),
+		[]byte(`{"key":"3","value":"foo 3","ttl":"1h"}`),
+	})
+
+	output, res := proc.ProcessMessage(input)
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+
+	if exp, act := message.GetAllBytes(input), message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+
+	for i, exp := range []bool{false, true, false} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+
+	for k, exp := range map[string]string{"1": "foo 1", "3": "foo 3"} {
+		actBytes, err := memCache.Get(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := string(actBytes); exp != act {
+			t.Errorf("Wrong result: %v != %v", act, exp)
+		}
+	}
+
+	if _, err = memCache.Get("2"); err != types.ErrKeyNotFound {
+		t.Errorf("Wrong result: %v != %v", err, types.ErrKeyNotFound)
+	}
+}
+
+func TestCacheAtomicOperators(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	newProc := func(operator string) Type {
+		t.Helper()
+		conf := NewConfig()
+		conf.Cache.Key = "${!json(\"key\")}"
+		conf.Cache.Value = "${!json(\"value\")}"
+		conf.Cache.OldValue = "${!json(\"old\")}"
+		conf.Cache.Resource = "foocache"
+		conf.Cache.Operator = operator
+		proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return proc
+	}
+
+	output, _ := newProc("incr").ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"count"}`),
+		[]byte(`{"key":"count","value":"5"}`),
+		[]byte(`{"key":"count","value":"nope"}`),
+	}))
+	if exp, act := [][]byte{
+		[]byte(`1`),
+		[]byte(`6`),
+		[]byte(`{"key":"count","value":"nope"}`),
+	}, message.GetAllBytes(output[0]); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong result messages: %s != %s", act, exp)
+	}
+	if !HasFailed(output[0].Get(2)) {
+		t.Error("Expected non-integer increment to fail")
+	}
+
+	output, _ = newProc("decr").ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"count","value":"2"}`),
+	}))
+	if exp, act := "4", string(output[0].Get(0).Get()); exp != act {
+		t.Errorf("Wrong result: %v != %v", act, exp)
+	}
+
+	output, _ = newProc("cas").ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"count","old":"3","value":"10"}`),
+		[]byte(`{"key":"count","old":"4","value":"10"}`),
+		[]byte(`{"key":"nope","old":"4","value":"10"}`),
+	}))
+	for i, exp := range []bool{true, false, true} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+	if actBytes, _ := memCache.Get("count"); string(actBytes) != "10" {
+		t.Errorf("Wrong result: %s != 10", actBytes)
+	}
+
+	output, _ = newProc("touch").ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"count"}`),
+		[]byte(`{"key":"nope"}`),
+	}))
+	for i, exp := range []bool{false, true} {
+		if act := HasFailed(output[0].Get(i)); exp != act {
+			t.Errorf("Wrong fail flag for part %v: %v != %v", i, act, exp)
+		}
+	}
+}
+
+func TestCacheAtomicOperatorsUnsupported(t *testing.T) {
+	cacheConf := cache.NewConfig()
+	cacheConf.Type = cache.TypeRistretto
+	ristrettoCache, err := cache.New(cacheConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": ristrettoCache,
+		},
+	}
+
+	for _, op := range []string{"cas", "incr", "decr", "touch"} {
+		conf := NewConfig()
+		conf.Cache.Resource = "foocache"
+		conf.Cache.Operator = op
+		if _, err := NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error for operator %v", op)
+		}
+	}
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	cacheConf := cache.NewConfig()
+	cacheConf.Type = cache.TypeDisk
+	cacheConf.Disk.Path = filepath.Join(t.TempDir(), "cache.db")
+	diskCache, err := cache.New(cacheConf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer diskCache.CloseAsync()
+
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": diskCache,
+		},
+	}
+
+	refreshConf := NewConfig()
+	refreshConf.Type = TypeBloblang
+	refreshConf.Bloblang = `root = this.value`
+
+	conf := NewConfig()
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Operator = "get"
+	conf.Cache.Key = "${!json(\"key\")}"
+	conf.Cache.StaleWhileRevalidate.MaxAge = "50ms"
+	conf.Cache.StaleWhileRevalidate.Processors = []Config{refreshConf}
+	proc, err := NewCache(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(value string) string {
+		t.Helper()
+		output, res := proc.ProcessMessage(message.New([][]byte{
+			[]byte(`{"key":"foo","value":"` + value + `"}`),
+		}))
+		if res != nil {
+			t.Fatal(res.Error())
+		}
+		if HasFailed(output[0].Get(0)) {
+			t.Fatalf("Unexpected failure: %v", GetFail(output[0].Get(0)))
+		}
+		return string(output[0].Get(0).Get())
+	}
+
+	if exp, act := "a", get("a"); exp != act {
+		t.Errorf("Wrong result for missing key: %v != %v", act, exp)
+	}
+	if exp, act := "a", get("b"); exp != act {
+		t.Errorf("Wrong result for fresh key: %v != %v", act, exp)
+	}
+
+	<-time.After(time.Millisecond * 100)
+	if exp, act := "a", get("c"); exp != act {
+		t.Errorf("Wrong result for stale key: %v != %v", act, exp)
+	}
+
+	proc.CloseAsync()
+	if err = proc.WaitForClose(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	actBytes, err := diskCache.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "c", string(actBytes); exp != act {
+		t.Errorf("Wrong refreshed value: %v != %v", act, exp)
+	}
+}
+
+func TestCacheStaleWhileRevalidateBadConfig(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	refreshConf := NewConfig()
+	refreshConf.Type = TypeBloblang
+	refreshConf.Bloblang = `root = this.value`
+
+	tests := map[string]func(c *CacheConfig){
+		"set operator": func(c *CacheConfig) {
+			c.Operator = "set"
+		},
+		"bad max age": func(c *CacheConfig) {
+			c.StaleWhileRevalidate.MaxAge = "nope"
+		},
+		"no processors": func(c *CacheConfig) {
+			c.StaleWhileRevalidate.Processors = nil
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Cache.Resource = "foocache"
+		conf.Cache.Operator = "get"
+		conf.Cache.StaleWhileRevalidate.MaxAge = "1m"
+		conf.Cache.StaleWhileRevalidate.Processors = []Config{refreshConf}
+		test(&conf.Cache)
+		if _, err := NewCache(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error for %v", name)
+		}
+	}
+}
@@ -5,6 +5,10 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"compress/zlib"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -12,8 +16,47 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// A zstd dictionary trained from small JSON documents of the same structure as
+// testZstdDictInput.
+const testZstdDict = "" +
+	"N6Qw7LAtPXAbEOAKlcb/////628avety772lJJLZf4zWWmsBswMAAAAA2uO9AAAABEBGhIGToAUB" +
+	"gAMSAAAAAHRwLBzKAUAtgAAAIIACSgQGAAAAAAB0UOXqCwAAAAAAAAAAAAAAAAAAAQAAAAQAAAAI" +
+	"AAAAZSIsICJ0cyI6ICIyIiwgInRzIjogIjIwMjAtMTEtMTFUMTA6NTI6MDBaIn17ImlkIjogMTQ5" +
+	"MiwgInVzZXJldyIsICJ0cyI6ICIyMDIwLTExLTA1VDEwOjEwOjAwWiJ9eyJpZCI6IDMwLCAidXNl" +
+	"cnciLCAidHMiOiAiMjAyMC0xMS0xOFQxMDoyNDowMFoifXsiaWQiOiAxMTEsICJ1c2VyInVzZXIi" +
+	"OiB7Im5hbWUiOiAiYWxpY2UiLCAiZW1haWwiOiAidXNlcjI2NUBleGFtcGwiaWQiOiAxODQsICJ1" +
+	"c2VyIjogeyJuYW1lIjogImJvYiIsICJlbWFpbCI6ICJ1c2VyOSJ1c2VyIjogeyJuYW1lIjogImNh" +
+	"cm9sIiwgImVtYWlsIjogInVzZXI1NTdAZXhhbXBsfSwgImV2ZW50IjogImNsaWNrIiwgInRzIjog" +
+	"IjIwMjAtMTEtMDNUMTA6MDY6MDBaIn0iZXZlbnQiOiAicHVyY2hhc2UiLCAidHMiOiAiMjAyMC0="
+
+var testZstdDictInput = [][]byte{
+	[]byte(`{"id": 5, "user": {"name": "bob", "email": "user605@example.com"}, "event": "click", "ts": "2020-11-11T10:01:00Z"}`),
+	[]byte(`{"id": 6, "user": {"name": "alice", "email": "user12@example.com"}, "event": "view", "ts": "2020-11-03T10:42:00Z"}`),
+}
+
+func writeTestZstdDict(t *testing.T) string {
+	t.Helper()
+
+	dict, err := base64.StdEncoding.DecodeString(testZstdDict)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "benthos_zstd_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "test.zdict")
+	require.NoError(t, ioutil.WriteFile(path, dict, 0644))
+	return path
+}
+
 func TestCompressBadAlgo(t *testing.T) {
 	conf := NewConfig()
 	conf.Compress.Algorithm = "does not exist"
@@ -208,6 +251,111 @@ func TestCompressSnappy(t *testing.T) {
 	}
 }
 
+func TestCompressZstd(t *testing.T) {
+	conf := NewConfig()
+	conf.Compress.Algorithm = "zstd"
+
+	input := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+	}
+
+	proc, err := NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	for i, b := range message.GetAllBytes(msgs[0]) {
+		assert.NotEqual(t, input[i], b)
+		act, err := dec.DecodeAll(b, nil)
+		require.NoError(t, err)
+		assert.Equal(t, string(input[i]), string(act))
+	}
+}
+
+func TestCompressZstdDictionary(t *testing.T) {
+	conf := NewConfig()
+	conf.Compress.Algorithm = "zstd"
+
+	proc, err := NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New(testZstdDictInput))
+	require.Nil(t, res)
+	withoutDict := message.GetAllBytes(msgs[0])
+
+	dictPath := writeTestZstdDict(t)
+	conf.Compress.DictionaryPath = dictPath
+
+	proc, err = NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = proc.ProcessMessage(message.New(testZstdDictInput))
+	require.Nil(t, res)
+	withDict := message.GetAllBytes(msgs[0])
+
+	dict, err := ioutil.ReadFile(dictPath)
+	require.NoError(t, err)
+
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dict))
+	require.NoError(t, err)
+	defer dec.Close()
+
+	for i, b := range withDict {
+		assert.Less(t, len(b), len(withoutDict[i]))
+		act, err := dec.DecodeAll(b, nil)
+		require.NoError(t, err)
+		assert.Equal(t, string(testZstdDictInput[i]), string(act))
+	}
+}
+
+func TestCompressDictionaryBadAlgo(t *testing.T) {
+	conf := NewConfig()
+	conf.Compress.Algorithm = "gzip"
+	conf.Compress.DictionaryPath = writeTestZstdDict(t)
+
+	_, err := NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Compress.Algorithm = "zstd"
+	conf.Compress.DictionaryPath = "/does/not/exist"
+
+	_, err = NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestCompressLZ4(t *testing.T) {
+	conf := NewConfig()
+	conf.Compress.Algorithm = "lz4"
+
+	input := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+	}
+
+	proc, err := NewCompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	for i, b := range message.GetAllBytes(msgs[0]) {
+		assert.NotEqual(t, input[i], b)
+		act, err := ioutil.ReadAll(lz4.NewReader(bytes.NewReader(b)))
+		require.NoError(t, err)
+		assert.Equal(t, string(input[i]), string(act))
+	}
+}
+
 func TestCompressIndexBounds(t *testing.T) {
 	conf := NewConfig()
 
@@ -85,207 +85,231 @@ func Block(typeStr, reason string) {
 
 // String constants representing each processor type.
 const (
-	TypeArchive      = "archive"
-	TypeAvro         = "avro"
-	TypeAWK          = "awk"
-	TypeAWSLambda    = "aws_lambda"
-	TypeBatch        = "batch"
-	TypeBloblang     = "bloblang"
-	TypeBoundsCheck  = "bounds_check"
-	TypeBranch       = "branch"
-	TypeCache        = "cache"
-	TypeCatch        = "catch"
-	TypeCompress     = "compress"
-	TypeConditional  = "conditional"
-	TypeDecode       = "decode"
-	TypeDecompress   = "decompress"
-	TypeDedupe       = "dedupe"
-	TypeEncode       = "encode"
-	TypeFilter       = "filter"
-	TypeFilterParts  = "filter_parts"
-	TypeForEach      = "for_each"
-	TypeGrok         = "grok"
-	TypeGroupBy      = "group_by"
-	TypeGroupByValue = "group_by_value"
-	TypeHash         = "hash"
-	TypeHashSample   = "hash_sample"
-	TypeHTTP         = "http"
-	TypeInsertPart   = "insert_part"
-	TypeJMESPath     = "jmespath"
-	TypeJQ           = "jq"
-	TypeJSON         = "json"
-	TypeJSONSchema   = "json_schema"
-	TypeLambda       = "lambda"
-	TypeLog          = "log"
-	TypeMergeJSON    = "merge_json"
-	TypeMetadata     = "metadata"
-	TypeMetric       = "metric"
-	TypeNoop         = "noop"
-	TypeNumber       = "number"
-	TypeParallel     = "parallel"
-	TypeParseLog     = "parse_log"
-	TypeProcessBatch = "process_batch"
-	TypeProcessDAG   = "process_dag"
-	TypeProcessField = "process_field"
-	TypeProcessMap   = "process_map"
-	TypeProtobuf     = "protobuf"
-	TypeRateLimit    = "rate_limit"
-	TypeRedis        = "redis"
-	TypeResource     = "resource"
-	TypeSample       = "sample"
-	TypeSelectParts  = "select_parts"
-	TypeSleep        = "sleep"
-	TypeSplit        = "split"
-	TypeSQL          = "sql"
-	TypeSubprocess   = "subprocess"
-	TypeSwitch       = "switch"
-	TypeSyncResponse = "sync_response"
-	TypeText         = "text"
-	TypeTry          = "try"
-	TypeThrottle     = "throttle"
-	TypeUnarchive    = "unarchive"
-	TypeWhile        = "while"
-	TypeWorkflow     = "workflow"
-	TypeXML          = "xml"
+	TypeArchive            = "archive"
+	TypeAvro               = "avro"
+	TypeAWK                = "awk"
+	TypeAWSLambda          = "aws_lambda"
+	TypeBatch              = "batch"
+	TypeBloblang           = "bloblang"
+	TypeBoundsCheck        = "bounds_check"
+	TypeBranch             = "branch"
+	TypeCache              = "cache"
+	TypeCatch              = "catch"
+	TypeCompress           = "compress"
+	TypeConditional        = "conditional"
+	TypeDecode             = "decode"
+	TypeDecompress         = "decompress"
+	TypeDedupe             = "dedupe"
+	TypeEncode             = "encode"
+	TypeEnrich             = "enrich"
+	TypeEnvelopeEncryption = "envelope_encryption"
+	TypeFilter             = "filter"
+	TypeFilterParts        = "filter_parts"
+	TypeForEach            = "for_each"
+	TypeGeoIP              = "geoip"
+	TypeGrok               = "grok"
+	TypeGroupBy            = "group_by"
+	TypeGroupByValue       = "group_by_value"
+	TypeHash               = "hash"
+	TypeHashSample         = "hash_sample"
+	TypeHTTP               = "http"
+	TypeInsertPart         = "insert_part"
+	TypeJavaScript         = "javascript"
+	TypeJMESPath           = "jmespath"
+	TypeJQ                 = "jq"
+	TypeJSON               = "json"
+	TypeJSONSchema         = "json_schema"
+	TypeLambda             = "lambda"
+	TypeLog                = "log"
+	TypeMergeJSON          = "merge_json"
+	TypeMetadata           = "metadata"
+	TypeMetric             = "metric"
+	TypeNoop               = "noop"
+	TypeNumber             = "number"
+	TypeParallel           = "parallel"
+	TypeParseLog           = "parse_log"
+	TypeProcessBatch       = "process_batch"
+	TypeProcessDAG         = "process_dag"
+	TypeProcessField       = "process_field"
+	TypeProcessMap         = "process_map"
+	TypeProtobuf           = "protobuf"
+	TypeRateLimit          = "rate_limit"
+	TypeRedis              = "redis"
+	TypeResource           = "resource"
+	TypeRollup             = "rollup"
+	TypeSample             = "sample"
+	TypeSchemaMigration    = "schema_migration"
+	TypeSelectParts        = "select_parts"
+	TypeSleep              = "sleep"
+	TypeSplit              = "split"
+	TypeStarlark           = "starlark"
+	TypeSQL                = "sql"
+	TypeSubprocess         = "subprocess"
+	TypeSwitch             = "switch"
+	TypeSyncResponse       = "sync_response"
+	TypeText               = "text"
+	TypeTry                = "try"
+	TypeThrottle           = "throttle"
+	TypeUnarchive          = "unarchive"
+	TypeWASM               = "wasm"
+	TypeWhile              = "while"
+	TypeWorkflow           = "workflow"
+	TypeXML                = "xml"
 )
 
 //------------------------------------------------------------------------------
 
 // Config is the all encompassing configuration struct for all processor types.
 type Config struct {
-	Type         string             `json:"type" yaml:"type"`
-	Archive      ArchiveConfig      `json:"archive" yaml:"archive"`
-	Avro         AvroConfig         `json:"avro" yaml:"avro"`
-	AWK          AWKConfig          `json:"awk" yaml:"awk"`
-	AWSLambda    LambdaConfig       `json:"aws_lambda" yaml:"aws_lambda"`
-	Batch        BatchConfig        `json:"batch" yaml:"batch"`
-	Bloblang     BloblangConfig     `json:"bloblang" yaml:"bloblang"`
-	BoundsCheck  BoundsCheckConfig  `json:"bounds_check" yaml:"bounds_check"`
-	Branch       BranchConfig       `json:"branch" yaml:"branch"`
-	Cache        CacheConfig        `json:"cache" yaml:"cache"`
-	Catch        CatchConfig        `json:"catch" yaml:"catch"`
-	Compress     CompressConfig     `json:"compress" yaml:"compress"`
-	Conditional  ConditionalConfig  `json:"conditional" yaml:"conditional"`
-	Decode       DecodeConfig       `json:"decode" yaml:"decode"`
-	Decompress   DecompressConfig   `json:"decompress" yaml:"decompress"`
-	Dedupe       DedupeConfig       `json:"dedupe" yaml:"dedupe"`
-	Encode       EncodeConfig       `json:"encode" yaml:"encode"`
-	Filter       FilterConfig       `json:"filter" yaml:"filter"`
-	FilterParts  FilterPartsConfig  `json:"filter_parts" yaml:"filter_parts"`
-	ForEach      ForEachConfig      `json:"for_each" yaml:"for_each"`
-	Grok         GrokConfig         `json:"grok" yaml:"grok"`
-	GroupBy      GroupByConfig      `json:"group_by" yaml:"group_by"`
-	GroupByValue GroupByValueConfig `json:"group_by_value" yaml:"group_by_value"`
-	Hash         HashConfig         `json:"hash" yaml:"hash"`
-	HashSample   HashSampleConfig   `json:"hash_sample" yaml:"hash_sample"`
-	HTTP         HTTPConfig         `json:"http" yaml:"http"`
-	InsertPart   InsertPartConfig   `json:"insert_part" yaml:"insert_part"`
-	JMESPath     JMESPathConfig     `json:"jmespath" yaml:"jmespath"`
-	JQ           JQConfig           `json:"jq" yaml:"jq"`
-	JSON         JSONConfig         `json:"json" yaml:"json"`
-	JSONSchema   JSONSchemaConfig   `json:"json_schema" yaml:"json_schema"`
-	Lambda       LambdaConfig       `json:"lambda" yaml:"lambda"`
-	Log          LogConfig          `json:"log" yaml:"log"`
-	MergeJSON    MergeJSONConfig    `json:"merge_json" yaml:"merge_json"`
-	Metadata     MetadataConfig     `json:"metadata" yaml:"metadata"`
-	Metric       MetricConfig       `json:"metric" yaml:"metric"`
-	Noop         NoopConfig         `json:"noop" yaml:"noop"`
-	Number       NumberConfig       `json:"number" yaml:"number"`
-	Plugin       interface{}        `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	Parallel     ParallelConfig     `json:"parallel" yaml:"parallel"`
-	ParseLog     ParseLogConfig     `json:"parse_log" yaml:"parse_log"`
-	ProcessBatch ForEachConfig      `json:"process_batch" yaml:"process_batch"`
-	ProcessDAG   ProcessDAGConfig   `json:"process_dag" yaml:"process_dag"`
-	ProcessField ProcessFieldConfig `json:"process_field" yaml:"process_field"`
-	ProcessMap   ProcessMapConfig   `json:"process_map" yaml:"process_map"`
-	Protobuf     ProtobufConfig     `json:"protobuf" yaml:"protobuf"`
-	RateLimit    RateLimitConfig    `json:"rate_limit" yaml:"rate_limit"`
-	Redis        RedisConfig        `json:"redis" yaml:"redis"`
-	Resource     string             `json:"resource" yaml:"resource"`
-	Sample       SampleConfig       `json:"sample" yaml:"sample"`
-	SelectParts  SelectPartsConfig  `json:"select_parts" yaml:"select_parts"`
-	Sleep        SleepConfig        `json:"sleep" yaml:"sleep"`
-	Split        SplitConfig        `json:"split" yaml:"split"`
-	SQL          SQLConfig          `json:"sql" yaml:"sql"`
-	Subprocess   SubprocessConfig   `json:"subprocess" yaml:"subprocess"`
-	Switch       SwitchConfig       `json:"switch" yaml:"switch"`
-	SyncResponse SyncResponseConfig `json:"sync_response" yaml:"sync_response"`
-	Text         TextConfig         `json:"text" yaml:"text"`
-	Try          TryConfig          `json:"try" yaml:"try"`
-	Throttle     ThrottleConfig     `json:"throttle" yaml:"throttle"`
-	Unarchive    UnarchiveConfig    `json:"unarchive" yaml:"unarchive"`
-	While        WhileConfig        `json:"while" yaml:"while"`
-	Workflow     WorkflowConfig     `json:"workflow" yaml:"workflow"`
-	XML          XMLConfig          `json:"xml" yaml:"xml"`
+	Type               string                   `json:"type" yaml:"type"`
+	Archive            ArchiveConfig            `json:"archive" yaml:"archive"`
+	Avro               AvroConfig               `json:"avro" yaml:"avro"`
+	AWK                AWKConfig                `json:"awk" yaml:"awk"`
+	AWSLambda          LambdaConfig             `json:"aws_lambda" yaml:"aws_lambda"`
+	Batch              BatchConfig              `json:"batch" yaml:"batch"`
+	Bloblang           BloblangConfig           `json:"bloblang" yaml:"bloblang"`
+	BoundsCheck        BoundsCheckConfig        `json:"bounds_check" yaml:"bounds_check"`
+	Branch             BranchConfig             `json:"branch" yaml:"branch"`
+	Cache              CacheConfig              `json:"cache" yaml:"cache"`
+	Catch              CatchConfig              `json:"catch" yaml:"catch"`
+	Compress           CompressConfig           `json:"compress" yaml:"compress"`
+	Conditional        ConditionalConfig        `json:"conditional" yaml:"conditional"`
+	Decode             DecodeConfig             `json:"decode" yaml:"decode"`
+	Decompress         DecompressConfig         `json:"decompress" yaml:"decompress"`
+	Dedupe             DedupeConfig             `json:"dedupe" yaml:"dedupe"`
+	Encode             EncodeConfig             `json:"encode" yaml:"encode"`
+	Enrich             EnrichConfig             `json:"enrich" yaml:"enrich"`
+	EnvelopeEncryption EnvelopeEncryptionConfig `json:"envelope_encryption" yaml:"envelope_encryption"`
+	Filter             FilterConfig             `json:"filter" yaml:"filter"`
+	FilterParts        FilterPartsConfig        `json:"filter_parts" yaml:"filter_parts"`
+	ForEach            ForEachConfig            `json:"for_each" yaml:"for_each"`
+	GeoIP              GeoIPConfig              `json:"geoip" yaml:"geoip"`
+	Grok               GrokConfig               `json:"grok" yaml:"grok"`
+	GroupBy            GroupByConfig            `json:"group_by" yaml:"group_by"`
+	GroupByValue       GroupByValueConfig       `json:"group_by_value" yaml:"group_by_value"`
+	Hash               HashConfig               `json:"hash" yaml:"hash"`
+	HashSample         HashSampleConfig         `json:"hash_sample" yaml:"hash_sample"`
+	HTTP               HTTPConfig               `json:"http" yaml:"http"`
+	InsertPart         InsertPartConfig         `json:"insert_part" yaml:"insert_part"`
+	JavaScript         JavaScriptConfig         `json:"javascript" yaml:"javascript"`
+	JMESPath           JMESPathConfig           `json:"jmespath" yaml:"jmespath"`
+	JQ                 JQConfig                 `json:"jq" yaml:"jq"`
+	JSON               JSONConfig               `json:"json" yaml:"json"`
+	JSONSchema         JSONSchemaConfig         `json:"json_schema" yaml:"json_schema"`
+	Lambda             LambdaConfig             `json:"lambda" yaml:"lambda"`
+	Log                LogConfig                `json:"log" yaml:"log"`
+	MergeJSON          MergeJSONConfig          `json:"merge_json" yaml:"merge_json"`
+	Metadata           MetadataConfig           `json:"metadata" yaml:"metadata"`
+	Metric             MetricConfig             `json:"metric" yaml:"metric"`
+	Noop               NoopConfig               `json:"noop" yaml:"noop"`
+	Number             NumberConfig             `json:"number" yaml:"number"`
+	Plugin             interface{}              `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Parallel           ParallelConfig           `json:"parallel" yaml:"parallel"`
+	ParseLog           ParseLogConfig           `json:"parse_log" yaml:"parse_log"`
+	ProcessBatch       ForEachConfig            `json:"process_batch" yaml:"process_batch"`
+	ProcessDAG         ProcessDAGConfig         `json:"process_dag" yaml:"process_dag"`
+	ProcessField       ProcessFieldConfig       `json:"process_field" yaml:"process_field"`
+	ProcessMap         ProcessMapConfig         `json:"process_map" yaml:"process_map"`
+	Protobuf           ProtobufConfig           `json:"protobuf" yaml:"protobuf"`
+	RateLimit          RateLimitConfig          `json:"rate_limit" yaml:"rate_limit"`
+	Redis              RedisConfig              `json:"redis" yaml:"redis"`
+	Resource           string                   `json:"resource" yaml:"resource"`
+	Rollup             RollupConfig             `json:"rollup" yaml:"rollup"`
+	Sample             SampleConfig             `json:"sample" yaml:"sample"`
+	SchemaMigration    SchemaMigrationConfig    `json:"schema_migration" yaml:"schema_migration"`
+	SelectParts        SelectPartsConfig        `json:"select_parts" yaml:"select_parts"`
+	Sleep              SleepConfig              `json:"sleep" yaml:"sleep"`
+	Split              SplitConfig              `json:"split" yaml:"split"`
+	Starlark           StarlarkConfig           `json:"starlark" yaml:"starlark"`
+	SQL                SQLConfig                `json:"sql" yaml:"sql"`
+	Subprocess         SubprocessConfig         `json:"subprocess" yaml:"subprocess"`
+	Switch             SwitchConfig             `json:"switch" yaml:"switch"`
+	SyncResponse       SyncResponseConfig       `json:"sync_response" yaml:"sync_response"`
+	Text               TextConfig               `json:"text" yaml:"text"`
+	Try                TryConfig                `json:"try" yaml:"try"`
+	Throttle           ThrottleConfig           `json:"throttle" yaml:"throttle"`
+	Unarchive          UnarchiveConfig          `json:"unarchive" yaml:"unarchive"`
+	WASM               WASMConfig               `json:"wasm" yaml:"wasm"`
+	While              WhileConfig              `json:"while" yaml:"while"`
+	Workflow           WorkflowConfig           `json:"workflow" yaml:"workflow"`
+	XML                XMLConfig                `json:"xml" yaml:"xml"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:         "bounds_check",
-		Archive:      NewArchiveConfig(),
-		Avro:         NewAvroConfig(),
-		AWK:          NewAWKConfig(),
-		AWSLambda:    NewLambdaConfig(),
-		Batch:        NewBatchConfig(),
-		Bloblang:     NewBloblangConfig(),
-		BoundsCheck:  NewBoundsCheckConfig(),
-		Branch:       NewBranchConfig(),
-		Cache:        NewCacheConfig(),
-		Catch:        NewCatchConfig(),
-		Compress:     NewCompressConfig(),
-		Conditional:  NewConditionalConfig(),
-		Decode:       NewDecodeConfig(),
-		Decompress:   NewDecompressConfig(),
-		Dedupe:       NewDedupeConfig(),
-		Encode:       NewEncodeConfig(),
-		Filter:       NewFilterConfig(),
-		FilterParts:  NewFilterPartsConfig(),
-		ForEach:      NewForEachConfig(),
-		Grok:         NewGrokConfig(),
-		GroupBy:      NewGroupByConfig(),
-		GroupByValue: NewGroupByValueConfig(),
-		Hash:         NewHashConfig(),
-		HashSample:   NewHashSampleConfig(),
-		HTTP:         NewHTTPConfig(),
-		InsertPart:   NewInsertPartConfig(),
-		JMESPath:     NewJMESPathConfig(),
-		JQ:           NewJQConfig(),
-		JSON:         NewJSONConfig(),
-		JSONSchema:   NewJSONSchemaConfig(),
-		Lambda:       NewLambdaConfig(),
-		Log:          NewLogConfig(),
-		MergeJSON:    NewMergeJSONConfig(),
-		Metadata:     NewMetadataConfig(),
-		Metric:       NewMetricConfig(),
-		Noop:         NewNoopConfig(),
-		Number:       NewNumberConfig(),
-		Plugin:       nil,
-		Parallel:     NewParallelConfig(),
-		ParseLog:     NewParseLogConfig(),
-		ProcessBatch: NewForEachConfig(),
-		ProcessDAG:   NewProcessDAGConfig(),
-		ProcessField: NewProcessFieldConfig(),
-		ProcessMap:   NewProcessMapConfig(),
-		Protobuf:     NewProtobufConfig(),
-		RateLimit:    NewRateLimitConfig(),
-		Redis:        NewRedisConfig(),
-		Resource:     "",
-		Sample:       NewSampleConfig(),
-		SelectParts:  NewSelectPartsConfig(),
-		Sleep:        NewSleepConfig(),
-		Split:        NewSplitConfig(),
-		SQL:          NewSQLConfig(),
-		Subprocess:   NewSubprocessConfig(),
-		Switch:       NewSwitchConfig(),
-		SyncResponse: NewSyncResponseConfig(),
-		Text:         NewTextConfig(),
-		Try:          NewTryConfig(),
-		Throttle:     NewThrottleConfig(),
-		Unarchive:    NewUnarchiveConfig(),
-		While:        NewWhileConfig(),
-		Workflow:     NewWorkflowConfig(),
-		XML:          NewXMLConfig(),
+		Type:               "bounds_check",
+		Archive:            NewArchiveConfig(),
+		Avro:               NewAvroConfig(),
+		AWK:                NewAWKConfig(),
+		AWSLambda:          NewLambdaConfig(),
+		Batch:              NewBatchConfig(),
+		Bloblang:           NewBloblangConfig(),
+		BoundsCheck:        NewBoundsCheckConfig(),
+		Branch:             NewBranchConfig(),
+		Cache:              NewCacheConfig(),
+		Catch:              NewCatchConfig(),
+		Compress:           NewCompressConfig(),
+		Conditional:        NewConditionalConfig(),
+		Decode:             NewDecodeConfig(),
+		Decompress:         NewDecompressConfig(),
+		Dedupe:             NewDedupeConfig(),
+		Encode:             NewEncodeConfig(),
+		Enrich:             NewEnrichConfig(),
+		EnvelopeEncryption: NewEnvelopeEncryptionConfig(),
+		Filter:             NewFilterConfig(),
+		FilterParts:        NewFilterPartsConfig(),
+		ForEach:            NewForEachConfig(),
+		GeoIP:              NewGeoIPConfig(),
+		Grok:               NewGrokConfig(),
+		GroupBy:            NewGroupByConfig(),
+		GroupByValue:       NewGroupByValueConfig(),
+		Hash:               NewHashConfig(),
+		HashSample:         NewHashSampleConfig(),
+		HTTP:               NewHTTPConfig(),
+		InsertPart:         NewInsertPartConfig(),
+		JavaScript:         NewJavaScriptConfig(),
+		JMESPath:           NewJMESPathConfig(),
+		JQ:                 NewJQConfig(),
+		JSON:               NewJSONConfig(),
+		JSONSchema:         NewJSONSchemaConfig(),
+		Lambda:             NewLambdaConfig(),
+		Log:                NewLogConfig(),
+		MergeJSON:          NewMergeJSONConfig(),
+		Metadata:           NewMetadataConfig(),
+		Metric:             NewMetricConfig(),
+		Noop:               NewNoopConfig(),
+		Number:             NewNumberConfig(),
+		Plugin:             nil,
+		Parallel:           NewParallelConfig(),
+		ParseLog:           NewParseLogConfig(),
+		ProcessBatch:       NewForEachConfig(),
+		ProcessDAG:         NewProcessDAGConfig(),
+		ProcessField:       NewProcessFieldConfig(),
+		ProcessMap:         NewProcessMapConfig(),
+		Protobuf:           NewProtobufConfig(),
+		RateLimit:          NewRateLimitConfig(),
+		Redis:              NewRedisConfig(),
+		Resource:           "",
+		Rollup:             NewRollupConfig(),
+		Sample:             NewSampleConfig(),
+		SchemaMigration:    NewSchemaMigrationConfig(),
+		SelectParts:        NewSelectPartsConfig(),
+		Sleep:              NewSleepConfig(),
+		Split:              NewSplitConfig(),
+		Starlark:           NewStarlarkConfig(),
+		SQL:                NewSQLConfig(),
+		Subprocess:         NewSubprocessConfig(),
+		Switch:             NewSwitchConfig(),
+		SyncResponse:       NewSyncResponseConfig(),
+		Text:               NewTextConfig(),
+		Try:                NewTryConfig(),
+		Throttle:           NewThrottleConfig(),
+		Unarchive:          NewUnarchiveConfig(),
+		WASM:               NewWASMConfig(),
+		While:              NewWhileConfig(),
+		Workflow:           NewWorkflowConfig(),
+		XML:                NewXMLConfig(),
 	}
 }
 
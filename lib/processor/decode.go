@@ -142,7 +142,7 @@ func (c *Decode) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 	proc := func(i int, span opentracing.Span, part types.Part) error {
 		newBytes, err := c.fn(part.Get())
 		if err != nil {
-			c.log.Errorf("Failed to decode message part: %v\n", err)
+			log.WithMessageFields(c.log, i, msg).Errorf("Failed to decode message part: %v\n", err)
 			c.mErr.Incr(1)
 			return err
 		}
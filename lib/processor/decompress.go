@@ -6,8 +6,10 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"compress/zlib"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -16,7 +18,9 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/opentracing/opentracing-go"
+	"github.com/pierrec/lz4"
 )
 
 //------------------------------------------------------------------------------
@@ -29,9 +33,10 @@ func init() {
 		},
 		Summary: `
 Decompresses messages according to the selected algorithm. Supported
-decompression types are: gzip, zlib, bzip2, flate.`,
+decompression types are: gzip, zlib, bzip2, flate, snappy, zstd, lz4.`,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("algorithm", "The decompression algorithm to use.").HasOptions("gzip", "zlib", "bzip2", "flate", "snappy"),
+			docs.FieldCommon("algorithm", "The decompression algorithm to use.").HasOptions("gzip", "zlib", "bzip2", "flate", "snappy", "zstd", "lz4"),
+			docs.FieldAdvanced("dictionary_path", "An optional path to a zstd dictionary file to decompress messages with, which must be the dictionary that they were [compressed](/docs/components/processors/compress#dictionaries) with. Only applicable to the zstd algorithm.", "/etc/benthos/messages.zdict").AtVersion("3.39.0"),
 			partsFieldSpec,
 		},
 	}
@@ -41,15 +46,17 @@ decompression types are: gzip, zlib, bzip2, flate.`,
 
 // DecompressConfig contains configuration fields for the Decompress processor.
 type DecompressConfig struct {
-	Algorithm string `json:"algorithm" yaml:"algorithm"`
-	Parts     []int  `json:"parts" yaml:"parts"`
+	Algorithm      string `json:"algorithm" yaml:"algorithm"`
+	DictionaryPath string `json:"dictionary_path" yaml:"dictionary_path"`
+	Parts          []int  `json:"parts" yaml:"parts"`
 }
 
 // NewDecompressConfig returns a DecompressConfig with default values.
 func NewDecompressConfig() DecompressConfig {
 	return DecompressConfig{
-		Algorithm: "gzip",
-		Parts:     []int{},
+		Algorithm:      "gzip",
+		DictionaryPath: "",
+		Parts:          []int{},
 	}
 }
 
@@ -114,6 +121,35 @@ func bzip2Decompress(b []byte) ([]byte, error) {
 	return outBuf.Bytes(), nil
 }
 
+func lz4Decompress(b []byte) ([]byte, error) {
+	zr := lz4.NewReader(bytes.NewReader(b))
+
+	outBuf := bytes.Buffer{}
+	if _, err := outBuf.ReadFrom(zr); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return outBuf.Bytes(), nil
+}
+
+func newZstdDecompressor(dictPath string) (decompressFunc, func(), error) {
+	opts := []zstd.DOption{}
+	if dictPath != "" {
+		dict, err := ioutil.ReadFile(dictPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read dictionary: %v", err)
+		}
+		opts = append(opts, zstd.WithDecoderDicts(dict))
+	}
+
+	dec, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(b []byte) ([]byte, error) {
+		return dec.DecodeAll(b, nil)
+	}, dec.Close, nil
+}
+
 func strToDecompressor(str string) (decompressFunc, error) {
 	switch str {
 	case "gzip":
@@ -126,6 +162,8 @@ func strToDecompressor(str string) (decompressFunc, error) {
 		return bzip2Decompress, nil
 	case "snappy":
 		return snappyDecompress, nil
+	case "lz4":
+		return lz4Decompress, nil
 	}
 	return nil, fmt.Errorf("decompression type not recognised: %v", str)
 }
@@ -137,6 +175,7 @@ func strToDecompressor(str string) (decompressFunc, error) {
 type Decompress struct {
 	conf   DecompressConfig
 	decomp decompressFunc
+	close  func()
 
 	log   log.Modular
 	stats metrics.Type
@@ -151,13 +190,24 @@ type Decompress struct {
 func NewDecompress(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	dcor, err := strToDecompressor(conf.Decompress.Algorithm)
+	var dcor decompressFunc
+	closeFn := func() {}
+
+	var err error
+	if conf.Decompress.Algorithm == "zstd" {
+		dcor, closeFn, err = newZstdDecompressor(conf.Decompress.DictionaryPath)
+	} else if conf.Decompress.DictionaryPath != "" {
+		err = errors.New("a dictionary_path can only be used with the zstd algorithm")
+	} else {
+		dcor, err = strToDecompressor(conf.Decompress.Algorithm)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &Decompress{
 		conf:   conf.Decompress,
 		decomp: dcor,
+		close:  closeFn,
 		log:    log,
 		stats:  stats,
 
@@ -180,7 +230,7 @@ func (d *Decompress) ProcessMessage(msg types.Message) ([]types.Message, types.R
 		newBytes, err := d.decomp(part.Get())
 		if err != nil {
 			d.mErr.Incr(1)
-			d.log.Errorf("Failed to decompress message part: %v\n", err)
+			log.WithMessageFields(d.log, i, msg).Errorf("Failed to decompress message part: %v\n", err)
 			return err
 		}
 		part.Set(newBytes)
@@ -201,6 +251,7 @@ func (d *Decompress) ProcessMessage(msg types.Message) ([]types.Message, types.R
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (d *Decompress) CloseAsync() {
+	d.close()
 }
 
 // WaitForClose blocks until the processor has closed down.
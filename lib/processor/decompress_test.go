@@ -5,6 +5,7 @@ import (
 	"compress/flate"
 	"compress/gzip"
 	"compress/zlib"
+	"io/ioutil"
 	"reflect"
 	"testing"
 
@@ -12,6 +13,10 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDecompressBadAlgo(t *testing.T) {
@@ -212,6 +217,115 @@ func TestDecompressFlate(t *testing.T) {
 	}
 }
 
+func TestDecompressZstd(t *testing.T) {
+	conf := NewConfig()
+	conf.Decompress.Algorithm = "zstd"
+
+	enc, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+
+	exp := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+	}
+	input := [][]byte{}
+	for _, b := range exp {
+		input = append(input, enc.EncodeAll(b, nil))
+	}
+
+	proc, err := NewDecompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer proc.CloseAsync()
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, exp, message.GetAllBytes(msgs[0]))
+}
+
+func TestDecompressZstdDictionary(t *testing.T) {
+	dictPath := writeTestZstdDict(t)
+	dict, err := ioutil.ReadFile(dictPath)
+	require.NoError(t, err)
+
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dict))
+	require.NoError(t, err)
+
+	input := [][]byte{}
+	for _, b := range testZstdDictInput {
+		input = append(input, enc.EncodeAll(b, nil))
+	}
+
+	conf := NewConfig()
+	conf.Decompress.Algorithm = "zstd"
+
+	proc, err := NewDecompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer proc.CloseAsync()
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i := range input {
+		assert.True(t, HasFailed(msgs[0].Get(i)))
+	}
+
+	conf.Decompress.DictionaryPath = dictPath
+
+	proc, err = NewDecompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer proc.CloseAsync()
+
+	msgs, res = proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, testZstdDictInput, message.GetAllBytes(msgs[0]))
+}
+
+func TestDecompressDictionaryBadAlgo(t *testing.T) {
+	conf := NewConfig()
+	conf.Decompress.Algorithm = "gzip"
+	conf.Decompress.DictionaryPath = writeTestZstdDict(t)
+
+	_, err := NewDecompress(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Decompress.Algorithm = "zstd"
+	conf.Decompress.DictionaryPath = "/does/not/exist"
+
+	_, err = NewDecompress(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestDecompressLZ4(t *testing.T) {
+	conf := NewConfig()
+	conf.Decompress.Algorithm = "lz4"
+
+	exp := [][]byte{
+		[]byte("hello world first part"),
+		[]byte("hello world second part"),
+		[]byte("third part"),
+	}
+	input := [][]byte{}
+	for _, b := range exp {
+		var buf bytes.Buffer
+		zw := lz4.NewWriter(&buf)
+		_, err := zw.Write(b)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		input = append(input, buf.Bytes())
+	}
+
+	proc, err := NewDecompress(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, exp, message.GetAllBytes(msgs[0]))
+}
+
 func TestDecompressIndexBounds(t *testing.T) {
 	conf := NewConfig()
 
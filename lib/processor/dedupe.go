@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
@@ -71,12 +72,48 @@ sink followed by a restart of the Benthos instance.
 If you intend to preserve at-least-once delivery guarantees you can avoid this
 problem by using a memory based cache. This is a compromise that can achieve
 effective deduplication but parallel deployments of the pipeline as well as
-service restarts increase the chances of duplicates passing undetected.`,
+service restarts increase the chances of duplicates passing undetected.
+
+## Probabilistic Filters
+
+Caches hold every key exactly, which becomes impractical when deduplicating
+across billions of keys. The ` + "`filter`" + ` field allows you to instead
+deduplicate against an in-memory ` + "`bloom`" + ` or ` + "`cuckoo`" + ` filter,
+which are sized by a ` + "`capacity`" + ` of keys and a target
+` + "`false_positive_rate`" + `, where a false positive results in a message
+being dropped despite not having been seen before. Cuckoo filters are more
+space efficient than bloom filters for false positive rates lower than around
+0.3%, but unlike bloom filters they cannot exceed their capacity.
+
+When a ` + "`rotation_interval`" + ` is set the filter is replaced with an empty
+one at each interval, and keys of the previous window continue to be detected
+until the following rotation, giving a deduplication window of between one and
+two intervals. A cuckoo filter that reaches its capacity is rotated early.
+
+Filters are shared by all processing threads of a pipeline. If a
+` + "`cache`" + ` is also specified then a message is a duplicate when either
+the cache or the filter contains its key, which allows you to combine exact
+deduplication of recent keys (with a cache TTL) with a longer probabilistic
+window:
+
+` + "```yaml" + `
+pipeline:
+  processors:
+    - dedupe:
+        cache: recent_keys
+        key: ${! json("id") }
+        filter:
+          type: bloom
+          capacity: 500000000
+          false_positive_rate: 0.0001
+          rotation_interval: 24h
+` + "```" + ``,
 		FieldSpecs: docs.FieldSpecs{
-			docs.FieldCommon("cache", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
+			docs.FieldCommon("cache", "The [`cache` resource](/docs/components/caches/about) to target with this processor. This field is optional when a `filter` is configured."),
 			docs.FieldCommon("hash", "The hash type to used.").HasOptions("none", "xxhash"),
 			docs.FieldCommon("key", "An optional key to use for deduplication (instead of the entire message contents).").SupportsInterpolation(true),
 			docs.FieldCommon("drop_on_err", "Whether messages should be dropped when the cache returns an error."),
+			dedupeFilterFieldSpec(),
 			docs.FieldAdvanced("parts", "An array of message indexes within the batch to deduplicate based on. If left empty all messages included. This field is only applicable when batching messages [at the input level](/docs/configuration/batching)."),
 		},
 	}
@@ -86,11 +123,12 @@ service restarts increase the chances of duplicates passing undetected.`,
 
 // DedupeConfig contains configuration fields for the Dedupe processor.
 type DedupeConfig struct {
-	Cache          string `json:"cache" yaml:"cache"`
-	HashType       string `json:"hash" yaml:"hash"`
-	Parts          []int  `json:"parts" yaml:"parts"` // message parts to hash
-	Key            string `json:"key" yaml:"key"`
-	DropOnCacheErr bool   `json:"drop_on_err" yaml:"drop_on_err"`
+	Cache          string             `json:"cache" yaml:"cache"`
+	HashType       string             `json:"hash" yaml:"hash"`
+	Parts          []int              `json:"parts" yaml:"parts"` // message parts to hash
+	Key            string             `json:"key" yaml:"key"`
+	DropOnCacheErr bool               `json:"drop_on_err" yaml:"drop_on_err"`
+	Filter         DedupeFilterConfig `json:"filter" yaml:"filter"`
 }
 
 // NewDedupeConfig returns a DedupeConfig with default values.
@@ -101,6 +139,7 @@ func NewDedupeConfig() DedupeConfig {
 		Parts:          []int{0}, // only consider the 1st part
 		Key:            "",
 		DropOnCacheErr: true,
+		Filter:         NewDedupeFilterConfig(),
 	}
 }
 
@@ -159,11 +198,16 @@ type Dedupe struct {
 	cache      types.Cache
 	hasherFunc hasherFunc
 
+	filter    *dedupeFilter
+	filterKey string
+	closeOnce sync.Once
+
 	mCount     metrics.StatCounter
 	mErrHash   metrics.StatCounter
 	mErrCache  metrics.StatCounter
 	mErr       metrics.StatCounter
 	mDropped   metrics.StatCounter
+	mRotated   metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
 }
@@ -172,11 +216,6 @@ type Dedupe struct {
 func NewDedupe(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	c, err := mgr.GetCache(conf.Dedupe.Cache)
-	if err != nil {
-		return nil, err
-	}
-
 	hFunc, err := strToHasher(conf.Dedupe.HashType)
 	if err != nil {
 		return nil, err
@@ -187,6 +226,21 @@ func NewDedupe(
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
 
+	var c types.Cache
+	if conf.Dedupe.Cache != "" || conf.Dedupe.Filter.Type == "none" {
+		if c, err = mgr.GetCache(conf.Dedupe.Cache); err != nil {
+			return nil, err
+		}
+	}
+
+	var filter *dedupeFilter
+	var filterKey string
+	if conf.Dedupe.Filter.Type != "none" {
+		if filter, filterKey, err = acquireDedupeFilter(conf.Dedupe, mgr); err != nil {
+			return nil, err
+		}
+	}
+
 	return &Dedupe{
 		conf:  conf,
 		log:   log,
@@ -197,11 +251,15 @@ func NewDedupe(
 		cache:      c,
 		hasherFunc: hFunc,
 
+		filter:    filter,
+		filterKey: filterKey,
+
 		mCount:     stats.GetCounter("count"),
 		mErrHash:   stats.GetCounter("error.hash"),
 		mErrCache:  stats.GetCounter("error.cache"),
 		mErr:       stats.GetCounter("error"),
 		mDropped:   stats.GetCounter("dropped"),
+		mRotated:   stats.GetCounter("filter.rotated"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}, nil
@@ -249,22 +307,38 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 			d.mDropped.Incr(1)
 			return nil, response.NewAck()
 		}
-	} else if err := d.cache.Add(string(hasher.Bytes()), []byte{'t'}); err != nil {
-		if err != types.ErrKeyAlreadyExists {
-			d.mErrCache.Incr(1)
-			d.mErr.Incr(1)
-			d.log.Errorf("Cache error: %v\n", err)
-			for _, s := range spans {
-				s.LogFields(
-					olog.String("event", "error"),
-					olog.String("type", err.Error()),
-				)
+	} else {
+		hashBytes := hasher.Bytes()
+		duplicate := false
+		if d.cache != nil {
+			if err := d.cache.Add(string(hashBytes), []byte{'t'}); err != nil {
+				if err != types.ErrKeyAlreadyExists {
+					d.mErrCache.Incr(1)
+					d.mErr.Incr(1)
+					d.log.Errorf("Cache error: %v\n", err)
+					for _, s := range spans {
+						s.LogFields(
+							olog.String("event", "error"),
+							olog.String("type", err.Error()),
+						)
+					}
+					if d.conf.Dedupe.DropOnCacheErr {
+						d.mDropped.Incr(1)
+						return nil, response.NewAck()
+					}
+				} else {
+					duplicate = true
+				}
 			}
-			if d.conf.Dedupe.DropOnCacheErr {
-				d.mDropped.Incr(1)
-				return nil, response.NewAck()
+		}
+		if d.filter != nil {
+			exists, rotated := d.filter.TestAndAdd(hashBytes)
+			if rotated {
+				d.mRotated.Incr(1)
 			}
-		} else {
+			duplicate = duplicate || exists
+		}
+		if duplicate {
 			for _, s := range spans {
 				s.LogFields(
 					olog.String("event", "dropped"),
@@ -284,6 +358,11 @@ func (d *Dedupe) ProcessMessage(msg types.Message) ([]types.Message, types.Respo
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (d *Dedupe) CloseAsync() {
+	d.closeOnce.Do(func() {
+		if d.filter != nil {
+			releaseDedupeFilter(d.filterKey)
+		}
+	})
 }
 
 // WaitForClose blocks until the processor has closed down.
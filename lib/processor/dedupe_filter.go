@@ -0,0 +1,382 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+// DedupeFilterConfig contains configuration fields for a probabilistic filter
+// used by the dedupe processor.
+type DedupeFilterConfig struct {
+	Type              string  `json:"type" yaml:"type"`
+	Capacity          int     `json:"capacity" yaml:"capacity"`
+	FalsePositiveRate float64 `json:"false_positive_rate" yaml:"false_positive_rate"`
+	RotationInterval  string  `json:"rotation_interval" yaml:"rotation_interval"`
+}
+
+// NewDedupeFilterConfig returns a DedupeFilterConfig with default values.
+func NewDedupeFilterConfig() DedupeFilterConfig {
+	return DedupeFilterConfig{
+		Type:              "none",
+		Capacity:          10000000,
+		FalsePositiveRate: 0.001,
+		RotationInterval:  "",
+	}
+}
+
+func dedupeFilterFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced(
+		"filter", "An optional probabilistic filter to deduplicate against, which can be used instead of or in combination with a `cache`.",
+	).WithChildren(
+		docs.FieldCommon("type", "The type of filter to use.").HasOptions("none", "bloom", "cuckoo"),
+		docs.FieldCommon("capacity", "The number of keys that the filter is sized to hold whilst maintaining the target false positive rate."),
+		docs.FieldCommon("false_positive_rate", "The target probability of a key being reported as a duplicate when it has not been seen before."),
+		docs.FieldCommon("rotation_interval", "An optional period after which the filter is rotated. When empty the filter is never rotated unless it reaches capacity.", "1h", "24h"),
+	).AtVersion("3.39.0")
+}
+
+//------------------------------------------------------------------------------
+
+// probFilter is a set of hashes that can report false positives.
+type probFilter interface {
+	// test returns whether the hash might exist within the filter.
+	test(h uint64) bool
+
+	// add inserts a hash into the filter, returning false if the filter is
+	// full.
+	add(h uint64) bool
+}
+
+//------------------------------------------------------------------------------
+
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	n := float64(capacity)
+	m := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// location derives the ith bit location of a hash by double hashing, as
+// described by Kirsch and Mitzenmacher.
+func (b *bloomFilter) location(h uint64, i uint64) uint64 {
+	h1, h2 := h&0xffffffff, h>>32
+	return (h1 + i*h2) % b.m
+}
+
+func (b *bloomFilter) test(h uint64) bool {
+	for i := uint64(0); i < b.k; i++ {
+		l := b.location(h, i)
+		if b.bits[l/64]&(1<<(l%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomFilter) add(h uint64) bool {
+	for i := uint64(0); i < b.k; i++ {
+		l := b.location(h, i)
+		b.bits[l/64] |= 1 << (l % 64)
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	cuckooBucketSize = 4
+	cuckooMaxKicks   = 500
+)
+
+type cuckooFilter struct {
+	// Fingerprints are stored in the smallest of these slices capable of
+	// holding them.
+	fps16 []uint16
+	fps32 []uint32
+
+	fpMask     uint64
+	bucketMask uint64
+
+	victim    uint32
+	victimIdx uint64
+	hasVictim bool
+}
+
+func newCuckooFilter(capacity int, fpRate float64) *cuckooFilter {
+	fpBits := int(math.Ceil(math.Log2(2 * cuckooBucketSize / fpRate)))
+	if fpBits < 4 {
+		fpBits = 4
+	} else if fpBits > 32 {
+		fpBits = 32
+	}
+
+	// Cuckoo filters perform poorly as they approach full occupancy, and so we
+	// size the buckets to target a maximum load of 95%.
+	buckets := uint64(math.Ceil(float64(capacity) / cuckooBucketSize / 0.95))
+	if buckets < 1 {
+		buckets = 1
+	}
+	buckets = 1 << uint(bits.Len64(buckets-1))
+
+	c := &cuckooFilter{
+		fpMask:     (1 << uint(fpBits)) - 1,
+		bucketMask: buckets - 1,
+	}
+	if fpBits <= 16 {
+		c.fps16 = make([]uint16, buckets*cuckooBucketSize)
+	} else {
+		c.fps32 = make([]uint32, buckets*cuckooBucketSize)
+	}
+	return c
+}
+
+func (c *cuckooFilter) get(i uint64) uint32 {
+	if c.fps16 != nil {
+		return uint32(c.fps16[i])
+	}
+	return c.fps32[i]
+}
+
+func (c *cuckooFilter) set(i uint64, fp uint32) {
+	if c.fps16 != nil {
+		c.fps16[i] = uint16(fp)
+		return
+	}
+	c.fps32[i] = fp
+}
+
+func (c *cuckooFilter) indexesOf(h uint64) (fp uint32, i1, i2 uint64) {
+	// An empty slot is represented by a zero fingerprint.
+	if fp = uint32((h >> 32) & c.fpMask); fp == 0 {
+		fp = 1
+	}
+	i1 = h & c.bucketMask
+	return fp, i1, c.altIndex(i1, fp)
+}
+
+func (c *cuckooFilter) altIndex(i uint64, fp uint32) uint64 {
+	return (i ^ (uint64(fp) * 0x5bd1e995)) & c.bucketMask
+}
+
+func (c *cuckooFilter) bucketHas(b uint64, fp uint32) bool {
+	for i := b * cuckooBucketSize; i < (b+1)*cuckooBucketSize; i++ {
+		if c.get(i) == fp {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cuckooFilter) bucketInsert(b uint64, fp uint32) bool {
+	for i := b * cuckooBucketSize; i < (b+1)*cuckooBucketSize; i++ {
+		if c.get(i) == 0 {
+			c.set(i, fp)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cuckooFilter) test(h uint64) bool {
+	fp, i1, i2 := c.indexesOf(h)
+	if c.hasVictim && c.victim == fp && (c.victimIdx == i1 || c.victimIdx == i2) {
+		return true
+	}
+	return c.bucketHas(i1, fp) || c.bucketHas(i2, fp)
+}
+
+func (c *cuckooFilter) add(h uint64) bool {
+	if c.hasVictim {
+		return false
+	}
+	fp, i1, i2 := c.indexesOf(h)
+	if c.bucketInsert(i1, fp) || c.bucketInsert(i2, fp) {
+		return true
+	}
+
+	b := i1
+	if rand.Intn(2) == 0 {
+		b = i2
+	}
+	for n := 0; n < cuckooMaxKicks; n++ {
+		slot := b*cuckooBucketSize + uint64(rand.Intn(cuckooBucketSize))
+		evicted := c.get(slot)
+		c.set(slot, fp)
+		fp = evicted
+
+		b = c.altIndex(b, fp)
+		if c.bucketInsert(b, fp) {
+			return true
+		}
+	}
+
+	// The last evicted fingerprint is kept aside so that it continues to be
+	// found, but the filter is now full.
+	c.victim, c.victimIdx, c.hasVictim = fp, b, true
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// dedupeFilter wraps a probabilistic filter with rotation, where the keys of
+// the previous window continue to be checked until the following rotation.
+type dedupeFilter struct {
+	newFilter func() probFilter
+	interval  time.Duration
+
+	mut          sync.Mutex
+	current      probFilter
+	previous     probFilter
+	lastRotation time.Time
+
+	refs int
+}
+
+func newDedupeFilter(conf DedupeFilterConfig) (*dedupeFilter, error) {
+	if conf.Capacity <= 0 {
+		return nil, errors.New("filter capacity must be greater than zero")
+	}
+	if conf.FalsePositiveRate <= 0 || conf.FalsePositiveRate >= 1 {
+		return nil, errors.New("filter false_positive_rate must be between zero and one")
+	}
+
+	f := &dedupeFilter{}
+	switch conf.Type {
+	case "bloom":
+		f.newFilter = func() probFilter {
+			return newBloomFilter(conf.Capacity, conf.FalsePositiveRate)
+		}
+	case "cuckoo":
+		f.newFilter = func() probFilter {
+			return newCuckooFilter(conf.Capacity, conf.FalsePositiveRate)
+		}
+	default:
+		return nil, fmt.Errorf("filter type not recognised: %v", conf.Type)
+	}
+
+	if conf.RotationInterval != "" {
+		var err error
+		if f.interval, err = time.ParseDuration(conf.RotationInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse filter rotation_interval: %v", err)
+		}
+	}
+
+	f.current = f.newFilter()
+	f.lastRotation = time.Now()
+	return f, nil
+}
+
+func (f *dedupeFilter) rotate() {
+	f.previous = f.current
+	f.current = f.newFilter()
+	f.lastRotation = time.Now()
+}
+
+// TestAndAdd adds a key to the filter and returns whether it was already
+// present, where false positives are possible.
+func (f *dedupeFilter) TestAndAdd(key []byte) (exists bool, rotated bool) {
+	h := xxhash.Checksum64(key)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.interval > 0 && time.Since(f.lastRotation) >= f.interval {
+		f.rotate()
+		rotated = true
+	}
+
+	if f.current.test(h) {
+		return true, rotated
+	}
+	exists = f.previous != nil && f.previous.test(h)
+
+	// Keys found within the previous window are added to the current one so
+	// that they remain detectable after the next rotation.
+	if !f.current.add(h) {
+		f.rotate()
+		rotated = true
+		f.current.add(h)
+	}
+	return exists, rotated
+}
+
+//------------------------------------------------------------------------------
+
+// Processors are constructed once for each pipeline thread, and therefore
+// filters are shared between instances of the same config within a manager in
+// order to deduplicate across all threads.
+var dedupeFilters = struct {
+	sync.Mutex
+	filters map[string]*dedupeFilter
+}{filters: map[string]*dedupeFilter{}}
+
+func dedupeFilterKey(conf DedupeConfig, mgr types.Manager) (string, error) {
+	confBytes, err := json.Marshal(conf)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%p:%s", mgr, confBytes), nil
+}
+
+func acquireDedupeFilter(conf DedupeConfig, mgr types.Manager) (*dedupeFilter, string, error) {
+	key, err := dedupeFilterKey(conf, mgr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dedupeFilters.Lock()
+	defer dedupeFilters.Unlock()
+
+	f, exists := dedupeFilters.filters[key]
+	if !exists {
+		if f, err = newDedupeFilter(conf.Filter); err != nil {
+			return nil, "", err
+		}
+		dedupeFilters.filters[key] = f
+	}
+	f.refs++
+	return f, key, nil
+}
+
+func releaseDedupeFilter(key string) {
+	dedupeFilters.Lock()
+	defer dedupeFilters.Unlock()
+
+	f, exists := dedupeFilters.filters[key]
+	if !exists {
+		return
+	}
+	if f.refs--; f.refs <= 0 {
+		delete(dedupeFilters.filters, key)
+	}
+}
+
+//------------------------------------------------------------------------------
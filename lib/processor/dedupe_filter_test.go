@@ -0,0 +1,180 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbFilterFalsePositives(t *testing.T) {
+	filters := map[string]func() probFilter{
+		"bloom": func() probFilter {
+			return newBloomFilter(10000, 0.01)
+		},
+		"cuckoo": func() probFilter {
+			return newCuckooFilter(10000, 0.01)
+		},
+	}
+
+	for name, ctor := range filters {
+		t.Run(name, func(t *testing.T) {
+			f := ctor()
+			for i := 0; i < 10000; i++ {
+				require.True(t, f.add(xxhash.Checksum64([]byte(fmt.Sprintf("foo%v", i)))))
+			}
+			for i := 0; i < 10000; i++ {
+				require.True(t, f.test(xxhash.Checksum64([]byte(fmt.Sprintf("foo%v", i)))), i)
+			}
+
+			falsePositives := 0
+			for i := 0; i < 10000; i++ {
+				if f.test(xxhash.Checksum64([]byte(fmt.Sprintf("bar%v", i)))) {
+					falsePositives++
+				}
+			}
+			assert.Less(t, falsePositives, 200)
+		})
+	}
+}
+
+func TestCuckooFilterFull(t *testing.T) {
+	f := newCuckooFilter(100, 0.01)
+
+	i := 0
+	for ; i < 10000; i++ {
+		if !f.add(xxhash.Checksum64([]byte(fmt.Sprintf("foo%v", i)))) {
+			break
+		}
+	}
+	require.Less(t, i, 10000)
+	for j := 0; j < i; j++ {
+		require.True(t, f.test(xxhash.Checksum64([]byte(fmt.Sprintf("foo%v", j)))), j)
+	}
+}
+
+func TestDedupeFilterRotation(t *testing.T) {
+	conf := NewDedupeFilterConfig()
+	conf.Type = "bloom"
+	conf.Capacity = 1000
+	conf.RotationInterval = "1h"
+
+	f, err := newDedupeFilter(conf)
+	require.NoError(t, err)
+
+	exists, rotated := f.TestAndAdd([]byte("foo"))
+	assert.False(t, exists)
+	assert.False(t, rotated)
+
+	f.lastRotation = time.Now().Add(-time.Hour)
+	exists, rotated = f.TestAndAdd([]byte("bar"))
+	assert.False(t, exists)
+	assert.True(t, rotated)
+
+	// Found within the previous window, and therefore added to the current.
+	exists, _ = f.TestAndAdd([]byte("foo"))
+	assert.True(t, exists)
+
+	f.lastRotation = time.Now().Add(-time.Hour)
+	exists, _ = f.TestAndAdd([]byte("foo"))
+	assert.True(t, exists)
+
+	f.lastRotation = time.Now().Add(-time.Hour)
+	_, _ = f.TestAndAdd([]byte("baz"))
+	f.lastRotation = time.Now().Add(-time.Hour)
+	exists, _ = f.TestAndAdd([]byte("bar"))
+	assert.False(t, exists)
+}
+
+func TestDedupeFilterSharedInstances(t *testing.T) {
+	conf := NewConfig()
+	conf.Dedupe.Filter.Type = "cuckoo"
+	conf.Dedupe.Filter.Capacity = 1000
+
+	procA, err := NewDedupe(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	procB, err := NewDedupe(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := procA.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	msgs, res = procB.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.NotNil(t, res)
+	require.Len(t, msgs, 0)
+
+	msgs, res = procB.ProcessMessage(message.New([][]byte{[]byte("bar")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	procA.CloseAsync()
+	procB.CloseAsync()
+
+	// Once all instances are closed the filter is discarded.
+	procC, err := NewDedupe(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = procC.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	procC.CloseAsync()
+}
+
+func TestDedupeFilterWithCache(t *testing.T) {
+	memCache, err := cache.NewMemory(cache.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": memCache,
+		},
+	}
+
+	conf := NewConfig()
+	conf.Dedupe.Cache = "foocache"
+	conf.Dedupe.Filter.Type = "bloom"
+	conf.Dedupe.Filter.Capacity = 1000
+
+	proc, err := NewDedupe(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer proc.CloseAsync()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	// Expired from the cache but still detected by the filter.
+	require.NoError(t, memCache.Delete("foo"))
+
+	msgs, res = proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	require.NotNil(t, res)
+	require.Len(t, msgs, 0)
+
+	_, err = memCache.Get("foo")
+	require.NoError(t, err)
+}
+
+func TestDedupeFilterBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Dedupe.Filter.Type = "nope"
+
+	_, err := NewDedupe(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Dedupe.Filter.Type = "bloom"
+	conf.Dedupe.Filter.FalsePositiveRate = 1.5
+
+	_, err = NewDedupe(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
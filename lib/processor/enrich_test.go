@@ -0,0 +1,196 @@
+package processor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingCache struct {
+	types.Cache
+	multiGets [][]string
+}
+
+func (c *countingCache) GetMulti(keys ...string) (map[string][]byte, error) {
+	c.multiGets = append(c.multiGets, keys)
+	return c.Cache.(types.CacheMultiGetter).GetMulti(keys...)
+}
+
+func newEnrichTestMgr(t *testing.T) (*fakeMgr, *countingCache) {
+	t.Helper()
+
+	conf := cache.NewConfig()
+	conf.Memory.InitValues = map[string]string{
+		"1": `{"name":"foo"}`,
+		"2": `{"name":"bar"}`,
+		"3": `not json`,
+	}
+	memCache, err := cache.NewMemory(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	c := &countingCache{Cache: memCache}
+	return &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": c,
+		},
+	}, c
+}
+
+func TestEnrichCache(t *testing.T) {
+	mgr, c := newEnrichTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeEnrich
+	conf.Enrich.Key = `${! json("id") }`
+	conf.Enrich.Cache = "foocache"
+	conf.Enrich.ResultMap = `root.user = this.name | content().string()`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"id":"1"}`),
+		[]byte(`{"id":"2"}`),
+		[]byte(`{"id":"1"}`),
+		[]byte(`{"id":"3"}`),
+		[]byte(`{"id":"4"}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`{"id":"1","user":"foo"}`),
+		[]byte(`{"id":"2","user":"bar"}`),
+		[]byte(`{"id":"1","user":"foo"}`),
+		[]byte(`{"id":"3","user":"not json"}`),
+		[]byte(`{"id":"4"}`),
+	}, message.GetAllBytes(msgs[0]))
+	assert.Equal(t, "bar", msgs[0].Get(0).Metadata().Get("foo"))
+	assert.False(t, HasFailed(msgs[0].Get(4)))
+
+	// Keys are deduplicated and obtained with a single request.
+	assert.Equal(t, [][]string{{"1", "2", "3", "4"}}, c.multiGets)
+
+	// The original batch is unchanged.
+	assert.Equal(t, `{"id":"1"}`, string(input.Get(0).Get()))
+}
+
+func TestEnrichCacheOnMissingError(t *testing.T) {
+	mgr, _ := newEnrichTestMgr(t)
+
+	conf := NewConfig()
+	conf.Type = TypeEnrich
+	conf.Enrich.Key = `${! json("id") }`
+	conf.Enrich.Cache = "foocache"
+	conf.Enrich.ResultMap = `root.user = this.name`
+	conf.Enrich.OnMissing = "error"
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"1"}`),
+		[]byte(`{"id":"4"}`),
+		[]byte(`{"id":"3"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, `{"id":"1","user":"foo"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "key not found: 4", GetFail(msgs[0].Get(1)))
+	assert.Contains(t, GetFail(msgs[0].Get(2)), "result map")
+}
+
+type errCache struct {
+	types.Cache
+}
+
+func (e errCache) Get(key string) ([]byte, error) {
+	return nil, errors.New("nope")
+}
+
+func TestEnrichCacheLookupError(t *testing.T) {
+	mgr := &fakeMgr{
+		caches: map[string]types.Cache{
+			"foocache": errCache{},
+		},
+	}
+
+	conf := NewConfig()
+	conf.Type = TypeEnrich
+	conf.Enrich.Key = `${! json("id") }`
+	conf.Enrich.Cache = "foocache"
+	conf.Enrich.ResultMap = `root.user = this.name`
+
+	proc, err := New(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"id":"1"}`),
+		[]byte(`{"id":"2"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, "lookup failed: nope", GetFail(msgs[0].Get(0)))
+	assert.Equal(t, "lookup failed: nope", GetFail(msgs[0].Get(1)))
+}
+
+func TestEnrichSQLQuery(t *testing.T) {
+	conf := NewEnrichSQLConfig()
+	conf.Table = "customers"
+	conf.KeyColumn = "id"
+
+	assert.Equal(t, "SELECT * FROM customers WHERE id IN (?, ?)", enrichSQLQuery(conf, 2))
+
+	conf.Driver = "postgres"
+	conf.Columns = []string{"name", "tier"}
+	assert.Equal(t, "SELECT id, name, tier FROM customers WHERE id IN ($1, $2, $3)", enrichSQLQuery(conf, 3))
+}
+
+func TestEnrichBadConfig(t *testing.T) {
+	mgr, _ := newEnrichTestMgr(t)
+
+	tests := map[string]func(c *EnrichConfig){
+		"no key": func(c *EnrichConfig) {
+			c.Key = ""
+		},
+		"no result map": func(c *EnrichConfig) {
+			c.ResultMap = ""
+		},
+		"bad on missing": func(c *EnrichConfig) {
+			c.OnMissing = "nope"
+		},
+		"no source": func(c *EnrichConfig) {
+			c.Cache = ""
+		},
+		"both sources": func(c *EnrichConfig) {
+			c.SQL.DataSourceName = "foo"
+		},
+		"missing cache": func(c *EnrichConfig) {
+			c.Cache = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeEnrich
+		conf.Enrich.Key = `${! json("id") }`
+		conf.Enrich.Cache = "foocache"
+		conf.Enrich.ResultMap = `root.user = this.name`
+		fn(&conf.Enrich)
+
+		_, err := New(conf, mgr, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,801 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/opentracing/opentracing-go"
+	"golang.org/x/oauth2/google"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeEnvelopeEncryption] = TypeSpec{
+		constructor: NewEnvelopeEncryption,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Encrypts or decrypts messages, or fields of messages, with envelope encryption
+using data keys obtained from a key management service.`,
+		Description: `
+When encrypting, a data key is obtained from the key management service and
+used to encrypt each value with AES-256-GCM. The result is an envelope
+containing the encrypted form of the data key along with the ciphertext, and
+therefore only the key management service is able to unlock it. When
+decrypting, the data key of each envelope is decrypted by the key management
+service and used to obtain the original value.
+
+When ` + "`fields`" + ` is empty the entire contents of each message are
+replaced with a binary envelope. Otherwise each message must be a JSON document,
+and the value of each listed field is replaced with a base64 encoded envelope of
+its JSON serialised form. Fields that do not exist within a message are
+skipped.
+
+Messages that fail to be encrypted or decrypted are left unchanged and flagged
+as having failed, which allows you to handle them with
+[error handling patterns](/docs/configuration/error_handling).
+
+### Data Keys
+
+In order to avoid a request to the key management service for each message,
+data keys are cached for the period of ` + "`data_key_ttl`" + `. When
+encrypting, the same data key is used for all messages until it expires, after
+which a new data key is obtained. When decrypting, the decrypted form of each
+distinct data key is cached until it expires.
+
+### Providers
+
+The ` + "`aws_kms`" + ` provider generates data keys using the
+[AWS KMS](https://aws.amazon.com/kms/) key identified by ` + "`key_id`" + `,
+which can be a key ID, key ARN or alias.
+
+The ` + "`gcp_kms`" + ` provider generates data keys locally and encrypts them
+with the [GCP Cloud KMS](https://cloud.google.com/kms) crypto key identified by
+` + "`key_id`" + `, which is the resource name of the key in the form
+` + "`projects/<project>/locations/<location>/keyRings/<key ring>/cryptoKeys/<key>`" + `.
+Requests are authenticated with
+[application default credentials](https://cloud.google.com/docs/authentication/production).
+
+The ` + "`vault_transit`" + ` provider generates data keys using the
+[Vault transit secrets engine](https://www.vaultproject.io/docs/secrets/transit)
+key named by ` + "`key_id`" + `.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Protecting Sensitive Fields",
+				Summary: `
+Here we encrypt the fields ` + "`user.email`" + ` and ` + "`user.address`" + `
+of documents before they leave our network, and a separate pipeline with access
+to the KMS key is able to decrypt them again:`,
+				Config: `
+pipeline:
+  processors:
+    - envelope_encryption:
+        operator: encrypt
+        fields: [ user.email, user.address ]
+        provider: aws_kms
+        key_id: alias/customer-data
+        aws_kms:
+          region: eu-west-1
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("operator", "Whether to encrypt or decrypt messages.").HasOptions("encrypt", "decrypt"),
+			docs.FieldCommon("fields", "An optional list of [dot paths](/docs/configuration/field_paths) of JSON fields to encrypt or decrypt. When empty the entire contents of messages are encrypted or decrypted.", []string{"user.email", "user.address"}),
+			docs.FieldCommon("provider", "The key management service to obtain data keys from.").HasOptions("aws_kms", "gcp_kms", "vault_transit"),
+			docs.FieldCommon("key_id", "The identifier of the key used to encrypt data keys. For `aws_kms` this is only required when encrypting."),
+			docs.FieldAdvanced("data_key_ttl", "The period of time for which data keys are cached. When empty data keys are never cached."),
+			docs.FieldAdvanced("aws_kms", "Configuration for the `aws_kms` provider.").WithChildren(
+				session.FieldSpecs().Add(
+					docs.FieldAdvanced("encryption_context", "An optional map of key/value pairs bound to data keys, which must match when decrypting.", map[string]string{"tenant": "foo"}),
+				)...,
+			),
+			docs.FieldAdvanced("gcp_kms", "Configuration for the `gcp_kms` provider.").WithChildren(
+				docs.FieldAdvanced("timeout", "The maximum period of time to wait for requests to Cloud KMS."),
+			),
+			docs.FieldAdvanced("vault_transit", "Configuration for the `vault_transit` provider.").WithChildren(
+				docs.FieldCommon("address", "The address of the Vault server.", "https://vault.example.com:8200"),
+				docs.FieldCommon("token", "A token used to authenticate with Vault."),
+				docs.FieldAdvanced("mount", "The path at which the transit secrets engine is mounted."),
+				docs.FieldAdvanced("timeout", "The maximum period of time to wait for requests to Vault."),
+			),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// EnvelopeAWSKMSConfig contains configuration fields for the aws_kms provider
+// of the EnvelopeEncryption processor.
+type EnvelopeAWSKMSConfig struct {
+	session.Config    `json:",inline" yaml:",inline"`
+	EncryptionContext map[string]string `json:"encryption_context" yaml:"encryption_context"`
+}
+
+// EnvelopeGCPKMSConfig contains configuration fields for the gcp_kms provider
+// of the EnvelopeEncryption processor.
+type EnvelopeGCPKMSConfig struct {
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// EnvelopeVaultTransitConfig contains configuration fields for the
+// vault_transit provider of the EnvelopeEncryption processor.
+type EnvelopeVaultTransitConfig struct {
+	Address string `json:"address" yaml:"address"`
+	Token   string `json:"token" yaml:"token"`
+	Mount   string `json:"mount" yaml:"mount"`
+	Timeout string `json:"timeout" yaml:"timeout"`
+}
+
+// EnvelopeEncryptionConfig contains configuration fields for the
+// EnvelopeEncryption processor.
+type EnvelopeEncryptionConfig struct {
+	Operator     string                     `json:"operator" yaml:"operator"`
+	Fields       []string                   `json:"fields" yaml:"fields"`
+	Provider     string                     `json:"provider" yaml:"provider"`
+	KeyID        string                     `json:"key_id" yaml:"key_id"`
+	DataKeyTTL   string                     `json:"data_key_ttl" yaml:"data_key_ttl"`
+	AWSKMS       EnvelopeAWSKMSConfig       `json:"aws_kms" yaml:"aws_kms"`
+	GCPKMS       EnvelopeGCPKMSConfig       `json:"gcp_kms" yaml:"gcp_kms"`
+	VaultTransit EnvelopeVaultTransitConfig `json:"vault_transit" yaml:"vault_transit"`
+}
+
+// NewEnvelopeEncryptionConfig returns a EnvelopeEncryptionConfig with default
+// values.
+func NewEnvelopeEncryptionConfig() EnvelopeEncryptionConfig {
+	return EnvelopeEncryptionConfig{
+		Operator:   "encrypt",
+		Fields:     []string{},
+		Provider:   "aws_kms",
+		KeyID:      "",
+		DataKeyTTL: "5m",
+		AWSKMS: EnvelopeAWSKMSConfig{
+			Config:            session.NewConfig(),
+			EncryptionContext: map[string]string{},
+		},
+		GCPKMS: EnvelopeGCPKMSConfig{
+			Timeout: "5s",
+		},
+		VaultTransit: EnvelopeVaultTransitConfig{
+			Address: "",
+			Token:   "",
+			Mount:   "transit",
+			Timeout: "5s",
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// envelopeKeyProvider generates 256 bit data keys and decrypts the encrypted
+// form of data keys that it generated.
+type envelopeKeyProvider interface {
+	GenerateDataKey() (plaintext, ciphertext []byte, err error)
+	DecryptDataKey(ciphertext []byte) ([]byte, error)
+}
+
+type awsKMSKeyProvider struct {
+	client  kmsiface.KMSAPI
+	keyID   string
+	context map[string]*string
+}
+
+func newAWSKMSKeyProvider(conf EnvelopeEncryptionConfig) (*awsKMSKeyProvider, error) {
+	sess, err := conf.AWSKMS.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	var encContext map[string]*string
+	if len(conf.AWSKMS.EncryptionContext) > 0 {
+		encContext = aws.StringMap(conf.AWSKMS.EncryptionContext)
+	}
+	return &awsKMSKeyProvider{
+		client:  kms.New(sess),
+		keyID:   conf.KeyID,
+		context: encContext,
+	}, nil
+}
+
+func (a *awsKMSKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	if a.keyID == "" {
+		return nil, nil, errors.New("a key_id must be specified in order to generate data keys")
+	}
+	out, err := a.client.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:             aws.String(a.keyID),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: a.context,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out.Plaintext, out.CiphertextBlob, nil
+}
+
+func (a *awsKMSKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	input := &kms.DecryptInput{
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: a.context,
+	}
+	if a.keyID != "" {
+		input.KeyId = aws.String(a.keyID)
+	}
+	out, err := a.client.Decrypt(input)
+	if err != nil {
+		return nil, err
+	}
+	return out.Plaintext, nil
+}
+
+// gcpKMSBaseURL is the base URL of the Cloud KMS REST API.
+const gcpKMSBaseURL = "https://cloudkms.googleapis.com/v1/"
+
+// gcpKMSKeyProvider generates data keys locally, as Cloud KMS has no API for
+// generating them, and encrypts them with a Cloud KMS crypto key.
+type gcpKMSKeyProvider struct {
+	client  *http.Client
+	baseURL string
+	keyName string
+}
+
+func newGCPKMSKeyProvider(conf EnvelopeEncryptionConfig) (*gcpKMSKeyProvider, error) {
+	if conf.KeyID == "" {
+		return nil, errors.New("a key_id must be specified")
+	}
+	client, err := google.DefaultClient(context.Background(), "https://www.googleapis.com/auth/cloudkms")
+	if err != nil {
+		return nil, err
+	}
+	if conf.GCPKMS.Timeout != "" {
+		if client.Timeout, err = time.ParseDuration(conf.GCPKMS.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse gcp_kms timeout: %v", err)
+		}
+	}
+	return &gcpKMSKeyProvider{
+		client:  client,
+		baseURL: gcpKMSBaseURL,
+		keyName: strings.Trim(conf.KeyID, "/"),
+	}, nil
+}
+
+func (g *gcpKMSKeyProvider) do(method string, reqBody, resBody interface{}) error {
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", g.baseURL+g.keyName+":"+method, bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var errBody struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(resBytes, &errBody) == nil && errBody.Error.Message != "" {
+			return fmt.Errorf("cloud kms returned status %v: %v", res.StatusCode, errBody.Error.Message)
+		}
+		return fmt.Errorf("cloud kms returned status %v", res.StatusCode)
+	}
+	return json.Unmarshal(resBytes, resBody)
+}
+
+func (g *gcpKMSKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	plaintext := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, nil, err
+	}
+	var res struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := g.do("encrypt", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	}, &res); err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(res.Ciphertext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode data key: %v", err)
+	}
+	return plaintext, ciphertext, nil
+}
+
+func (g *gcpKMSKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	var res struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := g.do("decrypt", map[string]interface{}{
+		"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+	}, &res); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(res.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data key: %v", err)
+	}
+	return plaintext, nil
+}
+
+type vaultTransitKeyProvider struct {
+	client  *http.Client
+	baseURL string
+	keyName string
+	token   string
+}
+
+func newVaultTransitKeyProvider(conf EnvelopeEncryptionConfig) (*vaultTransitKeyProvider, error) {
+	if conf.VaultTransit.Address == "" {
+		return nil, errors.New("a vault_transit address must be specified")
+	}
+	if conf.KeyID == "" {
+		return nil, errors.New("a key_id must be specified")
+	}
+	var timeout time.Duration
+	if conf.VaultTransit.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(conf.VaultTransit.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse vault_transit timeout: %v", err)
+		}
+	}
+	return &vaultTransitKeyProvider{
+		client: &http.Client{Timeout: timeout},
+		baseURL: fmt.Sprintf(
+			"%v/v1/%v", strings.TrimSuffix(conf.VaultTransit.Address, "/"),
+			strings.Trim(conf.VaultTransit.Mount, "/"),
+		),
+		keyName: url.PathEscape(conf.KeyID),
+		token:   conf.VaultTransit.Token,
+	}, nil
+}
+
+func (v *vaultTransitKeyProvider) do(path string, reqBody, resBody interface{}) error {
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", v.baseURL+path, bytes.NewReader(reqBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var errBody struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(resBytes, &errBody) == nil && len(errBody.Errors) > 0 {
+			return fmt.Errorf("vault returned status %v: %v", res.StatusCode, strings.Join(errBody.Errors, ", "))
+		}
+		return fmt.Errorf("vault returned status %v", res.StatusCode)
+	}
+	return json.Unmarshal(resBytes, resBody)
+}
+
+func (v *vaultTransitKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	var res struct {
+		Data struct {
+			Plaintext  string `json:"plaintext"`
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := v.do("/datakey/plaintext/"+v.keyName, map[string]interface{}{"bits": 256}, &res); err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(res.Data.Plaintext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode data key: %v", err)
+	}
+	return plaintext, []byte(res.Data.Ciphertext), nil
+}
+
+func (v *vaultTransitKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	var res struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.do("/decrypt/"+v.keyName, map[string]interface{}{"ciphertext": string(ciphertext)}, &res); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(res.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data key: %v", err)
+	}
+	return plaintext, nil
+}
+
+//------------------------------------------------------------------------------
+
+type envelopeDataKey struct {
+	ciphertext []byte
+	aead       cipher.AEAD
+	expires    time.Time
+}
+
+func newEnvelopeDataKey(plaintext, ciphertext []byte, expires time.Time) (*envelopeDataKey, error) {
+	if len(plaintext) != 32 {
+		return nil, fmt.Errorf("expected a 256 bit data key, received %v bits", len(plaintext)*8)
+	}
+	if len(ciphertext) > 0xFFFF {
+		return nil, errors.New("encrypted data key exceeds the maximum length")
+	}
+	block, err := aes.NewCipher(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeDataKey{
+		ciphertext: ciphertext,
+		aead:       aead,
+		expires:    expires,
+	}, nil
+}
+
+// envelopeKeyCache obtains data keys from a provider and caches them for a TTL.
+type envelopeKeyCache struct {
+	provider envelopeKeyProvider
+	ttl      time.Duration
+	nowFn    func() time.Time
+
+	mut       sync.Mutex
+	current   *envelopeDataKey
+	decrypted map[string]*envelopeDataKey
+
+	mGenerated metrics.StatCounter
+	mDecrypted metrics.StatCounter
+}
+
+func (c *envelopeKeyCache) encryptionKey() (*envelopeDataKey, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.nowFn()
+	if c.current != nil && now.Before(c.current.expires) {
+		return c.current, nil
+	}
+
+	plaintext, ciphertext, err := c.provider.GenerateDataKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	c.mGenerated.Incr(1)
+
+	key, err := newEnvelopeDataKey(plaintext, ciphertext, now.Add(c.ttl))
+	if err != nil {
+		return nil, err
+	}
+	c.current = key
+	return key, nil
+}
+
+func (c *envelopeKeyCache) decryptionKey(ciphertext []byte) (*envelopeDataKey, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := c.nowFn()
+	if key, exists := c.decrypted[string(ciphertext)]; exists && now.Before(key.expires) {
+		return key, nil
+	}
+
+	plaintext, err := c.provider.DecryptDataKey(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %v", err)
+	}
+	c.mDecrypted.Incr(1)
+
+	key, err := newEnvelopeDataKey(plaintext, ciphertext, now.Add(c.ttl))
+	if err != nil {
+		return nil, err
+	}
+	if c.ttl > 0 {
+		for k, v := range c.decrypted {
+			if !now.Before(v.expires) {
+				delete(c.decrypted, k)
+			}
+		}
+		c.decrypted[string(ciphertext)] = key
+	}
+	return key, nil
+}
+
+//------------------------------------------------------------------------------
+
+// envelopeVersion is the first byte of each envelope, which allows the format
+// to change in future.
+const envelopeVersion byte = 1
+
+// The format of an envelope is the version byte, the length of the encrypted
+// data key as a big endian uint16, the encrypted data key, the nonce and then
+// the ciphertext.
+func envelopeSeal(key *envelopeDataKey, plaintext []byte) ([]byte, error) {
+	nonceSize := key.aead.NonceSize()
+	header := 3 + len(key.ciphertext)
+
+	envelope := make([]byte, header+nonceSize, header+nonceSize+len(plaintext)+key.aead.Overhead())
+	envelope[0] = envelopeVersion
+	binary.BigEndian.PutUint16(envelope[1:3], uint16(len(key.ciphertext)))
+	copy(envelope[3:], key.ciphertext)
+
+	nonce := envelope[header:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return key.aead.Seal(envelope, nonce, plaintext, nil), nil
+}
+
+func envelopeOpen(keys *envelopeKeyCache, envelope []byte) ([]byte, error) {
+	if len(envelope) < 3 {
+		return nil, errors.New("envelope is too short")
+	}
+	if envelope[0] != envelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version: %v", envelope[0])
+	}
+	keyLen := int(binary.BigEndian.Uint16(envelope[1:3]))
+	if len(envelope) < 3+keyLen {
+		return nil, errors.New("envelope is too short")
+	}
+
+	key, err := keys.decryptionKey(envelope[3 : 3+keyLen])
+	if err != nil {
+		return nil, err
+	}
+
+	rest := envelope[3+keyLen:]
+	nonceSize := key.aead.NonceSize()
+	if len(rest) < nonceSize {
+		return nil, errors.New("envelope is too short")
+	}
+	return key.aead.Open(nil, rest[:nonceSize], rest[nonceSize:], nil)
+}
+
+//------------------------------------------------------------------------------
+
+// EnvelopeEncryption is a processor that encrypts or decrypts messages using
+// data keys from a key management service.
+type EnvelopeEncryption struct {
+	log   log.Modular
+	stats metrics.Type
+
+	operator string
+	encrypt  bool
+	fields   [][]string
+	keys     *envelopeKeyCache
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewEnvelopeEncryption returns a EnvelopeEncryption processor.
+func NewEnvelopeEncryption(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	var provider envelopeKeyProvider
+	var err error
+	switch conf.EnvelopeEncryption.Provider {
+	case "aws_kms":
+		provider, err = newAWSKMSKeyProvider(conf.EnvelopeEncryption)
+	case "gcp_kms":
+		provider, err = newGCPKMSKeyProvider(conf.EnvelopeEncryption)
+	case "vault_transit":
+		provider, err = newVaultTransitKeyProvider(conf.EnvelopeEncryption)
+	default:
+		return nil, fmt.Errorf("provider not recognised: %v", conf.EnvelopeEncryption.Provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v provider: %v", conf.EnvelopeEncryption.Provider, err)
+	}
+	return newEnvelopeEncryption(conf.EnvelopeEncryption, provider, log, stats)
+}
+
+func newEnvelopeEncryption(
+	conf EnvelopeEncryptionConfig, provider envelopeKeyProvider, log log.Modular, stats metrics.Type,
+) (*EnvelopeEncryption, error) {
+	e := &EnvelopeEncryption{
+		log:        log,
+		stats:      stats,
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	e.operator = conf.Operator
+	switch conf.Operator {
+	case "encrypt":
+		e.encrypt = true
+	case "decrypt":
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", conf.Operator)
+	}
+
+	for _, f := range conf.Fields {
+		if f == "" {
+			return nil, errors.New("fields must not be empty")
+		}
+		e.fields = append(e.fields, gabs.DotPathToSlice(f))
+	}
+
+	var ttl time.Duration
+	if conf.DataKeyTTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(conf.DataKeyTTL); err != nil {
+			return nil, fmt.Errorf("failed to parse data_key_ttl: %v", err)
+		}
+	}
+
+	e.keys = &envelopeKeyCache{
+		provider:   provider,
+		ttl:        ttl,
+		nowFn:      time.Now,
+		decrypted:  map[string]*envelopeDataKey{},
+		mGenerated: stats.GetCounter("data_key.generated"),
+		mDecrypted: stats.GetCounter("data_key.decrypted"),
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (e *EnvelopeEncryption) encryptBytes(b []byte) ([]byte, error) {
+	key, err := e.keys.encryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	return envelopeSeal(key, b)
+}
+
+func (e *EnvelopeEncryption) processFields(part types.Part) error {
+	jObj, err := part.JSON()
+	if err == nil {
+		jObj, err = message.CopyJSON(jObj)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse message as JSON: %v", err)
+	}
+
+	gObj := gabs.Wrap(jObj)
+	for _, path := range e.fields {
+		if !gObj.Exists(path...) {
+			continue
+		}
+		value := gObj.S(path...).Data()
+
+		var result interface{}
+		if e.encrypt {
+			valueBytes, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to serialise field '%v': %v", strings.Join(path, "."), err)
+			}
+			envelope, err := e.encryptBytes(valueBytes)
+			if err != nil {
+				return err
+			}
+			result = base64.StdEncoding.EncodeToString(envelope)
+		} else {
+			str, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("expected field '%v' to be a string, found %T", strings.Join(path, "."), value)
+			}
+			envelope, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return fmt.Errorf("failed to decode field '%v': %v", strings.Join(path, "."), err)
+			}
+			valueBytes, err := envelopeOpen(e.keys, envelope)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt field '%v': %v", strings.Join(path, "."), err)
+			}
+			if err = json.Unmarshal(valueBytes, &result); err != nil {
+				return fmt.Errorf("failed to parse field '%v': %v", strings.Join(path, "."), err)
+			}
+		}
+		if _, err = gObj.Set(result, path...); err != nil {
+			return err
+		}
+	}
+	return part.SetJSON(gObj.Data())
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (e *EnvelopeEncryption) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	e.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		var err error
+		if len(e.fields) > 0 {
+			err = e.processFields(part)
+		} else {
+			var result []byte
+			if e.encrypt {
+				result, err = e.encryptBytes(part.Get())
+			} else {
+				result, err = envelopeOpen(e.keys, part.Get())
+			}
+			if err == nil {
+				part.Set(result)
+			}
+		}
+		if err != nil {
+			e.mErr.Incr(1)
+			e.log.Debugf("Failed to %v message: %v\n", e.operator, err)
+		}
+		return err
+	}
+
+	IteratePartsWithSpan(TypeEnvelopeEncryption, nil, newMsg, proc)
+
+	e.mBatchSent.Incr(1)
+	e.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (e *EnvelopeEncryption) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (e *EnvelopeEncryption) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,322 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKeyProvider "encrypts" data keys by prefixing them with "fake:".
+type fakeKeyProvider struct {
+	mut       sync.Mutex
+	generated int
+	decrypted int
+	n         byte
+}
+
+func (f *fakeKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.generated++
+	f.n++
+	plaintext := bytes.Repeat([]byte{f.n}, 32)
+	return plaintext, append([]byte("fake:"), plaintext...), nil
+}
+
+func (f *fakeKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.decrypted++
+	if !bytes.HasPrefix(ciphertext, []byte("fake:")) {
+		return nil, errors.New("unknown data key")
+	}
+	return ciphertext[5:], nil
+}
+
+func newTestEnvelopeEncryption(t *testing.T, provider envelopeKeyProvider, operator string, fields ...string) *EnvelopeEncryption {
+	t.Helper()
+
+	conf := NewEnvelopeEncryptionConfig()
+	conf.Operator = operator
+	conf.Fields = fields
+
+	e, err := newEnvelopeEncryption(conf, provider, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	return e
+}
+
+func TestEnvelopeEncryptionWholeMessage(t *testing.T) {
+	provider := &fakeKeyProvider{}
+	enc := newTestEnvelopeEncryption(t, provider, "encrypt")
+	dec := newTestEnvelopeEncryption(t, provider, "decrypt")
+
+	input := [][]byte{
+		[]byte(`hello world`),
+		[]byte(`{"foo":"bar"}`),
+		[]byte(``),
+	}
+
+	msgs, res := enc.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i, b := range message.GetAllBytes(msgs[0]) {
+		assert.False(t, HasFailed(msgs[0].Get(i)))
+		assert.NotEqual(t, input[i], b)
+		if len(input[i]) > 0 {
+			assert.NotContains(t, string(b), string(input[i]))
+		}
+	}
+	assert.Equal(t, 1, provider.generated)
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, input, message.GetAllBytes(msgs[0]))
+	assert.Equal(t, 1, provider.decrypted)
+
+	msgs, res = dec.ProcessMessage(message.New([][]byte{[]byte(`not an envelope`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `not an envelope`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "unsupported envelope version: 110", GetFail(msgs[0].Get(0)))
+}
+
+func TestEnvelopeEncryptionFields(t *testing.T) {
+	provider := &fakeKeyProvider{}
+	enc := newTestEnvelopeEncryption(t, provider, "encrypt", "user.email", "user.address", "nope")
+	dec := newTestEnvelopeEncryption(t, provider, "decrypt", "user.email", "user.address", "nope")
+
+	input := `{"id":"1","user":{"address":{"city":"London","street":"Foo Lane"},"email":"foo@example.com"}}`
+
+	msgs, res := enc.ProcessMessage(message.New([][]byte{[]byte(input)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.False(t, HasFailed(msgs[0].Get(0)))
+
+	var encrypted struct {
+		ID   string `json:"id"`
+		User struct {
+			Address string `json:"address"`
+			Email   string `json:"email"`
+		} `json:"user"`
+	}
+	require.NoError(t, json.Unmarshal(msgs[0].Get(0).Get(), &encrypted))
+	assert.Equal(t, "1", encrypted.ID)
+	for _, v := range []string{encrypted.User.Address, encrypted.User.Email} {
+		_, err := base64.StdEncoding.DecodeString(v)
+		assert.NoError(t, err)
+	}
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.False(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, input, string(msgs[0].Get(0).Get()))
+
+	msgs, res = dec.ProcessMessage(message.New([][]byte{[]byte(`{"user":{"email":10}}`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"user":{"email":10}}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "expected field 'user.email' to be a string, found float64", GetFail(msgs[0].Get(0)))
+}
+
+func TestEnvelopeEncryptionDataKeyTTL(t *testing.T) {
+	provider := &fakeKeyProvider{}
+	enc := newTestEnvelopeEncryption(t, provider, "encrypt")
+	dec := newTestEnvelopeEncryption(t, provider, "decrypt")
+
+	now := time.Now()
+	enc.keys.nowFn = func() time.Time { return now }
+	dec.keys.nowFn = func() time.Time { return now }
+
+	encrypt := func() []byte {
+		msgs, res := enc.ProcessMessage(message.New([][]byte{[]byte(`foo`)}))
+		require.Nil(t, res)
+		require.False(t, HasFailed(msgs[0].Get(0)))
+		return msgs[0].Get(0).Get()
+	}
+	decrypt := func(b []byte) {
+		msgs, res := dec.ProcessMessage(message.New([][]byte{b}))
+		require.Nil(t, res)
+		require.False(t, HasFailed(msgs[0].Get(0)))
+		assert.Equal(t, `foo`, string(msgs[0].Get(0).Get()))
+	}
+
+	first, second := encrypt(), encrypt()
+	assert.Equal(t, 1, provider.generated)
+
+	now = now.Add(time.Minute * 6)
+	third := encrypt()
+	assert.Equal(t, 2, provider.generated)
+
+	decrypt(first)
+	decrypt(second)
+	decrypt(third)
+	assert.Equal(t, 2, provider.decrypted)
+
+	now = now.Add(time.Minute * 6)
+	decrypt(first)
+	assert.Equal(t, 3, provider.decrypted)
+	assert.Len(t, dec.keys.decrypted, 1)
+}
+
+func TestEnvelopeEncryptionVaultTransit(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "foo" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/v1/transit/datakey/plaintext/bar":
+			assert.Equal(t, float64(256), body["bits"])
+			fmt.Fprintf(w, `{"data":{"plaintext":%q,"ciphertext":"vault:v1:abc"}}`, base64.StdEncoding.EncodeToString(key))
+		case "/v1/transit/decrypt/bar":
+			assert.Equal(t, "vault:v1:abc", body["ciphertext"])
+			fmt.Fprintf(w, `{"data":{"plaintext":%q}}`, base64.StdEncoding.EncodeToString(key))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeEnvelopeEncryption
+	conf.EnvelopeEncryption.Provider = "vault_transit"
+	conf.EnvelopeEncryption.KeyID = "bar"
+	conf.EnvelopeEncryption.VaultTransit.Address = ts.URL
+	conf.EnvelopeEncryption.VaultTransit.Token = "foo"
+
+	enc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	conf.EnvelopeEncryption.Operator = "decrypt"
+	dec, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := enc.ProcessMessage(message.New([][]byte{[]byte(`hello world`)}))
+	require.Nil(t, res)
+	require.False(t, HasFailed(msgs[0].Get(0)))
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.False(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, `hello world`, string(msgs[0].Get(0).Get()))
+
+	conf.EnvelopeEncryption.Operator = "encrypt"
+	conf.EnvelopeEncryption.VaultTransit.Token = "nope"
+	enc, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res = enc.ProcessMessage(message.New([][]byte{[]byte(`hello world`)}))
+	require.Nil(t, res)
+	assert.Equal(t, `hello world`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "failed to generate data key: vault returned status 403: permission denied", GetFail(msgs[0].Get(0)))
+}
+
+func TestEnvelopeEncryptionGCPKMS(t *testing.T) {
+	keyName := "projects/foo/locations/global/keyRings/bar/cryptoKeys/baz"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		switch r.URL.Path {
+		case "/" + keyName + ":encrypt":
+			plaintext, err := base64.StdEncoding.DecodeString(body["plaintext"])
+			assert.NoError(t, err)
+			assert.Len(t, plaintext, 32)
+			fmt.Fprintf(w, `{"name":%q,"ciphertext":%q}`, keyName, base64.StdEncoding.EncodeToString(append([]byte("gcp:"), plaintext...)))
+		case "/" + keyName + ":decrypt":
+			ciphertext, err := base64.StdEncoding.DecodeString(body["ciphertext"])
+			assert.NoError(t, err)
+			if !bytes.HasPrefix(ciphertext, []byte("gcp:")) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"code":400,"message":"Decryption failed"}}`))
+				return
+			}
+			fmt.Fprintf(w, `{"plaintext":%q}`, base64.StdEncoding.EncodeToString(ciphertext[4:]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	provider := &gcpKMSKeyProvider{
+		client:  ts.Client(),
+		baseURL: ts.URL + "/",
+		keyName: keyName,
+	}
+	enc := newTestEnvelopeEncryption(t, provider, "encrypt")
+	dec := newTestEnvelopeEncryption(t, provider, "decrypt")
+
+	msgs, res := enc.ProcessMessage(message.New([][]byte{[]byte(`hello world`)}))
+	require.Nil(t, res)
+	require.False(t, HasFailed(msgs[0].Get(0)))
+	assert.NotContains(t, string(msgs[0].Get(0).Get()), "hello world")
+
+	msgs, res = dec.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.False(t, HasFailed(msgs[0].Get(0)))
+	assert.Equal(t, `hello world`, string(msgs[0].Get(0).Get()))
+
+	_, err := provider.DecryptDataKey([]byte("nope"))
+	assert.EqualError(t, err, "cloud kms returned status 400: Decryption failed")
+}
+
+func TestEnvelopeEncryptionBadConfig(t *testing.T) {
+	tests := map[string]func(c *EnvelopeEncryptionConfig){
+		"bad operator": func(c *EnvelopeEncryptionConfig) {
+			c.Operator = "nope"
+		},
+		"bad provider": func(c *EnvelopeEncryptionConfig) {
+			c.Provider = "nope"
+		},
+		"empty field": func(c *EnvelopeEncryptionConfig) {
+			c.Fields = []string{""}
+		},
+		"bad data key ttl": func(c *EnvelopeEncryptionConfig) {
+			c.DataKeyTTL = "nope"
+		},
+		"vault without address": func(c *EnvelopeEncryptionConfig) {
+			c.Provider = "vault_transit"
+			c.VaultTransit.Address = ""
+		},
+		"vault without key": func(c *EnvelopeEncryptionConfig) {
+			c.Provider = "vault_transit"
+			c.KeyID = ""
+		},
+		"gcp without key": func(c *EnvelopeEncryptionConfig) {
+			c.Provider = "gcp_kms"
+			c.KeyID = ""
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeEnvelopeEncryption
+		conf.EnvelopeEncryption.Provider = "vault_transit"
+		conf.EnvelopeEncryption.KeyID = "foo"
+		conf.EnvelopeEncryption.VaultTransit.Address = "http://localhost:8200"
+		fn(&conf.EnvelopeEncryption)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
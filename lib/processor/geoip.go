@@ -0,0 +1,495 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/opentracing/opentracing-go"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeGeoIP] = TypeSpec{
+		constructor: NewGeoIP,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryIntegration,
+		},
+		Summary: `
+Looks up the geographical location and autonomous system of an IP address from
+[MaxMind](https://www.maxmind.com) GeoIP2 or GeoLite2 databases, and writes the
+results into the message at a target path.`,
+		Description: `
+Databases are read from ` + "`.mmdb`" + ` files, where any combination of the
+City, Country and ASN databases can be listed in order to obtain the fields
+that they provide. When an IP address is found in multiple databases the results
+are merged, with databases listed later taking precedence.
+
+Messages must be JSON objects, and the results of a lookup are written as an
+object at the ` + "`target_path`" + ` of the following form, where fields are
+omitted when they are not provided by the databases:
+
+` + "```json" + `
+{
+  "city": "London",
+  "postal_code": "SW1A",
+  "subdivision": { "iso_code": "ENG", "name": "England" },
+  "country": { "iso_code": "GB", "name": "United Kingdom" },
+  "continent": { "code": "EU", "name": "Europe" },
+  "location": { "latitude": 51.5, "longitude": -0.12, "time_zone": "Europe/London", "accuracy_radius": 10 },
+  "asn": { "number": 2856, "organization": "British Telecommunications PLC" }
+}
+` + "```" + `
+
+Messages with an IP address that isn't found within any database are left
+unchanged, and messages with an invalid IP address are flagged as having failed,
+which allows you to handle them with
+[error handling patterns](/docs/configuration/error_handling).
+
+### Reloading
+
+The database files are checked for changes at the ` + "`reload_interval`" + `,
+and when the modification time or size of a file changes it is loaded again
+without interrupting the pipeline. Database files should be replaced
+atomically, for example by writing to a temporary file and renaming it, in order
+to avoid reading partially written files.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Client Locations",
+				Summary: `
+Here we add the city, country and network provider of the client IP of HTTP
+access logs to the field ` + "`client.geo`" + `:`,
+				Config: `
+pipeline:
+  processors:
+    - geoip:
+        ip: ${! json("client.ip") }
+        target_path: client.geo
+        databases:
+          - /var/lib/GeoIP/GeoLite2-City.mmdb
+          - /var/lib/GeoIP/GeoLite2-ASN.mmdb
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("ip", "The IP address to look up for each message.", `${! json("client.ip") }`, `${! meta("remote_addr") }`).SupportsInterpolation(false),
+			docs.FieldCommon("target_path", "A [dot path](/docs/configuration/field_paths) indicating where to write the results of a lookup."),
+			docs.FieldCommon("databases", "A list of paths to MaxMind `.mmdb` database files to look up IP addresses from.", []string{"/var/lib/GeoIP/GeoLite2-City.mmdb"}),
+			docs.FieldAdvanced("language", "The language of names to obtain, such as the names of cities and countries."),
+			docs.FieldAdvanced("reload_interval", "The period of time between checks for changes to the database files. When empty the database files are never reloaded."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIPConfig contains configuration fields for the GeoIP processor.
+type GeoIPConfig struct {
+	IP             string   `json:"ip" yaml:"ip"`
+	TargetPath     string   `json:"target_path" yaml:"target_path"`
+	Databases      []string `json:"databases" yaml:"databases"`
+	Language       string   `json:"language" yaml:"language"`
+	ReloadInterval string   `json:"reload_interval" yaml:"reload_interval"`
+}
+
+// NewGeoIPConfig returns a GeoIPConfig with default values.
+func NewGeoIPConfig() GeoIPConfig {
+	return GeoIPConfig{
+		IP:             "",
+		TargetPath:     "geoip",
+		Databases:      []string{},
+		Language:       "en",
+		ReloadInterval: "1m",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// geoIPRecord contains the fields of all supported database types, where
+// fields that are not provided by a database remain empty.
+type geoIPRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Continent struct {
+		Code  string            `maxminddb:"code"`
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"continent"`
+	Country struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+		Latitude       float64 `maxminddb:"latitude"`
+		Longitude      float64 `maxminddb:"longitude"`
+		TimeZone       string  `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+func geoIPSetNonEmpty(obj map[string]interface{}, key string, value string) {
+	if value != "" {
+		obj[key] = value
+	}
+}
+
+// mergeInto adds the non-empty fields of a record to a result object.
+func (r *geoIPRecord) mergeInto(obj map[string]interface{}, lang string) {
+	geoIPSetNonEmpty(obj, "city", r.City.Names[lang])
+	geoIPSetNonEmpty(obj, "postal_code", r.Postal.Code)
+
+	objField := func(key string) map[string]interface{} {
+		if o, ok := obj[key].(map[string]interface{}); ok {
+			return o
+		}
+		o := map[string]interface{}{}
+		obj[key] = o
+		return o
+	}
+	dropEmpty := func(key string) {
+		if o, ok := obj[key].(map[string]interface{}); ok && len(o) == 0 {
+			delete(obj, key)
+		}
+	}
+
+	if len(r.Subdivisions) > 0 {
+		sub := objField("subdivision")
+		geoIPSetNonEmpty(sub, "iso_code", r.Subdivisions[0].ISOCode)
+		geoIPSetNonEmpty(sub, "name", r.Subdivisions[0].Names[lang])
+		dropEmpty("subdivision")
+	}
+
+	country := objField("country")
+	geoIPSetNonEmpty(country, "iso_code", r.Country.ISOCode)
+	geoIPSetNonEmpty(country, "name", r.Country.Names[lang])
+	dropEmpty("country")
+
+	continent := objField("continent")
+	geoIPSetNonEmpty(continent, "code", r.Continent.Code)
+	geoIPSetNonEmpty(continent, "name", r.Continent.Names[lang])
+	dropEmpty("continent")
+
+	if r.Location.Latitude != 0 || r.Location.Longitude != 0 || r.Location.TimeZone != "" {
+		location := objField("location")
+		location["latitude"] = r.Location.Latitude
+		location["longitude"] = r.Location.Longitude
+		geoIPSetNonEmpty(location, "time_zone", r.Location.TimeZone)
+		if r.Location.AccuracyRadius > 0 {
+			location["accuracy_radius"] = int64(r.Location.AccuracyRadius)
+		}
+	}
+
+	if r.AutonomousSystemNumber > 0 {
+		asn := objField("asn")
+		asn["number"] = int64(r.AutonomousSystemNumber)
+		geoIPSetNonEmpty(asn, "organization", r.AutonomousSystemOrganization)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type geoIPDatabase struct {
+	path    string
+	modTime time.Time
+	size    int64
+	reader  *maxminddb.Reader
+}
+
+func openGeoIPDatabase(path string) (*geoIPDatabase, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &geoIPDatabase{
+		path:    path,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		reader:  reader,
+	}, nil
+}
+
+func (d *geoIPDatabase) changed() bool {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return false
+	}
+	return !info.ModTime().Equal(d.modTime) || info.Size() != d.size
+}
+
+// lookup decodes the record of an IP address into a result, returning false if
+// the IP address was not found.
+func (d *geoIPDatabase) lookup(ip net.IP, result *geoIPRecord) (bool, error) {
+	if ip.To4() == nil && d.reader.Metadata.IPVersion == 4 {
+		return false, nil
+	}
+	offset, err := d.reader.LookupOffset(ip)
+	if err != nil {
+		return false, err
+	}
+	if offset == maxminddb.NotFound {
+		return false, nil
+	}
+	return true, d.reader.Decode(offset, result)
+}
+
+//------------------------------------------------------------------------------
+
+// GeoIP is a processor that looks up the location of IP addresses from MaxMind
+// databases.
+type GeoIP struct {
+	log   log.Modular
+	stats metrics.Type
+
+	ip         field.Expression
+	targetPath []string
+	lang       string
+
+	dbMut sync.RWMutex
+	dbs   []*geoIPDatabase
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mNotFound  metrics.StatCounter
+	mReloaded  metrics.StatCounter
+	mErrReload metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewGeoIP returns a GeoIP processor.
+func NewGeoIP(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.GeoIP.IP == "" {
+		return nil, errors.New("an ip must be specified")
+	}
+	if len(conf.GeoIP.Databases) == 0 {
+		return nil, errors.New("at least one database must be specified")
+	}
+	if conf.GeoIP.TargetPath == "" {
+		return nil, errors.New("a target_path must be specified")
+	}
+
+	ip, err := bloblang.NewField(conf.GeoIP.IP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ip expression: %v", err)
+	}
+
+	var reloadInterval time.Duration
+	if conf.GeoIP.ReloadInterval != "" {
+		if reloadInterval, err = time.ParseDuration(conf.GeoIP.ReloadInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse reload_interval: %v", err)
+		}
+	}
+
+	g := &GeoIP{
+		log:        log,
+		stats:      stats,
+		ip:         ip,
+		targetPath: gabs.DotPathToSlice(conf.GeoIP.TargetPath),
+		lang:       conf.GeoIP.Language,
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mNotFound:  stats.GetCounter("not_found"),
+		mReloaded:  stats.GetCounter("reload.success"),
+		mErrReload: stats.GetCounter("reload.error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+
+	for _, path := range conf.GeoIP.Databases {
+		db, err := openGeoIPDatabase(path)
+		if err != nil {
+			g.closeDBs()
+			return nil, fmt.Errorf("failed to open database '%v': %v", path, err)
+		}
+		g.dbs = append(g.dbs, db)
+	}
+
+	go g.loop(reloadInterval)
+	return g, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (g *GeoIP) closeDBs() {
+	for _, db := range g.dbs {
+		db.reader.Close()
+	}
+}
+
+func (g *GeoIP) reload() {
+	for i, db := range g.dbs {
+		if !db.changed() {
+			continue
+		}
+
+		newDB, err := openGeoIPDatabase(db.path)
+		if err != nil {
+			g.mErrReload.Incr(1)
+			g.log.Errorf("Failed to reload database '%v': %v\n", db.path, err)
+			continue
+		}
+
+		g.dbMut.Lock()
+		g.dbs[i] = newDB
+		g.dbMut.Unlock()
+
+		db.reader.Close()
+		g.mReloaded.Incr(1)
+		g.log.Infof("Reloaded database '%v'\n", db.path)
+	}
+}
+
+func (g *GeoIP) loop(reloadInterval time.Duration) {
+	defer func() {
+		g.dbMut.Lock()
+		g.closeDBs()
+		g.dbMut.Unlock()
+		close(g.closedChan)
+	}()
+
+	if reloadInterval <= 0 {
+		<-g.closeChan
+		return
+	}
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.reload()
+		case <-g.closeChan:
+			return
+		}
+	}
+}
+
+func (g *GeoIP) lookup(ip net.IP) (map[string]interface{}, error) {
+	g.dbMut.RLock()
+	defer g.dbMut.RUnlock()
+
+	var result map[string]interface{}
+	for _, db := range g.dbs {
+		var record geoIPRecord
+		found, err := db.lookup(ip, &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up '%v' in database '%v': %v", ip, db.path, err)
+		}
+		if !found {
+			continue
+		}
+		if result == nil {
+			result = map[string]interface{}{}
+		}
+		record.mergeInto(result, g.lang)
+	}
+	return result, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (g *GeoIP) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	g.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		ipStr := g.ip.String(index, msg)
+		ip := net.ParseIP(ipStr)
+		if ip == nil {
+			g.mErr.Incr(1)
+			return fmt.Errorf("invalid IP address: %q", ipStr)
+		}
+
+		result, err := g.lookup(ip)
+		if err != nil {
+			g.mErr.Incr(1)
+			g.log.Debugf("Lookup error: %v\n", err)
+			return err
+		}
+		if result == nil {
+			g.mNotFound.Incr(1)
+			return nil
+		}
+
+		jObj, err := part.JSON()
+		if err == nil {
+			jObj, err = message.CopyJSON(jObj)
+		}
+		if err != nil {
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to parse message as JSON: %v\n", err)
+			return err
+		}
+
+		gObj := gabs.Wrap(jObj)
+		if _, err = gObj.Set(result, g.targetPath...); err != nil {
+			g.mErr.Incr(1)
+			g.log.Debugf("Failed to set target path: %v\n", err)
+			return err
+		}
+		return part.SetJSON(gObj.Data())
+	}
+
+	IteratePartsWithSpan(TypeGeoIP, nil, newMsg, proc)
+
+	g.mBatchSent.Incr(1)
+	g.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (g *GeoIP) CloseAsync() {
+	g.closeOnce.Do(func() {
+		close(g.closeChan)
+	})
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (g *GeoIP) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	case <-g.closedChan:
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
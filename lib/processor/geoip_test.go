@@ -0,0 +1,315 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMMDBEncode encodes a value in the MaxMind DB data section format.
+func testMMDBEncode(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	ctrl := func(typ int, size int) []byte {
+		var b []byte
+		if typ > 7 {
+			b = []byte{0, byte(typ - 7)}
+		} else {
+			b = []byte{byte(typ << 5)}
+		}
+		require.Less(t, size, 29)
+		b[0] |= byte(size)
+		return b
+	}
+	uintBytes := func(u uint64, n int) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, u)
+		return b[8-n:]
+	}
+
+	var buf bytes.Buffer
+	switch t2 := v.(type) {
+	case string:
+		buf.Write(ctrl(2, len(t2)))
+		buf.WriteString(t2)
+	case float64:
+		buf.Write(ctrl(3, 8))
+		buf.Write(uintBytes(math.Float64bits(t2), 8))
+	case uint16:
+		buf.Write(ctrl(5, 2))
+		buf.Write(uintBytes(uint64(t2), 2))
+	case uint32:
+		buf.Write(ctrl(6, 4))
+		buf.Write(uintBytes(uint64(t2), 4))
+	case uint64:
+		buf.Write(ctrl(9, 8))
+		buf.Write(uintBytes(t2, 8))
+	case []interface{}:
+		buf.Write(ctrl(11, len(t2)))
+		for _, e := range t2 {
+			buf.Write(testMMDBEncode(t, e))
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t2))
+		for k := range t2 {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.Write(ctrl(7, len(keys)))
+		for _, k := range keys {
+			buf.Write(testMMDBEncode(t, k))
+			buf.Write(testMMDBEncode(t, t2[k]))
+		}
+	default:
+		t.Fatalf("unsupported type: %T", v)
+	}
+	return buf.Bytes()
+}
+
+// writeTestMMDB writes an IPv4 MaxMind DB file containing a record for each
+// /24 network.
+func writeTestMMDB(t *testing.T, path, dbType string, networks map[string]map[string]interface{}) {
+	t.Helper()
+
+	type node struct {
+		children [2]*node
+		data     int
+	}
+	root := &node{data: -1}
+
+	var data []byte
+	for cidr, record := range networks {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		require.NoError(t, err)
+		ones, _ := ipNet.Mask.Size()
+
+		offset := len(data)
+		data = append(data, testMMDBEncode(t, record)...)
+
+		n := root
+		ip := ipNet.IP.To4()
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> (7 - uint(i%8))) & 1
+			if n.children[bit] == nil {
+				n.children[bit] = &node{data: -1}
+			}
+			n = n.children[bit]
+		}
+		n.data = offset
+	}
+
+	// Number the nodes of the search tree in breadth first order.
+	var nodes []*node
+	ids := map[*node]int{}
+	for queue := []*node{root}; len(queue) > 0; queue = queue[1:] {
+		n := queue[0]
+		ids[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil && c.data < 0 {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		for _, c := range n.children {
+			record := len(nodes)
+			if c != nil {
+				if c.data >= 0 {
+					record = len(nodes) + 16 + c.data
+				} else {
+					record = ids[c]
+				}
+			}
+			b := make([]byte, 4)
+			binary.BigEndian.PutUint32(b, uint32(record))
+			buf.Write(b[1:])
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	buf.Write(testMMDBEncode(t, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               dbType,
+		"description":                 map[string]interface{}{"en": "test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(len(nodes)),
+		"record_size":                 uint16(24),
+	}))
+
+	// Written and renamed so that reloads never observe a partial file.
+	tmpPath := path + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmpPath, buf.Bytes(), 0644))
+	require.NoError(t, os.Rename(tmpPath, path))
+}
+
+func testGeoIPCityRecord(city string) map[string]interface{} {
+	return map[string]interface{}{
+		"city": map[string]interface{}{
+			"names": map[string]interface{}{"en": city},
+		},
+		"country": map[string]interface{}{
+			"iso_code": "GB",
+			"names":    map[string]interface{}{"en": "United Kingdom"},
+		},
+		"location": map[string]interface{}{
+			"latitude":  51.5,
+			"longitude": -0.5,
+			"time_zone": "Europe/London",
+		},
+	}
+}
+
+func TestGeoIP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_geoip_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cityPath := filepath.Join(dir, "city.mmdb")
+	writeTestMMDB(t, cityPath, "GeoLite2-City", map[string]map[string]interface{}{
+		"1.2.3.0/24": testGeoIPCityRecord("London"),
+	})
+
+	asnPath := filepath.Join(dir, "asn.mmdb")
+	writeTestMMDB(t, asnPath, "GeoLite2-ASN", map[string]map[string]interface{}{
+		"1.2.3.0/24": {
+			"autonomous_system_number":       uint32(2856),
+			"autonomous_system_organization": "Foo Networks",
+		},
+		"5.6.7.0/24": {
+			"autonomous_system_number": uint32(10),
+		},
+	})
+
+	conf := NewConfig()
+	conf.Type = TypeGeoIP
+	conf.GeoIP.IP = `${! json("ip") }`
+	conf.GeoIP.TargetPath = "client.geo"
+	conf.GeoIP.Databases = []string{cityPath, asnPath}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		require.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"ip":"1.2.3.4"}`),
+		[]byte(`{"ip":"5.6.7.8"}`),
+		[]byte(`{"ip":"9.9.9.9"}`),
+		[]byte(`{"ip":"::1"}`),
+		[]byte(`{"ip":"nope"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`{"client":{"geo":{"asn":{"number":2856,"organization":"Foo Networks"},"city":"London","country":{"iso_code":"GB","name":"United Kingdom"},"location":{"latitude":51.5,"longitude":-0.5,"time_zone":"Europe/London"}}},"ip":"1.2.3.4"}`),
+		[]byte(`{"client":{"geo":{"asn":{"number":10}}},"ip":"5.6.7.8"}`),
+		[]byte(`{"ip":"9.9.9.9"}`),
+		[]byte(`{"ip":"::1"}`),
+		[]byte(`{"ip":"nope"}`),
+	}, message.GetAllBytes(msgs[0]))
+	assert.False(t, HasFailed(msgs[0].Get(2)))
+	assert.False(t, HasFailed(msgs[0].Get(3)))
+	assert.Equal(t, `invalid IP address: "nope"`, GetFail(msgs[0].Get(4)))
+}
+
+func TestGeoIPReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_geoip_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	cityPath := filepath.Join(dir, "city.mmdb")
+	writeTestMMDB(t, cityPath, "GeoLite2-City", map[string]map[string]interface{}{
+		"1.2.3.0/24": testGeoIPCityRecord("London"),
+	})
+
+	conf := NewConfig()
+	conf.Type = TypeGeoIP
+	conf.GeoIP.IP = `${! json("ip") }`
+	conf.GeoIP.TargetPath = "geo"
+	conf.GeoIP.Databases = []string{cityPath}
+	conf.GeoIP.ReloadInterval = "10ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		require.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	city := func(ip string) string {
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`{"ip":"` + ip + `"}`)}))
+		require.Nil(t, res)
+		require.Len(t, msgs, 1)
+		jObj, err := msgs[0].Get(0).JSON()
+		require.NoError(t, err)
+		geo, _ := jObj.(map[string]interface{})["geo"].(map[string]interface{})
+		c, _ := geo["city"].(string)
+		return c
+	}
+	assert.Equal(t, "London", city("1.2.3.4"))
+	assert.Equal(t, "", city("5.6.7.8"))
+
+	writeTestMMDB(t, cityPath, "GeoLite2-City", map[string]map[string]interface{}{
+		"1.2.3.0/24": testGeoIPCityRecord("Reading"),
+		"5.6.7.0/24": testGeoIPCityRecord("Oxford"),
+	})
+
+	assert.Eventually(t, func() bool {
+		return city("1.2.3.4") == "Reading" && city("5.6.7.8") == "Oxford"
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestGeoIPBadConfig(t *testing.T) {
+	tests := map[string]func(c *GeoIPConfig){
+		"no ip": func(c *GeoIPConfig) {
+			c.IP = ""
+		},
+		"no databases": func(c *GeoIPConfig) {
+			c.Databases = nil
+		},
+		"missing database": func(c *GeoIPConfig) {
+			c.Databases = []string{"/does/not/exist.mmdb"}
+		},
+		"bad reload interval": func(c *GeoIPConfig) {
+			c.ReloadInterval = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeGeoIP
+		conf.GeoIP.IP = `${! json("ip") }`
+		conf.GeoIP.Databases = []string{"/does/not/exist.mmdb"}
+		fn(&conf.GeoIP)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
@@ -1,15 +1,12 @@
 package processor
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
-	"github.com/Jeffail/benthos/v3/internal/filepath"
+	igrok "github.com/Jeffail/benthos/v3/internal/grok"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -149,7 +146,7 @@ func NewGrok(
 	}
 
 	for _, path := range conf.Grok.PatternPaths {
-		if err := addGrokPatternsFromPath(path, grokConf.Patterns); err != nil {
+		if err := igrok.AddPatternsFromPath(path, grokConf.Patterns); err != nil {
 			return nil, fmt.Errorf("failed to parse patterns from path '%v': %v", path, err)
 		}
 	}
@@ -194,40 +191,6 @@ func NewGrok(
 
 //------------------------------------------------------------------------------
 
-func addGrokPatternsFromPath(path string, patterns map[string]string) error {
-	if s, err := os.Stat(path); err != nil {
-		return err
-	} else if s.IsDir() {
-		path = path + "/*"
-	}
-
-	files, err := filepath.Globs([]string{path})
-	if err != nil {
-		return err
-	}
-
-	for _, f := range files {
-		file, err := os.Open(f)
-		if err != nil {
-			return err
-		}
-
-		scanner := bufio.NewScanner(file)
-
-		for scanner.Scan() {
-			l := scanner.Text()
-			if len(l) > 0 && l[0] != '#' {
-				names := strings.SplitN(l, " ", 2)
-				patterns[names[0]] = names[1]
-			}
-		}
-
-		file.Close()
-	}
-
-	return nil
-}
-
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (g *Grok) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
@@ -260,7 +260,7 @@ func (g *GroupBy) ProcessMessage(msg types.Message) ([]types.Message, types.Resp
 				res, err := group.Check.QueryPart(i, msg)
 				if err != nil {
 					res = false
-					g.log.Errorf("Failed to test group %v: %v\n", j, err)
+					log.WithMessageFields(g.log, i, msg).Errorf("Failed to test group %v: %v\n", j, err)
 				}
 				if res {
 					groupStr := strconv.Itoa(j)
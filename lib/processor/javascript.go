@@ -0,0 +1,324 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/dop251/goja"
+	"github.com/dop251/goja_nodejs/require"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeJavaScript] = TypeSpec{
+		constructor: NewJavaScript,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryMapping,
+		},
+		Summary: `
+Executes a JavaScript program for each message, allowing you to modify the
+contents and metadata of messages with imperative logic.`,
+		Description: `
+Programs are executed with [goja](https://github.com/dop251/goja), an ECMAScript
+5.1 engine written in pure Go, and therefore run within the Benthos process
+rather than as a subprocess. Runtimes are reused between messages, and so global
+variables persist across executions, but multiple runtimes exist in order to
+process messages in parallel and so the state of one is not shared with others.
+
+Other JavaScript files can be loaded as CommonJS modules with
+` + "`require`" + `, where relative paths are resolved from the directory of
+the ` + "`file`" + ` being executed (or the current working directory when
+` + "`code`" + ` is used) followed by each of the ` + "`global_folders`" + `.
+
+### Functions
+
+The message being processed is accessed and modified with the following
+functions of the global object ` + "`benthos`" + `:
+
+- ` + "`benthos.v0_msg_as_string()`" + ` returns the contents of the message as
+  a string.
+- ` + "`benthos.v0_msg_set_string(value)`" + ` replaces the contents of the
+  message with a string.
+- ` + "`benthos.v0_msg_as_structured()`" + ` parses the message as JSON and
+  returns the result.
+- ` + "`benthos.v0_msg_set_structured(value)`" + ` replaces the contents of the
+  message with a value serialised as JSON.
+- ` + "`benthos.v0_msg_get_meta(key)`" + ` returns the value of a metadata key,
+  or an empty string when the key does not exist.
+- ` + "`benthos.v0_msg_set_meta(key, value)`" + ` sets a metadata key.
+
+The functions ` + "`console.log`" + `, ` + "`console.debug`" + `,
+` + "`console.warn`" + ` and ` + "`console.error`" + ` write to the Benthos
+logger at the equivalent levels.
+
+When a program throws an exception the message is left unchanged and flagged as
+having failed, which allows you to handle it with
+[error handling patterns](/docs/configuration/error_handling).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Field Manipulation",
+				Summary: `
+Here we lowercase the field ` + "`name`" + ` and add a metadata key based on
+the number of items of an order:`,
+				Config: `
+pipeline:
+  processors:
+    - javascript:
+        code: |
+          (function() {
+            var doc = benthos.v0_msg_as_structured();
+            doc.name = doc.name.toLowerCase();
+            benthos.v0_msg_set_meta("order_size", doc.items.length > 10 ? "large" : "small");
+            benthos.v0_msg_set_structured(doc);
+          })();
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("code", "An inline JavaScript program to execute. Either `code` or `file` must be specified."),
+			docs.FieldCommon("file", "A path to a file containing a JavaScript program to execute. Either `code` or `file` must be specified."),
+			docs.FieldAdvanced("global_folders", "A list of directories to search for modules loaded with `require` when they aren't found relative to the program."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// JavaScriptConfig contains configuration fields for the JavaScript processor.
+type JavaScriptConfig struct {
+	Code          string   `json:"code" yaml:"code"`
+	File          string   `json:"file" yaml:"file"`
+	GlobalFolders []string `json:"global_folders" yaml:"global_folders"`
+}
+
+// NewJavaScriptConfig returns a JavaScriptConfig with default values.
+func NewJavaScriptConfig() JavaScriptConfig {
+	return JavaScriptConfig{
+		Code:          "",
+		File:          "",
+		GlobalFolders: []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// javaScriptVM is a runtime along with the message it is currently processing,
+// which the functions of the benthos object refer to.
+type javaScriptVM struct {
+	runtime *goja.Runtime
+	part    types.Part
+}
+
+func (j *JavaScript) newVM() *javaScriptVM {
+	vm := &javaScriptVM{
+		runtime: goja.New(),
+	}
+	j.registry.Enable(vm.runtime)
+
+	throw := func(err error) {
+		panic(vm.runtime.NewGoError(err))
+	}
+
+	benthos := vm.runtime.NewObject()
+	benthos.Set("v0_msg_as_string", func(call goja.FunctionCall) goja.Value {
+		return vm.runtime.ToValue(string(vm.part.Get()))
+	})
+	benthos.Set("v0_msg_set_string", func(call goja.FunctionCall) goja.Value {
+		vm.part.Set([]byte(call.Argument(0).String()))
+		return goja.Undefined()
+	})
+	benthos.Set("v0_msg_as_structured", func(call goja.FunctionCall) goja.Value {
+		jObj, err := vm.part.JSON()
+		if err == nil {
+			// The script may modify the result, which mustn't affect the
+			// cached structure of the message.
+			jObj, err = message.CopyJSON(jObj)
+		}
+		if err != nil {
+			throw(fmt.Errorf("failed to parse message as JSON: %v", err))
+		}
+		return vm.runtime.ToValue(jObj)
+	})
+	benthos.Set("v0_msg_set_structured", func(call goja.FunctionCall) goja.Value {
+		if err := vm.part.SetJSON(call.Argument(0).Export()); err != nil {
+			throw(fmt.Errorf("failed to set message as JSON: %v", err))
+		}
+		return goja.Undefined()
+	})
+	benthos.Set("v0_msg_get_meta", func(call goja.FunctionCall) goja.Value {
+		return vm.runtime.ToValue(vm.part.Metadata().Get(call.Argument(0).String()))
+	})
+	benthos.Set("v0_msg_set_meta", func(call goja.FunctionCall) goja.Value {
+		vm.part.Metadata().Set(call.Argument(0).String(), call.Argument(1).String())
+		return goja.Undefined()
+	})
+	vm.runtime.Set("benthos", benthos)
+
+	logFn := func(fn func(format string, v ...interface{})) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			args := make([]string, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				args[i] = arg.String()
+			}
+			fn("%v\n", strings.Join(args, " "))
+			return goja.Undefined()
+		}
+	}
+	console := vm.runtime.NewObject()
+	console.Set("log", logFn(j.log.Infof))
+	console.Set("debug", logFn(j.log.Debugf))
+	console.Set("warn", logFn(j.log.Warnf))
+	console.Set("error", logFn(j.log.Errorf))
+	vm.runtime.Set("console", console)
+
+	return vm
+}
+
+//------------------------------------------------------------------------------
+
+// JavaScript is a processor that executes a JavaScript program for each
+// message.
+type JavaScript struct {
+	log   log.Modular
+	stats metrics.Type
+
+	program  *goja.Program
+	registry *require.Registry
+	vms      sync.Pool
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewJavaScript returns a JavaScript processor.
+func NewJavaScript(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	code, name := conf.JavaScript.Code, "main.js"
+	if conf.JavaScript.File != "" {
+		if code != "" {
+			return nil, errors.New("only one of code or file can be specified")
+		}
+		codeBytes, err := ioutil.ReadFile(conf.JavaScript.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+		code, name = string(codeBytes), conf.JavaScript.File
+	}
+	if code == "" {
+		return nil, errors.New("either code or file must be specified")
+	}
+
+	program, err := goja.Compile(name, code, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile program: %v", err)
+	}
+
+	baseDir := "."
+	if conf.JavaScript.File != "" {
+		baseDir = filepath.Dir(conf.JavaScript.File)
+	}
+	folders := append([]string{baseDir}, conf.JavaScript.GlobalFolders...)
+
+	j := &JavaScript{
+		log:        log,
+		stats:      stats,
+		program:    program,
+		registry:   require.NewRegistryWithLoader(javaScriptModuleLoader(folders)),
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	j.vms.New = func() interface{} {
+		return j.newVM()
+	}
+	return j, nil
+}
+
+// javaScriptModuleLoader returns a loader of module sources that resolves
+// relative paths from a list of folders.
+func javaScriptModuleLoader(folders []string) require.SourceLoader {
+	return func(path string) ([]byte, error) {
+		if filepath.IsAbs(path) {
+			return ioutil.ReadFile(path)
+		}
+		for _, folder := range folders {
+			for _, p := range []string{path, path + ".js"} {
+				b, err := ioutil.ReadFile(filepath.Join(folder, p))
+				if err == nil {
+					return b, nil
+				}
+				if !os.IsNotExist(err) {
+					return nil, err
+				}
+			}
+		}
+		return nil, require.ModuleFileDoesNotExistError
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (j *JavaScript) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	j.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	vm := j.vms.Get().(*javaScriptVM)
+	defer j.vms.Put(vm)
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		// Changes are made to a copy so that a failed execution leaves the
+		// message unchanged.
+		vm.part = part.Copy()
+		defer func() {
+			vm.part = nil
+		}()
+
+		if _, err := vm.runtime.RunProgram(j.program); err != nil {
+			j.mErr.Incr(1)
+			j.log.Debugf("Failed to execute program: %v\n", err)
+			return err
+		}
+
+		part.Set(vm.part.Get())
+		part.SetMetadata(vm.part.Metadata())
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeJavaScript, nil, newMsg, proc)
+
+	j.mBatchSent.Incr(1)
+	j.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (j *JavaScript) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (j *JavaScript) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,141 @@
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJavaScript(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeJavaScript
+	conf.JavaScript.Code = `
+var doc = benthos.v0_msg_as_structured();
+doc.name = doc.name.toUpperCase();
+benthos.v0_msg_set_meta("original", benthos.v0_msg_as_string());
+benthos.v0_msg_set_meta("copied", benthos.v0_msg_get_meta("foo"));
+benthos.v0_msg_set_structured(doc);
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"name":"foo"}`),
+		[]byte(`{"name":"bar"}`),
+	})
+	input.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"name":"FOO"}`),
+		[]byte(`{"name":"BAR"}`),
+	}, message.GetAllBytes(msgs[0]))
+
+	assert.Equal(t, `{"name":"foo"}`, msgs[0].Get(0).Metadata().Get("original"))
+	assert.Equal(t, "bar", msgs[0].Get(0).Metadata().Get("copied"))
+	assert.Equal(t, `{"name":"bar"}`, msgs[0].Get(1).Metadata().Get("original"))
+	assert.Equal(t, "", msgs[0].Get(1).Metadata().Get("copied"))
+
+	assert.Equal(t, `{"name":"foo"}`, string(input.Get(0).Get()))
+}
+
+func TestJavaScriptError(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeJavaScript
+	conf.JavaScript.Code = `
+benthos.v0_msg_set_string("changed");
+if (benthos.v0_msg_as_string() !== "changed") {
+  throw new Error("unexpected content");
+}
+benthos.v0_msg_as_structured();
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"foo":"bar"}`, string(msgs[0].Get(0).Get()))
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+	assert.Contains(t, GetFail(msgs[0].Get(0)), "failed to parse message as JSON")
+}
+
+func TestJavaScriptRequire(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benthos_javascript_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	libDir := filepath.Join(dir, "lib")
+	require.NoError(t, os.Mkdir(libDir, 0755))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.js"), []byte(`
+var helpers = require("./helpers.js");
+var shout = require("shout.js");
+benthos.v0_msg_set_string(shout.shout(helpers.greet(benthos.v0_msg_as_string())));
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "helpers.js"), []byte(`
+exports.greet = function(name) { return "hello " + name; };
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(libDir, "shout.js"), []byte(`
+exports.shout = function(str) { return str.toUpperCase() + "!"; };
+`), 0644))
+
+	conf := NewConfig()
+	conf.Type = TypeJavaScript
+	conf.JavaScript.File = filepath.Join(dir, "main.js")
+	conf.JavaScript.GlobalFolders = []string{libDir}
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`world`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.False(t, HasFailed(msgs[0].Get(0)), GetFail(msgs[0].Get(0)))
+	assert.Equal(t, "HELLO WORLD!", string(msgs[0].Get(0).Get()))
+}
+
+func TestJavaScriptBadConfig(t *testing.T) {
+	tests := map[string]func(c *JavaScriptConfig){
+		"no code or file": func(c *JavaScriptConfig) {
+			c.Code = ""
+		},
+		"code and file": func(c *JavaScriptConfig) {
+			c.File = "main.js"
+		},
+		"missing file": func(c *JavaScriptConfig) {
+			c.Code = ""
+			c.File = "/does/not/exist.js"
+		},
+		"bad syntax": func(c *JavaScriptConfig) {
+			c.Code = "this is not javascript"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeJavaScript
+		conf.JavaScript.Code = `benthos.v0_msg_set_string("foo");`
+		fn(&conf.JavaScript)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
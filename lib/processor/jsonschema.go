@@ -1,9 +1,16 @@
 package processor
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -13,6 +20,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/opentracing/opentracing-go"
+	sjsonschema "github.com/santhosh-tekuri/jsonschema/v5"
 	jsonschema "github.com/xeipuuv/gojsonschema"
 )
 
@@ -30,7 +38,41 @@ payload under any circumstances. If a message does not match the schema it can
 be caught using error handling methods outlined [here](/docs/configuration/error_handling).`,
 		Description: `
 Please refer to the [JSON Schema website](https://json-schema.org/) for
-information and tutorials regarding the syntax of the schema.`,
+information and tutorials regarding the syntax of the schema.
+
+## Drafts
+
+Drafts 4, 6, 7, 2019-09 and 2020-12 are supported. By default the draft is
+detected from the ` + "`$schema`" + ` keyword of the schema, and can otherwise
+be set explicitly with the field ` + "`draft`" + `.
+
+## References
+
+The ` + "`$ref`" + ` keyword can target relative or absolute URIs with the
+schemes ` + "`file`" + `, ` + "`http`" + ` and ` + "`https`" + `, where relative
+URIs are resolved against the location of the referring schema. When validating
+schemas of draft 2019-09 or later remote documents are fetched once and cached
+for the lifetime of the process, and are shared by all processors that reference
+them.
+
+## Violations
+
+By default the error of a failing message lists each violation on its own line.
+When the field ` + "`violations_metadata_key`" + ` is set the violations are
+also added to the message as a metadata field containing a JSON array, where
+each violation has the following structure:
+
+` + "```json" + `
+{
+  "field": "addresses.0",
+  "instance_location": "/addresses/0",
+  "keyword_location": "/properties/addresses/items/required",
+  "message": "missing properties: 'cityName'"
+}
+` + "```" + `
+
+The field ` + "`keyword_location`" + ` is only present when validating schemas
+of draft 2019-09 or later.`,
 		Footnotes: `
 ## Examples
 
@@ -85,6 +127,10 @@ dropped.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("schema", "A schema to apply. Use either this or the `schema_path` field."),
 			docs.FieldCommon("schema_path", "The path of a schema document to apply. Use either this or the `schema` field."),
+			docs.FieldAdvanced("draft", "The draft of the schema. When empty the draft is detected from the `$schema` keyword of the schema.").HasOptions(
+				"", "4", "6", "7", "2019-09", "2020-12",
+			).AtVersion("3.39.0"),
+			docs.FieldAdvanced("violations_metadata_key", "An optional metadata key to store the violations of failing messages under as a JSON array.").AtVersion("3.39.0"),
 			partsFieldSpec,
 		},
 	}
@@ -95,28 +141,236 @@ dropped.`,
 // JSONSchemaConfig is a configuration struct containing fields for the
 // jsonschema processor.
 type JSONSchemaConfig struct {
-	Parts      []int  `json:"parts" yaml:"parts"`
-	SchemaPath string `json:"schema_path" yaml:"schema_path"`
-	Schema     string `json:"schema" yaml:"schema"`
+	Parts                 []int  `json:"parts" yaml:"parts"`
+	SchemaPath            string `json:"schema_path" yaml:"schema_path"`
+	Schema                string `json:"schema" yaml:"schema"`
+	Draft                 string `json:"draft" yaml:"draft"`
+	ViolationsMetadataKey string `json:"violations_metadata_key" yaml:"violations_metadata_key"`
 }
 
 // NewJSONSchemaConfig returns a JSONSchemaConfig with default values.
 func NewJSONSchemaConfig() JSONSchemaConfig {
 	return JSONSchemaConfig{
-		Parts:      []int{},
-		SchemaPath: "",
-		Schema:     "",
+		Parts:                 []int{},
+		SchemaPath:            "",
+		Schema:                "",
+		Draft:                 "",
+		ViolationsMetadataKey: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// jsonSchemaViolation describes a single reason for a document failing
+// validation.
+type jsonSchemaViolation struct {
+	Field            string `json:"field"`
+	InstanceLocation string `json:"instance_location"`
+	KeywordLocation  string `json:"keyword_location,omitempty"`
+	Message          string `json:"message"`
+}
+
+type jsonSchemaValidator func(doc interface{}) ([]jsonSchemaViolation, error)
+
+// jsonSchemaFieldFromPointer converts a JSON pointer to the dot separated
+// field path format used within validation errors.
+func jsonSchemaFieldFromPointer(ptr string) string {
+	if ptr == "" || ptr == "/" {
+		return "(root)"
+	}
+	return strings.Join(strings.Split(strings.TrimPrefix(ptr, "/"), "/"), ".")
+}
+
+func newLegacyJSONSchemaValidator(conf JSONSchemaConfig) (jsonSchemaValidator, error) {
+	var rootLoader jsonschema.JSONLoader
+	if conf.SchemaPath != "" {
+		rootLoader = jsonschema.NewReferenceLoader(conf.SchemaPath)
+	} else {
+		rootLoader = jsonschema.NewStringLoader(conf.Schema)
+	}
+
+	sl := jsonschema.NewSchemaLoader()
+	switch conf.Draft {
+	case "4":
+		sl.AutoDetect, sl.Draft = false, jsonschema.Draft4
+	case "6":
+		sl.AutoDetect, sl.Draft = false, jsonschema.Draft6
+	case "7":
+		sl.AutoDetect, sl.Draft = false, jsonschema.Draft7
+	}
+
+	schema, err := sl.Compile(rootLoader)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(doc interface{}) ([]jsonSchemaViolation, error) {
+		result, err := schema.Validate(jsonschema.NewGoLoader(doc))
+		if err != nil {
+			return nil, err
+		}
+		if result.Valid() {
+			return nil, nil
+		}
+		violations := make([]jsonSchemaViolation, 0, len(result.Errors()))
+		for _, desc := range result.Errors() {
+			description := strings.ToLower(desc.Description())
+			if property := desc.Details()["property"]; property != nil {
+				description = property.(string) + strings.TrimPrefix(description, strings.ToLower(property.(string)))
+			}
+			var ptr string
+			if ctx := desc.Context(); ctx != nil {
+				ptr = strings.TrimPrefix(ctx.String("/"), "(root)")
+			}
+			violations = append(violations, jsonSchemaViolation{
+				Field:            desc.Field(),
+				InstanceLocation: ptr,
+				Message:          description,
+			})
+		}
+		return violations, nil
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// jsonSchemaHTTPClient is used for fetching remote schema documents.
+var jsonSchemaHTTPClient = &http.Client{Timeout: time.Second * 30}
+
+// jsonSchemaRemoteDocs caches remote schema documents by their URL, allowing
+// processors (and their parallel instances) to share the results of fetching
+// them.
+var jsonSchemaRemoteDocs = struct {
+	sync.Mutex
+	docs map[string][]byte
+}{docs: map[string][]byte{}}
+
+func loadJSONSchemaURL(s string) (io.ReadCloser, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		return sjsonschema.LoadURL(s)
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported reference scheme: %v", u.Scheme)
+	}
+
+	jsonSchemaRemoteDocs.Lock()
+	defer jsonSchemaRemoteDocs.Unlock()
+
+	if doc, exists := jsonSchemaRemoteDocs.docs[s]; exists {
+		return ioutil.NopCloser(bytes.NewReader(doc)), nil
 	}
+
+	res, err := jsonSchemaHTTPClient.Get(s)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request to %v returned status code %v", s, res.StatusCode)
+	}
+	doc, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	jsonSchemaRemoteDocs.docs[s] = doc
+	return ioutil.NopCloser(bytes.NewReader(doc)), nil
+}
+
+func newJSONSchemaValidator(conf JSONSchemaConfig) (jsonSchemaValidator, error) {
+	compiler := sjsonschema.NewCompiler()
+	compiler.LoadURL = loadJSONSchemaURL
+	compiler.AssertFormat = true
+	if conf.Draft == "2019-09" {
+		compiler.Draft = sjsonschema.Draft2019
+	} else {
+		compiler.Draft = sjsonschema.Draft2020
+	}
+
+	schemaURL := conf.SchemaPath
+	if schemaURL == "" {
+		schemaURL = "schema.json"
+		if err := compiler.AddResource(schemaURL, strings.NewReader(conf.Schema)); err != nil {
+			return nil, err
+		}
+	}
+
+	schema, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(doc interface{}) ([]jsonSchemaViolation, error) {
+		err := schema.Validate(doc)
+		if err == nil {
+			return nil, nil
+		}
+		var vErr *sjsonschema.ValidationError
+		if !errors.As(err, &vErr) {
+			return nil, err
+		}
+		var violations []jsonSchemaViolation
+		var flatten func(e *sjsonschema.ValidationError)
+		flatten = func(e *sjsonschema.ValidationError) {
+			if len(e.Causes) == 0 {
+				violations = append(violations, jsonSchemaViolation{
+					Field:            jsonSchemaFieldFromPointer(e.InstanceLocation),
+					InstanceLocation: e.InstanceLocation,
+					KeywordLocation:  e.KeywordLocation,
+					Message:          e.Message,
+				})
+				return
+			}
+			for _, cause := range e.Causes {
+				flatten(cause)
+			}
+		}
+		flatten(vErr)
+		return violations, nil
+	}, nil
+}
+
+// jsonSchemaDetectDraft returns the draft declared by the $schema keyword of a
+// schema document, or an empty string if it cannot be determined.
+func jsonSchemaDetectDraft(conf JSONSchemaConfig) string {
+	doc := []byte(conf.Schema)
+	if conf.SchemaPath != "" {
+		r, err := loadJSONSchemaURL(conf.SchemaPath)
+		if err != nil {
+			return ""
+		}
+		defer r.Close()
+		if doc, err = ioutil.ReadAll(r); err != nil {
+			return ""
+		}
+	}
+	var root struct {
+		Schema string `json:"$schema"`
+	}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return ""
+	}
+	switch {
+	case strings.Contains(root.Schema, "2020-12"):
+		return "2020-12"
+	case strings.Contains(root.Schema, "2019-09"):
+		return "2019-09"
+	}
+	return ""
 }
 
 //------------------------------------------------------------------------------
 
 // JSONSchema is a processor that validates messages against a specified json schema.
 type JSONSchema struct {
-	conf   JSONSchemaConfig
-	stats  metrics.Type
-	log    log.Modular
-	schema *jsonschema.Schema
+	conf     JSONSchemaConfig
+	stats    metrics.Type
+	log      log.Modular
+	validate jsonSchemaValidator
 
 	mCount     metrics.StatCounter
 	mErrJSONP  metrics.StatCounter
@@ -129,32 +383,43 @@ type JSONSchema struct {
 func NewJSONSchema(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	var schema *jsonschema.Schema
-	var err error
-
-	// load JSONSchema definition
-	if schemaPath := conf.JSONSchema.SchemaPath; schemaPath != "" {
-		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://")) {
-			return nil, fmt.Errorf("invalid schema_path provided, must start with file:// or http://")
+	jConf := conf.JSONSchema
+	if jConf.SchemaPath != "" {
+		if !(strings.HasPrefix(jConf.SchemaPath, "file://") ||
+			strings.HasPrefix(jConf.SchemaPath, "http://") ||
+			strings.HasPrefix(jConf.SchemaPath, "https://")) {
+			return nil, fmt.Errorf("invalid schema_path provided, must start with file://, http:// or https://")
 		}
+	} else if jConf.Schema == "" {
+		return nil, fmt.Errorf("either schema or schema_path must be provided")
+	}
 
-		schema, err = jsonschema.NewSchema(jsonschema.NewReferenceLoader(conf.JSONSchema.SchemaPath))
-		if err != nil {
-			return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
-		}
-	} else if conf.JSONSchema.Schema != "" {
-		schema, err = jsonschema.NewSchema(jsonschema.NewStringLoader(conf.JSONSchema.Schema))
-		if err != nil {
-			return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
-		}
+	draft := jConf.Draft
+	switch draft {
+	case "":
+		draft = jsonSchemaDetectDraft(jConf)
+	case "4", "6", "7", "2019-09", "2020-12":
+	default:
+		return nil, fmt.Errorf("draft not recognised: %v", draft)
+	}
+
+	var validate jsonSchemaValidator
+	var err error
+	if draft == "2019-09" || draft == "2020-12" {
+		jConf.Draft = draft
+		validate, err = newJSONSchemaValidator(jConf)
 	} else {
-		return nil, fmt.Errorf("either schema or schema_path must be provided")
+		validate, err = newLegacyJSONSchemaValidator(jConf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JSON schema definition: %v", err)
 	}
 
 	return &JSONSchema{
-		stats:  stats,
-		log:    log,
-		schema: schema,
+		conf:     jConf,
+		stats:    stats,
+		log:      log,
+		validate: validate,
 
 		mCount:     stats.GetCounter("count"),
 		mErrJSONP:  stats.GetCounter("error_json_parse"),
@@ -180,27 +445,27 @@ func (s *JSONSchema) ProcessMessage(msg types.Message) ([]types.Message, types.R
 			return err
 		}
 
-		partLoader := jsonschema.NewGoLoader(jsonPart)
-		result, err := s.schema.Validate(partLoader)
+		violations, err := s.validate(jsonPart)
 		if err != nil {
 			s.log.Debugf("Failed to validate json: %v\n", err)
 			s.mErr.Incr(1)
 			return err
 		}
 
-		if !result.Valid() {
+		if len(violations) > 0 {
 			s.log.Debugf("The document is not valid\n")
 			s.mErr.Incr(1)
+			if s.conf.ViolationsMetadataKey != "" {
+				if vBytes, err := json.Marshal(violations); err == nil {
+					part.Metadata().Set(s.conf.ViolationsMetadataKey, string(vBytes))
+				}
+			}
 			var errStr string
-			for i, desc := range result.Errors() {
+			for i, v := range violations {
 				if i > 0 {
 					errStr = errStr + "\n"
 				}
-				description := strings.ToLower(desc.Description())
-				if property := desc.Details()["property"]; property != nil {
-					description = property.(string) + strings.TrimPrefix(description, strings.ToLower(property.(string)))
-				}
-				errStr = errStr + desc.Field() + " " + description
+				errStr = errStr + v.Field + " " + v.Message
 			}
 			return errors.New(errStr)
 		}
@@ -1,9 +1,14 @@
 package processor
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"sync/atomic"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -354,3 +359,145 @@ func TestJSONSchemaInvalidSchema(t *testing.T) {
 		t.Error("expected error from loading bad schema")
 	}
 }
+
+func TestJSONSchemaDraft2020RemoteRef(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/address.schema.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"properties": {
+				"cityName": { "type": "string" }
+			},
+			"required": [ "cityName" ]
+		}`))
+	}))
+	defer server.Close()
+
+	schema := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"name": { "$ref": "#/$defs/name" },
+			"location": {
+				"type": "array",
+				"prefixItems": [
+					{ "type": "number" },
+					{ "type": "number" }
+				],
+				"items": false
+			},
+			"addresses": {
+				"type": "array",
+				"items": { "$ref": "` + server.URL + `/address.schema.json" }
+			}
+		},
+		"$defs": {
+			"name": { "type": "string", "minLength": 1 }
+		}
+	}`
+
+	conf := NewConfig()
+	conf.Type = "jsonschema"
+	conf.JSONSchema.Schema = schema
+	conf.JSONSchema.ViolationsMetadataKey = "violations"
+
+	for i := 0; i < 2; i++ {
+		if _, err := NewJSONSchema(conf, nil, log.Noop(), metrics.Noop()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := int32(1), atomic.LoadInt32(&requests); exp != act {
+		t.Errorf("Wrong count of remote schema requests: %v != %v", act, exp)
+	}
+
+	c, err := NewJSONSchema(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"name":"foo","location":[1.5,2.5],"addresses":[{"cityName":"Reading"}]}`),
+		[]byte(`{"name":"","location":[1.5,2.5,3.5],"addresses":[{"postCode":"RG1"}]}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Expected one message batch")
+	}
+
+	if act := msgs[0].Get(0).Metadata().Get(FailFlagKey); act != "" {
+		t.Errorf("Unexpected error: %v", act)
+	}
+	if act := msgs[0].Get(0).Metadata().Get("violations"); act != "" {
+		t.Errorf("Unexpected violations: %v", act)
+	}
+
+	if act := msgs[0].Get(1).Metadata().Get(FailFlagKey); act == "" {
+		t.Error("Expected error")
+	}
+
+	var violations []map[string]interface{}
+	if err = json.Unmarshal([]byte(msgs[0].Get(1).Metadata().Get("violations")), &violations); err != nil {
+		t.Fatal(err)
+	}
+	locations := map[string]string{}
+	for _, v := range violations {
+		locations[v["instance_location"].(string)] = v["keyword_location"].(string)
+	}
+	exp := map[string]string{
+		"/name":        "/properties/name/$ref/minLength",
+		"/location/2":  "/properties/location/items",
+		"/addresses/0": "/properties/addresses/items/$ref/required",
+	}
+	if !reflect.DeepEqual(exp, locations) {
+		t.Errorf("Wrong violations: %v != %v", locations, exp)
+	}
+}
+
+func TestJSONSchemaLegacyViolations(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "jsonschema"
+	conf.JSONSchema.Schema = `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"properties": {
+			"age": { "type": "integer", "minimum": 0 }
+		}
+	}`
+	conf.JSONSchema.ViolationsMetadataKey = "violations"
+
+	c, err := NewJSONSchema(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, _ := c.ProcessMessage(message.New([][]byte{
+		[]byte(`{"age":-20}`),
+	}))
+	if len(msgs) != 1 {
+		t.Fatal("Expected one message batch")
+	}
+
+	if exp, act := "age must be greater than or equal to 0", msgs[0].Get(0).Metadata().Get(FailFlagKey); exp != act {
+		t.Errorf("Wrong error message: %v != %v", act, exp)
+	}
+	exp := `[{"field":"age","instance_location":"/age","message":"must be greater than or equal to 0"}]`
+	if act := msgs[0].Get(0).Metadata().Get("violations"); exp != act {
+		t.Errorf("Wrong violations: %v != %v", act, exp)
+	}
+}
+
+func TestJSONSchemaBadDraft(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "jsonschema"
+	conf.JSONSchema.Schema = `{"type":"object"}`
+	conf.JSONSchema.Draft = "3"
+
+	if _, err := NewJSONSchema(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("expected error from unrecognised draft")
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/internal/bloblang"
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -35,6 +36,8 @@ Custom metrics such as these are emitted along with Benthos internal metrics, wh
 				"counter_by",
 				"gauge",
 				"timing",
+				"histogram",
+				"summary",
 			),
 			docs.FieldDeprecated("path"),
 			docs.FieldCommon("name", "The name of the metric to create, this must be unique across all Benthos components otherwise it will overwrite those other metrics."),
@@ -45,7 +48,18 @@ Custom metrics such as these are emitted along with Benthos internal metrics, wh
 					"topic": "${! meta(\"kafka_topic\") }",
 				},
 			).SupportsInterpolation(true),
+			docs.FieldAdvanced(
+				"label_mappings", "A map of label names and [Bloblang queries](/docs/guides/bloblang/about) that derive label values from messages, which are combined with the `labels` field. Messages where a query fails are not counted.",
+				map[string]string{
+					"size": `if this.items.length() > 10 { "large" } else { "small" }`,
+				},
+			).AtVersion("3.39.0"),
 			docs.FieldCommon("value", "For some metric types specifies a value to set, increment.").SupportsInterpolation(true),
+			docs.FieldAdvanced("buckets", "The upper bounds of the buckets of a `histogram` metric, where an empty list results in a default set of buckets.", []float64{0.1, 0.5, 1, 5, 10}).AtVersion("3.39.0"),
+			docs.FieldAdvanced("objectives", "The quantiles to calculate for a `summary` metric and their absolute errors, where an empty list results in a default set of objectives.").HasType(docs.FieldArray).WithChildren(
+				docs.FieldCommon("quantile", "A quantile to calculate, between zero and one.").HasDefault(0),
+				docs.FieldCommon("error", "The absolute error of the quantile.").HasDefault(0),
+			).AtVersion("3.39.0"),
 			partsFieldSpec,
 		},
 		Examples: []docs.AnnotatedExample{
@@ -134,7 +148,34 @@ metric:
 
 ### ` + "`timing`" + `
 
-Equivalent to ` + "`gauge`" + ` where instead the metric is a timing.`,
+Equivalent to ` + "`gauge`" + ` where instead the metric is a timing.
+
+### ` + "`histogram`" + `
+
+If the contents of ` + "`value`" + ` can be parsed as a number then it is
+added as an observation to a histogram with the upper bounds configured with
+` + "`buckets`" + `.
+
+For example, the following configuration will observe the value of the field
+` + "`order.total`" + ` labelled by a category derived with Bloblang:
+
+` + "```yaml" + `
+metric:
+  type: histogram
+  name: OrderTotal
+  value: ${! json("order.total") }
+  buckets: [ 10, 50, 100, 500 ]
+  label_mappings:
+    category: this.order.category.lowercase().or("none")
+` + "```" + `
+
+Histograms are only supported by the ` + "`prometheus`" + ` metrics type, with
+other types the observations are instead recorded as timings.
+
+### ` + "`summary`" + `
+
+Equivalent to ` + "`histogram`" + ` where instead the metric is a summary of
+the quantiles configured with ` + "`objectives`" + `.`,
 	}
 }
 
@@ -142,26 +183,39 @@ Equivalent to ` + "`gauge`" + ` where instead the metric is a timing.`,
 
 // MetricConfig contains configuration fields for the Metric processor.
 type MetricConfig struct {
-	Parts  []int             `json:"parts" yaml:"parts"`
-	Type   string            `json:"type" yaml:"type"`
-	Path   string            `json:"path" yaml:"path"`
-	Name   string            `json:"name" yaml:"name"`
-	Labels map[string]string `json:"labels" yaml:"labels"`
-	Value  string            `json:"value" yaml:"value"`
+	Parts         []int                   `json:"parts" yaml:"parts"`
+	Type          string                  `json:"type" yaml:"type"`
+	Path          string                  `json:"path" yaml:"path"`
+	Name          string                  `json:"name" yaml:"name"`
+	Labels        map[string]string       `json:"labels" yaml:"labels"`
+	LabelMappings map[string]string       `json:"label_mappings" yaml:"label_mappings"`
+	Value         string                  `json:"value" yaml:"value"`
+	Buckets       []float64               `json:"buckets" yaml:"buckets"`
+	Objectives    []MetricObjectiveConfig `json:"objectives" yaml:"objectives"`
 }
 
 // NewMetricConfig returns a MetricConfig with default values.
 func NewMetricConfig() MetricConfig {
 	return MetricConfig{
-		Parts:  []int{},
-		Type:   "counter",
-		Path:   "",
-		Name:   "",
-		Labels: map[string]string{},
-		Value:  "",
+		Parts:         []int{},
+		Type:          "counter",
+		Path:          "",
+		Name:          "",
+		Labels:        map[string]string{},
+		LabelMappings: map[string]string{},
+		Value:         "",
+		Buckets:       []float64{},
+		Objectives:    []MetricObjectiveConfig{},
 	}
 }
 
+// MetricObjectiveConfig contains a quantile of a summary metric and its
+// absolute error.
+type MetricObjectiveConfig struct {
+	Quantile float64 `json:"quantile" yaml:"quantile"`
+	Error    float64 `json:"error" yaml:"error"`
+}
+
 //------------------------------------------------------------------------------
 
 // Metric is a processor that creates a metric from extracted values from a message part.
@@ -180,21 +234,33 @@ type Metric struct {
 	mGauge   metrics.StatGauge
 	mTimer   metrics.StatTimer
 
-	mCounterVec metrics.StatCounterVec
-	mGaugeVec   metrics.StatGaugeVec
-	mTimerVec   metrics.StatTimerVec
+	mCounterVec  metrics.StatCounterVec
+	mGaugeVec    metrics.StatGaugeVec
+	mTimerVec    metrics.StatTimerVec
+	mObserverVec metrics.StatObserverVec
 
 	handler func(string, int, types.Message) error
 }
 
 type labels []label
 type label struct {
-	name  string
-	value field.Expression
+	name    string
+	value   field.Expression
+	mapping *mapping.Executor
 }
 
-func (l *label) val(index int, msg types.Message) string {
-	return l.value.String(index, msg)
+func (l *label) val(index int, msg types.Message) (string, error) {
+	if l.mapping == nil {
+		return l.value.String(index, msg), nil
+	}
+	p, err := l.mapping.MapPart(index, msg)
+	if err != nil {
+		return "", fmt.Errorf("label '%v': %w", l.name, err)
+	}
+	if p == nil {
+		return "", fmt.Errorf("label '%v': mapping deleted the value", l.name)
+	}
+	return string(p.Get()), nil
 }
 
 func (l labels) names() []string {
@@ -205,12 +271,16 @@ func (l labels) names() []string {
 	return names
 }
 
-func (l labels) values(index int, msg types.Message) []string {
+func (l labels) values(index int, msg types.Message) ([]string, error) {
 	var values []string
 	for i := range l {
-		values = append(values, l[i].val(index, msg))
+		v, err := l[i].val(index, msg)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
 	}
-	return values
+	return values, nil
 }
 
 func unwrapMetric(t metrics.Type) metrics.Type {
@@ -259,13 +329,30 @@ func NewMetric(
 		stats = unwrapMetric(stats)
 	}
 
-	labelNames := make([]string, 0, len(conf.Metric.Labels))
+	labelNames := make([]string, 0, len(conf.Metric.Labels)+len(conf.Metric.LabelMappings))
 	for n := range conf.Metric.Labels {
 		labelNames = append(labelNames, n)
 	}
+	for n := range conf.Metric.LabelMappings {
+		if _, exists := conf.Metric.Labels[n]; exists {
+			return nil, fmt.Errorf("label '%v' is specified in both labels and label_mappings", n)
+		}
+		labelNames = append(labelNames, n)
+	}
 	sort.Strings(labelNames)
 
 	for _, n := range labelNames {
+		if q, exists := conf.Metric.LabelMappings[n]; exists {
+			exec, err := bloblang.NewMapping("", q)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse label '%v' mapping: %v", n, err)
+			}
+			m.labels = append(m.labels, label{
+				name:    n,
+				mapping: exec,
+			})
+			continue
+		}
 		v, err := bloblang.NewField(conf.Metric.Labels[n])
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse label '%v' expression: %v", n, err)
@@ -312,6 +399,25 @@ func NewMetric(
 			m.mTimer = stats.GetTimer(name)
 		}
 		m.handler = m.handleTimer
+	case "histogram":
+		if _, ok := stats.(metrics.WithDistributions); !ok {
+			log.Warnf("Metrics type does not support histograms, observations of '%v' will be recorded as timings\n", name)
+		}
+		m.mObserverVec = metrics.HistogramVecOf(stats, name, m.labels.names(), conf.Metric.Buckets)
+		m.handler = m.handleObservation
+	case "summary":
+		objectives := make(map[float64]float64, len(conf.Metric.Objectives))
+		for _, o := range conf.Metric.Objectives {
+			if o.Quantile < 0 || o.Quantile > 1 {
+				return nil, fmt.Errorf("summary quantile must be between zero and one, received: %v", o.Quantile)
+			}
+			objectives[o.Quantile] = o.Error
+		}
+		if _, ok := stats.(metrics.WithDistributions); !ok {
+			log.Warnf("Metrics type does not support summaries, observations of '%v' will be recorded as timings\n", name)
+		}
+		m.mObserverVec = metrics.SummaryVecOf(stats, name, m.labels.names(), objectives)
+		m.handler = m.handleObservation
 	default:
 		return nil, fmt.Errorf("metric type unrecognised: %v", conf.Metric.Type)
 	}
@@ -321,7 +427,11 @@ func NewMetric(
 
 func (m *Metric) handleCounter(val string, index int, msg types.Message) error {
 	if len(m.labels) > 0 {
-		m.mCounterVec.With(m.labels.values(index, msg)...).Incr(1)
+		values, err := m.labels.values(index, msg)
+		if err != nil {
+			return err
+		}
+		m.mCounterVec.With(values...).Incr(1)
 	} else {
 		m.mCounter.Incr(1)
 	}
@@ -334,7 +444,11 @@ func (m *Metric) handleCounterParts(val string, index int, msg types.Message) er
 		return nil
 	}
 	if len(m.labels) > 0 {
-		m.mCounterVec.With(m.labels.values(index, msg)...).Incr(int64(msg.Len()))
+		values, err := m.labels.values(index, msg)
+		if err != nil {
+			return err
+		}
+		m.mCounterVec.With(values...).Incr(int64(msg.Len()))
 	} else {
 		m.mCounter.Incr(int64(msg.Len()))
 	}
@@ -350,7 +464,11 @@ func (m *Metric) handleCounterBy(val string, index int, msg types.Message) error
 		return errors.New("value is negative")
 	}
 	if len(m.labels) > 0 {
-		m.mCounterVec.With(m.labels.values(index, msg)...).Incr(i)
+		values, err := m.labels.values(index, msg)
+		if err != nil {
+			return err
+		}
+		m.mCounterVec.With(values...).Incr(i)
 	} else {
 		m.mCounter.Incr(i)
 	}
@@ -366,7 +484,11 @@ func (m *Metric) handleGauge(val string, index int, msg types.Message) error {
 		return errors.New("value is negative")
 	}
 	if len(m.labels) > 0 {
-		m.mGaugeVec.With(m.labels.values(index, msg)...).Set(i)
+		values, err := m.labels.values(index, msg)
+		if err != nil {
+			return err
+		}
+		m.mGaugeVec.With(values...).Set(i)
 	} else {
 		m.mGauge.Set(i)
 	}
@@ -382,13 +504,30 @@ func (m *Metric) handleTimer(val string, index int, msg types.Message) error {
 		return errors.New("value is negative")
 	}
 	if len(m.labels) > 0 {
-		m.mTimerVec.With(m.labels.values(index, msg)...).Timing(i)
+		values, err := m.labels.values(index, msg)
+		if err != nil {
+			return err
+		}
+		m.mTimerVec.With(values...).Timing(i)
 	} else {
 		m.mTimer.Timing(i)
 	}
 	return nil
 }
 
+func (m *Metric) handleObservation(val string, index int, msg types.Message) error {
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return err
+	}
+	values, err := m.labels.values(index, msg)
+	if err != nil {
+		return err
+	}
+	m.mObserverVec.With(values...).Observe(f)
+	return nil
+}
+
 // ProcessMessage applies the processor to a message
 func (m *Metric) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	if m.deprecated {
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
@@ -250,3 +251,176 @@ func TestMetricTiming(t *testing.T) {
 
 	assert.Equal(t, expMetrics, mockStats.values)
 }
+
+type mockDistributionMetric struct {
+	metrics.DudType
+
+	labelNames  []string
+	buckets     []float64
+	objectives  map[float64]float64
+	observation map[string][]float64
+}
+
+func (d *mockDistributionMetric) GetHistogramVec(path string, labelNames []string, buckets []float64) metrics.StatObserverVec {
+	d.labelNames, d.buckets = labelNames, buckets
+	return &mockObserverVec{path: path, d: d}
+}
+
+func (d *mockDistributionMetric) GetSummaryVec(path string, labelNames []string, objectives map[float64]float64) metrics.StatObserverVec {
+	d.labelNames, d.objectives = labelNames, objectives
+	return &mockObserverVec{path: path, d: d}
+}
+
+type mockObserverVec struct {
+	path string
+	d    *mockDistributionMetric
+}
+
+func (m *mockObserverVec) With(labelValues ...string) metrics.StatObserver {
+	return &mockObserver{key: m.path + ":" + strings.Join(labelValues, ","), d: m.d}
+}
+
+type mockObserver struct {
+	key string
+	d   *mockDistributionMetric
+}
+
+func (m *mockObserver) Observe(value float64) error {
+	m.d.observation[m.key] = append(m.d.observation[m.key], value)
+	return nil
+}
+
+func TestMetricHistogram(t *testing.T) {
+	mockStats := &mockDistributionMetric{
+		observation: map[string][]float64{},
+	}
+
+	conf := NewConfig()
+	conf.Type = "metric"
+	conf.Metric.Type = "histogram"
+	conf.Metric.Name = "foo.bar"
+	conf.Metric.Value = "${!json(\"foo.bar\")}"
+	conf.Metric.Buckets = []float64{1, 10, 100}
+	conf.Metric.Labels = map[string]string{
+		"static": "baz",
+	}
+	conf.Metric.LabelMappings = map[string]string{
+		"size": `if this.foo.bar > 10 { "large" } else { "small" }`,
+	}
+
+	proc, err := New(conf, nil, log.Noop(), mockStats)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"size", "static"}, mockStats.labelNames)
+	assert.Equal(t, []float64{1, 10, 100}, mockStats.buckets)
+
+	inputs := [][][]byte{
+		{
+			[]byte(`{"foo":{"bar":5.5}}`),
+			[]byte(`{}`),
+		},
+		{
+			[]byte(`not even json`),
+		},
+		{
+			[]byte(`{"foo":{"bar":20}}`),
+			[]byte(`{"foo":{"bar":7}}`),
+		},
+		{
+			[]byte(`{"foo":{"bar":"hello world"}}`),
+		},
+	}
+
+	for _, i := range inputs {
+		msg, res := proc.ProcessMessage(message.New(i))
+		assert.Len(t, msg, 1)
+		assert.Nil(t, res)
+	}
+
+	assert.Equal(t, map[string][]float64{
+		"foo.bar:small,baz": {5.5, 7},
+		"foo.bar:large,baz": {20},
+	}, mockStats.observation)
+}
+
+func TestMetricSummary(t *testing.T) {
+	mockStats := &mockDistributionMetric{
+		observation: map[string][]float64{},
+	}
+
+	conf := NewConfig()
+	conf.Type = "metric"
+	conf.Metric.Type = "summary"
+	conf.Metric.Name = "foo.bar"
+	conf.Metric.Value = "${!json(\"foo.bar\")}"
+	conf.Metric.Objectives = []MetricObjectiveConfig{
+		{Quantile: 0.5, Error: 0.05},
+		{Quantile: 0.99, Error: 0.001},
+	}
+
+	proc, err := New(conf, nil, log.Noop(), mockStats)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[float64]float64{0.5: 0.05, 0.99: 0.001}, mockStats.objectives)
+
+	msg, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":{"bar":3}}`),
+		[]byte(`{"foo":{"bar":4}}`),
+	}))
+	assert.Len(t, msg, 1)
+	assert.Nil(t, res)
+
+	assert.Equal(t, map[string][]float64{
+		"foo.bar:": {3, 4},
+	}, mockStats.observation)
+
+	conf.Metric.Objectives[0].Quantile = 2
+	_, err = New(conf, nil, log.Noop(), mockStats)
+	require.Error(t, err)
+}
+
+func TestMetricHistogramTimingFallback(t *testing.T) {
+	mockStats := &mockMetric{
+		values: map[string]int64{},
+	}
+
+	conf := NewConfig()
+	conf.Type = "metric"
+	conf.Metric.Type = "histogram"
+	conf.Metric.Name = "foo.bar"
+	conf.Metric.Value = "${!json(\"foo.bar\")}"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.WrapFlat(mockStats))
+	require.NoError(t, err)
+
+	msg, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":{"bar":5}}`),
+	}))
+	assert.Len(t, msg, 1)
+	assert.Nil(t, res)
+
+	assert.Equal(t, map[string]int64{
+		"foo.bar": 5,
+	}, mockStats.values)
+}
+
+func TestMetricLabelMappingsBad(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = "metric"
+	conf.Metric.Type = "counter"
+	conf.Metric.Name = "foo.bar"
+	conf.Metric.LabelMappings = map[string]string{
+		"foo": "this is not bloblang",
+	}
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Metric.Labels = map[string]string{
+		"foo": "bar",
+	}
+	conf.Metric.LabelMappings = map[string]string{
+		"foo": "this.foo",
+	}
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
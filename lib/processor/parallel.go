@@ -1,6 +1,8 @@
 package processor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -12,6 +14,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/aimd"
 )
 
 //------------------------------------------------------------------------------
@@ -29,7 +32,12 @@ though they were each a batch of one message (similar to the
 processed in parallel.`,
 		Description: `
 The field ` + "`cap`" + `, if greater than zero, caps the maximum number of
-parallel processing threads.`,
+parallel processing threads.
+
+When ` + "`adaptive.enabled`" + ` is set the number of messages processed at a
+given time is tuned automatically up to ` + "`cap`" + `, where messages that
+fail processing or take longer than ` + "`adaptive.latency_target`" + ` to
+process cause the number to be reduced.`,
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			var err error
 			procConfs := make([]interface{}, len(conf.Parallel.Processors))
@@ -40,12 +48,14 @@ parallel processing threads.`,
 			}
 			return map[string]interface{}{
 				"cap":        conf.Parallel.Cap,
+				"adaptive":   conf.Parallel.Adaptive,
 				"processors": procConfs,
 			}, nil
 		},
 		UsesBatches: true,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("cap", "The maximum number of messages to have processing at a given time."),
+			aimd.FieldSpec("adaptive", "cap"),
 			docs.FieldCommon("processors", "A list of child processors to apply."),
 		},
 	}
@@ -56,14 +66,16 @@ parallel processing threads.`,
 // ParallelConfig is a config struct containing fields for the Parallel
 // processor.
 type ParallelConfig struct {
-	Cap        int      `json:"cap" yaml:"cap"`
-	Processors []Config `json:"processors" yaml:"processors"`
+	Cap        int         `json:"cap" yaml:"cap"`
+	Adaptive   aimd.Config `json:"adaptive" yaml:"adaptive"`
+	Processors []Config    `json:"processors" yaml:"processors"`
 }
 
 // NewParallelConfig returns a default ParallelConfig.
 func NewParallelConfig() ParallelConfig {
 	return ParallelConfig{
 		Cap:        0,
+		Adaptive:   aimd.NewConfig(),
 		Processors: []Config{},
 	}
 }
@@ -75,6 +87,7 @@ func NewParallelConfig() ParallelConfig {
 type Parallel struct {
 	children []types.Processor
 	cap      int
+	limiter  *aimd.Limiter
 
 	log log.Modular
 
@@ -97,9 +110,20 @@ func NewParallel(
 		}
 		children = append(children, proc)
 	}
+	var limiter *aimd.Limiter
+	if conf.Parallel.Adaptive.Enabled {
+		if conf.Parallel.Cap < 1 {
+			return nil, errors.New("a cap must be specified in order to use adaptive concurrency")
+		}
+		var err error
+		if limiter, err = aimd.New(conf.Parallel.Adaptive, conf.Parallel.Cap, stats); err != nil {
+			return nil, fmt.Errorf("failed to create adaptive limiter: %v", err)
+		}
+	}
 	return &Parallel{
 		children: children,
 		cap:      conf.Parallel.Cap,
+		limiter:  limiter,
 		log:      log,
 
 		mCount:     stats.GetCounter("count"),
@@ -137,18 +161,29 @@ func (p *Parallel) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 	for i := 0; i < max; i++ {
 		go func() {
 			for index := range reqChan {
+				if p.limiter != nil {
+					p.limiter.Acquire(context.Background())
+				}
+				t0 := time.Now()
 				resMsgs, res := ExecuteAll(p.children, resultMsgs[index])
 				if res != nil && res.SkipAck() {
 					atomic.AddInt32(&unAcks, 1)
 				}
+				var failed error
 				resultParts := []types.Part{}
 				for _, m := range resMsgs {
 					m.Iter(func(i int, p types.Part) error {
+						if failed == nil && HasFailed(p) {
+							failed = errors.New(GetFail(p))
+						}
 						resultParts = append(resultParts, p)
 						return nil
 					})
 				}
 				resultMsgs[index].SetAll(resultParts)
+				if p.limiter != nil {
+					p.limiter.Release(time.Since(t0), failed)
+				}
 			}
 			wg.Done()
 		}()
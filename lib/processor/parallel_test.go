@@ -187,3 +187,63 @@ func TestParallelCapped(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", act, exp)
 	}
 }
+
+func TestParallelAdaptive(t *testing.T) {
+	var reqs, maxReqs int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := atomic.AddInt64(&reqs, 1)
+		for {
+			prev := atomic.LoadInt64(&maxReqs)
+			if req <= prev || atomic.CompareAndSwapInt64(&maxReqs, prev, req) {
+				break
+			}
+		}
+		<-time.After(time.Millisecond * 5)
+		atomic.AddInt64(&reqs, -1)
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	httpConf := NewConfig()
+	httpConf.Type = TypeHTTP
+	httpConf.HTTP.Client.URL = ts.URL + "/testpost"
+	httpConf.HTTP.Client.NumRetries = 0
+
+	conf := NewConfig()
+	conf.Parallel.Processors = []Config{httpConf}
+	conf.Parallel.Cap = 5
+	conf.Parallel.Adaptive.Enabled = true
+
+	h, err := NewParallel(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, res := h.ProcessMessage(message.New([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+		[]byte("qux"),
+		[]byte("quz"),
+		[]byte("foo2"),
+		[]byte("bar2"),
+		[]byte("baz2"),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if expC, actC := 8, msgs[0].Len(); actC != expC {
+		t.Errorf("Wrong result count: %v != %v", actC, expC)
+	}
+	if !HasFailed(msgs[0].Get(0)) {
+		t.Error("Expected message to have failed")
+	}
+	if exp, act := int64(1), atomic.LoadInt64(&maxReqs); act != exp {
+		t.Errorf("Wrong max parallelism: %v != %v", act, exp)
+	}
+
+	conf.Parallel.Cap = 0
+	if _, err = NewParallel(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from adaptive without cap")
+	}
+}
@@ -0,0 +1,460 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+//------------------------------------------------------------------------------
+
+// parquetSchemaItem is an element of the JSON schema format used by
+// github.com/xitongsys/parquet-go.
+type parquetSchemaItem struct {
+	Tag    string               `json:"Tag"`
+	Fields []*parquetSchemaItem `json:"Fields,omitempty"`
+}
+
+func parquetCheckName(name string) error {
+	if name == "" || strings.ContainsAny(name, ",=") || strings.TrimSpace(name) != name {
+		return fmt.Errorf("field name '%v' cannot be used as a parquet column", name)
+	}
+	return nil
+}
+
+func validateParquetSchema(jsonSchema string) error {
+	_, err := schema.NewSchemaHandlerFromJSON(jsonSchema)
+	return err
+}
+
+//------------------------------------------------------------------------------
+
+// parquetInferred is a field of a parquet schema inferred from JSON documents.
+type parquetInferred struct {
+	name   string
+	kind   string
+	fields []*parquetInferred
+}
+
+func inferParquetField(name string, v interface{}) (*parquetInferred, error) {
+	if err := parquetCheckName(name); err != nil {
+		return nil, err
+	}
+	f := &parquetInferred{name: name}
+	switch t := v.(type) {
+	case string:
+		f.kind = "UTF8"
+	case bool:
+		f.kind = "BOOLEAN"
+	case json.Number:
+		f.kind = "DOUBLE"
+		if _, err := t.Int64(); err == nil {
+			f.kind = "INT64"
+		}
+	case float64:
+		f.kind = "DOUBLE"
+		if t == math.Trunc(t) && math.Abs(t) < (1<<53) {
+			f.kind = "INT64"
+		}
+	case int, int64, uint64:
+		f.kind = "INT64"
+	case map[string]interface{}:
+		fields, err := inferParquetFields(t)
+		if err != nil || len(fields) == 0 {
+			return nil, err
+		}
+		f.kind = "group"
+		f.fields = fields
+	case []interface{}:
+		var elements []*parquetInferred
+		for _, e := range t {
+			ef, err := inferParquetField("element", e)
+			if err != nil {
+				return nil, err
+			}
+			if ef != nil {
+				elements = mergeParquetFields(elements, []*parquetInferred{ef})
+			}
+		}
+		if len(elements) == 0 {
+			return nil, nil
+		}
+		f.kind = "LIST"
+		f.fields = elements
+	default:
+		// Nulls (and anything else we can't type) are skipped, the column will
+		// be inferred from a later document if possible.
+		return nil, nil
+	}
+	return f, nil
+}
+
+func inferParquetFields(obj map[string]interface{}) ([]*parquetInferred, error) {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]*parquetInferred, 0, len(keys))
+	for _, k := range keys {
+		f, err := inferParquetField(k, obj[k])
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// mergeParquetFields adds fields from src to dst that are missing, and merges
+// the children of those that share a name and kind. Integer columns are
+// widened to doubles when a later document contains a floating point value.
+func mergeParquetFields(dst, src []*parquetInferred) []*parquetInferred {
+	for _, s := range src {
+		var match *parquetInferred
+		for _, d := range dst {
+			if d.name == s.name {
+				match = d
+				break
+			}
+		}
+		if match == nil {
+			dst = append(dst, s)
+			continue
+		}
+		if match.kind == "INT64" && s.kind == "DOUBLE" {
+			match.kind = "DOUBLE"
+		}
+		if match.kind == s.kind && len(s.fields) > 0 {
+			match.fields = mergeParquetFields(match.fields, s.fields)
+		}
+	}
+	return dst
+}
+
+func (f *parquetInferred) schemaItem(isElement bool) *parquetSchemaItem {
+	tag := "name=" + f.name
+	if isElement {
+		// The element of a list must have the internal name the parquet
+		// library expects.
+		tag += ", inname=Element"
+	}
+	if f.kind != "group" {
+		tag += ", type=" + f.kind
+	}
+	item := &parquetSchemaItem{
+		Tag: tag + ", repetitiontype=OPTIONAL",
+	}
+	for _, c := range f.fields {
+		item.Fields = append(item.Fields, c.schemaItem(f.kind == "LIST"))
+	}
+	return item
+}
+
+func inferParquetSchema(docs []map[string]interface{}) (string, error) {
+	var fields []*parquetInferred
+	for _, doc := range docs {
+		docFields, err := inferParquetFields(doc)
+		if err != nil {
+			return "", err
+		}
+		fields = mergeParquetFields(fields, docFields)
+	}
+	if len(fields) == 0 {
+		return "", errors.New("unable to infer a parquet schema as no fields were found with a known type")
+	}
+
+	root := &parquetSchemaItem{
+		Tag: "name=root, repetitiontype=REQUIRED",
+	}
+	for _, f := range fields {
+		root.Fields = append(root.Fields, f.schemaItem(false))
+	}
+
+	schemaBytes, err := json.Marshal(root)
+	if err != nil {
+		return "", err
+	}
+	return string(schemaBytes), nil
+}
+
+//------------------------------------------------------------------------------
+
+func newParquetArchiver(jsonSchema string) archiveFunc {
+	return func(hFunc headerFunc, msg types.Message) (types.Part, error) {
+		docs := make([]map[string]interface{}, msg.Len())
+		err := msg.Iter(func(i int, part types.Part) error {
+			doc, jerr := part.JSON()
+			if jerr != nil {
+				return fmt.Errorf("failed to parse message as JSON: %v", jerr)
+			}
+			obj, ok := doc.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("expected message to be a JSON object, found: %T", doc)
+			}
+			docs[i] = obj
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		schemaStr := jsonSchema
+		if schemaStr == "" {
+			if schemaStr, err = inferParquetSchema(docs); err != nil {
+				return nil, err
+			}
+		}
+
+		buf := &bytes.Buffer{}
+		pw, err := writer.NewJSONWriterFromWriter(schemaStr, buf, 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parquet writer: %v", err)
+		}
+		if err = msg.Iter(func(i int, part types.Part) error {
+			return pw.Write(string(part.Get()))
+		}); err != nil {
+			return nil, fmt.Errorf("failed to write parquet row: %v", err)
+		}
+		if err = pw.WriteStop(); err != nil {
+			return nil, fmt.Errorf("failed to write parquet file: %v", err)
+		}
+
+		newPart := msg.Get(0).Copy()
+		newPart.Set(buf.Bytes())
+		return newPart, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// parquetNode is an element of the schema tree of a parquet file.
+type parquetNode struct {
+	element  *parquet.SchemaElement
+	children []*parquetNode
+}
+
+func (n *parquetNode) name() string {
+	return n.element.GetName()
+}
+
+func (n *parquetNode) isLeaf() bool {
+	return len(n.children) == 0 && n.element.IsSetType()
+}
+
+func (n *parquetNode) repetition() parquet.FieldRepetitionType {
+	return n.element.GetRepetitionType()
+}
+
+func parquetSchemaTree(elements []*parquet.SchemaElement) (*parquetNode, error) {
+	pos := 0
+	var build func() (*parquetNode, error)
+	build = func() (*parquetNode, error) {
+		if pos >= len(elements) {
+			return nil, errors.New("parquet schema is truncated")
+		}
+		n := &parquetNode{element: elements[pos]}
+		pos++
+		for i := int32(0); i < n.element.GetNumChildren(); i++ {
+			c, err := build()
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, c)
+		}
+		return n, nil
+	}
+	return build()
+}
+
+// Converted types that the parquet library can map onto their physical type
+// when declared within a JSON schema.
+var parquetSchemaConvertedTypes = map[parquet.ConvertedType]struct{}{
+	parquet.ConvertedType_UTF8:             {},
+	parquet.ConvertedType_JSON:             {},
+	parquet.ConvertedType_BSON:             {},
+	parquet.ConvertedType_INT_8:            {},
+	parquet.ConvertedType_INT_16:           {},
+	parquet.ConvertedType_INT_32:           {},
+	parquet.ConvertedType_INT_64:           {},
+	parquet.ConvertedType_UINT_8:           {},
+	parquet.ConvertedType_UINT_16:          {},
+	parquet.ConvertedType_UINT_32:          {},
+	parquet.ConvertedType_UINT_64:          {},
+	parquet.ConvertedType_DATE:             {},
+	parquet.ConvertedType_TIME_MILLIS:      {},
+	parquet.ConvertedType_TIME_MICROS:      {},
+	parquet.ConvertedType_TIMESTAMP_MILLIS: {},
+	parquet.ConvertedType_TIMESTAMP_MICROS: {},
+	parquet.ConvertedType_INTERVAL:         {},
+}
+
+// schemaItem converts the tree into a JSON schema where each field is given a
+// generated internal name. The parquet library builds Go structs from these
+// names, which must therefore be exported identifiers that are unique within
+// their parent, neither of which is guaranteed of the column names themselves.
+func (n *parquetNode) schemaItem(inName string, repetition parquet.FieldRepetitionType) (*parquetSchemaItem, error) {
+	if err := parquetCheckName(n.name()); err != nil {
+		return nil, err
+	}
+	tag := fmt.Sprintf("name=%v, inname=%v, repetitiontype=%v", n.name(), inName, repetition)
+	if n.isLeaf() {
+		typeStr := n.element.GetType().String()
+		if _, exists := parquetSchemaConvertedTypes[n.element.GetConvertedType()]; exists && n.element.IsSetConvertedType() {
+			typeStr = n.element.GetConvertedType().String()
+		} else if n.element.GetType() == parquet.Type_FIXED_LEN_BYTE_ARRAY {
+			typeStr += fmt.Sprintf(", length=%v", n.element.GetTypeLength())
+		}
+		tag += ", type=" + typeStr
+	}
+
+	item := &parquetSchemaItem{Tag: tag}
+	for i, c := range n.children {
+		cItem, err := c.schemaItem(fmt.Sprintf("F%v", i), c.repetition())
+		if err != nil {
+			return nil, err
+		}
+		item.Fields = append(item.Fields, cItem)
+	}
+	return item, nil
+}
+
+// toJSON converts a value read with a schema generated by schemaItem into a
+// JSON structure.
+func (n *parquetNode) toJSON(v reflect.Value) interface{} {
+	switch n.repetition() {
+	case parquet.FieldRepetitionType_REPEATED:
+		arr := make([]interface{}, v.Len())
+		for i := range arr {
+			arr[i] = n.valueToJSON(v.Index(i))
+		}
+		return arr
+	case parquet.FieldRepetitionType_OPTIONAL:
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+	}
+	return n.valueToJSON(v)
+}
+
+func (n *parquetNode) valueToJSON(v reflect.Value) interface{} {
+	if n.isLeaf() {
+		return v.Interface()
+	}
+
+	switch n.element.GetConvertedType() {
+	case parquet.ConvertedType_LIST:
+		if !n.element.IsSetConvertedType() || len(n.children) != 1 ||
+			n.children[0].repetition() != parquet.FieldRepetitionType_REPEATED {
+			break
+		}
+		repeated, items := n.children[0], v.Field(0)
+		// Lists are normally three levels deep, where the repeated group wraps
+		// the element. Older writers put the element at the repeated level.
+		threeLevel := !repeated.isLeaf() && len(repeated.children) == 1 &&
+			repeated.name() != "array" && repeated.name() != n.name()+"_tuple"
+
+		arr := make([]interface{}, items.Len())
+		for i := range arr {
+			if threeLevel {
+				arr[i] = repeated.children[0].toJSON(items.Index(i).Field(0))
+			} else {
+				arr[i] = repeated.valueToJSON(items.Index(i))
+			}
+		}
+		return arr
+	case parquet.ConvertedType_MAP, parquet.ConvertedType_MAP_KEY_VALUE:
+		if !n.element.IsSetConvertedType() || len(n.children) != 1 ||
+			len(n.children[0].children) != 2 ||
+			n.children[0].repetition() != parquet.FieldRepetitionType_REPEATED {
+			break
+		}
+		keyValue, entries := n.children[0], v.Field(0)
+		obj := make(map[string]interface{}, entries.Len())
+		for i := 0; i < entries.Len(); i++ {
+			entry := entries.Index(i)
+			key := keyValue.children[0].toJSON(entry.Field(0))
+			obj[fmt.Sprintf("%v", key)] = keyValue.children[1].toJSON(entry.Field(1))
+		}
+		return obj
+	}
+
+	obj := make(map[string]interface{}, len(n.children))
+	for i, c := range n.children {
+		obj[c.name()] = c.toJSON(v.Field(i))
+	}
+	return obj
+}
+
+func parquetUnarchive(part types.Part) (parts []types.Part, err error) {
+	// The parquet library is prone to panicking on malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read parquet file: %v", r)
+		}
+	}()
+
+	pFile, err := buffer.NewBufferFile(part.Get())
+	if err != nil {
+		return nil, err
+	}
+
+	footerReader := &reader.ParquetReader{PFile: pFile}
+	if err = footerReader.ReadFooter(); err != nil {
+		return nil, fmt.Errorf("failed to read parquet footer: %v", err)
+	}
+
+	root, err := parquetSchemaTree(footerReader.Footer.Schema)
+	if err != nil {
+		return nil, err
+	}
+	rootItem, err := root.schemaItem("Root", parquet.FieldRepetitionType_REQUIRED)
+	if err != nil {
+		return nil, err
+	}
+	schemaBytes, err := json.Marshal(rootItem)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := reader.NewParquetReader(pFile, string(schemaBytes), 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet reader: %v", err)
+	}
+	defer pr.ReadStop()
+
+	rows, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet rows: %v", err)
+	}
+
+	parts = make([]types.Part, len(rows))
+	for i, row := range rows {
+		newPart := part.Copy()
+		if err = newPart.SetJSON(root.valueToJSON(reflect.ValueOf(row))); err != nil {
+			return nil, fmt.Errorf("failed to marshal row into new message: %v", err)
+		}
+		parts[i] = newPart
+	}
+	return parts, nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parquetRoundTrip(t *testing.T, schema string, input [][]byte) types.Message {
+	t.Helper()
+
+	aConf := NewConfig()
+	aConf.Archive.Format = "parquet"
+	aConf.Archive.ParquetSchema = schema
+
+	archive, err := NewArchive(aConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	uConf := NewConfig()
+	uConf.Unarchive.Format = "parquet"
+
+	unarchive, err := NewUnarchive(uConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := archive.ProcessMessage(message.New(input))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	require.False(t, HasFailed(msgs[0].Get(0)), GetFail(msgs[0].Get(0)))
+	assert.Equal(t, "PAR1", string(msgs[0].Get(0).Get()[:4]))
+
+	msgs, res = unarchive.ProcessMessage(msgs[0])
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i := 0; i < msgs[0].Len(); i++ {
+		require.False(t, HasFailed(msgs[0].Get(i)), GetFail(msgs[0].Get(i)))
+	}
+	return msgs[0]
+}
+
+func TestParquetInferSchema(t *testing.T) {
+	schema, err := inferParquetSchema([]map[string]interface{}{
+		{
+			"id":   float64(1),
+			"tags": []interface{}{"a", "b"},
+			"meta": map[string]interface{}{"score": float64(1)},
+			"gone": nil,
+		},
+		{
+			"id":     float64(2),
+			"meta":   map[string]interface{}{"score": 2.5, "ok": true},
+			"fields": []interface{}{},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"Tag":"name=root, repetitiontype=REQUIRED","Fields":[`+
+		`{"Tag":"name=id, type=INT64, repetitiontype=OPTIONAL"},`+
+		`{"Tag":"name=meta, repetitiontype=OPTIONAL","Fields":[`+
+		`{"Tag":"name=score, type=DOUBLE, repetitiontype=OPTIONAL"},`+
+		`{"Tag":"name=ok, type=BOOLEAN, repetitiontype=OPTIONAL"}]},`+
+		`{"Tag":"name=tags, type=LIST, repetitiontype=OPTIONAL","Fields":[`+
+		`{"Tag":"name=element, inname=Element, type=UTF8, repetitiontype=OPTIONAL"}]}]}`, schema)
+
+	_, err = inferParquetSchema([]map[string]interface{}{{"foo": nil}})
+	assert.Error(t, err)
+
+	_, err = inferParquetSchema([]map[string]interface{}{{"foo,bar": "baz"}})
+	assert.Error(t, err)
+}
+
+func TestParquetArchiveInferred(t *testing.T) {
+	msg := parquetRoundTrip(t, "", [][]byte{
+		[]byte(`{"id":1,"name":"foo","tags":["a","b"],"meta":{"score":1.5}}`),
+		[]byte(`{"id":2,"meta":{"score":2}}`),
+	})
+	assert.Equal(t, [][]byte{
+		[]byte(`{"id":1,"meta":{"score":1.5},"name":"foo","tags":["a","b"]}`),
+		[]byte(`{"id":2,"meta":{"score":2},"name":null,"tags":null}`),
+	}, message.GetAllBytes(msg))
+}
+
+func TestParquetArchiveSchema(t *testing.T) {
+	schema := `{
+  "Tag": "name=root, repetitiontype=REQUIRED",
+  "Fields": [
+    {"Tag": "name=id, type=INT32, repetitiontype=REQUIRED"},
+    {"Tag": "name=Name, type=UTF8, repetitiontype=OPTIONAL"},
+    {"Tag": "name=tags, type=UTF8, repetitiontype=REPEATED"}
+  ]
+}`
+
+	msg := parquetRoundTrip(t, schema, [][]byte{
+		[]byte(`{"id":1,"Name":"foo","tags":["a","b"],"ignored":true}`),
+		[]byte(`{"id":2}`),
+	})
+	assert.Equal(t, [][]byte{
+		[]byte(`{"Name":"foo","id":1,"tags":["a","b"]}`),
+		[]byte(`{"Name":null,"id":2,"tags":[]}`),
+	}, message.GetAllBytes(msg))
+}
+
+func TestParquetArchiveErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Archive.Format = "parquet"
+	conf.Archive.ParquetSchema = `not a schema`
+
+	_, err := NewArchive(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+
+	conf.Archive.ParquetSchema = ""
+	archive, err := NewArchive(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := archive.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"bar"}`),
+		[]byte(`["not","an","object"]`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 2, msgs[0].Len())
+	assert.Equal(t, "expected message to be a JSON object, found: []interface {}", GetFail(msgs[0].Get(0)))
+}
+
+func TestParquetUnarchiveErrors(t *testing.T) {
+	conf := NewConfig()
+	conf.Unarchive.Format = "parquet"
+
+	unarchive, err := NewUnarchive(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := unarchive.ProcessMessage(message.New([][]byte{
+		[]byte(`not a parquet file`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.Equal(t, `not a parquet file`, string(msgs[0].Get(0).Get()))
+	assert.True(t, HasFailed(msgs[0].Get(0)))
+}
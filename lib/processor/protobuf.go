@@ -1,10 +1,14 @@
 package processor
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
@@ -12,6 +16,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/jhump/protoreflect/desc"
 	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/jhump/protoreflect/dynamic"
@@ -51,11 +56,35 @@ to manipulate the contents of the document within Benthos.
 
 ### ` + "`from_json`" + `
 
-Attempts to create a target protobuf message from a generic JSON structure.`,
+Attempts to create a target protobuf message from a generic JSON structure.
+
+## Loading Descriptors
+
+By default the message definition is obtained by parsing all .proto files found
+within ` + "`import_path`" + ` at startup. Alternatively, a compiled
+` + "`FileDescriptorSet`" + ` can be loaded from the path ` + "`descriptor_set`" + `,
+which can be generated with:
+
+` + "```sh" + `
+protoc --include_imports --descriptor_set_out=./schema.protoset -I./schema ./schema/*.proto
+` + "```" + `
+
+Or the definition can be obtained from a Confluent compatible schema registry by
+enabling ` + "`schema_registry`" + `, in which case the .proto source of the
+configured subject version, along with all of its references, is fetched and
+parsed.
+
+When ` + "`reload_interval`" + ` is set descriptors are periodically reloaded
+from their source, allowing schema changes to be picked up without a restart. If
+a reload fails then an error is logged and the previously loaded descriptor
+continues to be used.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("operator", "The [operator](#operators) to execute").HasOptions("to_json", "from_json"),
 			docs.FieldCommon("message", "The fully qualified name of the protobuf message to convert to/from."),
 			docs.FieldCommon("import_path", "A path to a .proto file, or directory containing all .proto files required for parsing the target message. If left empty the current directory is used."),
+			docs.FieldAdvanced("descriptor_set", "An optional path to a compiled `FileDescriptorSet` to load the target message from instead of parsing .proto files.").AtVersion("3.39.0"),
+			protobufSchemaRegistryFieldSpec(),
+			docs.FieldAdvanced("reload_interval", "An optional period of time after which descriptors are reloaded from their source. If left empty descriptors are only loaded at startup.", "5m").AtVersion("3.39.0"),
 			partsFieldSpec,
 		},
 		Examples: []docs.AnnotatedExample{
@@ -149,23 +178,63 @@ pipeline:
 	}
 }
 
+func protobufSchemaRegistryFieldSpec() docs.FieldSpec {
+	children := docs.FieldSpecs{
+		docs.FieldCommon("enabled", "Whether to load the target message from a schema registry."),
+	}
+	children = append(children, schemaRegistryFieldSpecs()...)
+	children = append(children,
+		docs.FieldCommon("subject", "The subject containing the target message."),
+		docs.FieldCommon("version", "The version of the subject to load, either a number or `latest`."),
+	)
+	return docs.FieldAdvanced(
+		"schema_registry", "Allows you to load the target message from a Confluent compatible schema registry instead of parsing local .proto files.",
+	).WithChildren(children...).AtVersion("3.39.0")
+}
+
 //------------------------------------------------------------------------------
 
 // ProtobufConfig contains configuration fields for the Protobuf processor.
 type ProtobufConfig struct {
-	Parts      []int  `json:"parts" yaml:"parts"`
-	Operator   string `json:"operator" yaml:"operator"`
-	Message    string `json:"message" yaml:"message"`
-	ImportPath string `json:"import_path" yaml:"import_path"`
+	Parts          []int                        `json:"parts" yaml:"parts"`
+	Operator       string                       `json:"operator" yaml:"operator"`
+	Message        string                       `json:"message" yaml:"message"`
+	ImportPath     string                       `json:"import_path" yaml:"import_path"`
+	DescriptorSet  string                       `json:"descriptor_set" yaml:"descriptor_set"`
+	SchemaRegistry ProtobufSchemaRegistryConfig `json:"schema_registry" yaml:"schema_registry"`
+	ReloadInterval string                       `json:"reload_interval" yaml:"reload_interval"`
 }
 
 // NewProtobufConfig returns a ProtobufConfig with default values.
 func NewProtobufConfig() ProtobufConfig {
 	return ProtobufConfig{
-		Parts:      []int{},
-		Operator:   "to_json",
-		Message:    "",
-		ImportPath: "",
+		Parts:          []int{},
+		Operator:       "to_json",
+		Message:        "",
+		ImportPath:     "",
+		DescriptorSet:  "",
+		SchemaRegistry: NewProtobufSchemaRegistryConfig(),
+		ReloadInterval: "",
+	}
+}
+
+// ProtobufSchemaRegistryConfig contains configuration fields for loading
+// protobuf descriptors from a schema registry.
+type ProtobufSchemaRegistryConfig struct {
+	Enabled              bool `json:"enabled" yaml:"enabled"`
+	SchemaRegistryConfig `json:",inline" yaml:",inline"`
+	Subject              string `json:"subject" yaml:"subject"`
+	Version              string `json:"version" yaml:"version"`
+}
+
+// NewProtobufSchemaRegistryConfig returns a ProtobufSchemaRegistryConfig with
+// default values.
+func NewProtobufSchemaRegistryConfig() ProtobufSchemaRegistryConfig {
+	return ProtobufSchemaRegistryConfig{
+		Enabled:              false,
+		SchemaRegistryConfig: NewSchemaRegistryConfig(),
+		Subject:              "",
+		Version:              "latest",
 	}
 }
 
@@ -173,13 +242,9 @@ func NewProtobufConfig() ProtobufConfig {
 
 type protobufOperator func(part types.Part) error
 
-func newProtobufToJSONOperator(message, importPath string) (protobufOperator, error) {
-	m, err := loadDescriptor(message, importPath)
-	if err != nil {
-		return nil, err
-	}
+func newProtobufToJSONOperator(getDesc func() *desc.MessageDescriptor) protobufOperator {
 	return func(part types.Part) error {
-		msg := dynamic.NewMessage(m)
+		msg := dynamic.NewMessage(getDesc())
 		if err := proto.Unmarshal(part.Get(), msg); err != nil {
 			return fmt.Errorf("failed to unmarshal message: %w", err)
 		}
@@ -191,16 +256,12 @@ func newProtobufToJSONOperator(message, importPath string) (protobufOperator, er
 
 		part.Set(data)
 		return nil
-	}, nil
+	}
 }
 
-func newProtobufFromJSONOperator(message, importPath string) (protobufOperator, error) {
-	m, err := loadDescriptor(message, importPath)
-	if err != nil {
-		return nil, err
-	}
+func newProtobufFromJSONOperator(getDesc func() *desc.MessageDescriptor) protobufOperator {
 	return func(part types.Part) error {
-		msg := dynamic.NewMessage(m)
+		msg := dynamic.NewMessage(getDesc())
 		if err := msg.UnmarshalJSON(part.Get()); err != nil {
 			return fmt.Errorf("failed to unmarshal JSON message: %w", err)
 		}
@@ -212,24 +273,76 @@ func newProtobufFromJSONOperator(message, importPath string) (protobufOperator,
 
 		part.Set(data)
 		return nil
-	}, nil
+	}
 }
 
-func strToProtobufOperator(opStr, message, importPath string) (protobufOperator, error) {
+func strToProtobufOperator(opStr string, getDesc func() *desc.MessageDescriptor) (protobufOperator, error) {
 	switch opStr {
 	case "to_json":
-		return newProtobufToJSONOperator(message, importPath)
+		return newProtobufToJSONOperator(getDesc), nil
 	case "from_json":
-		return newProtobufFromJSONOperator(message, importPath)
+		return newProtobufFromJSONOperator(getDesc), nil
 	}
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
-func loadDescriptor(message, importPath string) (*desc.MessageDescriptor, error) {
-	if len(message) == 0 {
+//------------------------------------------------------------------------------
+
+type protobufDescriptorLoader func() (*desc.MessageDescriptor, error)
+
+func newProtobufDescriptorLoader(conf ProtobufConfig) (protobufDescriptorLoader, error) {
+	if len(conf.Message) == 0 {
 		return nil, errors.New("message field must not be empty")
 	}
+	if conf.SchemaRegistry.Enabled {
+		if conf.DescriptorSet != "" {
+			return nil, errors.New("cannot combine descriptor_set with schema_registry")
+		}
+		if conf.SchemaRegistry.Subject == "" {
+			return nil, errors.New("a schema registry subject must be specified")
+		}
+		client, err := newSchemaRegistryClient(conf.SchemaRegistry.SchemaRegistryConfig)
+		if err != nil {
+			return nil, err
+		}
+		return func() (*desc.MessageDescriptor, error) {
+			return loadRegistryDescriptor(client, conf.SchemaRegistry.Subject, conf.SchemaRegistry.Version, conf.Message)
+		}, nil
+	}
+	if conf.DescriptorSet != "" {
+		return func() (*desc.MessageDescriptor, error) {
+			return loadDescriptorSet(conf.Message, conf.DescriptorSet)
+		}, nil
+	}
+	return func() (*desc.MessageDescriptor, error) {
+		return loadDescriptor(conf.Message, conf.ImportPath)
+	}, nil
+}
 
+// findMessageDescriptor searches a list of file descriptors, and their
+// dependencies, for a message.
+func findMessageDescriptor(fds []*desc.FileDescriptor, message string) *desc.MessageDescriptor {
+	seen := map[string]struct{}{}
+	var find func(fds []*desc.FileDescriptor) *desc.MessageDescriptor
+	find = func(fds []*desc.FileDescriptor) *desc.MessageDescriptor {
+		for _, d := range fds {
+			if _, exists := seen[d.GetName()]; exists {
+				continue
+			}
+			seen[d.GetName()] = struct{}{}
+			if msg := d.FindMessage(message); msg != nil {
+				return msg
+			}
+			if msg := find(d.GetDependencies()); msg != nil {
+				return msg
+			}
+		}
+		return nil
+	}
+	return find(fds)
+}
+
+func loadDescriptor(message, importPath string) (*desc.MessageDescriptor, error) {
 	var parser protoparse.Parser
 	if len(importPath) > 0 {
 		parser.ImportPaths = []string{importPath}
@@ -263,14 +376,77 @@ func loadDescriptor(message, importPath string) (*desc.MessageDescriptor, error)
 		return nil, fmt.Errorf("no .proto files were found in the path '%v'", importPath)
 	}
 
-	var msg *desc.MessageDescriptor
-	for _, d := range fds {
-		if msg = d.FindMessage(message); msg != nil {
-			break
+	msg := findMessageDescriptor(fds, message)
+	if msg == nil {
+		err = fmt.Errorf("unable to find message '%v' definition within '%v'", message, importPath)
+	}
+	return msg, err
+}
+
+func loadDescriptorSet(message, setPath string) (*desc.MessageDescriptor, error) {
+	setBytes, err := ioutil.ReadFile(setPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set: %v", err)
+	}
+
+	var set dpb.FileDescriptorSet
+	if err = proto.Unmarshal(setBytes, &set); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal descriptor set: %v", err)
+	}
+
+	fdMap, err := desc.CreateFileDescriptorsFromSet(&set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create descriptors from set: %v", err)
+	}
+	fds := make([]*desc.FileDescriptor, 0, len(fdMap))
+	for _, f := range set.GetFile() {
+		if fd, exists := fdMap[f.GetName()]; exists {
+			fds = append(fds, fd)
 		}
 	}
+
+	msg := findMessageDescriptor(fds, message)
 	if msg == nil {
-		err = fmt.Errorf("unable to find message '%v' definition within '%v'", message, importPath)
+		err = fmt.Errorf("unable to find message '%v' definition within '%v'", message, setPath)
+	}
+	return msg, err
+}
+
+func loadRegistryDescriptor(client *schemaRegistryClient, subject, version, message string) (*desc.MessageDescriptor, error) {
+	ctx := context.Background()
+
+	schema, err := client.GetSchemaBySubjectVersion(ctx, subject, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema: %v", err)
+	}
+	if schema.SchemaType != "PROTOBUF" {
+		return nil, fmt.Errorf("expected schema type PROTOBUF, got %v", schema.SchemaType)
+	}
+
+	mainFile := subject + ".proto"
+	files := map[string]string{}
+	if err = client.WalkReferences(ctx, schema.References, func(name string, s *schemaRegistrySchema) error {
+		if s.SchemaType != "PROTOBUF" {
+			return fmt.Errorf("expected reference '%v' schema type PROTOBUF, got %v", name, s.SchemaType)
+		}
+		files[name] = s.Schema
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	files[mainFile] = schema.Schema
+
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(files),
+	}
+	fds, err := parser.ParseFiles(mainFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+
+	msg := findMessageDescriptor(fds, message)
+	if msg == nil {
+		err = fmt.Errorf("unable to find message '%v' definition within subject '%v'", message, subject)
 	}
 	return msg, err
 }
@@ -279,13 +455,18 @@ func loadDescriptor(message, importPath string) (*desc.MessageDescriptor, error)
 
 // Protobuf is a processor that performs an operation on an Protobuf payload.
 type Protobuf struct {
-	parts    []int
-	operator protobufOperator
+	parts      []int
+	operator   protobufOperator
+	descriptor atomic.Value
 
 	conf  Config
 	log   log.Modular
 	stats metrics.Type
 
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+
 	mCount     metrics.StatCounter
 	mErr       metrics.StatCounter
 	mSent      metrics.StatCounter
@@ -302,19 +483,75 @@ func NewProtobuf(
 		log:   log,
 		stats: stats,
 
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+
 		mCount:     stats.GetCounter("count"),
 		mErr:       stats.GetCounter("error"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
 
-	var err error
-	if p.operator, err = strToProtobufOperator(conf.Protobuf.Operator, conf.Protobuf.Message, conf.Protobuf.ImportPath); err != nil {
+	var reloadInterval time.Duration
+	if conf.Protobuf.ReloadInterval != "" {
+		var err error
+		if reloadInterval, err = time.ParseDuration(conf.Protobuf.ReloadInterval); err != nil {
+			return nil, fmt.Errorf("failed to parse reload_interval: %v", err)
+		}
+	}
+
+	loader, err := newProtobufDescriptorLoader(conf.Protobuf)
+	if err != nil {
+		return nil, err
+	}
+	m, err := loader()
+	if err != nil {
 		return nil, err
 	}
+	p.descriptor.Store(m)
+
+	if p.operator, err = strToProtobufOperator(conf.Protobuf.Operator, p.getDescriptor); err != nil {
+		return nil, err
+	}
+
+	if reloadInterval > 0 {
+		go p.reloadLoop(loader, reloadInterval)
+	} else {
+		close(p.closedChan)
+	}
 	return p, nil
 }
 
+func (p *Protobuf) getDescriptor() *desc.MessageDescriptor {
+	return p.descriptor.Load().(*desc.MessageDescriptor)
+}
+
+func (p *Protobuf) reloadLoop(loader protobufDescriptorLoader, interval time.Duration) {
+	defer close(p.closedChan)
+
+	mReloadSuccess := p.stats.GetCounter("reload.success")
+	mReloadErr := p.stats.GetCounter("reload.error")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.closeChan:
+			return
+		}
+		m, err := loader()
+		if err != nil {
+			mReloadErr.Incr(1)
+			p.log.Errorf("Failed to reload protobuf descriptor: %v\n", err)
+			continue
+		}
+		mReloadSuccess.Incr(1)
+		p.descriptor.Store(m)
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // ProcessMessage applies the processor to a message, either creating >0
@@ -341,10 +578,18 @@ func (p *Protobuf) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 
 // CloseAsync shuts down the processor and stops processing requests.
 func (p *Protobuf) CloseAsync() {
+	p.closeOnce.Do(func() {
+		close(p.closeChan)
+	})
 }
 
 // WaitForClose blocks until the processor has closed down.
 func (p *Protobuf) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-p.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
 	return nil
 }
 
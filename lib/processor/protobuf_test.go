@@ -1,12 +1,23 @@
 package processor
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -154,3 +165,138 @@ func TestProtobufErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestProtobufDescriptorSet(t *testing.T) {
+	parser := protoparse.Parser{ImportPaths: []string{"../../config/test/protobuf/schema"}}
+	fds, err := parser.ParseFiles("house.proto")
+	require.NoError(t, err)
+
+	setBytes, err := proto.Marshal(desc.ToFileDescriptorSet(fds...))
+	require.NoError(t, err)
+
+	tmpDir, err := ioutil.TempDir("", "benthos_protobuf_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	setPath := filepath.Join(tmpDir, "schema.protoset")
+	require.NoError(t, ioutil.WriteFile(setPath, setBytes, 0644))
+
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = "testing.Person"
+	conf.Protobuf.DescriptorSet = setPath
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"firstName":"daryl","lastName":"hall"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		{0x0a, 0x05, 0x64, 0x61, 0x72, 0x79, 0x6c, 0x12, 0x04, 0x68, 0x61, 0x6c, 0x6c},
+	}, message.GetAllBytes(msgs[0]))
+}
+
+func TestProtobufSchemaRegistry(t *testing.T) {
+	personSchema := `
+syntax = "proto3";
+package testing;
+
+message Person {
+  string first_name = 1;
+  string last_name = 2;
+}
+`
+	houseSchemas := []string{`
+syntax = "proto3";
+package testing;
+
+import "person.proto";
+
+message House {
+  repeated testing.Person people = 1;
+}
+`, `
+syntax = "proto3";
+package testing;
+
+import "person.proto";
+
+message House {
+  repeated testing.Person people = 1;
+  string address = 2;
+}
+`}
+
+	var houseVersion int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var res interface{}
+		switch r.URL.Path {
+		case "/subjects/house/versions/latest":
+			v := atomic.LoadInt32(&houseVersion)
+			res = map[string]interface{}{
+				"subject":    "house",
+				"version":    v + 1,
+				"id":         v + 10,
+				"schemaType": "PROTOBUF",
+				"schema":     houseSchemas[v],
+				"references": []interface{}{
+					map[string]interface{}{"name": "person.proto", "subject": "person", "version": 1},
+				},
+			}
+		case "/subjects/person/versions/1":
+			res = map[string]interface{}{
+				"subject":    "person",
+				"version":    1,
+				"id":         1,
+				"schemaType": "PROTOBUF",
+				"schema":     personSchema,
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code":40401,"message":"Subject not found."}`))
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeProtobuf
+	conf.Protobuf.Operator = "from_json"
+	conf.Protobuf.Message = "testing.House"
+	conf.Protobuf.SchemaRegistry.Enabled = true
+	conf.Protobuf.SchemaRegistry.URL = server.URL
+	conf.Protobuf.SchemaRegistry.Subject = "house"
+	conf.Protobuf.ReloadInterval = "10ms"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	input := []byte(`{"people":[{"firstName":"daryl"}],"address":"foo"}`)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{input}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Contains(t, msgs[0].Get(0).Metadata().Get(FailFlagKey), "no known field named address")
+
+	atomic.StoreInt32(&houseVersion, 1)
+	assert.Eventually(t, func() bool {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{input}))
+		return msgs[0].Get(0).Metadata().Get(FailFlagKey) == ""
+	}, time.Second*5, time.Millisecond*10)
+
+	conf.Protobuf.SchemaRegistry.Subject = "nope"
+	_, err = New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Subject not found.")
+}
@@ -2,9 +2,12 @@ package processor
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
@@ -24,8 +27,30 @@ Throttles the throughput of a pipeline according to a specified
 ` + "[`rate_limit`](/docs/components/rate_limits/about)" + ` resource. Rate limits are
 shared across components and therefore apply globally to all processing
 pipelines.`,
+		Description: `
+When a ` + "`key`" + ` is specified each message is limited according to the
+limit of its key, where each key has an independent limit of the same size. This
+allows you to, for example, prevent messages of a noisy tenant from throttling
+the messages of all other tenants. Limiting by key is currently supported by the
+` + "[`local`](/docs/components/rate_limits/local)" + ` rate limit, which
+bounds the number of tracked keys with its field ` + "`max_keys`" + `.
+
+When a ` + "`cost`" + ` is specified each message consumes that many units of
+the limit, which allows large messages to consume proportionally more of the
+limit than small ones. Costs are currently supported by the
+` + "[`local`](/docs/components/rate_limits/local)" + ` rate limit.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("resource", "The target [`rate_limit` resource](/docs/components/rate_limits/about)."),
+			docs.FieldAdvanced(
+				"key",
+				"An optional key to limit each message by, where each key is given an independent limit.",
+				`${! json("tenant_id") }`, `${! meta("kafka_key") }`,
+			).SupportsInterpolation(false).AtVersion("3.39.0"),
+			docs.FieldAdvanced(
+				"cost",
+				"An optional integer cost of each message, where each message consumes that many units of the limit. When empty each message has a cost of one.",
+				`${! content().length() }`, `${! json("items").length() }`,
+			).SupportsInterpolation(false).AtVersion("3.39.0"),
 		},
 	}
 }
@@ -35,12 +60,16 @@ pipelines.`,
 // RateLimitConfig contains configuration fields for the RateLimit processor.
 type RateLimitConfig struct {
 	Resource string `json:"resource" yaml:"resource"`
+	Key      string `json:"key" yaml:"key"`
+	Cost     string `json:"cost" yaml:"cost"`
 }
 
 // NewRateLimitConfig returns a RateLimitConfig with default values.
 func NewRateLimitConfig() RateLimitConfig {
 	return RateLimitConfig{
 		Resource: "",
+		Key:      "",
+		Cost:     "",
 	}
 }
 
@@ -49,7 +78,11 @@ func NewRateLimitConfig() RateLimitConfig {
 // RateLimit is a processor that performs an RateLimit request using the message as the
 // request body, and returns the response.
 type RateLimit struct {
-	rl types.RateLimit
+	rl         types.RateLimit
+	rlKeyed    types.RateLimitKeyed
+	rlWeighted types.RateLimitWeighted
+	key        field.Expression
+	cost       field.Expression
 
 	log log.Modular
 
@@ -81,18 +114,67 @@ func NewRateLimit(
 		mBatchSent:   stats.GetCounter("batch.sent"),
 		closeChan:    make(chan struct{}),
 	}
+	if conf.RateLimit.Key != "" {
+		var ok bool
+		if r.rlKeyed, ok = rl.(types.RateLimitKeyed); !ok {
+			return nil, fmt.Errorf("rate limit resource '%v' does not support limiting by key", conf.RateLimit.Resource)
+		}
+		if r.key, err = bloblang.NewField(conf.RateLimit.Key); err != nil {
+			return nil, fmt.Errorf("failed to parse key expression: %v", err)
+		}
+	}
+	if conf.RateLimit.Cost != "" {
+		var ok bool
+		if r.rlWeighted, ok = rl.(types.RateLimitWeighted); !ok {
+			return nil, fmt.Errorf("rate limit resource '%v' does not support costs", conf.RateLimit.Resource)
+		}
+		if r.cost, err = bloblang.NewField(conf.RateLimit.Cost); err != nil {
+			return nil, fmt.Errorf("failed to parse cost expression: %v", err)
+		}
+	}
 	return r, nil
 }
 
 //------------------------------------------------------------------------------
 
+func (r *RateLimit) getCost(index int, msg types.Message) int {
+	costStr := r.cost.String(index, msg)
+	cost, err := strconv.Atoi(costStr)
+	if err != nil {
+		r.mErr.Incr(1)
+		r.log.Errorf("Failed to parse cost '%v' as an integer, falling back to a cost of one: %v\n", costStr, err)
+		return 1
+	}
+	return cost
+}
+
 // ProcessMessage applies the processor to a message, either creating >0
 // resulting messages or a response to be sent back to the message source.
 func (r *RateLimit) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	r.mCount.Incr(1)
 
 	msg.Iter(func(i int, p types.Part) error {
-		waitFor, err := r.rl.Access()
+		access := r.rl.Access
+		if r.rlWeighted != nil {
+			cost := r.getCost(i, msg)
+			if r.rlKeyed != nil {
+				key := r.key.String(i, msg)
+				access = func() (time.Duration, error) {
+					return r.rlWeighted.AccessKeyCost(key, cost)
+				}
+			} else {
+				access = func() (time.Duration, error) {
+					return r.rlWeighted.AccessCost(cost)
+				}
+			}
+		} else if r.rlKeyed != nil {
+			key := r.key.String(i, msg)
+			access = func() (time.Duration, error) {
+				return r.rlKeyed.AccessKey(key)
+			}
+		}
+
+		waitFor, err := access()
 		for err != nil || waitFor > 0 {
 			if err == types.ErrTypeClosed {
 				return err
@@ -109,7 +191,7 @@ func (r *RateLimit) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			case <-r.closeChan:
 				return types.ErrTypeClosed
 			}
-			waitFor, err = r.rl.Access()
+			waitFor, err = access()
 		}
 		return err
 	})
@@ -210,3 +210,140 @@ func TestRateLimitBlocked(t *testing.T) {
 		t.Error("Timed out")
 	}
 }
+
+type fakeKeyedRateLimit struct {
+	fakeRateLimit
+	keyFn func(key string) (time.Duration, error)
+}
+
+func (f fakeKeyedRateLimit) AccessKey(key string) (time.Duration, error) {
+	return f.keyFn(key)
+}
+
+func TestRateLimitKeyed(t *testing.T) {
+	var keys []string
+	rl := fakeKeyedRateLimit{
+		fakeRateLimit: fakeRateLimit{resFn: func() (time.Duration, error) {
+			t.Error("unexpected unkeyed access")
+			return 0, nil
+		}},
+		keyFn: func(key string) (time.Duration, error) {
+			keys = append(keys, key)
+			return 0, nil
+		},
+	}
+
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": rl,
+			"bar": fakeRateLimit{},
+		},
+	}
+
+	conf := NewConfig()
+	conf.RateLimit.Resource = "foo"
+	conf.RateLimit.Key = `${! json("key") }`
+	proc, err := NewRateLimit(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"1","value":"foo 1"}`),
+		[]byte(`{"key":"2","value":"foo 2"}`),
+		[]byte(`{"key":"1","value":"foo 3"}`),
+	}))
+	if res != nil {
+		t.Fatal(res.Error())
+	}
+	if len(output) != 1 {
+		t.Fatalf("Wrong count of result messages: %v", len(output))
+	}
+	if exp, act := []string{"1", "2", "1"}, keys; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit keys: %v != %v", act, exp)
+	}
+
+	conf.RateLimit.Resource = "bar"
+	if _, err = NewRateLimit(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from rate limit without key support")
+	}
+}
+
+type fakeWeightedRateLimit struct {
+	fakeKeyedRateLimit
+	costFn func(key string, cost int) (time.Duration, error)
+}
+
+func (f fakeWeightedRateLimit) AccessCost(cost int) (time.Duration, error) {
+	return f.costFn("", cost)
+}
+
+func (f fakeWeightedRateLimit) AccessKeyCost(key string, cost int) (time.Duration, error) {
+	return f.costFn(key, cost)
+}
+
+func TestRateLimitCost(t *testing.T) {
+	var keys []string
+	var costs []int
+	rl := fakeWeightedRateLimit{
+		costFn: func(key string, cost int) (time.Duration, error) {
+			keys = append(keys, key)
+			costs = append(costs, cost)
+			return 0, nil
+		},
+	}
+
+	mgr := &fakeMgr{
+		ratelimits: map[string]types.RateLimit{
+			"foo": rl,
+			"bar": fakeRateLimit{},
+		},
+	}
+
+	conf := NewConfig()
+	conf.RateLimit.Resource = "foo"
+	conf.RateLimit.Cost = `${! content().length() }`
+	proc, err := NewRateLimit(conf, mgr, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`hello world`),
+	})); res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []int{3, 11}, costs; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit costs: %v != %v", act, exp)
+	}
+	if exp, act := []string{"", ""}, keys; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit keys: %v != %v", act, exp)
+	}
+
+	keys, costs = nil, nil
+	conf.RateLimit.Key = `${! json("key") }`
+	conf.RateLimit.Cost = `${! json("cost") }`
+	if proc, err = NewRateLimit(conf, mgr, log.Noop(), metrics.Noop()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`{"key":"a","cost":5}`),
+		[]byte(`{"key":"b","cost":"nope"}`),
+	})); res != nil {
+		t.Fatal(res.Error())
+	}
+	if exp, act := []int{5, 1}, costs; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit costs: %v != %v", act, exp)
+	}
+	if exp, act := []string{"a", "b"}, keys; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong rate limit keys: %v != %v", act, exp)
+	}
+
+	conf.RateLimit.Resource = "bar"
+	conf.RateLimit.Key = ""
+	if _, err = NewRateLimit(conf, mgr, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from rate limit without cost support")
+	}
+}
@@ -0,0 +1,442 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/OneOfOne/xxhash"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRollup] = TypeSpec{
+		constructor: NewRollup,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Accumulates aggregates of messages grouped by a key over a period of time, and
+emits a summary message for each key once the period has passed.`,
+		Description: `
+Periods are aligned to the epoch, and so a ` + "`period`" + ` of ` + "`1m`" + `
+results in a summary for each key at the end of every minute. Since processors
+only execute when messages arrive, the summaries of a period are emitted as a
+batch along with the first batch processed after the period has ended, and any
+summaries of the current period are lost when Benthos shuts down.
+
+By default messages are dropped once they've been aggregated, and only the
+summaries are emitted. Set ` + "`pass_through`" + ` to ` + "`true`" + ` in order
+to also keep the original messages.
+
+Each summary is a JSON object of the following form, containing the key, the
+bounds of the period and a field for each aggregate:
+
+` + "```json" + `
+{
+  "key": "host-1",
+  "period_start": "2020-11-10T10:00:00Z",
+  "period_end": "2020-11-10T10:01:00Z",
+  "requests": 512,
+  "bytes_total": 1.048576e+06
+}
+` + "```" + `
+
+### Aggregates
+
+The following aggregate types are supported, where the ` + "`value`" + ` of an
+aggregate is ignored by ` + "`count`" + `, must be a number for ` + "`sum`" + `,
+` + "`min`" + ` and ` + "`max`" + `, and can be any string for
+` + "`distinct`" + `:
+
+- ` + "`count`" + `: The number of messages.
+- ` + "`sum`" + `: The sum of values.
+- ` + "`min`" + `: The smallest value, or ` + "`null`" + ` if there were none.
+- ` + "`max`" + `: The largest value, or ` + "`null`" + ` if there were none.
+- ` + "`distinct`" + `: An estimate of the number of distinct values, with a standard error of roughly 3%.
+
+Messages with a value that cannot be parsed as a number are skipped by the
+aggregates that require one, and are logged at the debug level.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Downsampling Telemetry",
+				Summary: `
+Here we replace HTTP access logs with a summary per host every minute,
+containing the number of requests, the total and maximum response sizes and an
+estimate of the number of unique clients:`,
+				Config: `
+pipeline:
+  processors:
+    - rollup:
+        key: ${! json("host") }
+        period: 1m
+        aggregates:
+          - name: requests
+            type: count
+          - name: bytes_total
+            type: sum
+            value: ${! json("response.bytes") }
+          - name: bytes_max
+            type: max
+            value: ${! json("response.bytes") }
+          - name: unique_clients
+            type: distinct
+            value: ${! json("client.ip") }
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("key", "An optional key to group aggregates by.", `${! json("host") }`).SupportsInterpolation(false),
+			docs.FieldCommon("period", "The period of time over which to accumulate aggregates."),
+			docs.FieldCommon("aggregates", "A list of [aggregates](#aggregates) to accumulate, each with a `name` of the summary field to write it to, a `type`, and an [interpolated](/docs/configuration/interpolation#bloblang-queries) `value` to aggregate for each message.").HasType(docs.FieldArray),
+			docs.FieldCommon("pass_through", "Whether to emit the original messages as well as the summaries."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RollupAggregateConfig contains configuration fields for an aggregate of the
+// Rollup processor.
+type RollupAggregateConfig struct {
+	Name  string `json:"name" yaml:"name"`
+	Type  string `json:"type" yaml:"type"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// RollupConfig contains configuration fields for the Rollup processor.
+type RollupConfig struct {
+	Key         string                  `json:"key" yaml:"key"`
+	Period      string                  `json:"period" yaml:"period"`
+	Aggregates  []RollupAggregateConfig `json:"aggregates" yaml:"aggregates"`
+	PassThrough bool                    `json:"pass_through" yaml:"pass_through"`
+}
+
+// NewRollupConfig returns a RollupConfig with default values.
+func NewRollupConfig() RollupConfig {
+	return RollupConfig{
+		Key:         "",
+		Period:      "1m",
+		Aggregates:  []RollupAggregateConfig{},
+		PassThrough: false,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// rollupHLLPrecision is the number of bits of each hash used to select a
+// register of a distinct estimate.
+const rollupHLLPrecision = 10
+
+// rollupHLL is a HyperLogLog estimate of the number of distinct values added.
+type rollupHLL [1 << rollupHLLPrecision]uint8
+
+func (h *rollupHLL) add(value string) {
+	hash := xxhash.ChecksumString64(value)
+	idx := hash >> (64 - rollupHLLPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<rollupHLLPrecision|1<<(rollupHLLPrecision-1))) + 1
+	if rank > h[idx] {
+		h[idx] = rank
+	}
+}
+
+func (h *rollupHLL) estimate() int64 {
+	m := float64(len(h))
+	sum, zeros := 0.0, 0
+	for _, r := range h {
+		sum += math.Pow(2, -float64(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := (0.7213 / (1 + 1.079/m)) * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		// Linear counting is more accurate for small cardinalities.
+		est = m * math.Log(m/float64(zeros))
+	}
+	return int64(est + 0.5)
+}
+
+//------------------------------------------------------------------------------
+
+type rollupAggregate struct {
+	name  string
+	typ   string
+	value field.Expression
+}
+
+// rollupState holds the accumulated aggregates of a key, with a value per
+// aggregate.
+type rollupState struct {
+	counts    []int64
+	values    []float64
+	set       []bool
+	distincts []*rollupHLL
+}
+
+// Rollup is a processor that accumulates aggregates of messages over a period
+// of time and emits summaries.
+type Rollup struct {
+	log   log.Modular
+	stats metrics.Type
+
+	key         field.Expression
+	period      time.Duration
+	aggregates  []rollupAggregate
+	passThrough bool
+	nowFn       func() time.Time
+
+	mut         sync.Mutex
+	periodStart time.Time
+	states      map[string]*rollupState
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSummaries metrics.StatCounter
+	mDropped   metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewRollup returns a Rollup processor.
+func NewRollup(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	r := &Rollup{
+		log:         log,
+		stats:       stats,
+		passThrough: conf.Rollup.PassThrough,
+		nowFn:       time.Now,
+		states:      map[string]*rollupState{},
+		mCount:      stats.GetCounter("count"),
+		mErr:        stats.GetCounter("error"),
+		mSummaries:  stats.GetCounter("summaries"),
+		mDropped:    stats.GetCounter("dropped"),
+		mSent:       stats.GetCounter("sent"),
+		mBatchSent:  stats.GetCounter("batch.sent"),
+	}
+
+	var err error
+	if r.key, err = bloblang.NewField(conf.Rollup.Key); err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+	if r.period, err = time.ParseDuration(conf.Rollup.Period); err != nil {
+		return nil, fmt.Errorf("failed to parse period: %v", err)
+	}
+	if r.period <= 0 {
+		return nil, errors.New("period must be greater than zero")
+	}
+
+	if len(conf.Rollup.Aggregates) == 0 {
+		return nil, errors.New("at least one aggregate must be specified")
+	}
+	names := map[string]struct{}{
+		"key":          {},
+		"period_start": {},
+		"period_end":   {},
+	}
+	for i, aConf := range conf.Rollup.Aggregates {
+		if aConf.Name == "" {
+			return nil, fmt.Errorf("aggregate %v must have a name", i)
+		}
+		if _, exists := names[aConf.Name]; exists {
+			return nil, fmt.Errorf("aggregate name '%v' is reserved or already used", aConf.Name)
+		}
+		names[aConf.Name] = struct{}{}
+
+		agg := rollupAggregate{
+			name: aConf.Name,
+			typ:  aConf.Type,
+		}
+		switch aConf.Type {
+		case "count":
+		case "sum", "min", "max", "distinct":
+			if aConf.Value == "" {
+				return nil, fmt.Errorf("aggregate '%v' of type %v must have a value", aConf.Name, aConf.Type)
+			}
+			if agg.value, err = bloblang.NewField(aConf.Value); err != nil {
+				return nil, fmt.Errorf("failed to parse value expression of aggregate '%v': %v", aConf.Name, err)
+			}
+		default:
+			return nil, fmt.Errorf("aggregate '%v' type not recognised: %v", aConf.Name, aConf.Type)
+		}
+		r.aggregates = append(r.aggregates, agg)
+	}
+
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Rollup) newState() *rollupState {
+	n := len(r.aggregates)
+	s := &rollupState{
+		counts:    make([]int64, n),
+		values:    make([]float64, n),
+		set:       make([]bool, n),
+		distincts: make([]*rollupHLL, n),
+	}
+	for i, agg := range r.aggregates {
+		if agg.typ == "distinct" {
+			s.distincts[i] = &rollupHLL{}
+		}
+	}
+	return s
+}
+
+func (r *Rollup) accumulate(index int, msg types.Message) {
+	key := r.key.String(index, msg)
+	state, exists := r.states[key]
+	if !exists {
+		state = r.newState()
+		r.states[key] = state
+	}
+
+	for i, agg := range r.aggregates {
+		switch agg.typ {
+		case "count":
+			state.counts[i]++
+		case "distinct":
+			state.distincts[i].add(agg.value.String(index, msg))
+		default:
+			valueStr := agg.value.String(index, msg)
+			value, err := strconv.ParseFloat(strings.TrimSpace(valueStr), 64)
+			if err != nil {
+				r.mErr.Incr(1)
+				r.log.Debugf("Failed to parse value of aggregate '%v' as number: %v\n", agg.name, err)
+				continue
+			}
+			switch {
+			case !state.set[i]:
+				state.values[i] = value
+			case agg.typ == "sum":
+				state.values[i] += value
+			case agg.typ == "min":
+				state.values[i] = math.Min(state.values[i], value)
+			case agg.typ == "max":
+				state.values[i] = math.Max(state.values[i], value)
+			}
+			state.set[i] = true
+		}
+	}
+}
+
+// flush returns a summary for each key of the current period and resets the
+// aggregates, or nil if there are no summaries.
+func (r *Rollup) flush(periodEnd time.Time) types.Message {
+	if len(r.states) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(r.states))
+	for k := range r.states {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	summaries := message.New(nil)
+	for _, k := range keys {
+		state := r.states[k]
+		summary := map[string]interface{}{
+			"key":          k,
+			"period_start": r.periodStart.UTC().Format(time.RFC3339Nano),
+			"period_end":   periodEnd.UTC().Format(time.RFC3339Nano),
+		}
+		for i, agg := range r.aggregates {
+			switch agg.typ {
+			case "count":
+				summary[agg.name] = state.counts[i]
+			case "distinct":
+				summary[agg.name] = state.distincts[i].estimate()
+			case "sum":
+				summary[agg.name] = state.values[i]
+			default:
+				if state.set[i] {
+					summary[agg.name] = state.values[i]
+				} else {
+					summary[agg.name] = nil
+				}
+			}
+		}
+
+		part := message.NewPart(nil)
+		if err := part.SetJSON(summary); err != nil {
+			r.log.Errorf("Failed to serialise summary: %v\n", err)
+			continue
+		}
+		summaries.Append(part)
+	}
+
+	r.states = map[string]*rollupState{}
+	r.mSummaries.Incr(int64(summaries.Len()))
+	return summaries
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (r *Rollup) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mCount.Incr(1)
+
+	r.mut.Lock()
+	var summaries types.Message
+	now := r.nowFn()
+	if r.periodStart.IsZero() {
+		r.periodStart = now.Truncate(r.period)
+	} else if !now.Before(r.periodStart.Add(r.period)) {
+		summaries = r.flush(r.periodStart.Add(r.period))
+		r.periodStart = now.Truncate(r.period)
+	}
+	for i := 0; i < msg.Len(); i++ {
+		r.accumulate(i, msg)
+	}
+	r.mut.Unlock()
+
+	var msgs []types.Message
+	if summaries != nil {
+		msgs = append(msgs, summaries)
+	}
+	if r.passThrough {
+		msgs = append(msgs, msg)
+	} else {
+		r.mDropped.Incr(int64(msg.Len()))
+	}
+	if len(msgs) == 0 {
+		return nil, response.NewAck()
+	}
+
+	r.mBatchSent.Incr(int64(len(msgs)))
+	for _, m := range msgs {
+		r.mSent.Incr(int64(m.Len()))
+	}
+	return msgs, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (r *Rollup) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (r *Rollup) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
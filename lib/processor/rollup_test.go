@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRollup(t *testing.T, conf RollupConfig, now *time.Time) *Rollup {
+	t.Helper()
+
+	pConf := NewConfig()
+	pConf.Type = TypeRollup
+	pConf.Rollup = conf
+
+	proc, err := New(pConf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	r, ok := proc.(*Rollup)
+	require.True(t, ok)
+	r.nowFn = func() time.Time { return *now }
+	return r
+}
+
+func TestRollup(t *testing.T) {
+	conf := NewRollupConfig()
+	conf.Key = `${! json("host") }`
+	conf.Aggregates = []RollupAggregateConfig{
+		{Name: "requests", Type: "count"},
+		{Name: "bytes_total", Type: "sum", Value: `${! json("bytes") }`},
+		{Name: "bytes_min", Type: "min", Value: `${! json("bytes") }`},
+		{Name: "bytes_max", Type: "max", Value: `${! json("bytes") }`},
+		{Name: "clients", Type: "distinct", Value: `${! json("client") }`},
+	}
+
+	now := time.Unix(1600000000, 0)
+	r := newTestRollup(t, conf, &now)
+
+	msgs, res := r.ProcessMessage(message.New([][]byte{
+		[]byte(`{"host":"a","bytes":10,"client":"x"}`),
+		[]byte(`{"host":"b","bytes":5,"client":"x"}`),
+		[]byte(`{"host":"a","bytes":30,"client":"y"}`),
+	}))
+	assert.Empty(t, msgs)
+	require.NotNil(t, res)
+	require.NoError(t, res.Error())
+
+	now = now.Add(time.Second * 10)
+	msgs, res = r.ProcessMessage(message.New([][]byte{
+		[]byte(`{"host":"a","bytes":"nope","client":"x"}`),
+	}))
+	assert.Empty(t, msgs)
+	require.NotNil(t, res)
+
+	now = now.Add(time.Minute)
+	msgs, res = r.ProcessMessage(message.New([][]byte{
+		[]byte(`{"host":"b","client":"z"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	start := time.Unix(1600000000, 0).Truncate(time.Minute).UTC()
+	period := fmt.Sprintf(`"period_end":"%v","period_start":"%v"`,
+		start.Add(time.Minute).Format(time.RFC3339Nano), start.Format(time.RFC3339Nano))
+
+	assert.Equal(t, [][]byte{
+		[]byte(`{"bytes_max":30,"bytes_min":10,"bytes_total":40,"clients":2,"key":"a",` + period + `,"requests":3}`),
+		[]byte(`{"bytes_max":5,"bytes_min":5,"bytes_total":5,"clients":1,"key":"b",` + period + `,"requests":1}`),
+	}, message.GetAllBytes(msgs[0]))
+
+	now = now.Add(time.Minute)
+	msgs, res = r.ProcessMessage(message.New([][]byte{
+		[]byte(`{"host":"a","bytes":1,"client":"x"}`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	start = start.Add(time.Minute)
+	period = fmt.Sprintf(`"period_end":"%v","period_start":"%v"`,
+		start.Add(time.Minute).Format(time.RFC3339Nano), start.Format(time.RFC3339Nano))
+
+	assert.Equal(t, [][]byte{
+		[]byte(`{"bytes_max":null,"bytes_min":null,"bytes_total":0,"clients":1,"key":"b",` + period + `,"requests":1}`),
+	}, message.GetAllBytes(msgs[0]))
+}
+
+func TestRollupPassThrough(t *testing.T) {
+	conf := NewRollupConfig()
+	conf.PassThrough = true
+	conf.Period = "10s"
+	conf.Aggregates = []RollupAggregateConfig{
+		{Name: "count", Type: "count"},
+	}
+
+	now := time.Unix(1600000000, 0)
+	r := newTestRollup(t, conf, &now)
+
+	msgs, res := r.ProcessMessage(message.New([][]byte{[]byte(`foo`), []byte(`bar`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{[]byte(`foo`), []byte(`bar`)}, message.GetAllBytes(msgs[0]))
+
+	now = now.Add(time.Second * 10)
+	msgs, res = r.ProcessMessage(message.New([][]byte{[]byte(`baz`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 2)
+
+	start := time.Unix(1600000000, 0).UTC()
+	assert.Equal(t, fmt.Sprintf(
+		`{"count":2,"key":"","period_end":"%v","period_start":"%v"}`,
+		start.Add(time.Second*10).Format(time.RFC3339Nano), start.Format(time.RFC3339Nano),
+	), string(msgs[0].Get(0).Get()))
+	assert.Equal(t, [][]byte{[]byte(`baz`)}, message.GetAllBytes(msgs[1]))
+}
+
+func TestRollupDistinctEstimate(t *testing.T) {
+	for _, n := range []int{10, 1000, 50000} {
+		h := &rollupHLL{}
+		for i := 0; i < n; i++ {
+			h.add(fmt.Sprintf("value-%v", i))
+			h.add(fmt.Sprintf("value-%v", i))
+		}
+		assert.InEpsilon(t, n, h.estimate(), 0.1, n)
+	}
+}
+
+func TestRollupBadConfig(t *testing.T) {
+	tests := map[string]func(c *RollupConfig){
+		"no aggregates": func(c *RollupConfig) {
+			c.Aggregates = nil
+		},
+		"bad period": func(c *RollupConfig) {
+			c.Period = "nope"
+		},
+		"zero period": func(c *RollupConfig) {
+			c.Period = "0s"
+		},
+		"bad type": func(c *RollupConfig) {
+			c.Aggregates[0].Type = "nope"
+		},
+		"no name": func(c *RollupConfig) {
+			c.Aggregates[0].Name = ""
+		},
+		"reserved name": func(c *RollupConfig) {
+			c.Aggregates[0].Name = "key"
+		},
+		"duplicate name": func(c *RollupConfig) {
+			c.Aggregates = append(c.Aggregates, c.Aggregates[0])
+		},
+		"sum without value": func(c *RollupConfig) {
+			c.Aggregates[0].Type = "sum"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeRollup
+		conf.Rollup.Aggregates = []RollupAggregateConfig{
+			{Name: "count", Type: "count"},
+		}
+		fn(&conf.Rollup)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
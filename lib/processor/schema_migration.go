@@ -0,0 +1,286 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/parser"
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeSchemaMigration] = TypeSpec{
+		constructor: NewSchemaMigration,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryMapping,
+		},
+		Summary: `
+Migrates messages from the schema version they were produced with to a target
+version by executing a chain of [Bloblang](/docs/guides/bloblang/about)
+migration mappings.`,
+		Description: `
+Each migration maps messages from one version of a schema to another, and the
+version of each message is resolved with the interpolated field ` + "`version`" + `.
+Messages are then migrated through each version in turn until the
+` + "`target`" + ` version is reached, which allows producers and consumers
+of a schema to be upgraded independently.
+
+Migrations are expected to update the version recorded within a message
+themselves. Messages that are already at the target version are left unchanged,
+and messages at a version that isn't covered by a migration fail, which can be
+detected with [processor error handling](/docs/configuration/error_handling).
+When any migration of a chain fails the message is left unchanged.
+
+Every migration must lead to the target version, either directly or through
+other migrations, otherwise the processor fails to start.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Upgrading User Documents",
+				Summary: `
+Here we upgrade user documents to version 3, where version 2 split the field
+` + "`name`" + ` into first and last names and version 3 moved them into an
+object:`,
+				Config: `
+pipeline:
+  processors:
+    - schema_migration:
+        version: ${! json("version") }
+        target: "3"
+        migrations:
+          - from: "1"
+            to: "2"
+            mapping: |
+              root = this.without("name")
+              root.first_name = this.name.split(" ").index(0)
+              root.last_name = this.name.split(" ").index(1)
+              root.version = 2
+          - from: "2"
+            to: "3"
+            mapping: |
+              root = this.without("first_name", "last_name")
+              root.name.first = this.first_name
+              root.name.last = this.last_name
+              root.version = 3
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("version", "The schema version of each message.").SupportsInterpolation(false),
+			docs.FieldCommon("target", "The schema version to migrate messages to."),
+			docs.FieldCommon("migrations", "A list of migrations between versions.").HasType(docs.FieldArray).WithChildren(
+				docs.FieldCommon("from", "The version that the migration maps messages from.").HasDefault(""),
+				docs.FieldCommon("to", "The version that the migration maps messages to.").HasDefault(""),
+				docs.FieldCommon("mapping", "A [Bloblang mapping](/docs/guides/bloblang/about) that maps messages from the `from` version to the `to` version.").HasDefault(""),
+			),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaMigrationStepConfig contains configuration fields for a single
+// migration between two schema versions.
+type SchemaMigrationStepConfig struct {
+	From    string `json:"from" yaml:"from"`
+	To      string `json:"to" yaml:"to"`
+	Mapping string `json:"mapping" yaml:"mapping"`
+}
+
+// NewSchemaMigrationStepConfig returns a SchemaMigrationStepConfig with default
+// values.
+func NewSchemaMigrationStepConfig() SchemaMigrationStepConfig {
+	return SchemaMigrationStepConfig{
+		From:    "",
+		To:      "",
+		Mapping: "",
+	}
+}
+
+// SchemaMigrationConfig contains configuration fields for the SchemaMigration
+// processor.
+type SchemaMigrationConfig struct {
+	Version    string                      `json:"version" yaml:"version"`
+	Target     string                      `json:"target" yaml:"target"`
+	Migrations []SchemaMigrationStepConfig `json:"migrations" yaml:"migrations"`
+}
+
+// NewSchemaMigrationConfig returns a SchemaMigrationConfig with default values.
+func NewSchemaMigrationConfig() SchemaMigrationConfig {
+	return SchemaMigrationConfig{
+		Version:    "",
+		Target:     "",
+		Migrations: []SchemaMigrationStepConfig{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaMigration is a processor that migrates messages between versions of a
+// schema with Bloblang mappings.
+type SchemaMigration struct {
+	log   log.Modular
+	stats metrics.Type
+
+	version field.Expression
+	target  string
+	chains  map[string][]*mapping.Executor
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mMigrated  metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewSchemaMigration returns a SchemaMigration processor.
+func NewSchemaMigration(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.SchemaMigration.Version == "" {
+		return nil, errors.New("a version must be specified")
+	}
+	if conf.SchemaMigration.Target == "" {
+		return nil, errors.New("a target version must be specified")
+	}
+
+	version, err := bloblang.NewField(conf.SchemaMigration.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse version expression: %v", err)
+	}
+
+	type step struct {
+		to   string
+		exec *mapping.Executor
+	}
+	steps := map[string]step{}
+	for i, mConf := range conf.SchemaMigration.Migrations {
+		if mConf.From == "" || mConf.To == "" {
+			return nil, fmt.Errorf("migration %v must specify both from and to versions", i)
+		}
+		if mConf.From == conf.SchemaMigration.Target {
+			return nil, fmt.Errorf("migration %v maps from the target version '%v'", i, mConf.From)
+		}
+		if _, exists := steps[mConf.From]; exists {
+			return nil, fmt.Errorf("multiple migrations from version '%v'", mConf.From)
+		}
+		exec, err := bloblang.NewMapping("", mConf.Mapping)
+		if err != nil {
+			if perr, ok := err.(*parser.Error); ok {
+				return nil, fmt.Errorf("failed to parse migration %v mapping: %v", i, perr.ErrorAtPosition([]rune(mConf.Mapping)))
+			}
+			return nil, fmt.Errorf("failed to parse migration %v mapping: %v", i, err)
+		}
+		steps[mConf.From] = step{to: mConf.To, exec: exec}
+	}
+
+	chains := make(map[string][]*mapping.Executor, len(steps))
+	for from := range steps {
+		var chain []*mapping.Executor
+		for v := from; v != conf.SchemaMigration.Target; {
+			s, exists := steps[v]
+			if !exists {
+				return nil, fmt.Errorf("no migration path from version '%v' to target version '%v'", from, conf.SchemaMigration.Target)
+			}
+			if len(chain) == len(steps) {
+				return nil, fmt.Errorf("migrations from version '%v' contain a cycle", from)
+			}
+			chain = append(chain, s.exec)
+			v = s.to
+		}
+		chains[from] = chain
+	}
+
+	return &SchemaMigration{
+		log:        log,
+		stats:      stats,
+		version:    version,
+		target:     conf.SchemaMigration.Target,
+		chains:     chains,
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mMigrated:  stats.GetCounter("migrated"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (s *SchemaMigration) migrate(chain []*mapping.Executor, index int, msg types.Message) (types.Part, error) {
+	for i, exec := range chain {
+		p, err := exec.MapPart(index, msg)
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, fmt.Errorf("migration %v of the chain deleted the message", i)
+		}
+		msg, index = message.New(nil), 0
+		msg.Append(p)
+	}
+	return msg.Get(0), nil
+}
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *SchemaMigration) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		version := s.version.String(index, msg)
+		if version == s.target {
+			return nil
+		}
+
+		chain, exists := s.chains[version]
+		if !exists {
+			s.mErr.Incr(1)
+			err := fmt.Errorf("no migration from version '%v'", version)
+			s.log.Debugf("Failed to migrate message: %v\n", err)
+			return err
+		}
+
+		p, err := s.migrate(chain, index, msg)
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to migrate message from version '%v': %v\n", version, err)
+			return err
+		}
+
+		s.mMigrated.Incr(1)
+		part.Set(p.Get())
+		part.SetMetadata(p.Metadata())
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeSchemaMigration, nil, newMsg, proc)
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *SchemaMigration) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *SchemaMigration) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSchemaMigrationConfig() Config {
+	conf := NewConfig()
+	conf.Type = TypeSchemaMigration
+	conf.SchemaMigration.Version = `${! json("v") }`
+	conf.SchemaMigration.Target = "3"
+	conf.SchemaMigration.Migrations = []SchemaMigrationStepConfig{
+		{From: "1", To: "2", Mapping: `root = this
+root.v = 2
+root.b = this.a`},
+		{From: "2", To: "3", Mapping: `root = this.without("a")
+root.v = 3
+meta migrated = "true"`},
+		{From: "0", To: "1", Mapping: `root = if this.fail == true { throw("nope") } else { this }
+root.v = 1`},
+	}
+	return conf
+}
+
+func TestSchemaMigration(t *testing.T) {
+	proc, err := New(testSchemaMigrationConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"v":1,"a":"foo"}`),
+		[]byte(`{"v":2,"a":"foo","b":"bar"}`),
+		[]byte(`{"v":3,"b":"baz"}`),
+		[]byte(`{"v":0,"a":"foo"}`),
+	})
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i := 0; i < msgs[0].Len(); i++ {
+		require.False(t, HasFailed(msgs[0].Get(i)), GetFail(msgs[0].Get(i)))
+	}
+	assert.Equal(t, [][]byte{
+		[]byte(`{"b":"foo","v":3}`),
+		[]byte(`{"b":"bar","v":3}`),
+		[]byte(`{"v":3,"b":"baz"}`),
+		[]byte(`{"b":"foo","v":3}`),
+	}, message.GetAllBytes(msgs[0]))
+
+	assert.Equal(t, "true", msgs[0].Get(0).Metadata().Get("migrated"))
+	assert.Equal(t, "", msgs[0].Get(2).Metadata().Get("migrated"))
+}
+
+func TestSchemaMigrationErrors(t *testing.T) {
+	proc, err := New(testSchemaMigrationConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"v":4}`),
+		[]byte(`{"v":0,"fail":true}`),
+	})
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, message.GetAllBytes(input), message.GetAllBytes(msgs[0]))
+
+	assert.Contains(t, GetFail(msgs[0].Get(0)), "no migration from version '4'")
+	assert.Contains(t, GetFail(msgs[0].Get(1)), "nope")
+}
+
+func TestSchemaMigrationBadConfig(t *testing.T) {
+	tests := map[string]func(c *SchemaMigrationConfig){
+		"no version": func(c *SchemaMigrationConfig) {
+			c.Version = ""
+		},
+		"no target": func(c *SchemaMigrationConfig) {
+			c.Target = ""
+		},
+		"bad version": func(c *SchemaMigrationConfig) {
+			c.Version = "${! json( }"
+		},
+		"bad mapping": func(c *SchemaMigrationConfig) {
+			c.Migrations[0].Mapping = "this is not bloblang"
+		},
+		"missing to": func(c *SchemaMigrationConfig) {
+			c.Migrations[0].To = ""
+		},
+		"duplicate from": func(c *SchemaMigrationConfig) {
+			c.Migrations[1].From = "1"
+		},
+		"from target": func(c *SchemaMigrationConfig) {
+			c.Migrations[1].From = "3"
+		},
+		"no path": func(c *SchemaMigrationConfig) {
+			c.Migrations[1].To = "4"
+		},
+		"cycle": func(c *SchemaMigrationConfig) {
+			c.Migrations[1].To = "0"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := testSchemaMigrationConfig()
+		fn(&conf.SchemaMigration)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,243 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/util/http/auth"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+)
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryConfig contains configuration fields for connecting to a
+// Confluent compatible schema registry.
+type SchemaRegistryConfig struct {
+	URL       string               `json:"url" yaml:"url"`
+	BasicAuth auth.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	TLS       btls.Config          `json:"tls" yaml:"tls"`
+	Timeout   string               `json:"timeout" yaml:"timeout"`
+}
+
+// NewSchemaRegistryConfig returns a SchemaRegistryConfig with default values.
+func NewSchemaRegistryConfig() SchemaRegistryConfig {
+	return SchemaRegistryConfig{
+		URL:       "",
+		BasicAuth: auth.NewBasicAuthConfig(),
+		TLS:       btls.NewConfig(),
+		Timeout:   "5s",
+	}
+}
+
+func schemaRegistryFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldCommon("url", "The base URL of the schema registry service.", "http://localhost:8081"),
+		auth.BasicAuthFieldSpec(),
+		btls.FieldSpec(),
+		docs.FieldAdvanced("timeout", "The maximum period of time to wait for requests to the schema registry."),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type schemaRegistryReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+type schemaRegistrySchema struct {
+	ID         int                       `json:"id"`
+	Subject    string                    `json:"subject"`
+	Version    int                       `json:"version"`
+	Schema     string                    `json:"schema"`
+	SchemaType string                    `json:"schemaType"`
+	References []schemaRegistryReference `json:"references"`
+}
+
+type schemaRegistryClient struct {
+	baseURL *url.URL
+	auth    auth.BasicAuthConfig
+	client  *http.Client
+}
+
+func newSchemaRegistryClient(conf SchemaRegistryConfig) (*schemaRegistryClient, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a schema registry url must be specified")
+	}
+	baseURL, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema registry url: %w", err)
+	}
+
+	var timeout time.Duration
+	if conf.Timeout != "" {
+		if timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse schema registry timeout: %w", err)
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if conf.TLS.Enabled {
+		tlsConf, err := conf.TLS.Get()
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConf,
+		}
+	}
+	return &schemaRegistryClient{
+		baseURL: baseURL,
+		auth:    conf.BasicAuth,
+		client:  client,
+	}, nil
+}
+
+func (c *schemaRegistryClient) do(ctx context.Context, method string, reqPath string, body []byte, v interface{}) error {
+	reqURL := *c.baseURL
+	reqURL.Path = path.Join(reqURL.Path, reqPath)
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, reqURL.String(), bodyReader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+	if err = c.auth.Sign(req); err != nil {
+		return err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var resErr struct {
+			Message string `json:"message"`
+		}
+		if jErr := json.Unmarshal(resBody, &resErr); jErr == nil && resErr.Message != "" {
+			return fmt.Errorf("schema registry request %v returned status code %v: %v", reqPath, res.StatusCode, resErr.Message)
+		}
+		return fmt.Errorf("schema registry request %v returned status code %v", reqPath, res.StatusCode)
+	}
+	return json.Unmarshal(resBody, v)
+}
+
+// GetSchemaBySubjectVersion obtains a schema by its subject and version, where
+// the version can be either a number or "latest".
+func (c *schemaRegistryClient) GetSchemaBySubjectVersion(ctx context.Context, subject, version string) (*schemaRegistrySchema, error) {
+	if version == "" {
+		version = "latest"
+	}
+	var s schemaRegistrySchema
+	if err := c.do(ctx, "GET", path.Join("subjects", subject, "versions", version), nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// WalkReferences calls a closure for the schema of each reference of a schema,
+// recursively, where each reference is visited once.
+func (c *schemaRegistryClient) WalkReferences(ctx context.Context, refs []schemaRegistryReference, fn func(name string, s *schemaRegistrySchema) error) error {
+	seen := map[string]struct{}{}
+	var walk func(refs []schemaRegistryReference) error
+	walk = func(refs []schemaRegistryReference) error {
+		for _, ref := range refs {
+			if _, exists := seen[ref.Name]; exists {
+				continue
+			}
+			seen[ref.Name] = struct{}{}
+
+			s, err := c.GetSchemaBySubjectVersion(ctx, ref.Subject, strconv.Itoa(ref.Version))
+			if err != nil {
+				return fmt.Errorf("failed to resolve reference '%v': %w", ref.Name, err)
+			}
+			if err = walk(s.References); err != nil {
+				return err
+			}
+			if err = fn(ref.Name, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(refs)
+}
+
+// GetSchemaByID obtains a schema by its globally unique ID.
+func (c *schemaRegistryClient) GetSchemaByID(ctx context.Context, id int) (*schemaRegistrySchema, error) {
+	var s schemaRegistrySchema
+	if err := c.do(ctx, "GET", path.Join("schemas", "ids", strconv.Itoa(id)), nil, &s); err != nil {
+		return nil, err
+	}
+	s.ID = id
+	return &s, nil
+}
+
+func schemaRegistryRequestBody(schema, schemaType string) ([]byte, error) {
+	body := map[string]interface{}{
+		"schema": schema,
+	}
+	// The registry assumes AVRO when the type is omitted, and older versions
+	// reject the field entirely.
+	if schemaType != "" && schemaType != "AVRO" {
+		body["schemaType"] = schemaType
+	}
+	return json.Marshal(body)
+}
+
+// RegisterSchema registers a schema under a subject, returning its ID. If the
+// schema is already registered under the subject the existing ID is returned.
+func (c *schemaRegistryClient) RegisterSchema(ctx context.Context, subject, schema, schemaType string) (int, error) {
+	body, err := schemaRegistryRequestBody(schema, schemaType)
+	if err != nil {
+		return 0, err
+	}
+	var res struct {
+		ID int `json:"id"`
+	}
+	if err = c.do(ctx, "POST", path.Join("subjects", subject, "versions"), body, &res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+// LookupSchema obtains the ID of a schema that is registered under a subject.
+func (c *schemaRegistryClient) LookupSchema(ctx context.Context, subject, schema, schemaType string) (int, error) {
+	body, err := schemaRegistryRequestBody(schema, schemaType)
+	if err != nil {
+		return 0, err
+	}
+	var res schemaRegistrySchema
+	if err = c.do(ctx, "POST", path.Join("subjects", subject), body, &res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+//------------------------------------------------------------------------------
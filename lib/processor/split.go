@@ -1,8 +1,12 @@
 package processor
 
 import (
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/internal/bloblang"
+	"github.com/Jeffail/benthos/v3/internal/bloblang/mapping"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/message"
@@ -22,13 +26,53 @@ func init() {
 		Summary: `
 Breaks message batches (synonymous with multiple part messages) into smaller batches. The size of the resulting batches are determined either by a discrete size or, if the field ` + "`byte_size`" + ` is non-zero, then by total size in bytes (which ever limit is reached first).`,
 		Description: `
-This processor is for breaking batches down into smaller ones. In order to break a single message out into multiple messages use the ` + "[`unarchive` processor](/docs/components/processors/unarchive)" + `.
+This processor is for breaking batches down into smaller ones. In order to break a single message out into multiple messages either use the ` + "`mapping`" + ` field or the ` + "[`unarchive` processor](/docs/components/processors/unarchive)" + `.
 
-If there is a remainder of messages after splitting a batch the remainder is also sent as a single batch. For example, if your target size was 10, and the processor received a batch of 95 message parts, the result would be 9 batches of 10 messages followed by a batch of 5 messages.`,
+If there is a remainder of messages after splitting a batch the remainder is also sent as a single batch. For example, if your target size was 10, and the processor received a batch of 95 message parts, the result would be 9 batches of 10 messages followed by a batch of 5 messages.
+
+### Splitting Messages
+
+When a ` + "`mapping`" + ` is specified it is executed on each message of a batch and must result in an array, where each element of the array becomes a new message in place of the original before the batch is broken down. String and binary elements become the raw contents of a new message and any other value is serialised as JSON. Each new message inherits the metadata of the original message, including any metadata set by the mapping, and the metadata fields ` + "`split_index`" + ` and ` + "`split_count`" + ` are added with the index of the element and the total number of elements respectively.
+
+Messages where the mapping fails or doesn't result in an array are left unchanged and flagged as having failed, and messages that are deleted by the mapping are removed.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Splitting JSON Arrays",
+				Summary: `
+Here we break a document containing an array of events out into a message per
+event, with each message enriched with the ID of the document it came from:`,
+				Config: `
+pipeline:
+  processors:
+    - split:
+        mapping: |
+          root = this.events.map_each(event -> event.merge({"batch_id": this.id}))
+`,
+			},
+			{
+				Title: "Record Aligned Byte Size",
+				Summary: `
+A message containing newline delimited records can be broken into batches of
+at most 1MB without dividing any records by splitting it into a message per
+record and then limiting the size of batches. The batches can then be archived
+back into single messages:`,
+				Config: `
+pipeline:
+  processors:
+    - split:
+        mapping: 'root = content().string().split("\n")'
+        size: 0
+        byte_size: 1000000
+    - archive:
+        format: lines
+`,
+			},
+		},
 		UsesBatches: true,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("size", "The target number of messages."),
 			docs.FieldCommon("byte_size", "An optional target of total message bytes."),
+			docs.FieldCommon("mapping", "An optional [Bloblang mapping](/docs/guides/bloblang/about) that splits each message into an array of new messages.", `root = this.events`).AtVersion("3.39.0"),
 		},
 	}
 }
@@ -38,8 +82,9 @@ If there is a remainder of messages after splitting a batch the remainder is als
 // SplitConfig is a configuration struct containing fields for the Split
 // processor, which breaks message batches down into batches of a smaller size.
 type SplitConfig struct {
-	Size     int `json:"size" yaml:"size"`
-	ByteSize int `json:"byte_size" yaml:"byte_size"`
+	Size     int    `json:"size" yaml:"size"`
+	ByteSize int    `json:"byte_size" yaml:"byte_size"`
+	Mapping  string `json:"mapping" yaml:"mapping"`
 }
 
 // NewSplitConfig returns a SplitConfig with default values.
@@ -47,6 +92,7 @@ func NewSplitConfig() SplitConfig {
 	return SplitConfig{
 		Size:     1,
 		ByteSize: 0,
+		Mapping:  "",
 	}
 }
 
@@ -59,8 +105,10 @@ type Split struct {
 
 	size     int
 	byteSize int
+	mapping  *mapping.Executor
 
 	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
 	mDropped   metrics.StatCounter
 	mSent      metrics.StatCounter
 	mBatchSent metrics.StatCounter
@@ -70,7 +118,7 @@ type Split struct {
 func NewSplit(
 	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
 ) (Type, error) {
-	return &Split{
+	s := &Split{
 		log:   log,
 		stats: stats,
 
@@ -78,10 +126,80 @@ func NewSplit(
 		byteSize: conf.Split.ByteSize,
 
 		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
 		mDropped:   stats.GetCounter("dropped"),
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
-	}, nil
+	}
+	if conf.Split.Mapping != "" {
+		var err error
+		if s.mapping, err = bloblang.NewMapping("", conf.Split.Mapping); err != nil {
+			return nil, fmt.Errorf("failed to parse mapping: %v", err)
+		}
+	}
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// splitParts executes the mapping on each message of a batch and returns the
+// resulting messages.
+func (s *Split) splitParts(msg types.Message) []types.Part {
+	var parts []types.Part
+	msg.Iter(func(i int, p types.Part) error {
+		children, err := s.splitPart(i, msg)
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to split message: %v\n", err)
+			p = p.Copy()
+			FlagErr(p, err)
+			parts = append(parts, p)
+			return nil
+		}
+		parts = append(parts, children...)
+		return nil
+	})
+	return parts
+}
+
+func (s *Split) splitPart(index int, msg types.Message) ([]types.Part, error) {
+	mapped, err := s.mapping.MapPart(index, msg)
+	if err != nil {
+		return nil, err
+	}
+	if mapped == nil {
+		return nil, nil
+	}
+
+	jObj, err := mapped.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping result: %v", err)
+	}
+	elements, ok := jObj.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected mapping to result in an array, found %T", jObj)
+	}
+
+	children := make([]types.Part, 0, len(elements))
+	for i, e := range elements {
+		child := message.NewPart(nil)
+		switch t := e.(type) {
+		case string:
+			child.Set([]byte(t))
+		case []byte:
+			child.Set(t)
+		default:
+			if err := child.SetJSON(t); err != nil {
+				return nil, fmt.Errorf("failed to set element %v: %v", i, err)
+			}
+		}
+		child.SetMetadata(mapped.Metadata().Copy())
+		child.Metadata().
+			Set("split_index", strconv.Itoa(i)).
+			Set("split_count", strconv.Itoa(len(elements)))
+		children = append(children, child)
+	}
+	return children, nil
 }
 
 //------------------------------------------------------------------------------
@@ -96,6 +214,16 @@ func (s *Split) ProcessMessage(msg types.Message) ([]types.Message, types.Respon
 		return nil, response.NewAck()
 	}
 
+	if s.mapping != nil {
+		parts := s.splitParts(msg)
+		if len(parts) == 0 {
+			s.mDropped.Incr(1)
+			return nil, response.NewAck()
+		}
+		msg = message.New(nil)
+		msg.SetAll(parts)
+	}
+
 	msgs := []types.Message{}
 
 	nextMsg := message.New(nil)
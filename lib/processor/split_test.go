@@ -7,6 +7,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSplitToSingleParts(t *testing.T) {
@@ -170,3 +172,93 @@ func TestSplitByBytesTooLarge(t *testing.T) {
 		t.Errorf("Wrong contents: %v != %v", act, exp)
 	}
 }
+
+func TestSplitMapping(t *testing.T) {
+	conf := NewConfig()
+	conf.Split.Size = 2
+	conf.Split.Mapping = `
+meta batch_id = this.id
+root = this.events.map_each(e -> if e.type == "drop" { deleted() } else { e })
+`
+
+	proc, err := NewSplit(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	inMsg := message.New([][]byte{
+		[]byte(`{"id":"a","events":[{"type":"foo"},"bar",10]}`),
+		[]byte(`{"id":"b","events":[]}`),
+		[]byte(`{"id":"c","events":[{"type":"baz"}]}`),
+		[]byte(`{"id":"d","events":"nope"}`),
+	})
+	inMsg.Get(0).Metadata().Set("foo", "bar")
+
+	msgs, res := proc.ProcessMessage(inMsg)
+	require.Nil(t, res)
+	require.Len(t, msgs, 3)
+
+	assert.Equal(t, [][]byte{
+		[]byte(`{"type":"foo"}`),
+		[]byte(`bar`),
+	}, message.GetAllBytes(msgs[0]))
+	assert.Equal(t, [][]byte{
+		[]byte(`10`),
+		[]byte(`{"type":"baz"}`),
+	}, message.GetAllBytes(msgs[1]))
+	assert.Equal(t, [][]byte{
+		[]byte(`{"id":"d","events":"nope"}`),
+	}, message.GetAllBytes(msgs[2]))
+
+	for i, exp := range []map[string]string{
+		{"foo": "bar", "batch_id": "a", "split_index": "0", "split_count": "3"},
+		{"foo": "bar", "batch_id": "a", "split_index": "1", "split_count": "3"},
+		{"foo": "bar", "batch_id": "a", "split_index": "2", "split_count": "3"},
+		{"batch_id": "c", "split_index": "0", "split_count": "1"},
+	} {
+		act := map[string]string{}
+		msgs[i/2].Get(i % 2).Metadata().Iter(func(k, v string) error {
+			act[k] = v
+			return nil
+		})
+		assert.Equal(t, exp, act, i)
+	}
+
+	assert.Contains(t, GetFail(msgs[2].Get(0)), "expected array value")
+}
+
+func TestSplitMappingByteSize(t *testing.T) {
+	conf := NewConfig()
+	conf.Split.Size = 0
+	conf.Split.ByteSize = 10
+	conf.Split.Mapping = `root = content().string().split("\n")`
+
+	proc, err := NewSplit(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte("foo\nbar\nbaz\nbuz\nthis is too big\nqux"),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 4)
+
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar"), []byte("baz")}, message.GetAllBytes(msgs[0]))
+	assert.Equal(t, [][]byte{[]byte("buz")}, message.GetAllBytes(msgs[1]))
+	assert.Equal(t, [][]byte{[]byte("this is too big")}, message.GetAllBytes(msgs[2]))
+	assert.Equal(t, [][]byte{[]byte("qux")}, message.GetAllBytes(msgs[3]))
+}
+
+func TestSplitMappingDeleted(t *testing.T) {
+	conf := NewConfig()
+	conf.Split.Mapping = `root = deleted()`
+
+	proc, err := NewSplit(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`foo`)}))
+	assert.Empty(t, msgs)
+	require.NotNil(t, res)
+	assert.NoError(t, res.Error())
+
+	conf.Split.Mapping = `root = this.`
+	_, err = NewSplit(conf, nil, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
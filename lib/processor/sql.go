@@ -11,9 +11,12 @@ import (
 	"github.com/Jeffail/benthos/v3/internal/bloblang/field"
 	"github.com/Jeffail/benthos/v3/internal/docs"
 	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/opentracing/opentracing-go"
+	olog "github.com/opentracing/opentracing-go/log"
 
 	// SQL Drivers
 	_ "github.com/ClickHouse/clickhouse-go"
@@ -116,6 +119,10 @@ pipeline:
 				"result_codec",
 				"A [codec](#result-codecs) to determine how resulting rows are converted into messages.",
 			).HasOptions("none", "json_array"),
+			docs.FieldAdvanced(
+				"batch_transaction",
+				"Whether to execute the queries of all messages within a batch in a single transaction. When enabled a failure of any query rolls back the entire transaction and the batch is rejected, which causes it to be reprocessed according to the delivery guarantees of the input.",
+			).AtVersion("3.39.0"),
 		},
 		Footnotes: `
 ## Result Codecs
@@ -146,6 +153,7 @@ type SQLConfig struct {
 	Query          string   `json:"query" yaml:"query"`
 	Args           []string `json:"args" yaml:"args"`
 	ResultCodec    string   `json:"result_codec" yaml:"result_codec"`
+	BatchTx        bool     `json:"batch_transaction" yaml:"batch_transaction"`
 }
 
 // NewSQLConfig returns a SQLConfig with default values.
@@ -157,6 +165,7 @@ func NewSQLConfig() SQLConfig {
 		Query:          "",
 		Args:           []string{},
 		ResultCodec:    "none",
+		BatchTx:        false,
 	}
 }
 
@@ -241,6 +250,9 @@ func NewSQL(
 		if conf.SQL.Driver != "mysql" && conf.SQL.Driver != "postgres" {
 			return nil, fmt.Errorf("driver '%v' is not supported with deprecated SQL features (using field 'dsn')", conf.SQL.Driver)
 		}
+		if conf.SQL.BatchTx {
+			return nil, errors.New("batch_transaction is not supported with deprecated SQL features (using field 'dsn')")
+		}
 		if s.resCodecDeprecated, err = strToSQLResultCodecDeprecated(conf.SQL.ResultCodec); err != nil {
 			return nil, err
 		}
@@ -376,6 +388,79 @@ func (s *SQL) doExecute(argSets [][]interface{}) (errs []error) {
 	return
 }
 
+func (s *SQL) queryInto(stmt *sql.Stmt, args []interface{}, part types.Part) error {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %v", err)
+	}
+	defer rows.Close()
+	if err = s.resCodec(rows, part); err != nil {
+		return fmt.Errorf("failed to apply result codec: %v", err)
+	}
+	return nil
+}
+
+// doTransaction executes the queries of all messages of a batch within a single
+// transaction, which is rolled back if any query fails.
+func (s *SQL) doTransaction(msg, newMsg types.Message) (err error) {
+	var tx *sql.Tx
+	if tx, err = s.db.Begin(); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			if rerr := tx.Rollback(); rerr != nil && rerr != sql.ErrTxDone {
+				s.log.Errorf("Failed to roll back transaction: %v\n", rerr)
+			}
+		}
+	}()
+
+	stmt := s.query
+	if stmt == nil {
+		if stmt, err = tx.Prepare(s.conf.Query); err != nil {
+			return
+		}
+		defer stmt.Close()
+	} else {
+		stmt = tx.Stmt(stmt)
+	}
+
+	spans := tracing.CreateChildSpans(TypeSQL, newMsg)
+	defer func() {
+		for _, span := range spans {
+			if err != nil {
+				span.SetTag("error", true)
+				span.LogFields(
+					olog.String("event", "error"),
+					olog.String("type", err.Error()),
+				)
+			}
+			span.Finish()
+		}
+	}()
+
+	for index := 0; index < newMsg.Len(); index++ {
+		args := make([]interface{}, len(s.args))
+		for i, v := range s.args {
+			args[i] = v.String(index, msg)
+		}
+		if s.resCodec == nil {
+			if _, err = stmt.Exec(args...); err != nil {
+				err = fmt.Errorf("failed to execute query for message %v: %v", index, err)
+				return
+			}
+			continue
+		}
+		if err = s.queryInto(stmt, args, newMsg.Get(index)); err != nil {
+			err = fmt.Errorf("message %v: %v", index, err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
 // ProcessMessage logs an event and returns the message unchanged.
 func (s *SQL) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	s.dbMux.RLock()
@@ -388,7 +473,13 @@ func (s *SQL) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 	s.mCount.Incr(1)
 	newMsg := msg.Copy()
 
-	if s.resCodec == nil {
+	if s.conf.BatchTx {
+		if err := s.doTransaction(msg, newMsg); err != nil {
+			s.mErr.Incr(1)
+			s.log.Errorf("SQL transaction failed: %v\n", err)
+			return nil, response.NewError(fmt.Errorf("transaction rolled back: %w", err))
+		}
+	} else if s.resCodec == nil {
 		argSets := make([][]interface{}, newMsg.Len())
 		newMsg.Iter(func(index int, p types.Part) error {
 			args := make([]interface{}, len(s.args))
@@ -412,16 +503,7 @@ func (s *SQL) ProcessMessage(msg types.Message) ([]types.Message, types.Response
 			for i, v := range s.args {
 				args[i] = v.String(index, msg)
 			}
-			rows, err := s.query.Query(args...)
-			if err == nil {
-				defer rows.Close()
-				if err = s.resCodec(rows, part); err != nil {
-					err = fmt.Errorf("failed to apply result codec: %v", err)
-				}
-			} else {
-				err = fmt.Errorf("failed to execute query: %v", err)
-			}
-			if err != nil {
+			if err := s.queryInto(s.query, args, part); err != nil {
 				s.mErr.Incr(1)
 				s.log.Debugf("SQL error: %v\n", err)
 				return err
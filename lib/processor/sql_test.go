@@ -170,6 +170,9 @@ func TestSQLPostgresIntegration(t *testing.T) {
 	t.Run("testSQLPostgresDeprecated", func(t *testing.T) {
 		testSQLPostgresDeprecated(t, dsn)
 	})
+	t.Run("testSQLPostgresBatchTransaction", func(t *testing.T) {
+		testSQLPostgresBatchTransaction(t, dsn)
+	})
 }
 
 func testSQLPostgres(t *testing.T, dsn string) {
@@ -285,6 +288,58 @@ func testSQLPostgresDeprecated(t *testing.T, dsn string) {
 	}
 }
 
+func testSQLPostgresBatchTransaction(t *testing.T, dsn string) {
+	conf := NewConfig()
+	conf.Type = TypeSQL
+	conf.SQL.Driver = "postgres"
+	conf.SQL.DataSourceName = dsn
+	conf.SQL.BatchTx = true
+	conf.SQL.Query = "INSERT INTO footable (foo, bar, baz) VALUES ($1, $2, $3);"
+	conf.SQL.Args = []string{
+		"${! json(\"foo\") }",
+		"${! json(\"bar\") }",
+		"${! json(\"baz\") }",
+	}
+
+	s, err := NewSQL(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	// The second insert violates the primary key and so the first is rolled
+	// back with it.
+	resMsgs, res := s.ProcessMessage(message.New([][]byte{
+		[]byte(`{"foo":"foo5","bar":"bar5","baz":"baz5"}`),
+		[]byte(`{"foo":"foo5","bar":"bar6","baz":"baz6"}`),
+	}))
+	require.NotNil(t, res)
+	require.Error(t, res.Error())
+	require.Len(t, resMsgs, 0)
+
+	parts := [][]byte{
+		[]byte(`{"foo":"foo5","bar":"bar5","baz":"baz5"}`),
+		[]byte(`{"foo":"foo6","bar":"bar6","baz":"baz6"}`),
+	}
+	resMsgs, res = s.ProcessMessage(message.New(parts))
+	require.Nil(t, res)
+	require.Len(t, resMsgs, 1)
+	assert.Equal(t, parts, message.GetAllBytes(resMsgs[0]))
+
+	conf.SQL.Query = "SELECT bar FROM footable WHERE foo = $1;"
+	conf.SQL.Args = []string{
+		"${! json(\"foo\") }",
+	}
+	conf.SQL.ResultCodec = "json_array"
+	s, err = NewSQL(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	resMsgs, res = s.ProcessMessage(message.New(parts))
+	require.Nil(t, res)
+	require.Len(t, resMsgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`[{"bar":"bar5"}]`),
+		[]byte(`[{"bar":"bar6"}]`),
+	}, message.GetAllBytes(resMsgs[0]))
+}
+
 func TestSQLMySQLIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
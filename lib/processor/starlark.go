@@ -0,0 +1,270 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message/metadata"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeStarlark] = TypeSpec{
+		constructor: NewStarlark,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryMapping,
+		},
+		Summary: `
+Executes a [Starlark](https://github.com/bazelbuild/starlark) program for each
+message within a sandbox with limits on execution.`,
+		Description: `
+Starlark is a dialect of Python designed to be deterministic and hermetic,
+programs have no access to the file system, network or clock, and are limited
+in the number of computation steps and the length of time that each execution
+may take. The dialect of Starlark used also forbids ` + "`while`" + ` loops and
+recursion. This makes it suitable for running transformations supplied by
+untrusted parties.
+
+The program must define a function ` + "`process`" + ` which is called for
+each message with the contents of the message as a string and its metadata as a
+dictionary of strings. Changes made to the metadata dictionary are applied to
+the message, and the contents of the message are replaced by the return value
+of the function, where strings are used verbatim, ` + "`None`" + ` leaves the
+contents unchanged and any other value is serialised as JSON.
+
+The module ` + "`json`" + `, which provides the functions
+` + "`json.encode`" + ` and ` + "`json.decode`" + `, is available to
+programs, and messages written with ` + "`print`" + ` are sent to the Benthos
+logger at the debug level. Global variables are frozen once the top level of a
+program has executed, and therefore state is not carried between messages.
+
+When the function fails, either by calling ` + "`fail`" + ` or by exceeding
+the limits of execution, the message is left unchanged and flagged as having
+failed, which allows you to handle it with
+[error handling patterns](/docs/configuration/error_handling).`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Field Manipulation",
+				Summary: `
+Here we lowercase the field ` + "`name`" + ` and add a metadata key based on
+the number of items of an order:`,
+				Config: `
+pipeline:
+  processors:
+    - starlark:
+        code: |
+          def process(content, meta):
+            doc = json.decode(content)
+            doc["name"] = doc["name"].lower()
+            meta["order_size"] = "large" if len(doc["items"]) > 10 else "small"
+            return doc
+`,
+			},
+		},
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("code", "An inline Starlark program to execute. Either `code` or `file` must be specified."),
+			docs.FieldCommon("file", "A path to a file containing a Starlark program to execute. Either `code` or `file` must be specified."),
+			docs.FieldAdvanced("max_steps", "The maximum number of computation steps that an execution may take, or zero for no limit."),
+			docs.FieldAdvanced("timeout", "The maximum length of time that an execution may take, or empty for no limit."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// StarlarkConfig contains configuration fields for the Starlark processor.
+type StarlarkConfig struct {
+	Code     string `json:"code" yaml:"code"`
+	File     string `json:"file" yaml:"file"`
+	MaxSteps uint64 `json:"max_steps" yaml:"max_steps"`
+	Timeout  string `json:"timeout" yaml:"timeout"`
+}
+
+// NewStarlarkConfig returns a StarlarkConfig with default values.
+func NewStarlarkConfig() StarlarkConfig {
+	return StarlarkConfig{
+		Code:     "",
+		File:     "",
+		MaxSteps: 1000000,
+		Timeout:  "1s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Starlark is a processor that executes a Starlark program for each message.
+type Starlark struct {
+	log   log.Modular
+	stats metrics.Type
+
+	process  starlark.Callable
+	maxSteps uint64
+	timeout  time.Duration
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewStarlark returns a Starlark processor.
+func NewStarlark(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	code, name := conf.Starlark.Code, "main.star"
+	if conf.Starlark.File != "" {
+		if code != "" {
+			return nil, errors.New("only one of code or file can be specified")
+		}
+		codeBytes, err := ioutil.ReadFile(conf.Starlark.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+		code, name = string(codeBytes), conf.Starlark.File
+	}
+	if code == "" {
+		return nil, errors.New("either code or file must be specified")
+	}
+
+	s := &Starlark{
+		log:        log,
+		stats:      stats,
+		maxSteps:   conf.Starlark.MaxSteps,
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if conf.Starlark.Timeout != "" {
+		var err error
+		if s.timeout, err = time.ParseDuration(conf.Starlark.Timeout); err != nil {
+			return nil, fmt.Errorf("failed to parse timeout: %v", err)
+		}
+	}
+
+	var globals starlark.StringDict
+	err := s.exec(func(thread *starlark.Thread) (err error) {
+		globals, err = starlark.ExecFile(thread, name, code, starlark.StringDict{
+			"json": starlarkjson.Module,
+		})
+		return
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute program: %v", err)
+	}
+
+	var ok bool
+	if s.process, ok = globals["process"].(starlark.Callable); !ok {
+		return nil, errors.New("program does not define a function process")
+	}
+	return s, nil
+}
+
+// exec calls a function with a thread that enforces the configured limits of
+// execution.
+func (s *Starlark) exec(fn func(thread *starlark.Thread) error) error {
+	thread := &starlark.Thread{
+		Name: TypeStarlark,
+		Print: func(_ *starlark.Thread, msg string) {
+			s.log.Debugln(msg)
+		},
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, errors.New("loading modules is not permitted")
+		},
+	}
+	if s.maxSteps > 0 {
+		thread.SetMaxExecutionSteps(s.maxSteps)
+	}
+	if s.timeout > 0 {
+		timer := time.AfterFunc(s.timeout, func() {
+			thread.Cancel("timed out")
+		})
+		defer timer.Stop()
+	}
+	return fn(thread)
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (s *Starlark) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	s.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		meta := starlark.NewDict(0)
+		part.Metadata().Iter(func(k, v string) error {
+			return meta.SetKey(starlark.String(k), starlark.String(v))
+		})
+
+		var res starlark.Value
+		err := s.exec(func(thread *starlark.Thread) (err error) {
+			if res, err = starlark.Call(thread, s.process, starlark.Tuple{
+				starlark.String(part.Get()), meta,
+			}, nil); err != nil {
+				return
+			}
+			switch res.(type) {
+			case starlark.NoneType, starlark.String:
+			default:
+				res, err = starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{res}, nil)
+			}
+			return
+		})
+
+		var newMeta map[string]string
+		if err == nil {
+			newMeta = map[string]string{}
+			for _, item := range meta.Items() {
+				k, kOk := starlark.AsString(item[0])
+				v, vOk := starlark.AsString(item[1])
+				if !kOk || !vOk {
+					err = fmt.Errorf("expected metadata of type string, found key %v and value %v", item[0].Type(), item[1].Type())
+					break
+				}
+				newMeta[k] = v
+			}
+		}
+		if err != nil {
+			s.mErr.Incr(1)
+			s.log.Debugf("Failed to execute program: %v\n", err)
+			return err
+		}
+
+		if str, ok := res.(starlark.String); ok {
+			part.Set([]byte(str))
+		}
+		part.SetMetadata(metadata.New(newMeta))
+		return nil
+	}
+
+	IteratePartsWithSpan(TypeStarlark, nil, newMsg, proc)
+
+	s.mBatchSent.Incr(1)
+	s.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (s *Starlark) CloseAsync() {
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (s *Starlark) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStarlark(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeStarlark
+	conf.Starlark.Code = `
+def process(content, meta):
+  if content == "raw":
+    return None
+  if content == "text":
+    return "replaced"
+  doc = json.decode(content)
+  doc["name"] = doc["name"].upper()
+  meta["original"] = content
+  meta.pop("remove", None)
+  return doc
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	input := message.New([][]byte{
+		[]byte(`{"name":"foo"}`),
+		[]byte(`raw`),
+		[]byte(`text`),
+	})
+	input.Get(0).Metadata().Set("remove", "me").Set("keep", "me")
+
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i := 0; i < msgs[0].Len(); i++ {
+		require.False(t, HasFailed(msgs[0].Get(i)), GetFail(msgs[0].Get(i)))
+	}
+	assert.Equal(t, [][]byte{
+		[]byte(`{"name":"FOO"}`),
+		[]byte(`raw`),
+		[]byte(`replaced`),
+	}, message.GetAllBytes(msgs[0]))
+
+	meta := msgs[0].Get(0).Metadata()
+	assert.Equal(t, `{"name":"foo"}`, meta.Get("original"))
+	assert.Equal(t, "me", meta.Get("keep"))
+	assert.Equal(t, "", meta.Get("remove"))
+	assert.Equal(t, "me", input.Get(0).Metadata().Get("remove"))
+}
+
+func TestStarlarkErrors(t *testing.T) {
+	tests := map[string]struct {
+		code string
+		err  string
+	}{
+		"fail": {
+			code: `
+def process(content, meta):
+  meta["foo"] = "bar"
+  fail("nope")
+`,
+			err: "fail: nope",
+		},
+		"bad metadata": {
+			code: `
+def process(content, meta):
+  meta["foo"] = 10
+  return "changed"
+`,
+			err: "expected metadata of type string, found key string and value int",
+		},
+		"too many steps": {
+			code: `
+def process(content, meta):
+  for i in range(10000000):
+    pass
+`,
+			err: "too many steps",
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		conf.Type = TypeStarlark
+		conf.Starlark.Code = test.code
+		conf.Starlark.MaxSteps = 1000
+
+		proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err, name)
+
+		msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`hello world`)}))
+		require.Nil(t, res, name)
+		require.Len(t, msgs, 1, name)
+
+		part := msgs[0].Get(0)
+		assert.Equal(t, `hello world`, string(part.Get()), name)
+		assert.Equal(t, "", part.Metadata().Get("foo"), name)
+		assert.True(t, HasFailed(part), name)
+		assert.Contains(t, GetFail(part), test.err, name)
+	}
+}
+
+func TestStarlarkTimeout(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeStarlark
+	conf.Starlark.MaxSteps = 0
+	conf.Starlark.Timeout = "10ms"
+	conf.Starlark.Code = `
+def process(content, meta):
+  while True:
+    pass
+`
+
+	_, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err, "while loops are disallowed by default")
+
+	conf.Starlark.Code = `
+def process(content, meta):
+  for i in range(1000000000):
+    pass
+`
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`hello world`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Contains(t, GetFail(msgs[0].Get(0)), "timed out")
+}
+
+func TestStarlarkBadConfig(t *testing.T) {
+	tests := map[string]func(c *StarlarkConfig){
+		"no code or file": func(c *StarlarkConfig) {
+			c.Code = ""
+		},
+		"code and file": func(c *StarlarkConfig) {
+			c.File = "main.star"
+		},
+		"missing file": func(c *StarlarkConfig) {
+			c.Code = ""
+			c.File = "/does/not/exist.star"
+		},
+		"bad syntax": func(c *StarlarkConfig) {
+			c.Code = "this is not starlark"
+		},
+		"no process function": func(c *StarlarkConfig) {
+			c.Code = `foo = "bar"`
+		},
+		"load": func(c *StarlarkConfig) {
+			c.Code = "load('foo.star', 'bar')\n" + c.Code
+		},
+		"bad timeout": func(c *StarlarkConfig) {
+			c.Timeout = "nope"
+		},
+		"top level steps": func(c *StarlarkConfig) {
+			c.MaxSteps = 10
+			c.Code = "x = [i for i in range(1000)]\n" + c.Code
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeStarlark
+		conf.Starlark.Code = "def process(content, meta):\n  return content\n"
+		fn(&conf.Starlark)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
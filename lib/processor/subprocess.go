@@ -19,6 +19,8 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/retries"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/opentracing/opentracing-go"
 )
 
@@ -43,7 +45,9 @@ The field ` + "`max_buffer`" + ` defines the maximum response size able to be re
 
 ## Subprocess requirements
 
-It is required that subprocesses flush their stdout and stderr pipes for each line. Benthos will attempt to keep the process alive for as long as the pipeline is running. If the process exits early it will be restarted.
+It is required that subprocesses flush their stdout and stderr pipes for each line. Benthos will attempt to keep the process alive for as long as the pipeline is running. If the process exits early it will be restarted according to the field ` + "`restart_policy`" + `, where restarts are delayed by an exponential backoff that is reset once the process successfully processes a message.
+
+Messages are sent to a subprocess one at a time, and therefore in order to process messages in parallel the field ` + "`max_in_flight`" + ` can be used in order to run multiple instances of the subprocess, where the messages of a batch are distributed amongst them.
 
 ## Messages containing line breaks
 
@@ -58,6 +62,18 @@ If a message contains line breaks each line of the message is piped to the subpr
 			docs.FieldAdvanced(
 				"codec_recv", "Determines how messages read from the subprocess are decoded, which allows them to be logically separated.",
 			).HasOptions("lines", "length_prefixed_uint32_be", "netstring").AtVersion("3.37.0"),
+			docs.FieldAdvanced("max_in_flight", "The number of instances of the subprocess to run, which is the maximum number of messages processed in parallel.").AtVersion("3.39.0"),
+			docs.FieldAdvanced("restart_policy", "Determines whether the subprocess is restarted after it exits.").HasAnnotatedOptions(
+				"always", "Always restart the subprocess.",
+				"on_failure", "Only restart the subprocess when it exits with an error.",
+				"never", "Never restart the subprocess, messages sent after it exits will fail.",
+			).AtVersion("3.39.0"),
+			docs.FieldAdvanced("restart_backoff", "Control time intervals between restarts of the subprocess.").WithChildren(
+				docs.FieldAdvanced("initial_interval", "The initial period to wait before restarting the subprocess."),
+				docs.FieldAdvanced("max_interval", "The maximum period to wait before restarting the subprocess."),
+				docs.FieldAdvanced("max_elapsed_time", "The maximum period of consecutive restarts after which restarts are abandoned. If zero then no limit is used."),
+			).AtVersion("3.39.0"),
+			docs.FieldAdvanced("log_stderr", "Whether output written by the subprocess to stderr should be logged rather than treated as an error response to a message.").AtVersion("3.39.0"),
 			partsFieldSpec,
 		},
 	}
@@ -67,23 +83,35 @@ If a message contains line breaks each line of the message is piped to the subpr
 
 // SubprocessConfig contains configuration fields for the Subprocess processor.
 type SubprocessConfig struct {
-	Parts     []int    `json:"parts" yaml:"parts"`
-	Name      string   `json:"name" yaml:"name"`
-	Args      []string `json:"args" yaml:"args"`
-	MaxBuffer int      `json:"max_buffer" yaml:"max_buffer"`
-	CodecSend string   `json:"codec_send" yaml:"codec_send"`
-	CodecRecv string   `json:"codec_recv" yaml:"codec_recv"`
+	Parts          []int           `json:"parts" yaml:"parts"`
+	Name           string          `json:"name" yaml:"name"`
+	Args           []string        `json:"args" yaml:"args"`
+	MaxBuffer      int             `json:"max_buffer" yaml:"max_buffer"`
+	CodecSend      string          `json:"codec_send" yaml:"codec_send"`
+	CodecRecv      string          `json:"codec_recv" yaml:"codec_recv"`
+	MaxInFlight    int             `json:"max_in_flight" yaml:"max_in_flight"`
+	RestartPolicy  string          `json:"restart_policy" yaml:"restart_policy"`
+	RestartBackoff retries.Backoff `json:"restart_backoff" yaml:"restart_backoff"`
+	LogStderr      bool            `json:"log_stderr" yaml:"log_stderr"`
 }
 
 // NewSubprocessConfig returns a SubprocessConfig with default values.
 func NewSubprocessConfig() SubprocessConfig {
 	return SubprocessConfig{
-		Parts:     []int{},
-		Name:      "cat",
-		Args:      []string{},
-		MaxBuffer: bufio.MaxScanTokenSize,
-		CodecSend: "lines",
-		CodecRecv: "lines",
+		Parts:         []int{},
+		Name:          "cat",
+		Args:          []string{},
+		MaxBuffer:     bufio.MaxScanTokenSize,
+		CodecSend:     "lines",
+		CodecRecv:     "lines",
+		MaxInFlight:   1,
+		RestartPolicy: "always",
+		RestartBackoff: retries.Backoff{
+			InitialInterval: "100ms",
+			MaxInterval:     "10s",
+			MaxElapsedTime:  "0s",
+		},
+		LogStderr: false,
 	}
 }
 
@@ -97,9 +125,9 @@ type Subprocess struct {
 	stats metrics.Type
 
 	conf     SubprocessConfig
-	subproc  *subprocWrapper
-	procFunc func(index int, span opentracing.Span, part types.Part) error
-	mut      sync.Mutex
+	subprocs []*subprocWrapper
+	pool     chan *subprocWrapper
+	procFunc func(s *subprocWrapper, part types.Part) error
 
 	mCount     metrics.StatCounter
 	mErr       metrics.StatCounter
@@ -126,29 +154,48 @@ func newSubprocess(
 		mSent:      stats.GetCounter("sent"),
 		mBatchSent: stats.GetCounter("batch.sent"),
 	}
-	var err error
-	if e.subproc, err = newSubprocWrapper(conf.Name, conf.Args, e.conf.MaxBuffer, conf.CodecRecv, log); err != nil {
+	if conf.MaxInFlight < 1 {
+		return nil, fmt.Errorf("max_in_flight must be at least one, received: %v", conf.MaxInFlight)
+	}
+	switch conf.RestartPolicy {
+	case "always", "on_failure", "never":
+	default:
+		return nil, fmt.Errorf("unrecognized restart_policy value: %v", conf.RestartPolicy)
+	}
+	boffCtor, err := (&retries.Config{Backoff: conf.RestartBackoff}).GetCtor()
+	if err != nil {
 		return nil, err
 	}
 	if e.procFunc, err = e.getSendSubprocessorFunc(conf.CodecSend); err != nil {
 		return nil, err
 	}
+
+	e.pool = make(chan *subprocWrapper, conf.MaxInFlight)
+	for i := 0; i < conf.MaxInFlight; i++ {
+		s, err := newSubprocWrapper(conf, boffCtor, log)
+		if err != nil {
+			e.CloseAsync()
+			return nil, err
+		}
+		e.subprocs = append(e.subprocs, s)
+		e.pool <- s
+	}
 	return e, nil
 }
 
 //------------------------------------------------------------------------------
 
-func (e *Subprocess) getSendSubprocessorFunc(codec string) (func(index int, span opentracing.Span, part types.Part) error, error) {
+func (e *Subprocess) getSendSubprocessorFunc(codec string) (func(s *subprocWrapper, part types.Part) error, error) {
 	switch codec {
 	case "length_prefixed_uint32_be":
-		return func(_ int, _ opentracing.Span, part types.Part) error {
+		return func(s *subprocWrapper, part types.Part) error {
 			const prefixBytes int = 4
 
 			lenBuf := make([]byte, prefixBytes)
 			m := part.Get()
 			binary.BigEndian.PutUint32(lenBuf, uint32(len(m)))
 
-			res, err := e.subproc.Send(lenBuf, m, nil)
+			res, err := s.Send(lenBuf, m, nil)
 			if err != nil {
 				e.log.Errorf("Failed to send message to subprocess: %v\n", err)
 				e.mErr.Incr(1)
@@ -158,11 +205,11 @@ func (e *Subprocess) getSendSubprocessorFunc(codec string) (func(index int, span
 			return nil
 		}, nil
 	case "netstring":
-		return func(_ int, _ opentracing.Span, part types.Part) error {
+		return func(s *subprocWrapper, part types.Part) error {
 			lenBuf := make([]byte, 0)
 			m := part.Get()
 			lenBuf = append(strconv.AppendUint(lenBuf, uint64(len(m)), 10), ':')
-			res, err := e.subproc.Send(lenBuf, m, commaBytes)
+			res, err := s.Send(lenBuf, m, commaBytes)
 			if err != nil {
 				e.log.Errorf("Failed to send message to subprocess: %v\n", err)
 				e.mErr.Incr(1)
@@ -172,7 +219,7 @@ func (e *Subprocess) getSendSubprocessorFunc(codec string) (func(index int, span
 			return nil
 		}, nil
 	case "lines":
-		return func(_ int, _ opentracing.Span, part types.Part) error {
+		return func(s *subprocWrapper, part types.Part) error {
 			results := [][]byte{}
 			splitMsg := bytes.Split(part.Get(), newLineBytes)
 			for j, p := range splitMsg {
@@ -180,7 +227,7 @@ func (e *Subprocess) getSendSubprocessorFunc(codec string) (func(index int, span
 					results = append(results, []byte(""))
 					continue
 				}
-				res, err := e.subproc.Send(nil, p, newLineBytes)
+				res, err := s.Send(nil, p, newLineBytes)
 				if err != nil {
 					e.log.Errorf("Failed to send message to subprocess: %v\n", err)
 					e.mErr.Incr(1)
@@ -196,13 +243,18 @@ func (e *Subprocess) getSendSubprocessorFunc(codec string) (func(index int, span
 }
 
 type subprocWrapper struct {
-	name   string
-	args   []string
-	maxBuf int
+	name      string
+	args      []string
+	maxBuf    int
+	logStderr bool
 
 	splitFunc bufio.SplitFunc
 	logger    log.Modular
 
+	restartPolicy string
+	boff          backoff.BackOff
+	responded     int32
+
 	cmdMut      sync.Mutex
 	cmdExitChan chan struct{}
 	stdoutChan  chan []byte
@@ -216,16 +268,19 @@ type subprocWrapper struct {
 	closedChan chan struct{}
 }
 
-func newSubprocWrapper(name string, args []string, maxBuf int, codecRecv string, log log.Modular) (*subprocWrapper, error) {
+func newSubprocWrapper(conf SubprocessConfig, boffCtor func() backoff.BackOff, log log.Modular) (*subprocWrapper, error) {
 	s := &subprocWrapper{
-		name:       name,
-		args:       args,
-		maxBuf:     maxBuf,
-		logger:     log,
-		closeChan:  make(chan struct{}),
-		closedChan: make(chan struct{}),
-	}
-	switch codecRecv {
+		name:          conf.Name,
+		args:          conf.Args,
+		maxBuf:        conf.MaxBuffer,
+		logStderr:     conf.LogStderr,
+		logger:        log,
+		restartPolicy: conf.RestartPolicy,
+		boff:          boffCtor(),
+		closeChan:     make(chan struct{}),
+		closedChan:    make(chan struct{}),
+	}
+	switch codecRecv := conf.CodecRecv; codecRecv {
 	case "lines":
 		s.splitFunc = bufio.ScanLines
 	case "length_prefixed_uint32_be":
@@ -246,8 +301,12 @@ func newSubprocWrapper(name string, args []string, maxBuf int, codecRecv string,
 		for {
 			select {
 			case <-s.cmdExitChan:
-				log.Warnln("Subprocess exited")
-				s.stop()
+				exitErr := s.stop()
+				if exitErr != nil {
+					log.Warnf("Subprocess exited: %v\n", exitErr)
+				} else {
+					log.Warnln("Subprocess exited")
+				}
 
 				// Flush channels
 				var msgBytes []byte
@@ -265,7 +324,9 @@ func newSubprocWrapper(name string, args []string, maxBuf int, codecRecv string,
 					log.Errorln(string(msgBytes))
 				}
 
-				s.start()
+				if !s.restart(exitErr) {
+					return
+				}
 			case <-s.closeChan:
 				return
 			}
@@ -398,6 +459,10 @@ func (s *subprocWrapper) start() error {
 			scanner.Buffer(nil, s.maxBuf)
 		}
 		for scanner.Scan() {
+			if s.logStderr {
+				s.logger.Errorln(scanner.Text())
+				continue
+			}
 			stderrChan <- scanner.Bytes()
 		}
 		if err := scanner.Err(); err != nil {
@@ -412,12 +477,57 @@ func (s *subprocWrapper) start() error {
 	return nil
 }
 
+// restart attempts to start the subprocess again after it has exited, subject
+// to the restart policy and backoff, and returns false if the subprocess
+// should no longer be restarted.
+func (s *subprocWrapper) restart(exitErr error) bool {
+	switch s.restartPolicy {
+	case "never":
+		s.logger.Warnln("Subprocess will not be restarted")
+		return false
+	case "on_failure":
+		if exitErr == nil {
+			s.logger.Warnln("Subprocess exited successfully and will not be restarted")
+			return false
+		}
+	}
+
+	if atomic.SwapInt32(&s.responded, 0) == 1 {
+		s.boff.Reset()
+	}
+	for {
+		wait := s.boff.NextBackOff()
+		if wait == backoff.Stop {
+			s.logger.Errorln("Abandoning restarts of subprocess after exceeding the maximum elapsed time")
+			return false
+		}
+		select {
+		case <-time.After(wait):
+		case <-s.closeChan:
+			return false
+		}
+		err := s.start()
+		if err == nil {
+			return true
+		}
+		s.logger.Errorf("Failed to restart subprocess: %v\n", err)
+	}
+}
+
 func (s *subprocWrapper) stop() error {
 	s.cmdMut.Lock()
 	var err error
 	if s.cmd != nil {
 		s.cmdCancelFn()
 		err = s.cmd.Wait()
+		if state := s.cmd.ProcessState; state != nil {
+			// The context is cancelled before waiting and so the error
+			// returned by Wait might not reflect how the process exited.
+			err = nil
+			if !state.Success() {
+				err = errors.New(state.String())
+			}
+		}
 		s.cmd = nil
 		s.cmdStdin = nil
 		s.cmdCancelFn = func() {}
@@ -476,6 +586,7 @@ func (s *subprocWrapper) Send(prolog []byte, payload []byte, epilog []byte) ([]b
 	if len(errBytes) > 0 {
 		return nil, errors.New(string(errBytes))
 	}
+	atomic.StoreInt32(&s.responded, 1)
 	return outBytes, nil
 }
 
@@ -487,12 +598,32 @@ var commaBytes = []byte(",")
 // ProcessMessage logs an event and returns the message unchanged.
 func (e *Subprocess) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
 	e.mCount.Incr(1)
-	e.mut.Lock()
-	defer e.mut.Unlock()
 
 	result := msg.Copy()
 
-	IteratePartsWithSpan(TypeSubprocess, e.conf.Parts, result, e.procFunc)
+	indexes := e.conf.Parts
+	if len(indexes) == 0 {
+		indexes = make([]int, result.Len())
+		for i := range indexes {
+			indexes[i] = i
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(indexes))
+	for _, index := range indexes {
+		s := <-e.pool
+		go func(index int) {
+			defer func() {
+				e.pool <- s
+				wg.Done()
+			}()
+			IteratePartsWithSpan(TypeSubprocess, []int{index}, result, func(_ int, _ opentracing.Span, part types.Part) error {
+				return e.procFunc(s, part)
+			})
+		}(index)
+	}
+	wg.Wait()
 
 	e.mSent.Incr(int64(result.Len()))
 	e.mBatchSent.Incr(1)
@@ -504,16 +635,21 @@ func (e *Subprocess) ProcessMessage(msg types.Message) ([]types.Message, types.R
 // CloseAsync shuts down the processor and stops processing requests.
 func (e *Subprocess) CloseAsync() {
 	if atomic.CompareAndSwapInt32(&e.subprocClosed, 0, 1) {
-		close(e.subproc.closeChan)
+		for _, s := range e.subprocs {
+			close(s.closeChan)
+		}
 	}
 }
 
 // WaitForClose blocks until the processor has closed down.
 func (e *Subprocess) WaitForClose(timeout time.Duration) error {
-	select {
-	case <-time.After(timeout):
-		return fmt.Errorf("subprocess failed to close in allotted time: %w", types.ErrTimeout)
-	case <-e.subproc.closedChan:
+	stopBy := time.After(timeout)
+	for _, s := range e.subprocs {
+		select {
+		case <-stopBy:
+			return fmt.Errorf("subprocess failed to close in allotted time: %w", types.ErrTimeout)
+		case <-s.closedChan:
+		}
 	}
 	return nil
 }
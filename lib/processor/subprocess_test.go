@@ -302,3 +302,132 @@ func main() {
 	f("length_prefixed_uint32_be", "netstring", true)
 	f("length_prefixed_uint32_be", "length_prefixed_uint32_be", true)
 }
+
+func TestSubprocessLogStderr(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Name = "sh"
+	conf.Subprocess.Args = []string{"-c", `while read l; do echo "logged $l" 1>&2; echo "$l"; done`}
+	conf.Subprocess.LogStderr = true
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Skipf("Not sure if this is due to missing executable: %v", err)
+	}
+	t.Cleanup(func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	})
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	for i := 0; i < msgs[0].Len(); i++ {
+		assert.False(t, HasFailed(msgs[0].Get(i)), GetFail(msgs[0].Get(i)))
+	}
+	assert.Equal(t, [][]byte{[]byte(`foo`), []byte(`bar`)}, message.GetAllBytes(msgs[0]))
+}
+
+func TestSubprocessRestartPolicy(t *testing.T) {
+	newProc := func(policy string) Type {
+		t.Helper()
+
+		conf := NewConfig()
+		conf.Type = TypeSubprocess
+		conf.Subprocess.Name = "sh"
+		conf.Subprocess.Args = []string{"-c", `read l; echo "$l"`}
+		conf.Subprocess.RestartPolicy = policy
+		conf.Subprocess.RestartBackoff.InitialInterval = "10ms"
+
+		proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+		if err != nil {
+			t.Skipf("Not sure if this is due to missing executable: %v", err)
+		}
+		t.Cleanup(func() {
+			proc.CloseAsync()
+			assert.NoError(t, proc.WaitForClose(time.Second))
+		})
+		return proc
+	}
+
+	succeeds := func(proc Type) bool {
+		msgs, _ := proc.ProcessMessage(message.New([][]byte{[]byte(`foo`)}))
+		return !HasFailed(msgs[0].Get(0)) && string(msgs[0].Get(0).Get()) == "foo"
+	}
+
+	proc := newProc("never")
+	require.True(t, succeeds(proc))
+	<-time.After(time.Millisecond * 100)
+	assert.False(t, succeeds(proc))
+
+	proc = newProc("on_failure")
+	require.True(t, succeeds(proc))
+	<-time.After(time.Millisecond * 100)
+	assert.False(t, succeeds(proc))
+
+	proc = newProc("always")
+	require.True(t, succeeds(proc))
+	assert.Eventually(t, func() bool {
+		return succeeds(proc)
+	}, time.Second*5, time.Millisecond*50)
+}
+
+func TestSubprocessMaxInFlight(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeSubprocess
+	conf.Subprocess.Name = "sh"
+	conf.Subprocess.Args = []string{"-c", `while read l; do sleep 0.5; echo "$l"; done`}
+	conf.Subprocess.MaxInFlight = 4
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Skipf("Not sure if this is due to missing executable: %v", err)
+	}
+	t.Cleanup(func() {
+		proc.CloseAsync()
+		assert.NoError(t, proc.WaitForClose(time.Second))
+	})
+
+	exp := [][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`baz`),
+		[]byte(`buz`),
+	}
+
+	start := time.Now()
+	msgs, res := proc.ProcessMessage(message.New(exp))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, exp, message.GetAllBytes(msgs[0]))
+	assert.Less(t, int64(time.Since(start)), int64(time.Millisecond*1500))
+}
+
+func TestSubprocessBadConfig(t *testing.T) {
+	tests := map[string]func(c *SubprocessConfig){
+		"zero max in flight": func(c *SubprocessConfig) {
+			c.MaxInFlight = 0
+		},
+		"bad restart policy": func(c *SubprocessConfig) {
+			c.RestartPolicy = "nope"
+		},
+		"bad restart backoff": func(c *SubprocessConfig) {
+			c.RestartBackoff.InitialInterval = "nope"
+		},
+		"bad codec": func(c *SubprocessConfig) {
+			c.CodecSend = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeSubprocess
+		fn(&conf.Subprocess)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
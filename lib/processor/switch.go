@@ -325,7 +325,7 @@ func (s *Switch) ProcessMessage(msg types.Message) (msgs []types.Message, res ty
 				var err error
 				if test, err = switchCase.check.QueryPart(j, testMsg); err != nil {
 					test = false
-					s.log.Errorf("Failed to test case %v: %v\n", i, err)
+					log.WithMessageFields(s.log, j, testMsg).Errorf("Failed to test case %v: %v\n", i, err)
 				}
 			}
 			if test {
@@ -40,7 +40,7 @@ field is added to each message called ` + "`archive_filename`" + ` with the
 extracted filename.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("format", "The unarchive [format](#formats) to use.").HasOptions(
-				"tar", "zip", "binary", "lines", "json_documents", "json_array", "json_map",
+				"tar", "zip", "binary", "lines", "json_documents", "json_array", "json_map", "parquet",
 			),
 			partsFieldSpec,
 		},
@@ -83,7 +83,15 @@ own message.
 Attempt to parse the message as a JSON map and for each element of the map
 expands its contents into a new message. A metadata field is added to each
 message called ` + "`archive_key`" + ` with the relevant key from the top-level
-map.`,
+map.
+
+### ` + "`parquet`" + `
+
+Read a message as a [Parquet](https://parquet.apache.org/) file and expand each
+row, across all row groups, into a new message as a JSON object. ` + "`LIST`" + `
+and ` + "`MAP`" + ` columns become JSON arrays and objects respectively. Other
+logical types, such as decimals and ` + "`INT96`" + ` timestamps, are emitted as
+their raw physical values.`,
 	}
 }
 
@@ -278,6 +286,8 @@ func strToUnarchiver(str string) (unarchiveFunc, error) {
 		return jsonArrayUnarchive, nil
 	case "json_map":
 		return jsonMapUnarchive, nil
+	case "parquet":
+		return parquetUnarchive, nil
 	}
 	return nil, fmt.Errorf("archive format not recognised: %v", str)
 }
@@ -359,7 +369,7 @@ func (d *Unarchive) ProcessMessage(msg types.Message) ([]types.Message, types.Re
 			newMsg.Append(newParts...)
 		} else {
 			d.mErr.Incr(1)
-			d.log.Errorf("Failed to unarchive message part: %v\n", err)
+			log.WithMessageFields(d.log, i, msg).Errorf("Failed to unarchive message part: %v\n", err)
 			newMsg.Append(part)
 			FlagErr(newMsg.Get(-1), err)
 			span.LogFields(
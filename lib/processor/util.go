@@ -1,6 +1,8 @@
 package processor
 
 import (
+	"github.com/Jeffail/benthos/v3/lib/event"
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/message/tracing"
 	"github.com/Jeffail/benthos/v3/lib/response"
 	"github.com/Jeffail/benthos/v3/lib/types"
@@ -22,7 +24,7 @@ func ExecuteAll(procs []types.Processor, msgs ...types.Message) ([]types.Message
 		var nextResultMsgs []types.Message
 		for _, m := range resultMsgs {
 			var rMsgs []types.Message
-			if rMsgs, resultRes = procs[i].ProcessMessage(m); resultRes != nil && resultRes.Error() != nil {
+			if rMsgs, resultRes = journey.Execute(procs[i], m); resultRes != nil && resultRes.Error() != nil {
 				// We immediately return if a processor hits an unrecoverable
 				// error on a message.
 				return nil, resultRes
@@ -60,7 +62,7 @@ func ExecuteTryAll(procs []types.Processor, msgs ...types.Message) ([]types.Mess
 				continue
 			}
 			var rMsgs []types.Message
-			if rMsgs, resultRes = procs[i].ProcessMessage(m); resultRes != nil && resultRes.Error() != nil {
+			if rMsgs, resultRes = journey.Execute(procs[i], m); resultRes != nil && resultRes.Error() != nil {
 				// We immediately return if a processor hits an unrecoverable
 				// error on a message.
 				return nil, resultRes
@@ -97,7 +99,7 @@ func ExecuteCatchAll(procs []types.Processor, msgs ...types.Message) ([]types.Me
 				continue
 			}
 			var rMsgs []types.Message
-			if rMsgs, resultRes = procs[i].ProcessMessage(m); resultRes != nil && resultRes.Error() != nil {
+			if rMsgs, resultRes = journey.Execute(procs[i], m); resultRes != nil && resultRes.Error() != nil {
 				// We immediately return if a processor hits an unrecoverable
 				// error on a message.
 				return nil, resultRes
@@ -130,9 +132,12 @@ func FlagFail(part types.Part) {
 
 // FlagErr marks a message part as having failed at a processing step with an
 // error message. If the error is nil the message part remains unchanged.
+//
+// A processor error lifecycle event is also emitted for each flagged error.
 func FlagErr(part types.Part, err error) {
 	if err != nil {
 		part.Metadata().Set(FailFlagKey, err.Error())
+		event.Emit(event.TypeProcessorError, "", err)
 	}
 }
 
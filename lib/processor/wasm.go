@@ -0,0 +1,296 @@
+// +build WASM
+
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/opentracing/opentracing-go"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeWASM] = TypeSpec{
+		constructor: NewWASM,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Categories: []Category{
+			CategoryUtility,
+		},
+		Summary: `
+Executes a function exported by a [WebAssembly](https://webassembly.org/) module
+for each message.`,
+		Description: `
+This allows you to write processors in any language that compiles to
+WebAssembly, such as Rust or TinyGo, without the need to build a custom version
+of Benthos. Modules are executed with [wazero](https://wazero.io/), a runtime
+written in pure Go, and have access to the
+[WASI](https://wasi.dev/) ` + "`wasi_snapshot_preview1`" + ` functions with
+the exception of file system and network access.
+
+The module must export its memory as ` + "`memory`" + `, the function named by
+the field ` + "`function`" + `, which takes no arguments and returns nothing,
+and a function ` + "`allocate`" + ` which takes a size in bytes as an ` + "`i32`" + `
+and returns an ` + "`i32`" + ` pointer to a buffer of that size within the
+memory of the module.
+
+### Host Functions
+
+The message being processed is accessed and modified with the following
+functions imported from the module ` + "`benthos_wasm`" + `:
+
+- ` + "`v0_msg_as_bytes() i64`" + ` copies the contents of the message into a
+  buffer obtained from ` + "`allocate`" + ` and returns the pointer to the
+  buffer in the upper 32 bits and its length in the lower 32 bits.
+- ` + "`v0_msg_set_bytes(ptr i32, len i32)`" + ` replaces the contents of the
+  message.
+- ` + "`v0_msg_get_meta(key_ptr i32, key_len i32) i64`" + ` copies the value
+  of a metadata key into a buffer obtained from ` + "`allocate`" + `, returning
+  the pointer and length as above. Missing keys result in an empty value.
+- ` + "`v0_msg_set_meta(key_ptr i32, key_len i32, value_ptr i32, value_len i32)`" + `
+  sets a metadata key of the message.
+- ` + "`v0_msg_set_error(ptr i32, len i32)`" + ` flags the message as having
+  failed with an error message, which allows you to handle it with
+  [error handling patterns](/docs/configuration/error_handling).
+
+Buffers obtained from ` + "`allocate`" + ` are owned by the module, which is
+responsible for freeing them. Calls to the module are serialised, and therefore
+it's recommended to run multiple pipeline threads in order to process messages
+in parallel.
+
+### Building
+
+The wazero runtime requires Go 1.18 or newer, which is more recent than the
+version required to build Benthos, and therefore this processor is not compiled
+by default. You can build it into your project with the tag:
+
+` + "```sh" + `
+go install -tags "WASM" github.com/Jeffail/benthos/v3/cmd/benthos
+` + "```",
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("module_path", "The path of the WebAssembly module to load."),
+			docs.FieldCommon("function", "The name of the function exported by the module to call for each message."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const wasmHostModule = "benthos_wasm"
+
+type wasmCallKey struct{}
+
+// wasmCall is the state of a single call into the module, which host functions
+// obtain from the context of the call.
+type wasmCall struct {
+	part types.Part
+	err  error
+}
+
+func getWASMCall(ctx context.Context) *wasmCall {
+	call, ok := ctx.Value(wasmCallKey{}).(*wasmCall)
+	if !ok {
+		// Panics within host functions are returned as errors by the call.
+		panic(errors.New("host function called outside of message processing"))
+	}
+	return call
+}
+
+//------------------------------------------------------------------------------
+
+// WASM is a processor that executes a function of a WebAssembly module for
+// each message.
+type WASM struct {
+	log   log.Modular
+	stats metrics.Type
+
+	mut      sync.Mutex
+	runtime  wazero.Runtime
+	allocate api.Function
+	process  api.Function
+
+	mCount     metrics.StatCounter
+	mErr       metrics.StatCounter
+	mSent      metrics.StatCounter
+	mBatchSent metrics.StatCounter
+}
+
+// NewWASM returns a WASM processor.
+func NewWASM(
+	conf Config, mgr types.Manager, log log.Modular, stats metrics.Type,
+) (Type, error) {
+	if conf.WASM.ModulePath == "" {
+		return nil, errors.New("a module_path must be specified")
+	}
+	if conf.WASM.Function == "" {
+		return nil, errors.New("a function must be specified")
+	}
+
+	moduleBytes, err := ioutil.ReadFile(conf.WASM.ModulePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module: %v", err)
+	}
+
+	w := &WASM{
+		log:        log,
+		stats:      stats,
+		mCount:     stats.GetCounter("count"),
+		mErr:       stats.GetCounter("error"),
+		mSent:      stats.GetCounter("sent"),
+		mBatchSent: stats.GetCounter("batch.sent"),
+	}
+	if err = w.init(moduleBytes, conf.WASM.Function); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *WASM) init(moduleBytes []byte, function string) (err error) {
+	ctx := context.Background()
+
+	w.runtime = wazero.NewRuntime(ctx)
+	defer func() {
+		if err != nil {
+			w.runtime.Close(ctx)
+		}
+	}()
+
+	if _, err = wasi_snapshot_preview1.Instantiate(ctx, w.runtime); err != nil {
+		return fmt.Errorf("failed to instantiate WASI: %v", err)
+	}
+
+	if _, err = w.runtime.NewHostModuleBuilder(wasmHostModule).
+		NewFunctionBuilder().WithFunc(w.msgAsBytes).Export("v0_msg_as_bytes").
+		NewFunctionBuilder().WithFunc(w.msgSetBytes).Export("v0_msg_set_bytes").
+		NewFunctionBuilder().WithFunc(w.msgGetMeta).Export("v0_msg_get_meta").
+		NewFunctionBuilder().WithFunc(w.msgSetMeta).Export("v0_msg_set_meta").
+		NewFunctionBuilder().WithFunc(w.msgSetError).Export("v0_msg_set_error").
+		Instantiate(ctx); err != nil {
+		return fmt.Errorf("failed to instantiate host module: %v", err)
+	}
+
+	compiled, err := w.runtime.CompileModule(ctx, moduleBytes)
+	if err != nil {
+		return fmt.Errorf("failed to compile module: %v", err)
+	}
+
+	mod, err := w.runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	if err != nil {
+		return fmt.Errorf("failed to instantiate module: %v", err)
+	}
+
+	if w.allocate = mod.ExportedFunction("allocate"); w.allocate == nil {
+		return errors.New("module does not export an allocate function")
+	}
+	if w.process = mod.ExportedFunction(function); w.process == nil {
+		return fmt.Errorf("module does not export a function '%v'", function)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *WASM) readGuest(m api.Module, ptr, length uint32) []byte {
+	b, ok := m.Memory().Read(ptr, length)
+	if !ok {
+		panic(fmt.Errorf("out of range memory read of %v bytes at %v", length, ptr))
+	}
+	// The returned slice is a view of the memory of the module.
+	return append([]byte(nil), b...)
+}
+
+func (w *WASM) writeGuest(ctx context.Context, m api.Module, b []byte) uint64 {
+	res, err := w.allocate.Call(ctx, uint64(len(b)))
+	if err != nil {
+		panic(fmt.Errorf("failed to allocate %v bytes: %v", len(b), err))
+	}
+	ptr := uint32(res[0])
+	if !m.Memory().Write(ptr, b) {
+		panic(fmt.Errorf("out of range memory write of %v bytes at %v", len(b), ptr))
+	}
+	return uint64(ptr)<<32 | uint64(len(b))
+}
+
+func (w *WASM) msgAsBytes(ctx context.Context, m api.Module) uint64 {
+	return w.writeGuest(ctx, m, getWASMCall(ctx).part.Get())
+}
+
+func (w *WASM) msgSetBytes(ctx context.Context, m api.Module, ptr, length uint32) {
+	getWASMCall(ctx).part.Set(w.readGuest(m, ptr, length))
+}
+
+func (w *WASM) msgGetMeta(ctx context.Context, m api.Module, keyPtr, keyLen uint32) uint64 {
+	key := w.readGuest(m, keyPtr, keyLen)
+	value := getWASMCall(ctx).part.Metadata().Get(string(key))
+	return w.writeGuest(ctx, m, []byte(value))
+}
+
+func (w *WASM) msgSetMeta(ctx context.Context, m api.Module, keyPtr, keyLen, valuePtr, valueLen uint32) {
+	key := w.readGuest(m, keyPtr, keyLen)
+	value := w.readGuest(m, valuePtr, valueLen)
+	getWASMCall(ctx).part.Metadata().Set(string(key), string(value))
+}
+
+func (w *WASM) msgSetError(ctx context.Context, m api.Module, ptr, length uint32) {
+	getWASMCall(ctx).err = errors.New(string(w.readGuest(m, ptr, length)))
+}
+
+//------------------------------------------------------------------------------
+
+// ProcessMessage applies the processor to a message, either creating >0
+// resulting messages or a response to be sent back to the message source.
+func (w *WASM) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	w.mCount.Incr(1)
+	newMsg := msg.Copy()
+
+	proc := func(index int, span opentracing.Span, part types.Part) error {
+		call := &wasmCall{part: part}
+
+		w.mut.Lock()
+		_, err := w.process.Call(context.WithValue(context.Background(), wasmCallKey{}, call))
+		w.mut.Unlock()
+
+		if err == nil {
+			err = call.err
+		} else {
+			w.log.Debugf("Failed to call function: %v\n", err)
+		}
+		if err != nil {
+			w.mErr.Incr(1)
+		}
+		return err
+	}
+
+	IteratePartsWithSpan(TypeWASM, nil, newMsg, proc)
+
+	w.mBatchSent.Incr(1)
+	w.mSent.Incr(int64(newMsg.Len()))
+	return []types.Message{newMsg}, nil
+}
+
+// CloseAsync shuts down the processor and stops processing requests.
+func (w *WASM) CloseAsync() {
+	w.mut.Lock()
+	w.runtime.Close(context.Background())
+	w.mut.Unlock()
+}
+
+// WaitForClose blocks until the processor has closed down.
+func (w *WASM) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,17 @@
+package processor
+
+//------------------------------------------------------------------------------
+
+// WASMConfig contains configuration fields for the WASM processor.
+type WASMConfig struct {
+	ModulePath string `json:"module_path" yaml:"module_path"`
+	Function   string `json:"function" yaml:"function"`
+}
+
+// NewWASMConfig returns a WASMConfig with default values.
+func NewWASMConfig() WASMConfig {
+	return WASMConfig{
+		ModulePath: "",
+		Function:   "process",
+	}
+}
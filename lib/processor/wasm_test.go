@@ -0,0 +1,238 @@
+// +build WASM
+
+package processor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWASMVec encodes a vector of byte sequences prefixed by its length.
+func testWASMVec(items ...[]byte) []byte {
+	b := testWASMULEB(uint32(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func testWASMULEB(v uint32) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+func testWASMName(s string) []byte {
+	return append(testWASMULEB(uint32(len(s))), s...)
+}
+
+func testWASMSection(id byte, contents []byte) []byte {
+	return append(append([]byte{id}, testWASMULEB(uint32(len(contents)))...), contents...)
+}
+
+func testWASMFuncType(params, results []byte) []byte {
+	b := append([]byte{0x60}, testWASMULEB(uint32(len(params)))...)
+	b = append(b, params...)
+	b = append(b, testWASMULEB(uint32(len(results)))...)
+	return append(b, results...)
+}
+
+func testWASMCode(locals []byte, body ...byte) []byte {
+	fn := append(locals, body...)
+	fn = append(fn, 0x0b)
+	return append(testWASMULEB(uint32(len(fn))), fn...)
+}
+
+const (
+	testWASMI32 = 0x7f
+	testWASMI64 = 0x7e
+)
+
+// testWASMModule returns a module that implements the function "process", which
+// copies the message into the metadata key "original" and sets the message to
+// "processed", and the function "fail", which flags the message with an error.
+func testWASMModule() []byte {
+	importFn := func(name string, typeIdx byte) []byte {
+		b := append(testWASMName("benthos_wasm"), testWASMName(name)...)
+		return append(b, 0x00, typeIdx)
+	}
+	exportItem := func(name string, kind, idx byte) []byte {
+		return append(testWASMName(name), kind, idx)
+	}
+
+	data := "originalprocessednope"
+
+	m := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	m = append(m, testWASMSection(1, testWASMVec(
+		testWASMFuncType(nil, []byte{testWASMI64}),                                        // 0: () -> i64
+		testWASMFuncType([]byte{testWASMI32, testWASMI32}, nil),                           // 1: (i32, i32)
+		testWASMFuncType([]byte{testWASMI32, testWASMI32, testWASMI32, testWASMI32}, nil), // 2: (i32, i32, i32, i32)
+		testWASMFuncType([]byte{testWASMI32}, []byte{testWASMI32}),                        // 3: (i32) -> i32
+		testWASMFuncType(nil, nil),                                                        // 4: ()
+	))...)
+	m = append(m, testWASMSection(2, testWASMVec(
+		importFn("v0_msg_as_bytes", 0),  // func 0
+		importFn("v0_msg_set_bytes", 1), // func 1
+		importFn("v0_msg_set_meta", 2),  // func 2
+		importFn("v0_msg_set_error", 1), // func 3
+	))...)
+	m = append(m, testWASMSection(3, testWASMVec([]byte{3}, []byte{4}, []byte{4}))...)
+	m = append(m, testWASMSection(5, testWASMVec([]byte{0x00, 0x01}))...)
+	m = append(m, testWASMSection(6, testWASMVec([]byte{testWASMI32, 0x01, 0x41, 0x80, 0x08, 0x0b}))...)
+	m = append(m, testWASMSection(7, testWASMVec(
+		exportItem("memory", 0x02, 0),
+		exportItem("allocate", 0x00, 4),
+		exportItem("process", 0x00, 5),
+		exportItem("fail", 0x00, 6),
+	))...)
+	m = append(m, testWASMSection(10, testWASMVec(
+		// allocate: bump the heap pointer global by the requested size.
+		testWASMCode(testWASMVec(),
+			0x23, 0x00, // global.get 0
+			0x23, 0x00, // global.get 0
+			0x20, 0x00, // local.get 0
+			0x6a,       // i32.add
+			0x24, 0x00, // global.set 0
+		),
+		// process: copy the message into the metadata key "original" and set
+		// the message to "processed".
+		testWASMCode(testWASMVec([]byte{0x01, testWASMI64}),
+			0x10, 0x00, // call v0_msg_as_bytes
+			0x21, 0x00, // local.set 0
+			0x41, 0x00, // i32.const 0
+			0x41, 0x08, // i32.const 8
+			0x20, 0x00, // local.get 0
+			0x42, 0x20, // i64.const 32
+			0x88,       // i64.shr_u
+			0xa7,       // i32.wrap_i64
+			0x20, 0x00, // local.get 0
+			0xa7,       // i32.wrap_i64
+			0x10, 0x02, // call v0_msg_set_meta
+			0x41, 0x08, // i32.const 8
+			0x41, 0x09, // i32.const 9
+			0x10, 0x01, // call v0_msg_set_bytes
+		),
+		// fail: flag the message with the error "nope".
+		testWASMCode(testWASMVec(),
+			0x41, 0x11, // i32.const 17
+			0x41, 0x04, // i32.const 4
+			0x10, 0x03, // call v0_msg_set_error
+		),
+	))...)
+	m = append(m, testWASMSection(11, testWASMVec(
+		append([]byte{0x00, 0x41, 0x00, 0x0b}, testWASMName(data)...),
+	))...)
+	return m
+}
+
+func writeTestWASMModule(t *testing.T, content []byte) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "benthos_wasm_test")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	path := filepath.Join(dir, "test.wasm")
+	require.NoError(t, ioutil.WriteFile(path, content, 0644))
+	return path
+}
+
+func TestWASM(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.ModulePath = writeTestWASMModule(t, testWASMModule())
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		require.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	input := message.New([][]byte{[]byte(`hello world`), []byte(`foo`)})
+	msgs, res := proc.ProcessMessage(input)
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, [][]byte{[]byte(`processed`), []byte(`processed`)}, message.GetAllBytes(msgs[0]))
+	assert.Equal(t, "hello world", msgs[0].Get(0).Metadata().Get("original"))
+	assert.Equal(t, "foo", msgs[0].Get(1).Metadata().Get("original"))
+	assert.False(t, HasFailed(msgs[0].Get(0)))
+	assert.False(t, HasFailed(msgs[0].Get(1)))
+
+	assert.Equal(t, [][]byte{[]byte(`hello world`), []byte(`foo`)}, message.GetAllBytes(input))
+}
+
+func TestWASMError(t *testing.T) {
+	conf := NewConfig()
+	conf.Type = TypeWASM
+	conf.WASM.ModulePath = writeTestWASMModule(t, testWASMModule())
+	conf.WASM.Function = "fail"
+
+	proc, err := New(conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer func() {
+		proc.CloseAsync()
+		require.NoError(t, proc.WaitForClose(time.Second))
+	}()
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte(`hello world`)}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, `hello world`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, "nope", GetFail(msgs[0].Get(0)))
+}
+
+func TestWASMBadConfig(t *testing.T) {
+	modulePath := writeTestWASMModule(t, testWASMModule())
+	badModulePath := writeTestWASMModule(t, []byte("not a module"))
+
+	tests := map[string]func(c *WASMConfig){
+		"no module path": func(c *WASMConfig) {
+			c.ModulePath = ""
+		},
+		"no function": func(c *WASMConfig) {
+			c.Function = ""
+		},
+		"missing module": func(c *WASMConfig) {
+			c.ModulePath = "/does/not/exist.wasm"
+		},
+		"invalid module": func(c *WASMConfig) {
+			c.ModulePath = badModulePath
+		},
+		"missing function": func(c *WASMConfig) {
+			c.Function = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeWASM
+		conf.WASM.ModulePath = modulePath
+		fn(&conf.WASM)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
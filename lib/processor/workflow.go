@@ -254,6 +254,10 @@ resources:
 				"branches",
 				"An object of named [`branch` processors](/docs/components/processors/branch) that make up the workflow. The order and parallelism in which branches are executed can either be made explicit with the field `order`, or if omitted an attempt is made to automatically resolve an ordering based on the mappings of each branch.",
 			),
+			docs.FieldAdvanced(
+				"max_in_flight",
+				"The maximum number of branches of a parallel tier that may be executed at the same time for each batch processed, where the remaining branches of the tier wait for a slot to become available. When set to zero all branches of a tier are executed at the same time.",
+			).AtVersion("3.39.0"),
 		},
 		sanitiseConfigFunc: func(conf Config) (interface{}, error) {
 			sanitBranches := map[string]interface{}{}
@@ -269,6 +273,7 @@ resources:
 				"order":            conf.Workflow.Order,
 				"branch_resources": conf.Workflow.BranchResources,
 				"branches":         sanitBranches,
+				"max_in_flight":    conf.Workflow.MaxInFlight,
 			}
 			if len(conf.Workflow.Stages) > 0 {
 				sanitChildren := map[string]interface{}{}
@@ -298,6 +303,7 @@ type WorkflowConfig struct {
 	BranchResources []string                       `json:"branch_resources" yaml:"branch_resources"`
 	Branches        map[string]BranchConfig        `json:"branches" yaml:"branches"`
 	Stages          map[string]DepProcessMapConfig `json:"stages" yaml:"stages"`
+	MaxInFlight     int                            `json:"max_in_flight" yaml:"max_in_flight"`
 }
 
 // NewWorkflowConfig returns a default WorkflowConfig.
@@ -308,6 +314,7 @@ func NewWorkflowConfig() WorkflowConfig {
 		BranchResources: []string{},
 		Branches:        map[string]BranchConfig{},
 		Stages:          map[string]DepProcessMapConfig{},
+		MaxInFlight:     0,
 	}
 }
 
@@ -324,6 +331,8 @@ type Workflow struct {
 	allStages map[string]struct{}
 	metaPath  []string
 
+	maxInFlight int
+
 	mCount           metrics.StatCounter
 	mSent            metrics.StatCounter
 	mSentParts       metrics.StatCounter
@@ -361,6 +370,10 @@ func NewWorkflow(
 	if len(conf.Workflow.MetaPath) > 0 {
 		w.metaPath = gabs.DotPathToSlice(conf.Workflow.MetaPath)
 	}
+	if conf.Workflow.MaxInFlight < 0 {
+		return nil, fmt.Errorf("max_in_flight must not be negative, received: %v", conf.Workflow.MaxInFlight)
+	}
+	w.maxInFlight = conf.Workflow.MaxInFlight
 
 	var err error
 	if w.children, err = newWorkflowBranchMap(conf.Workflow, mgr, log, stats); err != nil {
@@ -574,10 +587,25 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 		results := make([][]types.Part, len(layer))
 		errors := make([]error, len(layer))
 
+		// Slots are scoped to the tier of this call so that concurrent calls,
+		// and subsequent tiers, are not limited by each other.
+		var inFlight chan struct{}
+		if w.maxInFlight > 0 {
+			inFlight = make(chan struct{}, w.maxInFlight)
+		}
+
 		wg := sync.WaitGroup{}
 		wg.Add(len(layer))
 		for i, eid := range layer {
 			go func(id string, index int) {
+				defer wg.Done()
+				if inFlight != nil {
+					inFlight <- struct{}{}
+					defer func() {
+						<-inFlight
+					}()
+				}
+
 				requestParts := make([]types.Part, propMsg.Len())
 				propMsg.Iter(func(partIndex int, p types.Part) error {
 					if _, exists := skipOnMeta[partIndex][id]; !exists {
@@ -605,7 +633,6 @@ func (w *Workflow) ProcessMessage(msg types.Message) ([]types.Message, types.Res
 				for _, e := range mapErrs {
 					records[e.index].Failed(id, e.err.Error())
 				}
-				wg.Done()
 			}(eid, i)
 		}
 		wg.Wait()
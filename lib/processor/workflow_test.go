@@ -3,6 +3,8 @@ package processor
 import (
 	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -769,3 +771,99 @@ func TestWorkflowsWithOrderResources(t *testing.T) {
 		})
 	}
 }
+
+type concurrencyProc struct {
+	current int64
+	peak    int64
+	gate    chan struct{}
+}
+
+func (c *concurrencyProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	n := atomic.AddInt64(&c.current, 1)
+	for {
+		peak := atomic.LoadInt64(&c.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&c.peak, peak, n) {
+			break
+		}
+	}
+	<-c.gate
+	atomic.AddInt64(&c.current, -1)
+	return []types.Message{msg}, nil
+}
+
+func (c *concurrencyProc) CloseAsync() {}
+
+func (c *concurrencyProc) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestWorkflowMaxInFlight(t *testing.T) {
+	counter := &concurrencyProc{gate: make(chan struct{})}
+	mgr := &fakeProcMgr{
+		procs: map[string]Type{"counter": counter},
+	}
+
+	conf := NewConfig()
+	conf.Workflow.MaxInFlight = 2
+	conf.Workflow.Order = [][]string{{"a", "b", "c", "d"}}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		branchConf := NewBranchConfig()
+		branchConf.RequestMap = "root = this"
+		branchConf.ResultMap = "root." + id + " = this.value"
+
+		proc := NewConfig()
+		proc.Type = TypeResource
+		proc.Resource = "counter"
+		branchConf.Processors = append(branchConf.Processors, proc)
+		conf.Workflow.Branches[id] = branchConf
+	}
+
+	p, err := NewWorkflow(conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	resChan := make(chan []types.Message)
+	go func() {
+		msgs, res := p.ProcessMessage(message.New([][]byte{[]byte(`{"value":"foo"}`)}))
+		assert.Nil(t, res)
+		resChan <- msgs
+	}()
+
+	// Only two branches of the tier are executed at a time.
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&counter.current) == 2
+	}, time.Second*5, time.Millisecond)
+	close(counter.gate)
+
+	msgs := <-resChan
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"a":"foo","b":"foo","c":"foo","d":"foo","meta":{"workflow":{"succeeded":["a","b","c","d"]}},"value":"foo"}`, string(msgs[0].Get(0).Get()))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&counter.peak))
+
+	// Concurrent calls are not limited by each other.
+	atomic.StoreInt64(&counter.peak, 0)
+	counter.gate = make(chan struct{})
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			defer wg.Done()
+			msgs, res := p.ProcessMessage(message.New([][]byte{[]byte(`{"value":"foo"}`)}))
+			assert.Nil(t, res)
+			assert.Len(t, msgs, 1)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt64(&counter.current) == 4
+	}, time.Second*5, time.Millisecond)
+	close(counter.gate)
+	wg.Wait()
+	assert.Equal(t, int64(4), atomic.LoadInt64(&counter.peak))
+
+	p.CloseAsync()
+	assert.NoError(t, p.WaitForClose(time.Second))
+
+	conf.Workflow.MaxInFlight = -1
+	_, err = NewWorkflow(conf, mgr, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+}
@@ -38,6 +38,7 @@ var Constructors = map[string]TypeSpec{}
 // String constants representing each ratelimit type.
 const (
 	TypeLocal = "local"
+	TypeRedis = "redis"
 )
 
 //------------------------------------------------------------------------------
@@ -47,6 +48,7 @@ type Config struct {
 	Type   string      `json:"type" yaml:"type"`
 	Local  LocalConfig `json:"local" yaml:"local"`
 	Plugin interface{} `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Redis  RedisConfig `json:"redis" yaml:"redis"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -55,6 +57,7 @@ func NewConfig() Config {
 		Type:   "local",
 		Local:  NewLocalConfig(),
 		Plugin: nil,
+		Redis:  NewRedisConfig(),
 	}
 }
 
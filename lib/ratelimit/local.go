@@ -1,8 +1,10 @@
 package ratelimit
 
 import (
+	"container/list"
 	"errors"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -21,20 +23,50 @@ func init() {
 The local rate limit is a simple X every Y type rate limit that can be shared
 across any number of components within the pipeline but does not support
 distributed rate limits across multiple running instances of Benthos.`,
+		Description: `
+### Bursts
+
+By default the limit allows ` + "`count`" + ` requests at any time within each
+` + "`interval`" + `, after which requests are blocked until the interval has
+passed. When ` + "`burst`" + ` is set to a value above zero the limit instead
+becomes a token bucket that is refilled at a constant rate of ` + "`count`" + `
+tokens per ` + "`interval`" + ` and holds up to ` + "`burst`" + ` tokens, where
+each request takes a token. This smooths requests out over the interval whilst
+allowing short bursts of up to ` + "`burst`" + ` requests.
+
+### Costs
+
+Requests can consume more than one unit of the limit, such as with the
+` + "`cost`" + ` field of the
+[` + "`rate_limit` processor" + `](/docs/components/processors/rate_limit), which
+allows large messages to consume proportionally more of the limit than small
+ones. A request with a cost larger than ` + "`count`" + `, or ` + "`burst`" + `
+when set, consumes the entire limit.`,
 		FieldSpecs: docs.FieldSpecs{
 			docs.FieldCommon("count", "The maximum number of requests to allow for a given period of time."),
 			docs.FieldCommon("interval", "The time window to limit requests by."),
+			docs.FieldAdvanced(
+				"max_keys",
+				"The maximum number of keys to track independent limits for when the rate limit is accessed by key, such as with the `key` field of the [`rate_limit` processor](/docs/components/processors/rate_limit). When this number is exceeded the limit of the least recently accessed key is discarded. When set to zero the default of 10000 is used.",
+			).AtVersion("3.39.0"),
+			docs.FieldAdvanced("burst", "When set to a value above zero the limit becomes a token bucket that holds up to this number of tokens, see [bursts](#bursts) for more information.").AtVersion("3.39.0"),
 		},
 	}
 }
 
 //------------------------------------------------------------------------------
 
+// localDefaultMaxKeys is the number of keys tracked by a local rate limit when
+// max_keys is zero.
+const localDefaultMaxKeys = 10000
+
 // LocalConfig is a config struct containing rate limit fields for a local rate
 // limit.
 type LocalConfig struct {
 	Count    int    `json:"count" yaml:"count"`
 	Interval string `json:"interval" yaml:"interval"`
+	MaxKeys  int    `json:"max_keys" yaml:"max_keys"`
+	Burst    int    `json:"burst" yaml:"burst"`
 }
 
 // NewLocalConfig returns a local rate limit configuration struct with default
@@ -43,6 +75,8 @@ func NewLocalConfig() LocalConfig {
 	return LocalConfig{
 		Count:    1000,
 		Interval: "1s",
+		MaxKeys:  localDefaultMaxKeys,
+		Burst:    0,
 	}
 }
 
@@ -52,14 +86,25 @@ func NewLocalConfig() LocalConfig {
 // parallel processes in order to maintain a maximum rate of a protected
 // resource.
 type Local struct {
-	mut         sync.Mutex
-	bucket      int
-	lastRefresh time.Time
+	mut    sync.Mutex
+	bucket localBucket
+
+	keyed    map[string]*list.Element
+	keyedLRU *list.List
+	maxKeys  int
 
 	size   int
+	burst  int
 	period time.Duration
 }
 
+type localBucket struct {
+	key         string
+	bucket      int
+	tokens      float64
+	lastRefresh time.Time
+}
+
 // NewLocal creates a local rate limit from a configuration struct. This type is
 // safe to share and call from parallel goroutines.
 func NewLocal(
@@ -75,12 +120,38 @@ func NewLocal(
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse interval: %v", err)
 	}
-	return &Local{
-		bucket:      conf.Local.Count,
+	maxKeys := conf.Local.MaxKeys
+	if maxKeys < 0 {
+		return nil, errors.New("max_keys must not be negative")
+	}
+	if maxKeys == 0 {
+		maxKeys = localDefaultMaxKeys
+	}
+	if conf.Local.Burst < 0 {
+		return nil, errors.New("burst must not be negative")
+	}
+	if conf.Local.Burst > 0 && period <= 0 {
+		return nil, errors.New("interval must be a positive duration when burst is set")
+	}
+	r := &Local{
+		keyed:    map[string]*list.Element{},
+		keyedLRU: list.New(),
+		maxKeys:  maxKeys,
+		size:     conf.Local.Count,
+		burst:    conf.Local.Burst,
+		period:   period,
+	}
+	r.bucket = r.newBucket("")
+	return r, nil
+}
+
+func (r *Local) newBucket(key string) localBucket {
+	return localBucket{
+		key:         key,
+		bucket:      r.size,
+		tokens:      float64(r.burst),
 		lastRefresh: time.Now(),
-		size:        conf.Local.Count,
-		period:      period,
-	}, nil
+	}
 }
 
 //------------------------------------------------------------------------------
@@ -90,22 +161,88 @@ func NewLocal(
 // can be accessed) or a reasonable length of time to wait before requesting
 // again.
 func (r *Local) Access() (time.Duration, error) {
+	return r.AccessCost(1)
+}
+
+// AccessCost accesses the rate limited resource with a given cost, which
+// consumes that many units of the limit.
+func (r *Local) AccessCost(cost int) (time.Duration, error) {
 	r.mut.Lock()
-	r.bucket--
+	defer r.mut.Unlock()
+	return r.access(&r.bucket, cost), nil
+}
+
+// AccessKey accesses the rate limited resource for a given key, where each key
+// has its own limit that is independent of the limit of Access and other keys.
+func (r *Local) AccessKey(key string) (time.Duration, error) {
+	return r.AccessKeyCost(key, 1)
+}
 
-	if r.bucket < 0 {
-		r.bucket = 0
-		remaining := r.period - time.Since(r.lastRefresh)
+// AccessKeyCost accesses the rate limited resource for a given key with a given
+// cost.
+func (r *Local) AccessKeyCost(key string, cost int) (time.Duration, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if e, exists := r.keyed[key]; exists {
+		r.keyedLRU.MoveToFront(e)
+		return r.access(e.Value.(*localBucket), cost), nil
+	}
 
+	if r.keyedLRU.Len() >= r.maxKeys {
+		oldest := r.keyedLRU.Back()
+		r.keyedLRU.Remove(oldest)
+		delete(r.keyed, oldest.Value.(*localBucket).key)
+	}
+
+	b := r.newBucket(key)
+	r.keyed[key] = r.keyedLRU.PushFront(&b)
+	return r.access(&b, cost), nil
+}
+
+func (r *Local) access(b *localBucket, cost int) time.Duration {
+	if cost < 1 {
+		cost = 1
+	}
+	if r.burst > 0 {
+		return r.accessTokens(b, cost)
+	}
+	if cost > r.size {
+		cost = r.size
+	}
+
+	if b.bucket < cost {
+		remaining := r.period - time.Since(b.lastRefresh)
 		if remaining > 0 {
-			r.mut.Unlock()
-			return remaining, nil
+			return remaining
 		}
-		r.bucket = r.size - 1
-		r.lastRefresh = time.Now()
+		b.bucket = r.size
+		b.lastRefresh = time.Now()
+	}
+	b.bucket -= cost
+	return 0
+}
+
+// accessTokens takes tokens from a bucket that is refilled at a constant rate
+// of size tokens per period, up to a maximum of burst tokens.
+func (r *Local) accessTokens(b *localBucket, cost int) time.Duration {
+	if cost > r.burst {
+		cost = r.burst
+	}
+
+	now := time.Now()
+	rate := float64(r.size) / float64(r.period)
+	b.tokens += float64(now.Sub(b.lastRefresh)) * rate
+	if b.tokens > float64(r.burst) {
+		b.tokens = float64(r.burst)
+	}
+	b.lastRefresh = now
+
+	if b.tokens >= float64(cost) {
+		b.tokens -= float64(cost)
+		return 0
 	}
-	r.mut.Unlock()
-	return 0, nil
+	return time.Duration(math.Ceil((float64(cost) - b.tokens) / rate))
 }
 
 // CloseAsync shuts down the rate limit.
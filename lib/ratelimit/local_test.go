@@ -7,6 +7,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
 //------------------------------------------------------------------------------
@@ -137,3 +138,150 @@ func BenchmarkRateLimit(b *testing.B) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestLocalRateLimitKeyed(t *testing.T) {
+	conf := NewConfig()
+	conf.Local.Count = 2
+	conf.Local.Interval = "1s"
+	conf.Local.MaxKeys = 2
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	krl, ok := rl.(types.RateLimitKeyed)
+	if !ok {
+		t.Fatal("Expected keyed rate limit")
+	}
+
+	for _, key := range []string{"foo", "foo", "bar", "bar"} {
+		if period, _ := krl.AccessKey(key); period > 0 {
+			t.Errorf("Period above zero for key %v: %v", key, period)
+		}
+	}
+	if period, _ := krl.AccessKey("foo"); period == 0 {
+		t.Error("Expected limit on key foo")
+	}
+
+	// Unkeyed access has its own limit.
+	if period, _ := rl.Access(); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+
+	// Evicts the least recently accessed key bar, which resets its limit.
+	if period, _ := krl.AccessKey("baz"); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := krl.AccessKey("bar"); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := krl.AccessKey("baz"); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := krl.AccessKey("baz"); period == 0 {
+		t.Error("Expected limit on key baz")
+	}
+}
+
+func TestLocalRateLimitCost(t *testing.T) {
+	conf := NewConfig()
+	conf.Local.Count = 10
+	conf.Local.Interval = "1s"
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrl, ok := rl.(types.RateLimitWeighted)
+	if !ok {
+		t.Fatal("Expected weighted rate limit")
+	}
+
+	if period, _ := wrl.AccessCost(6); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := wrl.AccessCost(4); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := wrl.AccessCost(1); period == 0 {
+		t.Error("Expected limit on final request")
+	}
+
+	// Costs above the count consume the entire limit of a fresh key.
+	if period, _ := wrl.AccessKeyCost("foo", 100); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := wrl.AccessKeyCost("foo", 1); period == 0 {
+		t.Error("Expected limit on key foo")
+	}
+}
+
+func TestLocalRateLimitBurst(t *testing.T) {
+	conf := NewConfig()
+	conf.Local.Count = 100
+	conf.Local.Interval = "1s"
+	conf.Local.Burst = 5
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < conf.Local.Burst; i++ {
+		if period, _ := rl.Access(); period > 0 {
+			t.Errorf("Period above zero: %v", period)
+		}
+	}
+
+	period, _ := rl.Access()
+	if period == 0 {
+		t.Fatal("Expected limit after burst")
+	}
+	if period > time.Millisecond*10 {
+		t.Errorf("Period beyond the refill of one token: %v", period)
+	}
+
+	<-time.After(period)
+	if period, _ = rl.Access(); period > 0 {
+		t.Errorf("Period above zero after refill: %v", period)
+	}
+
+	conf.Local.Burst = -1
+	if _, err = New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from negative burst")
+	}
+}
+
+func TestLocalRateLimitZeroMaxKeys(t *testing.T) {
+	conf := NewConfig()
+	conf.Local = LocalConfig{
+		Count:    1,
+		Interval: "1s",
+	}
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	krl, ok := rl.(types.RateLimitKeyed)
+	if !ok {
+		t.Fatal("Expected keyed rate limit")
+	}
+	if period, _ := krl.AccessKey("foo"); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := krl.AccessKey("bar"); period > 0 {
+		t.Errorf("Period above zero: %v", period)
+	}
+	if period, _ := krl.AccessKey("foo"); period == 0 {
+		t.Error("Expected limit on key foo")
+	}
+
+	conf.Local.MaxKeys = -1
+	if _, err = New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+		t.Error("Expected error from negative max_keys")
+	}
+}
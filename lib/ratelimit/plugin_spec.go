@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
+)
+
+//------------------------------------------------------------------------------
+
+// SpecPluginConstructor is a func that constructs a rate limit plugin from a
+// configuration that has been parsed according to its spec.
+//
+// The returned rate limit may also implement types.RateLimitKeyed and
+// types.RateLimitWeighted in order to support keys and costs.
+type SpecPluginConstructor func(
+	conf *plugin.ParsedConfig,
+	manager types.Manager,
+	logger log.Modular,
+	metrics metrics.Type,
+) (types.RateLimit, error)
+
+// RegisterPluginWithSpec registers a plugin by a unique name along with a spec
+// of its configuration fields. Unlike RegisterPlugin a configuration struct is
+// not needed, instead the constructor is given the user configuration parsed
+// according to the spec.
+//
+// An error is returned if the name is already used by a rate limit type or
+// another plugin, or if the spec is invalid.
+func RegisterPluginWithSpec(name string, spec *plugin.ConfigSpec, constructor SpecPluginConstructor) error {
+	if _, exists := Constructors[name]; exists {
+		return fmt.Errorf("rate limit type '%v' already exists", name)
+	}
+	if _, exists := pluginSpecs[name]; exists {
+		return fmt.Errorf("rate limit plugin '%v' already exists", name)
+	}
+	if constructor == nil {
+		return errors.New("a constructor must be provided")
+	}
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	pluginSpecs[name] = pluginSpec{
+		constructor: func(conf Config, mgr types.Manager, log log.Modular, stats metrics.Type) (types.RateLimit, error) {
+			pConf, err := spec.Parse(conf.Plugin)
+			if err != nil {
+				return nil, err
+			}
+			return constructor(pConf, mgr, log, stats)
+		},
+		confConstructor: func() interface{} {
+			return spec.Defaults()
+		},
+		description: spec.Document(),
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
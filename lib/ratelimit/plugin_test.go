@@ -3,12 +3,15 @@ package ratelimit
 import (
 	"encoding/json"
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/Jeffail/benthos/v3/lib/util/plugin"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -178,3 +181,104 @@ func TestJSONPluginNilConf(t *testing.T) {
 		t.Errorf("Wrong error returned: %v != %v", err, errTest)
 	}
 }
+
+type mockSpecRateLimit struct {
+	period time.Duration
+}
+
+func (m *mockSpecRateLimit) Access() (time.Duration, error) {
+	return m.period, nil
+}
+
+func (m *mockSpecRateLimit) CloseAsync() {}
+
+func (m *mockSpecRateLimit) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestYAMLPluginWithSpec(t *testing.T) {
+	spec := plugin.NewConfigSpec().
+		Summary("A quota service backed rate limit.").
+		Field("url", "The URL of the quota service.", nil).
+		Field("period", "The period to wait.", "1s").
+		Field("quotas", "A list of quotas.", []string{}).
+		Field("retries", "The number of retries.", 3)
+
+	var url, period string
+	var quotas []string
+	var retries int
+	err := RegisterPluginWithSpec("foo_spec", spec, func(conf *plugin.ParsedConfig, mgr types.Manager, logger log.Modular, stats metrics.Type) (types.RateLimit, error) {
+		var err error
+		if url, err = conf.FieldString("url"); err != nil {
+			return nil, err
+		}
+		if period, err = conf.FieldString("period"); err != nil {
+			return nil, err
+		}
+		if quotas, err = conf.FieldStringList("quotas"); err != nil {
+			return nil, err
+		}
+		if retries, err = conf.FieldInt("retries"); err != nil {
+			return nil, err
+		}
+		d, err := conf.FieldDuration("period")
+		if err != nil {
+			return nil, err
+		}
+		return &mockSpecRateLimit{period: d}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(pluginSpecs, "foo_spec")
+
+	if err = RegisterPluginWithSpec("foo_spec", spec, nil); err == nil {
+		t.Error("Expected error from duplicate plugin")
+	}
+	if err = RegisterPluginWithSpec(TypeLocal, spec, nil); err == nil {
+		t.Error("Expected error from plugin with the name of a rate limit type")
+	}
+
+	confStr := `type: foo_spec
+plugin:
+  url: http://localhost:8080
+  quotas: [ foo, bar ]`
+
+	conf := NewConfig()
+	if err = yaml.Unmarshal([]byte(confStr), &conf); err != nil {
+		t.Fatal(err)
+	}
+
+	rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "http://localhost:8080", url; exp != act {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+	if exp, act := "1s", period; exp != act {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+	if exp, act := []string{"foo", "bar"}, quotas; !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+	if exp, act := 3, retries; exp != act {
+		t.Errorf("Wrong config value: %v != %v", act, exp)
+	}
+	if tout, _ := rl.Access(); tout != time.Second {
+		t.Errorf("Wrong access period: %v", tout)
+	}
+
+	for _, badConf := range []string{
+		"type: foo_spec\nplugin:\n  period: 1s",
+		"type: foo_spec\nplugin:\n  url: foo\n  nope: bar",
+	} {
+		conf = NewConfig()
+		if err = yaml.Unmarshal([]byte(badConf), &conf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = New(conf, nil, log.Noop(), metrics.Noop()); err == nil {
+			t.Errorf("Expected error from config: %v", badConf)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	bredis "github.com/Jeffail/benthos/v3/internal/service/redis"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/go-redis/redis/v7"
+	"github.com/gofrs/uuid"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeRedis] = TypeSpec{
+		constructor: NewRedis,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+A rate limit that is stored in Redis, which enforces a single X every Y type
+limit across any number of running instances of Benthos.`,
+		Description: `
+Each access of the rate limit runs a Lua script against Redis that checks and
+updates the limit atomically, using the clock of the Redis server in order to
+avoid problems with clock drift between instances.
+
+### Algorithms
+
+The ` + "`sliding_window`" + ` algorithm allows at most ` + "`count`" + `
+requests within any period of ` + "`interval`" + ` by recording the time of
+each request, which makes it precise but requires memory within Redis for each
+request of the window.
+
+The ` + "`token_bucket`" + ` algorithm refills a bucket of ` + "`count`" + `
+tokens at a constant rate over ` + "`interval`" + `, where each request takes
+a token. This uses a constant amount of memory within Redis and smooths requests
+out over the interval, but allows bursts of up to ` + "`count`" + ` requests
+after a period of inactivity.
+
+### Keys
+
+The limit is stored under the key configured with ` + "`key`" + `, and when
+the rate limit is accessed by key, such as with the ` + "`key`" + ` field of
+the [` + "`rate_limit` processor" + `](/docs/components/processors/rate_limit),
+each key has an independent limit stored under the configured key suffixed with
+a colon and the accessed key. Limits expire from Redis once they are no longer
+accessed.`,
+		FieldSpecs: bredis.ConfigDocs().Add(
+			docs.FieldCommon("count", "The maximum number of requests to allow for a given period of time."),
+			docs.FieldCommon("interval", "The time window to limit requests by."),
+			docs.FieldCommon("key", "The key to store the limit under, which must be the same for all instances that share the limit."),
+			docs.FieldAdvanced("algorithm", "The algorithm to use for enforcing the limit, see [algorithms](#algorithms) for more information.").HasOptions("sliding_window", "token_bucket"),
+		),
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// RedisConfig is a config struct containing rate limit fields for a redis rate
+// limit.
+type RedisConfig struct {
+	bredis.Config `json:",inline" yaml:",inline"`
+	Count         int    `json:"count" yaml:"count"`
+	Interval      string `json:"interval" yaml:"interval"`
+	Key           string `json:"key" yaml:"key"`
+	Algorithm     string `json:"algorithm" yaml:"algorithm"`
+}
+
+// NewRedisConfig returns a redis rate limit configuration struct with default
+// values.
+func NewRedisConfig() RedisConfig {
+	return RedisConfig{
+		Config:    bredis.NewConfig(),
+		Count:     1000,
+		Interval:  "1s",
+		Key:       "benthos_rate_limit",
+		Algorithm: "sliding_window",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Both scripts take the key of the limit, and the arguments of the count, the
+// interval in microseconds and a unique request ID. They return the number of
+// microseconds to wait before the next request, which is zero when the request
+// is allowed.
+//
+// Writes following a call to TIME require effect replication, which is enabled
+// explicitly for versions of Redis prior to 5.0.
+
+var redisSlidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+if redis.call("ZCARD", KEYS[1]) < limit then
+  redis.call("ZADD", KEYS[1], now, ARGV[3])
+  redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+  return 0
+end
+
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+return math.max(1, tonumber(oldest[2]) + window - now)
+`)
+
+var redisTokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = limit
+  ts = now
+end
+tokens = math.min(limit, tokens + math.max(0, now - ts) * limit / window)
+
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+else
+  wait = math.ceil((1 - tokens) * window / limit)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+return wait
+`)
+
+//------------------------------------------------------------------------------
+
+// Redis is a rate limit that is stored in Redis and can therefore be shared
+// across multiple instances of Benthos.
+type Redis struct {
+	client redis.UniversalClient
+	script *redis.Script
+
+	key    string
+	count  int
+	period time.Duration
+
+	log log.Modular
+
+	mErr metrics.StatCounter
+}
+
+// NewRedis creates a redis rate limit from a configuration struct. This type is
+// safe to share and call from parallel goroutines.
+func NewRedis(
+	conf Config,
+	mgr types.Manager,
+	logger log.Modular,
+	stats metrics.Type,
+) (types.RateLimit, error) {
+	if conf.Redis.Count <= 0 {
+		return nil, errors.New("count must be larger than zero")
+	}
+	period, err := time.ParseDuration(conf.Redis.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse interval: %v", err)
+	}
+	if period < time.Millisecond {
+		return nil, errors.New("interval must be at least one millisecond")
+	}
+	if conf.Redis.Key == "" {
+		return nil, errors.New("a key must be specified")
+	}
+
+	var script *redis.Script
+	switch conf.Redis.Algorithm {
+	case "sliding_window":
+		script = redisSlidingWindowScript
+	case "token_bucket":
+		script = redisTokenBucketScript
+	default:
+		return nil, fmt.Errorf("unrecognised algorithm: %v", conf.Redis.Algorithm)
+	}
+
+	client, err := conf.Redis.Config.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Redis{
+		client: client,
+		script: script,
+		key:    conf.Redis.Key,
+		count:  conf.Redis.Count,
+		period: period,
+		log:    logger,
+		mErr:   stats.GetCounter("error"),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *Redis) access(key string) (time.Duration, error) {
+	u4, err := uuid.NewV4()
+	if err != nil {
+		return 0, err
+	}
+	wait, err := r.script.Run(
+		r.client, []string{key},
+		r.count, int64(r.period/time.Microsecond), u4.String(),
+	).Int64()
+	if err != nil {
+		r.mErr.Incr(1)
+		r.log.Errorf("Failed to access rate limit: %v\n", err)
+		return 0, err
+	}
+	return time.Duration(wait) * time.Microsecond, nil
+}
+
+// Access the rate limited resource. Returns a duration or an error if the rate
+// limit check fails. The returned duration is either zero (meaning the resource
+// can be accessed) or a reasonable length of time to wait before requesting
+// again.
+func (r *Redis) Access() (time.Duration, error) {
+	return r.access(r.key)
+}
+
+// AccessKey accesses the rate limited resource for a given key, where each key
+// has its own limit that is independent of the limit of Access and other keys.
+func (r *Redis) AccessKey(key string) (time.Duration, error) {
+	return r.access(r.key + ":" + key)
+}
+
+// CloseAsync shuts down the rate limit.
+func (r *Redis) CloseAsync() {
+	r.client.Close()
+}
+
+// WaitForClose blocks until the rate limit has closed down.
+func (r *Redis) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisRateLimitConfErrors(t *testing.T) {
+	tests := map[string]func(c *RedisConfig){
+		"zero count": func(c *RedisConfig) {
+			c.Count = 0
+		},
+		"bad interval": func(c *RedisConfig) {
+			c.Interval = "nope"
+		},
+		"tiny interval": func(c *RedisConfig) {
+			c.Interval = "10us"
+		},
+		"no key": func(c *RedisConfig) {
+			c.Key = ""
+		},
+		"bad algorithm": func(c *RedisConfig) {
+			c.Algorithm = "nope"
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Type = TypeRedis
+		fn(&conf.Redis)
+
+		_, err := New(conf, nil, log.Noop(), metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRedisRateLimitKeyed(t *testing.T) {
+	for _, algo := range []string{"sliding_window", "token_bucket"} {
+		conf := NewConfig()
+		conf.Type = TypeRedis
+		conf.Redis.Algorithm = algo
+
+		rl, err := New(conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err, algo)
+
+		_, ok := rl.(types.RateLimitKeyed)
+		assert.True(t, ok, algo)
+
+		rl.CloseAsync()
+	}
+}
@@ -135,7 +135,7 @@ func NewHandler(conf config.Type) (*Handler, error) {
 
 	// Create our tracer type.
 	var trac tracer.Type
-	if trac, err = tracer.New(conf.Tracer); err != nil {
+	if trac, err = tracer.New(conf.Tracer, tracer.OptSetLogger(logger)); err != nil {
 		logger.Errorf("Failed to initialise tracer: %v\n", err)
 		trac = tracer.Noop()
 	}
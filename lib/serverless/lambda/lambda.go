@@ -7,6 +7,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/serverless"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
 	"github.com/Jeffail/benthos/v3/lib/util/text"
 	"github.com/aws/aws-lambda-go/lambda"
 	"gopkg.in/yaml.v3"
@@ -32,8 +33,11 @@ func Run() {
 	conf.Output.Type = serverless.ServerlessResponseType
 
 	if confStr := os.Getenv("BENTHOS_CONFIG"); len(confStr) > 0 {
-		confBytes := text.ReplaceEnvVariables([]byte(confStr))
-		if err := yaml.Unmarshal(confBytes, &conf); err != nil {
+		confBytes, err := secrets.ReplaceWithTimeout([]byte(confStr), secrets.DefaultTimeout)
+		if err == nil {
+			err = yaml.Unmarshal(text.ReplaceEnvVariables(confBytes), &conf)
+		}
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
 			os.Exit(1)
 		}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// prompter asks questions of a user, writing prompts to w and reading answers
+// line by line from r.
+type prompter struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+func newPrompter(r io.Reader, w io.Writer) *prompter {
+	return &prompter{
+		r: bufio.NewReader(r),
+		w: w,
+	}
+}
+
+// ask prints a question along with a default value and returns the answer, or
+// the default if the answer is empty.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.w, "%v [%v]: ", question, def)
+	} else {
+		fmt.Fprintf(p.w, "%v: ", question)
+	}
+	line, err := p.r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return "", errors.New("unexpected end of input")
+		}
+		return "", err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def, nil
+	}
+	return line, nil
+}
+
+//------------------------------------------------------------------------------
+
+// wrapList formats a list of words into lines of a maximum width.
+func wrapList(words []string, indent string, width int) string {
+	var lines []string
+	line := indent
+	for i, w := range words {
+		if i < len(words)-1 {
+			w += ","
+		}
+		if len(line) > len(indent) && len(line)+len(w)+1 > width {
+			lines = append(lines, line)
+			line = indent
+		}
+		if len(line) > len(indent) {
+			line += " "
+		}
+		line += w
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
+// summarise returns the first paragraph of a markdown description collapsed
+// into a single line.
+func summarise(desc string) string {
+	desc = strings.TrimSpace(desc)
+	if i := strings.Index(desc, "\n\n"); i >= 0 {
+		desc = desc[:i]
+	}
+	return strings.Join(strings.Fields(desc), " ")
+}
+
+// chooseTypes asks the user for a comma separated list of component types and
+// repeats the question until all types are recognised.
+func (p *prompter) chooseTypes(kind, plural, def string, min int, types map[string]docs.Status) ([]string, error) {
+	var names []string
+	for k, status := range types {
+		if status != docs.StatusDeprecated {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	fmt.Fprintf(p.w, "\nAvailable %v:\n%v\n\n", plural, wrapList(names, "  ", 80))
+
+	question := fmt.Sprintf("Choose %v (comma separated)", plural)
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil {
+			return nil, err
+		}
+		var chosen []string
+		var unknown []string
+		for _, t := range strings.Split(answer, ",") {
+			if t = strings.TrimSpace(t); t == "" {
+				continue
+			}
+			if _, exists := types[t]; !exists {
+				unknown = append(unknown, t)
+			}
+			chosen = append(chosen, t)
+		}
+		switch {
+		case len(unknown) > 0:
+			fmt.Fprintf(p.w, "Unrecognised %v type: %v\n", kind, strings.Join(unknown, ", "))
+		case len(chosen) < min:
+			fmt.Fprintf(p.w, "At least %v %v must be chosen\n", min, kind)
+		default:
+			return chosen, nil
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// promptable returns true for values that can be set with a single answer,
+// which are scalars and arrays of scalars.
+func promptable(v interface{}) bool {
+	switch t := v.(type) {
+	case string, float64, bool:
+		return true
+	case []interface{}:
+		for _, e := range t {
+			if !promptable(e) {
+				return false
+			}
+			if _, isArr := e.([]interface{}); isArr {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func formatValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// parseValue parses an answer into a value of the same type as the current
+// value of a field.
+func parseValue(answer string, current interface{}) (interface{}, error) {
+	if _, isStr := current.(string); isStr {
+		return answer, nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(answer), &v); err != nil {
+		return nil, err
+	}
+	if _, isArr := current.([]interface{}); isArr {
+		if _, isArr = v.([]interface{}); !isArr {
+			var arr []interface{}
+			for _, e := range strings.Split(answer, ",") {
+				if e = strings.TrimSpace(e); e != "" {
+					arr = append(arr, e)
+				}
+			}
+			v = arr
+		}
+		return v, nil
+	}
+	if v == nil || docs.GetFieldType(v) != docs.GetFieldType(current) {
+		return nil, fmt.Errorf("expected a %v value", docs.GetFieldType(current))
+	}
+	return v, nil
+}
+
+// askValue asks for the value of a field and repeats the question until the
+// answer can be parsed.
+func (p *prompter) askValue(question string, current interface{}) (interface{}, error) {
+	def := formatValue(current)
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil {
+			return nil, err
+		}
+		if answer == def {
+			return current, nil
+		}
+		v, err := parseValue(answer, current)
+		if err == nil {
+			return v, nil
+		}
+		fmt.Fprintf(p.w, "Invalid value: %v\n", err)
+	}
+}
+
+// configure walks the common fields of a component, printing their docs and
+// asking for their values, and then parses the resulting config into target.
+func (p *prompter) configure(kind, cType, summary string, specs docs.FieldSpecs, sanit, target interface{}) error {
+	jBytes, err := json.Marshal(sanit)
+	if err != nil {
+		return err
+	}
+	var obj map[string]interface{}
+	if err = json.Unmarshal(jBytes, &obj); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(p.w, "\nConfiguring %v '%v'", kind, cType)
+	if summary = summarise(summary); summary != "" {
+		fmt.Fprintf(p.w, ": %v", summary)
+	}
+	fmt.Fprintln(p.w, "")
+
+	switch t := obj[cType].(type) {
+	case map[string]interface{}:
+		for _, spec := range specs {
+			current, exists := t[spec.Name]
+			if spec.Advanced || spec.Deprecated || !exists || !promptable(current) {
+				continue
+			}
+			if desc := summarise(spec.Description); desc != "" {
+				fmt.Fprintf(p.w, "\n  # %v\n", desc)
+			} else {
+				fmt.Fprintln(p.w, "")
+			}
+			if t[spec.Name], err = p.askValue("  "+spec.Name, current); err != nil {
+				return err
+			}
+		}
+	default:
+		if promptable(t) {
+			if obj[cType], err = p.askValue("  "+cType, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	confBytes, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(confBytes, target)
+}
+
+func (p *prompter) configureInput(conf *input.Config) error {
+	spec := input.Constructors[conf.Type]
+	sanit, err := input.SanitiseConfig(*conf)
+	if err != nil {
+		return err
+	}
+	return p.configure("input", conf.Type, spec.Summary, spec.FieldSpecs, sanit, conf)
+}
+
+func (p *prompter) configureProcessor(conf *processor.Config) error {
+	spec := processor.Constructors[conf.Type]
+	sanit, err := processor.SanitiseConfig(*conf)
+	if err != nil {
+		return err
+	}
+	return p.configure("processor", conf.Type, spec.Summary, spec.FieldSpecs, sanit, conf)
+}
+
+func (p *prompter) configureOutput(conf *output.Config) error {
+	spec := output.Constructors[conf.Type]
+	sanit, err := output.SanitiseConfig(*conf)
+	if err != nil {
+		return err
+	}
+	return p.configure("output", conf.Type, spec.Summary, spec.FieldSpecs, sanit, conf)
+}
+
+//------------------------------------------------------------------------------
+
+// createInteractive walks a user through choosing the inputs, processors and
+// outputs of a config and setting their common fields, reading answers from r
+// and writing prompts and field documentation to w.
+func createInteractive(conf *config.Type, r io.Reader, w io.Writer) error {
+	p := newPrompter(r, w)
+
+	inputTypes := map[string]docs.Status{}
+	for k, v := range input.Constructors {
+		inputTypes[k] = v.Status
+	}
+	procTypes := map[string]docs.Status{}
+	for k, v := range processor.Constructors {
+		procTypes[k] = v.Status
+	}
+	outputTypes := map[string]docs.Status{}
+	for k, v := range output.Constructors {
+		outputTypes[k] = v.Status
+	}
+
+	inputs, err := p.chooseTypes("input", "inputs", input.TypeStdin, 1, inputTypes)
+	if err != nil {
+		return err
+	}
+	procs, err := p.chooseTypes("processor", "processors", "", 0, procTypes)
+	if err != nil {
+		return err
+	}
+	outputs, err := p.chooseTypes("output", "outputs", output.TypeStdout, 1, outputTypes)
+	if err != nil {
+		return err
+	}
+
+	if err = addExpression(conf, strings.Join([]string{
+		strings.Join(inputs, ","),
+		strings.Join(procs, ","),
+		strings.Join(outputs, ","),
+	}, "/")); err != nil {
+		return err
+	}
+
+	if conf.Input.Type == input.TypeBroker && len(inputs) > 1 {
+		for i := range conf.Input.Broker.Inputs {
+			if err = p.configureInput(&conf.Input.Broker.Inputs[i]); err != nil {
+				return err
+			}
+		}
+	} else if err = p.configureInput(&conf.Input); err != nil {
+		return err
+	}
+	for i := range conf.Pipeline.Processors {
+		if err = p.configureProcessor(&conf.Pipeline.Processors[i]); err != nil {
+			return err
+		}
+	}
+	if conf.Output.Type == output.TypeBroker && len(outputs) > 1 {
+		for i := range conf.Output.Broker.Outputs {
+			if err = p.configureOutput(&conf.Output.Broker.Outputs[i]); err != nil {
+				return err
+			}
+		}
+	} else if err = p.configureOutput(&conf.Output); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "")
+	return nil
+}
+
+// lintCreated lints a created config along with any custom lint rules.
+func lintCreated(confBytes []byte, rules *config.LintRuleSet) ([]string, error) {
+	conf := config.New()
+	if err := yaml.Unmarshal(confBytes, &conf); err != nil {
+		return nil, err
+	}
+	lints, err := config.Lint(confBytes, conf)
+	if err != nil {
+		return nil, err
+	}
+	errs, warnings, err := applyLintRules(rules, conf)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(lints, errs...), warnings...), nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateInteractive(t *testing.T) {
+	answers := strings.Join([]string{
+		"nope",
+		"",
+		"bloblang,sleep",
+		"",
+		"root = content().uppercase()",
+		"1s",
+		"",
+	}, "\n") + "\n"
+
+	var prompts bytes.Buffer
+	conf := config.New()
+	require.NoError(t, createInteractive(&conf, strings.NewReader(answers), &prompts))
+
+	assert.Contains(t, prompts.String(), "Unrecognised input type: nope")
+	assert.Contains(t, prompts.String(), "# The duration of time to sleep for each execution.")
+
+	assert.Equal(t, "stdin", conf.Input.Type)
+	require.Len(t, conf.Pipeline.Processors, 2)
+	assert.Equal(t, "bloblang", conf.Pipeline.Processors[0].Type)
+	assert.Equal(t, "root = content().uppercase()", string(conf.Pipeline.Processors[0].Bloblang))
+	assert.Equal(t, "sleep", conf.Pipeline.Processors[1].Type)
+	assert.Equal(t, "1s", conf.Pipeline.Processors[1].Sleep.Duration)
+	assert.Equal(t, "stdout", conf.Output.Type)
+
+	sanit, err := conf.SanitisedNoDeprecated()
+	require.NoError(t, err)
+	confBytes, err := uconfig.MarshalYAML(sanit)
+	require.NoError(t, err)
+
+	lints, err := lintCreated(confBytes, nil)
+	require.NoError(t, err)
+	assert.Empty(t, lints)
+
+	conf = config.New()
+	err = createInteractive(&conf, strings.NewReader("stdin\n"), &prompts)
+	assert.EqualError(t, err, "unexpected end of input")
+}
+
+func TestCreateParseValue(t *testing.T) {
+	tests := []struct {
+		answer  string
+		current interface{}
+		output  interface{}
+		err     string
+	}{
+		{answer: "true", current: "foo", output: "true"},
+		{answer: "true", current: false, output: true},
+		{answer: "10", current: 5.0, output: 10},
+		{answer: "nope", current: 5.0, err: "expected a number value"},
+		{answer: "foo, bar", current: []interface{}{}, output: []interface{}{"foo", "bar"}},
+		{answer: "[ foo, bar ]", current: []interface{}{}, output: []interface{}{"foo", "bar"}},
+	}
+
+	for _, test := range tests {
+		v, err := parseValue(test.answer, test.current)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.answer)
+			continue
+		}
+		require.NoError(t, err, test.answer)
+		assert.Equal(t, test.output, v, test.answer)
+	}
+}
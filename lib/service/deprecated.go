@@ -353,6 +353,14 @@ config with a websocket input and output and a jmespath processor.`[1:],
 	return nil, false
 }
 
+// configPaths returns a slice containing a config path if it is not empty.
+func configPaths(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return []string{path}
+}
+
 func deprecatedExecute(configPath string, testSuffix string) {
 	fmt.Fprintln(os.Stderr, "Running with deprecated CLI flags, use --help to see an up to date summary.")
 
@@ -365,13 +373,13 @@ func deprecatedExecute(configPath string, testSuffix string) {
 	}
 
 	if depFlags.lintConfig {
-		lints := readConfig(configPath, nil)
+		lints := readConfig(configPaths(configPath), nil)
 		cmdDeprecatedLintConfig(lints)
 	}
 
 	// If the user wants the configuration to be printed we do so and then exit.
 	if depFlags.showConfigJSON || depFlags.showConfigYAML {
-		readConfig(configPath, nil)
+		readConfig(configPaths(configPath), nil)
 		cmdDeprecatedPrintConfig(&conf, depFlags.examples, depFlags.showAll, depFlags.showConfigJSON)
 	}
 
@@ -405,6 +413,6 @@ func deprecatedExecute(configPath string, testSuffix string) {
 		if len(depFlags.streamsDir) > 0 {
 			dirs = append(dirs, depFlags.streamsDir)
 		}
-		os.Exit(cmdService(configPath, nil, "", depFlags.strictConfig, depFlags.streamsMode, dirs))
+		os.Exit(cmdService(configPaths(configPath), nil, "", depFlags.strictConfig, depFlags.streamsMode, dirs, "", streamsSyncConfig{}, false, ""))
 	}
 }
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/input"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/output"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/tracer"
+	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
+	"github.com/Jeffail/benthos/v3/lib/util/text"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// componentStatuses maps the keys under which components are configured to a
+// function returning the status of a component type, which is used for
+// flagging deprecated components.
+var componentStatuses = map[string]func(string) docs.Status{
+	"input": func(t string) docs.Status {
+		return input.Constructors[t].Status
+	},
+	"output": func(t string) docs.Status {
+		return output.Constructors[t].Status
+	},
+	"processors": func(t string) docs.Status {
+		return processor.Constructors[t].Status
+	},
+	"buffer": func(t string) docs.Status {
+		return buffer.Constructors[t].Status
+	},
+	"caches": func(t string) docs.Status {
+		return cache.Constructors[t].Status
+	},
+	"rate_limits": func(t string) docs.Status {
+		return ratelimit.Constructors[t].Status
+	},
+	"metrics": func(t string) docs.Status {
+		return metrics.Constructors[t].Status
+	},
+	"tracer": func(t string) docs.Status {
+		return tracer.Constructors[t].Status
+	},
+}
+
+func init() {
+	componentStatuses["inputs"] = componentStatuses["input"]
+	componentStatuses["outputs"] = componentStatuses["output"]
+}
+
+// canonicalConfigYAML returns a config as YAML with all fields sorted by key.
+// Fields that are deprecated, or components of a deprecated type, are marked
+// with a comment, as are fields that were resolved from environment variables
+// or secrets within the raw config files at rawPaths, in which case the
+// comment contains the original expression. Any resolved secrets are
+// redacted.
+func canonicalConfigYAML(conf config.Type, rawPaths []string) ([]byte, error) {
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return nil, err
+	}
+	sanitNoDep, err := conf.SanitisedNoDeprecated()
+	if err != nil {
+		return nil, err
+	}
+
+	redactedBytes, err := secrets.MarshalRedactedYAML(sanit)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err = yaml.Unmarshal(redactedBytes, &root); err != nil {
+		return nil, err
+	}
+	var rootNoDep yaml.Node
+	if err = rootNoDep.Encode(sanitNoDep); err != nil {
+		return nil, err
+	}
+
+	exprs := map[string]string{}
+	for _, p := range rawPaths {
+		rawBytes, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config '%v': %v", p, err)
+		}
+		var rawRoot yaml.Node
+		if err = yaml.Unmarshal(rawBytes, &rawRoot); err != nil {
+			return nil, fmt.Errorf("failed to parse config '%v': %v", p, err)
+		}
+		walkEnvExpressions(nil, &rawRoot, exprs)
+	}
+
+	node := &root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	annotateDeprecated(node, &rootNoDep, "")
+	annotateEnvExpressions(nil, node, exprs)
+	sortMappingKeys(node)
+
+	return uconfig.MarshalYAML(node)
+}
+
+// walkEnvExpressions records the path of each scalar within a raw config that
+// contains environment variable or secret interpolations.
+func walkEnvExpressions(path []string, node *yaml.Node, exprs map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, c := range node.Content {
+			walkEnvExpressions(path, c, exprs)
+		}
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			walkEnvExpressions(append(path, node.Content[i].Value), node.Content[i+1], exprs)
+		}
+	case yaml.SequenceNode:
+		for i, c := range node.Content {
+			walkEnvExpressions(append(path, strconv.Itoa(i)), c, exprs)
+		}
+	case yaml.ScalarNode:
+		if text.ContainsEnvVariables([]byte(node.Value)) {
+			exprs[strings.Join(path, ".")] = node.Value
+		}
+	}
+}
+
+// annotateEnvExpressions adds a comment to scalars that were resolved from an
+// environment variable or secret containing the original expression.
+func annotateEnvExpressions(path []string, node *yaml.Node, exprs map[string]string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			annotateEnvExpressions(append(path, node.Content[i].Value), node.Content[i+1], exprs)
+		}
+	case yaml.SequenceNode:
+		for i, c := range node.Content {
+			annotateEnvExpressions(append(path, strconv.Itoa(i)), c, exprs)
+		}
+	case yaml.ScalarNode:
+		if expr, exists := exprs[strings.Join(path, ".")]; exists {
+			addComment(node, "from "+expr)
+		}
+	}
+}
+
+// annotateDeprecated adds a comment to fields of a config that are absent from
+// the same config with deprecated fields omitted, and to components of a
+// deprecated type. The parent argument is the nearest key under which
+// components might be configured.
+func annotateDeprecated(node, noDep *yaml.Node, parent string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+
+			if k.Value == "type" && v.Kind == yaml.ScalarNode {
+				if getStatus, exists := componentStatuses[parent]; exists {
+					if getStatus(v.Value) == docs.StatusDeprecated {
+						addComment(v, "deprecated component")
+					}
+				}
+			}
+
+			noDepV := mappingValue(noDep, k.Value)
+			if noDepV == nil {
+				if v.Kind == yaml.ScalarNode {
+					addComment(v, "deprecated")
+				} else {
+					addComment(k, "deprecated")
+				}
+				noDepV = v
+			}
+
+			childParent := parent
+			if _, exists := componentStatuses[k.Value]; exists {
+				childParent = k.Value
+			}
+			annotateDeprecated(v, noDepV, childParent)
+		}
+	case yaml.SequenceNode:
+		for i, c := range node.Content {
+			noDepC := c
+			if noDep != nil && noDep.Kind == yaml.SequenceNode && i < len(noDep.Content) {
+				noDepC = noDep.Content[i]
+			}
+			annotateDeprecated(c, noDepC, parent)
+		}
+	}
+}
+
+// mappingValue returns the value of a key within a mapping node, or nil if the
+// node is not a mapping or the key does not exist.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(node.Content)-1; i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func addComment(node *yaml.Node, comment string) {
+	if node.LineComment != "" {
+		node.LineComment += ", " + comment
+		return
+	}
+	node.LineComment = "# " + comment
+}
+
+// sortMappingKeys recursively sorts the key/value pairs of all mappings within
+// a node by key.
+func sortMappingKeys(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+		}
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return pairs[i][0].Value < pairs[j][0].Value
+		})
+		node.Content = node.Content[:0]
+		for _, p := range pairs {
+			node.Content = append(node.Content, p[0], p[1])
+		}
+	}
+	for _, c := range node.Content {
+		sortMappingKeys(c)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestCanonicalConfigYAML(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_echo_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	os.Setenv("BENTHOS_TEST_ECHO_PATH", "/tmp/echo.txt")
+	defer os.Unsetenv("BENTHOS_TEST_ECHO_PATH")
+
+	confPath := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte(`
+output:
+  file:
+    path: ${BENTHOS_TEST_ECHO_PATH}
+    delimiter: foo
+pipeline:
+  processors:
+    - sample:
+        retain: 50
+input:
+  stdin: {}
+`), 0644))
+
+	conf := config.New()
+	_, err = config.Read(confPath, true, &conf)
+	require.NoError(t, err)
+
+	res, err := canonicalConfigYAML(conf, []string{confPath})
+	require.NoError(t, err)
+
+	assert.Equal(t, "path: /tmp/echo.txt # from ${BENTHOS_TEST_ECHO_PATH}", findLine(string(res), "path: /tmp"))
+	assert.True(t, strings.HasSuffix(findLine(string(res), "delimiter: foo"), "# deprecated"))
+	assert.Equal(t, "type: sample # deprecated component", findLine(string(res), "type: sample"))
+	assert.Equal(t, "type: stdin", findLine(string(res), "type: stdin"))
+
+	var root yaml.Node
+	require.NoError(t, yaml.Unmarshal(res, &root))
+	var checkSorted func(node *yaml.Node)
+	checkSorted = func(node *yaml.Node) {
+		if node.Kind == yaml.MappingNode {
+			var keys []string
+			for i := 0; i < len(node.Content)-1; i += 2 {
+				keys = append(keys, node.Content[i].Value)
+			}
+			assert.True(t, sort.StringsAreSorted(keys), "%v", keys)
+		}
+		for _, c := range node.Content {
+			checkSorted(c)
+		}
+	}
+	checkSorted(&root)
+}
+
+func findLine(content, prefix string) string {
+	for _, l := range strings.Split(content, "\n") {
+		if l = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "- ")); strings.HasPrefix(l, prefix) {
+			return l
+		}
+	}
+	return ""
+}
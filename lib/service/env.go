@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Jeffail/benthos/v3/internal/filepath"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
+	"github.com/Jeffail/benthos/v3/lib/util/text"
+)
+
+//------------------------------------------------------------------------------
+
+// strictEnv determines whether configs that reference environment variables
+// that are not set, and have no default value, are rejected.
+var strictEnv = false
+
+// configEnvVariable is an environment variable referenced by one or more
+// config files.
+type configEnvVariable struct {
+	Name       string
+	Default    string
+	HasDefault bool
+	Paths      []string
+}
+
+// Set returns whether the environment variable is set.
+func (v configEnvVariable) Set() bool {
+	_, exists := os.LookupEnv(v.Name)
+	return exists
+}
+
+// configEnvVariables returns the environment variables referenced by a list of
+// config files in the order that they first appear, excluding secret
+// references. A variable is considered to have a default value only when all
+// references to it specify one.
+func configEnvVariables(paths []string) ([]configEnvVariable, error) {
+	var vars []configEnvVariable
+	indexes := map[string]int{}
+	for _, p := range paths {
+		configBytes, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config '%v': %v", p, err)
+		}
+		for _, ref := range text.EnvVariables(configBytes) {
+			if secrets.ContainsSecrets([]byte(ref.Expression)) {
+				continue
+			}
+			i, exists := indexes[ref.Name]
+			if !exists {
+				i = len(vars)
+				indexes[ref.Name] = i
+				vars = append(vars, configEnvVariable{
+					Name:       ref.Name,
+					Default:    ref.Default,
+					HasDefault: ref.HasDefault,
+				})
+			}
+			v := &vars[i]
+			if !ref.HasDefault {
+				v.HasDefault = false
+				v.Default = ""
+			}
+			if l := len(v.Paths); l == 0 || v.Paths[l-1] != p {
+				v.Paths = append(v.Paths, p)
+			}
+		}
+	}
+	return vars, nil
+}
+
+// checkEnvVariables returns a single error listing every environment variable
+// referenced by a list of config files that is not set and has no default
+// value.
+func checkEnvVariables(paths []string) error {
+	vars, err := configEnvVariables(paths)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, v := range vars {
+		if !v.HasDefault && !v.Set() {
+			missing = append(missing, fmt.Sprintf("%v (%v)", v.Name, strings.Join(v.Paths, ", ")))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.New("environment variables without a default value are not set: " + strings.Join(missing, ", "))
+}
+
+// printEnvVariables writes a table of the environment variables referenced by
+// a list of config files, including whether each is set. The values of
+// variables are not printed as they might contain secrets.
+func printEnvVariables(w io.Writer, paths []string) error {
+	vars, err := configEnvVariables(paths)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDEFAULT\tSTATUS\tFILES")
+	for _, v := range vars {
+		def, status := "-", "set"
+		if v.HasDefault {
+			def = v.Default
+		}
+		if !v.Set() {
+			status = "unset"
+			if !v.HasDefault {
+				status = "missing"
+			}
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", v.Name, def, status, strings.Join(v.Paths, ", "))
+	}
+	return tw.Flush()
+}
+
+func cmdPrintEnv(confPaths, resourcesPaths []string) int {
+	var err error
+	if resourcesPaths, err = filepath.Globs(resourcesPaths); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve resource glob pattern: %v\n", err)
+		return 1
+	}
+	if err = printEnvVariables(os.Stdout, append(confPaths, resourcesPaths...)); err != nil {
+		fmt.Fprintf(os.Stderr, "Print env error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+//------------------------------------------------------------------------------
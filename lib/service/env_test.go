@@ -0,0 +1,95 @@
+package service
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigEnvVariables(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_env_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	os.Setenv("BENTHOS_TEST_ENV_SET", "foo")
+	defer os.Unsetenv("BENTHOS_TEST_ENV_SET")
+	os.Unsetenv("BENTHOS_TEST_ENV_MISSING_A")
+	os.Unsetenv("BENTHOS_TEST_ENV_MISSING_B")
+	os.Unsetenv("BENTHOS_TEST_ENV_DEFAULT")
+
+	pathA := filepath.Join(tmpDir, "a.yaml")
+	require.NoError(t, ioutil.WriteFile(pathA, []byte(`
+input:
+  kafka:
+    addresses: [ ${BENTHOS_TEST_ENV_SET} ]
+    topics: [ ${BENTHOS_TEST_ENV_MISSING_A} ]
+    client_id: ${BENTHOS_TEST_ENV_DEFAULT:benthos}
+    tls:
+      root_cas: ${secret:file:/tmp/ca.pem}
+`), 0644))
+
+	pathB := filepath.Join(tmpDir, "b.yaml")
+	require.NoError(t, ioutil.WriteFile(pathB, []byte(`
+output:
+  kafka:
+    addresses: [ ${BENTHOS_TEST_ENV_SET} ]
+    topic: ${BENTHOS_TEST_ENV_MISSING_B}
+    client_id: ${BENTHOS_TEST_ENV_MISSING_A:benthos}
+`), 0644))
+
+	vars, err := configEnvVariables([]string{pathA, pathB})
+	require.NoError(t, err)
+	assert.Equal(t, []configEnvVariable{
+		{Name: "BENTHOS_TEST_ENV_SET", Paths: []string{pathA, pathB}},
+		{Name: "BENTHOS_TEST_ENV_MISSING_A", Paths: []string{pathA, pathB}},
+		{Name: "BENTHOS_TEST_ENV_DEFAULT", Default: "benthos", HasDefault: true, Paths: []string{pathA}},
+		{Name: "BENTHOS_TEST_ENV_MISSING_B", Paths: []string{pathB}},
+	}, vars)
+
+	err = checkEnvVariables([]string{pathA, pathB})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BENTHOS_TEST_ENV_MISSING_A ("+pathA+", "+pathB+")")
+	assert.Contains(t, err.Error(), "BENTHOS_TEST_ENV_MISSING_B ("+pathB+")")
+	assert.NotContains(t, err.Error(), "BENTHOS_TEST_ENV_SET")
+	assert.NotContains(t, err.Error(), "BENTHOS_TEST_ENV_DEFAULT")
+
+	os.Setenv("BENTHOS_TEST_ENV_MISSING_A", "bar")
+	os.Setenv("BENTHOS_TEST_ENV_MISSING_B", "baz")
+	defer os.Unsetenv("BENTHOS_TEST_ENV_MISSING_A")
+	defer os.Unsetenv("BENTHOS_TEST_ENV_MISSING_B")
+	assert.NoError(t, checkEnvVariables([]string{pathA, pathB}))
+
+	os.Unsetenv("BENTHOS_TEST_ENV_MISSING_B")
+
+	var buf bytes.Buffer
+	require.NoError(t, printEnvVariables(&buf, []string{pathA, pathB}))
+	assert.Contains(t, buf.String(), "NAME")
+	assert.Regexp(t, `BENTHOS_TEST_ENV_DEFAULT +benthos +unset`, buf.String())
+	assert.Regexp(t, `BENTHOS_TEST_ENV_MISSING_B +- +missing`, buf.String())
+	assert.Regexp(t, `BENTHOS_TEST_ENV_SET +- +set`, buf.String())
+	assert.NotContains(t, buf.String(), "foo")
+}
+
+func TestStreamConfigFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_env_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	for _, p := range []string{"a.yaml", "b/c.yml", "b/d.txt", "b/e" + testSuffix + ".yaml"} {
+		p = filepath.Join(tmpDir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, ioutil.WriteFile(p, []byte("{}"), 0644))
+	}
+
+	files, err := streamConfigFiles([]string{tmpDir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(tmpDir, "a.yaml"),
+		filepath.Join(tmpDir, "b", "c.yml"),
+	}, files)
+}
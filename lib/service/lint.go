@@ -32,13 +32,77 @@ func resolveLintPath(path string) (string, bool) {
 }
 
 type pathLint struct {
-	source string
-	line   int
-	lint   string
-	err    string
+	source  string
+	line    int
+	lint    string
+	err     string
+	warning string
 }
 
-func lintFile(path string) (pathLints []pathLint) {
+// readLintRules reads custom lint rules from a path, or returns nil if the path
+// is empty.
+func readLintRules(path string) *config.LintRuleSet {
+	if path == "" {
+		return nil
+	}
+	rules, err := config.ReadLintRules(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Lint rules read error: %v\n", err)
+		os.Exit(1)
+	}
+	return rules
+}
+
+// applyLintRules executes custom lint rules against a config and returns the
+// violations of rules with an error severity and those with a warning severity
+// separately.
+func applyLintRules(rules *config.LintRuleSet, conf config.Type) (errs, warnings []string, err error) {
+	if rules == nil {
+		return nil, nil, nil
+	}
+	violations, err := rules.Lint(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, v := range violations {
+		if v.Severity == config.LintRuleSeverityWarning {
+			warnings = append(warnings, v.String())
+		} else {
+			errs = append(errs, v.String())
+		}
+	}
+	return errs, warnings, nil
+}
+
+// ruleLints executes custom lint rules against a config and returns the
+// violations as lints.
+func ruleLints(source string, line int, rules *config.LintRuleSet, conf config.Type) (pathLints []pathLint) {
+	errs, warnings, err := applyLintRules(rules, conf)
+	if err != nil {
+		return []pathLint{{
+			source: source,
+			line:   line,
+			err:    err.Error(),
+		}}
+	}
+	for _, l := range errs {
+		pathLints = append(pathLints, pathLint{
+			source: source,
+			line:   line,
+			lint:   l,
+		})
+	}
+	for _, l := range warnings {
+		pathLints = append(pathLints, pathLint{
+			source:  source,
+			line:    line,
+			warning: l,
+		})
+	}
+	return
+}
+
+func lintFile(path string, rules *config.LintRuleSet) (pathLints []pathLint) {
 	conf := config.New()
 	lints, err := config.Read(path, true, &conf)
 	if err != nil {
@@ -54,10 +118,35 @@ func lintFile(path string) (pathLints []pathLint) {
 			lint:   l,
 		})
 	}
+	pathLints = append(pathLints, ruleLints(path, 0, rules, conf)...)
+	return
+}
+
+// lintLayeredFiles lints the result of merging config overlay files, where
+// lints are attributed to the list of files.
+func lintLayeredFiles(paths []string, rules *config.LintRuleSet) (pathLints []pathLint) {
+	source := strings.Join(paths, ", ")
+
+	conf := config.New()
+	lints, err := config.ReadLayered(paths, true, &conf)
+	if err != nil {
+		pathLints = append(pathLints, pathLint{
+			source: source,
+			err:    err.Error(),
+		})
+		return
+	}
+	for _, l := range lints {
+		pathLints = append(pathLints, pathLint{
+			source: source,
+			lint:   l,
+		})
+	}
+	pathLints = append(pathLints, ruleLints(source, 0, rules, conf)...)
 	return
 }
 
-func lintMDSnippets(path string) (pathLints []pathLint) {
+func lintMDSnippets(path string, rules *config.LintRuleSet) (pathLints []pathLint) {
 	rawBytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		pathLints = append(pathLints, pathLint{
@@ -111,6 +200,7 @@ func lintMDSnippets(path string) (pathLints []pathLint) {
 					lint:   l,
 				})
 			}
+			pathLints = append(pathLints, ruleLints(path, snippetLine, rules, conf)...)
 		}
 
 		if nextSnippet = bytes.Index(rawBytes[endOfSnippet:], []byte("```yaml")); nextSnippet != -1 {
@@ -133,8 +223,14 @@ func lintCliCommand() *cli.Command {
    benthos lint ./configs/...
    
    If a path ends with '...' then Benthos will walk the target and lint any
-   files with the .yaml or .yml extension.`[4:],
+   files with the .yaml or .yml extension.
+
+   Custom lint rules can be enforced with the flag --lint-rules, where
+   violations of rules with a warning severity are reported without failing:
+
+   benthos --lint-rules ./rules.yaml lint ./configs/...`[4:],
 		Action: func(c *cli.Context) error {
+			rules := readLintRules(c.String("lint-rules"))
 			var targets []string
 			for _, p := range c.Args().Slice() {
 				var recurse bool
@@ -159,27 +255,32 @@ func lintCliCommand() *cli.Command {
 					targets = append(targets, p)
 				}
 			}
-			if conf := c.String("config"); len(conf) > 0 {
-				targets = append(targets, conf)
-			}
 			var pathLints []pathLint
+			if confs := c.StringSlice("config"); len(confs) == 1 {
+				targets = append(targets, confs[0])
+			} else if len(confs) > 1 {
+				pathLints = append(pathLints, lintLayeredFiles(confs, rules)...)
+			}
 			for _, target := range targets {
 				if len(target) == 0 {
 					continue
 				}
 				if path.Ext(target) == ".md" {
-					pathLints = append(pathLints, lintMDSnippets(target)...)
+					pathLints = append(pathLints, lintMDSnippets(target, rules)...)
 				} else {
-					pathLints = append(pathLints, lintFile(target)...)
+					pathLints = append(pathLints, lintFile(target, rules)...)
 				}
 			}
-			if len(pathLints) == 0 {
-				os.Exit(0)
-			}
+			failed := false
 			for _, lint := range pathLints {
 				message := yellow(lint.lint)
 				if len(lint.err) > 0 {
 					message = red(lint.err)
+				} else if len(lint.warning) > 0 {
+					message = "warning: " + lint.warning
+				}
+				if len(lint.warning) == 0 {
+					failed = true
 				}
 				if lint.line > 0 {
 					fmt.Fprintf(os.Stderr, "%v: from snippet at line %v: %v\n", lint.source, lint.line, message)
@@ -187,7 +288,10 @@ func lintCliCommand() *cli.Command {
 					fmt.Fprintf(os.Stderr, "%v: %v\n", lint.source, message)
 				}
 			}
-			os.Exit(1)
+			if failed {
+				os.Exit(1)
+			}
+			os.Exit(0)
 			return nil
 		},
 	}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	strmmgr "github.com/Jeffail/benthos/v3/lib/stream/manager"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// watchInterval is the period between checks for changes to watched config
+// files.
+const watchInterval = time.Second
+
+// snapshotFiles returns the modification times of all files at the provided
+// paths, where directories are walked.
+func snapshotFiles(paths []string) map[string]time.Time {
+	snapshot := map[string]time.Time{}
+	for _, p := range paths {
+		_ = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			snapshot[path] = info.ModTime()
+			return nil
+		})
+	}
+	return snapshot
+}
+
+// watchFiles polls the provided paths at an interval and writes to reloadChan
+// each time a file is modified, created or removed, until closeChan is closed.
+func watchFiles(paths []string, interval time.Duration, reloadChan chan<- struct{}, closeChan <-chan struct{}) {
+	snapshot := snapshotFiles(paths)
+	for {
+		select {
+		case <-time.After(interval):
+		case <-closeChan:
+			return
+		}
+		if next := snapshotFiles(paths); !reflect.DeepEqual(snapshot, next) {
+			snapshot = next
+			select {
+			case reloadChan <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// changedServiceFields returns the names of service wide config fields, which
+// excludes the stream, that differ between two configs.
+func changedServiceFields(before, after config.Type) []string {
+	var changed []string
+	for _, f := range []struct {
+		name          string
+		before, after interface{}
+	}{
+		{"http", before.HTTP, after.HTTP},
+		{"resources", before.Manager, after.Manager},
+		{"logger", before.Logger, after.Logger},
+		{"metrics", before.Metrics, after.Metrics},
+		{"tracer", before.Tracer, after.Tracer},
+		{"load_shedding", before.LoadShedding, after.LoadShedding},
+		{"shutdown_timeout", before.SystemCloseTimeout, after.SystemCloseTimeout},
+		{"shutdown_drain_timeout", before.DrainTimeout, after.DrainTimeout},
+	} {
+		if !reflect.DeepEqual(f.before, f.after) {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+//------------------------------------------------------------------------------
+
+// reloadableStream is a stream that can be restarted with a new config without
+// restarting the service. A stream being restarted is stopped gracefully, and
+// therefore messages in flight are delivered before the new stream begins.
+type reloadableStream struct {
+	opts    []func(*stream.Type)
+	onClose func()
+
+	mut    sync.Mutex
+	conf   stream.Config
+	strm   *stream.Type
+	closed bool
+}
+
+// newReloadableStream creates and starts a stream from a config. The onClose
+// closure is called when the stream shuts down, but not when it is stopped in
+// order to be restarted.
+func newReloadableStream(conf stream.Config, onClose func(), opts ...func(*stream.Type)) (*reloadableStream, error) {
+	r := &reloadableStream{
+		opts:    opts,
+		onClose: onClose,
+	}
+	if err := r.start(conf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *reloadableStream) start(conf stream.Config) error {
+	var strm *stream.Type
+	opts := append([]func(*stream.Type){}, r.opts...)
+	opts = append(opts, stream.OptOnClose(func() {
+		r.mut.Lock()
+		current := r.strm == strm
+		r.mut.Unlock()
+		if current {
+			r.onClose()
+		}
+	}))
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.closed {
+		return types.ErrTypeClosed
+	}
+
+	var err error
+	if strm, err = stream.New(conf, opts...); err != nil {
+		return err
+	}
+	r.conf, r.strm = conf, strm
+	return nil
+}
+
+// Reload restarts the stream with a new config, returning false if the config
+// is unchanged. If the stream fails to start with the new config it is
+// restarted with the previous config and an error is returned. If the previous
+// config also fails to start then the stream is closed and the onClose closure
+// is called.
+func (r *reloadableStream) Reload(conf stream.Config, timeout time.Duration) (bool, error) {
+	r.mut.Lock()
+	if r.closed {
+		r.mut.Unlock()
+		return false, types.ErrTypeClosed
+	}
+	if reflect.DeepEqual(r.conf, conf) {
+		r.mut.Unlock()
+		return false, nil
+	}
+	prevConf, prev := r.conf, r.strm
+	r.strm = nil
+	r.mut.Unlock()
+
+	// A stream that fails to stop in time has still been closed, albeit not
+	// gracefully, and therefore the new stream is started regardless.
+	stopErr := prev.Stop(timeout)
+
+	err := r.start(conf)
+	if err == nil {
+		if stopErr != nil {
+			return true, fmt.Errorf("failed to stop previous stream gracefully: %v", stopErr)
+		}
+		return true, nil
+	}
+	if prevErr := r.start(prevConf); prevErr != nil {
+		r.mut.Lock()
+		alreadyClosed := r.closed
+		r.closed = true
+		r.mut.Unlock()
+		if !alreadyClosed {
+			r.onClose()
+		}
+		return false, fmt.Errorf("%v, and failed to restart the previous stream: %v", err, prevErr)
+	}
+	return false, err
+}
+
+// Stop attempts to close the stream within the specified timeout period.
+func (r *reloadableStream) Stop(timeout time.Duration) error {
+	r.mut.Lock()
+	r.closed = true
+	strm := r.strm
+	r.mut.Unlock()
+	if strm == nil {
+		return nil
+	}
+	return strm.Stop(timeout)
+}
+
+// loadStreamConfigs reads stream configs from files and directories into a map
+// of stream ids to configs, returning any lint errors followed by violations of
+// custom lint rules with a warning severity.
+func loadStreamConfigs(paths []string, rules *config.LintRuleSet) (map[string]stream.Config, []string, []string, error) {
+	if strictEnv {
+		files, err := streamConfigFiles(paths)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if err = checkEnvVariables(files); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	streamConfs := map[string]stream.Config{}
+	var streamLints, streamWarnings []string
+	for _, path := range paths {
+		lints, err := strmmgr.LoadStreamConfigsFromPath(path, testSuffix, streamConfs)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		streamLints = append(streamLints, lints...)
+	}
+
+	ids := make([]string, 0, len(streamConfs))
+	for id := range streamConfs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		conf := config.New()
+		conf.Config = streamConfs[id]
+		errs, warnings, err := applyLintRules(rules, conf)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to apply lint rules to stream '%v': %v", id, err)
+		}
+		for _, l := range errs {
+			streamLints = append(streamLints, fmt.Sprintf("stream '%v': %v", id, l))
+		}
+		for _, l := range warnings {
+			streamWarnings = append(streamWarnings, fmt.Sprintf("stream '%v': %v", id, l))
+		}
+	}
+	return streamConfs, streamLints, streamWarnings, nil
+}
+
+// streamConfigFiles returns the stream config files at the provided paths,
+// where directories are walked for .yaml and .yml files that are not unit
+// test definitions.
+func streamConfigFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		if err = filepath.Walk(p, func(path string, info os.FileInfo, werr error) error {
+			if werr != nil {
+				return werr
+			}
+			ext := filepath.Ext(path)
+			if info.IsDir() || (ext != ".yaml" && ext != ".yml") ||
+				strings.HasSuffix(strings.TrimSuffix(path, ext), testSuffix) {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// streamsDiff describes the changes applied to a stream manager when stream
+// configs are reloaded.
+type streamsDiff struct {
+	Created []string          `json:"created,omitempty"`
+	Updated []string          `json:"updated,omitempty"`
+	Removed []string          `json:"removed,omitempty"`
+	Failed  map[string]string `json:"failed,omitempty"`
+}
+
+func (d *streamsDiff) fail(id string, err error) {
+	if d.Failed == nil {
+		d.Failed = map[string]string{}
+	}
+	d.Failed[id] = err.Error()
+}
+
+// reloadStreamConfigs applies the differences between two sets of stream
+// configs loaded from files to a stream manager. Streams that are new are
+// created, streams that have changed are restarted and streams that no longer
+// exist are removed. Streams that are unchanged are left running, even if
+// they have since been modified via the REST API.
+func reloadStreamConfigs(
+	mgr *strmmgr.Type,
+	before, after map[string]stream.Config,
+	timeout time.Duration,
+	logger log.Modular,
+) (diff streamsDiff) {
+	ids := make([]string, 0, len(after))
+	for id := range after {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		conf := after[id]
+		prevConf, exists := before[id]
+		if !exists {
+			if err := mgr.Create(id, conf); err != nil {
+				logger.Errorf("Failed to create stream (%v): %v\n", id, err)
+				diff.fail(id, err)
+			} else {
+				logger.Infof("Created stream (%v) from reloaded config\n", id)
+				diff.Created = append(diff.Created, id)
+			}
+			continue
+		}
+		if reflect.DeepEqual(prevConf, conf) {
+			continue
+		}
+		if err := mgr.Update(id, conf, timeout); err != nil {
+			logger.Errorf("Failed to restart stream (%v): %v\n", id, err)
+			diff.fail(id, err)
+		} else {
+			logger.Infof("Restarted stream (%v) with reloaded config\n", id)
+			diff.Updated = append(diff.Updated, id)
+		}
+	}
+
+	ids = ids[:0]
+	for id := range before {
+		if _, exists := after[id]; !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := mgr.Delete(id, timeout); err == strmmgr.ErrStreamDoesNotExist {
+			continue
+		} else if err != nil {
+			logger.Errorf("Failed to remove stream (%v): %v\n", id, err)
+			diff.fail(id, err)
+		} else {
+			logger.Infof("Removed stream (%v) as it no longer exists within the reloaded config\n", id)
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// logIgnoredChanges logs a warning for service wide config changes that cannot
+// be applied without restarting the service.
+func logIgnoredChanges(logger log.Modular, before, after config.Type) {
+	for _, name := range changedServiceFields(before, after) {
+		logger.Warnf("Config field %v has changed but cannot be reloaded, restart the service in order to apply it\n", name)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedServiceFields(t *testing.T) {
+	before, after := config.New(), config.New()
+	assert.Empty(t, changedServiceFields(before, after))
+
+	after.Input.Type = "nats"
+	after.Pipeline.Threads = 10
+	assert.Empty(t, changedServiceFields(before, after))
+
+	after.HTTP.Address = "0.0.0.0:4196"
+	after.Logger.LogLevel = "DEBUG"
+	assert.Equal(t, []string{"http", "logger"}, changedServiceFields(before, after))
+}
+
+func TestWatchFiles(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_watch_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	reloadChan := make(chan struct{}, 1)
+	closeChan := make(chan struct{})
+	defer close(closeChan)
+
+	path := filepath.Join(tmpDir, "foo.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("foo"), 0644))
+
+	go watchFiles([]string{tmpDir}, time.Millisecond*10, reloadChan, closeChan)
+
+	select {
+	case <-reloadChan:
+		t.Fatal("unexpected reload")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "bar.yaml"), []byte("bar"), 0644))
+
+	select {
+	case <-reloadChan:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
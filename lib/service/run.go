@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/Jeffail/benthos/v3/internal/template"
 	"github.com/Jeffail/benthos/v3/lib/config"
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/output"
@@ -13,6 +14,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/service/blobl"
 	"github.com/Jeffail/benthos/v3/lib/service/test"
 	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
 	"github.com/urfave/cli/v2"
 )
 
@@ -164,11 +166,10 @@ func Run() {
 			Value: "",
 			Usage: "override the configured log level, options are: off, error, warn, info, debug, trace",
 		},
-		&cli.StringFlag{
+		&cli.StringSliceFlag{
 			Name:    "config",
 			Aliases: []string{"c"},
-			Value:   "",
-			Usage:   "a path to a configuration file",
+			Usage:   "a path to a configuration file, can be specified multiple times in order to deep merge overlay files onto a base config",
 		},
 		&cli.StringSliceFlag{
 			Name:    "resources",
@@ -180,6 +181,32 @@ func Run() {
 			Value: false,
 			Usage: "continue to execute a config containing linter errors",
 		},
+		&cli.BoolFlag{
+			Name:    "watch",
+			Aliases: []string{"w"},
+			Value:   false,
+			Usage:   "watch config files for changes, and reload them when they change or a SIGHUP is received",
+		},
+		&cli.StringSliceFlag{
+			Name:    "templates",
+			Aliases: []string{"t"},
+			Usage:   "pull in component templates from a file or directory, which can then be used as components within configs, supports glob patterns (requires quotes)",
+		},
+		&cli.BoolFlag{
+			Name:  "strict-env",
+			Value: false,
+			Usage: "fail to read configs that reference environment variables that are not set and have no default value",
+		},
+		&cli.BoolFlag{
+			Name:  "print-env",
+			Value: false,
+			Usage: "print the environment variables referenced by the config and resource files, then exit",
+		},
+		&cli.StringFlag{
+			Name:  "lint-rules",
+			Value: "",
+			Usage: "a path to a file of custom lint rules to enforce, which are Bloblang queries over the config",
+		},
 	}
 	if len(customFlags) > 0 {
 		flags = append(flags, customFlags...)
@@ -194,24 +221,45 @@ func Run() {
    benthos list inputs
    benthos create kafka//file > ./config.yaml
    benthos -c ./config.yaml
-   benthos -r "./production/*.yaml" -c ./config.yaml`[4:],
+   benthos -r "./production/*.yaml" -c ./config.yaml
+   benthos -c ./config.yaml -c ./production.yaml
+   benthos -w -c ./config.yaml
+   benthos --print-env -c ./config.yaml
+   benthos -t "./templates/*.yaml" -c ./config.yaml`[4:],
 		Flags: flags,
+		Before: func(c *cli.Context) error {
+			strictEnv = c.Bool("strict-env")
+			if templates := c.StringSlice("templates"); len(templates) > 0 {
+				if err := template.InitTemplates(templates...); err != nil {
+					fmt.Fprintf(os.Stderr, "Template read error: %v\n", err)
+					os.Exit(1)
+				}
+			}
+			return nil
+		},
 		Action: func(c *cli.Context) error {
 			if c.Bool("version") {
 				cmdVersion(Version, DateBuilt)
 			}
+			if c.Bool("print-env") {
+				os.Exit(cmdPrintEnv(resolveConfigPaths(c.StringSlice("config")), c.StringSlice("resources")))
+			}
 			if c.Args().Len() > 0 {
 				fmt.Fprintf(os.Stderr, "Unrecognised command: %v\n", c.Args().First())
 				cli.ShowAppHelp(c)
 				os.Exit(1)
 			}
 			os.Exit(cmdService(
-				c.String("config"),
+				c.StringSlice("config"),
 				c.StringSlice("resources"),
 				c.String("log.level"),
 				!c.Bool("chilled"),
 				false,
 				nil,
+				"",
+				streamsSyncConfig{},
+				c.Bool("watch"),
+				c.String("lint-rules"),
 			))
 			return nil
 		},
@@ -222,18 +270,39 @@ func Run() {
 				Description: `
    This simple command is useful for sanity checking a config if it isn't
    behaving as expected, as it shows you a normalised version after environment
-   variables have been resolved:
+   variables have been resolved. Any resolved secrets are redacted:
+
+   benthos -c ./config.yaml echo | less
+
+   With the --canonical flag fields are sorted by key, and comments are added
+   to fields that are deprecated and fields that were resolved from
+   environment variables or secrets, which makes the output useful for
+   comparing the behaviour of a config across versions of Benthos:
 
-   benthos -c ./config.yaml echo | less`[4:],
+   benthos -c ./config.yaml echo --canonical`[4:],
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "canonical",
+						Value: false,
+						Usage: "sort fields by key and annotate deprecated fields and those resolved from environment variables",
+					},
+				},
 				Action: func(c *cli.Context) error {
-					readConfig(c.String("config"), c.StringSlice("resources"))
-					outConf, err := conf.Sanitised()
-					if err == nil {
-						var configYAML []byte
-						if configYAML, err = uconfig.MarshalYAML(outConf); err == nil {
-							fmt.Println(string(configYAML))
+					confPaths := resolveConfigPaths(c.StringSlice("config"))
+					readConfig(confPaths, c.StringSlice("resources"))
+					var configYAML []byte
+					var err error
+					if c.Bool("canonical") {
+						configYAML, err = canonicalConfigYAML(conf, append(confPaths, c.StringSlice("resources")...))
+					} else {
+						var outConf interface{}
+						if outConf, err = conf.Sanitised(); err == nil {
+							configYAML, err = secrets.MarshalRedactedYAML(outConf)
 						}
 					}
+					if err == nil {
+						fmt.Println(string(configYAML))
+					}
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "Echo error: %v\n", err)
 						os.Exit(1)
@@ -258,16 +327,47 @@ func Run() {
    pipeline, output) will be ignored. Other fields will be shared across all
    loaded streams (resources, metrics, etc).
 
+   Stream configs can also be synchronised from a git repository or an S3
+   prefix, in which case streams are created, updated and removed as their
+   configs change:
+
+   benthos streams --sync 's3://bucket/streams/'
+   benthos streams --sync 'git+https://github.com/org/repo.git?ref=main&path=streams'
+
    For more information check out the docs at:
    https://benthos.dev/docs/guides/streams_mode/about`[4:],
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "audit-log",
+						Value: "",
+						Usage: "An optional file path where a line of JSON is appended for each stream created, updated or deleted via the REST API.",
+					},
+					&cli.StringFlag{
+						Name:  "sync",
+						Value: "",
+						Usage: "An optional git repository (git+https://host/repo.git?ref=main&path=streams) or S3 prefix (s3://bucket/prefix) to synchronise stream configs from.",
+					},
+					&cli.StringFlag{
+						Name:  "sync-interval",
+						Value: "1m",
+						Usage: "The period between synchronisations of stream configs from the sync source, set to an empty string in order to only synchronise when triggered via the /sync endpoint.",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					os.Exit(cmdService(
-						c.String("config"),
+						c.StringSlice("config"),
 						c.StringSlice("resources"),
 						c.String("log.level"),
 						!c.Bool("chilled"),
 						true,
 						c.Args().Slice(),
+						c.String("audit-log"),
+						streamsSyncConfig{
+							Source:   c.String("sync"),
+							Interval: c.String("sync-interval"),
+						},
+						c.Bool("watch"),
+						c.String("lint-rules"),
 					))
 					return nil
 				},
@@ -307,25 +407,55 @@ func Run() {
    benthos create stdin/bloblang,awk/nats
    benthos create file,http_server/protobuf/http_client
 
-   If the expression is omitted a default config is created.`[4:],
+   If the expression is omitted a default config is created.
+
+   With the --interactive flag the components are instead chosen by answering
+   prompts, which also walk through the common fields of each component along
+   with their documentation. The resulting config is linted before it is
+   printed:
+
+   benthos create --interactive > ./config.yaml`[4:],
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "interactive",
+						Aliases: []string{"i"},
+						Value:   false,
+						Usage:   "Choose and configure components by answering prompts.",
+					},
+				},
 				Action: func(c *cli.Context) error {
-					if expression := c.Args().First(); len(expression) > 0 {
+					interactive := c.Bool("interactive")
+					if interactive {
+						if err := createInteractive(&conf, os.Stdin, os.Stderr); err != nil {
+							fmt.Fprintf(os.Stderr, "Generate error: %v\n", err)
+							os.Exit(1)
+						}
+					} else if expression := c.Args().First(); len(expression) > 0 {
 						if err := addExpression(&conf, expression); err != nil {
 							fmt.Fprintf(os.Stderr, "Generate error: %v\n", err)
 							os.Exit(1)
 						}
 					}
+					var configYAML []byte
 					outConf, err := conf.SanitisedNoDeprecated()
 					if err == nil {
-						var configYAML []byte
-						if configYAML, err = uconfig.MarshalYAML(outConf); err == nil {
-							fmt.Println(string(configYAML))
-						}
+						configYAML, err = uconfig.MarshalYAML(outConf)
 					}
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "Generate error: %v\n", err)
 						os.Exit(1)
 					}
+					if interactive {
+						lints, err := lintCreated(configYAML, readLintRules(c.String("lint-rules")))
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "Lint error: %v\n", err)
+							os.Exit(1)
+						}
+						for _, l := range lints {
+							fmt.Fprintf(os.Stderr, "Lint: %v\n", l)
+						}
+					}
+					fmt.Println(string(configYAML))
 					return nil
 				},
 			},
@@ -359,7 +489,7 @@ func Run() {
 		}
 
 		deprecatedExecute(*configPath, testSuffix)
-		os.Exit(cmdService(*configPath, nil, "", false, false, nil))
+		os.Exit(cmdService(configPaths(*configPath), nil, "", false, false, nil, "", streamsSyncConfig{}, false, ""))
 		return nil
 	}
 
@@ -3,12 +3,14 @@ package service
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime/pprof"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/manager"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/shedding"
 	"github.com/Jeffail/benthos/v3/lib/stream"
 	strmmgr "github.com/Jeffail/benthos/v3/lib/stream/manager"
 	"github.com/Jeffail/benthos/v3/lib/tracer"
@@ -92,7 +95,13 @@ func OptOnManagerInit(fn ManagerInitFunc) func() {
 
 //------------------------------------------------------------------------------
 
-func readConfig(path string, resourcesPaths []string) (lints []string) {
+// resolveConfigPaths returns the provided config paths, or if there are none
+// the first default config path that exists.
+func resolveConfigPaths(paths []string) []string {
+	if len(paths) > 0 {
+		return paths
+	}
+
 	// A list of default config paths to check for if not explicitly defined
 	defaultPaths := []string{
 		"/benthos.yaml",
@@ -100,32 +109,35 @@ func readConfig(path string, resourcesPaths []string) (lints []string) {
 		"/etc/benthos.yaml",
 	}
 
-	if len(path) > 0 {
-		var err error
-		if lints, err = config.Read(path, true, &conf); err != nil {
-			fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
-			os.Exit(1)
+	// Iterate default config paths
+	for _, path := range defaultPaths {
+		if _, err := os.Stat(path); err == nil {
+			fmt.Fprintf(os.Stderr, "Config file not specified, reading from %v\n", path)
+			return []string{path}
 		}
-	} else {
-		// Iterate default config paths
-		for _, path := range defaultPaths {
-			if _, err := os.Stat(path); err == nil {
-				fmt.Fprintf(os.Stderr, "Config file not specified, reading from %v\n", path)
-
-				if lints, err = config.Read(path, true, &conf); err != nil {
-					fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
-					os.Exit(1)
-				}
-				break
-			}
+	}
+	return nil
+}
+
+// readConfigFiles reads and merges config files followed by resource files
+// into a config, returning any lint errors.
+func readConfigFiles(paths []string, resourcesPaths []string, conf *config.Type) (lints []string, err error) {
+	if strictEnv {
+		if err = checkEnvVariables(append(append([]string{}, paths...), resourcesPaths...)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(paths) > 0 {
+		if lints, err = config.ReadLayered(paths, true, conf); err != nil {
+			return nil, err
 		}
 	}
 
 	for _, rPath := range resourcesPaths {
 		resourceBytes, err := config.ReadWithJSONPointers(rPath, true)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Resource configuration file read error: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to read resources '%v': %v", rPath, err)
 		}
 		extraMgrWrapper := struct {
 			Manager manager.Config `yaml:"resources"`
@@ -133,34 +145,64 @@ func readConfig(path string, resourcesPaths []string) (lints []string) {
 			Manager: manager.NewConfig(),
 		}
 		if err = yaml.Unmarshal(resourceBytes, &extraMgrWrapper); err != nil {
-			fmt.Fprintf(os.Stderr, "Resource configuration file read error: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to read resources '%v': %v", rPath, err)
 		}
 		if err = conf.Manager.AddFrom(&extraMgrWrapper.Manager); err != nil {
-			fmt.Fprintf(os.Stderr, "Resource configuration file read error: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to read resources '%v': %v", rPath, err)
 		}
 	}
+	return lints, nil
+}
 
+func readConfig(paths []string, resourcesPaths []string) (lints []string) {
+	var err error
+	if lints, err = readConfigFiles(resolveConfigPaths(paths), resourcesPaths, &conf); err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
+		os.Exit(1)
+	}
 	return
 }
 
 //------------------------------------------------------------------------------
 
 func cmdService(
-	confPath string,
+	confPaths []string,
 	resourcesPaths []string,
 	overrideLogLevel string,
 	strict bool,
 	streamsMode bool,
 	streamsConfigs []string,
+	streamsAuditLog string,
+	streamsSync streamsSyncConfig,
+	watch bool,
+	lintRulesPath string,
 ) int {
 	var err error
 	if resourcesPaths, err = filepath.Globs(resourcesPaths); err != nil {
 		fmt.Printf("Failed to resolve resource glob pattern: %v\n", err)
 		return 1
 	}
-	lints := readConfig(confPath, resourcesPaths)
+
+	// Keep a copy of the config defaults so that configs can be reloaded.
+	var defaultConfBytes []byte
+	if defaultConfBytes, err = yaml.Marshal(conf); err != nil {
+		fmt.Printf("Failed to marshal config defaults: %v\n", err)
+		return 1
+	}
+
+	confPaths = resolveConfigPaths(confPaths)
+	lints := readConfig(confPaths, resourcesPaths)
+
+	rules := readLintRules(lintRulesPath)
+	var ruleWarnings []string
+	if !streamsMode {
+		var ruleErrs []string
+		if ruleErrs, ruleWarnings, err = applyLintRules(rules, conf); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to apply lint rules: %v\n", err)
+			return 1
+		}
+		lints = append(lints, ruleErrs...)
+	}
 	if strict && len(lints) > 0 {
 		for _, lint := range lints {
 			fmt.Fprintln(os.Stderr, lint)
@@ -187,12 +229,16 @@ func cmdService(
 		fmt.Printf("Failed to create logger: %v\n", err)
 		return 1
 	}
+	listenForLogLevelSignals(logger)
 
-	if len(lints) > 0 {
+	if len(lints) > 0 || len(ruleWarnings) > 0 {
 		lintlog := logger.NewModule(".linter")
 		for _, lint := range lints {
 			lintlog.Infoln(lint)
 		}
+		for _, warning := range ruleWarnings {
+			lintlog.Warnln(warning)
+		}
 	}
 
 	// Create our metrics type.
@@ -211,7 +257,7 @@ func cmdService(
 
 	// Create our tracer type.
 	var trac tracer.Type
-	if trac, err = tracer.New(conf.Tracer); err != nil {
+	if trac, err = tracer.New(conf.Tracer, tracer.OptSetLogger(logger)); err != nil {
 		logger.Errorf("Failed to initialise tracer: %v\n", err)
 		return 1
 	}
@@ -234,31 +280,83 @@ func cmdService(
 		logger.Errorf("Failed to create resource: %v\n", err)
 		return 1
 	}
+	httpServer.RegisterJourneysEndpoint(manager.GetJourneyTap())
 	if err = onManagerInit(manager, logger, stats); err != nil {
 		logger.Errorf("Failed to initialise manager: %v\n", err)
 		return 1
 	}
 
+	// Create load shedding guard.
+	var guard *shedding.Guard
+	if conf.LoadShedding.Enabled {
+		if guard, err = shedding.New(
+			conf.LoadShedding, logger.NewModule(".load_shedding"),
+			metrics.Namespaced(stats, "load_shedding"),
+		); err != nil {
+			logger.Errorf("Failed to initialise load shedding: %v\n", err)
+			return 1
+		}
+		defer guard.CloseAsync()
+	}
+
+	var drainTimeout time.Duration
+	if tout := conf.DrainTimeout; len(tout) > 0 {
+		if drainTimeout, err = time.ParseDuration(tout); err != nil {
+			logger.Errorf("Failed to parse shutdown drain timeout period string: %v\n", err)
+			return 1
+		}
+	}
+
+	var exitTimeout time.Duration
+	if tout := conf.SystemCloseTimeout; len(tout) > 0 {
+		var err error
+		if exitTimeout, err = time.ParseDuration(tout); err != nil {
+			logger.Errorf("Failed to parse shutdown timeout period string: %v\n", err)
+			return 1
+		}
+	}
+
 	var dataStream stoppableStreams
+	var reloadStream func(newConf config.Type)
+	var syncer *streamsSyncer
 	dataStreamClosedChan := make(chan struct{})
 
 	// Create data streams.
 	if streamsMode {
-		streamMgr := strmmgr.New(
-			strmmgr.OptSetAPITimeout(time.Second*5),
+		streamMgrOpts := []func(*strmmgr.Type){
+			strmmgr.OptSetAPITimeout(time.Second * 5),
 			strmmgr.OptSetLogger(logger),
 			strmmgr.OptSetManager(manager),
 			strmmgr.OptSetStats(stats),
-		)
-		streamConfs := map[string]stream.Config{}
-		var streamLints []string
-		for _, path := range streamsConfigs {
-			lints, err := strmmgr.LoadStreamConfigsFromPath(path, testSuffix, streamConfs)
+			strmmgr.OptSetLoadShedding(guard),
+			strmmgr.OptSetLayerMetrics(conf.Metrics.StreamLayers),
+			strmmgr.OptSetDrainTimeout(drainTimeout),
+		}
+		if streamsAuditLog != "" {
+			auditFile, err := os.OpenFile(streamsAuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to load stream configs: %v\n", err)
+				logger.Errorf("Failed to open audit log: %v\n", err)
 				return 1
 			}
-			streamLints = append(streamLints, lints...)
+			defer auditFile.Close()
+			streamMgrOpts = append(streamMgrOpts, strmmgr.OptSetAuditLog(auditFile))
+		}
+		streamMgr := strmmgr.New(streamMgrOpts...)
+
+		streamsPaths := streamsConfigs
+		if streamsSync.Source != "" {
+			if syncer, err = newStreamsSyncer(streamsSync, logger.NewModule(".sync")); err != nil {
+				logger.Errorf("Failed to sync stream configs: %v\n", err)
+				return 1
+			}
+			defer syncer.Close()
+			streamsPaths = append(streamsPaths[:len(streamsPaths):len(streamsPaths)], syncer.Path())
+		}
+
+		streamConfs, streamLints, streamWarnings, err := loadStreamConfigs(streamsPaths, rules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load stream configs: %v\n", err)
+			return 1
 		}
 
 		if strict && len(streamLints) > 0 {
@@ -267,11 +365,14 @@ func cmdService(
 			}
 			fmt.Println("Shutting down due to linter errors, to prevent shutdown run Benthos with --chilled")
 			return 1
-		} else if len(streamLints) > 0 {
+		} else if len(streamLints) > 0 || len(streamWarnings) > 0 {
 			lintlog := logger.NewModule(".linter")
 			for _, lint := range streamLints {
 				lintlog.Infoln(lint)
 			}
+			for _, warning := range streamWarnings {
+				lintlog.Warnln(warning)
+			}
 		}
 
 		dataStream = streamMgr
@@ -281,20 +382,70 @@ func cmdService(
 				return 1
 			}
 		}
+		var streamsMut sync.Mutex
+		reloadStreams := func() (streamsDiff, error) {
+			streamsMut.Lock()
+			defer streamsMut.Unlock()
+
+			newStreamConfs, lints, warnings, err := loadStreamConfigs(streamsPaths, rules)
+			if err != nil {
+				return streamsDiff{}, fmt.Errorf("failed to load stream configs: %v", err)
+			}
+			if strict && len(lints) > 0 {
+				for _, lint := range lints {
+					logger.Errorln(lint)
+				}
+				return streamsDiff{}, errors.New("stream configs were not reloaded due to linter errors")
+			}
+			for _, warning := range warnings {
+				logger.Warnln(warning)
+			}
+			diff := reloadStreamConfigs(streamMgr, streamConfs, newStreamConfs, exitTimeout, logger)
+			streamConfs = newStreamConfs
+			return diff, nil
+		}
+		reloadStream = func(newConf config.Type) {
+			if _, err := reloadStreams(); err != nil {
+				logger.Errorf("Failed to reload stream configs: %v\n", err)
+			}
+		}
+		if syncer != nil {
+			syncer.apply = reloadStreams
+			httpServer.RegisterEndpoint(
+				"/sync",
+				"GET: Returns the status of the most recent synchronisation of stream configs."+
+					" POST: Triggers a synchronisation of stream configs, intended for use as a webhook.",
+				syncer.HandleSync,
+			)
+		}
 		logger.Infoln("Launching benthos in streams mode, use CTRL+C to close.")
 	} else {
-		if dataStream, err = stream.New(
+		strm, err := newReloadableStream(
 			conf.Config,
+			func() {
+				close(dataStreamClosedChan)
+			},
 			stream.OptSetLogger(logger),
 			stream.OptSetStats(stats),
 			stream.OptSetManager(manager),
-			stream.OptOnClose(func() {
-				close(dataStreamClosedChan)
-			}),
-		); err != nil {
+			stream.OptSetLoadShedding(guard, ""),
+			stream.OptSetLayerMetrics(conf.Metrics.StreamLayers),
+			stream.OptSetDrainTimeout(drainTimeout),
+		)
+		if err != nil {
 			logger.Errorf("Service closing due to: %v\n", err)
 			return 1
 		}
+		dataStream = strm
+		reloadStream = func(newConf config.Type) {
+			restarted, err := strm.Reload(newConf.Config, exitTimeout)
+			if err != nil {
+				logger.Errorf("Failed to reload stream: %v\n", err)
+			}
+			if restarted {
+				logger.Infoln("Restarted stream with reloaded config.")
+			}
+		}
 		logger.Infoln("Launching a benthos instance, use CTRL+C to close.")
 	}
 
@@ -312,17 +463,75 @@ func cmdService(
 		close(httpServerClosedChan)
 	}()
 
-	var exitTimeout time.Duration
-	if tout := conf.SystemCloseTimeout; len(tout) > 0 {
-		var err error
-		if exitTimeout, err = time.ParseDuration(tout); err != nil {
-			logger.Errorf("Failed to parse shutdown timeout period string: %v\n", err)
-			return 1
+	// Reload configs when their files change or a SIGHUP is received.
+	reloadCloseChan := make(chan struct{})
+	if syncer != nil {
+		go syncer.loop(reloadCloseChan)
+		logger.Infof("Syncing stream configs from: %v\n", syncer.Status().Source)
+	}
+	if watch {
+		reloadConfig := func() {
+			newConf := config.New()
+			if err := yaml.Unmarshal(defaultConfBytes, &newConf); err != nil {
+				logger.Errorf("Failed to reload config: %v\n", err)
+				return
+			}
+			lints, err := readConfigFiles(confPaths, resourcesPaths, &newConf)
+			if err != nil {
+				logger.Errorf("Failed to reload config: %v\n", err)
+				return
+			}
+			if !streamsMode {
+				ruleErrs, ruleWarnings, err := applyLintRules(rules, newConf)
+				if err != nil {
+					logger.Errorf("Failed to apply lint rules: %v\n", err)
+					return
+				}
+				lints = append(lints, ruleErrs...)
+				for _, warning := range ruleWarnings {
+					logger.Warnln(warning)
+				}
+			}
+			if strict && len(lints) > 0 {
+				for _, lint := range lints {
+					logger.Errorln(lint)
+				}
+				logger.Errorln("Config was not reloaded due to linter errors")
+				return
+			}
+			if len(overrideLogLevel) > 0 {
+				newConf.Logger.LogLevel = strings.ToUpper(overrideLogLevel)
+			}
+			logIgnoredChanges(logger, conf, newConf)
+			reloadStream(newConf)
 		}
+
+		reloadChan := make(chan struct{}, 1)
+		var watchPaths []string
+		watchPaths = append(watchPaths, confPaths...)
+		watchPaths = append(watchPaths, resourcesPaths...)
+		watchPaths = append(watchPaths, streamsConfigs...)
+
+		go watchFiles(watchPaths, watchInterval, reloadChan, reloadCloseChan)
+		go relayReloadSignals(reloadChan, reloadCloseChan)
+		go func() {
+			for {
+				select {
+				case <-reloadChan:
+					logger.Infoln("Reloading config.")
+					reloadConfig()
+				case <-reloadCloseChan:
+					return
+				}
+			}
+		}()
+		logger.Infof("Watching config files for changes: %v\n", strings.Join(watchPaths, ", "))
 	}
 
 	// Defer clean up.
 	defer func() {
+		close(reloadCloseChan)
+
 		go func() {
 			httpServer.Shutdown(context.Background())
 			select {
@@ -0,0 +1,69 @@
+// +build !windows,!wasm
+
+package service
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// listenForLogLevelSignals sets the log level of all components to DEBUG when
+// a SIGUSR1 is received, and removes that override when a SIGUSR2 is
+// received.
+func listenForLogLevelSignals(logger log.Modular) {
+	ls, ok := logger.(log.LevelSetter)
+	if !ok {
+		return
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for sig := range sigChan {
+			switch sig {
+			case syscall.SIGUSR1:
+				if err := ls.SetLevel("", "DEBUG"); err != nil {
+					logger.Errorf("Failed to set log level: %v\n", err)
+				} else {
+					logger.Infoln("Received SIGUSR1, the log level of all components is now DEBUG.")
+				}
+			case syscall.SIGUSR2:
+				if err := ls.SetLevel("", ""); err != nil {
+					logger.Errorf("Failed to reset log level: %v\n", err)
+				} else {
+					logger.Infoln("Received SIGUSR2, the log level override of all components has been removed.")
+				}
+			}
+		}
+	}()
+}
+
+//------------------------------------------------------------------------------
+
+// relayReloadSignals writes to reloadChan each time a SIGHUP is received, until
+// closeChan is closed.
+func relayReloadSignals(reloadChan chan<- struct{}, closeChan <-chan struct{}) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	for {
+		select {
+		case <-sigChan:
+			select {
+			case reloadChan <- struct{}{}:
+			default:
+			}
+		case <-closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
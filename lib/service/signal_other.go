@@ -0,0 +1,20 @@
+// +build windows wasm
+
+package service
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/log"
+)
+
+//------------------------------------------------------------------------------
+
+// listenForLogLevelSignals does nothing as SIGUSR1 and SIGUSR2 are not
+// supported on this platform.
+func listenForLogLevelSignals(logger log.Modular) {}
+
+//------------------------------------------------------------------------------
+
+// relayReloadSignals does nothing as SIGHUP is not supported on this platform.
+func relayReloadSignals(reloadChan chan<- struct{}, closeChan <-chan struct{}) {}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,450 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+//------------------------------------------------------------------------------
+
+// syncTimeout is the maximum period of time that a single synchronisation of
+// stream configs from a remote source may take.
+const syncTimeout = time.Minute * 5
+
+// streamsSyncConfig describes a remote source that stream configs are
+// synchronised from in streams mode.
+type streamsSyncConfig struct {
+	Source   string
+	Interval string
+}
+
+// syncSource is a remote location of stream configs that can be synchronised
+// into a local directory.
+type syncSource interface {
+	// Sync updates the local directory with the latest stream configs and
+	// returns a version that changes whenever the configs change.
+	Sync(ctx context.Context) (string, error)
+
+	// Path returns the local path that stream configs are synchronised to.
+	Path() string
+}
+
+// newSyncSource creates a sync source from a URL, which is either an S3 URL of
+// the form s3://bucket/prefix or a git repository URL prefixed with git+.
+func newSyncSource(source, dir string) (syncSource, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sync source: %v", err)
+	}
+	switch {
+	case u.Scheme == "s3":
+		return newS3SyncSource(u, dir)
+	case strings.HasPrefix(u.Scheme, "git+"):
+		return newGitSyncSource(u, dir), nil
+	}
+	return nil, fmt.Errorf("sync source scheme '%v' not recognised, expected s3 or a git+ prefixed scheme", u.Scheme)
+}
+
+// isConfigFile returns true for paths with a YAML extension.
+func isConfigFile(path string) bool {
+	return strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+}
+
+//------------------------------------------------------------------------------
+
+// gitSyncSource synchronises stream configs from a git repository by cloning
+// it into a local directory and subsequently fetching the latest commit of a
+// ref.
+type gitSyncSource struct {
+	repo     string
+	redacted string
+	ref      string
+	subPath  string
+	dir      string
+}
+
+func newGitSyncSource(u *url.URL, dir string) *gitSyncSource {
+	query := u.Query()
+	ref, subPath := query.Get("ref"), query.Get("path")
+	query.Del("ref")
+	query.Del("path")
+
+	repoURL := *u
+	repoURL.Scheme = strings.TrimPrefix(u.Scheme, "git+")
+	repoURL.RawQuery = query.Encode()
+
+	return &gitSyncSource{
+		repo:     repoURL.String(),
+		redacted: repoURL.Redacted(),
+		ref:      ref,
+		subPath:  subPath,
+		dir:      filepath.Join(dir, "repo"),
+	}
+}
+
+func (g *gitSyncSource) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Avoid leaking credentials within the repository URL.
+		msg := strings.ReplaceAll(strings.TrimSpace(stderr.String()), g.repo, g.redacted)
+		return "", fmt.Errorf("git %v failed: %v: %v", args[0], err, msg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *gitSyncSource) Sync(ctx context.Context) (string, error) {
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); os.IsNotExist(err) {
+		args := []string{"clone", "--depth", "1"}
+		if g.ref != "" {
+			args = append(args, "--branch", g.ref)
+		}
+		if _, err = g.git(ctx, append(args, "--", g.repo, g.dir)...); err != nil {
+			return "", err
+		}
+	} else {
+		ref := g.ref
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err = g.git(ctx, "-C", g.dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return "", err
+		}
+		if _, err = g.git(ctx, "-C", g.dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return g.git(ctx, "-C", g.dir, "rev-parse", "HEAD")
+}
+
+func (g *gitSyncSource) Path() string {
+	return filepath.Join(g.dir, g.subPath)
+}
+
+//------------------------------------------------------------------------------
+
+// s3SyncSource synchronises stream configs from the objects of an S3 bucket
+// under a prefix, downloading only objects that have changed since the last
+// synchronisation.
+type s3SyncSource struct {
+	bucket string
+	prefix string
+	dir    string
+	s3     s3iface.S3API
+
+	etags map[string]string
+}
+
+func newS3SyncSource(u *url.URL, dir string) (*s3SyncSource, error) {
+	conf := session.NewConfig()
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		conf.Region = region
+	}
+	if region := u.Query().Get("region"); region != "" {
+		conf.Region = region
+	}
+	conf.Endpoint = u.Query().Get("endpoint")
+
+	sess, err := conf.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	return &s3SyncSource{
+		bucket: u.Host,
+		prefix: strings.TrimPrefix(u.Path, "/"),
+		dir:    filepath.Join(dir, "s3"),
+		s3:     s3.New(sess),
+		etags:  map[string]string{},
+	}, nil
+}
+
+func (s *s3SyncSource) Sync(ctx context.Context) (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", err
+	}
+
+	remote := map[string]string{}
+	keys := map[string]string{}
+	if err := s.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			if !isConfigFile(key) {
+				continue
+			}
+			rel := filepath.Clean(strings.TrimPrefix(strings.TrimPrefix(key, s.prefix), "/"))
+			if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			remote[rel] = aws.StringValue(obj.ETag)
+			keys[rel] = key
+		}
+		return true
+	}); err != nil {
+		return "", fmt.Errorf("failed to list objects: %v", err)
+	}
+
+	for rel, etag := range remote {
+		if s.etags[rel] == etag {
+			continue
+		}
+		obj, err := s.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(keys[rel]),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to download object '%v': %v", keys[rel], err)
+		}
+		contents, err := ioutil.ReadAll(obj.Body)
+		obj.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to download object '%v': %v", keys[rel], err)
+		}
+		path := filepath.Join(s.dir, rel)
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err = ioutil.WriteFile(path, contents, 0644); err != nil {
+			return "", err
+		}
+		s.etags[rel] = etag
+	}
+
+	for rel := range s.etags {
+		if _, exists := remote[rel]; exists {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, rel)); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		delete(s.etags, rel)
+	}
+
+	rels := make([]string, 0, len(remote))
+	for rel := range remote {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	h := sha256.New()
+	for _, rel := range rels {
+		fmt.Fprintf(h, "%v:%v\n", rel, remote[rel])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (s *s3SyncSource) Path() string {
+	return s.dir
+}
+
+//------------------------------------------------------------------------------
+
+// streamsSyncStatus describes the result of the most recent synchronisation of
+// stream configs.
+type streamsSyncStatus struct {
+	Source      string `json:"source"`
+	Version     string `json:"version"`
+	LastAttempt string `json:"last_attempt,omitempty"`
+	LastSuccess string `json:"last_success,omitempty"`
+	Error       string `json:"error,omitempty"`
+	streamsDiff
+}
+
+// streamsSyncer periodically synchronises stream configs from a remote source
+// and applies any changes to the running streams. A synchronisation can also
+// be triggered with a request to an HTTP endpoint, which is intended for use
+// as a webhook.
+type streamsSyncer struct {
+	source   syncSource
+	dir      string
+	interval time.Duration
+	apply    func() (streamsDiff, error)
+	log      log.Modular
+
+	triggerChan chan struct{}
+
+	mut    sync.Mutex
+	status streamsSyncStatus
+}
+
+// newStreamsSyncer creates a syncer from a config and performs the initial
+// synchronisation, returning an error if it fails.
+func newStreamsSyncer(conf streamsSyncConfig, logger log.Modular) (*streamsSyncer, error) {
+	var interval time.Duration
+	if conf.Interval != "" {
+		var err error
+		if interval, err = time.ParseDuration(conf.Interval); err != nil {
+			return nil, fmt.Errorf("failed to parse sync interval: %v", err)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "benthos_streams_sync")
+	if err != nil {
+		return nil, err
+	}
+	source, err := newSyncSource(conf.Source, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	displaySource := conf.Source
+	if u, err := url.Parse(conf.Source); err == nil {
+		displaySource = u.Redacted()
+	}
+
+	s := &streamsSyncer{
+		source:      source,
+		dir:         dir,
+		interval:    interval,
+		log:         logger,
+		triggerChan: make(chan struct{}, 1),
+		status: streamsSyncStatus{
+			Source: displaySource,
+		},
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), syncTimeout)
+	defer done()
+
+	version, err := source.Sync(ctx)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to sync stream configs from '%v': %v", displaySource, err)
+	}
+	now := time.Now().Format(time.RFC3339)
+	s.status.Version = version
+	s.status.LastAttempt = now
+	s.status.LastSuccess = now
+	return s, nil
+}
+
+// Path returns the local path that stream configs are synchronised to.
+func (s *streamsSyncer) Path() string {
+	return s.source.Path()
+}
+
+// Close removes the local copy of synchronised stream configs.
+func (s *streamsSyncer) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// sync fetches the latest stream configs from the source and applies them when
+// they have changed or when the previous attempt to apply them failed.
+func (s *streamsSyncer) sync() {
+	ctx, done := context.WithTimeout(context.Background(), syncTimeout)
+	defer done()
+
+	version, err := s.source.Sync(ctx)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.status.LastAttempt = time.Now().Format(time.RFC3339)
+	if err != nil {
+		s.log.Errorf("Failed to sync stream configs: %v\n", err)
+		s.status.Error = err.Error()
+		return
+	}
+	if version == s.status.Version && s.status.Error == "" {
+		return
+	}
+
+	s.log.Infof("Applying stream configs at version %v\n", version)
+	diff, err := s.apply()
+	s.status.Version = version
+	s.status.streamsDiff = diff
+	if err != nil {
+		s.log.Errorf("Failed to apply synced stream configs: %v\n", err)
+		s.status.Error = err.Error()
+		return
+	}
+	s.status.Error = ""
+	if len(diff.Failed) > 0 {
+		s.status.Error = fmt.Sprintf("failed to apply %v stream configs", len(diff.Failed))
+	}
+	s.status.LastSuccess = s.status.LastAttempt
+}
+
+// Trigger schedules a synchronisation, unless one is already scheduled.
+func (s *streamsSyncer) Trigger() {
+	select {
+	case s.triggerChan <- struct{}{}:
+	default:
+	}
+}
+
+// Status returns the result of the most recent synchronisation.
+func (s *streamsSyncer) Status() streamsSyncStatus {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.status
+}
+
+// loop synchronises stream configs at the interval of the syncer, and whenever
+// a synchronisation is triggered, until closeChan is closed.
+func (s *streamsSyncer) loop(closeChan <-chan struct{}) {
+	var tickChan <-chan time.Time
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		tickChan = ticker.C
+	}
+	for {
+		select {
+		case <-tickChan:
+		case <-s.triggerChan:
+		case <-closeChan:
+			return
+		}
+		s.sync()
+	}
+}
+
+// HandleSync is an HTTP handler where a GET request returns the status of the
+// most recent synchronisation and a POST request triggers a synchronisation.
+func (s *streamsSyncer) HandleSync(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		resBytes, err := json.Marshal(s.Status())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resBytes)
+	case http.MethodPost:
+		s.Trigger()
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "Method not supported", http.StatusMethodNotAllowed)
+	}
+}
+
+//------------------------------------------------------------------------------
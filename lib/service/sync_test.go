@@ -0,0 +1,223 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitSyncSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repoDir, err := ioutil.TempDir("", "benthos_sync_repo")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoDir)
+
+	syncDir, err := ioutil.TempDir("", "benthos_sync_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(syncDir)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{
+			"-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	writeConf := func(content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(repoDir, "streams"), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(repoDir, "streams", "foo.yaml"), []byte(content), 0644))
+		git("add", "-A")
+		git("commit", "-m", "update")
+	}
+
+	git("init")
+	writeConf("input:\n  type: stdin\n")
+
+	source, err := newSyncSource("git+file://"+filepath.ToSlash(repoDir)+"?path=streams", syncDir)
+	require.NoError(t, err)
+
+	firstVersion, err := source.Sync(context.Background())
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(source.Path(), "foo.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "input:\n  type: stdin\n", string(content))
+
+	writeConf("input:\n  type: nats\n")
+
+	secondVersion, err := source.Sync(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, firstVersion, secondVersion)
+
+	content, err = ioutil.ReadFile(filepath.Join(source.Path(), "foo.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "input:\n  type: nats\n", string(content))
+}
+
+type mockSyncS3 struct {
+	s3iface.S3API
+	objects map[string]string
+}
+
+func (m *mockSyncS3) ListObjectsV2PagesWithContext(
+	ctx aws.Context, input *s3.ListObjectsV2Input,
+	fn func(*s3.ListObjectsV2Output, bool) bool, opts ...request.Option,
+) error {
+	keys := make([]string, 0, len(m.objects))
+	for k := range m.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	page := &s3.ListObjectsV2Output{}
+	for _, k := range keys {
+		sum := md5.Sum([]byte(m.objects[k]))
+		page.Contents = append(page.Contents, &s3.Object{
+			Key:  aws.String(k),
+			ETag: aws.String(hex.EncodeToString(sum[:])),
+		})
+	}
+	fn(page, true)
+	return nil
+}
+
+func (m *mockSyncS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	content, exists := m.objects[*input.Key]
+	if !exists {
+		return nil, errors.New("not found")
+	}
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(bytes.NewReader([]byte(content))),
+	}, nil
+}
+
+func TestS3SyncSource(t *testing.T) {
+	syncDir, err := ioutil.TempDir("", "benthos_sync_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(syncDir)
+
+	mockS3 := &mockSyncS3{
+		objects: map[string]string{
+			"streams/foo.yaml":     "foo",
+			"streams/bar/baz.yaml": "baz",
+			"streams/README.md":    "nope",
+		},
+	}
+	source := &s3SyncSource{
+		bucket: "bucket",
+		prefix: "streams",
+		dir:    syncDir,
+		s3:     mockS3,
+		etags:  map[string]string{},
+	}
+
+	readFile := func(path string) string {
+		b, err := ioutil.ReadFile(filepath.Join(source.Path(), path))
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	firstVersion, err := source.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "foo", readFile("foo.yaml"))
+	assert.Equal(t, "baz", readFile(filepath.Join("bar", "baz.yaml")))
+	_, err = os.Stat(filepath.Join(source.Path(), "README.md"))
+	assert.True(t, os.IsNotExist(err))
+
+	sameVersion, err := source.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, firstVersion, sameVersion)
+
+	mockS3.objects["streams/foo.yaml"] = "foo2"
+	delete(mockS3.objects, "streams/bar/baz.yaml")
+
+	secondVersion, err := source.Sync(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, firstVersion, secondVersion)
+	assert.Equal(t, "foo2", readFile("foo.yaml"))
+	_, err = os.Stat(filepath.Join(source.Path(), "bar", "baz.yaml"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+type mockSyncSource struct {
+	version string
+	err     error
+}
+
+func (m *mockSyncSource) Sync(ctx context.Context) (string, error) {
+	return m.version, m.err
+}
+
+func (m *mockSyncSource) Path() string {
+	return ""
+}
+
+func TestStreamsSyncer(t *testing.T) {
+	source := &mockSyncSource{version: "a"}
+
+	var applied int
+	s := &streamsSyncer{
+		source: source,
+		log:    log.Noop(),
+		apply: func() (streamsDiff, error) {
+			applied++
+			return streamsDiff{Created: []string{"foo"}}, nil
+		},
+		triggerChan: make(chan struct{}, 1),
+		status: streamsSyncStatus{
+			Source:  "s3://bucket/streams",
+			Version: "a",
+		},
+	}
+
+	s.sync()
+	assert.Equal(t, 0, applied)
+
+	source.version = "b"
+	s.sync()
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, "b", s.Status().Version)
+	assert.Equal(t, []string{"foo"}, s.Status().Created)
+
+	source.err = errors.New("nope")
+	s.sync()
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, "nope", s.Status().Error)
+
+	source.err = nil
+	s.sync()
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, "", s.Status().Error)
+
+	w := httptest.NewRecorder()
+	s.HandleSync(w, httptest.NewRequest(http.MethodPost, "/sync", nil))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Len(t, s.triggerChan, 1)
+
+	w = httptest.NewRecorder()
+	s.HandleSync(w, httptest.NewRequest(http.MethodGet, "/sync", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"version":"b"`)
+	assert.Contains(t, w.Body.String(), `"created":["foo"]`)
+}
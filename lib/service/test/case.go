@@ -1,10 +1,18 @@
 package test
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
 	"github.com/Jeffail/benthos/v3/lib/message/metadata"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	yaml "gopkg.in/yaml.v3"
@@ -55,6 +63,15 @@ type Case struct {
 	InputBatch       []InputPart       `yaml:"input_batch"`
 	OutputBatches    [][]ConditionsMap `yaml:"output_batches"`
 
+	Mocks       map[string]interface{}              `yaml:"mocks,omitempty"`
+	SentBatches map[string][][]ConditionsMap        `yaml:"sent_batches,omitempty"`
+	CacheSeeds  map[string]map[string]string        `yaml:"cache_seeds,omitempty"`
+	CacheValues map[string]map[string]ConditionsMap `yaml:"cache_values,omitempty"`
+	GoldenFile  string                              `yaml:"golden_file,omitempty"`
+
+	DroppedMessages    []int                        `yaml:"dropped_messages,omitempty"`
+	ErrorStreamBatches map[string][][]ConditionsMap `yaml:"error_stream_batches,omitempty"`
+
 	line int
 }
 
@@ -121,11 +138,30 @@ type ProcProvider interface {
 
 // Execute attempts to execute a test case against a Benthos configuration.
 func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error) {
+	return c.execute(provider, "", false)
+}
+
+func (c *Case) execute(provider ProcProvider, dir string, updateGolden bool) (failures []CaseFailure, err error) {
 	var procSet []types.Processor
-	if procSet, err = provider.Provide(c.TargetProcessors, c.Environment); err != nil {
+	var resources Resources
+	if c.needsResources() {
+		mockProvider, ok := provider.(MockProcProvider)
+		if !ok {
+			return nil, errMocksNotSupported
+		}
+		if procSet, resources, err = mockProvider.ProvideMocked(c.TargetProcessors, c.Environment, c.Mocks); err != nil {
+			return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
+		}
+	} else if procSet, err = provider.Provide(c.TargetProcessors, c.Environment); err != nil {
 		return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
 	}
 
+	if resources != nil {
+		if err = c.seedCaches(resources); err != nil {
+			return nil, err
+		}
+	}
+
 	reportFailure := func(reason string) {
 		failures = append(failures, CaseFailure{
 			Name:     c.Name,
@@ -138,53 +174,296 @@ func (c *Case) Execute(provider ProcProvider) (failures []CaseFailure, err error
 	for i, v := range c.InputBatch {
 		part := message.NewPart([]byte(v.Content))
 		part.SetMetadata(metadata.New(v.Metadata))
+		if len(c.DroppedMessages) > 0 {
+			part.Metadata().Set(inputIndexKey, strconv.Itoa(i))
+		}
 		parts[i] = part
 	}
 
 	inputMsg := message.New(nil)
 	inputMsg.SetAll(parts)
 	outputBatches, result := processor.ExecuteAll(procSet, inputMsg)
+
+	var routed map[string]types.Message
+	if result == nil && len(c.ErrorStreamBatches) > 0 {
+		if outputBatches, routed, err = pipeline.RouteErrors(resources.ErrorStreams(), outputBatches); err != nil {
+			return nil, fmt.Errorf("failed to route messages to error streams: %v", err)
+		}
+	}
+	if len(c.DroppedMessages) > 0 {
+		c.checkDropped(outputBatches, routed, reportFailure)
+	}
+
 	if result != nil {
-		if len(c.OutputBatches) == 0 {
-			return
+		if len(c.OutputBatches) > 0 {
+			if result.Error() != nil {
+				reportFailure(fmt.Sprintf("processors resulted in error: %v", result.Error()))
+			} else {
+				reportFailure("processors resulted in zero output batches")
+			}
 		}
-		if result.Error() != nil {
-			reportFailure(fmt.Sprintf("processors resulted in error: %v", result.Error()))
-		} else {
-			reportFailure("processors resulted in zero output batches")
+	} else {
+		checkBatches("", c.OutputBatches, outputBatches, reportFailure)
+	}
+
+	if len(c.ErrorStreamBatches) > 0 {
+		streams := make([]string, 0, len(c.ErrorStreamBatches))
+		for k := range c.ErrorStreamBatches {
+			streams = append(streams, k)
+		}
+		sort.Strings(streams)
+		for _, k := range streams {
+			var batches []types.Message
+			if msg, exists := routed[k]; exists {
+				batches = append(batches, msg)
+			}
+			checkBatches(fmt.Sprintf("error stream '%v': ", k), c.ErrorStreamBatches[k], batches, reportFailure)
 		}
-		return
 	}
 
-	if lExp, lAct := len(c.OutputBatches), len(outputBatches); lAct < lExp {
-		reportFailure(fmt.Sprintf("wrong batch count, expected %v, got %v", lExp, lAct))
+	if resources != nil {
+		c.checkMocks(resources, reportFailure)
+	}
+	if c.GoldenFile != "" {
+		c.checkGolden(dir, updateGolden, outputBatches, reportFailure)
 	}
+	return
+}
 
-	for i, v := range outputBatches {
-		if len(c.OutputBatches) <= i {
-			reportFailure(fmt.Sprintf("unexpected batch: %s", message.GetAllBytes(v)))
+// needsResources returns true if the case mocks, seeds or asserts on resources,
+// or asserts on error streams.
+func (c *Case) needsResources() bool {
+	return len(c.Mocks) > 0 || len(c.SentBatches) > 0 ||
+		len(c.CacheSeeds) > 0 || len(c.CacheValues) > 0 ||
+		len(c.ErrorStreamBatches) > 0
+}
+
+// seedCaches sets the keys of caches to values before the case is executed.
+func (c *Case) seedCaches(resources Resources) error {
+	caches := make([]string, 0, len(c.CacheSeeds))
+	for k := range c.CacheSeeds {
+		caches = append(caches, k)
+	}
+	sort.Strings(caches)
+	for _, name := range caches {
+		cache, err := resources.GetCache(name)
+		if err != nil {
+			return fmt.Errorf("failed to seed cache '%v': %v", name, err)
+		}
+		for key, value := range c.CacheSeeds[name] {
+			if err = cache.Set(key, []byte(value)); err != nil {
+				return fmt.Errorf("failed to seed cache '%v' key '%v': %v", name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// inputIndexKey is a metadata key set on input messages in order to identify
+// them within the output of a case.
+const inputIndexKey = "benthos_test_input_index"
+
+// checkDropped checks that the expected input messages are absent from the
+// output batches and error streams, and removes the metadata used to identify
+// input messages from all messages.
+func (c *Case) checkDropped(outputBatches []types.Message, routed map[string]types.Message, reportFailure func(string)) {
+	present := map[string]struct{}{}
+	strip := func(msg types.Message) {
+		msg.Iter(func(i int, p types.Part) error {
+			if index := p.Metadata().Get(inputIndexKey); index != "" {
+				present[index] = struct{}{}
+				p.Metadata().Delete(inputIndexKey)
+			}
+			return nil
+		})
+	}
+	for _, msg := range outputBatches {
+		strip(msg)
+	}
+	for _, msg := range routed {
+		strip(msg)
+	}
+
+	for _, index := range c.DroppedMessages {
+		if index < 0 || index >= len(c.InputBatch) {
+			reportFailure(fmt.Sprintf("dropped message index %v is out of bounds of the input batch", index))
 			continue
 		}
-		expectedBatch := c.OutputBatches[i]
+		if _, exists := present[strconv.Itoa(index)]; exists {
+			reportFailure(fmt.Sprintf("input message %v was expected to be dropped: %v", index, red(c.InputBatch[index].Content)))
+		}
+	}
+}
+
+// checkBatches compares batches against expected conditions and reports any
+// failures with a prefix.
+func checkBatches(prefix string, expected [][]ConditionsMap, actual []types.Message, reportFailure func(string)) {
+	if lExp, lAct := len(expected), len(actual); lAct < lExp {
+		reportFailure(fmt.Sprintf("%vwrong batch count, expected %v, got %v", prefix, lExp, lAct))
+	}
+
+	for i, v := range actual {
+		if len(expected) <= i {
+			reportFailure(fmt.Sprintf("%vunexpected batch: %s", prefix, message.GetAllBytes(v)))
+			continue
+		}
+		expectedBatch := expected[i]
 		if lExp, lAct := len(expectedBatch), v.Len(); lExp != lAct {
-			reportFailure(fmt.Sprintf("mismatch of output batch %v message counts, expected %v, got %v", i, lExp, lAct))
+			reportFailure(fmt.Sprintf("%vmismatch of output batch %v message counts, expected %v, got %v", prefix, i, lExp, lAct))
 		}
 		v.Iter(func(i2 int, part types.Part) error {
 			if len(expectedBatch) <= i2 {
-				reportFailure(fmt.Sprintf("unexpected message from batch %v: %s", i, part.Get()))
+				reportFailure(fmt.Sprintf("%vunexpected message from batch %v: %s", prefix, i, part.Get()))
 				return nil
 			}
 			condErrs := expectedBatch[i2].CheckAll(part)
 			for _, condErr := range condErrs {
-				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, condErr))
+				reportFailure(fmt.Sprintf("%vbatch %v message %v: %v", prefix, i, i2, condErr))
 			}
 			if procErr := processor.GetFail(part); len(procErr) > 0 && len(condErrs) > 0 {
-				reportFailure(fmt.Sprintf("batch %v message %v: %v", i, i2, red(procErr)))
+				reportFailure(fmt.Sprintf("%vbatch %v message %v: %v", prefix, i, i2, red(procErr)))
 			}
 			return nil
 		})
 	}
-	return
+}
+
+// checkMocks compares the batches sent to mocked processors and the values of
+// caches against expected conditions.
+func (c *Case) checkMocks(resources Resources, reportFailure func(string)) {
+	mocks := make([]string, 0, len(c.SentBatches))
+	for k := range c.SentBatches {
+		mocks = append(mocks, k)
+	}
+	sort.Strings(mocks)
+	for _, k := range mocks {
+		sent, err := resources.SentBatches(k)
+		if err != nil {
+			reportFailure(err.Error())
+			continue
+		}
+		for _, msg := range sent {
+			msg.Iter(func(i int, p types.Part) error {
+				p.Metadata().Delete(inputIndexKey)
+				return nil
+			})
+		}
+		checkBatches(fmt.Sprintf("mock '%v': ", k), c.SentBatches[k], sent, reportFailure)
+	}
+
+	caches := make([]string, 0, len(c.CacheValues))
+	for k := range c.CacheValues {
+		caches = append(caches, k)
+	}
+	sort.Strings(caches)
+	for _, name := range caches {
+		cache, err := resources.GetCache(name)
+		if err != nil {
+			reportFailure(fmt.Sprintf("cache '%v': %v", name, err))
+			continue
+		}
+		keys := make([]string, 0, len(c.CacheValues[name]))
+		for k := range c.CacheValues[name] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, err := cache.Get(key)
+			if err != nil {
+				reportFailure(fmt.Sprintf("cache '%v' key '%v': %v", name, key, err))
+				continue
+			}
+			for _, condErr := range c.CacheValues[name][key].CheckAll(message.NewPart(value)) {
+				reportFailure(fmt.Sprintf("cache '%v' key '%v': %v", name, key, condErr))
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// goldenPart is the representation of a message part within a golden file.
+type goldenPart struct {
+	Content  string            `yaml:"content"`
+	Metadata map[string]string `yaml:"metadata,omitempty"`
+}
+
+func goldenBytes(batches []types.Message) ([]byte, error) {
+	golden := [][]goldenPart{}
+	for _, b := range batches {
+		var parts []goldenPart
+		b.Iter(func(i int, p types.Part) error {
+			gPart := goldenPart{Content: string(p.Get())}
+			p.Metadata().Iter(func(k, v string) error {
+				if gPart.Metadata == nil {
+					gPart.Metadata = map[string]string{}
+				}
+				gPart.Metadata[k] = v
+				return nil
+			})
+			parts = append(parts, gPart)
+			return nil
+		})
+		golden = append(golden, parts)
+	}
+	return yaml.Marshal(golden)
+}
+
+// checkGolden compares the output batches of a case against a golden file, or
+// writes the golden file when updateGolden is true.
+func (c *Case) checkGolden(dir string, updateGolden bool, batches []types.Message, reportFailure func(string)) {
+	path := c.GoldenFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	actual, err := goldenBytes(batches)
+	if err != nil {
+		reportFailure(fmt.Sprintf("failed to serialise output batches: %v", err))
+		return
+	}
+
+	if updateGolden {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = ioutil.WriteFile(path, actual, 0644)
+		}
+		if err != nil {
+			reportFailure(fmt.Sprintf("failed to update golden file '%v': %v", c.GoldenFile, err))
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			reportFailure(fmt.Sprintf("golden file '%v' does not exist, run with --update to create it", c.GoldenFile))
+		} else {
+			reportFailure(fmt.Sprintf("failed to read golden file '%v': %v", c.GoldenFile, err))
+		}
+		return
+	}
+	if bytes.Equal(expected, actual) {
+		return
+	}
+
+	expLines := strings.Split(string(expected), "\n")
+	actLines := strings.Split(string(actual), "\n")
+	for i := 0; i < len(expLines) || i < len(actLines); i++ {
+		var expLine, actLine string
+		if i < len(expLines) {
+			expLine = expLines[i]
+		}
+		if i < len(actLines) {
+			actLine = actLines[i]
+		}
+		if expLine != actLine {
+			reportFailure(fmt.Sprintf(
+				"output does not match golden file '%v' at line %v, run with --update to update it\n  expected: %v\n  received: %v",
+				c.GoldenFile, i+1, expLine, actLine,
+			))
+			return
+		}
+	}
 }
 
 //------------------------------------------------------------------------------
@@ -21,6 +21,11 @@ func CliCommand(testSuffix string) *cli.Command {
    benthos test ./foo_configs ./bar_configs
    benthos test ./foo.yaml
 
+   Golden files referenced by test cases can be created or updated with the
+   current output of their processors with the --update flag:
+
+   benthos test --update ./foo.yaml
+
    For more information check out the docs at:
    https://benthos.dev/docs/configuration/unit_testing`[4:],
 		Flags: []cli.Flag{
@@ -34,6 +39,11 @@ func CliCommand(testSuffix string) *cli.Command {
 				Value: "",
 				Usage: "allow components to write logs at a provided level to stdout.",
 			},
+			&cli.BoolFlag{
+				Name:  "update",
+				Value: false,
+				Usage: "instead of comparing the output of test cases against their golden files, write the output to them.",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			if c.Bool("generate") {
@@ -49,11 +59,11 @@ func CliCommand(testSuffix string) *cli.Command {
 				logConf := log.NewConfig()
 				logConf.LogLevel = logLevel
 				logger := log.New(os.Stdout, logConf)
-				if runAll(c.Args().Slice(), testSuffix, true, logger, c.StringSlice("resources")) {
+				if runAll(c.Args().Slice(), testSuffix, true, logger, c.StringSlice("resources"), c.Bool("update")) {
 					os.Exit(0)
 				}
 			} else {
-				if runAll(c.Args().Slice(), testSuffix, true, log.Noop(), c.StringSlice("resources")) {
+				if runAll(c.Args().Slice(), testSuffix, true, log.Noop(), c.StringSlice("resources"), c.Bool("update")) {
 					os.Exit(0)
 				}
 			}
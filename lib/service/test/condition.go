@@ -10,6 +10,7 @@ import (
 
 	"github.com/Jeffail/benthos/v3/lib/bloblang"
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/nsf/jsondiff"
 	yaml "gopkg.in/yaml.v3"
@@ -75,6 +76,12 @@ func (c *ConditionsMap) UnmarshalYAML(value *yaml.Node) error {
 				return fmt.Errorf("line %v: %v", v.Line, err)
 			}
 			cond = val
+		case "errored":
+			val := ErroredCondition(false)
+			if err := v.Decode(&val); err != nil {
+				return fmt.Errorf("line %v: %v", v.Line, err)
+			}
+			cond = val
 		default:
 			return fmt.Errorf("line %v: message part condition type not recognised: %v", v.Line, k)
 		}
@@ -215,6 +222,24 @@ func (m MetadataEqualsCondition) Check(p types.Part) error {
 
 //------------------------------------------------------------------------------
 
+// ErroredCondition checks whether a message has failed processing, or when
+// false that it has not.
+type ErroredCondition bool
+
+// Check this condition against a message part.
+func (e ErroredCondition) Check(p types.Part) error {
+	failed := processor.HasFailed(p)
+	if bool(e) && !failed {
+		return errors.New("expected message to have failed processing")
+	}
+	if !bool(e) && failed {
+		return fmt.Errorf("expected message not to have failed processing\n  received: %v", red(processor.GetFail(p)))
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // Helper function for converting yaml.Node to a string
 // simple nodes are converted to their string equivalents
 // complex nodes are converted to a JSON representation
@@ -2,6 +2,7 @@ package test
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"golang.org/x/sync/errgroup"
@@ -28,25 +29,25 @@ func ExampleDefinition() Definition {
 // ExecuteWithLogger attempts to run a test definition on a target config file,
 // with a logger. Returns an array of test failures or an error.
 func (d Definition) ExecuteWithLogger(filepath string, logger log.Modular) ([]CaseFailure, error) {
-	return d.execute(filepath, nil, logger)
+	return d.execute(filepath, nil, logger, false)
 }
 
 // Execute attempts to run a test definition on a target config file. Returns
 // an array of test failures or an error.
 func (d Definition) Execute(filepath string) ([]CaseFailure, error) {
-	return d.execute(filepath, nil, log.Noop())
+	return d.execute(filepath, nil, log.Noop(), false)
 }
 
-func (d Definition) execute(filepath string, resourcesPaths []string, logger log.Modular) ([]CaseFailure, error) {
+func (d Definition) execute(targetPath string, resourcesPaths []string, logger log.Modular, updateGolden bool) ([]CaseFailure, error) {
 	procsProvider := NewProcessorsProvider(
-		filepath,
+		targetPath,
 		OptAddResourcesPaths(resourcesPaths),
 		OptProcessorsProviderSetLogger(logger),
 	)
 	if d.Parallel {
 		// Warm the cache of processor configs.
 		for _, c := range d.Cases {
-			if _, err := procsProvider.getConfs(c.TargetProcessors, c.Environment); err != nil {
+			if _, err := procsProvider.getConfs(c.TargetProcessors, c.Environment, c.Mocks); err != nil {
 				return nil, err
 			}
 		}
@@ -56,7 +57,7 @@ func (d Definition) execute(filepath string, resourcesPaths []string, logger log
 	if !d.Parallel {
 		for i, c := range d.Cases {
 			cleanupEnv := setEnvironment(c.Environment)
-			failures, err := c.Execute(procsProvider)
+			failures, err := c.execute(procsProvider, filepath.Dir(targetPath), updateGolden)
 			if err != nil {
 				cleanupEnv()
 				return nil, fmt.Errorf("test case %v failed: %v", i, err)
@@ -72,7 +73,7 @@ func (d Definition) execute(filepath string, resourcesPaths []string, logger log
 			i := i
 			c := c
 			g.Go(func() error {
-				failures, err := c.Execute(procsProvider)
+				failures, err := c.execute(procsProvider, filepath.Dir(targetPath), updateGolden)
 				if err != nil {
 					return fmt.Errorf("test case %v failed: %v", i, err)
 				}
@@ -0,0 +1,249 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/benthos/v3/lib/cache"
+	"github.com/Jeffail/benthos/v3/lib/config"
+	"github.com/Jeffail/benthos/v3/lib/manager"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	yaml "gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// Resources provides access to the mocked components of a test case after its
+// processors have been executed.
+type Resources interface {
+	// SentBatches returns the batches that were sent to a mocked processor,
+	// identified by the key of its mock.
+	SentBatches(mock string) ([]types.Message, error)
+
+	// GetCache attempts to find a cache resource by its name.
+	GetCache(name string) (types.Cache, error)
+
+	// ErrorStreams returns the error streams of the pipeline when the target
+	// processors are those of the pipeline.
+	ErrorStreams() []pipeline.ErrorStreamConfig
+}
+
+// MockProcProvider is a ProcProvider that is able to replace components of a
+// config with mocks before extracting processors from it.
+type MockProcProvider interface {
+	ProcProvider
+
+	// ProvideMocked extracts processors from a config after replacing any
+	// components identified by the keys of mocks, which are either the names
+	// of processor, cache or rate limit resources, or JSON Pointers to
+	// processors, with the respective config values.
+	ProvideMocked(jsonPtr string, environment map[string]string, mocks map[string]interface{}) ([]types.Processor, Resources, error)
+}
+
+//------------------------------------------------------------------------------
+
+// recordingProc is a processor that records the batches it receives before
+// passing them to a wrapped processor.
+type recordingProc struct {
+	types.Processor
+
+	mut  sync.Mutex
+	sent []types.Message
+}
+
+func (r *recordingProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	r.mut.Lock()
+	r.sent = append(r.sent, msg.Copy())
+	r.mut.Unlock()
+	return r.Processor.ProcessMessage(msg)
+}
+
+// mockManager is a manager that records the batches sent to mocked processor
+// resources.
+type mockManager struct {
+	*manager.Type
+	recorders    map[string]*recordingProc
+	errorStreams []pipeline.ErrorStreamConfig
+}
+
+func newMockManager(mgr *manager.Type, mocked []string, errorStreams []pipeline.ErrorStreamConfig) (*mockManager, error) {
+	m := &mockManager{
+		Type:         mgr,
+		recorders:    map[string]*recordingProc{},
+		errorStreams: errorStreams,
+	}
+	for _, name := range mocked {
+		proc, err := mgr.GetProcessor(name)
+		if err != nil {
+			return nil, err
+		}
+		m.recorders[name] = &recordingProc{Processor: proc}
+	}
+	return m, nil
+}
+
+// GetProcessor attempts to find a processor resource by its name, mocked
+// processors are wrapped in order to record the batches they receive.
+func (m *mockManager) GetProcessor(name string) (types.Processor, error) {
+	if r, exists := m.recorders[name]; exists {
+		return r, nil
+	}
+	return m.Type.GetProcessor(name)
+}
+
+// SentBatches returns the batches that were sent to a mocked processor.
+func (m *mockManager) SentBatches(mock string) ([]types.Message, error) {
+	r, exists := m.recorders[mock]
+	if !exists {
+		return nil, fmt.Errorf("processor mock '%v' was not found", mock)
+	}
+	r.mut.Lock()
+	sent := make([]types.Message, len(r.sent))
+	copy(sent, r.sent)
+	r.mut.Unlock()
+	return sent, nil
+}
+
+// ErrorStreams returns the error streams of the pipeline when the target
+// processors are those of the pipeline.
+func (m *mockManager) ErrorStreams() []pipeline.ErrorStreamConfig {
+	return m.errorStreams
+}
+
+//------------------------------------------------------------------------------
+
+// setJSONPointer replaces the value of a generic object at a JSON Pointer.
+func setJSONPointer(path string, object, value interface{}) error {
+	i := strings.LastIndex(path, "/")
+	if i < 0 || path == "/" {
+		return fmt.Errorf("failed to set JSON pointer '%v': path must begin with '/' and target a child", path)
+	}
+	key := strings.Replace(strings.Replace(path[i+1:], "~1", "/", -1), "~0", "~", -1)
+
+	parent := object
+	if i > 0 {
+		var err error
+		if parent, err = config.JSONPointer(path[:i], object); err != nil {
+			return err
+		}
+	}
+
+	switch t := parent.(type) {
+	case map[string]interface{}:
+		if _, exists := t[key]; !exists {
+			return fmt.Errorf("failed to set JSON pointer '%v': field '%v' was not found", path, key)
+		}
+		t[key] = value
+	case []interface{}:
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			return fmt.Errorf("failed to set JSON pointer '%v': could not parse '%v' into array index: %v", path, key, err)
+		}
+		if index < 0 || index >= len(t) {
+			return fmt.Errorf("failed to set JSON pointer '%v': index '%v' exceeded target array size of '%v'", path, index, len(t))
+		}
+		t[index] = value
+	default:
+		return fmt.Errorf("failed to set JSON pointer '%v': parent is not an object or array", path)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of mocks in a deterministic order.
+func sortedKeys(mocks map[string]interface{}) []string {
+	keys := make([]string, 0, len(mocks))
+	for k := range mocks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mockPointers replaces the processors of a generic config that are targeted
+// by mocks keyed by a JSON Pointer with references to processor resources named
+// after the pointer.
+func mockPointers(root interface{}, mocks map[string]interface{}) error {
+	for _, key := range sortedKeys(mocks) {
+		if !strings.HasPrefix(key, "/") {
+			continue
+		}
+		if err := setJSONPointer(key, root, map[string]interface{}{
+			"resource": key,
+		}); err != nil {
+			return fmt.Errorf("failed to apply mock '%v': %v", key, err)
+		}
+	}
+	return nil
+}
+
+// mockResources replaces processor, cache and rate limit resources with mocks,
+// and adds processor resources for mocks keyed by a JSON Pointer. Returns the
+// names of mocked processor resources.
+func mockResources(mgr *manager.Config, mocks map[string]interface{}) ([]string, error) {
+	var mocked []string
+	for _, key := range sortedKeys(mocks) {
+		_, isProc := mgr.Processors[key]
+		_, isCache := mgr.Caches[key]
+		_, isRateLimit := mgr.RateLimits[key]
+
+		isPtr := strings.HasPrefix(key, "/")
+		if isPtr && isProc {
+			return nil, fmt.Errorf("failed to apply mock '%v': a processor resource of the same name already exists", key)
+		}
+
+		matches := 0
+		for _, is := range []bool{isProc, isCache, isRateLimit} {
+			if is {
+				matches++
+			}
+		}
+		if matches > 1 {
+			return nil, fmt.Errorf("failed to apply mock '%v': name matches multiple resources", key)
+		}
+
+		switch {
+		case isPtr, isProc:
+			conf := processor.NewConfig()
+			if err := remarshal(mocks[key], &conf); err != nil {
+				return nil, fmt.Errorf("failed to parse processor mock '%v': %v", key, err)
+			}
+			mgr.Processors[key] = conf
+			mocked = append(mocked, key)
+		case isCache:
+			conf := cache.NewConfig()
+			if err := remarshal(mocks[key], &conf); err != nil {
+				return nil, fmt.Errorf("failed to parse cache mock '%v': %v", key, err)
+			}
+			mgr.Caches[key] = conf
+		case isRateLimit:
+			conf := ratelimit.NewConfig()
+			if err := remarshal(mocks[key], &conf); err != nil {
+				return nil, fmt.Errorf("failed to parse rate limit mock '%v': %v", key, err)
+			}
+			mgr.RateLimits[key] = conf
+		default:
+			return nil, fmt.Errorf("failed to apply mock '%v': key must be a JSON pointer or the name of a processor, cache or rate limit resource", key)
+		}
+	}
+	return mocked, nil
+}
+
+// remarshal parses a generic value into a config struct.
+func remarshal(value, target interface{}) error {
+	rawBytes, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(rawBytes, target)
+}
+
+var errMocksNotSupported = errors.New("processor provider does not support mocks")
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,233 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestDefinitionMocks(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+pipeline:
+  processors:
+    - bloblang: 'root = content().uppercase()'
+    - resource: get_foo
+    - http:
+        url: http://localhost:1/nope
+    - cache:
+        resource: foocache
+        operator: set
+        key: '${! content() }'
+        value: bar
+resources:
+  processors:
+    get_foo:
+      http:
+        url: http://localhost:1/nope
+  caches:
+    foocache:
+      redis:
+        url: tcp://localhost:1
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	var def Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: mocked
+    target_processors: /pipeline/processors
+    mocks:
+      get_foo:
+        bloblang: 'root = content() + " FROM GET_FOO"'
+      /pipeline/processors/2:
+        bloblang: 'root = content() + " FROM HTTP"'
+      foocache:
+        memory: {}
+    input_batch:
+      - content: hello
+        metadata:
+          foo: bar
+    output_batches:
+      - - content_equals: HELLO FROM GET_FOO FROM HTTP
+    sent_batches:
+      get_foo:
+        - - content_equals: HELLO
+      /pipeline/processors/2:
+        - - content_equals: HELLO FROM FOO
+    cache_values:
+      foocache:
+        HELLO FROM GET_FOO FROM HTTP:
+          content_equals: bar
+    golden_file: ./golden/mocked.yaml
+`), &def))
+
+	configPath := filepath.Join(testDir, "config1.yaml")
+
+	failures, err := def.execute(configPath, nil, log.Noop(), true)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "mock '/pipeline/processors/2': batch 0 message 0: content_equals: content mismatch\n  expected: HELLO FROM FOO\n  received: HELLO FROM GET_FOO", failures[0].Reason)
+
+	goldenBytes, err := ioutil.ReadFile(filepath.Join(testDir, "golden", "mocked.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "- - content: HELLO FROM GET_FOO FROM HTTP\n    metadata:\n        foo: bar\n", string(goldenBytes))
+
+	def.Cases[0].SentBatches["/pipeline/processors/2"][0][0]["content_equals"] = ContentEqualsCondition("HELLO FROM GET_FOO")
+	failures, err = def.Execute(configPath)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(testDir, "golden", "mocked.yaml"),
+		[]byte("- - content: HELLO FROM GET_FOO\n    metadata:\n        foo: bar\n"), 0644,
+	))
+	failures, err = def.Execute(configPath)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "output does not match golden file './golden/mocked.yaml' at line 1, run with --update to update it\n  expected: - - content: HELLO FROM GET_FOO\n  received: - - content: HELLO FROM GET_FOO FROM HTTP", failures[0].Reason)
+}
+
+func TestDefinitionMocksErrors(t *testing.T) {
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+pipeline:
+  processors:
+    - bloblang: 'root = content().uppercase()'
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	tests := map[string]struct {
+		mocks map[string]interface{}
+		err   string
+	}{
+		"unknown resource": {
+			mocks: map[string]interface{}{"nope": map[string]interface{}{"noop": map[string]interface{}{}}},
+			err:   "test case 0 failed: failed to initialise processors '/pipeline/processors': failed to apply mock 'nope': key must be a JSON pointer or the name of a processor, cache or rate limit resource",
+		},
+		"bad pointer": {
+			mocks: map[string]interface{}{"/pipeline/processors/5": map[string]interface{}{"noop": map[string]interface{}{}}},
+			err:   "test case 0 failed: failed to initialise processors '/pipeline/processors': failed to apply mock '/pipeline/processors/5': failed to set JSON pointer '/pipeline/processors/5': index '5' exceeded target array size of '1'",
+		},
+	}
+
+	for name, test := range tests {
+		def := Definition{
+			Cases: []Case{{
+				Name:             name,
+				TargetProcessors: "/pipeline/processors",
+				Mocks:            test.mocks,
+			}},
+		}
+		_, err := def.Execute(filepath.Join(testDir, "config1.yaml"))
+		assert.EqualError(t, err, test.err, name)
+	}
+}
+
+func TestDefinitionCacheSeedsAndErrors(t *testing.T) {
+	color.NoColor = true
+
+	testDir, err := initTestFiles(map[string]string{
+		"config1.yaml": `
+pipeline:
+  error_streams:
+    - name: dlq
+      check: 'error().contains("nope")'
+      resource: dlq_out
+  processors:
+    - rate_limit:
+        resource: limiter
+    - cache:
+        resource: foocache
+        operator: get
+        key: '${! content() }'
+    - bloblang: 'root = if content() == "bad" { throw("nope") } else { content() }'
+    - bloblang: 'root = if content() == "drop" { deleted() } else { content() }'
+resources:
+  caches:
+    foocache:
+      redis:
+        url: tcp://localhost:1
+  rate_limits:
+    limiter:
+      redis:
+        url: tcp://localhost:1
+`,
+	})
+	require.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	var def Definition
+	require.NoError(t, yaml.Unmarshal([]byte(`
+tests:
+  - name: seeded
+    target_processors: /pipeline/processors
+    mocks:
+      foocache:
+        memory: {}
+      limiter:
+        local:
+          count: 1000
+    cache_seeds:
+      foocache:
+        a: good
+        b: bad
+        c: drop
+    input_batch:
+      - content: a
+      - content: b
+      - content: c
+    output_batches:
+      - - content_equals: good
+          errored: false
+    error_stream_batches:
+      dlq:
+        - - content_equals: bad
+            errored: true
+            metadata_equals:
+              error_stream: dlq
+    dropped_messages: [ 2 ]
+    cache_values:
+      foocache:
+        a:
+          content_equals: good
+`), &def))
+
+	configPath := filepath.Join(testDir, "config1.yaml")
+
+	failures, err := def.execute(configPath, nil, log.Noop(), false)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	def.Cases[0].DroppedMessages = []int{0, 1, 5}
+	def.Cases[0].ErrorStreamBatches["dlq"][0][0]["errored"] = ErroredCondition(false)
+
+	failures, err = def.execute(configPath, nil, log.Noop(), false)
+	require.NoError(t, err)
+
+	reasons := make([]string, 0, len(failures))
+	for _, f := range failures {
+		reasons = append(reasons, f.Reason)
+	}
+	require.Len(t, reasons, 5)
+	assert.Equal(t, []string{
+		"input message 0 was expected to be dropped: a",
+		"input message 1 was expected to be dropped: b",
+		"dropped message index 5 is out of bounds of the input batch",
+	}, reasons[:3])
+	assert.Contains(t, reasons[3], "error stream 'dlq': batch 0 message 0: errored: expected message not to have failed processing")
+	assert.Contains(t, reasons[3], "nope")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/manager"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/pipeline"
 	"github.com/Jeffail/benthos/v3/lib/processor"
 	"github.com/Jeffail/benthos/v3/lib/types"
 	yaml "gopkg.in/yaml.v3"
@@ -18,8 +19,10 @@ import (
 //------------------------------------------------------------------------------
 
 type cachedConfig struct {
-	mgr   manager.Config
-	procs []processor.Config
+	mgr          manager.Config
+	procs        []processor.Config
+	mocked       []string
+	errorStreams []pipeline.ErrorStreamConfig
 }
 
 // ProcessorsProvider consumes a Benthos config and, given a JSON Pointer,
@@ -67,32 +70,52 @@ func OptProcessorsProviderSetLogger(logger log.Modular) func(*ProcessorsProvider
 // the JSON Pointer targets a single processor config it will be constructed and
 // returned as an array of one element.
 func (p *ProcessorsProvider) Provide(jsonPtr string, environment map[string]string) ([]types.Processor, error) {
-	confs, err := p.getConfs(jsonPtr, environment)
+	confs, err := p.getConfs(jsonPtr, environment, nil)
 	if err != nil {
 		return nil, err
 	}
+	procs, _, err := p.initProcs(confs)
+	return procs, err
+}
+
+// ProvideMocked attempts to extract an array of processors from a Benthos
+// config after replacing components with mocks, where each key of mocks is
+// either the name of a processor or cache resource, or a JSON Pointer to a
+// processor. Returns the processors along with access to the mocked resources.
+func (p *ProcessorsProvider) ProvideMocked(jsonPtr string, environment map[string]string, mocks map[string]interface{}) ([]types.Processor, Resources, error) {
+	confs, err := p.getConfs(jsonPtr, environment, mocks)
+	if err != nil {
+		return nil, nil, err
+	}
 	return p.initProcs(confs)
 }
 
 //------------------------------------------------------------------------------
 
-func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, error) {
-	mgr, err := manager.New(confs.mgr, types.NoopMgr(), p.logger, metrics.Noop())
+func (p *ProcessorsProvider) initProcs(confs cachedConfig) ([]types.Processor, *mockManager, error) {
+	baseMgr, err := manager.New(confs.mgr, types.NoopMgr(), p.logger, metrics.Noop())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialise resources: %v", err)
+	}
+	mgr, err := newMockManager(baseMgr, confs.mocked, confs.errorStreams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialise resources: %v", err)
+		return nil, nil, fmt.Errorf("failed to initialise mocks: %v", err)
 	}
 
 	procs := make([]types.Processor, len(confs.procs))
 	for i, conf := range confs.procs {
 		if procs[i], err = processor.New(conf, mgr, p.logger, metrics.Noop()); err != nil {
-			return nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
+			return nil, nil, fmt.Errorf("failed to initialise processor index '%v': %v", i, err)
 		}
 	}
-	return procs, nil
+	return procs, mgr, nil
 }
 
-func confTargetID(jsonPtr string, environment map[string]string) string {
-	return fmt.Sprintf("%v-%v", jsonPtr, environment)
+func confTargetID(jsonPtr string, environment map[string]string, mocks map[string]interface{}) string {
+	if len(mocks) == 0 {
+		return fmt.Sprintf("%v-%v", jsonPtr, environment)
+	}
+	return fmt.Sprintf("%v-%v-%v", jsonPtr, environment, mocks)
 }
 
 func setEnvironment(vars map[string]string) func() {
@@ -144,8 +167,8 @@ func resolveProcessorsPointer(targetFile string, jsonPtr string) (filePath, proc
 	return
 }
 
-func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]string) (cachedConfig, error) {
-	cacheKey := confTargetID(jsonPtr, environment)
+func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]string, mocks map[string]interface{}) (cachedConfig, error) {
+	cacheKey := confTargetID(jsonPtr, environment, mocks)
 
 	confs, exists := p.cachedConfigs[cacheKey]
 	if exists {
@@ -175,6 +198,19 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
 	}
 
+	var root interface{}
+	if err = yaml.Unmarshal(configBytes, &root); err != nil {
+		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	}
+	if len(mocks) > 0 {
+		if err = mockPointers(root, mocks); err != nil {
+			return confs, err
+		}
+		if configBytes, err = yaml.Marshal(root); err != nil {
+			return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		}
+	}
+
 	mgrWrapper := struct {
 		Manager manager.Config `yaml:"resources"`
 	}{
@@ -202,11 +238,21 @@ func (p *ProcessorsProvider) getConfs(jsonPtr string, environment map[string]str
 		}
 	}
 
+	if confs.mocked, err = mockResources(&mgrWrapper.Manager, mocks); err != nil {
+		return confs, err
+	}
 	confs.mgr = mgrWrapper.Manager
 
-	var root interface{}
-	if err = yaml.Unmarshal(configBytes, &root); err != nil {
-		return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+	if procPath == "/pipeline/processors" {
+		pipeWrapper := struct {
+			Pipeline struct {
+				ErrorStreams []pipeline.ErrorStreamConfig `yaml:"error_streams"`
+			} `yaml:"pipeline"`
+		}{}
+		if err = yaml.Unmarshal(configBytes, &pipeWrapper); err != nil {
+			return confs, fmt.Errorf("failed to parse config file '%v': %v", targetPath, err)
+		}
+		confs.errorStreams = pipeWrapper.Pipeline.ErrorStreams
 	}
 
 	var procs interface{}
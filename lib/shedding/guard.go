@@ -0,0 +1,240 @@
+package shedding
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/event"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// Config contains fields for configuring load shedding.
+type Config struct {
+	Enabled            bool     `json:"enabled" yaml:"enabled"`
+	CheckPeriod        string   `json:"check_period" yaml:"check_period"`
+	MaxHeapBytes       int      `json:"max_heap_bytes" yaml:"max_heap_bytes"`
+	MaxGoroutines      int      `json:"max_goroutines" yaml:"max_goroutines"`
+	ResumeRatio        float64  `json:"resume_ratio" yaml:"resume_ratio"`
+	LowPriorityStreams []string `json:"low_priority_streams" yaml:"low_priority_streams"`
+}
+
+// NewConfig returns a Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:            false,
+		CheckPeriod:        "1s",
+		MaxHeapBytes:       0,
+		MaxGoroutines:      0,
+		ResumeRatio:        0.9,
+		LowPriorityStreams: []string{},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// resumedChan is returned to streams that are not currently being shed.
+var resumedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// Guard periodically checks the heap usage and goroutine count of the process
+// and, when either crosses its configured watermark, sheds load by pausing the
+// inputs of streams until usage falls back below the watermark multiplied by
+// the resume ratio.
+type Guard struct {
+	conf        Config
+	period      time.Duration
+	lowPriority map[string]struct{}
+	readUsage   func() (heapBytes uint64, goroutines int)
+
+	mut      sync.RWMutex
+	shedding bool
+	resumed  chan struct{}
+
+	log              log.Modular
+	mActive          metrics.StatGauge
+	mHeapBytes       metrics.StatGauge
+	mGoroutines      metrics.StatGauge
+	mSheddingStarted metrics.StatCounter
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// New creates a new load shedding guard and starts monitoring resource usage.
+func New(conf Config, log log.Modular, stats metrics.Type) (*Guard, error) {
+	return newGuard(conf, log, stats, readRuntimeUsage)
+}
+
+func newGuard(
+	conf Config,
+	log log.Modular,
+	stats metrics.Type,
+	readUsage func() (heapBytes uint64, goroutines int),
+) (*Guard, error) {
+	if conf.MaxHeapBytes <= 0 && conf.MaxGoroutines <= 0 {
+		return nil, errors.New("at least one of max_heap_bytes or max_goroutines must be set")
+	}
+	if conf.ResumeRatio <= 0 || conf.ResumeRatio > 1 {
+		return nil, fmt.Errorf("resume_ratio must be greater than 0 and no greater than 1, got %v", conf.ResumeRatio)
+	}
+
+	g := &Guard{
+		conf:        conf,
+		lowPriority: map[string]struct{}{},
+		readUsage:   readUsage,
+		resumed:     resumedChan,
+
+		log:              log,
+		mActive:          stats.GetGauge("active"),
+		mHeapBytes:       stats.GetGauge("heap_bytes"),
+		mGoroutines:      stats.GetGauge("goroutines"),
+		mSheddingStarted: stats.GetCounter("started"),
+
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	var err error
+	if g.period, err = time.ParseDuration(conf.CheckPeriod); err != nil {
+		return nil, fmt.Errorf("failed to parse check period: %v", err)
+	}
+	for _, id := range conf.LowPriorityStreams {
+		g.lowPriority[id] = struct{}{}
+	}
+
+	go g.loop()
+	return g, nil
+}
+
+func readRuntimeUsage() (uint64, int) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return memStats.HeapAlloc, runtime.NumGoroutine()
+}
+
+//------------------------------------------------------------------------------
+
+// Resumed returns a channel that is closed when the inputs of a stream are
+// permitted to consume data. When low priority streams are configured only
+// those streams are paused whilst load is being shed, otherwise all streams
+// are paused. The ID of a stream is empty when not running in streams mode.
+//
+// This method is safe to call on a nil Guard, in which case streams are never
+// paused.
+func (g *Guard) Resumed(streamID string) <-chan struct{} {
+	if g == nil {
+		return resumedChan
+	}
+	if len(g.lowPriority) > 0 {
+		if _, exists := g.lowPriority[streamID]; !exists {
+			return resumedChan
+		}
+	}
+	g.mut.RLock()
+	defer g.mut.RUnlock()
+	return g.resumed
+}
+
+// Shedding returns whether load is currently being shed.
+func (g *Guard) Shedding() bool {
+	g.mut.RLock()
+	defer g.mut.RUnlock()
+	return g.shedding
+}
+
+func (g *Guard) check() {
+	heapBytes, goroutines := g.readUsage()
+	g.mHeapBytes.Set(int64(heapBytes))
+	g.mGoroutines.Set(int64(goroutines))
+
+	maxHeap, maxGoroutines := uint64(g.conf.MaxHeapBytes), g.conf.MaxGoroutines
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if !g.shedding {
+		if (maxHeap > 0 && heapBytes >= maxHeap) || (maxGoroutines > 0 && goroutines >= maxGoroutines) {
+			g.shedding = true
+			g.resumed = make(chan struct{})
+			g.mActive.Set(1)
+			g.mSheddingStarted.Incr(1)
+			g.log.Warnf("Resource watermark crossed with %v bytes of heap and %v goroutines, pausing inputs\n", heapBytes, goroutines)
+			event.Emit(event.TypeSheddingStarted, "", nil)
+		}
+		return
+	}
+
+	ratio := g.conf.ResumeRatio
+	if (maxHeap == 0 || float64(heapBytes) < float64(maxHeap)*ratio) &&
+		(maxGoroutines == 0 || float64(goroutines) < float64(maxGoroutines)*ratio) {
+		g.resume()
+		g.log.Infof("Resource usage fell to %v bytes of heap and %v goroutines, resuming inputs\n", heapBytes, goroutines)
+	}
+}
+
+// resume stops shedding load, the lock must be held.
+func (g *Guard) resume() {
+	if !g.shedding {
+		return
+	}
+	g.shedding = false
+	close(g.resumed)
+	g.resumed = resumedChan
+	g.mActive.Set(0)
+	event.Emit(event.TypeSheddingStopped, "", nil)
+}
+
+func (g *Guard) loop() {
+	defer func() {
+		g.mut.Lock()
+		g.resume()
+		g.mut.Unlock()
+		close(g.closedChan)
+	}()
+
+	ticker := time.NewTicker(g.period)
+	defer ticker.Stop()
+	for {
+		g.check()
+		select {
+		case <-ticker.C:
+		case <-g.closeChan:
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CloseAsync shuts down the guard and resumes any paused streams.
+func (g *Guard) CloseAsync() {
+	g.mut.Lock()
+	select {
+	case <-g.closeChan:
+	default:
+		close(g.closeChan)
+	}
+	g.mut.Unlock()
+}
+
+// WaitForClose blocks until the guard has closed down.
+func (g *Guard) WaitForClose(timeout time.Duration) error {
+	select {
+	case <-g.closedChan:
+	case <-time.After(timeout):
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,141 @@
+package shedding
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/event"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+	}
+	return false
+}
+
+func TestGuardConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	_, err := New(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.MaxGoroutines = 10
+	conf.ResumeRatio = 2
+	_, err = New(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.ResumeRatio = 0.9
+	conf.CheckPeriod = "nope"
+	_, err = New(conf, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestGuardWatermarks(t *testing.T) {
+	var mut sync.Mutex
+	heapBytes, goroutines := uint64(0), 0
+	setUsage := func(h uint64, g int) {
+		mut.Lock()
+		heapBytes, goroutines = h, g
+		mut.Unlock()
+	}
+
+	events, cancel := event.Subscribe(10)
+	defer cancel()
+
+	conf := NewConfig()
+	conf.CheckPeriod = "1h"
+	conf.MaxHeapBytes = 1000
+	conf.MaxGoroutines = 100
+	conf.ResumeRatio = 0.5
+
+	g, err := newGuard(conf, log.Noop(), metrics.Noop(), func() (uint64, int) {
+		mut.Lock()
+		defer mut.Unlock()
+		return heapBytes, goroutines
+	})
+	require.NoError(t, err)
+	defer func() {
+		g.CloseAsync()
+		assert.NoError(t, g.WaitForClose(time.Second))
+	}()
+
+	g.check()
+	assert.False(t, g.Shedding())
+	assert.True(t, isClosed(g.Resumed("")))
+
+	setUsage(1000, 10)
+	g.check()
+	assert.True(t, g.Shedding())
+	resumed := g.Resumed("")
+	assert.False(t, isClosed(resumed))
+
+	// Usage must fall below the watermark multiplied by the resume ratio.
+	setUsage(600, 10)
+	g.check()
+	assert.True(t, g.Shedding())
+
+	setUsage(400, 10)
+	g.check()
+	assert.False(t, g.Shedding())
+	assert.True(t, isClosed(resumed))
+
+	setUsage(0, 100)
+	g.check()
+	assert.True(t, g.Shedding())
+
+	setUsage(0, 10)
+	g.check()
+	assert.False(t, g.Shedding())
+
+	var types []event.Type
+	for i := 0; i < 4; i++ {
+		select {
+		case e := <-events:
+			types = append(types, e.Type)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+	assert.Equal(t, []event.Type{
+		event.TypeSheddingStarted,
+		event.TypeSheddingStopped,
+		event.TypeSheddingStarted,
+		event.TypeSheddingStopped,
+	}, types)
+}
+
+func TestGuardLowPriorityStreams(t *testing.T) {
+	conf := NewConfig()
+	conf.CheckPeriod = "1h"
+	conf.MaxHeapBytes = 1000
+	conf.LowPriorityStreams = []string{"foo"}
+
+	g, err := newGuard(conf, log.Noop(), metrics.Noop(), func() (uint64, int) {
+		return 2000, 0
+	})
+	require.NoError(t, err)
+
+	g.check()
+	require.True(t, g.Shedding())
+
+	fooResumed := g.Resumed("foo")
+	assert.False(t, isClosed(fooResumed))
+	assert.True(t, isClosed(g.Resumed("bar")))
+
+	g.CloseAsync()
+	require.NoError(t, g.WaitForClose(time.Second))
+	assert.True(t, isClosed(fooResumed))
+	assert.False(t, g.Shedding())
+}
+
+func TestGuardNil(t *testing.T) {
+	var g *Guard
+	assert.True(t, isClosed(g.Resumed("foo")))
+}
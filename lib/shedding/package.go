@@ -0,0 +1,5 @@
+// Package shedding provides a guard that monitors the resource usage of the
+// process and, when configured watermarks are crossed, applies back pressure
+// to the inputs of streams in order to prevent the process from running out of
+// memory.
+package shedding
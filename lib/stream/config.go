@@ -16,6 +16,8 @@ type Config struct {
 	Buffer   buffer.Config   `json:"buffer" yaml:"buffer"`
 	Pipeline pipeline.Config `json:"pipeline" yaml:"pipeline"`
 	Output   output.Config   `json:"output" yaml:"output"`
+	SLO      SLOConfig       `json:"slo" yaml:"slo"`
+	Limits   LimitsConfig    `json:"limits" yaml:"limits"`
 }
 
 // NewConfig returns a new configuration with default values.
@@ -25,6 +27,8 @@ func NewConfig() Config {
 		Buffer:   buffer.NewConfig(),
 		Pipeline: pipeline.NewConfig(),
 		Output:   output.NewConfig(),
+		SLO:      NewSLOConfig(),
+		Limits:   NewLimitsConfig(),
 	}
 }
 
@@ -60,11 +64,15 @@ func (c Config) Sanitised() (interface{}, error) {
 		Buffer   interface{} `json:"buffer" yaml:"buffer"`
 		Pipeline interface{} `json:"pipeline" yaml:"pipeline"`
 		Output   interface{} `json:"output" yaml:"output"`
+		SLO      interface{} `json:"slo,omitempty" yaml:"slo,omitempty"`
+		Limits   interface{} `json:"limits,omitempty" yaml:"limits,omitempty"`
 	}{
 		Input:    inConf,
 		Buffer:   bufConf,
 		Pipeline: pipeConf,
 		Output:   outConf,
+		SLO:      c.SLO.Sanitised(),
+		Limits:   c.Limits.Sanitised(),
 	}, nil
 }
 
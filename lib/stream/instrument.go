@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"sync/atomic"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// OptSetLayerMetrics sets whether the stream emits an in flight gauge and a
+// batch size distribution for each of its layers. Since this relays every
+// transaction between layers through an extra goroutine it is disabled by
+// default.
+func OptSetLayerMetrics(enabled bool) func(*Type) {
+	return func(t *Type) {
+		t.layerMetrics = enabled
+	}
+}
+
+// relayLayer chains the transactions produced by one layer of the stream to
+// the next. Transactions are only relayed through instrumentTransactions when
+// layer metrics are enabled, or when pending is not nil and in flight messages
+// are counted for draining the stream during shutdown.
+func (t *Type) relayLayer(
+	in <-chan types.Transaction,
+	producerStats, consumerStats metrics.Type,
+	pending *int64,
+) <-chan types.Transaction {
+	if !t.countInFlight() {
+		pending = nil
+	}
+	if !t.layerMetrics {
+		if pending == nil {
+			return in
+		}
+		producerStats, consumerStats = metrics.Noop(), metrics.Noop()
+	}
+	return instrumentTransactions(in, producerStats, consumerStats, pending, t.relayClose)
+}
+
+//------------------------------------------------------------------------------
+
+// batchSizeBuckets are the upper bounds of the buckets of batch size histograms.
+var batchSizeBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// instrumentTransactions relays transactions between two layers of a stream.
+// The size of each batch relayed is recorded as a distribution under the
+// metrics of the producing layer, and the number of transactions relayed that
+// have not yet been acknowledged is recorded as a gauge under the metrics of
+// the consuming layer. When pending is not nil the number of messages relayed
+// that have not yet been acknowledged is also added to it atomically.
+//
+// Once closeChan is closed the relay, along with any goroutines waiting to
+// forward responses upstream, is abandoned.
+func instrumentTransactions(
+	in <-chan types.Transaction,
+	producerStats, consumerStats metrics.Type,
+	pending *int64,
+	closeChan <-chan struct{},
+) <-chan types.Transaction {
+	mBatchSize := metrics.HistogramVecOf(producerStats, "batch.size", nil, batchSizeBuckets).With()
+	mInFlight := consumerStats.GetGauge("in_flight")
+
+	out := make(chan types.Transaction)
+	go func() {
+		defer close(out)
+		for {
+			var tran types.Transaction
+			var open bool
+			select {
+			case tran, open = <-in:
+				if !open {
+					return
+				}
+			case <-closeChan:
+				return
+			}
+
+			batchSize := int64(tran.Payload.Len())
+			mBatchSize.Observe(float64(batchSize))
+			mInFlight.Incr(1)
+			if pending != nil {
+				atomic.AddInt64(pending, batchSize)
+			}
+
+			resChan := make(chan types.Response)
+			go func(upstream chan<- types.Response) {
+				var res types.Response
+				select {
+				case res = <-resChan:
+				case <-closeChan:
+					return
+				}
+				mInFlight.Decr(1)
+				if pending != nil {
+					atomic.AddInt64(pending, -batchSize)
+				}
+				select {
+				case upstream <- res:
+				case <-closeChan:
+				}
+			}(tran.ResponseChan)
+
+			select {
+			case out <- types.NewTransaction(tran.Payload, resChan):
+			case <-closeChan:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+//------------------------------------------------------------------------------
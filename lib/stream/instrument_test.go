@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentTransactions(t *testing.T) {
+	stats := metrics.NewLocal()
+
+	var pending int64
+	in := make(chan types.Transaction)
+	out := instrumentTransactions(in, metrics.Namespaced(stats, "input"), metrics.Namespaced(stats, "output"), &pending, nil)
+
+	resChans := []chan types.Response{}
+	var trans []types.Transaction
+	for i := 1; i <= 2; i++ {
+		parts := make([][]byte, i)
+		for j := range parts {
+			parts[j] = []byte("foo")
+		}
+		resChan := make(chan types.Response)
+		resChans = append(resChans, resChan)
+
+		select {
+		case in <- types.NewTransaction(message.New(parts), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		select {
+		case tran := <-out:
+			assert.Equal(t, i, tran.Payload.Len())
+			trans = append(trans, tran)
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	assert.Equal(t, int64(2), stats.GetCounters()["output.in_flight"])
+	assert.Equal(t, int64(2), stats.GetTimings()["input.batch.size"])
+	assert.Equal(t, int64(3), atomic.LoadInt64(&pending))
+
+	for i, tran := range trans {
+		go func(c chan<- types.Response) {
+			c <- response.NewAck()
+		}(tran.ResponseChan)
+		select {
+		case res := <-resChans[i]:
+			require.NoError(t, res.Error())
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	assert.Eventually(t, func() bool {
+		return stats.GetCounters()["output.in_flight"] == 0 && atomic.LoadInt64(&pending) == 0
+	}, time.Second, time.Millisecond*10)
+
+	close(in)
+	select {
+	case _, open := <-out:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestInstrumentTransactionsClose(t *testing.T) {
+	in := make(chan types.Transaction)
+	closeChan := make(chan struct{})
+	out := instrumentTransactions(in, metrics.Noop(), metrics.Noop(), nil, closeChan)
+
+	resChan := make(chan types.Response)
+	select {
+	case in <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// Nothing consumes the relayed transaction, which must not block the relay
+	// from exiting once closed.
+	close(closeChan)
+	select {
+	case _, open := <-out:
+		if open {
+			_, open = <-out
+		}
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestRelayLayerOptIn(t *testing.T) {
+	strm := &Type{relayClose: make(chan struct{})}
+	defer close(strm.relayClose)
+
+	var pending int64
+	in := make(chan types.Transaction)
+	assert.Equal(t, (<-chan types.Transaction)(in), strm.relayLayer(in, metrics.Noop(), metrics.Noop(), &pending))
+
+	strm.drainTimeout = time.Second
+	assert.NotEqual(t, (<-chan types.Transaction)(in), strm.relayLayer(in, metrics.Noop(), metrics.Noop(), &pending))
+	assert.Equal(t, (<-chan types.Transaction)(in), strm.relayLayer(in, metrics.Noop(), metrics.Noop(), nil))
+
+	strm.layerMetrics = true
+	assert.NotEqual(t, (<-chan types.Transaction)(in), strm.relayLayer(in, metrics.Noop(), metrics.Noop(), nil))
+}
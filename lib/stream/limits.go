@@ -0,0 +1,304 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/processor"
+	"github.com/Jeffail/benthos/v3/lib/ratelimit"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// LimitsRateConfig contains configuration fields for capping the rate of
+// messages consumed from the input of a stream.
+type LimitsRateConfig struct {
+	Count    int    `json:"count" yaml:"count"`
+	Interval string `json:"interval" yaml:"interval"`
+}
+
+// LimitsConfig contains configuration fields for limiting the resources
+// consumed by a stream, which is mostly useful in streams mode where many
+// streams share a process.
+type LimitsConfig struct {
+	MaxThreads       int              `json:"max_threads" yaml:"max_threads"`
+	MaxBufferedBytes int              `json:"max_buffered_bytes" yaml:"max_buffered_bytes"`
+	Rate             LimitsRateConfig `json:"rate" yaml:"rate"`
+	Isolate          bool             `json:"isolate" yaml:"isolate"`
+}
+
+// NewLimitsConfig returns a LimitsConfig with default values.
+func NewLimitsConfig() LimitsConfig {
+	return LimitsConfig{
+		MaxThreads:       0,
+		MaxBufferedBytes: 0,
+		Rate: LimitsRateConfig{
+			Count:    0,
+			Interval: "1s",
+		},
+		Isolate: false,
+	}
+}
+
+// Sanitised returns the config if any limit is set, otherwise nil.
+func (c LimitsConfig) Sanitised() interface{} {
+	if c.MaxThreads <= 0 && c.MaxBufferedBytes <= 0 && c.Rate.Count <= 0 && !c.Isolate {
+		return nil
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+// applyLimits modifies the config of a stream in order to respect the
+// configured maximum threads and buffered bytes.
+func applyLimits(conf Config, logger log.Modular) Config {
+	limits := conf.Limits
+	if limits.MaxThreads > 0 && conf.Pipeline.Threads > limits.MaxThreads {
+		logger.Warnf(
+			"Reducing pipeline threads from %v to the maximum of %v\n",
+			conf.Pipeline.Threads, limits.MaxThreads,
+		)
+		conf.Pipeline.Threads = limits.MaxThreads
+	}
+	if limits.MaxBufferedBytes > 0 && conf.Buffer.Type == buffer.TypeMemory &&
+		conf.Buffer.Memory.Limit > limits.MaxBufferedBytes {
+		logger.Warnf(
+			"Reducing memory buffer limit from %v to the maximum of %v bytes\n",
+			conf.Buffer.Memory.Limit, limits.MaxBufferedBytes,
+		)
+		conf.Buffer.Memory.Limit = limits.MaxBufferedBytes
+	}
+	return conf
+}
+
+// costLimiter is implemented by rate limits that support consuming more than a
+// single unit of the limit per access.
+type costLimiter interface {
+	AccessCost(cost int) (time.Duration, error)
+}
+
+func newLimitsRate(conf LimitsRateConfig) (costLimiter, error) {
+	rConf := ratelimit.NewConfig()
+	rConf.Type = ratelimit.TypeLocal
+	rConf.Local.Count = conf.Count
+	rConf.Local.Interval = conf.Interval
+
+	rl, err := ratelimit.NewLocal(rConf, types.NoopMgr(), log.Noop(), metrics.Noop())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit: %v", err)
+	}
+	limiter, ok := rl.(costLimiter)
+	if !ok {
+		return nil, errors.New("rate limit does not support access costs")
+	}
+	return limiter, nil
+}
+
+// limitTransactions relays transactions from an input, waiting for the rate
+// limit (when not nil) to allow the number of messages of each batch, and
+// whilst the number of bytes of unacknowledged batches would exceed maxBytes
+// (when larger than zero). A batch is always relayed when no other batches
+// are pending, regardless of its size. Once the close channel is closed
+// transactions are relayed regardless in order to allow the stream to drain.
+//
+// Once relayClose is closed the relay, along with any goroutines waiting to
+// forward responses upstream, is abandoned.
+func limitTransactions(
+	in <-chan types.Transaction,
+	rate costLimiter,
+	maxBytes int64,
+	stats metrics.Type,
+	closeChan, relayClose <-chan struct{},
+) <-chan types.Transaction {
+	mBufferedBytes := stats.GetGauge("limits.buffered_bytes")
+	mThrottled := stats.GetCounter("limits.throttled")
+
+	var mut sync.Mutex
+	var pendingBytes int64
+	released := make(chan struct{}, 1)
+
+	out := make(chan types.Transaction)
+	go func() {
+		defer close(out)
+		for {
+			var tran types.Transaction
+			var open bool
+			select {
+			case tran, open = <-in:
+				if !open {
+					return
+				}
+			case <-relayClose:
+				return
+			}
+
+			throttled := false
+			for rate != nil {
+				wait, _ := rate.AccessCost(tran.Payload.Len())
+				if wait <= 0 {
+					break
+				}
+				throttled = true
+				select {
+				case <-time.After(wait):
+				case <-closeChan:
+					rate = nil
+				case <-relayClose:
+					return
+				}
+			}
+
+			var size int64
+			tran.Payload.Iter(func(i int, p types.Part) error {
+				size += int64(len(p.Get()))
+				return nil
+			})
+
+			for maxBytes > 0 {
+				mut.Lock()
+				fits := pendingBytes == 0 || pendingBytes+size <= maxBytes
+				if fits {
+					pendingBytes += size
+					mBufferedBytes.Set(pendingBytes)
+				}
+				mut.Unlock()
+				if fits {
+					break
+				}
+				throttled = true
+				select {
+				case <-released:
+				case <-closeChan:
+					maxBytes = 0
+				case <-relayClose:
+					return
+				}
+			}
+			if throttled {
+				mThrottled.Incr(1)
+			}
+
+			if maxBytes <= 0 {
+				select {
+				case out <- tran:
+				case <-relayClose:
+					return
+				}
+				continue
+			}
+
+			resChan := make(chan types.Response)
+			go func(upstream chan<- types.Response) {
+				var res types.Response
+				select {
+				case res = <-resChan:
+				case <-relayClose:
+					return
+				}
+				mut.Lock()
+				pendingBytes -= size
+				mBufferedBytes.Set(pendingBytes)
+				mut.Unlock()
+				select {
+				case released <- struct{}{}:
+				default:
+				}
+				select {
+				case upstream <- res:
+				case <-relayClose:
+				}
+			}(tran.ResponseChan)
+
+			select {
+			case out <- types.NewTransaction(tran.Payload, resChan):
+			case <-relayClose:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+//------------------------------------------------------------------------------
+
+// isolatedProc is a processor that recovers from panics, rejecting the batch
+// that caused the panic and reporting it.
+type isolatedProc struct {
+	types.Processor
+	onPanic func(r interface{}, stack []byte)
+}
+
+func (p *isolatedProc) ProcessMessage(msg types.Message) (msgs []types.Message, res types.Response) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.onPanic(r, debug.Stack())
+			msgs, res = nil, response.NewError(fmt.Errorf("processor panicked: %v", r))
+		}
+	}()
+	return p.Processor.ProcessMessage(msg)
+}
+
+// isolatedProcessors returns constructors for the processors of the pipeline
+// config, followed by the complementary processors of the stream, where each
+// processor recovers from panics and reports them as a failure of the stream.
+func (t *Type) isolatedProcessors(stats metrics.Type) []types.ProcessorConstructorFunc {
+	mPanics := t.stats.GetCounter("panics")
+	onPanic := func(r interface{}, stack []byte) {
+		mPanics.Incr(1)
+		t.logger.Errorf("Processor panicked: %v\n%s\n", r, stack)
+		t.failOnce.Do(func() {
+			go t.onFailure(fmt.Errorf("processor panicked: %v", r))
+		})
+	}
+
+	logger := t.logger.NewModule(".pipeline")
+
+	var ctors []types.ProcessorConstructorFunc
+	procs := 0
+	for _, procConf := range t.conf.Pipeline.Processors {
+		procConf := procConf
+		ctors = append(ctors, func() (types.Processor, error) {
+			prefix := fmt.Sprintf("processor.%v", procs)
+			procs++
+			proc, err := processor.New(procConf, t.manager, logger.NewModule("."+prefix), metrics.Namespaced(stats, prefix))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
+			}
+			return &isolatedProc{Processor: proc, onPanic: onPanic}, nil
+		})
+	}
+	for _, ctor := range t.complementaryProcs {
+		ctor := ctor
+		ctors = append(ctors, func() (types.Processor, error) {
+			proc, err := ctor()
+			if err != nil {
+				return nil, err
+			}
+			return &isolatedProc{Processor: proc, onPanic: onPanic}, nil
+		})
+	}
+	return ctors
+}
+
+//------------------------------------------------------------------------------
+
+// OptOnFailure sets a closure to be called when the stream fails in a way
+// that it cannot recover from and should be restarted, which currently only
+// occurs when a processor panics whilst the stream is isolated. The closure
+// is called at most once and from its own goroutine.
+func OptOnFailure(fn func(err error)) func(*Type) {
+	return func(t *Type) {
+		t.onFailure = fn
+	}
+}
+
+//------------------------------------------------------------------------------
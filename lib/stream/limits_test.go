@@ -0,0 +1,177 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyLimits(t *testing.T) {
+	conf := NewConfig()
+	conf.Pipeline.Threads = 8
+	conf.Buffer.Type = buffer.TypeMemory
+	conf.Buffer.Memory.Limit = 1000
+
+	assert.Equal(t, conf, applyLimits(conf, log.Noop()))
+
+	conf.Limits.MaxThreads = 2
+	conf.Limits.MaxBufferedBytes = 100
+
+	limited := applyLimits(conf, log.Noop())
+	assert.Equal(t, 2, limited.Pipeline.Threads)
+	assert.Equal(t, 100, limited.Buffer.Memory.Limit)
+}
+
+func TestLimitTransactionsBytes(t *testing.T) {
+	in := make(chan types.Transaction)
+	closeChan := make(chan struct{})
+	out := limitTransactions(in, nil, 10, metrics.Noop(), closeChan, make(chan struct{}))
+
+	send := func(content string) chan types.Response {
+		resChan := make(chan types.Response)
+		select {
+		case in <- types.NewTransaction(message.New([][]byte{[]byte(content)}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return resChan
+	}
+	receive := func() types.Transaction {
+		select {
+		case tran := <-out:
+			return tran
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+		return types.Transaction{}
+	}
+
+	// A batch larger than the limit is relayed when nothing is pending.
+	resA := send("aaaaaaaaaaaa")
+	tranA := receive()
+
+	resB := send("bbbbb")
+	select {
+	case <-out:
+		t.Fatal("batch exceeding the limit should not have been relayed")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	go func() {
+		tranA.ResponseChan <- response.NewAck()
+	}()
+	assert.Nil(t, (<-resA).Error())
+
+	tranB := receive()
+	assert.Equal(t, "bbbbb", string(tranB.Payload.Get(0).Get()))
+
+	resC := send("ccccc")
+	tranC := receive()
+
+	go func() {
+		tranB.ResponseChan <- response.NewAck()
+		tranC.ResponseChan <- response.NewError(errors.New("nope"))
+	}()
+	assert.Nil(t, (<-resB).Error())
+	assert.EqualError(t, (<-resC).Error(), "nope")
+
+	close(in)
+	_, open := <-out
+	assert.False(t, open)
+}
+
+func TestLimitTransactionsRate(t *testing.T) {
+	rate, err := newLimitsRate(LimitsRateConfig{Count: 2, Interval: "100ms"})
+	require.NoError(t, err)
+
+	in := make(chan types.Transaction)
+	closeChan := make(chan struct{})
+	out := limitTransactions(in, rate, 0, metrics.Noop(), closeChan, make(chan struct{}))
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			in <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), nil)
+		}
+		close(in)
+	}()
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		<-out
+	}
+	assert.True(t, time.Since(started) >= time.Millisecond*50)
+
+	_, open := <-out
+	assert.False(t, open)
+}
+
+func TestLimitTransactionsRelayClosed(t *testing.T) {
+	in := make(chan types.Transaction)
+	closeChan, relayClose := make(chan struct{}), make(chan struct{})
+	out := limitTransactions(in, nil, 10, metrics.Noop(), closeChan, relayClose)
+
+	resChan := make(chan types.Response)
+	select {
+	case in <- types.NewTransaction(message.New([][]byte{[]byte("foo")}), resChan):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	var tran types.Transaction
+	select {
+	case tran = <-out:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+
+	// The relay is abandoned without the upstream response being read.
+	close(relayClose)
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	select {
+	case _, open := <-out:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+type panicProc struct{}
+
+func (p panicProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	panic("oh no")
+}
+
+func (p panicProc) CloseAsync() {}
+
+func (p panicProc) WaitForClose(time.Duration) error {
+	return nil
+}
+
+func TestIsolatedProc(t *testing.T) {
+	var recovered interface{}
+	proc := &isolatedProc{
+		Processor: panicProc{},
+		onPanic: func(r interface{}, stack []byte) {
+			recovered = r
+			assert.NotEmpty(t, stack)
+		},
+	}
+
+	msgs, res := proc.ProcessMessage(message.New([][]byte{[]byte("foo")}))
+	assert.Empty(t, msgs)
+	require.NotNil(t, res)
+	assert.EqualError(t, res.Error(), "processor panicked: oh no")
+	assert.Equal(t, "oh no", recovered)
+}
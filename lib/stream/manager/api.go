@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,6 +17,7 @@ import (
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/pipeline"
 	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
 	"github.com/Jeffail/benthos/v3/lib/util/text"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/gorilla/mux"
@@ -44,9 +46,27 @@ func (m *Type) registerEndpoints() {
 		"GET a list of metrics for the stream.",
 		m.HandleStreamStats,
 	)
+	m.manager.RegisterEndpoint(
+		"/streams/{id}/versions",
+		"GET a list of the retained config versions of a stream.",
+		m.HandleStreamVersions,
+	)
+	m.manager.RegisterEndpoint(
+		"/streams/{id}/diff",
+		"GET the fields that differ between two config versions of a stream,"+
+			" given by the query parameters from and to, where to defaults to"+
+			" the current version.",
+		m.HandleStreamDiff,
+	)
+	m.manager.RegisterEndpoint(
+		"/streams/{id}/rollback",
+		"POST to replace the config of a stream with a previous version,"+
+			" given by the query parameter version.",
+		m.HandleStreamRollback,
+	)
 	m.manager.RegisterEndpoint(
 		"/ready",
-		"Returns 200 OK if the inputs and outputs of all running streams are connected, otherwise a 503 is returned. If there are no active streams 200 is returned.",
+		"Returns 200 OK if the inputs and outputs of all running streams are connected, otherwise a 503 is returned. If there are no active streams 200 is returned. Add the query parameter verbose=true in order to receive a JSON body describing the status of each input and output of each stream.",
 		m.HandleStreamReady,
 	)
 }
@@ -76,6 +96,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		UptimeStr string  `json:"uptime_str"`
 	}
 	infos := map[string]confInfo{}
+	confs := map[string]stream.Config{}
 
 	m.lock.Lock()
 	for id, strInfo := range m.streams {
@@ -84,6 +105,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 			Uptime:    strInfo.Uptime().Seconds(),
 			UptimeStr: strInfo.Uptime().String(),
 		}
+		confs[id] = strInfo.Config()
 	}
 	m.lock.Unlock()
 
@@ -145,6 +167,8 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	for i, id := range toDelete {
 		go func(sid string, j int) {
 			errDelete[j] = m.Delete(sid, time.Until(deadline))
+			before := confs[sid]
+			m.audit.record(r, "delete", sid, &before, nil, errDelete[j])
 			wg.Done()
 		}(id, i)
 	}
@@ -153,6 +177,8 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
 			errUpdate[j] = m.Update(sid, *sconf, time.Until(deadline))
+			before := confs[sid]
+			m.audit.record(r, "update", sid, &before, sconf, errUpdate[j])
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -162,6 +188,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
 			errCreate[j] = m.Create(sid, *sconf)
+			m.audit.record(r, "create", sid, nil, sconf, errCreate[j])
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -221,8 +248,13 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		var resolvedBytes []byte
+		if resolvedBytes, err = secrets.Replace(r.Context(), confBytes); err != nil {
+			return
+		}
+
 		confOut = stream.NewConfig()
-		err = yaml.Unmarshal(text.ReplaceEnvVariables(confBytes), &confOut)
+		err = yaml.Unmarshal(text.ReplaceEnvVariables(resolvedBytes), &confOut)
 		if err == nil {
 			lConfig := config.New()
 			lConfig.Config = confOut
@@ -259,12 +291,11 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		if err = yaml.Unmarshal(patchBytes, &aliasedConf); err != nil {
 			return
 		}
-		confOut = stream.Config{
-			Input:    input.Config(aliasedConf.Input),
-			Buffer:   buffer.Config(aliasedConf.Buffer),
-			Pipeline: pipeline.Config(aliasedConf.Pipeline),
-			Output:   output.Config(aliasedConf.Output),
-		}
+		confOut = confIn
+		confOut.Input = input.Config(aliasedConf.Input)
+		confOut.Buffer = buffer.Config(aliasedConf.Buffer)
+		confOut.Pipeline = pipeline.Config(aliasedConf.Pipeline)
+		confOut.Output = output.Config(aliasedConf.Output)
 		return
 	}
 
@@ -280,6 +311,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		serverErr = m.Create(id, conf)
+		m.audit.record(r, "create", id, nil, &conf, serverErr)
 	case "GET":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
@@ -290,33 +322,43 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 				Active    bool        `json:"active"`
 				Uptime    float64     `json:"uptime"`
 				UptimeStr string      `json:"uptime_str"`
+				Restarts  int         `json:"restarts,omitempty"`
+				Version   int         `json:"version"`
 				Config    interface{} `json:"config"`
 			}{
 				Active:    info.IsRunning(),
 				Uptime:    info.Uptime().Seconds(),
 				UptimeStr: info.Uptime().String(),
+				Restarts:  info.Restarts(),
+				Version:   info.Version(),
 				Config:    sanit,
 			}); serverErr != nil {
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
-			w.Write(bodyBytes)
+			w.Write(secrets.RedactJSON(bodyBytes))
 		}
 	case "PUT":
 		if conf, requestErr = readConfig(); requestErr != nil {
 			return
 		}
+		before := m.readConfigForAudit(id)
 		serverErr = m.Update(id, conf, time.Until(deadline))
+		m.audit.record(r, "update", id, before, &conf, serverErr)
 	case "DELETE":
+		before := m.readConfigForAudit(id)
 		serverErr = m.Delete(id, time.Until(deadline))
+		m.audit.record(r, "delete", id, before, nil, serverErr)
 	case "PATCH":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
-			if conf, requestErr = patchConfig(info.Config()); requestErr != nil {
+			before := info.Config()
+			if conf, requestErr = patchConfig(before); requestErr != nil {
 				return
 			}
 			serverErr = m.Update(id, conf, time.Until(deadline))
+			m.audit.record(r, "update", id, &before, &conf, serverErr)
 		}
 	default:
 		requestErr = fmt.Errorf("verb not supported: %v", r.Method)
@@ -384,9 +426,198 @@ func (m *Type) HandleStreamStats(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// versionQuery parses a version number from a query parameter of a request.
+func versionQuery(r *http.Request, key string) (int, error) {
+	v, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse query parameter '%v': %v", key, err)
+	}
+	return v, nil
+}
+
+// HandleStreamVersions is an http.HandleFunc for listing the retained config
+// versions of a stream.
+func (m *Type) HandleStreamVersions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if len(id) == 0 {
+		http.Error(w, "Var `id` must be set", http.StatusBadRequest)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, fmt.Sprintf("Error: verb not supported: %v", r.Method), http.StatusBadRequest)
+		return
+	}
+
+	versions, err := m.Versions(id)
+	if err == ErrStreamDoesNotExist {
+		http.Error(w, "Stream not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	current := 0
+	if info, err := m.Read(id); err == nil {
+		current = info.Version()
+	}
+
+	type versionInfo struct {
+		Version int         `json:"version"`
+		Created string      `json:"created"`
+		Current bool        `json:"current"`
+		Config  interface{} `json:"config"`
+	}
+	infos := make([]versionInfo, 0, len(versions))
+	for _, v := range versions {
+		infos = append(infos, versionInfo{
+			Version: v.Version,
+			Created: v.Created.UTC().Format(time.RFC3339),
+			Current: v.Version == current,
+			Config:  sanitisedAuditConfig(&v.Config),
+		})
+	}
+
+	resBytes, err := json.Marshal(infos)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resBytes)
+}
+
+// HandleStreamDiff is an http.HandleFunc for obtaining the fields that differ
+// between two config versions of a stream.
+func (m *Type) HandleStreamDiff(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if len(id) == 0 {
+		http.Error(w, "Var `id` must be set", http.StatusBadRequest)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, fmt.Sprintf("Error: verb not supported: %v", r.Method), http.StatusBadRequest)
+		return
+	}
+
+	from, err := versionQuery(r, "from")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var to int
+	if r.URL.Query().Get("to") != "" {
+		if to, err = versionQuery(r, "to"); err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		info, err := m.Read(id)
+		if err == ErrStreamDoesNotExist {
+			http.Error(w, "Stream not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+			return
+		}
+		to = info.Version()
+	}
+
+	var fromVersion, toVersion ConfigVersion
+	if fromVersion, err = m.Version(id, from); err == nil {
+		toVersion, err = m.Version(id, to)
+	}
+	switch err {
+	case nil:
+	case ErrStreamDoesNotExist:
+		http.Error(w, "Stream not found", http.StatusNotFound)
+		return
+	case ErrVersionDoesNotExist:
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	default:
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	changes := auditChanges(
+		"",
+		sanitisedAuditConfig(&fromVersion.Config),
+		sanitisedAuditConfig(&toVersion.Config),
+	)
+	if changes == nil {
+		changes = []AuditChange{}
+	}
+
+	resBytes, err := json.Marshal(struct {
+		From    int           `json:"from"`
+		To      int           `json:"to"`
+		Changes []AuditChange `json:"changes"`
+	}{
+		From:    from,
+		To:      to,
+		Changes: changes,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resBytes)
+}
+
+// HandleStreamRollback is an http.HandleFunc for replacing the config of a
+// stream with a previous version.
+func (m *Type) HandleStreamRollback(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if len(id) == 0 {
+		http.Error(w, "Var `id` must be set", http.StatusBadRequest)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("Error: verb not supported: %v", r.Method), http.StatusBadRequest)
+		return
+	}
+
+	version, err := versionQuery(r, "version")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	deadline, hasDeadline := r.Context().Deadline()
+	if !hasDeadline {
+		deadline = time.Now().Add(m.apiTimeout)
+	}
+
+	before, target, err := m.rollback(id, version, time.Until(deadline))
+	if target != nil {
+		m.audit.record(r, "rollback", id, before, target, err)
+	}
+
+	switch err {
+	case nil:
+	case ErrStreamDoesNotExist:
+		http.Error(w, "Stream not found", http.StatusNotFound)
+	case ErrVersionDoesNotExist:
+		http.Error(w, "Version not found", http.StatusNotFound)
+	default:
+		m.logger.Errorf("Stream rollback Error: %v\n", err)
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+	}
+}
+
 // HandleStreamReady is an http.HandleFunc for providing a ready check across
 // all streams.
 func (m *Type) HandleStreamReady(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("verbose") == "true" {
+		m.handleStreamReadyVerbose(w)
+		return
+	}
+
 	var notReady []string
 
 	m.lock.Lock()
@@ -406,4 +637,36 @@ func (m *Type) HandleStreamReady(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprintf("streams %v are not connected\n", strings.Join(notReady, ", "))))
 }
 
+func (m *Type) handleStreamReadyVerbose(w http.ResponseWriter) {
+	ready := true
+	statuses := map[string]stream.ReadinessStatus{}
+
+	m.lock.Lock()
+	for k, v := range m.streams {
+		status := v.ReadinessStatus()
+		if !status.Ready {
+			ready = false
+		}
+		statuses[k] = status
+	}
+	m.lock.Unlock()
+
+	resBytes, err := json.Marshal(struct {
+		Ready   bool                              `json:"ready"`
+		Streams map[string]stream.ReadinessStatus `json:"streams"`
+	}{
+		Ready:   ready,
+		Streams: statuses,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(resBytes)
+}
+
 //------------------------------------------------------------------------------
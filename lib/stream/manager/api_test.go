@@ -25,6 +25,9 @@ func router(m *Type) *mux.Router {
 	router.HandleFunc("/streams", m.HandleStreamsCRUD)
 	router.HandleFunc("/streams/{id}", m.HandleStreamCRUD)
 	router.HandleFunc("/streams/{id}/stats", m.HandleStreamStats)
+	router.HandleFunc("/streams/{id}/versions", m.HandleStreamVersions)
+	router.HandleFunc("/streams/{id}/diff", m.HandleStreamDiff)
+	router.HandleFunc("/streams/{id}/rollback", m.HandleStreamRollback)
 	return router
 }
 
@@ -626,3 +629,142 @@ func TestTypeAPIGetStats(t *testing.T) {
 		t.Logf("Metrics: %v", stats)
 	}
 }
+
+func TestTypeAPIVersions(t *testing.T) {
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.NoopMgr()),
+		OptSetAPITimeout(time.Second*10),
+	)
+
+	r := router(mgr)
+	conf := harmlessConf()
+
+	request := genRequest("POST", "/streams/foo", conf)
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected result: %v != %v: %s", act, exp, response.Body.String())
+	}
+
+	newConf := harmlessConf()
+	newConf.Buffer.Type = "memory"
+
+	request = genRequest("PUT", "/streams/foo", newConf)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected result: %v != %v: %s", act, exp, response.Body.String())
+	}
+
+	request = genRequest("GET", "/streams/foo/versions", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected result: %v != %v", act, exp)
+	}
+
+	var versions []struct {
+		Version int  `json:"version"`
+		Current bool `json:"current"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &versions); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, len(versions); exp != act {
+		t.Fatalf("Wrong count of versions: %v != %v", act, exp)
+	}
+	if versions[0].Current || !versions[1].Current || versions[1].Version != 2 {
+		t.Errorf("Unexpected versions: %+v", versions)
+	}
+
+	request = genRequest("GET", "/streams/foo/diff?from=1", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected result: %v != %v", act, exp)
+	}
+
+	var diff struct {
+		From    int           `json:"from"`
+		To      int           `json:"to"`
+		Changes []AuditChange `json:"changes"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &diff); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 2, diff.To; exp != act {
+		t.Errorf("Wrong to version: %v != %v", act, exp)
+	}
+	found := false
+	for _, c := range diff.Changes {
+		if c.Path == "buffer.type" {
+			found = true
+			if c.Before != "none" || c.After != "memory" {
+				t.Errorf("Unexpected change: %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Change to buffer.type not found: %+v", diff.Changes)
+	}
+
+	request = genRequest("GET", "/streams/foo/diff?from=9", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	request = genRequest("POST", "/streams/foo/rollback?version=9", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	request = genRequest("POST", "/streams/foo/rollback?version=1", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected result: %v != %v: %s", act, exp, response.Body.String())
+	}
+
+	info, err := mgr.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := 3, info.Version(); exp != act {
+		t.Errorf("Wrong version: %v != %v", act, exp)
+	}
+	if exp, act := "none", info.Config().Buffer.Type; exp != act {
+		t.Errorf("Wrong buffer type: %v != %v", act, exp)
+	}
+
+	request = genRequest("DELETE", "/streams/foo", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Fatalf("Unexpected result: %v != %v", act, exp)
+	}
+
+	// The versions of a deleted stream are removed along with it.
+	request = genRequest("GET", "/streams/foo/versions", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	request = genRequest("POST", "/streams/foo/rollback?version=2", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v: %s", act, exp, response.Body.String())
+	}
+
+	if err := mgr.Stop(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,171 @@
+package manager
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/util/secrets"
+)
+
+//------------------------------------------------------------------------------
+
+// AuditChange describes a single field of a stream config that was changed by
+// a mutation.
+type AuditChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// AuditRecord describes a mutation of a stream performed through the streams
+// mode REST API.
+type AuditRecord struct {
+	Timestamp  time.Time     `json:"timestamp"`
+	Actor      string        `json:"actor,omitempty"`
+	RemoteAddr string        `json:"remote_addr"`
+	Operation  string        `json:"operation"`
+	StreamID   string        `json:"stream_id"`
+	Success    bool          `json:"success"`
+	Error      string        `json:"error,omitempty"`
+	Before     interface{}   `json:"before,omitempty"`
+	After      interface{}   `json:"after,omitempty"`
+	Changes    []AuditChange `json:"changes,omitempty"`
+}
+
+// auditLog writes audit records as lines of JSON.
+type auditLog struct {
+	mut sync.Mutex
+	w   io.Writer
+}
+
+// OptSetAuditLog sets a writer where a line of JSON is written for each create,
+// update and delete of a stream performed through the REST API, describing
+// who performed it, when, and how the config of the stream changed.
+func OptSetAuditLog(w io.Writer) func(*Type) {
+	return func(t *Type) {
+		t.audit = &auditLog{w: w}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// auditActor returns the identity of the user that performed a request, which
+// is the basic authentication username or, when the API is behind an
+// authenticating proxy, the X-Forwarded-User header.
+func auditActor(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	return r.Header.Get("X-Forwarded-User")
+}
+
+// sanitisedAuditConfig returns a generic representation of a stream config
+// for an audit record, or nil if the config is nil.
+func sanitisedAuditConfig(conf *stream.Config) interface{} {
+	if conf == nil {
+		return nil
+	}
+	sanit, err := conf.Sanitised()
+	if err != nil {
+		return nil
+	}
+	// Normalise into generic maps and slices so that configs can be compared,
+	// redacting any secrets so that they are not written to the audit log.
+	var generic interface{}
+	if jBytes, err := json.Marshal(sanit); err == nil {
+		_ = json.Unmarshal(secrets.RedactJSON(jBytes), &generic)
+	}
+	return generic
+}
+
+// auditChanges walks two generic configs and returns the paths of all fields
+// that differ between them.
+func auditChanges(path string, before, after interface{}) []AuditChange {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if !beforeIsMap || !afterIsMap {
+		if reflect.DeepEqual(before, after) {
+			return nil
+		}
+		return []AuditChange{{Path: path, Before: before, After: after}}
+	}
+
+	keys := map[string]struct{}{}
+	for k := range beforeMap {
+		keys[k] = struct{}{}
+	}
+	for k := range afterMap {
+		keys[k] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+
+	var changes []AuditChange
+	for _, k := range sortedKeys {
+		childPath := k
+		if path != "" {
+			childPath = path + "." + k
+		}
+		changes = append(changes, auditChanges(childPath, beforeMap[k], afterMap[k])...)
+	}
+	return changes
+}
+
+// readConfigForAudit returns the config of a stream before it is mutated, or
+// nil if the stream does not exist or audit logging is disabled.
+func (m *Type) readConfigForAudit(id string) *stream.Config {
+	if m.audit == nil {
+		return nil
+	}
+	info, err := m.Read(id)
+	if err != nil {
+		return nil
+	}
+	conf := info.Config()
+	return &conf
+}
+
+// record writes an audit record for a mutation of a stream. Either config may
+// be nil when the stream did not exist before or after the mutation.
+func (a *auditLog) record(r *http.Request, operation, id string, before, after *stream.Config, err error) {
+	if a == nil {
+		return
+	}
+
+	rec := AuditRecord{
+		Timestamp:  time.Now().UTC(),
+		Actor:      auditActor(r),
+		RemoteAddr: r.RemoteAddr,
+		Operation:  operation,
+		StreamID:   id,
+		Success:    err == nil,
+		Before:     sanitisedAuditConfig(before),
+		After:      sanitisedAuditConfig(after),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if rec.Before != nil && rec.After != nil {
+		rec.Changes = auditChanges("", rec.Before, rec.After)
+	}
+
+	recBytes, jErr := json.Marshal(rec)
+	if jErr != nil {
+		return
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.w.Write(append(recBytes, '\n'))
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+func TestAuditChanges(t *testing.T) {
+	before := map[string]interface{}{
+		"buffer": map[string]interface{}{
+			"type": "none",
+		},
+		"input": map[string]interface{}{
+			"type":  "http_server",
+			"limit": 10.0,
+		},
+	}
+	after := map[string]interface{}{
+		"buffer": map[string]interface{}{
+			"type": "memory",
+		},
+		"input": map[string]interface{}{
+			"type": "http_server",
+			"path": "/post",
+		},
+	}
+
+	exp := []AuditChange{
+		{Path: "buffer.type", Before: "none", After: "memory"},
+		{Path: "input.limit", Before: 10.0},
+		{Path: "input.path", After: "/post"},
+	}
+	if act := auditChanges("", before, after); !reflect.DeepEqual(exp, act) {
+		t.Errorf("Wrong changes: %v != %v", act, exp)
+	}
+	if act := auditChanges("", before, before); len(act) > 0 {
+		t.Errorf("Unexpected changes: %v", act)
+	}
+}
+
+func TestTypeAPIAuditLog(t *testing.T) {
+	auditBuf := &bytes.Buffer{}
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.NoopMgr()),
+		OptSetAPITimeout(time.Second*10),
+		OptSetAuditLog(auditBuf),
+	)
+
+	r := router(mgr)
+	conf := harmlessConf()
+
+	request := genRequest("POST", "/streams/foo", conf)
+	request.SetBasicAuth("alice", "secret")
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	newConf := harmlessConf()
+	newConf.Buffer.Type = "memory"
+
+	request = genRequest("PUT", "/streams/foo", newConf)
+	request.Header.Set("X-Forwarded-User", "bob")
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	request = genRequest("DELETE", "/streams/foo", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusOK, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	request = genRequest("DELETE", "/streams/foo", nil)
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	if exp, act := http.StatusNotFound, response.Code; exp != act {
+		t.Errorf("Unexpected result: %v != %v", act, exp)
+	}
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(auditBuf)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	if exp, act := 4, len(records); exp != act {
+		t.Fatalf("Wrong count of audit records: %v != %v", act, exp)
+	}
+
+	type summary struct {
+		actor, operation string
+		success          bool
+		hasBefore        bool
+		hasAfter         bool
+	}
+	exp := []summary{
+		{actor: "alice", operation: "create", success: true, hasAfter: true},
+		{actor: "bob", operation: "update", success: true, hasBefore: true, hasAfter: true},
+		{operation: "delete", success: true, hasBefore: true},
+		{operation: "delete", success: false},
+	}
+	for i, rec := range records {
+		act := summary{
+			actor:     rec.Actor,
+			operation: rec.Operation,
+			success:   rec.Success,
+			hasBefore: rec.Before != nil,
+			hasAfter:  rec.After != nil,
+		}
+		if !reflect.DeepEqual(exp[i], act) {
+			t.Errorf("Wrong audit record %v: %+v != %+v", i, act, exp[i])
+		}
+		if rec.StreamID != "foo" {
+			t.Errorf("Wrong stream id %v: %v", i, rec.StreamID)
+		}
+	}
+
+	expChange := AuditChange{Path: "buffer.type", Before: "none", After: "memory"}
+	var found bool
+	for _, c := range records[1].Changes {
+		if c.Path == expChange.Path {
+			found = true
+			if !reflect.DeepEqual(expChange, c) {
+				t.Errorf("Wrong change: %v != %v", c, expChange)
+			}
+		} else if !strings.HasPrefix(c.Path, "buffer.") {
+			t.Errorf("Unexpected change: %v", c)
+		}
+	}
+	if !found {
+		t.Errorf("Change to buffer type not found: %v", records[1].Changes)
+	}
+	if records[3].Error == "" {
+		t.Error("Expected error in failed delete record")
+	}
+}
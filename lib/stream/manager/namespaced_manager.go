@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"path"
 
+	"github.com/Jeffail/benthos/v3/lib/message/journey"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -100,6 +101,11 @@ func (n *NamespacedManager) UnsetPipe(name string, t <-chan types.Transaction) {
 	n.mgr.UnsetPipe(name, t)
 }
 
+// GetJourneyTap returns the journey tap of the wrapped manager.
+func (n *NamespacedManager) GetJourneyTap() *journey.Tap {
+	return journey.FromManager(n.mgr)
+}
+
 // GetUnderlying returns the underlying types.Manager implementation.
 func (n *NamespacedManager) GetUnderlying() types.Manager {
 	return n.mgr
@@ -1,15 +1,18 @@
 package manager
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime/pprof"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/shedding"
 	"github.com/Jeffail/benthos/v3/lib/stream"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
@@ -24,6 +27,8 @@ type StreamStatus struct {
 	logger       log.Modular
 	metrics      *metrics.Local
 	createdAt    time.Time
+	restarts     int
+	version      int
 }
 
 // NewStreamStatus creates a new StreamStatus.
@@ -54,6 +59,12 @@ func (s *StreamStatus) IsReady() bool {
 	return s.strm.IsReady()
 }
 
+// ReadinessStatus returns a detailed description of the connection status of
+// each input and output of the stream.
+func (s *StreamStatus) ReadinessStatus() stream.ReadinessStatus {
+	return s.strm.ReadinessStatus()
+}
+
 // Uptime returns a time.Duration indicating the current uptime of the stream.
 func (s *StreamStatus) Uptime() time.Duration {
 	if stoppedAfter := atomic.LoadInt64(&s.stoppedAfter); stoppedAfter > 0 {
@@ -72,6 +83,17 @@ func (s *StreamStatus) Metrics() *metrics.Local {
 	return s.metrics
 }
 
+// Restarts returns the number of times the stream has been restarted after
+// failing.
+func (s *StreamStatus) Restarts() int {
+	return s.restarts
+}
+
+// Version returns the version number of the config of the stream.
+func (s *StreamStatus) Version() int {
+	return s.version
+}
+
 // Logger returns the logger of the stream.
 func (s *StreamStatus) Logger() log.Modular {
 	return s.logger
@@ -96,10 +118,19 @@ type Type struct {
 	closed  bool
 	streams map[string]*StreamStatus
 
+	versions    map[string][]ConfigVersion
+	maxVersions int
+
 	manager    types.Manager
 	stats      metrics.Type
 	logger     log.Modular
 	apiTimeout time.Duration
+	audit      *auditLog
+	guard      *shedding.Guard
+
+	layerMetrics    bool
+	drainTimeout    time.Duration
+	onShutdownPhase func(id string, report stream.ShutdownReport)
 
 	pipelineProcCtors []StreamProcConstructorFunc
 
@@ -110,10 +141,13 @@ type Type struct {
 func New(opts ...func(*Type)) *Type {
 	t := &Type{
 		streams:    map[string]*StreamStatus{},
+		versions:   map[string][]ConfigVersion{},
 		manager:    types.DudMgr{},
 		stats:      metrics.Noop(),
 		apiTimeout: time.Second * 5,
 		logger:     log.Noop(),
+
+		maxVersions: defaultMaxVersions,
 	}
 	for _, opt := range opts {
 		opt(t)
@@ -155,6 +189,40 @@ func OptSetAPITimeout(tout time.Duration) func(*Type) {
 	}
 }
 
+// OptSetLoadShedding sets a load shedding guard that pauses the inputs of child
+// streams whilst load is being shed.
+func OptSetLoadShedding(g *shedding.Guard) func(*Type) {
+	return func(t *Type) {
+		t.guard = g
+	}
+}
+
+// OptSetLayerMetrics sets whether child streams emit an in flight gauge and a
+// batch size distribution for each of their layers.
+func OptSetLayerMetrics(enabled bool) func(*Type) {
+	return func(t *Type) {
+		t.layerMetrics = enabled
+	}
+}
+
+// OptSetDrainTimeout sets the maximum period of time that child streams wait
+// during shutdown for in flight messages to be delivered once their inputs
+// have stopped.
+func OptSetDrainTimeout(timeout time.Duration) func(*Type) {
+	return func(t *Type) {
+		t.drainTimeout = timeout
+	}
+}
+
+// OptOnShutdownPhase sets a closure to be called at the beginning of each
+// phase of the shutdown of a child stream, along with the ID of the stream.
+// The closure is called synchronously and therefore must not block.
+func OptOnShutdownPhase(fn func(id string, report stream.ShutdownReport)) func(*Type) {
+	return func(t *Type) {
+		t.onShutdownPhase = fn
+	}
+}
+
 // OptAddProcessors adds processor constructors that will be called for every
 // new stream and attached to the processor pipelines. The constructor is given
 // the name of the stream as an argument.
@@ -177,9 +245,18 @@ var (
 // Create attempts to construct and run a new stream under a unique ID. If the
 // ID already exists an error is returned.
 func (m *Type) Create(id string, conf stream.Config) error {
+	return m.create(id, conf, 0)
+}
+
+func (m *Type) create(id string, conf stream.Config, restarts int) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	return m.createLocked(id, conf, restarts)
+}
 
+// createLocked constructs and runs a new stream. The lock of the manager must
+// be held when calling this method.
+func (m *Type) createLocked(id string, conf stream.Config, restarts int) error {
 	if m.closed {
 		return types.ErrTypeClosed
 	}
@@ -201,25 +278,95 @@ func (m *Type) Create(id string, conf stream.Config) error {
 	strmFlatMetrics := metrics.NewLocal()
 
 	var wrapper *StreamStatus
-	strm, err := stream.New(
-		conf,
-		stream.OptAddProcessors(procCtors...),
-		stream.OptSetLogger(strmLogger),
-		stream.OptSetStats(metrics.Combine(metrics.Namespaced(m.stats, id), strmFlatMetrics)),
-		stream.OptSetManager(namespacedMgr(id, m.manager)),
-		stream.OptOnClose(func() {
-			wrapper.setClosed()
-		}),
-	)
+	var strm *stream.Type
+	var err error
+
+	// Goroutines inherit the profiler labels of the goroutine that starts them,
+	// and therefore labelling the construction of a stream attributes all of
+	// its work to the stream in CPU profiles.
+	pprof.Do(context.Background(), pprof.Labels("stream", id), func(context.Context) {
+		strm, err = stream.New(
+			conf,
+			stream.OptAddProcessors(procCtors...),
+			stream.OptSetLogger(strmLogger),
+			stream.OptSetStats(metrics.Combine(metrics.Namespaced(m.stats, id), strmFlatMetrics)),
+			stream.OptSetManager(namespacedMgr(id, m.manager)),
+			stream.OptSetStreamID(id),
+			stream.OptSetLoadShedding(m.guard, id),
+			stream.OptSetLayerMetrics(m.layerMetrics),
+			stream.OptSetDrainTimeout(m.drainTimeout),
+			stream.OptOnShutdownPhase(func(report stream.ShutdownReport) {
+				if m.onShutdownPhase != nil {
+					m.onShutdownPhase(id, report)
+				}
+			}),
+			stream.OptOnClose(func() {
+				wrapper.setClosed()
+			}),
+			stream.OptOnFailure(func(err error) {
+				// The wrapper is assigned whilst the lock is held.
+				m.lock.Lock()
+				failed := wrapper
+				m.lock.Unlock()
+				m.restart(id, failed, err)
+			}),
+		)
+	})
 	if err != nil {
 		return err
 	}
 
 	wrapper = NewStreamStatus(conf, strm, strmLogger, strmFlatMetrics)
+	wrapper.restarts = restarts
+	wrapper.version = m.recordVersion(id, conf)
 	m.streams[id] = wrapper
 	return nil
 }
 
+// maxRestartBackoff is the longest period that a failed stream waits before
+// being restarted.
+const maxRestartBackoff = time.Minute
+
+// restart replaces a failed stream with a new instance of the same config
+// after a backoff that grows with the number of times it has been restarted,
+// unless in the meantime the stream has been updated or removed, or the
+// manager has been closed.
+func (m *Type) restart(id string, failed *StreamStatus, err error) {
+	backoff := maxRestartBackoff
+	if failed.restarts < 6 {
+		backoff = time.Second << uint(failed.restarts)
+	}
+	m.logger.Errorf("Stream '%v' failed and will be restarted in %v: %v\n", id, backoff, err)
+	<-time.After(backoff)
+
+	isCurrent := func() bool {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		return !m.closed && m.streams[id] == failed
+	}
+	if !isCurrent() {
+		return
+	}
+	if err := failed.strm.Stop(m.apiTimeout); err != nil {
+		m.logger.Errorf("Failed to cleanly stop stream '%v' before restarting it: %v\n", id, err)
+	}
+
+	m.lock.Lock()
+	if m.closed || m.streams[id] != failed {
+		m.lock.Unlock()
+		return
+	}
+	delete(m.streams, id)
+	m.lock.Unlock()
+
+	if err := m.create(id, failed.config, failed.restarts+1); err != nil {
+		m.logger.Errorf("Failed to restart stream '%v': %v\n", id, err)
+		return
+	}
+	metrics.Namespaced(m.stats, id).GetCounter("restarts").Incr(1)
+	m.logger.Infof("Restarted stream '%v'\n", id)
+}
+
 // Read attempts to obtain the status of a managed stream. Returns an error if
 // the stream does not exist.
 func (m *Type) Read(id string) (*StreamStatus, error) {
@@ -239,7 +386,8 @@ func (m *Type) Read(id string) (*StreamStatus, error) {
 }
 
 // Update attempts to stop an existing stream and replace it with a new version
-// of the same stream.
+// of the same stream. If the new version fails to start the previous version
+// is restarted and the error is returned.
 func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) error {
 	m.lock.Lock()
 	wrapper, exists := m.streams[id]
@@ -257,16 +405,35 @@ func (m *Type) Update(id string, conf stream.Config, timeout time.Duration) erro
 		return nil
 	}
 
-	if err := m.Delete(id, timeout); err != nil {
+	if err := m.stop(id, timeout); err != nil {
+		return err
+	}
+	if err := m.Create(id, conf); err != nil {
+		if restoreErr := m.Create(id, wrapper.config); restoreErr != nil {
+			m.logger.Errorf("Failed to restore previous config of stream '%v': %v\n", id, restoreErr)
+		}
 		return err
 	}
-	return m.Create(id, conf)
+	return nil
 }
 
-// Delete attempts to stop and remove a stream by its ID. Returns an error if
-// the stream was not found, or if clean shutdown fails in the specified period
-// of time.
+// Delete attempts to stop and remove a stream by its ID, along with its config
+// versions. Returns an error if the stream was not found, or if clean shutdown
+// fails in the specified period of time.
 func (m *Type) Delete(id string, timeout time.Duration) error {
+	if err := m.stop(id, timeout); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	delete(m.versions, id)
+	m.lock.Unlock()
+	return nil
+}
+
+// stop attempts to stop and remove a stream by its ID whilst retaining its
+// config versions, in order for it to be replaced.
+func (m *Type) stop(id string, timeout time.Duration) error {
 	m.lock.Lock()
 	if m.closed {
 		m.lock.Unlock()
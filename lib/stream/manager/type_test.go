@@ -2,9 +2,11 @@ package manager
 
 import (
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
@@ -190,3 +192,95 @@ func TestTypeBasicClose(t *testing.T) {
 		t.Errorf("Unexpected error: %v != %v", act, exp)
 	}
 }
+
+type panicOnceProc struct {
+	panicked *int32
+}
+
+func (p panicOnceProc) ProcessMessage(msg types.Message) ([]types.Message, types.Response) {
+	if atomic.CompareAndSwapInt32(p.panicked, 0, 1) {
+		panic("oh no")
+	}
+	return []types.Message{msg}, nil
+}
+
+func (p panicOnceProc) CloseAsync() {}
+
+func (p panicOnceProc) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+func TestTypeIsolatedRestart(t *testing.T) {
+	var panicked int32
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.DudMgr{}),
+		OptAddProcessors(func(id string) (types.Processor, error) {
+			return panicOnceProc{panicked: &panicked}, nil
+		}),
+	)
+
+	conf := stream.NewConfig()
+	conf.Input.Type = input.TypeBloblang
+	conf.Input.Bloblang.Mapping = `root = "hello"`
+	conf.Input.Bloblang.Interval = "10ms"
+	conf.Output.Type = output.TypeDrop
+	conf.Limits.Isolate = true
+
+	if err := mgr.Create("foo", conf); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		info, err := mgr.Read("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Restarts() == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for stream to restart")
+		}
+		<-time.After(time.Millisecond * 50)
+	}
+
+	if err := mgr.Stop(time.Second * 5); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTypeUpdateRestoresOnFailure(t *testing.T) {
+	mgr := New(
+		OptSetLogger(log.Noop()),
+		OptSetStats(metrics.Noop()),
+		OptSetManager(types.DudMgr{}),
+	)
+
+	if err := mgr.Create("foo", harmlessConf()); err != nil {
+		t.Fatal(err)
+	}
+
+	badConf := harmlessConf()
+	badConf.Input.Type = "does_not_exist"
+	if err := mgr.Update("foo", badConf, time.Second); err == nil {
+		t.Error("Expected error from bad config")
+	}
+
+	info, err := mgr.Read("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := "http_server", info.Config().Input.Type; exp != act {
+		t.Errorf("Wrong input type: %v != %v", act, exp)
+	}
+	if exp, act := 1, info.Version(); exp != act {
+		t.Errorf("Wrong version: %v != %v", act, exp)
+	}
+
+	if err := mgr.Stop(time.Second); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/stream"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// ConfigVersion is a config that a stream has been created or updated with.
+type ConfigVersion struct {
+	Version int
+	Created time.Time
+	Config  stream.Config
+}
+
+// ErrVersionDoesNotExist is returned when a version of a stream config is not
+// retained by the manager.
+var ErrVersionDoesNotExist = errors.New("stream config version does not exist")
+
+// defaultMaxVersions is the default number of config versions retained for
+// each stream.
+const defaultMaxVersions = 10
+
+// OptSetMaxVersions sets the maximum number of previous configs that are
+// retained for each stream, which can be listed, compared and rolled back to.
+// The versions of a stream are removed when it is deleted.
+func OptSetMaxVersions(n int) func(*Type) {
+	return func(t *Type) {
+		t.maxVersions = n
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// recordVersion adds a config to the versions of a stream, unless it is equal
+// to the latest version, and returns its version number. The lock of the
+// manager must be held when calling this method.
+func (m *Type) recordVersion(id string, conf stream.Config) int {
+	versions := m.versions[id]
+	if l := len(versions); l > 0 {
+		if latest := versions[l-1]; reflect.DeepEqual(latest.Config, conf) {
+			return latest.Version
+		}
+	}
+
+	next := 1
+	if l := len(versions); l > 0 {
+		next = versions[l-1].Version + 1
+	}
+	versions = append(versions, ConfigVersion{
+		Version: next,
+		Created: time.Now(),
+		Config:  conf,
+	})
+	if m.maxVersions > 0 && len(versions) > m.maxVersions {
+		versions = versions[len(versions)-m.maxVersions:]
+	}
+	m.versions[id] = versions
+	return next
+}
+
+// Versions returns the retained config versions of a stream, ordered from
+// oldest to newest. Returns an error if no versions of the stream exist.
+func (m *Type) Versions(id string) ([]ConfigVersion, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.closed {
+		return nil, types.ErrTypeClosed
+	}
+
+	versions, exists := m.versions[id]
+	if !exists {
+		return nil, ErrStreamDoesNotExist
+	}
+	return append([]ConfigVersion(nil), versions...), nil
+}
+
+// Version returns a retained config version of a stream.
+func (m *Type) Version(id string, version int) (ConfigVersion, error) {
+	versions, err := m.Versions(id)
+	if err != nil {
+		return ConfigVersion{}, err
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return ConfigVersion{}, ErrVersionDoesNotExist
+}
+
+// Rollback replaces the config of a stream with a previous version, which is
+// recorded as a new version. If the stream cannot be started with the previous
+// version it continues to run with its current config.
+func (m *Type) Rollback(id string, version int, timeout time.Duration) error {
+	_, _, err := m.rollback(id, version, timeout)
+	return err
+}
+
+// rollback performs a rollback and returns the config of the stream prior to
+// the rollback, or nil if it was not running, and the config being rolled
+// back to. As with Update the running stream is stopped without holding the
+// lock of the manager, as stopping a stream can take up to the timeout.
+func (m *Type) rollback(id string, version int, timeout time.Duration) (before, target *stream.Config, err error) {
+	m.lock.Lock()
+	closed := m.closed
+	versions, exists := m.versions[id]
+	wrapper, running := m.streams[id]
+	m.lock.Unlock()
+
+	if closed {
+		return nil, nil, types.ErrTypeClosed
+	}
+	if !exists {
+		return nil, nil, ErrStreamDoesNotExist
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			conf := v.Config
+			target = &conf
+			break
+		}
+	}
+	if target == nil {
+		return nil, nil, ErrVersionDoesNotExist
+	}
+
+	if running {
+		conf := wrapper.config
+		before = &conf
+		if reflect.DeepEqual(*before, *target) {
+			return before, target, nil
+		}
+		if err = m.stop(id, timeout); err != nil {
+			return before, target, fmt.Errorf("failed to roll back to version %v: %v", version, err)
+		}
+	}
+
+	if err = m.Create(id, *target); err != nil {
+		if before != nil {
+			if restoreErr := m.Create(id, *before); restoreErr != nil {
+				m.logger.Errorf("Failed to restore previous config of stream '%v': %v\n", id, restoreErr)
+			}
+		}
+		return before, target, fmt.Errorf("failed to roll back to version %v: %v", version, err)
+	}
+	return before, target, nil
+}
+
+//------------------------------------------------------------------------------
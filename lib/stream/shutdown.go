@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// ShutdownPhase is a phase of the graceful shutdown of a stream.
+type ShutdownPhase string
+
+// Shutdown phases, which are entered in the order that they are listed.
+const (
+	// ShutdownPhaseInputs is entered when the inputs of the stream are
+	// stopped.
+	ShutdownPhaseInputs ShutdownPhase = "inputs"
+
+	// ShutdownPhaseDrain is entered once inputs have stopped, and lasts until
+	// all in flight messages have been delivered or the drain timeout elapses.
+	ShutdownPhaseDrain ShutdownPhase = "drain"
+
+	// ShutdownPhaseOutputs is entered when the processing pipelines and
+	// outputs of the stream are closed and flushed.
+	ShutdownPhaseOutputs ShutdownPhase = "outputs"
+
+	// ShutdownPhaseDone is entered once the stream has stopped, whether
+	// gracefully or not.
+	ShutdownPhaseDone ShutdownPhase = "done"
+)
+
+// ShutdownReport describes the progress of the shutdown of a stream at the
+// beginning of a phase.
+type ShutdownReport struct {
+	// Phase is the phase being entered.
+	Phase ShutdownPhase
+
+	// Elapsed is the time since the shutdown began.
+	Elapsed time.Duration
+
+	// InFlight is the number of messages consumed by inputs that have not yet
+	// been acknowledged. During the done phase these messages are considered
+	// dropped, and depending on the input may be lost or redelivered. Messages
+	// are only counted when a drain timeout is set, otherwise this is zero.
+	InFlight int64
+}
+
+// OptSetDrainTimeout sets the maximum period of time to wait during shutdown
+// for in flight messages to be delivered once the inputs of the stream have
+// stopped. Messages still in flight after this period are dropped. By default
+// in flight messages are not counted, and only the buffer of the stream is
+// drained, bounded by the overall shutdown timeout.
+func OptSetDrainTimeout(timeout time.Duration) func(*Type) {
+	return func(t *Type) {
+		t.drainTimeout = timeout
+	}
+}
+
+// OptOnShutdownPhase sets a closure to be called at the beginning of each
+// phase of the shutdown of the stream. The closure is called synchronously
+// and therefore must not block.
+func OptOnShutdownPhase(fn func(ShutdownReport)) func(*Type) {
+	return func(t *Type) {
+		t.onShutdownPhase = fn
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// countInFlight returns whether messages in flight are counted, which costs a
+// goroutine per transaction and is therefore only done when the stream has a
+// drain timeout.
+func (t *Type) countInFlight() bool {
+	return t.drainTimeout > 0
+}
+
+// inFlight returns the number of messages consumed by the input layer that
+// have not been acknowledged. When the stream has a buffer this includes both
+// messages yet to be stored in the buffer and messages read from the buffer
+// that are yet to be delivered.
+func (t *Type) inFlight() int64 {
+	return atomic.LoadInt64(&t.pendingInput) + atomic.LoadInt64(&t.pendingBuffered)
+}
+
+func (t *Type) enterShutdownPhase(phase ShutdownPhase, started time.Time) ShutdownReport {
+	report := ShutdownReport{
+		Phase:    phase,
+		Elapsed:  time.Since(started),
+		InFlight: t.inFlight(),
+	}
+	t.logger.Debugf("Entering shutdown phase %v with %v messages in flight\n", phase, report.InFlight)
+	if t.onShutdownPhase != nil {
+		t.onShutdownPhase(report)
+	}
+	return report
+}
+
+// waitForDrain blocks until there are no messages in flight, returning false
+// if the timeout elapses first.
+func (t *Type) waitForDrain(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for t.inFlight() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,337 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// SLOConfig contains configuration fields for service level objectives of a
+// stream, which when breached or recovered result in an event message being
+// written to an output resource.
+type SLOConfig struct {
+	Resource          string  `json:"resource" yaml:"resource"`
+	Window            string  `json:"window" yaml:"window"`
+	MaxLatency        string  `json:"max_latency" yaml:"max_latency"`
+	LatencyPercentile float64 `json:"latency_percentile" yaml:"latency_percentile"`
+	MaxErrorRate      float64 `json:"max_error_rate" yaml:"max_error_rate"`
+}
+
+// NewSLOConfig returns an SLOConfig with default values.
+func NewSLOConfig() SLOConfig {
+	return SLOConfig{
+		Resource:          "",
+		Window:            "1m",
+		MaxLatency:        "",
+		LatencyPercentile: 0.99,
+		MaxErrorRate:      0,
+	}
+}
+
+// Sanitised returns the config if a resource is set, otherwise nil.
+func (c SLOConfig) Sanitised() interface{} {
+	if c.Resource == "" {
+		return nil
+	}
+	return c
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	sloObjectiveLatency   = "latency"
+	sloObjectiveErrorRate = "error_rate"
+
+	// sloMaxSamples is the maximum number of latencies sampled within a window
+	// in order to calculate the latency percentile.
+	sloMaxSamples = 1000
+)
+
+// sloEvent is the body of messages written to the output resource when an
+// objective is breached or recovers.
+type sloEvent struct {
+	Event     string    `json:"event"`
+	Stream    string    `json:"stream,omitempty"`
+	Objective string    `json:"objective"`
+	Threshold float64   `json:"threshold"`
+	Value     float64   `json:"value"`
+	Window    string    `json:"window"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type sloOutputProvider interface {
+	GetOutput(name string) (types.OutputWriter, error)
+}
+
+// sloMonitor measures the latency and error rate of transactions relayed from
+// the input layer of a stream, and at the end of each window evaluates them
+// against the configured objectives.
+type sloMonitor struct {
+	streamID   string
+	resource   string
+	window     time.Duration
+	maxLatency time.Duration
+	percentile float64
+	maxErrRate float64
+
+	mgr sloOutputProvider
+	log log.Modular
+
+	mut       sync.Mutex
+	total     int64
+	failed    int64
+	latencies []time.Duration
+	breached  map[string]bool
+
+	mBreached  metrics.StatCounter
+	mRecovered metrics.StatCounter
+	mSendErr   metrics.StatCounter
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func newSLOMonitor(streamID string, conf SLOConfig, mgr types.Manager, log log.Modular, stats metrics.Type) (*sloMonitor, error) {
+	// TODO: V4 Remove this
+	provider, ok := mgr.(sloOutputProvider)
+	if !ok {
+		return nil, errors.New("manager does not support output resources")
+	}
+	if _, err := provider.GetOutput(conf.Resource); err != nil {
+		return nil, fmt.Errorf("failed to obtain slo output resource '%v': %v", conf.Resource, err)
+	}
+
+	s := &sloMonitor{
+		streamID:   streamID,
+		resource:   conf.Resource,
+		percentile: conf.LatencyPercentile,
+		maxErrRate: conf.MaxErrorRate,
+		mgr:        provider,
+		log:        log,
+		breached:   map[string]bool{},
+		mBreached:  stats.GetCounter("slo.breached"),
+		mRecovered: stats.GetCounter("slo.recovered"),
+		mSendErr:   stats.GetCounter("slo.send.error"),
+		closeChan:  make(chan struct{}),
+	}
+
+	var err error
+	if s.window, err = time.ParseDuration(conf.Window); err != nil {
+		return nil, fmt.Errorf("failed to parse slo window: %v", err)
+	}
+	if s.window <= 0 {
+		return nil, errors.New("slo window must be greater than zero")
+	}
+	if conf.MaxLatency != "" {
+		if s.maxLatency, err = time.ParseDuration(conf.MaxLatency); err != nil {
+			return nil, fmt.Errorf("failed to parse slo max latency: %v", err)
+		}
+	}
+	if s.maxLatency <= 0 && s.maxErrRate <= 0 {
+		return nil, errors.New("at least one of slo max_latency or max_error_rate must be set")
+	}
+	if s.percentile <= 0 || s.percentile > 1 {
+		return nil, fmt.Errorf("slo latency_percentile must be greater than 0 and no greater than 1, got %v", s.percentile)
+	}
+
+	go s.loop()
+	return s, nil
+}
+
+//------------------------------------------------------------------------------
+
+// observe relays transactions and records the latency and outcome of each one
+// once it has been acknowledged.
+//
+// Once closeChan is closed the relay, along with any goroutines waiting to
+// forward responses upstream, is abandoned.
+func (s *sloMonitor) observe(in <-chan types.Transaction, closeChan <-chan struct{}) <-chan types.Transaction {
+	out := make(chan types.Transaction)
+	go func() {
+		defer close(out)
+		for {
+			var tran types.Transaction
+			var open bool
+			select {
+			case tran, open = <-in:
+				if !open {
+					return
+				}
+			case <-closeChan:
+				return
+			}
+
+			started := time.Now()
+			resChan := make(chan types.Response)
+			go func(upstream chan<- types.Response) {
+				var res types.Response
+				select {
+				case res = <-resChan:
+				case <-closeChan:
+					return
+				}
+				s.record(time.Since(started), res.Error() != nil)
+				select {
+				case upstream <- res:
+				case <-closeChan:
+				}
+			}(tran.ResponseChan)
+
+			select {
+			case out <- types.NewTransaction(tran.Payload, resChan):
+			case <-closeChan:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (s *sloMonitor) record(latency time.Duration, failed bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.total++
+	if failed {
+		s.failed++
+	}
+
+	// Reservoir sample latencies so that memory usage is bounded regardless
+	// of throughput.
+	if len(s.latencies) < sloMaxSamples {
+		s.latencies = append(s.latencies, latency)
+	} else if i := rand.Int63n(s.total); i < sloMaxSamples {
+		s.latencies[i] = latency
+	}
+}
+
+// evaluate resets the current window and returns events for each objective
+// that was breached or recovered within it. A window without any transactions
+// is considered within all objectives.
+func (s *sloMonitor) evaluate() []sloEvent {
+	s.mut.Lock()
+	total, failed, latencies := s.total, s.failed, s.latencies
+	s.total, s.failed, s.latencies = 0, 0, nil
+	s.mut.Unlock()
+
+	var events []sloEvent
+	check := func(objective string, threshold, value float64) {
+		breached := value > threshold
+		if breached == s.breached[objective] {
+			return
+		}
+		s.breached[objective] = breached
+
+		e := sloEvent{
+			Event:     "recovery",
+			Stream:    s.streamID,
+			Objective: objective,
+			Threshold: threshold,
+			Value:     value,
+			Window:    s.window.String(),
+			Timestamp: time.Now(),
+		}
+		if breached {
+			e.Event = "breach"
+			s.mBreached.Incr(1)
+			s.log.Warnf("Service level objective %v breached with %v over a threshold of %v\n", objective, value, threshold)
+		} else {
+			s.mRecovered.Incr(1)
+			s.log.Infof("Service level objective %v recovered with %v within a threshold of %v\n", objective, value, threshold)
+		}
+		events = append(events, e)
+	}
+
+	if s.maxLatency > 0 {
+		var value time.Duration
+		if len(latencies) > 0 {
+			sort.Slice(latencies, func(i, j int) bool {
+				return latencies[i] < latencies[j]
+			})
+			index := int(float64(len(latencies))*s.percentile+0.5) - 1
+			if index < 0 {
+				index = 0
+			}
+			value = latencies[index]
+		}
+		check(sloObjectiveLatency, s.maxLatency.Seconds(), value.Seconds())
+	}
+	if s.maxErrRate > 0 {
+		var value float64
+		if total > 0 {
+			value = float64(failed) / float64(total)
+		}
+		check(sloObjectiveErrorRate, s.maxErrRate, value)
+	}
+	return events
+}
+
+// send writes an event to the output resource, events that fail to send are
+// logged and dropped.
+func (s *sloMonitor) send(e sloEvent) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		s.log.Errorf("Failed to marshal slo event: %v\n", err)
+		return
+	}
+	msg := message.New([][]byte{body})
+	msg.Get(0).Metadata().
+		Set("slo_event", e.Event).
+		Set("slo_objective", e.Objective)
+
+	ctx, done := context.WithTimeout(context.Background(), s.window)
+	defer done()
+
+	resChan := make(chan types.Response)
+	out, err := s.mgr.GetOutput(s.resource)
+	if err == nil {
+		if err = out.WriteTransaction(ctx, types.NewTransaction(msg, resChan)); err == nil {
+			select {
+			case res := <-resChan:
+				err = res.Error()
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+	}
+	if err != nil {
+		s.mSendErr.Incr(1)
+		s.log.Errorf("Failed to send slo %v event to resource '%v': %v\n", e.Event, s.resource, err)
+	}
+}
+
+func (s *sloMonitor) loop() {
+	ticker := time.NewTicker(s.window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, e := range s.evaluate() {
+				s.send(e)
+			}
+		case <-s.closeChan:
+			return
+		}
+	}
+}
+
+// CloseAsync stops evaluating objectives.
+func (s *sloMonitor) CloseAsync() {
+	s.closeOnce.Do(func() {
+		close(s.closeChan)
+	})
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,238 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/log"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/Jeffail/benthos/v3/lib/response"
+	"github.com/Jeffail/benthos/v3/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutputWriter struct {
+	tranChan chan types.Transaction
+}
+
+func (f *fakeOutputWriter) WriteTransaction(ctx context.Context, t types.Transaction) error {
+	select {
+	case f.tranChan <- t:
+	case <-ctx.Done():
+		return types.ErrTimeout
+	}
+	return nil
+}
+
+func (f *fakeOutputWriter) Connected() bool {
+	return true
+}
+
+func (f *fakeOutputWriter) CloseAsync() {
+}
+
+func (f *fakeOutputWriter) WaitForClose(timeout time.Duration) error {
+	return nil
+}
+
+type fakeMgr struct {
+	outputs map[string]types.OutputWriter
+}
+
+func (f *fakeMgr) RegisterEndpoint(path, desc string, h http.HandlerFunc) {
+}
+func (f *fakeMgr) GetCache(name string) (types.Cache, error) {
+	return nil, types.ErrCacheNotFound
+}
+func (f *fakeMgr) GetCondition(name string) (types.Condition, error) {
+	return nil, types.ErrConditionNotFound
+}
+func (f *fakeMgr) GetRateLimit(name string) (types.RateLimit, error) {
+	return nil, types.ErrRateLimitNotFound
+}
+func (f *fakeMgr) GetOutput(name string) (types.OutputWriter, error) {
+	if o, exists := f.outputs[name]; exists {
+		return o, nil
+	}
+	return nil, types.ErrOutputNotFound
+}
+func (f *fakeMgr) GetPlugin(name string) (interface{}, error) {
+	return nil, types.ErrPluginNotFound
+}
+func (f *fakeMgr) GetPipe(name string) (<-chan types.Transaction, error) {
+	return nil, types.ErrPipeNotFound
+}
+func (f *fakeMgr) SetPipe(name string, prod <-chan types.Transaction)   {}
+func (f *fakeMgr) UnsetPipe(name string, prod <-chan types.Transaction) {}
+
+func TestSLOConfigErrors(t *testing.T) {
+	mgr := &fakeMgr{outputs: map[string]types.OutputWriter{
+		"foo": &fakeOutputWriter{},
+	}}
+
+	conf := NewSLOConfig()
+	conf.Resource = "bar"
+	conf.MaxErrorRate = 0.1
+	_, err := newSLOMonitor("", conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.Resource = "foo"
+	conf.MaxErrorRate = 0
+	_, err = newSLOMonitor("", conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.MaxLatency = "nope"
+	_, err = newSLOMonitor("", conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+
+	conf.MaxLatency = "1s"
+	conf.LatencyPercentile = 2
+	_, err = newSLOMonitor("", conf, mgr, log.Noop(), metrics.Noop())
+	assert.Error(t, err)
+}
+
+func TestSLOBreachAndRecovery(t *testing.T) {
+	out := &fakeOutputWriter{tranChan: make(chan types.Transaction)}
+	mgr := &fakeMgr{outputs: map[string]types.OutputWriter{
+		"foo": out,
+	}}
+
+	conf := NewSLOConfig()
+	conf.Resource = "foo"
+	conf.Window = "1h"
+	conf.MaxLatency = "100ms"
+	conf.LatencyPercentile = 0.5
+	conf.MaxErrorRate = 0.5
+
+	s, err := newSLOMonitor("bar", conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer s.CloseAsync()
+
+	s.record(time.Millisecond*10, false)
+	s.record(time.Millisecond*10, false)
+	assert.Empty(t, s.evaluate())
+
+	s.record(time.Millisecond*200, true)
+	s.record(time.Millisecond*200, true)
+	s.record(time.Millisecond*10, false)
+
+	events := s.evaluate()
+	require.Len(t, events, 2)
+	assert.Equal(t, "breach", events[0].Event)
+	assert.Equal(t, "latency", events[0].Objective)
+	assert.Equal(t, 0.1, events[0].Threshold)
+	assert.Equal(t, 0.2, events[0].Value)
+	assert.Equal(t, "breach", events[1].Event)
+	assert.Equal(t, "error_rate", events[1].Objective)
+	assert.InDelta(t, 2.0/3.0, events[1].Value, 0.001)
+
+	// Objectives that remain breached are not reported again.
+	s.record(time.Millisecond*200, true)
+	assert.Empty(t, s.evaluate())
+
+	// A window without transactions is within all objectives.
+	events = s.evaluate()
+	require.Len(t, events, 2)
+	assert.Equal(t, "recovery", events[0].Event)
+	assert.Equal(t, "recovery", events[1].Event)
+
+	sent := make(chan struct{})
+	go func() {
+		s.send(events[0])
+		close(sent)
+	}()
+
+	select {
+	case tran := <-out.tranChan:
+		var e sloEvent
+		require.NoError(t, json.Unmarshal(tran.Payload.Get(0).Get(), &e))
+		assert.Equal(t, "recovery", e.Event)
+		assert.Equal(t, "bar", e.Stream)
+		assert.Equal(t, "latency", e.Objective)
+		assert.Equal(t, "recovery", tran.Payload.Get(0).Metadata().Get("slo_event"))
+		tran.ResponseChan <- response.NewAck()
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	<-sent
+}
+
+func TestSLOObserve(t *testing.T) {
+	mgr := &fakeMgr{outputs: map[string]types.OutputWriter{
+		"foo": &fakeOutputWriter{},
+	}}
+
+	conf := NewSLOConfig()
+	conf.Resource = "foo"
+	conf.Window = "1h"
+	conf.MaxErrorRate = 0.1
+
+	s, err := newSLOMonitor("", conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer s.CloseAsync()
+
+	in := make(chan types.Transaction)
+	out := s.observe(in, make(chan struct{}))
+
+	for _, resErr := range []error{nil, errors.New("nope")} {
+		resChan := make(chan types.Response)
+		in <- types.NewTransaction(nil, resChan)
+		tran := <-out
+		go func(err error) {
+			tran.ResponseChan <- response.NewError(err)
+		}(resErr)
+		<-resChan
+	}
+
+	s.mut.Lock()
+	assert.Equal(t, int64(2), s.total)
+	assert.Equal(t, int64(1), s.failed)
+	assert.Len(t, s.latencies, 2)
+	s.mut.Unlock()
+
+	close(in)
+	_, open := <-out
+	assert.False(t, open)
+}
+
+func TestSLOObserveClosed(t *testing.T) {
+	mgr := &fakeMgr{outputs: map[string]types.OutputWriter{
+		"foo": &fakeOutputWriter{},
+	}}
+
+	conf := NewSLOConfig()
+	conf.Resource = "foo"
+	conf.Window = "1h"
+	conf.MaxErrorRate = 0.1
+
+	s, err := newSLOMonitor("", conf, mgr, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	defer s.CloseAsync()
+
+	in, closeChan := make(chan types.Transaction), make(chan struct{})
+	out := s.observe(in, closeChan)
+
+	// Neither the relay nor the response forwarder should block once closed,
+	// even though the transaction is never acknowledged upstream.
+	resChan := make(chan types.Response)
+	in <- types.NewTransaction(nil, resChan)
+	tran := <-out
+	close(closeChan)
+
+	select {
+	case tran.ResponseChan <- response.NewAck():
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	select {
+	case _, open := <-out:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
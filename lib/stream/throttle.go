@@ -0,0 +1,36 @@
+package stream
+
+import (
+	"github.com/Jeffail/benthos/v3/lib/types"
+)
+
+//------------------------------------------------------------------------------
+
+// throttleTransactions relays transactions from an input only whilst the
+// resumed channel is closed, applying back pressure to the input when it is
+// not. Once the close channel is closed transactions are relayed regardless in
+// order to allow the stream to drain.
+func throttleTransactions(
+	in <-chan types.Transaction,
+	resumed func() <-chan struct{},
+	closeChan <-chan struct{},
+) <-chan types.Transaction {
+	out := make(chan types.Transaction)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-resumed():
+			case <-closeChan:
+			}
+			tran, open := <-in
+			if !open {
+				return
+			}
+			out <- tran
+		}
+	}()
+	return out
+}
+
+//------------------------------------------------------------------------------
@@ -2,16 +2,21 @@ package stream
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime/pprof"
+	"sync"
 	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/buffer"
+	"github.com/Jeffail/benthos/v3/lib/event"
 	"github.com/Jeffail/benthos/v3/lib/input"
 	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/metrics"
 	"github.com/Jeffail/benthos/v3/lib/output"
 	"github.com/Jeffail/benthos/v3/lib/pipeline"
+	"github.com/Jeffail/benthos/v3/lib/shedding"
 	"github.com/Jeffail/benthos/v3/lib/types"
 )
 
@@ -19,6 +24,10 @@ import (
 
 // Type creates and manages the lifetime of a Benthos stream.
 type Type struct {
+	// Accessed atomically, kept first for alignment.
+	pendingInput    int64
+	pendingBuffered int64
+
 	conf Config
 
 	inputLayer    input.Type
@@ -32,7 +41,27 @@ type Type struct {
 	stats   metrics.Type
 	logger  log.Modular
 
-	onClose func()
+	// id is the ID of the stream when running in streams mode.
+	id string
+
+	guard         *shedding.Guard
+	throttleClose chan struct{}
+	closeOnce     sync.Once
+
+	// relayClose is closed once Stop returns, at which point any relays between
+	// layers that remain blocked are abandoned.
+	relayClose     chan struct{}
+	relayCloseOnce sync.Once
+
+	layerMetrics    bool
+	drainTimeout    time.Duration
+	onShutdownPhase func(ShutdownReport)
+
+	slo *sloMonitor
+
+	onClose   func()
+	onFailure func(err error)
+	failOnce  sync.Once
 }
 
 // New creates a new stream.Type.
@@ -43,6 +72,9 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		logger:  log.Noop(),
 		manager: types.NoopMgr(),
 		onClose: func() {},
+
+		throttleClose: make(chan struct{}),
+		relayClose:    make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(t)
@@ -52,6 +84,20 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	}
 
 	healthCheck := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("verbose") == "true" {
+			status := t.ReadinessStatus()
+			resBytes, err := json.Marshal(status)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if !status.Ready {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			w.Write(resBytes)
+			return
+		}
 		connected := true
 		if !t.inputLayer.Connected() {
 			connected = false
@@ -69,9 +115,22 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 	}
 	t.manager.RegisterEndpoint(
 		"/ready",
-		"Returns 200 OK if all inputs and outputs are connected, otherwise a 503 is returned.",
+		"Returns 200 OK if all inputs and outputs are connected, otherwise a 503 is returned. Add the query parameter verbose=true in order to receive a JSON body describing the status of each input and output.",
 		healthCheck,
 	)
+	t.manager.RegisterEndpoint(
+		"/connections",
+		"Returns a JSON object describing the connection status of each input and output, including the number of consecutive failures they have encountered and whether they are currently backing off before their next attempt.",
+		func(w http.ResponseWriter, r *http.Request) {
+			resBytes, err := json.Marshal(t.ReadinessStatus())
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(resBytes)
+		},
+	)
 	return t, nil
 }
 
@@ -118,6 +177,28 @@ func OptSetManager(mgr types.Manager) func(*Type) {
 	}
 }
 
+// OptSetStreamID sets the ID of the stream when running in streams mode, which
+// identifies the stream within events such as service level objective
+// breaches.
+func OptSetStreamID(id string) func(*Type) {
+	return func(t *Type) {
+		t.id = id
+	}
+}
+
+// OptSetLoadShedding sets a load shedding guard that pauses the input of the
+// stream whilst load is being shed, along with the ID of the stream, which is
+// used by the guard to determine its priority. An empty ID leaves the ID of the
+// stream unchanged.
+func OptSetLoadShedding(g *shedding.Guard, id string) func(*Type) {
+	return func(t *Type) {
+		t.guard = g
+		if id != "" {
+			t.id = id
+		}
+	}
+}
+
 // OptOnClose sets a closure to be called when the stream closes.
 func OptOnClose(onClose func()) func(*Type) {
 	return func(t *Type) {
@@ -133,34 +214,123 @@ func (t *Type) IsReady() bool {
 	return t.inputLayer.Connected() && t.outputLayer.Connected()
 }
 
+// ComponentStatus describes the connection status of an input or output of a
+// stream.
+type ComponentStatus struct {
+	Path            string `json:"path"`
+	Type            string `json:"type,omitempty"`
+	Connected       bool   `json:"connected"`
+	LastError       string `json:"last_error,omitempty"`
+	SinceLastError  string `json:"since_last_error,omitempty"`
+	SinceLastActive string `json:"since_last_active,omitempty"`
+
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	Backoff             string `json:"backoff,omitempty"`
+	CircuitOpen         bool   `json:"circuit_open"`
+}
+
+// ReadinessStatus describes the connection status of each input and output of
+// a stream.
+type ReadinessStatus struct {
+	Ready   bool              `json:"ready"`
+	Inputs  []ComponentStatus `json:"inputs"`
+	Outputs []ComponentStatus `json:"outputs"`
+}
+
+func newComponentStatuses(statuses []types.ConnectionStatus) []ComponentStatus {
+	sinceStr := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return time.Since(t).Round(time.Millisecond).String()
+	}
+
+	components := make([]ComponentStatus, 0, len(statuses))
+	for _, s := range statuses {
+		c := ComponentStatus{
+			Path:            s.Path,
+			Type:            s.Type,
+			Connected:       s.Connected,
+			SinceLastActive: sinceStr(s.LastActive),
+
+			ConsecutiveFailures: s.ConsecutiveFailures,
+			CircuitOpen:         s.Backoff > 0,
+		}
+		if s.Backoff > 0 {
+			c.Backoff = s.Backoff.Round(time.Millisecond).String()
+		}
+		if s.LastError != nil {
+			c.LastError = s.LastError.Error()
+			c.SinceLastError = sinceStr(s.LastErrorAt)
+		}
+		components = append(components, c)
+	}
+	return components
+}
+
+// ReadinessStatus returns a detailed description of the connection status of
+// each input and output of the stream, including the last error they
+// encountered and how long ago they last successfully read or wrote data.
+func (t *Type) ReadinessStatus() ReadinessStatus {
+	return ReadinessStatus{
+		Ready:   t.IsReady(),
+		Inputs:  newComponentStatuses(types.ConnectionStatusesOf("input", t.inputLayer)),
+		Outputs: newComponentStatuses(types.ConnectionStatusesOf("output", t.outputLayer)),
+	}
+}
+
 func (t *Type) start() (err error) {
+	inputStats := metrics.Namespaced(t.stats, "input")
+	bufferStats := metrics.Namespaced(t.stats, "buffer")
+	pipelineStats := metrics.Namespaced(t.stats, "pipeline")
+	outputStats := metrics.Namespaced(t.stats, "output")
+
+	t.conf = applyLimits(t.conf, t.logger)
+
 	// Constructors
+	if t.conf.SLO.Resource != "" {
+		if t.slo, err = newSLOMonitor(
+			t.id, t.conf.SLO, t.manager,
+			t.logger.NewModule(".slo"), t.stats,
+		); err != nil {
+			return
+		}
+	}
 	if t.inputLayer, err = input.New(
 		t.conf.Input, t.manager,
-		t.logger.NewModule(".input"), metrics.Namespaced(t.stats, "input"),
+		t.logger.NewModule(".input"), inputStats,
 	); err != nil {
 		return
 	}
 	if t.conf.Buffer.Type != buffer.TypeNone {
 		if t.bufferLayer, err = buffer.New(
 			t.conf.Buffer, t.manager,
-			t.logger.NewModule(".buffer"), metrics.Namespaced(t.stats, "buffer"),
+			t.logger.NewModule(".buffer"), bufferStats,
 		); err != nil {
 			return
 		}
 	}
 	if tLen := len(t.complementaryProcs) + len(t.conf.Pipeline.Processors); tLen > 0 {
+		pipeConf, procCtors := t.conf.Pipeline, t.complementaryProcs
+		if t.conf.Limits.Isolate {
+			if t.onFailure != nil {
+				procCtors = t.isolatedProcessors(pipelineStats)
+				pipeConf.Processors = nil
+			} else {
+				t.logger.Warnln("Stream isolation is only supported in streams mode")
+			}
+		}
 		if t.pipelineLayer, err = pipeline.New(
-			t.conf.Pipeline, t.manager,
-			t.logger.NewModule(".pipeline"), metrics.Namespaced(t.stats, "pipeline"),
-			t.complementaryProcs...,
+			pipeConf, t.manager,
+			t.logger.NewModule(".pipeline"), pipelineStats,
+			procCtors...,
 		); err != nil {
 			return
 		}
 	}
 	if t.outputLayer, err = output.New(
 		t.conf.Output, t.manager,
-		t.logger.NewModule(".output"), metrics.Namespaced(t.stats, "output"),
+		t.logger.NewModule(".output"), outputStats,
 	); err != nil {
 		return
 	}
@@ -169,18 +339,45 @@ func (t *Type) start() (err error) {
 	var nextTranChan <-chan types.Transaction
 
 	nextTranChan = t.inputLayer.TransactionChan()
+	if t.guard != nil {
+		nextTranChan = throttleTransactions(nextTranChan, func() <-chan struct{} {
+			return t.guard.Resumed(t.id)
+		}, t.throttleClose)
+	}
+	if t.conf.Limits.Rate.Count > 0 || t.conf.Limits.MaxBufferedBytes > 0 {
+		var rate costLimiter
+		if t.conf.Limits.Rate.Count > 0 {
+			if rate, err = newLimitsRate(t.conf.Limits.Rate); err != nil {
+				return
+			}
+		}
+		nextTranChan = limitTransactions(
+			nextTranChan, rate, int64(t.conf.Limits.MaxBufferedBytes),
+			t.stats, t.throttleClose, t.relayClose,
+		)
+	}
+	if t.slo != nil {
+		nextTranChan = t.slo.observe(nextTranChan, t.relayClose)
+	}
+	producerStats, pending := inputStats, &t.pendingInput
 	if t.bufferLayer != nil {
+		nextTranChan = t.relayLayer(nextTranChan, producerStats, bufferStats, pending)
 		if err = t.bufferLayer.Consume(nextTranChan); err != nil {
 			return
 		}
 		nextTranChan = t.bufferLayer.TransactionChan()
+		producerStats, pending = bufferStats, &t.pendingBuffered
 	}
 	if t.pipelineLayer != nil {
+		nextTranChan = t.relayLayer(nextTranChan, producerStats, pipelineStats, pending)
+		pending = nil
 		if err = t.pipelineLayer.Consume(nextTranChan); err != nil {
 			return
 		}
 		nextTranChan = t.pipelineLayer.TransactionChan()
+		producerStats = pipelineStats
 	}
+	nextTranChan = t.relayLayer(nextTranChan, producerStats, outputStats, pending)
 	if err = t.outputLayer.Consume(nextTranChan); err != nil {
 		return
 	}
@@ -198,22 +395,49 @@ func (t *Type) start() (err error) {
 }
 
 // stopGracefully attempts to close the stream in the most graceful way by only
-// closing the input layer and waiting for all other layers to terminate by
-// proxy. This should guarantee that all in-flight and buffered data is resolved
-// before shutting down.
+// closing the input layer and waiting for in flight messages to drain through
+// the remaining layers, bounded by the drain timeout, before closing the
+// pipeline and output layers. This should guarantee that all in-flight and
+// buffered data is resolved before shutting down.
 func (t *Type) stopGracefully(timeout time.Duration) (err error) {
-	t.inputLayer.CloseAsync()
 	started := time.Now()
+	t.enterShutdownPhase(ShutdownPhaseInputs, started)
+	t.inputLayer.CloseAsync()
 	if err = t.inputLayer.WaitForClose(timeout); err != nil {
 		return
 	}
 
-	var remaining time.Duration
+	t.enterShutdownPhase(ShutdownPhaseDrain, started)
+	drainStarted := time.Now()
+	drainTimeout := timeout - time.Since(started)
+	if t.drainTimeout > 0 && t.drainTimeout < drainTimeout {
+		drainTimeout = t.drainTimeout
+	}
 
 	// If we have a buffer then wait right here. We want to try and allow the
 	// buffer to empty out before prompting the other layers to shut down.
+	drained := true
 	if t.bufferLayer != nil {
 		t.bufferLayer.StopConsuming()
+		if err = t.bufferLayer.WaitForClose(drainTimeout); err != nil {
+			if err != types.ErrTimeout {
+				return
+			}
+			drained = false
+			t.bufferLayer.CloseAsync()
+		}
+	}
+	if drained {
+		drained = t.waitForDrain(drainTimeout - time.Since(drainStarted))
+	}
+	if !drained {
+		t.logger.Warnf("Drain timeout elapsed with %v messages still in flight\n", t.inFlight())
+	}
+
+	// After this point we can start closing the remaining components.
+	t.enterShutdownPhase(ShutdownPhaseOutputs, started)
+	var remaining time.Duration
+	if t.bufferLayer != nil && !drained {
 		remaining = timeout - time.Since(started)
 		if remaining < 0 {
 			return types.ErrTimeout
@@ -223,7 +447,6 @@ func (t *Type) stopGracefully(timeout time.Duration) (err error) {
 		}
 	}
 
-	// After this point we can start closing the remaining components.
 	if t.pipelineLayer != nil {
 		t.pipelineLayer.CloseAsync()
 		remaining = timeout - time.Since(started)
@@ -349,9 +572,20 @@ func (t *Type) stopUnordered(timeout time.Duration) (err error) {
 // Initially the attempt is graceful, but as the timeout draws close the attempt
 // becomes progressively less graceful.
 func (t *Type) Stop(timeout time.Duration) error {
+	event.Emit(event.TypeShutdownStarted, "", nil)
+	t.closeOnce.Do(func() {
+		close(t.throttleClose)
+	})
+
 	tOutUnordered := timeout / 4
 	tOutGraceful := timeout - tOutUnordered
 
+	started := time.Now()
+	defer t.reportShutdown(started)
+	defer t.relayCloseOnce.Do(func() {
+		close(t.relayClose)
+	})
+
 	err := t.stopGracefully(tOutGraceful)
 	if err == nil {
 		return nil
@@ -380,4 +614,22 @@ func (t *Type) Stop(timeout time.Duration) error {
 	return err
 }
 
+// reportShutdown logs the number of messages dropped during shutdown.
+func (t *Type) reportShutdown(started time.Time) {
+	if t.slo != nil {
+		t.slo.CloseAsync()
+	}
+	report := t.enterShutdownPhase(ShutdownPhaseDone, started)
+	if !t.countInFlight() {
+		t.logger.Infof("Stream stopped after %v\n", report.Elapsed)
+	} else if report.InFlight > 0 {
+		t.logger.Warnf(
+			"Stream stopped after %v with %v messages dropped before being acknowledged\n",
+			report.Elapsed, report.InFlight,
+		)
+	} else {
+		t.logger.Infof("Stream stopped after %v with no messages dropped\n", report.Elapsed)
+	}
+}
+
 //------------------------------------------------------------------------------
@@ -181,3 +181,58 @@ func TestTypeCloseUnordered(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTypeShutdownPhases(t *testing.T) {
+	conf := NewConfig()
+	conf.Input.Type = input.TypeNanomsg
+	conf.Output.Type = output.TypeNanomsg
+	conf.Pipeline.Processors = []processor.Config{
+		processor.NewConfig(),
+	}
+
+	var reports []ShutdownReport
+	strm, err := New(
+		conf,
+		OptSetDrainTimeout(time.Millisecond*100),
+		OptOnShutdownPhase(func(r ShutdownReport) {
+			reports = append(reports, r)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := time.Millisecond*100, strm.drainTimeout; exp != act {
+		t.Errorf("Wrong drain timeout: %v != %v", act, exp)
+	}
+
+	if err = strm.Stop(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []ShutdownPhase{
+		ShutdownPhaseInputs,
+		ShutdownPhaseDrain,
+		ShutdownPhaseOutputs,
+		ShutdownPhaseDone,
+	}
+	if len(reports) != len(exp) {
+		t.Fatalf("Wrong count of shutdown phases: %v != %v", len(reports), len(exp))
+	}
+	for i, r := range reports {
+		if r.Phase != exp[i] {
+			t.Errorf("Wrong shutdown phase at index %v: %v != %v", i, r.Phase, exp[i])
+		}
+		if r.InFlight != 0 {
+			t.Errorf("Unexpected in flight messages at phase %v: %v", r.Phase, r.InFlight)
+		}
+	}
+}
+
+func TestTypeStreamID(t *testing.T) {
+	strm := &Type{}
+	OptSetStreamID("foo")(strm)
+	OptSetLoadShedding(nil, "")(strm)
+	if exp, act := "foo", strm.id; exp != act {
+		t.Errorf("Wrong stream ID: %v != %v", act, exp)
+	}
+}
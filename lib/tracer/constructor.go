@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
 	"github.com/Jeffail/benthos/v3/lib/util/config"
 	yaml "gopkg.in/yaml.v3"
 )
@@ -41,8 +42,9 @@ var Constructors = map[string]TypeSpec{}
 
 // String constants representing each tracer type.
 const (
-	TypeJaeger = "jaeger"
-	TypeNone   = "none"
+	TypeJaeger        = "jaeger"
+	TypeNone          = "none"
+	TypeOpenTelemetry = "open_telemetry"
 )
 
 //------------------------------------------------------------------------------
@@ -57,17 +59,19 @@ type Type interface {
 
 // Config is the all encompassing configuration struct for all tracer types.
 type Config struct {
-	Type   string       `json:"type" yaml:"type"`
-	Jaeger JaegerConfig `json:"jaeger" yaml:"jaeger"`
-	None   struct{}     `json:"none" yaml:"none"`
+	Type          string              `json:"type" yaml:"type"`
+	Jaeger        JaegerConfig        `json:"jaeger" yaml:"jaeger"`
+	None          struct{}            `json:"none" yaml:"none"`
+	OpenTelemetry OpenTelemetryConfig `json:"open_telemetry" yaml:"open_telemetry"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
 func NewConfig() Config {
 	return Config{
-		Type:   TypeNone,
-		Jaeger: NewJaegerConfig(),
-		None:   struct{}{},
+		Type:          TypeNone,
+		Jaeger:        NewJaegerConfig(),
+		None:          struct{}{},
+		OpenTelemetry: NewOpenTelemetryConfig(),
 	}
 }
 
@@ -211,6 +215,19 @@ func Descriptions() string {
 	return buf.String()
 }
 
+// OptSetLogger sets the logging output to be used by tracers that support it.
+func OptSetLogger(l log.Modular) func(Type) {
+	return func(t Type) {
+		if lt, ok := t.(interface {
+			SetLogger(log.Modular)
+		}); ok {
+			lt.SetLogger(l)
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // New creates a tracer type based on a configuration.
 func New(conf Config, opts ...func(Type)) (Type, error) {
 	if c, ok := Constructors[conf.Type]; ok {
@@ -0,0 +1,519 @@
+package tracer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/internal/docs"
+	"github.com/Jeffail/benthos/v3/lib/log"
+	btls "github.com/Jeffail/benthos/v3/lib/util/tls"
+	"github.com/opentracing/opentracing-go"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	Constructors[TypeOpenTelemetry] = TypeSpec{
+		constructor: NewOpenTelemetry,
+		Status:      docs.StatusExperimental,
+		Version:     "3.39.0",
+		Summary: `
+Send spans to an [OpenTelemetry](https://opentelemetry.io/) collector, or any
+other service that supports the OTLP protocol, over gRPC or HTTP.`,
+		Description: `
+Spans are queued in memory and exported in batches, either once a batch reaches
+` + "`max_batch_size`" + ` spans or each ` + "`flush_interval`" + `,
+whichever happens first. When the queue is full new spans are dropped rather
+than blocking the pipeline.
+
+Span contexts are propagated using the
+[W3C Trace Context](https://www.w3.org/TR/trace-context/) format, and
+[B3](https://github.com/openzipkin/b3-propagation) headers are also accepted
+when extracting a span context from an incoming message. Spans of inputs and
+outputs include the attributes ` + "`benthos.component`" + `,
+` + "`benthos.component.type`" + ` and ` + "`benthos.batch.size`" + `.`,
+		FieldSpecs: docs.FieldSpecs{
+			docs.FieldCommon("protocol", "The protocol to export spans with.").HasOptions("grpc", "http"),
+			docs.FieldCommon(
+				"endpoint", "The endpoint of the collector, which is a `host:port` address when using gRPC or a URL when using HTTP.",
+				"localhost:4317", "https://api.honeycomb.io/v1/traces",
+			),
+			btls.FieldSpec(),
+			docs.FieldAdvanced("headers", "A map of headers, or gRPC metadata, to add to each export request.", map[string]string{
+				"x-honeycomb-team": "YOUR_API_KEY",
+			}),
+			docs.FieldCommon("service_name", "A name to provide for this service."),
+			docs.FieldAdvanced("resource_attributes", "A map of attributes that describe the resource producing spans.", map[string]string{
+				"deployment.environment": "production",
+			}),
+			docs.FieldCommon("sampler_type", "The sampler to use for new traces.").HasAnnotatedOptions(
+				"always_on", "Sample all traces.",
+				"always_off", "Sample no traces.",
+				"trace_id_ratio", "Sample a ratio of traces, determined by `sampler_ratio`.",
+				"parent_based", "Follow the sampling decision of the parent span when there is one, and otherwise sample a ratio of traces determined by `sampler_ratio`.",
+			),
+			docs.FieldCommon("sampler_ratio", "The ratio of traces to sample, between 0 and 1, when using a ratio based sampler."),
+			docs.FieldAdvanced("flush_interval", "The maximum period of time to wait before exporting queued spans."),
+			docs.FieldAdvanced("max_batch_size", "The maximum number of spans to export in a single request."),
+			docs.FieldAdvanced("max_queue_size", "The maximum number of spans to queue before new spans are dropped."),
+			docs.FieldAdvanced("timeout", "The maximum period to wait for an export request to complete."),
+		},
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetryConfig is config for the OpenTelemetry tracer type.
+type OpenTelemetryConfig struct {
+	Protocol           string            `json:"protocol" yaml:"protocol"`
+	Endpoint           string            `json:"endpoint" yaml:"endpoint"`
+	TLS                btls.Config       `json:"tls" yaml:"tls"`
+	Headers            map[string]string `json:"headers" yaml:"headers"`
+	ServiceName        string            `json:"service_name" yaml:"service_name"`
+	ResourceAttributes map[string]string `json:"resource_attributes" yaml:"resource_attributes"`
+	SamplerType        string            `json:"sampler_type" yaml:"sampler_type"`
+	SamplerRatio       float64           `json:"sampler_ratio" yaml:"sampler_ratio"`
+	FlushInterval      string            `json:"flush_interval" yaml:"flush_interval"`
+	MaxBatchSize       int               `json:"max_batch_size" yaml:"max_batch_size"`
+	MaxQueueSize       int               `json:"max_queue_size" yaml:"max_queue_size"`
+	Timeout            string            `json:"timeout" yaml:"timeout"`
+}
+
+// NewOpenTelemetryConfig creates an OpenTelemetryConfig struct with default
+// values.
+func NewOpenTelemetryConfig() OpenTelemetryConfig {
+	return OpenTelemetryConfig{
+		Protocol:           "grpc",
+		Endpoint:           "localhost:4317",
+		TLS:                btls.NewConfig(),
+		Headers:            map[string]string{},
+		ServiceName:        "benthos",
+		ResourceAttributes: map[string]string{},
+		SamplerType:        "parent_based",
+		SamplerRatio:       1.0,
+		FlushInterval:      "5s",
+		MaxBatchSize:       512,
+		MaxQueueSize:       2048,
+		Timeout:            "10s",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// OpenTelemetry is a tracer with the capability to export spans to an OTLP
+// collector.
+type OpenTelemetry struct {
+	conf   OpenTelemetryConfig
+	tracer *otelTracer
+	log    log.Modular
+
+	timeout  time.Duration
+	interval time.Duration
+	resource *resourcepb.Resource
+
+	grpcConn   *grpc.ClientConn
+	grpcClient coltracepb.TraceServiceClient
+	httpClient *http.Client
+
+	queue chan *tracepb.Span
+
+	closeOnce  sync.Once
+	closeChan  chan struct{}
+	closedChan chan struct{}
+}
+
+// NewOpenTelemetry creates and returns a new OpenTelemetry tracer, and sets it
+// as the global tracer.
+func NewOpenTelemetry(config Config, opts ...func(Type)) (Type, error) {
+	conf := config.OpenTelemetry
+	o := &OpenTelemetry{
+		conf:       conf,
+		log:        log.Noop(),
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var err error
+	if o.interval, err = time.ParseDuration(conf.FlushInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse flush interval: %v", err)
+	}
+	if o.interval <= 0 {
+		return nil, errors.New("flush interval must be greater than zero")
+	}
+	if o.timeout, err = time.ParseDuration(conf.Timeout); err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %v", err)
+	}
+	if conf.MaxBatchSize <= 0 {
+		return nil, errors.New("max batch size must be greater than zero")
+	}
+	if conf.MaxQueueSize < conf.MaxBatchSize {
+		return nil, errors.New("max queue size must not be less than max batch size")
+	}
+
+	sampler, err := newOTelSampler(conf.SamplerType, conf.SamplerRatio)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]string{}
+	for k, v := range conf.ResourceAttributes {
+		attrs[k] = v
+	}
+	if conf.ServiceName != "" {
+		attrs["service.name"] = conf.ServiceName
+	}
+	attrKeys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		attrKeys = append(attrKeys, k)
+	}
+	sort.Strings(attrKeys)
+	o.resource = &resourcepb.Resource{}
+	for _, k := range attrKeys {
+		o.resource.Attributes = append(o.resource.Attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: attrs[k]}},
+		})
+	}
+
+	switch conf.Protocol {
+	case "grpc":
+		var dialOpts []grpc.DialOption
+		if conf.TLS.Enabled {
+			tlsConf, err := conf.TLS.Get()
+			if err != nil {
+				return nil, err
+			}
+			dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConf)))
+		} else {
+			dialOpts = append(dialOpts, grpc.WithInsecure())
+		}
+		if o.grpcConn, err = grpc.Dial(conf.Endpoint, dialOpts...); err != nil {
+			return nil, fmt.Errorf("failed to dial collector: %v", err)
+		}
+		o.grpcClient = coltracepb.NewTraceServiceClient(o.grpcConn)
+	case "http":
+		o.httpClient = &http.Client{
+			Timeout: o.timeout,
+		}
+		if conf.TLS.Enabled {
+			tlsConf, err := conf.TLS.Get()
+			if err != nil {
+				return nil, err
+			}
+			o.httpClient.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	default:
+		return nil, fmt.Errorf("protocol not recognised: %v", conf.Protocol)
+	}
+
+	o.queue = make(chan *tracepb.Span, conf.MaxQueueSize)
+	o.tracer = &otelTracer{
+		sampler: sampler,
+		export:  o.enqueue,
+	}
+
+	go o.loop()
+
+	opentracing.SetGlobalTracer(o.tracer)
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+type otelSampler func(traceID [16]byte, parent *otelSpanContext) bool
+
+func newOTelSampler(samplerType string, ratio float64) (otelSampler, error) {
+	if ratio < 0 || ratio > 1 {
+		return nil, errors.New("sampler ratio must be between 0 and 1")
+	}
+
+	// Sample trace IDs whose lower 8 bytes fall below the ratio, so that every
+	// service sampling by ratio makes the same decision for a trace.
+	bound := uint64(ratio * (1 << 63))
+	byRatio := func(traceID [16]byte) bool {
+		return binary.BigEndian.Uint64(traceID[8:])>>1 < bound
+	}
+
+	switch samplerType {
+	case "always_on":
+		return func([16]byte, *otelSpanContext) bool { return true }, nil
+	case "always_off":
+		return func([16]byte, *otelSpanContext) bool { return false }, nil
+	case "trace_id_ratio":
+		return func(traceID [16]byte, _ *otelSpanContext) bool {
+			return ratio >= 1 || byRatio(traceID)
+		}, nil
+	case "parent_based":
+		return func(traceID [16]byte, parent *otelSpanContext) bool {
+			if parent != nil {
+				return parent.sampled
+			}
+			return ratio >= 1 || byRatio(traceID)
+		}, nil
+	}
+	return nil, fmt.Errorf("unrecognised sampler type: %v", samplerType)
+}
+
+//------------------------------------------------------------------------------
+
+// otelTracer implements opentracing.Tracer.
+type otelTracer struct {
+	sampler otelSampler
+	export  func(*tracepb.Span)
+}
+
+// StartSpan creates a new span, which is a child of the first referenced span
+// context and linked to any others.
+func (t *otelTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+
+	span := &otelSpan{
+		tracer:    t,
+		name:      operationName,
+		startTime: sso.StartTime,
+		tags:      make(map[string]interface{}, len(sso.Tags)),
+	}
+	if span.startTime.IsZero() {
+		span.startTime = time.Now()
+	}
+	for k, v := range sso.Tags {
+		span.tags[k] = v
+	}
+
+	var parent *otelSpanContext
+	for _, ref := range sso.References {
+		refCtx, ok := ref.ReferencedContext.(otelSpanContext)
+		if !ok {
+			continue
+		}
+		if parent == nil {
+			parent = &refCtx
+		} else {
+			span.links = append(span.links, refCtx)
+		}
+	}
+
+	if parent != nil {
+		span.ctx.traceID = parent.traceID
+		span.ctx.baggage = parent.baggage
+		span.parentID = append([]byte{}, parent.spanID[:]...)
+	} else {
+		randomID(span.ctx.traceID[:])
+	}
+	randomID(span.ctx.spanID[:])
+	span.ctx.sampled = t.sampler(span.ctx.traceID, parent)
+	return span
+}
+
+// Inject writes a span context into a carrier using the W3C trace context
+// format.
+func (t *otelTracer) Inject(sm opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	sc, ok := sm.(otelSpanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return opentracing.ErrUnsupportedFormat
+	}
+	writer, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	writer.Set(otelTraceParentKey, sc.traceParent())
+	return nil
+}
+
+// Extract reads a span context from a carrier using the W3C trace context
+// format, falling back to B3 headers when a traceparent is not present.
+func (t *otelTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	reader, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+
+	var traceParent, b3, b3TraceID, b3SpanID, b3Sampled string
+	if err := reader.ForeachKey(func(k, v string) error {
+		switch strings.ToLower(k) {
+		case otelTraceParentKey:
+			traceParent = v
+		case otelB3Key:
+			b3 = v
+		case otelB3TraceIDKey:
+			b3TraceID = v
+		case otelB3SpanIDKey:
+			b3SpanID = v
+		case otelB3SampledKey:
+			b3Sampled = v
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var sc otelSpanContext
+	var err error
+	switch {
+	case traceParent != "":
+		sc, err = parseTraceParent(traceParent)
+	case b3 != "":
+		sc, err = parseB3Single(b3)
+	case b3TraceID != "" || b3SpanID != "":
+		sc, err = parseB3(b3TraceID, b3SpanID, b3Sampled)
+	default:
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *OpenTelemetry) enqueue(span *tracepb.Span) {
+	select {
+	case o.queue <- span:
+	default:
+		// Drop spans rather than blocking the pipeline.
+	}
+}
+
+func (o *OpenTelemetry) exportBatch(spans []*tracepb.Span) error {
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource: o.resource,
+			InstrumentationLibrarySpans: []*tracepb.InstrumentationLibrarySpans{{
+				InstrumentationLibrary: &commonpb.InstrumentationLibrary{
+					Name: "benthos",
+				},
+				Spans: spans,
+			}},
+		}},
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), o.timeout)
+	defer done()
+
+	if o.grpcClient != nil {
+		if len(o.conf.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.conf.Headers))
+		}
+		_, err := o.grpcClient.Export(ctx, req)
+		return err
+	}
+
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hReq, err := http.NewRequestWithContext(ctx, "POST", o.conf.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range o.conf.Headers {
+		hReq.Header.Set(k, v)
+	}
+
+	res, err := o.httpClient.Do(hReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, resBody)
+	}
+	return nil
+}
+
+func (o *OpenTelemetry) loop() {
+	defer close(o.closedChan)
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	batch := make([]*tracepb.Span, 0, o.conf.MaxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := o.exportBatch(batch); err != nil {
+			o.log.Errorf("Failed to export %v spans: %v\n", len(batch), err)
+		}
+		batch = make([]*tracepb.Span, 0, o.conf.MaxBatchSize)
+	}
+
+	for {
+		select {
+		case span := <-o.queue:
+			if batch = append(batch, span); len(batch) >= o.conf.MaxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-o.closeChan:
+			for {
+				select {
+				case span := <-o.queue:
+					if batch = append(batch, span); len(batch) >= o.conf.MaxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// SetLogger sets the logger used to print export errors.
+func (o *OpenTelemetry) SetLogger(log log.Modular) {
+	o.log = log
+}
+
+// Close exports any queued spans and stops the tracer.
+func (o *OpenTelemetry) Close() error {
+	o.closeOnce.Do(func() {
+		close(o.closeChan)
+	})
+	<-o.closedChan
+	if o.grpcConn != nil {
+		return o.grpcConn.Close()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
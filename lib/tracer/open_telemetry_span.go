@@ -0,0 +1,391 @@
+package tracer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+//------------------------------------------------------------------------------
+
+// The keys of headers used to propagate spans. W3C trace context is used for
+// injection, B3 headers are also accepted during extraction.
+const (
+	otelTraceParentKey = "traceparent"
+	otelB3Key          = "b3"
+	otelB3TraceIDKey   = "x-b3-traceid"
+	otelB3SpanIDKey    = "x-b3-spanid"
+	otelB3SampledKey   = "x-b3-sampled"
+)
+
+// otelSpanContext identifies a span and implements opentracing.SpanContext.
+type otelSpanContext struct {
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+	baggage map[string]string
+}
+
+// ForeachBaggageItem calls a handler for each baggage item of the context.
+func (c otelSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+func (c otelSpanContext) withBaggageItem(key, value string) otelSpanContext {
+	baggage := make(map[string]string, len(c.baggage)+1)
+	for k, v := range c.baggage {
+		baggage[k] = v
+	}
+	baggage[key] = value
+	c.baggage = baggage
+	return c
+}
+
+// traceParent encodes the context as a W3C trace context header value.
+func (c otelSpanContext) traceParent() string {
+	flags := "00"
+	if c.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%v-%v-%v", hex.EncodeToString(c.traceID[:]), hex.EncodeToString(c.spanID[:]), flags)
+}
+
+// parseTraceParent decodes a W3C trace context header value.
+func parseTraceParent(v string) (c otelSpanContext, err error) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if _, err = hex.Decode(c.traceID[:], []byte(parts[1])); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if _, err = hex.Decode(c.spanID[:], []byte(parts[2])); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	var flags [1]byte
+	if _, err = hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if c.traceID == ([16]byte{}) || c.spanID == ([8]byte{}) {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	c.sampled = flags[0]&1 == 1
+	return c, nil
+}
+
+// parseB3Single decodes a single B3 header value of the form
+// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}.
+func parseB3Single(v string) (otelSpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 2 {
+		return otelSpanContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	sampled := ""
+	if len(parts) > 2 {
+		sampled = parts[2]
+	}
+	return parseB3(parts[0], parts[1], sampled)
+}
+
+// parseB3 decodes B3 trace and span IDs, where 64 bit trace IDs are left padded
+// to 128 bits.
+func parseB3(traceID, spanID, sampled string) (c otelSpanContext, err error) {
+	traceID, spanID = strings.TrimSpace(traceID), strings.TrimSpace(spanID)
+	if len(traceID) == 16 {
+		traceID = "0000000000000000" + traceID
+	}
+	if len(traceID) != 32 || len(spanID) != 16 {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if _, err = hex.Decode(c.traceID[:], []byte(traceID)); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if _, err = hex.Decode(c.spanID[:], []byte(spanID)); err != nil {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if c.traceID == ([16]byte{}) || c.spanID == ([8]byte{}) {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	switch strings.TrimSpace(sampled) {
+	case "1", "d", "true":
+		c.sampled = true
+	}
+	return c, nil
+}
+
+func randomID(b []byte) {
+	for {
+		if _, err := rand.Read(b); err != nil {
+			// Fall back to a time based ID, which is unlikely to collide
+			// within a single trace.
+			binary.BigEndian.PutUint64(b[len(b)-8:], uint64(time.Now().UnixNano()))
+		}
+		for _, v := range b {
+			if v != 0 {
+				return
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// otelSpan implements opentracing.Span and is exported as an OTLP span once
+// finished.
+type otelSpan struct {
+	tracer *otelTracer
+	links  []otelSpanContext
+
+	mut       sync.Mutex
+	ctx       otelSpanContext
+	parentID  []byte
+	name      string
+	startTime time.Time
+	tags      map[string]interface{}
+	logs      []opentracing.LogRecord
+	finished  bool
+}
+
+// Finish sets the end time of the span and exports it.
+func (s *otelSpan) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions sets the end time of the span and exports it.
+func (s *otelSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	finishTime := opts.FinishTime
+	if finishTime.IsZero() {
+		finishTime = time.Now()
+	}
+
+	s.mut.Lock()
+	if s.finished {
+		s.mut.Unlock()
+		return
+	}
+	s.finished = true
+	s.logs = append(s.logs, opts.LogRecords...)
+	s.mut.Unlock()
+
+	if s.ctx.sampled {
+		s.tracer.export(s.toProto(finishTime))
+	}
+}
+
+// Context returns the context of the span.
+func (s *otelSpan) Context() opentracing.SpanContext {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.ctx
+}
+
+// SetOperationName sets the name of the span.
+func (s *otelSpan) SetOperationName(operationName string) opentracing.Span {
+	s.mut.Lock()
+	s.name = operationName
+	s.mut.Unlock()
+	return s
+}
+
+// SetTag adds a tag to the span, which is exported as an attribute.
+func (s *otelSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.mut.Lock()
+	s.tags[key] = value
+	s.mut.Unlock()
+	return s
+}
+
+// LogFields adds a log record to the span, which is exported as an event.
+func (s *otelSpan) LogFields(fields ...log.Field) {
+	s.mut.Lock()
+	s.logs = append(s.logs, opentracing.LogRecord{
+		Timestamp: time.Now(),
+		Fields:    fields,
+	})
+	s.mut.Unlock()
+}
+
+// LogKV adds a log record to the span from alternating keys and values.
+func (s *otelSpan) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+// SetBaggageItem sets a baggage item that is propagated to child spans.
+func (s *otelSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mut.Lock()
+	s.ctx = s.ctx.withBaggageItem(restrictedKey, value)
+	s.mut.Unlock()
+	return s
+}
+
+// BaggageItem returns the value of a baggage item.
+func (s *otelSpan) BaggageItem(restrictedKey string) string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.ctx.baggage[restrictedKey]
+}
+
+// Tracer returns the tracer that created the span.
+func (s *otelSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent is deprecated.
+func (s *otelSpan) LogEvent(event string) {
+	s.LogFields(log.String("event", event))
+}
+
+// LogEventWithPayload is deprecated.
+func (s *otelSpan) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String("event", event), log.Object("payload", payload))
+}
+
+// Log is deprecated.
+func (s *otelSpan) Log(data opentracing.LogData) {
+	s.mut.Lock()
+	s.logs = append(s.logs, data.ToLogRecord())
+	s.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+func otelAnyValue(v interface{}) *commonpb.AnyValue {
+	switch t := v.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: t}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: t}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: t}}
+	case uint32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case uint16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(t)}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(t)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: t}}
+	case error:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: t.Error()}}
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%v", v)}}
+}
+
+type otelFieldEncoder struct {
+	name  string
+	attrs []*commonpb.KeyValue
+}
+
+func (e *otelFieldEncoder) emit(key string, value interface{}) {
+	if key == "event" {
+		if s, ok := value.(string); ok {
+			e.name = s
+			return
+		}
+	}
+	e.attrs = append(e.attrs, &commonpb.KeyValue{Key: key, Value: otelAnyValue(value)})
+}
+
+func (e *otelFieldEncoder) EmitString(key, value string)             { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitBool(key string, value bool)          { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitInt(key string, value int)            { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitInt32(key string, value int32)        { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitInt64(key string, value int64)        { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitUint32(key string, value uint32)      { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitUint64(key string, value uint64)      { e.emit(key, int64(value)) }
+func (e *otelFieldEncoder) EmitFloat32(key string, value float32)    { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitFloat64(key string, value float64)    { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitObject(key string, value interface{}) { e.emit(key, value) }
+func (e *otelFieldEncoder) EmitLazyLogger(value log.LazyLogger)      { value(e) }
+
+var otelSpanKinds = map[interface{}]tracepb.Span_SpanKind{
+	ext.SpanKindRPCClientEnum: tracepb.Span_SPAN_KIND_CLIENT,
+	ext.SpanKindRPCServerEnum: tracepb.Span_SPAN_KIND_SERVER,
+	ext.SpanKindProducerEnum:  tracepb.Span_SPAN_KIND_PRODUCER,
+	ext.SpanKindConsumerEnum:  tracepb.Span_SPAN_KIND_CONSUMER,
+	"client":                  tracepb.Span_SPAN_KIND_CLIENT,
+	"server":                  tracepb.Span_SPAN_KIND_SERVER,
+	"producer":                tracepb.Span_SPAN_KIND_PRODUCER,
+	"consumer":                tracepb.Span_SPAN_KIND_CONSUMER,
+}
+
+func (s *otelSpan) toProto(finishTime time.Time) *tracepb.Span {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	span := &tracepb.Span{
+		TraceId:           append([]byte{}, s.ctx.traceID[:]...),
+		SpanId:            append([]byte{}, s.ctx.spanID[:]...),
+		ParentSpanId:      s.parentID,
+		Name:              s.name,
+		Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: uint64(s.startTime.UnixNano()),
+		EndTimeUnixNano:   uint64(finishTime.UnixNano()),
+		Status:            &tracepb.Status{},
+	}
+
+	for k, v := range s.tags {
+		switch k {
+		case string(ext.SpanKind):
+			if kind, exists := otelSpanKinds[v]; exists {
+				span.Kind = kind
+			}
+			continue
+		case string(ext.Error):
+			if b, _ := v.(bool); b {
+				span.Status.Code = tracepb.Status_STATUS_CODE_ERROR
+			}
+			continue
+		}
+		span.Attributes = append(span.Attributes, &commonpb.KeyValue{Key: k, Value: otelAnyValue(v)})
+	}
+
+	for _, l := range s.logs {
+		enc := &otelFieldEncoder{name: "log"}
+		for _, f := range l.Fields {
+			f.Marshal(enc)
+		}
+		ts := l.Timestamp
+		if ts.IsZero() {
+			ts = finishTime
+		}
+		span.Events = append(span.Events, &tracepb.Span_Event{
+			TimeUnixNano: uint64(ts.UnixNano()),
+			Name:         enc.name,
+			Attributes:   enc.attrs,
+		})
+	}
+
+	for _, l := range s.links {
+		span.Links = append(span.Links, &tracepb.Span_Link{
+			TraceId: append([]byte{}, l.traceID[:]...),
+			SpanId:  append([]byte{}, l.spanID[:]...),
+		})
+	}
+	return span
+}
+
+//------------------------------------------------------------------------------
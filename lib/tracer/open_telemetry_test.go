@@ -0,0 +1,198 @@
+package tracer
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/message/tracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOpenTelemetryBadConfig(t *testing.T) {
+	for _, fn := range []func(c *OpenTelemetryConfig){
+		func(c *OpenTelemetryConfig) { c.Protocol = "smoke_signals" },
+		func(c *OpenTelemetryConfig) { c.SamplerType = "sometimes" },
+		func(c *OpenTelemetryConfig) { c.SamplerRatio = 2 },
+		func(c *OpenTelemetryConfig) { c.MaxBatchSize = 0 },
+		func(c *OpenTelemetryConfig) { c.MaxQueueSize = 1 },
+		func(c *OpenTelemetryConfig) { c.FlushInterval = "nope" },
+	} {
+		conf := NewConfig()
+		conf.Type = TypeOpenTelemetry
+		fn(&conf.OpenTelemetry)
+		_, err := New(conf)
+		assert.Error(t, err)
+	}
+}
+
+func TestOpenTelemetrySampler(t *testing.T) {
+	parentSampled := &otelSpanContext{sampled: true}
+	parentUnsampled := &otelSpanContext{sampled: false}
+
+	var traceID [16]byte
+
+	s, err := newOTelSampler("always_on", 0)
+	require.NoError(t, err)
+	assert.True(t, s(traceID, parentUnsampled))
+
+	s, err = newOTelSampler("always_off", 1)
+	require.NoError(t, err)
+	assert.False(t, s(traceID, parentSampled))
+
+	s, err = newOTelSampler("parent_based", 0)
+	require.NoError(t, err)
+	assert.True(t, s(traceID, parentSampled))
+	assert.False(t, s(traceID, parentUnsampled))
+	assert.False(t, s(traceID, nil))
+
+	s, err = newOTelSampler("trace_id_ratio", 0.5)
+	require.NoError(t, err)
+	traceID[8] = 0x10
+	assert.True(t, s(traceID, nil))
+	traceID[8] = 0xf0
+	assert.False(t, s(traceID, nil))
+}
+
+func TestOpenTelemetryPropagation(t *testing.T) {
+	tracer := &otelTracer{
+		sampler: func([16]byte, *otelSpanContext) bool { return true },
+		export:  func(*tracepb.Span) {},
+	}
+
+	span := tracer.StartSpan("foo")
+	carrier := opentracing.TextMapCarrier{}
+	require.NoError(t, tracer.Inject(span.Context(), opentracing.TextMap, carrier))
+	assert.Regexp(t, "^00-[0-9a-f]{32}-[0-9a-f]{16}-01$", carrier["traceparent"])
+
+	extracted, err := tracer.Extract(opentracing.TextMap, carrier)
+	require.NoError(t, err)
+	assert.Equal(t, span.Context().(otelSpanContext).traceID, extracted.(otelSpanContext).traceID)
+	assert.Equal(t, span.Context().(otelSpanContext).spanID, extracted.(otelSpanContext).spanID)
+
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{})
+	assert.Equal(t, opentracing.ErrSpanContextNotFound, err)
+
+	_, err = tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{"traceparent": "00-nope-nope-01"})
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+}
+
+func TestOpenTelemetryExtractB3(t *testing.T) {
+	tracer := &otelTracer{}
+
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.TextMapCarrier{
+		"b3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1-05e3ac9a4f6e3b90",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01", sc.(otelSpanContext).traceParent())
+
+	sc, err = tracer.Extract(opentracing.HTTPHeaders, opentracing.TextMapCarrier{
+		"X-B3-TraceId": "a3ce929d0e0e4736",
+		"X-B3-SpanId":  "00f067aa0ba902b7",
+		"X-B3-Sampled": "0",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-00", sc.(otelSpanContext).traceParent())
+
+	_, err = tracer.Extract(opentracing.HTTPHeaders, opentracing.TextMapCarrier{
+		"X-B3-TraceId": "a3ce929d0e0e4736",
+	})
+	assert.Equal(t, opentracing.ErrSpanContextCorrupted, err)
+}
+
+func TestOpenTelemetryTraceID(t *testing.T) {
+	tracer := &otelTracer{
+		sampler: func([16]byte, *otelSpanContext) bool { return true },
+		export:  func(*tracepb.Span) {},
+	}
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	msg := message.New([][]byte{[]byte("foo")})
+	assert.Equal(t, "", tracing.BatchTraceID(msg))
+
+	tracing.InitSpans("foo", msg)
+	sc := tracing.GetSpan(msg.Get(0)).Context().(otelSpanContext)
+	assert.Equal(t, hex.EncodeToString(sc.traceID[:]), tracing.BatchTraceID(msg))
+
+	tracer.sampler = func([16]byte, *otelSpanContext) bool { return false }
+	msg = message.New([][]byte{[]byte("foo")})
+	tracing.InitSpans("foo", msg)
+	assert.Equal(t, "", tracing.BatchTraceID(msg))
+}
+
+func TestOpenTelemetryHTTPExport(t *testing.T) {
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	var spansMut sync.Mutex
+	var spans []*tracepb.Span
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bar", r.Header.Get("foo"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := &coltracepb.ExportTraceServiceRequest{}
+		require.NoError(t, proto.Unmarshal(body, req))
+
+		spansMut.Lock()
+		for _, rs := range req.ResourceSpans {
+			assert.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+			for _, ils := range rs.InstrumentationLibrarySpans {
+				spans = append(spans, ils.Spans...)
+			}
+		}
+		spansMut.Unlock()
+	}))
+	defer srv.Close()
+
+	conf := NewConfig()
+	conf.Type = TypeOpenTelemetry
+	conf.OpenTelemetry.Protocol = "http"
+	conf.OpenTelemetry.Endpoint = srv.URL
+	conf.OpenTelemetry.Headers = map[string]string{"foo": "bar"}
+	conf.OpenTelemetry.FlushInterval = "1h"
+
+	tr, err := New(conf)
+	require.NoError(t, err)
+
+	parent := opentracing.StartSpan("parent", opentracing.Tag{Key: "span.kind", Value: "consumer"})
+	child := opentracing.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	child.SetTag("benthos.batch.size", 3)
+	child.SetTag("error", true)
+	child.LogKV("event", "failed", "reason", "nope")
+	child.Finish()
+	parent.Finish()
+
+	require.NoError(t, tr.Close())
+
+	spansMut.Lock()
+	defer spansMut.Unlock()
+	require.Len(t, spans, 2)
+
+	childSpan, parentSpan := spans[0], spans[1]
+	assert.Equal(t, "child", childSpan.Name)
+	assert.Equal(t, "parent", parentSpan.Name)
+	assert.Equal(t, parentSpan.TraceId, childSpan.TraceId)
+	assert.Equal(t, parentSpan.SpanId, childSpan.ParentSpanId)
+	assert.Equal(t, tracepb.Span_SPAN_KIND_CONSUMER, parentSpan.Kind)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, childSpan.Status.Code)
+
+	require.Len(t, childSpan.Attributes, 1)
+	assert.Equal(t, "benthos.batch.size", childSpan.Attributes[0].Key)
+	assert.Equal(t, int64(3), childSpan.Attributes[0].Value.GetIntValue())
+
+	require.Len(t, childSpan.Events, 1)
+	assert.Equal(t, "failed", childSpan.Events[0].Name)
+	assert.Equal(t, "reason", childSpan.Events[0].Attributes[0].Key)
+}
@@ -93,6 +93,7 @@ var (
 	ErrPluginNotFound    = errors.New("plugin not found")
 	ErrKeyAlreadyExists  = errors.New("key already exists")
 	ErrKeyNotFound       = errors.New("key does not exist")
+	ErrKeyValueMismatch  = errors.New("key value does not match")
 	ErrPipeNotFound      = errors.New("pipe was not found")
 )
 
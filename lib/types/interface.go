@@ -59,6 +59,53 @@ type CacheWithTTL interface {
 	Cache
 }
 
+// CacheMultiGetter is a cache that is able to obtain the values of multiple
+// keys in a single request.
+type CacheMultiGetter interface {
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys, keys that do not exist are omitted from the result. Returns an
+	// error if the command fails.
+	GetMulti(keys ...string) (map[string][]byte, error)
+
+	Cache
+}
+
+// CacheCompareAndSwapper is a cache that is able to atomically replace the
+// value of a key only when it matches an expected value.
+type CacheCompareAndSwapper interface {
+	// CompareAndSwap attempts to set the value of a key only if its current
+	// value matches old. Returns ErrKeyNotFound if the key does not exist,
+	// ErrKeyValueMismatch if the current value does not match, or an error if
+	// the command fails. A nil ttl results in the default TTL of the cache.
+	CompareAndSwap(key string, old, value []byte, ttl *time.Duration) error
+
+	Cache
+}
+
+// CacheIncrementer is a cache that is able to atomically increment integer
+// values.
+type CacheIncrementer interface {
+	// Increment atomically adds delta, which can be negative, to the integer
+	// value of a key and returns the result. Keys that do not exist are
+	// created with the value of delta and the given ttl, where a nil ttl
+	// results in the default TTL of the cache. Returns an error if the
+	// existing value is not an integer or if the command fails.
+	Increment(key string, delta int64, ttl *time.Duration) (int64, error)
+
+	Cache
+}
+
+// CacheToucher is a cache that is able to reset the expiry of a key without
+// modifying its value.
+type CacheToucher interface {
+	// Touch resets the TTL of a key, where a nil ttl results in the default
+	// TTL of the cache. Returns ErrKeyNotFound if the key does not exist, or
+	// an error if the command fails.
+	Touch(key string, ttl *time.Duration) error
+
+	Cache
+}
+
 //------------------------------------------------------------------------------
 
 // RateLimit is a strategy for limiting access to a shared resource, this
@@ -73,6 +120,34 @@ type RateLimit interface {
 	Closable
 }
 
+// RateLimitKeyed is a rate limit that is able to track independent limits for
+// any number of keys.
+type RateLimitKeyed interface {
+	// AccessKey accesses the rate limited resource for a given key. Returns a
+	// duration or an error if the rate limit check fails. The returned duration
+	// is either zero (meaning the resource may be accessed) or a reasonable
+	// length of time to wait before requesting again.
+	AccessKey(key string) (time.Duration, error)
+
+	RateLimit
+}
+
+// RateLimitWeighted is a rate limit where each access consumes a variable
+// amount of the limit.
+type RateLimitWeighted interface {
+	// AccessCost accesses the rate limited resource with a given cost, which
+	// consumes that many units of the limit. Returns a duration or an error
+	// if the rate limit check fails.
+	AccessCost(cost int) (time.Duration, error)
+
+	// AccessKeyCost accesses the rate limited resource for a given key with a
+	// given cost. Returns a duration or an error if the rate limit check
+	// fails.
+	AccessKeyCost(key string, cost int) (time.Duration, error)
+
+	RateLimit
+}
+
 //------------------------------------------------------------------------------
 
 // Condition reads a message, calculates a condition and returns a boolean.
@@ -0,0 +1,140 @@
+package types
+
+import (
+	"sync"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// ConnectionStatus describes the current state of the connection between an
+// input or output and its target.
+type ConnectionStatus struct {
+	// Path is the location of the component relative to the component that
+	// reported it, e.g. `broker.inputs.1`.
+	Path string
+
+	// Type is the type of the component, e.g. `kafka`.
+	Type string
+
+	// Connected indicates whether the component is currently connected.
+	Connected bool
+
+	// LastError is the most recent error encountered whilst connecting,
+	// reading or writing, or nil if there hasn't been one.
+	LastError error
+
+	// LastErrorAt is the time at which LastError was encountered.
+	LastErrorAt time.Time
+
+	// LastActive is the time at which the component last successfully read or
+	// wrote a message, or the zero time if it hasn't yet.
+	LastActive time.Time
+
+	// ConsecutiveFailures is the number of consecutive attempts to connect,
+	// read or write that have failed since the last success.
+	ConsecutiveFailures int64
+
+	// Backoff is the remaining period that the component is waiting before
+	// its next attempt, or zero if it isn't currently backing off. Whilst a
+	// component is backing off its circuit is considered open.
+	Backoff time.Duration
+}
+
+// ConnectionStatuser is an optional interface implemented by inputs and
+// outputs that are able to report a detailed connection status for themselves
+// and any child components.
+type ConnectionStatuser interface {
+	// ConnectionStatuses returns the connection status of each component
+	// within this input or output.
+	ConnectionStatuses() []ConnectionStatus
+}
+
+// ConnectionStatusesOf returns the connection statuses of an input or output
+// with each path prefixed by the provided path. If the component does not
+// implement ConnectionStatuser then a single status is returned that only
+// reflects whether it is connected.
+func ConnectionStatusesOf(path string, c interface{ Connected() bool }) []ConnectionStatus {
+	cs, ok := c.(ConnectionStatuser)
+	if !ok {
+		return []ConnectionStatus{{Path: path, Connected: c.Connected()}}
+	}
+	statuses := cs.ConnectionStatuses()
+	for i, s := range statuses {
+		if s.Path == "" {
+			statuses[i].Path = path
+		} else if path != "" {
+			statuses[i].Path = path + "." + s.Path
+		}
+	}
+	return statuses
+}
+
+//------------------------------------------------------------------------------
+
+// RetryBackoff is implemented by retry throttles that are able to report the
+// state of their backoff.
+type RetryBackoff interface {
+	// ConsecutiveRetries returns the number of consecutive retries since the
+	// last success.
+	ConsecutiveRetries() int64
+
+	// Backoff returns the remaining period of the current backoff, or zero if
+	// a retry is not currently being delayed.
+	Backoff() time.Duration
+}
+
+// ConnectionActivity tracks the last error and the last successful activity
+// of an input or output, and is safe to use from multiple goroutines.
+type ConnectionActivity struct {
+	mut         sync.Mutex
+	lastError   error
+	lastErrorAt time.Time
+	lastActive  time.Time
+	backoff     RetryBackoff
+}
+
+// SetBackoff sets the retry throttle of a component, which is used in order to
+// report its consecutive failures and current backoff.
+func (c *ConnectionActivity) SetBackoff(b RetryBackoff) {
+	c.mut.Lock()
+	c.backoff = b
+	c.mut.Unlock()
+}
+
+// SetError records the most recent error encountered by a component.
+func (c *ConnectionActivity) SetError(err error) {
+	c.mut.Lock()
+	c.lastError = err
+	c.lastErrorAt = time.Now()
+	c.mut.Unlock()
+}
+
+// SetActive records that a component has successfully read or written a
+// message.
+func (c *ConnectionActivity) SetActive() {
+	c.mut.Lock()
+	c.lastActive = time.Now()
+	c.mut.Unlock()
+}
+
+// Status returns a ConnectionStatus for a component populated with the
+// recorded activity.
+func (c *ConnectionActivity) Status(typeStr string, connected bool) ConnectionStatus {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	status := ConnectionStatus{
+		Type:        typeStr,
+		Connected:   connected,
+		LastError:   c.lastError,
+		LastErrorAt: c.lastErrorAt,
+		LastActive:  c.lastActive,
+	}
+	if c.backoff != nil {
+		status.ConsecutiveFailures = c.backoff.ConsecutiveRetries()
+		status.Backoff = c.backoff.Backoff()
+	}
+	return status
+}
+
+//------------------------------------------------------------------------------
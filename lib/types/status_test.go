@@ -0,0 +1,91 @@
+package types
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockConnector bool
+
+func (m mockConnector) Connected() bool {
+	return bool(m)
+}
+
+type mockStatuser []ConnectionStatus
+
+func (m mockStatuser) Connected() bool {
+	return false
+}
+
+func (m mockStatuser) ConnectionStatuses() []ConnectionStatus {
+	return append([]ConnectionStatus{}, m...)
+}
+
+func TestConnectionStatusesOf(t *testing.T) {
+	assert.Equal(t, []ConnectionStatus{
+		{Path: "input", Connected: true},
+	}, ConnectionStatusesOf("input", mockConnector(true)))
+
+	errNope := errors.New("nope")
+	statuser := mockStatuser{
+		{Type: "foo", Connected: true},
+		{Path: "broker.outputs.1", Type: "bar", LastError: errNope},
+	}
+
+	assert.Equal(t, []ConnectionStatus{
+		{Path: "output", Type: "foo", Connected: true},
+		{Path: "output.broker.outputs.1", Type: "bar", LastError: errNope},
+	}, ConnectionStatusesOf("output", statuser))
+
+	assert.Equal(t, []ConnectionStatus(statuser), ConnectionStatusesOf("", statuser))
+}
+
+func TestConnectionActivity(t *testing.T) {
+	var a ConnectionActivity
+
+	s := a.Status("foo", false)
+	assert.Equal(t, "foo", s.Type)
+	assert.False(t, s.Connected)
+	assert.Nil(t, s.LastError)
+	assert.True(t, s.LastActive.IsZero())
+
+	errNope := errors.New("nope")
+	a.SetError(errNope)
+	a.SetActive()
+
+	s = a.Status("foo", true)
+	assert.True(t, s.Connected)
+	assert.Equal(t, errNope, s.LastError)
+	assert.False(t, s.LastErrorAt.IsZero())
+	assert.False(t, s.LastActive.Before(s.LastErrorAt))
+}
+
+type mockBackoff struct {
+	retries int64
+	backoff time.Duration
+}
+
+func (m mockBackoff) ConsecutiveRetries() int64 {
+	return m.retries
+}
+
+func (m mockBackoff) Backoff() time.Duration {
+	return m.backoff
+}
+
+func TestConnectionActivityBackoff(t *testing.T) {
+	var a ConnectionActivity
+
+	s := a.Status("foo", false)
+	assert.Equal(t, int64(0), s.ConsecutiveFailures)
+	assert.Equal(t, time.Duration(0), s.Backoff)
+
+	a.SetBackoff(mockBackoff{retries: 5, backoff: time.Second})
+
+	s = a.Status("foo", false)
+	assert.Equal(t, int64(5), s.ConsecutiveFailures)
+	assert.Equal(t, time.Second, s.Backoff)
+}
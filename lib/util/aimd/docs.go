@@ -0,0 +1,15 @@
+package aimd
+
+import "github.com/Jeffail/benthos/v3/internal/docs"
+
+// FieldSpec returns documentation specs for an adaptive concurrency field.
+func FieldSpec(name, maxField string) docs.FieldSpec {
+	return docs.FieldAdvanced(
+		name, "Tunes the number of concurrent operations automatically, up to the value of `"+maxField+"`, by increasing it gradually while operations succeed and halving it when operations fail or exceed a target latency. The current limit is exposed by the metric `adaptive.limit`.",
+	).WithChildren(
+		docs.FieldAdvanced("enabled", "Whether adaptive concurrency is enabled."),
+		docs.FieldAdvanced("min_limit", "The minimum number of concurrent operations, which is also the starting limit."),
+		docs.FieldAdvanced("latency_target", "An optional latency above which operations are treated as failed for the purposes of tuning."),
+		docs.FieldAdvanced("backoff_ratio", "The ratio, between zero and one, by which the limit is multiplied when operations fail."),
+	).AtVersion("3.39.0")
+}
@@ -0,0 +1,4 @@
+// Package aimd implements a concurrency limiter that adapts its limit with an
+// additive increase, multiplicative decrease (AIMD) algorithm based on the
+// observed latency and errors of operations.
+package aimd
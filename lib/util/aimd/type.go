@@ -0,0 +1,153 @@
+package aimd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// Config contains configuration params for an adaptive concurrency limiter.
+type Config struct {
+	Enabled       bool    `json:"enabled" yaml:"enabled"`
+	MinLimit      int     `json:"min_limit" yaml:"min_limit"`
+	LatencyTarget string  `json:"latency_target" yaml:"latency_target"`
+	BackoffRatio  float64 `json:"backoff_ratio" yaml:"backoff_ratio"`
+}
+
+// NewConfig creates a new Config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:       false,
+		MinLimit:      1,
+		LatencyTarget: "",
+		BackoffRatio:  0.5,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Limiter restricts the number of concurrent operations to a limit that is
+// increased by one for each window of successful operations, where a window is
+// a number of operations equal to the limit, and is multiplied by a ratio when
+// an operation fails, at most once per window.
+type Limiter struct {
+	min           float64
+	max           float64
+	latencyTarget time.Duration
+	ratio         float64
+
+	mut           sync.Mutex
+	limit         float64
+	inFlight      int
+	sinceDecrease int
+	released      chan struct{}
+
+	mLimit    metrics.StatGauge
+	mDecrease metrics.StatCounter
+}
+
+// New creates a Limiter from a config with an upper limit.
+func New(conf Config, max int, stats metrics.Type) (*Limiter, error) {
+	if conf.MinLimit < 1 {
+		return nil, fmt.Errorf("min_limit must be at least one, received: %v", conf.MinLimit)
+	}
+	if max < conf.MinLimit {
+		return nil, fmt.Errorf("maximum limit %v must not be less than min_limit %v", max, conf.MinLimit)
+	}
+	if conf.BackoffRatio <= 0 || conf.BackoffRatio >= 1 {
+		return nil, fmt.Errorf("backoff_ratio must be between zero and one, received: %v", conf.BackoffRatio)
+	}
+	l := &Limiter{
+		min:       float64(conf.MinLimit),
+		max:       float64(max),
+		ratio:     conf.BackoffRatio,
+		limit:     float64(conf.MinLimit),
+		released:  make(chan struct{}),
+		mLimit:    stats.GetGauge("adaptive.limit"),
+		mDecrease: stats.GetCounter("adaptive.decrease"),
+	}
+	if conf.LatencyTarget != "" {
+		var err error
+		if l.latencyTarget, err = time.ParseDuration(conf.LatencyTarget); err != nil {
+			return nil, fmt.Errorf("failed to parse latency_target: %v", err)
+		}
+	}
+	l.mLimit.Set(int64(l.limit))
+	return l, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Limit returns the current limit of concurrent operations.
+func (l *Limiter) Limit() int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return int(l.limit)
+}
+
+// Acquire blocks until an operation can begin within the current limit, or
+// the context is cancelled, in which case false is returned. Each successful
+// call must be followed by a call to Release once the operation completes, or
+// Cancel if the operation is abandoned.
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	for {
+		l.mut.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mut.Unlock()
+			return true
+		}
+		released := l.released
+		l.mut.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Release registers the completion of an operation along with its latency and
+// error, and adjusts the limit accordingly.
+func (l *Limiter) Release(latency time.Duration, err error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.inFlight--
+	l.sinceDecrease++
+	if err != nil || (l.latencyTarget > 0 && latency > l.latencyTarget) {
+		if l.sinceDecrease >= int(l.limit) {
+			if l.limit *= l.ratio; l.limit < l.min {
+				l.limit = l.min
+			}
+			l.sinceDecrease = 0
+			l.mDecrease.Incr(1)
+		}
+	} else if l.limit += 1 / l.limit; l.limit > l.max {
+		l.limit = l.max
+	}
+	l.mLimit.Set(int64(l.limit))
+
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// Cancel registers that an operation was abandoned before it could complete,
+// which frees its place without adjusting the limit.
+func (l *Limiter) Cancel() {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	l.inFlight--
+
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,123 @@
+package aimd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterIncreaseDecrease(t *testing.T) {
+	conf := NewConfig()
+	conf.MinLimit = 2
+	conf.LatencyTarget = "1s"
+
+	l, err := New(conf, 4, metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, 2, l.Limit())
+
+	// Each successful operation increases the limit by a fraction of one.
+	for i := 0; i < 3; i++ {
+		require.True(t, l.Acquire(context.Background()))
+		l.Release(time.Millisecond, nil)
+	}
+	assert.Equal(t, 3, l.Limit())
+
+	for i := 0; i < 10; i++ {
+		require.True(t, l.Acquire(context.Background()))
+		l.Release(time.Millisecond, nil)
+	}
+	assert.Equal(t, 4, l.Limit(), "capped at the maximum")
+
+	// A slow operation halves the limit, but only once per window.
+	for i := 0; i < 2; i++ {
+		require.True(t, l.Acquire(context.Background()))
+		l.Release(time.Second*2, nil)
+	}
+	assert.Equal(t, 2, l.Limit())
+
+	require.True(t, l.Acquire(context.Background()))
+	l.Release(0, errors.New("nope"))
+	require.True(t, l.Acquire(context.Background()))
+	l.Release(0, errors.New("nope"))
+	assert.Equal(t, 2, l.Limit(), "capped at the minimum")
+}
+
+func TestLimiterAcquireBlocks(t *testing.T) {
+	l, err := New(NewConfig(), 1, metrics.Noop())
+	require.NoError(t, err)
+
+	require.True(t, l.Acquire(context.Background()))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer done()
+	assert.False(t, l.Acquire(ctx))
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- l.Acquire(context.Background())
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired beyond limit")
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	l.Release(0, nil)
+	select {
+	case a := <-acquired:
+		assert.True(t, a)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l, err := New(NewConfig(), 1, metrics.Noop())
+	require.NoError(t, err)
+
+	require.True(t, l.Acquire(context.Background()))
+	l.Cancel()
+	assert.Equal(t, 1, l.Limit())
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer done()
+	assert.True(t, l.Acquire(ctx))
+}
+
+func TestLimiterBadConfig(t *testing.T) {
+	tests := map[string]struct {
+		fn  func(c *Config)
+		max int
+	}{
+		"zero min": {
+			fn:  func(c *Config) { c.MinLimit = 0 },
+			max: 10,
+		},
+		"max below min": {
+			fn:  func(c *Config) { c.MinLimit = 5 },
+			max: 4,
+		},
+		"bad ratio": {
+			fn:  func(c *Config) { c.BackoffRatio = 1 },
+			max: 10,
+		},
+		"bad latency": {
+			fn:  func(c *Config) { c.LatencyTarget = "nope" },
+			max: 10,
+		},
+	}
+
+	for name, test := range tests {
+		conf := NewConfig()
+		test.fn(&conf)
+
+		_, err := New(conf, test.max, metrics.Noop())
+		assert.Error(t, err, name)
+	}
+}
@@ -28,12 +28,39 @@ func oAuthFieldSpec() docs.FieldSpec {
 
 func oAuth2FieldSpec() docs.FieldSpec {
 	return docs.FieldAdvanced("oauth2",
-		"Allows you to specify open authentication via OAuth version 2 using the client credentials token flow.",
+		"Allows you to specify open authentication via OAuth version 2 using the client credentials token flow. Tokens are cached and refreshed before they expire, and requests rejected with a 401 status code are attempted once more with a fresh token.",
 	).WithChildren(
 		docs.FieldCommon("enabled", "Whether to use OAuth version 2 in requests."),
 		docs.FieldCommon("client_key", "A value used to identify the client to the token provider."),
 		docs.FieldCommon("client_secret", "A secret used to establish ownership of the client key."),
 		docs.FieldCommon("token_url", "The URL of the token provider."),
+		docs.FieldAdvanced("scopes", "A list of optional requested permissions."),
+		docs.FieldAdvanced("endpoint_params", "A map of optional additional parameters to send to the token provider.", map[string]interface{}{
+			"audience": []string{"https://example.com"},
+		}),
+		docs.FieldAdvanced("expiry_buffer", "The period before a token expires at which point it is refreshed."),
+	)
+}
+
+// HMACSigningFieldSpec returns a field spec for signing request bodies with an
+// HMAC.
+func HMACSigningFieldSpec() docs.FieldSpec {
+	return docs.FieldAdvanced("signing",
+		"Allows you to sign the body of each request with an HMAC, which the receiving server can use in order to verify the origin of the request. The `github`, `stripe` and `slack` styles follow the conventions of those services, in which case the fields `header`, `prefix`, `timestamp_header` and `encoding` are ignored.",
+	).WithChildren(
+		docs.FieldCommon("enabled", "Whether to sign requests."),
+		docs.FieldCommon("style", "The style of signature to add to requests.").HasAnnotatedOptions(
+			"custom", "Adds a signature of the body to the header `header`. When `timestamp_header` is set the current unix timestamp is added to that header and the signed payload becomes `<timestamp>.<body>`.",
+			"github", "Adds the header `X-Hub-Signature-256`, or `X-Hub-Signature` when the algorithm is `sha1`.",
+			"stripe", "Adds the header `Stripe-Signature` with a timestamp, always using the algorithm `sha256`.",
+			"slack", "Adds the headers `X-Slack-Request-Timestamp` and `X-Slack-Signature`, always using the algorithm `sha256`.",
+		),
+		docs.FieldCommon("secret", "The secret key used to compute signatures."),
+		docs.FieldCommon("algorithm", "The hash algorithm of the HMAC.").HasOptions("sha1", "sha256", "sha512"),
+		docs.FieldAdvanced("encoding", "The encoding of custom signatures.").HasOptions("hex", "base64"),
+		docs.FieldAdvanced("header", "The header to add custom signatures to."),
+		docs.FieldAdvanced("prefix", "An optional prefix to add to custom signatures.", "sha256="),
+		docs.FieldAdvanced("timestamp_header", "An optional header to add the unix timestamp of a request to, in which case the timestamp is also signed.", "X-Signature-Timestamp"),
 	)
 }
 
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// HMACSigningConfig holds the configuration parameters for signing the body of
+// HTTP requests with an HMAC.
+type HMACSigningConfig struct {
+	Enabled         bool   `json:"enabled" yaml:"enabled"`
+	Style           string `json:"style" yaml:"style"`
+	Secret          string `json:"secret" yaml:"secret"`
+	Algorithm       string `json:"algorithm" yaml:"algorithm"`
+	Encoding        string `json:"encoding" yaml:"encoding"`
+	Header          string `json:"header" yaml:"header"`
+	Prefix          string `json:"prefix" yaml:"prefix"`
+	TimestampHeader string `json:"timestamp_header" yaml:"timestamp_header"`
+}
+
+// NewHMACSigningConfig returns a new HMACSigningConfig with default values.
+func NewHMACSigningConfig() HMACSigningConfig {
+	return HMACSigningConfig{
+		Enabled:         false,
+		Style:           "custom",
+		Secret:          "",
+		Algorithm:       "sha256",
+		Encoding:        "hex",
+		Header:          "X-Signature",
+		Prefix:          "",
+		TimestampHeader: "",
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Signer returns a function that signs HTTP requests according to the config,
+// or nil if signing is disabled.
+func (s HMACSigningConfig) Signer() (func(req *http.Request) error, error) {
+	return s.signer(time.Now)
+}
+
+func (s HMACSigningConfig) signer(now func() time.Time) (func(req *http.Request) error, error) {
+	if !s.Enabled {
+		return nil, nil
+	}
+	if s.Secret == "" {
+		return nil, errors.New("a signing secret must be provided")
+	}
+
+	var hashFn func() hash.Hash
+	switch s.Algorithm {
+	case "sha1":
+		hashFn = sha1.New
+	case "sha256":
+		hashFn = sha256.New
+	case "sha512":
+		hashFn = sha512.New
+	default:
+		return nil, fmt.Errorf("signing algorithm not recognised: %v", s.Algorithm)
+	}
+
+	var encodeFn func([]byte) string
+	switch s.Encoding {
+	case "hex":
+		encodeFn = hex.EncodeToString
+	case "base64":
+		encodeFn = base64.StdEncoding.EncodeToString
+	default:
+		return nil, fmt.Errorf("signature encoding not recognised: %v", s.Encoding)
+	}
+
+	computeHMAC := func(hashFn func() hash.Hash, message []byte) []byte {
+		h := hmac.New(hashFn, []byte(s.Secret))
+		h.Write(message)
+		return h.Sum(nil)
+	}
+
+	switch s.Style {
+	case "github":
+		// https://docs.github.com/en/developers/webhooks-and-events/securing-your-webhooks
+		header, prefix := "X-Hub-Signature-256", "sha256="
+		if s.Algorithm == "sha1" {
+			header, prefix = "X-Hub-Signature", "sha1="
+		}
+		return func(req *http.Request) error {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return err
+			}
+			req.Header.Set(header, prefix+hex.EncodeToString(computeHMAC(hashFn, body)))
+			return nil
+		}, nil
+	case "stripe":
+		// https://stripe.com/docs/webhooks/signatures
+		return func(req *http.Request) error {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return err
+			}
+			ts := strconv.FormatInt(now().Unix(), 10)
+			sig := computeHMAC(sha256.New, append([]byte(ts+"."), body...))
+			req.Header.Set("Stripe-Signature", "t="+ts+",v1="+hex.EncodeToString(sig))
+			return nil
+		}, nil
+	case "slack":
+		// https://api.slack.com/authentication/verifying-requests-from-slack
+		return func(req *http.Request) error {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return err
+			}
+			ts := strconv.FormatInt(now().Unix(), 10)
+			sig := computeHMAC(sha256.New, append([]byte("v0:"+ts+":"), body...))
+			req.Header.Set("X-Slack-Request-Timestamp", ts)
+			req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(sig))
+			return nil
+		}, nil
+	case "custom":
+	default:
+		return nil, fmt.Errorf("signing style not recognised: %v", s.Style)
+	}
+
+	if s.Header == "" {
+		return nil, errors.New("a signature header must be provided")
+	}
+	return func(req *http.Request) error {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return err
+		}
+		if s.TimestampHeader != "" {
+			ts := strconv.FormatInt(now().Unix(), 10)
+			req.Header.Set(s.TimestampHeader, ts)
+			body = append([]byte(ts+"."), body...)
+		}
+		req.Header.Set(s.Header, s.Prefix+encodeFn(computeHMAC(hashFn, body)))
+		return nil
+	}, nil
+}
+
+// readRequestBody returns the body of a request without consuming it.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("unable to sign request as its body cannot be read more than once")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+//------------------------------------------------------------------------------
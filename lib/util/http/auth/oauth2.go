@@ -2,8 +2,14 @@ package auth
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
@@ -11,19 +17,25 @@ import (
 
 // OAuth2Config holds the configuration parameters for an OAuth2 exchange.
 type OAuth2Config struct {
-	Enabled      bool   `json:"enabled" yaml:"enabled"`
-	ClientKey    string `json:"client_key" yaml:"client_key"`
-	ClientSecret string `json:"client_secret" yaml:"client_secret"`
-	TokenURL     string `json:"token_url" yaml:"token_url"`
+	Enabled        bool                `json:"enabled" yaml:"enabled"`
+	ClientKey      string              `json:"client_key" yaml:"client_key"`
+	ClientSecret   string              `json:"client_secret" yaml:"client_secret"`
+	TokenURL       string              `json:"token_url" yaml:"token_url"`
+	Scopes         []string            `json:"scopes" yaml:"scopes"`
+	EndpointParams map[string][]string `json:"endpoint_params" yaml:"endpoint_params"`
+	ExpiryBuffer   string              `json:"expiry_buffer" yaml:"expiry_buffer"`
 }
 
 // NewOAuth2Config returns a new OAuth2Config with default values.
 func NewOAuth2Config() OAuth2Config {
 	return OAuth2Config{
-		Enabled:      false,
-		ClientKey:    "",
-		ClientSecret: "",
-		TokenURL:     "",
+		Enabled:        false,
+		ClientKey:      "",
+		ClientSecret:   "",
+		TokenURL:       "",
+		Scopes:         []string{},
+		EndpointParams: map[string][]string{},
+		ExpiryBuffer:   "10s",
 	}
 }
 
@@ -36,11 +48,144 @@ func (oauth OAuth2Config) Client(ctx context.Context) *http.Client {
 		return &client
 	}
 
+	// An invalid expiry buffer falls back to the default.
+	rt, _ := oauth.RoundTripper(ctx, nil)
+	return &http.Client{Transport: rt}
+}
+
+// RoundTripper wraps a base http.RoundTripper, which may be nil, with one that
+// authenticates requests using tokens obtained via the client credentials flow.
+// Tokens are cached and refreshed before they expire, and a request that is
+// rejected with a 401 status code is attempted once more with a freshly
+// obtained token.
+//
+// The base round tripper is also used for obtaining tokens. When OAuth2 is not
+// enabled the base round tripper is returned unchanged.
+func (oauth OAuth2Config) RoundTripper(ctx context.Context, base http.RoundTripper) (http.RoundTripper, error) {
+	if !oauth.Enabled {
+		return base, nil
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var err error
+	expiryBuffer := 10 * time.Second
+	if oauth.ExpiryBuffer != "" {
+		var tmp time.Duration
+		if tmp, err = time.ParseDuration(oauth.ExpiryBuffer); err != nil {
+			err = fmt.Errorf("failed to parse expiry buffer: %v", err)
+		} else {
+			expiryBuffer = tmp
+		}
+	}
+
 	conf := &clientcredentials.Config{
-		ClientID:     oauth.ClientKey,
-		ClientSecret: oauth.ClientSecret,
-		TokenURL:     oauth.TokenURL,
+		ClientID:       oauth.ClientKey,
+		ClientSecret:   oauth.ClientSecret,
+		TokenURL:       oauth.TokenURL,
+		Scopes:         oauth.Scopes,
+		EndpointParams: oauth.EndpointParams,
+	}
+
+	return &oauth2Transport{
+		source: &cachedTokenSource{
+			ctx:          context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: base}),
+			conf:         conf,
+			expiryBuffer: expiryBuffer,
+		},
+		base: base,
+	}, err
+}
+
+//------------------------------------------------------------------------------
+
+// cachedTokenSource obtains tokens via the client credentials flow and caches
+// them until they are within a buffer period of expiring.
+type cachedTokenSource struct {
+	ctx          context.Context
+	conf         *clientcredentials.Config
+	expiryBuffer time.Duration
+
+	mut   sync.Mutex
+	token *oauth2.Token
+}
+
+func (c *cachedTokenSource) Token() (*oauth2.Token, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.token != nil && c.token.AccessToken != "" {
+		if c.token.Expiry.IsZero() || time.Until(c.token.Expiry) > c.expiryBuffer {
+			return c.token, nil
+		}
+	}
+
+	token, err := c.conf.Token(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.token = token
+	return token, nil
+}
+
+// invalidate drops a cached token, unless it has already been replaced.
+func (c *cachedTokenSource) invalidate(token *oauth2.Token) {
+	c.mut.Lock()
+	if c.token == token {
+		c.token = nil
 	}
+	c.mut.Unlock()
+}
 
-	return conf.Client(ctx)
+//------------------------------------------------------------------------------
+
+type oauth2Transport struct {
+	source *cachedTokenSource
+	base   http.RoundTripper
 }
+
+func (t *oauth2Transport) authorisedRequest(req *http.Request, token *oauth2.Token) *http.Request {
+	authReq := req.Clone(req.Context())
+	token.SetAuthHeader(authReq)
+	return authReq
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.source.Token()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	res, err := t.base.RoundTrip(t.authorisedRequest(req, token))
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	// The token may have been revoked, therefore obtain a new one and attempt
+	// the request once more, but only if the body can be rewound.
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+
+	t.source.invalidate(token)
+	if token, err = t.source.Token(); err != nil {
+		return res, nil
+	}
+
+	retryReq := t.authorisedRequest(req, token)
+	if req.Body != nil {
+		if retryReq.Body, err = req.GetBody(); err != nil {
+			return res, nil
+		}
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	return t.base.RoundTrip(retryReq)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,310 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// ErrCircuitOpen is returned when a request is rejected because the circuit
+// breaker of a client is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreakerConfig contains configuration fields for the circuit breaker
+// of an HTTP client.
+type CircuitBreakerConfig struct {
+	Enabled          bool    `json:"enabled" yaml:"enabled"`
+	Window           string  `json:"window" yaml:"window"`
+	MinRequests      int     `json:"min_requests" yaml:"min_requests"`
+	FailureThreshold float64 `json:"failure_threshold" yaml:"failure_threshold"`
+	OpenPeriod       string  `json:"open_period" yaml:"open_period"`
+	HalfOpenRequests int     `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+// NewCircuitBreakerConfig creates a new CircuitBreakerConfig with default
+// values.
+func NewCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Enabled:          false,
+		Window:           "10s",
+		MinRequests:      20,
+		FailureThreshold: 0.5,
+		OpenPeriod:       "30s",
+		HalfOpenRequests: 1,
+	}
+}
+
+// RetryBudgetConfig contains configuration fields for limiting the retries of
+// an HTTP client relative to its requests.
+type RetryBudgetConfig struct {
+	Enabled    bool    `json:"enabled" yaml:"enabled"`
+	Window     string  `json:"window" yaml:"window"`
+	Ratio      float64 `json:"ratio" yaml:"ratio"`
+	MinRetries int     `json:"min_retries" yaml:"min_retries"`
+}
+
+// NewRetryBudgetConfig creates a new RetryBudgetConfig with default values.
+func NewRetryBudgetConfig() RetryBudgetConfig {
+	return RetryBudgetConfig{
+		Enabled:    false,
+		Window:     "10s",
+		Ratio:      0.2,
+		MinRetries: 10,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const rollingBuckets = 10
+
+// rollingCounter counts successes and failures over a sliding window of time,
+// which is divided into buckets that expire as the window moves on.
+type rollingCounter struct {
+	bucketPeriod time.Duration
+	starts       [rollingBuckets]time.Time
+	successes    [rollingBuckets]int
+	failures     [rollingBuckets]int
+}
+
+func newRollingCounter(window time.Duration) *rollingCounter {
+	bucketPeriod := window / rollingBuckets
+	if bucketPeriod <= 0 {
+		bucketPeriod = 1
+	}
+	return &rollingCounter{
+		bucketPeriod: bucketPeriod,
+	}
+}
+
+func (r *rollingCounter) bucket(now time.Time) int {
+	start := now.Truncate(r.bucketPeriod)
+	i := int(start.UnixNano()/int64(r.bucketPeriod)) % rollingBuckets
+	if !r.starts[i].Equal(start) {
+		r.starts[i] = start
+		r.successes[i], r.failures[i] = 0, 0
+	}
+	return i
+}
+
+func (r *rollingCounter) add(now time.Time, success bool) {
+	i := r.bucket(now)
+	if success {
+		r.successes[i]++
+	} else {
+		r.failures[i]++
+	}
+}
+
+func (r *rollingCounter) totals(now time.Time) (successes, failures int) {
+	oldest := now.Truncate(r.bucketPeriod).Add(-r.bucketPeriod * (rollingBuckets - 1))
+	for i := 0; i < rollingBuckets; i++ {
+		if r.starts[i].Before(oldest) {
+			continue
+		}
+		successes += r.successes[i]
+		failures += r.failures[i]
+	}
+	return
+}
+
+func (r *rollingCounter) reset() {
+	*r = rollingCounter{bucketPeriod: r.bucketPeriod}
+}
+
+func parseWindow(window string) (time.Duration, error) {
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse window: %v", err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("window must be a positive duration, received: %v", window)
+	}
+	return d, nil
+}
+
+//------------------------------------------------------------------------------
+
+type breakerState int64
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker rejects requests once the rate of failed requests over a
+// window exceeds a threshold, and after a period allows a limited number of
+// probe requests through in order to determine whether to close again.
+type circuitBreaker struct {
+	minRequests      int
+	failureThreshold float64
+	openPeriod       time.Duration
+	halfOpenRequests int
+
+	mut       sync.Mutex
+	state     breakerState
+	counter   *rollingCounter
+	openedAt  time.Time
+	probes    int
+	succeeded int
+
+	nowFn func() time.Time
+
+	mState    metrics.StatGauge
+	mTripped  metrics.StatCounter
+	mRejected metrics.StatCounter
+}
+
+func newCircuitBreaker(conf CircuitBreakerConfig, stats metrics.Type) (*circuitBreaker, error) {
+	window, err := parseWindow(conf.Window)
+	if err != nil {
+		return nil, err
+	}
+	if conf.FailureThreshold <= 0 || conf.FailureThreshold > 1 {
+		return nil, fmt.Errorf("failure_threshold must be greater than zero and at most one, received: %v", conf.FailureThreshold)
+	}
+	if conf.HalfOpenRequests < 1 {
+		return nil, fmt.Errorf("half_open_requests must be at least one, received: %v", conf.HalfOpenRequests)
+	}
+	openPeriod, err := time.ParseDuration(conf.OpenPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse open_period: %v", err)
+	}
+	b := &circuitBreaker{
+		minRequests:      conf.MinRequests,
+		failureThreshold: conf.FailureThreshold,
+		openPeriod:       openPeriod,
+		halfOpenRequests: conf.HalfOpenRequests,
+		counter:          newRollingCounter(window),
+		nowFn:            time.Now,
+		mState:           stats.GetGauge("circuit_breaker.state"),
+		mTripped:         stats.GetCounter("circuit_breaker.tripped"),
+		mRejected:        stats.GetCounter("circuit_breaker.rejected"),
+	}
+	b.mState.Set(int64(breakerClosed))
+	return b, nil
+}
+
+func (b *circuitBreaker) setState(state breakerState) {
+	b.state = state
+	b.mState.Set(int64(state))
+}
+
+// allow returns whether a request may be attempted.
+func (b *circuitBreaker) allow() bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.state == breakerOpen && b.nowFn().Sub(b.openedAt) >= b.openPeriod {
+		b.setState(breakerHalfOpen)
+		b.probes, b.succeeded = 0, 0
+	}
+
+	switch b.state {
+	case breakerOpen:
+		b.mRejected.Incr(1)
+		return false
+	case breakerHalfOpen:
+		if b.probes >= b.halfOpenRequests {
+			b.mRejected.Incr(1)
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// record registers the outcome of a request that was allowed.
+func (b *circuitBreaker) record(success bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	now := b.nowFn()
+	switch b.state {
+	case breakerClosed:
+		b.counter.add(now, success)
+		successes, failures := b.counter.totals(now)
+		total := successes + failures
+		if !success && total >= b.minRequests && float64(failures)/float64(total) >= b.failureThreshold {
+			b.trip(now)
+		}
+	case breakerHalfOpen:
+		if !success {
+			b.trip(now)
+			return
+		}
+		if b.succeeded++; b.succeeded >= b.halfOpenRequests {
+			b.counter.reset()
+			b.setState(breakerClosed)
+		}
+	}
+}
+
+func (b *circuitBreaker) trip(now time.Time) {
+	b.openedAt = now
+	b.setState(breakerOpen)
+	b.mTripped.Incr(1)
+}
+
+//------------------------------------------------------------------------------
+
+// retryBudget limits the number of retries over a window to a ratio of the
+// number of requests, plus a minimum number of retries.
+type retryBudget struct {
+	ratio      float64
+	minRetries int
+
+	mut     sync.Mutex
+	counter *rollingCounter
+
+	nowFn func() time.Time
+
+	mExhausted metrics.StatCounter
+}
+
+func newRetryBudget(conf RetryBudgetConfig, stats metrics.Type) (*retryBudget, error) {
+	window, err := parseWindow(conf.Window)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Ratio < 0 {
+		return nil, fmt.Errorf("ratio must not be negative, received: %v", conf.Ratio)
+	}
+	return &retryBudget{
+		ratio:      conf.Ratio,
+		minRetries: conf.MinRetries,
+		counter:    newRollingCounter(window),
+		nowFn:      time.Now,
+		mExhausted: stats.GetCounter("retry_budget.exhausted"),
+	}, nil
+}
+
+// request registers a request, which adds to the budget of retries.
+func (r *retryBudget) request() {
+	r.mut.Lock()
+	r.counter.add(r.nowFn(), true)
+	r.mut.Unlock()
+}
+
+// allowRetry returns whether a retry fits within the budget, and if so it is
+// deducted from it.
+func (r *retryBudget) allowRetry() bool {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	now := r.nowFn()
+	requests, retries := r.counter.totals(now)
+	if float64(retries) >= r.ratio*float64(requests)+float64(r.minRetries) {
+		r.mExhausted.Incr(1)
+		return false
+	}
+	r.counter.add(now, false)
+	return true
+}
+
+//------------------------------------------------------------------------------
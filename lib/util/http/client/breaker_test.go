@@ -0,0 +1,199 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	conf := NewCircuitBreakerConfig()
+	conf.MinRequests = 4
+	conf.HalfOpenRequests = 2
+
+	b, err := newCircuitBreaker(conf, metrics.Noop())
+	require.NoError(t, err)
+
+	now := time.Unix(1600000000, 0)
+	b.nowFn = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		require.True(t, b.allow())
+		b.record(false)
+	}
+	assert.Equal(t, breakerClosed, b.state, "below min requests")
+
+	require.True(t, b.allow())
+	b.record(false)
+	assert.Equal(t, breakerOpen, b.state)
+	assert.False(t, b.allow())
+
+	now = now.Add(time.Second * 30)
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+	assert.Equal(t, breakerHalfOpen, b.state)
+
+	b.record(true)
+	b.record(false)
+	assert.Equal(t, breakerOpen, b.state)
+
+	now = now.Add(time.Second * 30)
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+	b.record(true)
+	b.record(true)
+	assert.Equal(t, breakerClosed, b.state)
+
+	for i := 0; i < 3; i++ {
+		b.record(false)
+	}
+	now = now.Add(time.Second * 11)
+	b.record(false)
+	assert.Equal(t, breakerClosed, b.state, "failures expired from window")
+}
+
+func TestRetryBudget(t *testing.T) {
+	conf := NewRetryBudgetConfig()
+	conf.Ratio = 0.5
+	conf.MinRetries = 1
+
+	r, err := newRetryBudget(conf, metrics.Noop())
+	require.NoError(t, err)
+
+	now := time.Unix(1600000000, 0)
+	r.nowFn = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		r.request()
+	}
+	for i := 0; i < 3; i++ {
+		assert.True(t, r.allowRetry(), i)
+	}
+	assert.False(t, r.allowRetry())
+
+	now = now.Add(time.Second * 11)
+	assert.True(t, r.allowRetry())
+	assert.False(t, r.allowRetry())
+}
+
+func TestHTTPClientCircuitBreaker(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "test error", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.NumRetries = 3
+	conf.CircuitBreaker.Enabled = true
+	conf.CircuitBreaker.MinRequests = 2
+
+	h, err := New(conf)
+	require.NoError(t, err)
+
+	_, err = h.Send(message.New([][]byte{[]byte("test")}))
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+
+	_, err = h.Send(message.New([][]byte{[]byte("test")}))
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientCircuitBreakerDropOn(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "test error", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.DropOn = []int{http.StatusBadRequest}
+	conf.CircuitBreaker.Enabled = true
+	conf.CircuitBreaker.MinRequests = 2
+
+	h, err := New(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = h.Send(message.New([][]byte{[]byte("test")}))
+		assert.Error(t, err)
+		assert.NotEqual(t, ErrCircuitOpen, err)
+	}
+	assert.Equal(t, uint32(5), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientRetryBudget(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "test error", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.NumRetries = 3
+	conf.RetryBudget.Enabled = true
+	conf.RetryBudget.Ratio = 0
+	conf.RetryBudget.MinRetries = 4
+
+	h, err := New(conf)
+	require.NoError(t, err)
+
+	_, err = h.Send(message.New([][]byte{[]byte("test")}))
+	assert.Error(t, err)
+	assert.Equal(t, uint32(4), atomic.LoadUint32(&reqCount))
+
+	_, err = h.Send(message.New([][]byte{[]byte("test")}))
+	assert.Error(t, err)
+	assert.Equal(t, uint32(6), atomic.LoadUint32(&reqCount))
+
+	_, err = h.Send(message.New([][]byte{[]byte("test")}))
+	assert.Error(t, err)
+	assert.Equal(t, uint32(7), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientBadBreakerConfig(t *testing.T) {
+	tests := map[string]func(c *Config){
+		"bad window": func(c *Config) {
+			c.CircuitBreaker.Window = "nope"
+		},
+		"zero threshold": func(c *Config) {
+			c.CircuitBreaker.FailureThreshold = 0
+		},
+		"zero half open requests": func(c *Config) {
+			c.CircuitBreaker.HalfOpenRequests = 0
+		},
+		"bad open period": func(c *Config) {
+			c.CircuitBreaker.OpenPeriod = "nope"
+		},
+		"negative ratio": func(c *Config) {
+			c.RetryBudget.Ratio = -1
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.CircuitBreaker.Enabled = true
+		conf.RetryBudget.Enabled = true
+		fn(&conf)
+
+		_, err := New(conf)
+		assert.Error(t, err, name)
+	}
+}
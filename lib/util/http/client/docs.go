@@ -28,6 +28,33 @@ func FieldSpecs() docs.FieldSpecs {
 		docs.FieldAdvanced("drop_on", "A list of status codes whereby the attempt should be considered failed but retries should not be attempted.").HasType("array"),
 		docs.FieldAdvanced("successful_on", "A list of status codes whereby the attempt should be considered successful (allows you to configure non-2XX codes).").HasType("array"),
 		docs.FieldAdvanced("proxy_url", "An optional HTTP proxy URL.").HasType("string"),
+		docs.FieldAdvanced(
+			"circuit_breaker", "Prevents requests from being sent once the rate of failed requests exceeds a threshold. After a period the breaker becomes half-open and allows a number of requests through, closing again if they all succeed or opening again if any fail. Requests that fail with a status code listed in `drop_on` aren't counted as failures. The state of the breaker is exposed by the metric `circuit_breaker.state`, where `0` is closed, `1` is open and `2` is half-open.",
+		).WithChildren(
+			docs.FieldAdvanced("enabled", "Whether the circuit breaker is enabled.").HasType("bool"),
+			docs.FieldAdvanced("window", "The period of time over which the rate of failed requests is measured.").HasType("string"),
+			docs.FieldAdvanced("min_requests", "The minimum number of requests within the window before the breaker can be opened.").HasType("number"),
+			docs.FieldAdvanced("failure_threshold", "The ratio of failed requests within the window, between zero and one, at which the breaker is opened.").HasType("number"),
+			docs.FieldAdvanced("open_period", "The period of time to reject requests before the breaker becomes half-open.").HasType("string"),
+			docs.FieldAdvanced("half_open_requests", "The number of requests allowed while half-open, all of which must succeed in order to close the breaker.").HasType("number"),
+		).AtVersion("3.39.0"),
+		docs.FieldAdvanced(
+			"retry_budget", "Limits the number of retries within a window to a ratio of the number of requests, so that retries are abandoned during sustained failures rather than adding to the load of the server.",
+		).WithChildren(
+			docs.FieldAdvanced("enabled", "Whether the retry budget is enabled.").HasType("bool"),
+			docs.FieldAdvanced("window", "The period of time over which requests and retries are counted.").HasType("string"),
+			docs.FieldAdvanced("ratio", "The number of retries permitted within the window as a ratio of the number of requests.").HasType("number"),
+			docs.FieldAdvanced("min_retries", "A number of retries permitted within the window in addition to the ratio, which allows retries when the rate of requests is low.").HasType("number"),
+		).AtVersion("3.39.0"),
+		docs.FieldAdvanced(
+			"hedging", "Issues a second request when a request takes longer than a percentile of the latencies of recent successful requests, and uses whichever response succeeds first. This reduces the tail latency of requests to servers where a small number of requests are slow, at the cost of additional load. The number of hedged requests within a window is limited to a ratio of the number of requests. Only requests with the verbs `GET`, `HEAD` and `OPTIONS` are hedged, as requests with other verbs might not be safe to send twice.",
+		).WithChildren(
+			docs.FieldAdvanced("enabled", "Whether hedged requests are enabled.").HasType("bool"),
+			docs.FieldAdvanced("percentile", "The percentile of recent request latencies, between zero and one, after which a request is hedged.").HasType("number"),
+			docs.FieldAdvanced("min_samples", "The minimum number of successful requests to observe before requests are hedged.").HasType("number"),
+			docs.FieldAdvanced("window", "The period of time over which requests and hedged requests are counted.").HasType("string"),
+			docs.FieldAdvanced("ratio", "The number of hedged requests permitted within the window as a ratio of the number of requests.").HasType("number"),
+		).AtVersion("3.39.0"),
 	)
 
 	return httpSpecs
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+)
+
+//------------------------------------------------------------------------------
+
+// HedgingConfig contains configuration fields for issuing hedged requests from
+// an HTTP client.
+type HedgingConfig struct {
+	Enabled    bool    `json:"enabled" yaml:"enabled"`
+	Percentile float64 `json:"percentile" yaml:"percentile"`
+	MinSamples int     `json:"min_samples" yaml:"min_samples"`
+	Window     string  `json:"window" yaml:"window"`
+	Ratio      float64 `json:"ratio" yaml:"ratio"`
+}
+
+// NewHedgingConfig creates a new HedgingConfig with default values.
+func NewHedgingConfig() HedgingConfig {
+	return HedgingConfig{
+		Enabled:    false,
+		Percentile: 0.95,
+		MinSamples: 20,
+		Window:     "10s",
+		Ratio:      0.1,
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// The number of recent request latencies that the hedging delay is derived
+// from, and the number of latencies recorded between recalculations of it.
+const (
+	hedgeSamples     = 1000
+	hedgeRecalculate = 10
+)
+
+// hedger tracks the latencies of successful requests in order to determine how
+// long to wait for a response before issuing a hedged request, and limits the
+// number of hedged requests within a window to a ratio of the number of
+// requests.
+type hedger struct {
+	percentile float64
+	minSamples int
+	ratio      float64
+
+	mut       sync.Mutex
+	latencies []time.Duration
+	next      int
+	recorded  int
+	delay     time.Duration
+	counter   *rollingCounter
+
+	nowFn func() time.Time
+
+	mSent      metrics.StatCounter
+	mWon       metrics.StatCounter
+	mExhausted metrics.StatCounter
+}
+
+func newHedger(conf HedgingConfig, stats metrics.Type) (*hedger, error) {
+	window, err := parseWindow(conf.Window)
+	if err != nil {
+		return nil, err
+	}
+	if conf.Percentile <= 0 || conf.Percentile >= 1 {
+		return nil, fmt.Errorf("percentile must be greater than zero and less than one, received: %v", conf.Percentile)
+	}
+	if conf.Ratio < 0 {
+		return nil, fmt.Errorf("ratio must not be negative, received: %v", conf.Ratio)
+	}
+	minSamples := conf.MinSamples
+	if minSamples < 1 {
+		minSamples = 1
+	}
+	return &hedger{
+		percentile: conf.Percentile,
+		minSamples: minSamples,
+		ratio:      conf.Ratio,
+		latencies:  make([]time.Duration, 0, hedgeSamples),
+		counter:    newRollingCounter(window),
+		nowFn:      time.Now,
+		mSent:      stats.GetCounter("hedging.sent"),
+		mWon:       stats.GetCounter("hedging.won"),
+		mExhausted: stats.GetCounter("hedging.exhausted"),
+	}, nil
+}
+
+// record registers the latency of a successful request.
+func (h *hedger) record(latency time.Duration) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if len(h.latencies) < hedgeSamples {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.next] = latency
+		h.next = (h.next + 1) % hedgeSamples
+	}
+	if h.recorded++; h.recorded < hedgeRecalculate && h.delay > 0 {
+		return
+	}
+	h.recorded = 0
+	if len(h.latencies) < h.minSamples {
+		return
+	}
+
+	sorted := make([]time.Duration, len(h.latencies))
+	copy(sorted, h.latencies)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	h.delay = sorted[int(float64(len(sorted)-1)*h.percentile)]
+}
+
+// request registers a request, which adds to the budget of hedged requests, and
+// returns the period to wait for a response before hedging it, or false if not
+// enough latencies have been recorded yet.
+func (h *hedger) request() (time.Duration, bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	h.counter.add(h.nowFn(), true)
+	return h.delay, h.delay > 0
+}
+
+// allow returns whether a hedged request fits within the budget, and if so it
+// is deducted from it.
+func (h *hedger) allow() bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	now := h.nowFn()
+	requests, hedged := h.counter.totals(now)
+	if float64(hedged) >= h.ratio*float64(requests) {
+		h.mExhausted.Incr(1)
+		return false
+	}
+	h.counter.add(now, false)
+	h.mSent.Incr(1)
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// cancelOnClose cancels the context of a request once its response body is
+// closed, as cancelling it earlier would interrupt reading the body.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/metrics"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedger(t *testing.T) {
+	conf := NewHedgingConfig()
+	conf.Percentile = 0.9
+	conf.MinSamples = 10
+	conf.Ratio = 0.5
+
+	h, err := newHedger(conf, metrics.Noop())
+	require.NoError(t, err)
+
+	now := time.Unix(1600000000, 0)
+	h.nowFn = func() time.Time { return now }
+
+	for i := 1; i < 10; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := h.request()
+	assert.False(t, ok, "below min samples")
+
+	h.record(10 * time.Millisecond)
+	delay, ok := h.request()
+	require.True(t, ok)
+	assert.Equal(t, 9*time.Millisecond, delay)
+
+	for i := 0; i < 2; i++ {
+		h.request()
+	}
+	assert.True(t, h.allow())
+	assert.True(t, h.allow())
+	assert.False(t, h.allow())
+
+	now = now.Add(time.Second * 11)
+	h.request()
+	h.request()
+	assert.True(t, h.allow())
+	assert.False(t, h.allow())
+}
+
+func TestHTTPClientHedging(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	var reqCount uint32
+	traceIDs := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceIDs <- r.Header.Get("Mockpfx-Ids-Traceid")
+		if atomic.AddUint32(&reqCount, 1) == 1 {
+			select {
+			case <-time.After(time.Second * 5):
+			case <-r.Context().Done():
+				return
+			}
+			w.Write([]byte("slow"))
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testget"
+	conf.Verb = "GET"
+	conf.Hedging.Enabled = true
+	conf.Hedging.MinSamples = 1
+	conf.Hedging.Ratio = 1
+
+	h, err := New(conf)
+	require.NoError(t, err)
+	h.hedger.record(time.Millisecond * 10)
+
+	startedAt := time.Now()
+	resMsg, err := h.Send(message.New([][]byte{[]byte("test")}))
+	require.NoError(t, err)
+	assert.Equal(t, "fast", string(resMsg.Get(0).Get()))
+	assert.Less(t, int64(time.Since(startedAt)), int64(time.Second))
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+
+	// Both the original and the hedged request propagate the trace.
+	firstID, secondID := <-traceIDs, <-traceIDs
+	assert.NotEmpty(t, firstID)
+	assert.Equal(t, firstID, secondID)
+}
+
+func TestHTTPClientHedgingUnsafeVerb(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		<-time.After(time.Millisecond * 100)
+		w.Write([]byte("slow"))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Verb = "POST"
+	conf.Hedging.Enabled = true
+	conf.Hedging.MinSamples = 1
+	conf.Hedging.Ratio = 1
+
+	h, err := New(conf)
+	require.NoError(t, err)
+	h.hedger.record(time.Millisecond)
+
+	resMsg, err := h.Send(message.New([][]byte{[]byte("test")}))
+	require.NoError(t, err)
+	assert.Equal(t, "slow", string(resMsg.Get(0).Get()))
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientBadHedgingConfig(t *testing.T) {
+	tests := map[string]func(c *HedgingConfig){
+		"bad window": func(c *HedgingConfig) {
+			c.Window = "nope"
+		},
+		"zero percentile": func(c *HedgingConfig) {
+			c.Percentile = 0
+		},
+		"percentile of one": func(c *HedgingConfig) {
+			c.Percentile = 1
+		},
+		"negative ratio": func(c *HedgingConfig) {
+			c.Ratio = -1
+		},
+	}
+
+	for name, fn := range tests {
+		conf := NewConfig()
+		conf.Hedging.Enabled = true
+		fn(&conf.Hedging)
+
+		_, err := New(conf)
+		assert.Error(t, err, name)
+	}
+}
@@ -33,20 +33,23 @@ import (
 
 // Config is a configuration struct for an HTTP client.
 type Config struct {
-	URL                 string            `json:"url" yaml:"url"`
-	Verb                string            `json:"verb" yaml:"verb"`
-	Headers             map[string]string `json:"headers" yaml:"headers"`
-	CopyResponseHeaders bool              `json:"copy_response_headers" yaml:"copy_response_headers"`
-	RateLimit           string            `json:"rate_limit" yaml:"rate_limit"`
-	Timeout             string            `json:"timeout" yaml:"timeout"`
-	Retry               string            `json:"retry_period" yaml:"retry_period"`
-	MaxBackoff          string            `json:"max_retry_backoff" yaml:"max_retry_backoff"`
-	NumRetries          int               `json:"retries" yaml:"retries"`
-	BackoffOn           []int             `json:"backoff_on" yaml:"backoff_on"`
-	DropOn              []int             `json:"drop_on" yaml:"drop_on"`
-	SuccessfulOn        []int             `json:"successful_on" yaml:"successful_on"`
-	TLS                 tls.Config        `json:"tls" yaml:"tls"`
-	ProxyURL            string            `json:"proxy_url" yaml:"proxy_url"`
+	URL                 string               `json:"url" yaml:"url"`
+	Verb                string               `json:"verb" yaml:"verb"`
+	Headers             map[string]string    `json:"headers" yaml:"headers"`
+	CopyResponseHeaders bool                 `json:"copy_response_headers" yaml:"copy_response_headers"`
+	RateLimit           string               `json:"rate_limit" yaml:"rate_limit"`
+	Timeout             string               `json:"timeout" yaml:"timeout"`
+	Retry               string               `json:"retry_period" yaml:"retry_period"`
+	MaxBackoff          string               `json:"max_retry_backoff" yaml:"max_retry_backoff"`
+	NumRetries          int                  `json:"retries" yaml:"retries"`
+	BackoffOn           []int                `json:"backoff_on" yaml:"backoff_on"`
+	DropOn              []int                `json:"drop_on" yaml:"drop_on"`
+	SuccessfulOn        []int                `json:"successful_on" yaml:"successful_on"`
+	CircuitBreaker      CircuitBreakerConfig `json:"circuit_breaker" yaml:"circuit_breaker"`
+	RetryBudget         RetryBudgetConfig    `json:"retry_budget" yaml:"retry_budget"`
+	Hedging             HedgingConfig        `json:"hedging" yaml:"hedging"`
+	TLS                 tls.Config           `json:"tls" yaml:"tls"`
+	ProxyURL            string               `json:"proxy_url" yaml:"proxy_url"`
 	auth.Config         `json:",inline" yaml:",inline"`
 	OAuth2              auth.OAuth2Config `json:"oauth2" yaml:"oauth2"`
 }
@@ -68,6 +71,9 @@ func NewConfig() Config {
 		BackoffOn:           []int{429},
 		DropOn:              []int{},
 		SuccessfulOn:        []int{},
+		CircuitBreaker:      NewCircuitBreakerConfig(),
+		RetryBudget:         NewRetryBudgetConfig(),
+		Hedging:             NewHedgingConfig(),
 		TLS:                 tls.NewConfig(),
 		Config:              auth.NewConfig(),
 		OAuth2:              auth.NewOAuth2Config(),
@@ -87,10 +93,14 @@ type Type struct {
 	url     field.Expression
 	headers map[string]field.Expression
 	host    field.Expression
+	signer  func(*http.Request) error
 
 	conf          Config
 	retryThrottle *throttle.Type
 	rateLimit     types.RateLimit
+	breaker       *circuitBreaker
+	retryBudget   *retryBudget
+	hedger        *hedger
 
 	log   log.Modular
 	stats metrics.Type
@@ -135,7 +145,7 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		host:      nil,
 	}
 	h.ctx, h.done = context.WithCancel(context.Background())
-	h.client = conf.OAuth2.Client(h.ctx)
+	h.client = &http.Client{}
 
 	if tout := conf.Timeout; len(tout) > 0 {
 		var err error
@@ -198,6 +208,12 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		opt(&h)
 	}
 
+	// OAuth2 wraps the final transport so that token requests also benefit
+	// from any TLS or proxy settings.
+	if h.client.Transport, err = conf.OAuth2.RoundTripper(h.ctx, h.client.Transport); err != nil {
+		return nil, err
+	}
+
 	h.mCount = h.stats.GetCounter("count")
 	h.mErr = h.stats.GetCounter("error")
 	h.mErrReq = h.stats.GetCounter("error.request")
@@ -231,6 +247,22 @@ func New(conf Config, opts ...func(*Type)) (*Type, error) {
 		}
 	}
 
+	if conf.CircuitBreaker.Enabled {
+		if h.breaker, err = newCircuitBreaker(conf.CircuitBreaker, h.stats); err != nil {
+			return nil, fmt.Errorf("failed to create circuit breaker: %v", err)
+		}
+	}
+	if conf.RetryBudget.Enabled {
+		if h.retryBudget, err = newRetryBudget(conf.RetryBudget, h.stats); err != nil {
+			return nil, fmt.Errorf("failed to create retry budget: %v", err)
+		}
+	}
+	if conf.Hedging.Enabled {
+		if h.hedger, err = newHedger(conf.Hedging, h.stats); err != nil {
+			return nil, fmt.Errorf("failed to create hedging: %v", err)
+		}
+	}
+
 	h.retryThrottle = throttle.New(
 		throttle.OptMaxUnthrottledRetries(0),
 		throttle.OptCloseChan(h.closeChan),
@@ -291,6 +323,15 @@ func OptSetRoundTripper(rt http.RoundTripper) func(*Type) {
 	}
 }
 
+// OptSetRequestSigner sets a function to be called on each request after it
+// has been created and authenticated, which can be used in order to add
+// signatures of the request to its headers.
+func OptSetRequestSigner(fn func(*http.Request) error) func(*Type) {
+	return func(t *Type) {
+		t.signer = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 func (h *Type) incrCode(code int) {
@@ -399,6 +440,9 @@ func (h *Type) CreateRequest(msg types.Message) (req *http.Request, err error) {
 	if err == nil {
 		err = h.conf.Config.Sign(req)
 	}
+	if err == nil && h.signer != nil {
+		err = h.signer(req)
+	}
 	return
 }
 
@@ -516,6 +560,141 @@ func (h *Type) checkStatus(code int) (succeeded bool, retStrat retryStrategy) {
 	return true, noRetry
 }
 
+// attempt performs a single HTTP request, subject to the circuit breaker, and
+// returns the response along with the strategy for retrying it on failure.
+func (h *Type) attempt(ctx context.Context, req *http.Request) (res *http.Response, retryStrat retryStrategy, err error) {
+	if h.breaker != nil && !h.breaker.allow() {
+		return nil, noRetry, ErrCircuitOpen
+	}
+
+	retryStrat = retryLinear
+	if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+		h.incrCode(res.StatusCode)
+		var resolved bool
+		if resolved, retryStrat = h.checkStatus(res.StatusCode); !resolved {
+			err = types.ErrUnexpectedHTTPRes{Code: res.StatusCode, S: res.Status}
+			if res.Body != nil {
+				res.Body.Close()
+			}
+			res = nil
+		}
+	} else if err, ok := err.(net.Error); ok && err.Timeout() {
+		h.mErrReqTimeout.Incr(1)
+	}
+
+	if h.breaker != nil {
+		// Responses that aren't retried are the fault of the request rather
+		// than the server and therefore don't count as failures.
+		h.breaker.record(err == nil || retryStrat == noRetry)
+	}
+	return
+}
+
+// hedgeableMethods are the HTTP methods of requests that are safe to hedge, as
+// sending them twice has no side effects.
+var hedgeableMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodOptions: {},
+}
+
+// hedgedAttempt performs an HTTP request and, once the request has taken longer
+// than the hedging delay, performs a second request obtained from newReq and
+// returns whichever succeeds first. Only requests with methods that are safe
+// to send twice are hedged.
+func (h *Type) hedgedAttempt(ctx context.Context, req *http.Request, newReq func() (*http.Request, error)) (*http.Response, retryStrategy, error) {
+	if h.hedger == nil {
+		return h.attempt(ctx, req)
+	}
+	if _, safe := hedgeableMethods[req.Method]; !safe {
+		return h.attempt(ctx, req)
+	}
+
+	type result struct {
+		index      int
+		res        *http.Response
+		retryStrat retryStrategy
+		err        error
+	}
+	results := make(chan result, 2)
+
+	var cancels []func()
+	launch := func(r *http.Request, waitForAccess bool) {
+		index := len(cancels)
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		go func() {
+			if waitForAccess && !h.waitForAccess() {
+				cancel()
+				results <- result{index: index, retryStrat: noRetry, err: types.ErrTypeClosed}
+				return
+			}
+			startedAt := time.Now()
+			res, retryStrat, err := h.attempt(attemptCtx, r)
+			if err == nil {
+				h.hedger.record(time.Since(startedAt))
+			}
+			if res != nil && res.Body != nil {
+				res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+			} else {
+				cancel()
+			}
+			results <- result{index: index, res: res, retryStrat: retryStrat, err: err}
+		}()
+	}
+
+	var hedgeChan <-chan time.Time
+	if delay, ok := h.hedger.request(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeChan = timer.C
+	}
+
+	launch(req, false)
+	pending := 1
+
+	var last result
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err != nil {
+				last = r
+				continue
+			}
+			if r.index > 0 {
+				h.hedger.mWon.Incr(1)
+			}
+			if pending > 0 {
+				for i, cancel := range cancels {
+					if i != r.index {
+						cancel()
+					}
+				}
+				go func() {
+					if loser := <-results; loser.res != nil && loser.res.Body != nil {
+						loser.res.Body.Close()
+					}
+				}()
+			}
+			return r.res, r.retryStrat, nil
+		case <-hedgeChan:
+			hedgeChan = nil
+			if !h.hedger.allow() {
+				continue
+			}
+			hedgeReq, err := newReq()
+			if err != nil {
+				h.log.Errorf("Failed to create hedged request: %v\n", err)
+				continue
+			}
+			launch(hedgeReq, true)
+			pending++
+		}
+	}
+	return nil, last.retryStrat, last.err
+}
+
 // Do attempts to create and perform an HTTP request from a message payload.
 // This attempt may include retries, and if all retries fail an error is
 // returned.
@@ -540,6 +719,25 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 			}
 		}()
 	}
+	injectSpan := func(req *http.Request) {
+		// Propagate the span of the first message part so that the receiving
+		// service can continue the trace.
+		if len(spans) > 0 {
+			_ = opentracing.GlobalTracer().Inject(
+				spans[0].Context(),
+				opentracing.HTTPHeaders,
+				opentracing.HTTPHeadersCarrier(req.Header),
+			)
+		}
+	}
+	newHedgeReq := func() (*http.Request, error) {
+		req, err := h.CreateRequest(msg)
+		if err != nil {
+			return nil, err
+		}
+		injectSpan(req)
+		return req, nil
+	}
 	logErr := func(e error) {
 		for _, s := range spans {
 			s.LogFields(
@@ -556,6 +754,7 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 		logErr(err)
 		return nil, err
 	}
+	injectSpan(req)
 
 	startedAt := time.Now()
 
@@ -563,26 +762,23 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 		return nil, types.ErrTypeClosed
 	}
 
-	rateLimited := false
-	numRetries := h.conf.NumRetries
-	if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
-		h.incrCode(res.StatusCode)
-		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
-			rateLimited = retryStrat == retryBackoff
-			if retryStrat == noRetry {
-				numRetries = 0
-			}
-			err = types.ErrUnexpectedHTTPRes{Code: res.StatusCode, S: res.Status}
-			if res.Body != nil {
-				res.Body.Close()
-			}
-		}
-	} else if err, ok := err.(net.Error); ok && err.Timeout() {
-		h.mErrReqTimeout.Incr(1)
+	if h.retryBudget != nil {
+		h.retryBudget.request()
 	}
 
-	i, j := 0, numRetries
+	var retryStrat retryStrategy
+	res, retryStrat, err = h.hedgedAttempt(ctx, req, newHedgeReq)
+
+	i, j := 0, h.conf.NumRetries
+	if retryStrat == noRetry {
+		j = 0
+	}
 	for i < j && err != nil {
+		if h.retryBudget != nil && !h.retryBudget.allowRetry() {
+			h.log.Debugln("Abandoning retries of request as the retry budget is exhausted")
+			break
+		}
+
 		h.mErrRes.Incr(1)
 		h.mErr.Incr(1)
 		logErr(err)
@@ -594,7 +790,8 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 			logErr(err)
 			continue
 		}
-		if rateLimited {
+		injectSpan(req)
+		if retryStrat == retryBackoff {
 			if !h.retryThrottle.ExponentialRetry() {
 				return nil, types.ErrTypeClosed
 			}
@@ -606,21 +803,8 @@ func (h *Type) DoWithContext(ctx context.Context, msg types.Message) (res *http.
 		if !h.waitForAccess() {
 			return nil, types.ErrTypeClosed
 		}
-		rateLimited = false
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
-			h.incrCode(res.StatusCode)
-			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
-				rateLimited = retryStrat == retryBackoff
-				if retryStrat == noRetry {
-					j = 0
-				}
-				err = types.ErrUnexpectedHTTPRes{Code: res.StatusCode, S: res.Status}
-				if res.Body != nil {
-					res.Body.Close()
-				}
-			}
-		} else if err, ok := err.(net.Error); ok && err.Timeout() {
-			h.mErrReqTimeout.Incr(1)
+		if res, retryStrat, err = h.hedgedAttempt(ctx, req, newHedgeReq); retryStrat == noRetry {
+			j = 0
 		}
 		i++
 	}
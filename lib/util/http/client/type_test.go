@@ -575,3 +575,68 @@ func TestHTTPClientReceiveMultipartWithHeaders(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestHTTPClientOAuth2TokenRefresh(t *testing.T) {
+	var tokensIssued, apiCalls int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if exp, act := "client_credentials", r.PostForm.Get("grant_type"); exp != act {
+			t.Errorf("Wrong grant type: %v != %v", act, exp)
+		}
+		if exp, act := "foo bar", r.PostForm.Get("scope"); exp != act {
+			t.Errorf("Wrong scope: %v != %v", act, exp)
+		}
+		n := atomic.AddInt32(&tokensIssued, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token%v","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&apiCalls, 1)
+		body, _ := ioutil.ReadAll(r.Body)
+		if exp, act := "test", string(body); exp != act {
+			t.Errorf("Wrong body: %v != %v", act, exp)
+		}
+		// The first token issued has been revoked.
+		if r.Header.Get("Authorization") == "Bearer token1" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer ts.Close()
+
+	conf := NewConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.NumRetries = 0
+	conf.OAuth2.Enabled = true
+	conf.OAuth2.ClientKey = "fookey"
+	conf.OAuth2.ClientSecret = "foosecret"
+	conf.OAuth2.TokenURL = tokenServer.URL
+	conf.OAuth2.Scopes = []string{"foo", "bar"}
+
+	h, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		resMsg, err := h.Send(message.New([][]byte{[]byte("test")}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := "Bearer token2", string(resMsg.Get(0).Get()); exp != act {
+			t.Errorf("Wrong response: %v != %v", act, exp)
+		}
+	}
+
+	if exp, act := int32(2), atomic.LoadInt32(&tokensIssued); exp != act {
+		t.Errorf("Wrong count of issued tokens: %v != %v", act, exp)
+	}
+	if exp, act := int32(4), atomic.LoadInt32(&apiCalls); exp != act {
+		t.Errorf("Wrong count of API calls: %v != %v", act, exp)
+	}
+}
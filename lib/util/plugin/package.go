@@ -0,0 +1,4 @@
+// Package plugin implements a specification of the configuration fields of
+// component plugins, which is used to parse, validate and document their
+// configuration without a dedicated config struct.
+package plugin
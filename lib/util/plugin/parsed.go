@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+)
+
+//------------------------------------------------------------------------------
+
+// ParsedConfig is the configuration of a plugin parsed according to its
+// ConfigSpec.
+type ParsedConfig struct {
+	values map[string]interface{}
+}
+
+func (p *ParsedConfig) field(name string) (interface{}, error) {
+	v, exists := p.values[name]
+	if !exists {
+		return nil, fmt.Errorf("field '%v' was not found", name)
+	}
+	return v, nil
+}
+
+// FieldString returns the value of a string field.
+func (p *ParsedConfig) FieldString(name string) (string, error) {
+	v, err := p.field(name)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected field '%v' to be a string, got %T", name, v)
+	}
+	return s, nil
+}
+
+// FieldInt returns the value of an integer field.
+func (p *ParsedConfig) FieldInt(name string) (int, error) {
+	v, err := p.field(name)
+	if err != nil {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case int64:
+		return int(t), nil
+	case float64:
+		if t == float64(int(t)) {
+			return int(t), nil
+		}
+	}
+	return 0, fmt.Errorf("expected field '%v' to be an integer, got %T", name, v)
+}
+
+// FieldFloat returns the value of a number field.
+func (p *ParsedConfig) FieldFloat(name string) (float64, error) {
+	v, err := p.field(name)
+	if err != nil {
+		return 0, err
+	}
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	}
+	return 0, fmt.Errorf("expected field '%v' to be a number, got %T", name, v)
+}
+
+// FieldBool returns the value of a boolean field.
+func (p *ParsedConfig) FieldBool(name string) (bool, error) {
+	v, err := p.field(name)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected field '%v' to be a boolean, got %T", name, v)
+	}
+	return b, nil
+}
+
+// FieldDuration returns the value of a string field parsed as a duration.
+func (p *ParsedConfig) FieldDuration(name string) (time.Duration, error) {
+	s, err := p.FieldString(name)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse field '%v' as a duration: %v", name, err)
+	}
+	return d, nil
+}
+
+// FieldStringList returns the value of a field that is a list of strings.
+func (p *ParsedConfig) FieldStringList(name string) ([]string, error) {
+	v, err := p.field(name)
+	if err != nil {
+		return nil, err
+	}
+	switch t := v.(type) {
+	case []string:
+		return t, nil
+	case []interface{}:
+		strs := make([]string, 0, len(t))
+		for i, e := range t {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected element %v of field '%v' to be a string, got %T", i, name, e)
+			}
+			strs = append(strs, s)
+		}
+		return strs, nil
+	}
+	return nil, fmt.Errorf("expected field '%v' to be a list of strings, got %T", name, v)
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//------------------------------------------------------------------------------
+
+type fieldSpec struct {
+	name         string
+	description  string
+	defaultValue interface{}
+}
+
+// ConfigSpec describes the configuration fields of a plugin, and is built by
+// chaining calls from NewConfigSpec:
+//
+//	spec := plugin.NewConfigSpec().
+//	    Summary("Checks quotas with a remote service.").
+//	    Field("url", "The URL of the quota service.", nil).
+//	    Field("timeout", "The maximum period to wait for a response.", "5s")
+type ConfigSpec struct {
+	summary string
+	fields  []fieldSpec
+}
+
+// NewConfigSpec creates a new ConfigSpec without any fields.
+func NewConfigSpec() *ConfigSpec {
+	return &ConfigSpec{}
+}
+
+// Summary sets a description of the plugin, which is included in its
+// documentation.
+func (c *ConfigSpec) Summary(summary string) *ConfigSpec {
+	c.summary = summary
+	return c
+}
+
+// Field adds a field to the spec along with a description and the value of the
+// field when it is omitted from a config. A nil default value makes the field
+// required.
+func (c *ConfigSpec) Field(name, description string, defaultValue interface{}) *ConfigSpec {
+	c.fields = append(c.fields, fieldSpec{
+		name:         name,
+		description:  description,
+		defaultValue: defaultValue,
+	})
+	return c
+}
+
+// Validate returns an error if the spec is invalid.
+func (c *ConfigSpec) Validate() error {
+	seen := map[string]struct{}{}
+	for _, f := range c.fields {
+		if f.name == "" {
+			return errors.New("fields must have a name")
+		}
+		if _, exists := seen[f.name]; exists {
+			return fmt.Errorf("field '%v' is specified more than once", f.name)
+		}
+		seen[f.name] = struct{}{}
+	}
+	return nil
+}
+
+// Defaults returns a config populated with the default values of the fields of
+// the spec, fields without a default value are omitted.
+func (c *ConfigSpec) Defaults() map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, f := range c.fields {
+		if f.defaultValue != nil {
+			values[f.name] = f.defaultValue
+		}
+	}
+	return values
+}
+
+// Document returns a markdown description of the plugin and its fields.
+func (c *ConfigSpec) Document() string {
+	buf := bytes.Buffer{}
+	buf.WriteString(strings.TrimSpace(c.summary))
+	if len(c.fields) > 0 {
+		if buf.Len() > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString("### Fields\n")
+		for _, f := range c.fields {
+			buf.WriteString(fmt.Sprintf("\n- `%v`: %v", f.name, f.description))
+			if f.defaultValue == nil {
+				buf.WriteString(" (required)")
+			} else {
+				buf.WriteString(fmt.Sprintf(" (default: `%v`)", f.defaultValue))
+			}
+		}
+	}
+	return buf.String()
+}
+
+// Parse a raw config, which is the plugin section of a config, according to the
+// spec. Fields that are omitted are set to their default values, and an error
+// is returned if required fields are missing or unrecognised fields are found.
+func (c *ConfigSpec) Parse(raw interface{}) (*ParsedConfig, error) {
+	values := c.Defaults()
+	if raw != nil {
+		rawMap, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object value, got %T", raw)
+		}
+		for k, v := range rawMap {
+			values[k] = v
+		}
+	}
+
+	known := map[string]struct{}{}
+	for _, f := range c.fields {
+		known[f.name] = struct{}{}
+		if _, exists := values[f.name]; !exists {
+			return nil, fmt.Errorf("field '%v' is required", f.name)
+		}
+	}
+	for k := range values {
+		if _, exists := known[k]; !exists {
+			return nil, fmt.Errorf("field '%v' was not recognised", k)
+		}
+	}
+	return &ParsedConfig{values: values}, nil
+}
+
+//------------------------------------------------------------------------------
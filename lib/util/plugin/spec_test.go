@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSpecValidate(t *testing.T) {
+	assert.NoError(t, NewConfigSpec().Field("foo", "", nil).Validate())
+	assert.Error(t, NewConfigSpec().Field("", "", nil).Validate())
+	assert.Error(t, NewConfigSpec().Field("foo", "", nil).Field("foo", "", 1).Validate())
+}
+
+func TestConfigSpecParse(t *testing.T) {
+	spec := NewConfigSpec().
+		Field("url", "", nil).
+		Field("timeout", "", "5s").
+		Field("retries", "", 3).
+		Field("ratio", "", 0.5).
+		Field("enabled", "", false).
+		Field("tags", "", []string{})
+
+	conf, err := spec.Parse(map[string]interface{}{
+		"url":     "http://localhost",
+		"retries": float64(5),
+		"tags":    []interface{}{"foo", "bar"},
+	})
+	require.NoError(t, err)
+
+	s, err := conf.FieldString("url")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost", s)
+
+	d, err := conf.FieldDuration("timeout")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*5, d)
+
+	i, err := conf.FieldInt("retries")
+	require.NoError(t, err)
+	assert.Equal(t, 5, i)
+
+	f, err := conf.FieldFloat("ratio")
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, f)
+
+	b, err := conf.FieldBool("enabled")
+	require.NoError(t, err)
+	assert.False(t, b)
+
+	l, err := conf.FieldStringList("tags")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, l)
+
+	_, err = conf.FieldInt("url")
+	assert.Error(t, err)
+	_, err = conf.FieldString("nope")
+	assert.Error(t, err)
+}
+
+func TestConfigSpecParseErrors(t *testing.T) {
+	spec := NewConfigSpec().
+		Field("url", "", nil).
+		Field("timeout", "", "5s")
+
+	_, err := spec.Parse(nil)
+	assert.EqualError(t, err, "field 'url' is required")
+
+	_, err = spec.Parse(map[string]interface{}{"url": "foo", "nope": "bar"})
+	assert.EqualError(t, err, "field 'nope' was not recognised")
+
+	_, err = spec.Parse("nope")
+	assert.Error(t, err)
+}
+
+func TestConfigSpecDocument(t *testing.T) {
+	doc := NewConfigSpec().
+		Summary("Does a thing.").
+		Field("url", "The URL.", nil).
+		Field("timeout", "The timeout.", "5s").
+		Document()
+
+	assert.Equal(t, "Does a thing.\n\n### Fields\n\n- `url`: The URL. (required)\n- `timeout`: The timeout. (default: `5s`)", doc)
+}
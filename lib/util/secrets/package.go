@@ -0,0 +1,4 @@
+// Package secrets provides utilities for resolving references to secrets
+// within configuration files, and for redacting resolved secrets from any
+// configs that are echoed back.
+package secrets
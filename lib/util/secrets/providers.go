@@ -0,0 +1,162 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Jeffail/benthos/v3/lib/util/aws/session"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"golang.org/x/oauth2/google"
+)
+
+//------------------------------------------------------------------------------
+
+func init() {
+	RegisterProvider("vault", vaultProvider)
+	RegisterProvider("aws", awsProvider)
+	RegisterProvider("gcp", gcpProvider)
+	RegisterProvider("file", fileProvider)
+}
+
+// httpGetJSON performs a GET request and parses the response body as JSON.
+func httpGetJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("request returned unexpected status code %v: %s", res.StatusCode, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+//------------------------------------------------------------------------------
+
+// vaultProvider reads a secret from the HashiCorp Vault server at VAULT_ADDR
+// using the token VAULT_TOKEN. The path is read with the HTTP API and the data
+// of the secret is returned as a JSON object, where the data of KV version 2
+// secrets is unwrapped.
+func vaultProvider(ctx context.Context, path string) (string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", errors.New("environment variable VAULT_ADDR must be set")
+	}
+	headers := map[string]string{
+		"X-Vault-Token": os.Getenv("VAULT_TOKEN"),
+	}
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		headers["X-Vault-Namespace"] = ns
+	}
+
+	var res struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	url := strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	if err := httpGetJSON(ctx, http.DefaultClient, url, headers, &res); err != nil {
+		return "", err
+	}
+
+	data := res.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner
+		}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// awsProvider reads the string value of a secret from AWS Secrets Manager,
+// where the path is the name or ARN of the secret. The region is taken from
+// the ARN or otherwise AWS_REGION, and credentials from the environment.
+func awsProvider(ctx context.Context, path string) (string, error) {
+	conf := session.NewConfig()
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		conf.Region = region
+	}
+	if parts := strings.Split(path, ":"); len(parts) > 3 && parts[0] == "arn" {
+		conf.Region = parts[3]
+	}
+	sess, err := conf.GetSession()
+	if err != nil {
+		return "", err
+	}
+	out, err := secretsmanager.New(sess).GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(path),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString != nil {
+		return *out.SecretString, nil
+	}
+	return string(out.SecretBinary), nil
+}
+
+// gcpProvider reads a secret version from GCP Secret Manager using application
+// default credentials, where the path is the resource name of the version.
+// When the path is the name of a secret the latest version is read.
+func gcpProvider(ctx context.Context, path string) (string, error) {
+	name := strings.TrimPrefix(path, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	url := "https://secretmanager.googleapis.com/v1/" + name + ":access"
+	if err = httpGetJSON(ctx, client, url, nil, &res); err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(res.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode payload: %v", err)
+	}
+	return string(data), nil
+}
+
+// fileProvider reads a secret from a file, trimming any trailing newlines.
+func fileProvider(ctx context.Context, path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+//------------------------------------------------------------------------------
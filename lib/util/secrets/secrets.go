@@ -0,0 +1,219 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	uconfig "github.com/Jeffail/benthos/v3/lib/util/config"
+	"gopkg.in/yaml.v3"
+)
+
+//------------------------------------------------------------------------------
+
+// Redacted is the value that replaces resolved secrets within redacted
+// configs.
+const Redacted = "!!!SECRET_SCRUBBED!!!"
+
+// DefaultTimeout is the default maximum period of time to wait for the secrets
+// referenced within a config to be resolved.
+const DefaultTimeout = time.Second * 30
+
+// ProviderFunc resolves a secret from a path, which is the section of a secret
+// reference following the provider name and preceding any field.
+type ProviderFunc func(ctx context.Context, path string) (string, error)
+
+var secretRegex = regexp.MustCompile(`\${secret:([0-9A-Za-z_]+):([^}#]+)(#[^}]+)?}`)
+
+var (
+	providersMut sync.RWMutex
+	providers    = map[string]ProviderFunc{}
+
+	resolvedMut sync.RWMutex
+	resolved    = map[string]string{}
+)
+
+// RegisterProvider adds a secrets provider under a name, which can then be
+// referenced within configs with the pattern `${secret:name:path#field}`. A
+// provider registered with an existing name replaces it.
+func RegisterProvider(name string, fn ProviderFunc) {
+	providersMut.Lock()
+	providers[name] = fn
+	providersMut.Unlock()
+}
+
+// ContainsSecrets returns true if inBytes contains secret references.
+func ContainsSecrets(inBytes []byte) bool {
+	return secretRegex.Find(inBytes) != nil
+}
+
+// Replace searches a blob of data for the pattern `${secret:provider:path}`,
+// where `provider` is the name of a registered secrets provider and `path`
+// identifies a secret to that provider, and replaces each one with the value
+// of the secret. The path can optionally be followed by `#field`, in which case
+// the secret is parsed as a JSON object and the value of the field is used.
+//
+// Each reference is resolved only once per process, and all resolved values
+// are remembered so that they can be redacted. An error is returned if any
+// reference cannot be resolved.
+func Replace(ctx context.Context, inBytes []byte) ([]byte, error) {
+	var err error
+	replaced := secretRegex.ReplaceAllFunc(inBytes, func(content []byte) []byte {
+		if err != nil {
+			return content
+		}
+		var value string
+		if value, err = resolve(ctx, string(content)); err != nil {
+			return content
+		}
+		return []byte(value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replaced, nil
+}
+
+// ReplaceWithTimeout calls Replace with a context that is cancelled after the
+// provided timeout.
+func ReplaceWithTimeout(inBytes []byte, timeout time.Duration) ([]byte, error) {
+	if !ContainsSecrets(inBytes) {
+		return inBytes, nil
+	}
+	ctx, done := context.WithTimeout(context.Background(), timeout)
+	defer done()
+	return Replace(ctx, inBytes)
+}
+
+func resolve(ctx context.Context, ref string) (string, error) {
+	resolvedMut.RLock()
+	value, exists := resolved[ref]
+	resolvedMut.RUnlock()
+	if exists {
+		return value, nil
+	}
+
+	matches := secretRegex.FindStringSubmatch(ref)
+	name, path, field := matches[1], matches[2], strings.TrimPrefix(matches[3], "#")
+
+	providersMut.RLock()
+	fn, exists := providers[name]
+	providersMut.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("failed to resolve secret '%v': provider '%v' not recognised", path, name)
+	}
+
+	value, err := fn(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret '%v' from provider '%v': %v", path, name, err)
+	}
+	if field != "" {
+		if value, err = extractField(value, field); err != nil {
+			return "", fmt.Errorf("failed to resolve secret '%v' from provider '%v': %v", path, name, err)
+		}
+	}
+
+	resolvedMut.Lock()
+	resolved[ref] = value
+	resolvedMut.Unlock()
+	return value, nil
+}
+
+// extractField parses a secret as a JSON object and returns the value of a
+// field within it. Values that are not strings are returned as JSON.
+func extractField(secret, field string) (string, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &obj); err != nil {
+		return "", fmt.Errorf("failed to parse secret as a JSON object: %v", err)
+	}
+	v, exists := obj[field]
+	if !exists {
+		return "", fmt.Errorf("field '%v' not found", field)
+	}
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+//------------------------------------------------------------------------------
+
+// resolvedValues returns all non-empty resolved secrets, longest first so that
+// secrets containing other secrets are redacted in full.
+func resolvedValues() []string {
+	resolvedMut.RLock()
+	values := make([]string, 0, len(resolved))
+	for _, v := range resolved {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	resolvedMut.RUnlock()
+
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	return values
+}
+
+// Redact replaces any resolved secrets within a string.
+func Redact(s string) string {
+	for _, v := range resolvedValues() {
+		s = strings.Replace(s, v, Redacted, -1)
+	}
+	return s
+}
+
+// RedactJSON replaces any resolved secrets within a JSON document.
+func RedactJSON(b []byte) []byte {
+	for _, v := range resolvedValues() {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		// Strip the quotes in order to match the secret within strings.
+		encoded = encoded[1 : len(encoded)-1]
+		b = bytes.Replace(b, encoded, []byte(Redacted), -1)
+	}
+	return b
+}
+
+// MarshalRedactedYAML marshals a value as YAML, replacing any resolved secrets
+// within its scalar values.
+func MarshalRedactedYAML(v interface{}) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	values := resolvedValues()
+	if len(values) > 0 {
+		redactNode(&node, values)
+	}
+	return uconfig.MarshalYAML(&node)
+}
+
+func redactNode(node *yaml.Node, values []string) {
+	if node.Kind == yaml.ScalarNode {
+		for _, v := range values {
+			if strings.Contains(node.Value, v) {
+				node.Value = strings.Replace(node.Value, v, Redacted, -1)
+				node.Tag = "!!str"
+			}
+		}
+	}
+	for _, c := range node.Content {
+		redactNode(c, values)
+	}
+}
+
+//------------------------------------------------------------------------------
@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretsReplace(t *testing.T) {
+	var calls int
+	RegisterProvider("testreplace", func(ctx context.Context, path string) (string, error) {
+		calls++
+		switch path {
+		case "foo":
+			return "foosecret", nil
+		case "obj":
+			return `{"a":"asecret","b":10}`, nil
+		}
+		return "", errors.New("nope")
+	})
+
+	res, err := Replace(context.Background(), []byte(`a: ${secret:testreplace:foo}
+b: ${secret:testreplace:obj#a}
+c: ${secret:testreplace:obj#b}
+d: ${secret:testreplace:foo}
+e: ${FOO:bar}
+f: ${{secret:testreplace:foo}}`))
+	require.NoError(t, err)
+	assert.Equal(t, `a: foosecret
+b: asecret
+c: 10
+d: foosecret
+e: ${FOO:bar}
+f: ${{secret:testreplace:foo}}`, string(res))
+	assert.Equal(t, 3, calls)
+
+	_, err = Replace(context.Background(), []byte(`a: ${secret:testreplace:bar}`))
+	assert.EqualError(t, err, "failed to resolve secret 'bar' from provider 'testreplace': nope")
+
+	_, err = Replace(context.Background(), []byte(`a: ${secret:testreplace:obj#c}`))
+	assert.EqualError(t, err, "failed to resolve secret 'obj' from provider 'testreplace': field 'c' not found")
+
+	_, err = Replace(context.Background(), []byte(`a: ${secret:testnope:foo}`))
+	assert.EqualError(t, err, "failed to resolve secret 'foo': provider 'testnope' not recognised")
+}
+
+func TestSecretsRedact(t *testing.T) {
+	RegisterProvider("testredact", func(ctx context.Context, path string) (string, error) {
+		return path + `"quoted"`, nil
+	})
+
+	_, err := Replace(context.Background(), []byte(`${secret:testredact:bar}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "foo "+Redacted+" baz", Redact(`foo bar"quoted" baz`))
+	assert.Equal(t, `{"a":"foo `+Redacted+`"}`, string(RedactJSON([]byte(`{"a":"foo bar\"quoted\""}`))))
+
+	res, err := MarshalRedactedYAML(map[string]interface{}{
+		"a": `bar"quoted"`,
+		"b": "baz",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "a: '"+Redacted+"'\nb: baz\n", string(res))
+}
+
+func TestSecretsFileProvider(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "benthos_secrets_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "secret")
+	require.NoError(t, ioutil.WriteFile(path, []byte("filesecret\n"), 0600))
+
+	res, err := Replace(context.Background(), []byte("a: ${secret:file:"+path+"}"))
+	require.NoError(t, err)
+	assert.Equal(t, "a: filesecret", string(res))
+}
+
+func TestSecretsVaultProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "footoken" {
+			http.Error(w, "nope", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/foo":
+			w.Write([]byte(`{"data":{"data":{"token":"vaultsecret"},"metadata":{"version":1}}}`))
+		case "/v1/secret/bar":
+			w.Write([]byte(`{"data":{"token":"oldvaultsecret"}}`))
+		default:
+			http.Error(w, "nope", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	os.Setenv("VAULT_ADDR", ts.URL)
+	os.Setenv("VAULT_TOKEN", "footoken")
+	defer func() {
+		os.Unsetenv("VAULT_ADDR")
+		os.Unsetenv("VAULT_TOKEN")
+	}()
+
+	res, err := Replace(context.Background(), []byte(`a: ${secret:vault:kv/data/foo#token}
+b: ${secret:vault:secret/bar#token}`))
+	require.NoError(t, err)
+	assert.Equal(t, "a: vaultsecret\nb: oldvaultsecret", string(res))
+
+	_, err = Replace(context.Background(), []byte(`a: ${secret:vault:kv/data/baz#token}`))
+	assert.Error(t, err)
+}
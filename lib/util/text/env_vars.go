@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"regexp"
+	"strings"
 )
 
 //------------------------------------------------------------------------------
@@ -27,6 +28,42 @@ func ContainsEnvVariables(inBytes []byte) bool {
 	return envRegex.Find(inBytes) != nil || escapedEnvRegex.Find(inBytes) != nil
 }
 
+// EnvVariable is a reference to an environment variable of the pattern
+// `${FOO:bar}` found within a blob of data.
+type EnvVariable struct {
+	Expression string
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// EnvVariables returns each distinct environment variable reference within a
+// blob of data in the order that they first appear. Escaped patterns of the
+// form `${{FOO}}` are ignored.
+func EnvVariables(inBytes []byte) []EnvVariable {
+	var vars []EnvVariable
+	seen := map[string]struct{}{}
+	for _, match := range envRegex.FindAll(inBytes, -1) {
+		expr := string(match)
+		if _, exists := seen[expr]; exists {
+			continue
+		}
+		seen[expr] = struct{}{}
+
+		v := EnvVariable{
+			Expression: expr,
+			Name:       expr[2 : len(expr)-1],
+		}
+		if colonIndex := strings.IndexByte(expr, ':'); colonIndex != -1 {
+			v.Name = expr[2:colonIndex]
+			v.Default = expr[colonIndex+1 : len(expr)-1]
+			v.HasDefault = true
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
 // ReplaceEnvVariables will search a blob of data for the pattern `${FOO:bar}`,
 // where `FOO` is an environment variable name and `bar` is a default value. The
 // `bar` section (including the colon) can be left out if there is no
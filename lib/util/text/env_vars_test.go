@@ -2,6 +2,7 @@ package text
 
 import (
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -67,3 +68,19 @@ func TestEnvSwapping(t *testing.T) {
 		}
 	}
 }
+
+func TestEnvVariables(t *testing.T) {
+	vars := EnvVariables([]byte(`
+foo: ${BENTHOS_TEST_FOO}
+bar: ${BENTHOS_TEST_BAR:default value}
+baz: ${BENTHOS_TEST_FOO} and ${{BENTHOS_TEST_ESCAPED}}
+qux: ${foo.bar:}
+`))
+	exp := []EnvVariable{
+		{Expression: "${BENTHOS_TEST_FOO}", Name: "BENTHOS_TEST_FOO"},
+		{Expression: "${BENTHOS_TEST_BAR:default value}", Name: "BENTHOS_TEST_BAR", Default: "default value", HasDefault: true},
+	}
+	if !reflect.DeepEqual(exp, vars) {
+		t.Errorf("Wrong result: %v != %v", vars, exp)
+	}
+}
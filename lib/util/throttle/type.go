@@ -28,8 +28,16 @@ type Type struct {
 	// baseThrottlePeriod is the static duration for which our throttle lasts.
 	baseThrottlePeriod int64
 
+	// backoffUntil is the time, in unix nanoseconds, at which the current
+	// throttle ends, or zero if a retry is not currently being throttled.
+	backoffUntil int64
+
 	// closeChan can interrupt a throttle when closed.
 	closeChan <-chan struct{}
+
+	// onStateChange is called whenever the count of consecutive retries or the
+	// current backoff changes.
+	onStateChange func(consecutiveRetries int64, backoff time.Duration)
 }
 
 // New creates a new throttle, which permits a static number of consecutive
@@ -83,17 +91,37 @@ func OptCloseChan(c <-chan struct{}) func(*Type) {
 	}
 }
 
+// OptOnStateChange sets a closure to be called whenever the count of
+// consecutive retries or the current backoff period changes, which is useful
+// for exposing the state of the throttle as metrics.
+func OptOnStateChange(fn func(consecutiveRetries int64, backoff time.Duration)) func(*Type) {
+	return func(t *Type) {
+		t.onStateChange = fn
+	}
+}
+
 //------------------------------------------------------------------------------
 
 // Retry indicates that a retry is about to occur and, if appropriate, will
 // block until either the throttle period is over and the retry may be attempted
 // (returning true) or that the close channel has closed (returning false).
 func (t *Type) Retry() bool {
-	if rets := atomic.AddInt64(&t.consecutiveRetries, 1); rets <= t.unthrottledRetries {
+	rets := atomic.AddInt64(&t.consecutiveRetries, 1)
+	if rets <= t.unthrottledRetries {
+		t.stateChanged(rets, 0)
 		return true
 	}
+
+	period := time.Duration(atomic.LoadInt64(&t.throttlePeriod))
+	atomic.StoreInt64(&t.backoffUntil, time.Now().Add(period).UnixNano())
+	t.stateChanged(rets, period)
+	defer func() {
+		atomic.StoreInt64(&t.backoffUntil, 0)
+		t.stateChanged(atomic.LoadInt64(&t.consecutiveRetries), 0)
+	}()
+
 	select {
-	case <-time.After(time.Duration(atomic.LoadInt64(&t.throttlePeriod))):
+	case <-time.After(period):
 	case <-t.closeChan:
 		return false
 	}
@@ -118,8 +146,35 @@ func (t *Type) ExponentialRetry() bool {
 // Reset clears the count of consecutive retries and resets the exponential
 // backoff.
 func (t *Type) Reset() {
-	atomic.StoreInt64(&t.consecutiveRetries, 0)
+	if atomic.SwapInt64(&t.consecutiveRetries, 0) > 0 {
+		t.stateChanged(0, 0)
+	}
 	atomic.StoreInt64(&t.throttlePeriod, t.baseThrottlePeriod)
 }
 
+// ConsecutiveRetries returns the number of consecutive retries since the last
+// reset.
+func (t *Type) ConsecutiveRetries() int64 {
+	return atomic.LoadInt64(&t.consecutiveRetries)
+}
+
+// Backoff returns the remaining period of the current throttle, or zero if a
+// retry is not currently being throttled.
+func (t *Type) Backoff() time.Duration {
+	until := atomic.LoadInt64(&t.backoffUntil)
+	if until == 0 {
+		return 0
+	}
+	if remaining := time.Until(time.Unix(0, until)); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+func (t *Type) stateChanged(consecutiveRetries int64, backoff time.Duration) {
+	if t.onStateChange != nil {
+		t.onStateChange(consecutiveRetries, backoff)
+	}
+}
+
 //------------------------------------------------------------------------------
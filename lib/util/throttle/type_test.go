@@ -160,3 +160,64 @@ func TestThrottleExponent(t *testing.T) {
 		t.Errorf("Unexpected retry period: %v != %v", act, exp)
 	}
 }
+
+func TestThrottleState(t *testing.T) {
+	closeChan := make(chan struct{})
+
+	type state struct {
+		retries int64
+		backoff time.Duration
+	}
+	states := make(chan state, 10)
+
+	throt := New(
+		OptMaxUnthrottledRetries(1),
+		OptThrottlePeriod(time.Minute),
+		OptCloseChan(closeChan),
+		OptOnStateChange(func(retries int64, backoff time.Duration) {
+			states <- state{retries, backoff}
+		}),
+	)
+
+	if !throt.Retry() {
+		t.Error("Throttle blocked early")
+	}
+	if exp, act := int64(1), throt.ConsecutiveRetries(); exp != act {
+		t.Errorf("Wrong consecutive retries: %v != %v", act, exp)
+	}
+	if act := throt.Backoff(); act != 0 {
+		t.Errorf("Unexpected backoff: %v", act)
+	}
+
+	retried := make(chan bool)
+	go func() {
+		retried <- throt.Retry()
+	}()
+
+	<-states
+	if s := <-states; s.retries != 2 || s.backoff != time.Minute {
+		t.Errorf("Wrong state: %+v", s)
+	}
+	if act := throt.Backoff(); act <= 0 || act > time.Minute {
+		t.Errorf("Wrong backoff: %v", act)
+	}
+
+	close(closeChan)
+	if <-retried {
+		t.Error("Throttle wasn't interrupted")
+	}
+	if s := <-states; s.retries != 2 || s.backoff != 0 {
+		t.Errorf("Wrong state: %+v", s)
+	}
+	if act := throt.Backoff(); act != 0 {
+		t.Errorf("Unexpected backoff: %v", act)
+	}
+
+	throt.Reset()
+	if s := <-states; s.retries != 0 || s.backoff != 0 {
+		t.Errorf("Wrong state: %+v", s)
+	}
+	if act := throt.ConsecutiveRetries(); act != 0 {
+		t.Errorf("Unexpected consecutive retries: %v", act)
+	}
+}
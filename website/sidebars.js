@@ -21,6 +21,7 @@ module.exports = {
       items: [
         'configuration/about',
         'configuration/resources',
+        'configuration/templating',
         'configuration/batching',
         'configuration/windowed_processing',
         'configuration/metadata',